@@ -1,22 +1,33 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"time"
+
+	"sentinel-tui-go/internal/httpclient"
 )
 
 type Client struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *httpclient.Client
+	// Write actions (job runs, order submission) can take far longer than reads.
+	actionClient *httpclient.Client
 }
 
 func NewClient(baseURL string) *Client {
 	return &Client{
-		baseURL:    baseURL,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL: baseURL,
+		// Reads are polled, so a brief backend restart should not blank the screen.
+		httpClient: httpclient.New(httpclient.Config{
+			Timeout: 10 * time.Second,
+			Retry:   httpclient.RetryPolicy{MaxAttempts: 3, BaseDelay: 250 * time.Millisecond, MaxDelay: 2 * time.Second},
+		}),
+		actionClient: httpclient.New(httpclient.Config{Timeout: 5 * time.Minute}),
 	}
 }
 
@@ -27,7 +38,10 @@ func (c *Client) SetBaseURL(baseURL string) {
 // Response types
 
 type Health struct {
-	TradingMode string `json:"trading_mode"`
+	TradingMode   string `json:"trading_mode"`
+	TradingPaused bool   `json:"trading_paused"`
+	// Unix seconds; nil while paused means "until resumed".
+	TradingPausedUntilTS *int64 `json:"trading_paused_until_ts"`
 }
 
 type Portfolio struct {
@@ -68,6 +82,27 @@ type Recommendation struct {
 	Reason   string  `json:"reason"`
 }
 
+// Goal is an investment goal with its projected probability of being reached.
+type Goal struct {
+	Name            string  `json:"name"`
+	TargetAmountEUR float64 `json:"target_amount_eur"`
+	TargetDate      string  `json:"target_date"`
+	Probability     float64 `json:"probability"`
+	P50EUR          float64 `json:"p50_eur"`
+	OnTrack         bool    `json:"on_track"`
+}
+
+type JobSchedule struct {
+	JobType     string `json:"job_type"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+type LEDMode struct {
+	Mode   string `json:"mode"`
+	Source string `json:"source"`
+}
+
 type PricePoint struct {
 	Date  string  `json:"date"`
 	Close float64 `json:"close"`
@@ -88,9 +123,25 @@ type Security struct {
 	Industry          string       `json:"industry"`
 	Currency          string       `json:"currency"`
 	ExpectedReturn    float64      `json:"contrarian_score"`
+	ExcludedBy        []Exclusion  `json:"excluded_by"`
+	PositionTarget    *Target      `json:"position_target"`
 	Prices            []PricePoint `json:"prices"`
 }
 
+// Exclusion is an exclusion list that keeps a security from being bought.
+type Exclusion struct {
+	Name    string `json:"name"`
+	Reason  string `json:"reason"`
+	Matched string `json:"matched"`
+}
+
+// Target is a security's position target weight.
+type Target struct {
+	TargetPct float64 `json:"target_pct"`
+	Mode      string  `json:"mode"`
+	Source    string  `json:"source"`
+}
+
 // Internal helpers
 
 func (c *Client) get(path string, params url.Values, target any) error {
@@ -109,6 +160,45 @@ func (c *Client) get(path string, params url.Values, target any) error {
 	return json.NewDecoder(resp.Body).Decode(target)
 }
 
+// send issues a write request with an optional JSON body, decoding the reply
+// into target when non-nil. Non-2xx responses are returned as errors carrying
+// the API's "detail" message when present.
+func (c *Client) send(method, path string, body, target any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.actionClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Detail string `json:"detail"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Detail != "" {
+			return fmt.Errorf("API returned %d: %s", resp.StatusCode, apiErr.Detail)
+		}
+		return fmt.Errorf("API returned %d", resp.StatusCode)
+	}
+	if target == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
 // Endpoints
 
 func (c *Client) Health() (Health, error) {
@@ -138,3 +228,74 @@ func (c *Client) Unified() ([]Security, error) {
 	var s []Security
 	return s, c.get("/api/unified", nil, &s)
 }
+
+func (c *Client) GoalProjection() ([]Goal, error) {
+	var resp struct {
+		Goals []Goal `json:"goals"`
+	}
+	err := c.get("/api/goals/projection", nil, &resp)
+	return resp.Goals, err
+}
+
+func (c *Client) JobSchedules() ([]JobSchedule, error) {
+	var resp struct {
+		Schedules []JobSchedule `json:"schedules"`
+	}
+	err := c.get("/api/jobs/schedules", nil, &resp)
+	return resp.Schedules, err
+}
+
+func (c *Client) LEDMode() (LEDMode, error) {
+	var m LEDMode
+	return m, c.get("/api/led/mode", nil, &m)
+}
+
+// Write actions
+
+// RunJob runs a job to completion. The API reports job failures with a 200
+// and status "failed", so those are surfaced as errors here.
+func (c *Client) RunJob(jobType string) error {
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	if err := c.send(http.MethodPost, "/api/jobs/"+jobType+"/run", nil, &result); err != nil {
+		return err
+	}
+	switch {
+	case result.Status == "failed":
+		return fmt.Errorf("job failed: %s", result.Error)
+	case result.Reason != "":
+		return fmt.Errorf("job skipped: %s", result.Reason)
+	}
+	return nil
+}
+
+func (c *Client) ApproveRecommendation(symbol, action string) error {
+	return c.send(http.MethodPost, "/api/planner/recommendations/approve",
+		map[string]string{"symbol": symbol, "action": action}, nil)
+}
+
+func (c *Client) RejectRecommendation(symbol, action string) error {
+	return c.send(http.MethodPost, "/api/planner/recommendations/reject",
+		map[string]string{"symbol": symbol, "action": action}, nil)
+}
+
+func (c *Client) SetDisplayMode(mode string, minutes int) error {
+	return c.send(http.MethodPut, "/api/led/mode/override",
+		map[string]any{"mode": mode, "minutes": minutes}, nil)
+}
+
+func (c *Client) SetTradingMode(mode string) error {
+	return c.send(http.MethodPut, "/api/settings/trading_mode", map[string]string{"value": mode}, nil)
+}
+
+// PauseTrading stops all order placement for the given number of hours.
+func (c *Client) PauseTrading(hours int) error {
+	return c.send(http.MethodPost, "/api/trading/pause", map[string]any{"hours": hours}, nil)
+}
+
+func (c *Client) ResumeTrading() error {
+	return c.send(http.MethodDelete, "/api/trading/pause", nil, nil)
+}
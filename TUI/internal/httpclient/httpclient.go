@@ -0,0 +1,111 @@
+// Package httpclient builds the HTTP clients the TUI talks to the backend
+// with: per-client timeouts, retries with backoff and jitter, proxies from
+// the environment and TLS options.
+package httpclient
+
+import (
+	"crypto/tls"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed idempotent requests are retried.
+// Zero MaxAttempts (or 1) disables retries.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Config describes one client.
+type Config struct {
+	// Timeout bounds a single attempt, including reading the body.
+	Timeout time.Duration
+	Retry   RetryPolicy
+	// TLS overrides the transport's TLS settings (custom roots, skipping
+	// verification for a self-signed backend). Nil keeps Go's defaults.
+	TLS *tls.Config
+}
+
+// Client wraps an http.Client with a retry policy.
+type Client struct {
+	http  *http.Client
+	retry RetryPolicy
+}
+
+// New builds a client. Proxies come from HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY like any Go program.
+func New(cfg Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.TLS != nil {
+		transport.TLSClientConfig = cfg.TLS
+	}
+	return &Client{
+		http:  &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		retry: cfg.Retry,
+	}
+}
+
+// Get issues a GET request, retrying per the client's policy.
+func (c *Client) Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do sends req. Only body-less GET and HEAD requests are retried, on network
+// errors and on 429/502/503/504: writes such as order submission must never
+// run twice.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	attempts := c.retry.MaxAttempts
+	if !retryable(req) || attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := c.http.Do(req)
+		if attempt >= attempts || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(c.backoff(attempt)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func retryable(req *http.Request) bool {
+	return (req.Method == http.MethodGet || req.Method == http.MethodHead) && req.Body == nil
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff doubles BaseDelay per attempt up to MaxDelay, then picks a random
+// delay in its upper half so clients that failed together do not retry in
+// lockstep.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.BaseDelay << (attempt - 1)
+	if c.retry.MaxDelay > 0 && (delay > c.retry.MaxDelay || delay <= 0) {
+		delay = c.retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"sentinel-tui-go/internal/api"
+)
+
+// actionItem is one entry in the write-actions menu. Every item is confirmed
+// before it runs; apply updates local state optimistically so the screen
+// reflects the change before the next refresh confirms (or reverts) it.
+type actionItem struct {
+	label   string
+	confirm string
+	run     func(c *api.Client) error
+	apply   func(m *Model)
+}
+
+// How long a display mode picked from the TUI overrides the schedule.
+const displayOverrideMinutes = 60
+
+// How long "Pause trading" from the TUI stops order placement.
+const tradingPauseHours = 24
+
+var displayModes = []string{"ticker", "health", "stats"}
+
+type actionDoneMsg struct {
+	label string
+	err   error
+}
+
+type jobsMsg struct {
+	jobs []api.JobSchedule
+	err  error
+}
+
+type ledModeMsg struct {
+	mode api.LEDMode
+	err  error
+}
+
+func (m Model) buildActions() []actionItem {
+	var items []actionItem
+
+	for _, rec := range m.recommendations {
+		desc := fmt.Sprintf("%s %s x %s", strings.ToUpper(rec.Action), formatQuantity(rec.Quantity), rec.Symbol)
+		remove := func(m *Model) { m.removeRecommendation(rec.Symbol, rec.Action) }
+		items = append(items,
+			actionItem{
+				label:   "Approve " + desc,
+				confirm: fmt.Sprintf("Submit %s to the broker now?", desc),
+				run:     func(c *api.Client) error { return c.ApproveRecommendation(rec.Symbol, rec.Action) },
+				apply:   remove,
+			},
+			actionItem{
+				label:   "Reject " + desc,
+				confirm: fmt.Sprintf("Hide %s for 24h?", desc),
+				run:     func(c *api.Client) error { return c.RejectRecommendation(rec.Symbol, rec.Action) },
+				apply:   remove,
+			},
+		)
+	}
+
+	for _, mode := range displayModes {
+		if mode == m.displayMode {
+			continue
+		}
+		items = append(items, actionItem{
+			label:   fmt.Sprintf("Display mode: %s (%d min)", mode, displayOverrideMinutes),
+			confirm: fmt.Sprintf("Show %s on the LED display for %d minutes?", mode, displayOverrideMinutes),
+			run:     func(c *api.Client) error { return c.SetDisplayMode(mode, displayOverrideMinutes) },
+			apply:   func(m *Model) { m.displayMode = mode },
+		})
+	}
+
+	if m.tradingPaused {
+		items = append(items, actionItem{
+			label:   "Resume trading",
+			confirm: "Lift the trading pause and allow orders again?",
+			run:     func(c *api.Client) error { return c.ResumeTrading() },
+			apply:   func(m *Model) { m.tradingPaused, m.pausedUntil, m.contentDirty = false, nil, true },
+		})
+	} else {
+		items = append(items, actionItem{
+			label:   fmt.Sprintf("Pause trading (%dh)", tradingPauseHours),
+			confirm: fmt.Sprintf("Stop all order placement for %d hours?", tradingPauseHours),
+			run:     func(c *api.Client) error { return c.PauseTrading(tradingPauseHours) },
+			apply: func(m *Model) {
+				until := time.Now().Add(tradingPauseHours * time.Hour).Unix()
+				m.tradingPaused, m.pausedUntil, m.contentDirty = true, &until, true
+			},
+		})
+	}
+
+	next := "live"
+	if m.tradingMode == "live" {
+		next = "research"
+	}
+	items = append(items, actionItem{
+		label:   "Trading mode: switch to " + next,
+		confirm: fmt.Sprintf("Switch trading mode to %s?", strings.ToUpper(next)),
+		run:     func(c *api.Client) error { return c.SetTradingMode(next) },
+		apply:   func(m *Model) { m.tradingMode = next },
+	})
+
+	for _, job := range m.jobs {
+		items = append(items, actionItem{
+			label:   "Run job: " + job.JobType,
+			confirm: fmt.Sprintf("Run %s now?", job.JobType),
+			run:     func(c *api.Client) error { return c.RunJob(job.JobType) },
+		})
+	}
+
+	return items
+}
+
+func (m *Model) removeRecommendation(symbol, action string) {
+	kept := m.recommendations[:0:0]
+	for _, rec := range m.recommendations {
+		if rec.Symbol != symbol || rec.Action != action {
+			kept = append(kept, rec)
+		}
+	}
+	m.recommendations = kept
+}
+
+// refreshActions rebuilds the menu after state changes, keeping the cursor in range.
+func (m *Model) refreshActions() {
+	m.actionItems = m.buildActions()
+	if m.actionCursor >= len(m.actionItems) {
+		m.actionCursor = max(0, len(m.actionItems)-1)
+	}
+}
+
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// Commands
+
+func runAction(c *api.Client, item actionItem) tea.Cmd {
+	return func() tea.Msg {
+		return actionDoneMsg{label: item.label, err: item.run(c)}
+	}
+}
+
+func fetchJobs(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		j, err := c.JobSchedules()
+		return jobsMsg{j, err}
+	}
+}
+
+func fetchLEDMode(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		lm, err := c.LEDMode()
+		return ledModeMsg{lm, err}
+	}
+}
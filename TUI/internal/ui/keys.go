@@ -7,6 +7,12 @@ type keyMap struct {
 	Back         key.Binding
 	OpenSettings key.Binding
 	SaveSettings key.Binding
+	OpenActions  key.Binding
+	Up           key.Binding
+	Down         key.Binding
+	Select       key.Binding
+	Confirm      key.Binding
+	Cancel       key.Binding
 }
 
 var keys = keyMap{
@@ -14,4 +20,10 @@ var keys = keyMap{
 	Back:         key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
 	OpenSettings: key.NewBinding(key.WithKeys("s", "o"), key.WithHelp("s/o", "settings")),
 	SaveSettings: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save")),
+	OpenActions:  key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "actions")),
+	Up:           key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:         key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Select:       key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
+	Confirm:      key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
+	Cancel:       key.NewBinding(key.WithKeys("n", "esc"), key.WithHelp("n/esc", "cancel")),
 }
@@ -18,10 +18,15 @@ type Model struct {
 	// Data
 	connected       bool
 	tradingMode     string
+	tradingPaused   bool
+	pausedUntil     *int64
 	portfolio       *api.Portfolio
 	pnlHistory      *api.PnLHistory
 	recommendations []api.Recommendation
 	securities      []api.Security
+	goals           []api.Goal
+	jobs            []api.JobSchedule
+	displayMode     string
 
 	// UI state
 	width       int
@@ -33,6 +38,14 @@ type Model struct {
 	apiURLInput string
 	statusMsg   string
 
+	// Write-actions menu
+	inActions    bool
+	actionItems  []actionItem
+	actionCursor int
+	confirming   bool
+	actionBusy   bool
+	actionErr    bool
+
 	// Auto-scroll
 	scrolling    bool
 	scrollAccum  float64
@@ -70,6 +83,11 @@ type securitiesMsg struct {
 	err        error
 }
 
+type goalsMsg struct {
+	goals []api.Goal
+	err   error
+}
+
 // Scroll: ~43fps tick (matched to 43Hz display) with slow scroll for smooth kiosk viewing.
 const scrollLinesPerSec = 2.0
 const scrollInterval = 23 * time.Millisecond
@@ -105,6 +123,7 @@ func fetchAll(c *api.Client) []tea.Cmd {
 		fetchPnL(c),
 		fetchRecs(c),
 		fetchSecurities(c),
+		fetchGoals(c),
 	}
 }
 
@@ -148,6 +167,13 @@ func fetchSecurities(c *api.Client) tea.Cmd {
 	}
 }
 
+func fetchGoals(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		g, err := c.GoalProjection()
+		return goalsMsg{g, err}
+	}
+}
+
 func tickCmd() tea.Cmd {
 	return tea.Tick(scrollInterval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
@@ -30,6 +30,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.contentDirty = true
 
 	case tea.KeyPressMsg:
+		if m.inActions {
+			cmds = append(cmds, m.updateActions(msg))
+			break
+		}
+
+		if !m.inSettings && key.Matches(msg, keys.OpenActions) {
+			m.inActions = true
+			m.confirming = false
+			m.statusMsg = ""
+			m.refreshActions()
+			cmds = append(cmds, fetchJobs(m.client), fetchLEDMode(m.client))
+			break
+		}
+
 		if !m.inSettings && key.Matches(msg, keys.OpenSettings) {
 			m.inSettings = true
 			m.apiURLInput = m.apiURL
@@ -88,6 +102,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// reserved
 		}
 
+	case actionDoneMsg:
+		m.actionBusy = false
+		m.actionErr = msg.err != nil
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("%s failed: %v", msg.label, msg.err)
+		} else {
+			m.statusMsg = msg.label + " done"
+		}
+		// Reconcile optimistic updates with the server either way.
+		cmds = append(cmds, fetchAll(m.client)...)
+		cmds = append(cmds, fetchLEDMode(m.client))
+
+	case jobsMsg:
+		if msg.err == nil {
+			m.jobs = msg.jobs
+			m.refreshActions()
+		}
+
+	case ledModeMsg:
+		if msg.err == nil {
+			m.displayMode = msg.mode.Mode
+			m.refreshActions()
+		}
+
 	case refreshMsg:
 		cmds = append(cmds, fetchAll(m.client)...)
 		cmds = append(cmds, scheduleRefresh())
@@ -98,6 +136,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.connected = true
 			m.tradingMode = msg.health.TradingMode
+			if msg.health.TradingPaused != m.tradingPaused {
+				m.contentDirty = true
+			}
+			m.tradingPaused = msg.health.TradingPaused
+			m.pausedUntil = msg.health.TradingPausedUntilTS
+			if m.inActions && !m.confirming {
+				m.refreshActions()
+			}
 		}
 
 	case portfolioMsg:
@@ -120,6 +166,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err == nil {
 			m.recommendations = msg.recs
 			m.contentDirty = true
+			if m.inActions && !m.confirming {
+				m.refreshActions()
+			}
 		}
 
 	case securitiesMsg:
@@ -128,6 +177,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.contentDirty = true
 		}
 
+	case goalsMsg:
+		if msg.err == nil {
+			m.goals = msg.goals
+			m.contentDirty = true
+		}
+
 	case tickMsg:
 		if m.scrolling {
 			m.scrollAccum += scrollLinesPerSec * scrollInterval.Seconds()
@@ -149,7 +204,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.contentDirty = false
 		}
 		// Only forward non-tick messages to viewport (resize, scroll keys, etc.)
-		if _, isTick := msg.(tickMsg); !isTick && !m.inSettings {
+		if _, isTick := msg.(tickMsg); !isTick && !m.inSettings && !m.inActions {
 			var cmd tea.Cmd
 			m.viewport, cmd = m.viewport.Update(msg)
 			cmds = append(cmds, cmd)
@@ -158,3 +213,51 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	return m, tea.Batch(cmds...)
 }
+
+// updateActions handles keys while the actions menu is open.
+func (m *Model) updateActions(msg tea.KeyPressMsg) tea.Cmd {
+	if m.confirming {
+		switch {
+		case key.Matches(msg, keys.Confirm):
+			m.confirming = false
+			if m.actionCursor >= len(m.actionItems) {
+				return nil
+			}
+			item := m.actionItems[m.actionCursor]
+			if item.apply != nil {
+				item.apply(m)
+				m.contentDirty = true
+			}
+			m.actionBusy = true
+			m.actionErr = false
+			m.statusMsg = item.label + "..."
+			m.refreshActions()
+			return runAction(m.client, item)
+		case key.Matches(msg, keys.Cancel):
+			m.confirming = false
+		}
+		return nil
+	}
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return tea.Quit
+	case key.Matches(msg, keys.Back):
+		m.inActions = false
+		m.statusMsg = ""
+	case key.Matches(msg, keys.Up):
+		if m.actionCursor > 0 {
+			m.actionCursor--
+		}
+	case key.Matches(msg, keys.Down):
+		if m.actionCursor < len(m.actionItems)-1 {
+			m.actionCursor++
+		}
+	case key.Matches(msg, keys.Select):
+		// One write at a time: the result message reconciles state before the next.
+		if !m.actionBusy && m.actionCursor < len(m.actionItems) {
+			m.confirming = true
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"image/color"
 	"math"
 	"strings"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
@@ -22,6 +23,9 @@ func (m Model) View() tea.View {
 	if m.inSettings {
 		content = m.viewSettings()
 	}
+	if m.inActions {
+		content = m.viewActionMenu()
+	}
 	v := tea.NewView(content)
 	v.AltScreen = true
 	return v
@@ -71,6 +75,71 @@ func (m Model) viewSettings() string {
 		Render(strings.Join(body, "\n"))
 }
 
+func (m Model) viewActionMenu() string {
+	t := theme.Default
+
+	title := lipgloss.NewStyle().Foreground(t.Primary).Bold(true).Render("ACTIONS")
+	mode := strings.ToUpper(m.tradingMode)
+	if mode == "" {
+		mode = "UNKNOWN"
+	}
+	display := m.displayMode
+	if display == "" {
+		display = "unknown"
+	}
+	info := lipgloss.NewStyle().Foreground(t.Muted).
+		Render(fmt.Sprintf("Trading mode %s   Display %s", mode, display))
+	if m.tradingPaused {
+		info += lipgloss.NewStyle().Foreground(t.Error).Bold(true).
+			Render("   PAUSED " + pauseUntilText(m.pausedUntil))
+	}
+
+	body := []string{"", title, info, ""}
+
+	// Keep the cursor visible when the list is taller than the screen.
+	visible := max(1, m.height-12)
+	start := 0
+	if m.actionCursor >= visible {
+		start = m.actionCursor - visible + 1
+	}
+	end := min(len(m.actionItems), start+visible)
+	for i := start; i < end; i++ {
+		item := m.actionItems[i]
+		style := lipgloss.NewStyle().Foreground(t.Text)
+		prefix := "  "
+		if i == m.actionCursor {
+			style = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+			prefix = "> "
+		}
+		body = append(body, style.Render(prefix+item.label))
+	}
+
+	body = append(body, "")
+	if m.confirming && m.actionCursor < len(m.actionItems) {
+		prompt := m.actionItems[m.actionCursor].confirm + "  [y/N]"
+		body = append(body, lipgloss.NewStyle().Foreground(t.Warning).Bold(true).Render(prompt))
+	} else {
+		body = append(body, lipgloss.NewStyle().Foreground(t.Subtext).
+			Render("↑/↓ move   ENTER select   ESC back"))
+	}
+
+	if m.statusMsg != "" {
+		color := t.Success
+		if m.actionErr {
+			color = t.Error
+		} else if m.actionBusy {
+			color = t.Info
+		}
+		body = append(body, "", lipgloss.NewStyle().Foreground(color).Render(m.statusMsg))
+	}
+
+	return lipgloss.NewStyle().
+		Width(m.width).
+		Height(m.height).
+		Padding(1, 2).
+		Render(strings.Join(body, "\n"))
+}
+
 // contentWidth returns the usable content width after outer padding.
 func (m Model) contentWidth() int {
 	return m.width - 4
@@ -161,13 +230,54 @@ func (m Model) viewHero() string {
 		cashCol,
 	)
 
-	return lipgloss.JoinVertical(lipgloss.Left,
-		"",
-		valBlock,
-		"",
-		infoRow,
-		"",
-	)
+	rows := []string{""}
+	if banner := m.viewPauseBanner(); banner != "" {
+		rows = append(rows, banner, "")
+	}
+	rows = append(rows, valBlock, "", infoRow, "")
+	if goals := m.viewGoals(); goals != "" {
+		rows = append(rows, goals, "")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+// viewGoals lists investment goals with their odds, or "" without goals.
+func (m Model) viewGoals() string {
+	if len(m.goals) == 0 {
+		return ""
+	}
+	t := theme.Default
+	lines := make([]string, 0, len(m.goals))
+	for _, goal := range m.goals {
+		c := t.Success
+		status := "ON TRACK"
+		if !goal.OnTrack {
+			c, status = t.Warning, "BEHIND"
+		}
+		lines = append(lines, lipgloss.NewStyle().Foreground(c).Render(fmt.Sprintf(
+			"GOAL %s · %s by %s · %.0f%% likely · %s",
+			strings.ToUpper(goal.Name), formatWithSeparators(goal.TargetAmountEUR),
+			goal.TargetDate, goal.Probability*100, status)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// viewPauseBanner renders the trading-pause notice, or "" when trading runs.
+func (m Model) viewPauseBanner() string {
+	if !m.tradingPaused {
+		return ""
+	}
+	t := theme.Default
+	title := lipgloss.NewStyle().Foreground(t.Error).Bold(true).Render(bigtext.Render("TRADING PAUSED"))
+	return lipgloss.JoinVertical(lipgloss.Left, title,
+		lipgloss.NewStyle().Foreground(t.Warning).Render(pauseUntilText(m.pausedUntil)))
+}
+
+func pauseUntilText(until *int64) string {
+	if until == nil {
+		return "until resumed"
+	}
+	return "until " + time.Unix(*until, 0).Format("Mon 15:04")
 }
 
 func (m Model) viewActions() string {
@@ -273,7 +383,14 @@ func (m Model) viewCards() string {
 		expBar := expLabel + renderScoreBar(sec.ExpectedReturn, barWidth, t.Accent, t.Muted)
 
 		var cardLines []string
-		cardLines = append(cardLines, "", headerRow, nameBlock, "")
+		cardLines = append(cardLines, "", headerRow, nameBlock)
+		if excluded := exclusionText(sec.ExcludedBy); excluded != "" {
+			cardLines = append(cardLines, lipgloss.NewStyle().Foreground(t.Warning).Render(excluded))
+		}
+		if target := targetText(sec); target != "" {
+			cardLines = append(cardLines, lipgloss.NewStyle().Foreground(t.Muted).Render(target))
+		}
+		cardLines = append(cardLines, "")
 		if chartBlock != "" {
 			cardLines = append(cardLines, chartBlock, "")
 		}
@@ -290,6 +407,35 @@ func (m Model) viewCards() string {
 	return strings.Join(lines, "\n")
 }
 
+// exclusionText explains why a holding is excluded from buys, or "" when it is not.
+func exclusionText(exclusions []api.Exclusion) string {
+	if len(exclusions) == 0 {
+		return ""
+	}
+	parts := make([]string, len(exclusions))
+	for i, e := range exclusions {
+		parts[i] = fmt.Sprintf("%s (%s)", e.Name, e.Matched)
+		if e.Reason != "" {
+			parts[i] += ": " + e.Reason
+		}
+	}
+	return "EXCLUDED · " + strings.Join(parts, "; ")
+}
+
+// targetText shows a holding's position target and its drift, or "" without a target.
+func targetText(sec api.Security) string {
+	if sec.PositionTarget == nil {
+		return ""
+	}
+	target := sec.PositionTarget
+	label := target.Source
+	if target.Source == "manual" {
+		label = target.Mode
+	}
+	return fmt.Sprintf("TARGET %.1f%% (%s) · NOW %.1f%% · DRIFT %+.1fpp",
+		target.TargetPct, label, sec.CurrentAllocation, sec.CurrentAllocation-target.TargetPct)
+}
+
 // renderScoreBar renders a center-anchored horizontal bar for a score in [-1, 1].
 func renderScoreBar(score float64, width int, c, emptyColor color.Color) string {
 	fractionalBlocks := []rune{'▏', '▎', '▍', '▌', '▋', '▊', '▉', '█'}
@@ -2,8 +2,9 @@
 """
 Sentinel LED App — soroban abacus display for Arduino UNO Q.
 
-Fetches total portfolio value in EUR, sends as integer to MCU.
-MCU renders the value as soroban-style digits on an 8×5 NeoPixel shield.
+Fetches the batched display state (value, return, recs, broker, mode) and
+sends it to the MCU as a single versioned applyState call, skipping the call
+when the state version is unchanged. MCU renders the value as soroban-style digits on an 8×5 NeoPixel shield.
 """
 
 from __future__ import annotations
@@ -46,6 +47,9 @@ BRIDGE_RETRY_DELAY_SEC = _env_int("LED_BRIDGE_RETRY_DELAY_SEC", 1, minimum=0)
 MAX_CONSECUTIVE_FAILURES = _env_int("LED_MAX_CONSECUTIVE_FAILURES", 5)
 WATCHDOG_STALE_SEC = _env_int("LED_WATCHDOG_STALE_SEC", DEFAULT_HEARTBEAT_STALE_SEC)
 WATCHDOG_CHECK_INTERVAL_SEC = _env_int("LED_WATCHDOG_CHECK_INTERVAL_SEC", 30)
+API_RETRY_BASE_SEC = _env_int("LED_API_RETRY_BASE_SEC", 5)
+# Resend an unchanged state this often so the MCU heartbeat never goes stale.
+KEEPALIVE_SEC = min(_env_int("LED_KEEPALIVE_SEC", 300), DEFAULT_HEARTBEAT_STALE_SEC // 2)
 
 
 def _default_gateway_ip() -> str | None:
@@ -108,6 +112,14 @@ class BridgeRuntime:
     last_error: str | None = None
     consecutive_failures: int = 0
     last_payload: list[int] | None = None
+    last_sent_version: int | None = None
+    api_ok: bool = False
+    api_failures: int = 0
+    last_api_success_ts: int | None = None
+
+
+class ApiUnavailableError(RuntimeError):
+    """Sentinel API could not be reached; the MCU shows the offline fallback."""
 
 
 _runtime = BridgeRuntime(
@@ -127,6 +139,9 @@ def _report_bridge_health(bridge_ok: bool, watchdog_action: str | None = None) -
         "consecutive_failures": _runtime.consecutive_failures,
         "watchdog_action": watchdog_action,
         "app_instance": "arduino-app/sentinel",
+        "api_ok": _runtime.api_ok,
+        "api_failures": _runtime.api_failures,
+        "last_api_success_ts": _runtime.last_api_success_ts,
     }
     try:
         _post("/api/led/bridge/health", payload)
@@ -135,35 +150,12 @@ def _report_bridge_health(bridge_ok: bool, watchdog_action: str | None = None) -
 
 
 def _fetch_payload() -> tuple[list[int], dict[str, int]]:
-    """Fetch data from Sentinel API and build hm.u payload."""
-    portfolio = _fetch("/api/portfolio")
-    total_eur = portfolio.get("total_value_eur", 0)
-    value = max(0, min(99999999, round(total_eur)))
-
-    return_pct = max(-99, min(99, round(portfolio.get("portfolio_return_pct", 0))))
-
-    has_recs = 0
-    try:
-        planner = _fetch("/api/planner/recommendations")
-        recs = planner.get("recommendations", [])
-        has_recs = 1 if recs else 0
-    except Exception:  # noqa: BLE001, S110
-        pass  # Recommendations are optional; don't block the main update
-
-    broker_connected = 0
-    try:
-        health = _fetch("/api/health")
-        broker_connected = 1 if health.get("broker_connected") else 0
-    except Exception as e:  # noqa: BLE001
-        logger.warning("Failed to fetch broker health state: %s", e)
-
-    summary = {
-        "value": value,
-        "return_pct": return_pct,
-        "has_recs": has_recs,
-        "broker_connected": broker_connected,
-    }
-    return [value, return_pct, has_recs, broker_connected], summary
+    """Fetch the batched display state and its applyState frame from Sentinel."""
+    display = _fetch("/api/led/display-state")
+    frame = [int(x) for x in display["frame"]]
+    summary = {k: int(v) for k, v in display["state"].items()}
+    summary["version"] = int(display["version"])
+    return frame, summary
 
 
 def _force_restart(reason: str) -> None:
@@ -173,46 +165,92 @@ def _force_restart(reason: str) -> None:
     os._exit(1)
 
 
-def _push_once(source: str) -> None:
-    payload, summary = _fetch_payload()
-    _runtime.last_payload = payload
+def _bridge_call(method: str, payload: list[int]) -> None:
+    """Call an MCU method with retries, recording bridge success/failure."""
     _runtime.last_attempt_ts = int(time.time())
-    logger.info(
-        "Portfolio: EUR %d, P/L %d%%, recs=%d, broker_connected=%d, sending to MCU (%s)",
-        summary["value"],
-        summary["return_pct"],
-        summary["has_recs"],
-        summary["broker_connected"],
-        source,
-    )
-
     last_exc: Exception | None = None
     for attempt in range(1, BRIDGE_RETRIES + 1):
         try:
-            Bridge.call("hm.u", payload, timeout=BRIDGE_TIMEOUT_SEC)
+            Bridge.call(method, payload, timeout=BRIDGE_TIMEOUT_SEC)
             _runtime.last_success_ts = int(time.time())
             _runtime.consecutive_failures = 0
             _runtime.last_error = None
             _runtime.last_error_ts = None
-            logger.info("Bridge push success at %s", _ts_to_utc(_runtime.last_success_ts))
-            _report_bridge_health(bridge_ok=True)
             return
         except Exception as e:  # noqa: BLE001
             last_exc = e
-            logger.warning("Bridge push attempt %d/%d failed: %s", attempt, BRIDGE_RETRIES, e)
+            logger.warning("Bridge %s attempt %d/%d failed: %s", method, attempt, BRIDGE_RETRIES, e)
             if attempt < BRIDGE_RETRIES and BRIDGE_RETRY_DELAY_SEC > 0:
                 time.sleep(BRIDGE_RETRY_DELAY_SEC)
 
     _runtime.consecutive_failures += 1
     _runtime.last_error_ts = int(time.time())
     _runtime.last_error = str(last_exc) if last_exc is not None else "unknown bridge error"
-    _report_bridge_health(bridge_ok=False)
     raise RuntimeError(
-        f"Request 'hm.u' failed after {BRIDGE_RETRIES} attempts: {_runtime.last_error} "
+        f"Request '{method}' failed after {BRIDGE_RETRIES} attempts: {_runtime.last_error} "
         f"(consecutive_failures={_runtime.consecutive_failures})"
     )
 
 
+def _show_offline() -> None:
+    """Switch the MCU to its local "SENTINEL OFFLINE" scroll."""
+    # The MCU drops its applied version when going offline; force a resend on recovery.
+    _runtime.last_sent_version = None
+    try:
+        _bridge_call("hm.off", [])
+    except Exception as e:  # noqa: BLE001
+        logger.warning("Failed to show offline fallback: %s", e)
+
+
+def _api_retry_delay() -> int:
+    """Exponential backoff for API reconnects, capped at the refresh interval."""
+    exponent = max(0, min(_runtime.api_failures - 1, 10))
+    return min(REFRESH_INTERVAL_SEC, API_RETRY_BASE_SEC * 2**exponent)
+
+
+def _push_once(source: str) -> None:
+    try:
+        payload, summary = _fetch_payload()
+    except Exception as e:  # noqa: BLE001
+        _runtime.api_ok = False
+        _runtime.api_failures += 1
+        _show_offline()
+        raise ApiUnavailableError(str(e)) from e
+
+    _runtime.api_ok = True
+    _runtime.api_failures = 0
+    _runtime.last_api_success_ts = int(time.time())
+    _runtime.last_payload = payload
+
+    version = summary["version"]
+    now = int(time.time())
+    fresh = _runtime.last_success_ts is not None and now - _runtime.last_success_ts < KEEPALIVE_SEC
+    if version == _runtime.last_sent_version and fresh:
+        logger.debug("Display state v%d unchanged, skipping MCU send (%s)", version, source)
+        return
+
+    logger.info(
+        "Portfolio: EUR %d, P/L %d%%, recs=%d, broker_connected=%d, mode=%d, sending state v%d to MCU (%s)",
+        summary["value"],
+        summary["return_pct"],
+        summary["has_recs"],
+        summary["broker_connected"],
+        summary["mode"],
+        version,
+        source,
+    )
+
+    try:
+        _bridge_call("applyState", payload)
+    except Exception:
+        _runtime.last_sent_version = None
+        _report_bridge_health(bridge_ok=False)
+        raise
+    _runtime.last_sent_version = version
+    logger.info("Bridge push success at %s", _ts_to_utc(_runtime.last_success_ts))
+    _report_bridge_health(bridge_ok=True)
+
+
 def _watchdog_check() -> None:
     now = int(time.time())
 
@@ -225,26 +263,21 @@ def _watchdog_check() -> None:
     if stale_for < WATCHDOG_STALE_SEC:
         return
 
-    if _runtime.last_payload is None:
-        _force_restart("process_exit_stale_no_payload")
-
     logger.error(
         "Bridge stale for %ds (threshold=%ds). Executing watchdog ping.",
         stale_for,
         WATCHDOG_STALE_SEC,
     )
-    _runtime.last_attempt_ts = int(time.time())
+    # Without fresh API data, ping with the offline fallback instead of a stale value.
+    if _runtime.api_ok and _runtime.last_payload is not None:
+        method, payload = "applyState", _runtime.last_payload
+    else:
+        method, payload = "hm.off", []
     try:
-        Bridge.call("hm.u", _runtime.last_payload, timeout=BRIDGE_TIMEOUT_SEC)
-        _runtime.last_success_ts = int(time.time())
-        _runtime.consecutive_failures = 0
-        _runtime.last_error = None
-        _runtime.last_error_ts = None
+        _bridge_call(method, payload)
         logger.warning("Watchdog ping recovered bridge at %s", _ts_to_utc(_runtime.last_success_ts))
         _report_bridge_health(bridge_ok=True, watchdog_action="watchdog_recovered")
     except Exception as e:  # noqa: BLE001
-        _runtime.consecutive_failures += 1
-        _runtime.last_error_ts = int(time.time())
         _runtime.last_error = f"watchdog ping failed: {e}"
         _force_restart("process_exit_watchdog_ping_failed")
 
@@ -257,13 +290,17 @@ def _tick() -> None:
         _runtime.next_watchdog_at_ts = now + WATCHDOG_CHECK_INTERVAL_SEC
 
     if now >= _runtime.next_push_at_ts:
+        next_delay = REFRESH_INTERVAL_SEC
         try:
             _push_once("scheduled")
+        except ApiUnavailableError as e:
+            next_delay = _api_retry_delay()
+            logger.warning("Sentinel API unavailable (%s); showing offline fallback, retry in %ds", e, next_delay)
         except Exception as e:  # noqa: BLE001
             logger.warning("Heatmap push failed: %s", e)
             if _runtime.consecutive_failures >= MAX_CONSECUTIVE_FAILURES:
                 _force_restart("process_exit_consecutive_bridge_failures")
-        _runtime.next_push_at_ts = now + REFRESH_INTERVAL_SEC
+        _runtime.next_push_at_ts = now + next_delay
 
     time.sleep(1)
 
@@ -271,17 +308,24 @@ def _tick() -> None:
 def main() -> None:
     logger.info("Sentinel LED abacus app starting...")
     logger.info(
-        "Config: refresh=%ss retries=%s timeout=%ss stale=%ss watchdog=%ss max_failures=%s api=%s",
+        "Config: refresh=%ss retries=%s timeout=%ss stale=%ss watchdog=%ss max_failures=%s api_retry=%ss "
+        "keepalive=%ss api=%s",
         REFRESH_INTERVAL_SEC,
         BRIDGE_RETRIES,
         BRIDGE_TIMEOUT_SEC,
         WATCHDOG_STALE_SEC,
         WATCHDOG_CHECK_INTERVAL_SEC,
         MAX_CONSECUTIVE_FAILURES,
+        API_RETRY_BASE_SEC,
+        KEEPALIVE_SEC,
         SENTINEL_API_URL,
     )
     try:
         _push_once("startup")
+    except ApiUnavailableError as e:
+        # Not fatal: the tick loop keeps retrying with backoff while the MCU shows offline.
+        _runtime.next_push_at_ts = int(time.time()) + _api_retry_delay()
+        logger.warning("Sentinel API unavailable at startup: %s", e)
     except Exception as e:  # noqa: BLE001
         logger.warning("Initial push failed: %s", e)
     logger.info(
@@ -1,8 +1,9 @@
 // NeoPixel Shield (8x5) — soroban abacus portfolio value display.
 //
 // Shield is natively 8 wide x 5 tall, progressive (non-serpentine) wiring.
-// MPU sends Bridge.call("hm.u", [total_value_eur, return_pct, has_recs, broker_connected]).
-// MCU displays the value as soroban-style decimal digits:
+// MPU sends Bridge.call("hm.u", [total_value_eur, return_pct, has_recs, broker_connected, mode]).
+// mode (from the server-side display schedule): 0 ticker, 1 health, 2 stats.
+// Ticker mode displays the value as soroban-style decimal digits:
 //   Row 0 (top): heaven bead (orange, worth 5)
 //   Rows 1-4: earth bead position marker (amber, worth 1-4)
 //   Only the single position-indicator bead is lit per earth section.
@@ -10,6 +11,14 @@
 //   r0: broker connected (red blink, 200ms on / 1000ms off) — only when data is fresh
 //   r1-r3: P/L bar (green up / red down, 800ms blink)
 //   r4: recommendations (blue, 100ms on / 300ms off) — pending trades exist
+// Health mode: full-width rows — r0 broker (green ok / red down), r2 data
+//   freshness (green fresh / red stale), r4 recommendations (blue).
+// Stats mode: abacus shows |return_pct| in green (gain) or red (loss).
+// Offline fallback: MCU scrolls "SENTINEL OFFLINE" when the MPU reports the API
+//   is unreachable (Bridge.call("hm.off")) or no RPC arrived within the heartbeat
+//   window — rendered locally, so it works even if the MPU side is gone.
+// On-board LED3 reflects bridge connectivity: green = fresh data, yellow = API
+//   offline but bridge alive, red = no RPC within the heartbeat window.
 //
 // Device-only patches (not in this repo):
 // - bridge.h UPDATE_THREAD_STACK_SIZE changed from 500 to 8192
@@ -24,6 +33,7 @@
 #include <Adafruit_NeoPixel.h>
 
 #define PIN 6
+#define BUZZER_PIN 3  // active buzzer, HIGH = on
 #define NUMPIXELS 40
 #define BRIGHTNESS 3  // raw RGB value
 
@@ -75,7 +85,15 @@ static int displayValue = 0;
 static int displayPnl = 0;
 static int hasRecs = 0;
 static int brokerConnected = 0;
+static int displayMode = 0;
+static bool apiOffline = false;
 static bool needsRedraw = false;
+// Version of the last applyState frame; -1 forces the next frame to apply.
+static int appliedVersion = -1;
+
+#define MODE_TICKER 0
+#define MODE_HEALTH 1
+#define MODE_STATS  2
 
 // Last successful RPC timestamp (millis).
 static unsigned long lastRpcMs = 0;
@@ -87,7 +105,140 @@ static bool pnlBlinkOn = true;
 static bool heartbeatOn = false;
 static bool recBlinkOn = false;
 
+// --- Offline fallback (3x5 font, columns LSB = top row) ---
+
+#define SCROLL_STEP_MS 150
+#define OFFLINE_MAX_COLS 80
+
+static uint8_t offlineCols[OFFLINE_MAX_COLS];
+static int offlineLen = 0;
+
+static const uint8_t *glyphFor(char c) {
+  static const uint8_t S[3] = {0x17, 0x15, 0x1D};
+  static const uint8_t E[3] = {0x1F, 0x15, 0x15};
+  static const uint8_t N[3] = {0x1F, 0x06, 0x1F};
+  static const uint8_t T[3] = {0x01, 0x1F, 0x01};
+  static const uint8_t I[3] = {0x11, 0x1F, 0x11};
+  static const uint8_t L[3] = {0x1F, 0x10, 0x10};
+  static const uint8_t O[3] = {0x1F, 0x11, 0x1F};
+  static const uint8_t F[3] = {0x1F, 0x05, 0x05};
+  static const uint8_t SPACE[3] = {0x00, 0x00, 0x00};
+  switch (c) {
+    case 'S': return S;
+    case 'E': return E;
+    case 'N': return N;
+    case 'T': return T;
+    case 'I': return I;
+    case 'L': return L;
+    case 'O': return O;
+    case 'F': return F;
+    default: return SPACE;
+  }
+}
+
+static void buildOfflineText(const char *text) {
+  offlineLen = 0;
+  // Lead-in so the text scrolls in from the right edge.
+  for (int i = 0; i < 8 && offlineLen < OFFLINE_MAX_COLS; i++) offlineCols[offlineLen++] = 0;
+  for (const char *c = text; *c && offlineLen + 4 <= OFFLINE_MAX_COLS; c++) {
+    const uint8_t *g = glyphFor(*c);
+    for (int i = 0; i < 3; i++) offlineCols[offlineLen++] = g[i];
+    offlineCols[offlineLen++] = 0;
+  }
+}
+
+static bool isOffline() {
+  return apiOffline || lastRpcMs == 0 || (millis() - lastRpcMs >= HEARTBEAT_TIMEOUT_MS);
+}
+
+static void renderOffline() {
+  pixels.clear();
+  if (offlineLen > 0) {
+    int offset = (millis() / SCROLL_STEP_MS) % offlineLen;
+    for (int x = 0; x < 8; x++) {
+      uint8_t col = offlineCols[(offset + x) % offlineLen];
+      for (int row = 0; row < 5; row++) {
+        if (col & (1 << row)) {
+          pixels.setPixelColor(row * 8 + x, pixels.Color(BRIGHTNESS, BRIGHTNESS / 3, 0));
+        }
+      }
+    }
+  }
+  ws2812_show(pixels);
+}
+
+// --- On-board LED3 (active-low RGB) ---
+
+static void setLed3(bool r, bool g, bool b) {
+  digitalWrite(LED3_R, r ? LOW : HIGH);
+  digitalWrite(LED3_G, g ? LOW : HIGH);
+  digitalWrite(LED3_B, b ? LOW : HIGH);
+}
+
+static void updateLed3() {
+  bool stale = lastRpcMs == 0 || (millis() - lastRpcMs >= HEARTBEAT_TIMEOUT_MS);
+  if (stale) {
+    setLed3(true, false, false);
+  } else if (apiOffline) {
+    setLed3(true, true, false);
+  } else {
+    setLed3(false, true, false);
+  }
+}
+
+static void renderHealth() {
+  pixels.clear();
+  bool dataFresh = (millis() - lastRpcMs < HEARTBEAT_TIMEOUT_MS);
+  for (int col = 0; col < 8; col++) {
+    if (brokerConnected > 0 && dataFresh) {
+      pixels.setPixelColor(col, pixels.Color(0, BRIGHTNESS, 0));
+    } else {
+      pixels.setPixelColor(col, pixels.Color(BRIGHTNESS, 0, 0));
+    }
+    if (dataFresh) {
+      pixels.setPixelColor(2 * 8 + col, pixels.Color(0, BRIGHTNESS, 0));
+    } else {
+      pixels.setPixelColor(2 * 8 + col, pixels.Color(BRIGHTNESS, 0, 0));
+    }
+    if (hasRecs > 0 && recBlinkOn) {
+      pixels.setPixelColor(4 * 8 + col, pixels.Color(0, 0, BRIGHTNESS));
+    }
+  }
+  ws2812_show(pixels);
+}
+
+static void renderStats() {
+  pixels.clear();
+  int pnl = displayPnl < 0 ? -displayPnl : displayPnl;
+  uint32_t color = displayPnl < 0 ? pixels.Color(BRIGHTNESS, 0, 0) : pixels.Color(0, BRIGHTNESS, 0);
+
+  // Two digits on columns 6-7, same bead layout as the value abacus.
+  uint8_t digits[2] = {(uint8_t)(pnl / 10), (uint8_t)(pnl % 10)};
+  for (int i = 0; i < 2; i++) {
+    int col = 6 + i;
+    uint8_t d = digits[i];
+    if (d >= 5) pixels.setPixelColor(col, color);
+    uint8_t earth = d % 5;
+    if (earth > 0) pixels.setPixelColor((5 - earth) * 8 + col, color);
+  }
+  ws2812_show(pixels);
+}
+
 static void renderDisplay() {
+  updateLed3();
+  if (isOffline()) {
+    renderOffline();
+    return;
+  }
+  if (displayMode == MODE_HEALTH) {
+    renderHealth();
+    return;
+  }
+  if (displayMode == MODE_STATS) {
+    renderStats();
+    return;
+  }
+
   pixels.clear();
 
   int val = displayValue;
@@ -151,28 +302,125 @@ static void renderDisplay() {
   ws2812_show(pixels);
 }
 
-// --- RPC handler ---
-static void hmUpdate(MsgPack::arr_t<int> data) {
-  if ((int)data.size() < 1) return;
-  int val = data[0];
+// --- Buzzer ---
+// Patterns are {on_ms, off_ms} steps, indexed by the alert code from the MPU.
+#define ALERT_TRADE_EXECUTED 1
+#define ALERT_CRITICAL       2
+#define MAX_BEEP_STEPS 3
+
+struct BeepPattern {
+  int steps;
+  uint16_t onMs[MAX_BEEP_STEPS];
+  uint16_t offMs[MAX_BEEP_STEPS];
+};
+
+static const BeepPattern BEEP_PATTERNS[] = {
+  {0, {0}, {0}},                        // 0: none
+  {2, {80, 80, 0}, {80, 0, 0}},         // trade executed: two short
+  {3, {600, 600, 600}, {250, 250, 0}},  // critical: three long
+};
+
+static int lastAlertSeq = -1;
+static int beepPattern = 0;
+static int beepStep = 0;
+static bool beepOn = false;
+static unsigned long beepStepAt = 0;
+
+static void startBeep(int code) {
+  if (code <= 0 || code > ALERT_CRITICAL) return;
+  beepPattern = code;
+  beepStep = 0;
+  beepOn = true;
+  beepStepAt = millis();
+  digitalWrite(BUZZER_PIN, HIGH);
+}
+
+// Advance the active pattern without blocking the display loop.
+static void updateBeep(unsigned long now) {
+  if (beepPattern == 0) return;
+  const BeepPattern &p = BEEP_PATTERNS[beepPattern];
+  unsigned long dur = beepOn ? p.onMs[beepStep] : p.offMs[beepStep];
+  if (now - beepStepAt < dur) return;
+  beepStepAt = now;
+  if (beepOn) {
+    beepOn = false;
+    digitalWrite(BUZZER_PIN, LOW);
+    return;
+  }
+  beepStep++;
+  if (beepStep >= p.steps) {
+    beepPattern = 0;
+    return;
+  }
+  beepOn = true;
+  digitalWrite(BUZZER_PIN, HIGH);
+}
+
+// --- RPC handlers ---
+// Apply display fields starting at data[offset]:
+// [value, pnl, has_recs, broker_connected, mode, alert_seq, alert]
+static void applyFields(MsgPack::arr_t<int> &data, int offset) {
+  int n = (int)data.size() - offset;
+  if (n < 1) return;
+  int val = data[offset];
   if (val < 0) val = 0;
   if (val > 99999999) val = 99999999;
   displayValue = val;
 
-  if ((int)data.size() >= 2) {
-    displayPnl = data[1];
+  if (n >= 2) {
+    displayPnl = data[offset + 1];
     if (displayPnl < -99) displayPnl = -99;
     if (displayPnl >  99) displayPnl =  99;
   }
 
-  if ((int)data.size() >= 3) {
-    hasRecs = data[2];
+  if (n >= 3) {
+    hasRecs = data[offset + 2];
   }
 
-  if ((int)data.size() >= 4) {
-    brokerConnected = data[3] > 0 ? 1 : 0;
+  if (n >= 4) {
+    brokerConnected = data[offset + 3] > 0 ? 1 : 0;
   }
 
+  if (n >= 5) {
+    int mode = data[offset + 4];
+    displayMode = (mode >= MODE_TICKER && mode <= MODE_STATS) ? mode : MODE_TICKER;
+  }
+
+  // Play each alert sequence once; a code of 0 means it expired undelivered.
+  if (n >= 7 && data[offset + 5] != lastAlertSeq) {
+    lastAlertSeq = data[offset + 5];
+    startBeep(data[offset + 6]);
+  }
+
+  apiOffline = false;
+  lastRpcMs = millis();
+  needsRedraw = true;
+}
+
+// Legacy per-field update (pre-applyState MPU apps).
+static void hmUpdate(MsgPack::arr_t<int> data) {
+  appliedVersion = -1;
+  applyFields(data, 0);
+}
+
+// Batched full display state: [version, value, pnl, has_recs, broker_connected, mode, alert_seq, alert].
+// A repeated version is a keepalive: refresh the heartbeat without redrawing.
+static void applyState(MsgPack::arr_t<int> data) {
+  if ((int)data.size() < 2) return;
+  int version = data[0];
+  if (version == appliedVersion && !apiOffline) {
+    lastRpcMs = millis();
+    return;
+  }
+  applyFields(data, 1);
+  appliedVersion = version;
+}
+
+// MPU could reach the MCU but not the Sentinel API.
+static void hmOffline(MsgPack::arr_t<int> data) {
+  (void)data;
+  apiOffline = true;
+  appliedVersion = -1;
   lastRpcMs = millis();
   needsRedraw = true;
 }
@@ -182,14 +430,25 @@ void setup() {
   pixels.clear();
   ws2812_show(pixels);
 
+  pinMode(LED3_R, OUTPUT);
+  pinMode(LED3_G, OUTPUT);
+  pinMode(LED3_B, OUTPUT);
+  pinMode(BUZZER_PIN, OUTPUT);
+  digitalWrite(BUZZER_PIN, LOW);
+  buildOfflineText("SENTINEL OFFLINE");
+  updateLed3();
+
   Bridge.begin();
   Bridge.provide("hm.u", hmUpdate);
+  Bridge.provide("applyState", applyState);
+  Bridge.provide("hm.off", hmOffline);
 }
 
 void loop() {
   Bridge.update();
 
   unsigned long now = millis();
+  updateBeep(now);
 
   // Compute blink states from time (avoids per-feature timers).
   bool newPnlBlink = (now % 1600) < 800;
@@ -197,6 +456,7 @@ void loop() {
   bool newRecBlink  = (now % 400) < 100;
   bool newDataFresh = (now - lastRpcMs < HEARTBEAT_TIMEOUT_MS);
   static bool dataFresh = false;
+  static unsigned long lastScrollStep = 0;
 
   // Redraw only when a visible blink state changes.
   bool changed = false;
@@ -204,6 +464,10 @@ void loop() {
   if (newHeartbeat != heartbeatOn && newDataFresh && brokerConnected > 0) changed = true;
   if (newDataFresh != dataFresh) changed = true;
   if (newRecBlink != recBlinkOn && hasRecs > 0) changed = true;
+  if (isOffline() && now / SCROLL_STEP_MS != lastScrollStep) {
+    lastScrollStep = now / SCROLL_STEP_MS;
+    changed = true;
+  }
 
   pnlBlinkOn = newPnlBlink;
   heartbeatOn = newHeartbeat;
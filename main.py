@@ -3,17 +3,29 @@
 Sentinel - Entry point for running the application.
 
 Usage:
-    python main.py          # Run web server only
-    python main.py --all    # Run web server + scheduler
+    python main.py                  # Run web server only
+    python main.py --all            # Run web server + scheduler
+    python main.py --migrate-check  # List pending schema migrations (JSON)
+    python main.py --migrate        # Back up the database and apply them (JSON)
+    python main.py --migrate --dry-run     # List what --migrate would apply (JSON)
+    python main.py --migrate-status # Schema version and numbered migrations (JSON)
+    python main.py --migrate-down VERSION  # Back up and revert numbered migrations above VERSION (JSON)
+    python main.py --restore-archive FILE  # Insert an archive file's rows back (JSON)
+    python main.py --backfill-history [--since DATE]  # Import broker trade and cash flow history (JSON)
+    python main.py --encrypt-db     # Encrypt the database with SENTINEL_DB_PASSPHRASE (JSON)
+    python main.py --decrypt-db     # Decrypt it back to plain SQLite (JSON)
 """
 
 import argparse
 import asyncio
+import json
 import logging
+import sys
 
 import uvicorn
 
 from sentinel import Broker, Database, Settings
+from sentinel.systemd import RESTART_EXIT_CODE, restart_requested
 
 logging.basicConfig(level=logging.INFO, format="%(asctime)s - %(name)s - %(levelname)s - %(message)s")
 logger = logging.getLogger(__name__)
@@ -45,14 +57,123 @@ async def run_scheduler():
     raise NotImplementedError("Use --all flag to run scheduler with web server")
 
 
+def migrate(check_only: bool, dry_run: bool = False, down_to: int | None = None) -> int:
+    """Deploy-time schema gate; see sentinel.database.migrations."""
+    from sentinel.database.migrations import apply_migrations, pending_migrations, rollback_migrations
+    from sentinel.paths import DATA_DIR
+
+    path = Database()._path
+    if check_only:
+        print(json.dumps({"database": str(path), "pending": pending_migrations(path)}))
+        return 0
+    try:
+        if down_to is not None:
+            result = rollback_migrations(path, DATA_DIR / "backups", down_to, dry_run=dry_run)
+        else:
+            result = asyncio.run(apply_migrations(path, DATA_DIR / "backups", dry_run=dry_run))
+    except Exception as e:  # noqa: BLE001
+        print(json.dumps({"database": str(path), "error": str(e)}))
+        return 1
+    print(json.dumps({"database": str(path), **result}))
+    return 0
+
+
+def restore(file: str) -> int:
+    """Insert the rows of a retention archive file back; see sentinel.archive."""
+    from pathlib import Path
+
+    from sentinel.archive import restore_archive
+
+    async def run() -> dict:
+        db = Database()
+        await db.connect()
+        try:
+            return await restore_archive(db, Path(file))
+        finally:
+            await db.close()
+
+    try:
+        result = asyncio.run(run())
+    except Exception as e:  # noqa: BLE001
+        print(json.dumps({"file": file, "error": str(e)}))
+        return 1
+    print(json.dumps(result))
+    return 0
+
+
+def backfill_history(since: str | None) -> int:
+    """Import the broker's full trade and cash flow history; see sentinel.history_backfill."""
+    from sentinel.history_backfill import BACKFILL_SINCE, HistoryBackfill
+
+    async def run() -> dict:
+        db = Database()
+        await db.connect()
+        try:
+            broker = Broker()
+            await broker.connect()
+            return await HistoryBackfill(db, broker).run(since or BACKFILL_SINCE)
+        finally:
+            await db.close()
+
+    try:
+        result = asyncio.run(run())
+    except Exception as e:  # noqa: BLE001
+        print(json.dumps({"error": str(e)}))
+        return 1
+    print(json.dumps(result))
+    return 0
+
+
+def convert(encrypt: bool) -> int:
+    """Encrypt or decrypt the database in place; see sentinel.database.encryption."""
+    from sentinel.database.encryption import decrypt_database, encrypt_database
+
+    path = Database()._path
+    try:
+        result = encrypt_database(path) if encrypt else decrypt_database(path)
+    except Exception as e:  # noqa: BLE001
+        print(json.dumps({"database": str(path), "error": str(e)}))
+        return 1
+    print(json.dumps(result))
+    return 0
+
+
 def main():
     parser = argparse.ArgumentParser(description="Sentinel Portfolio Management")
     parser.add_argument("--all", action="store_true", help="Run scheduler alongside web server")
     parser.add_argument("--scheduler-only", action="store_true", help="Run scheduler only (no web server)")
     parser.add_argument("--host", default="::", help="Web server host")
     parser.add_argument("--port", type=int, default=8000, help="Web server port")
+    parser.add_argument("--migrate-check", action="store_true", help="List pending schema migrations and exit")
+    parser.add_argument("--migrate", action="store_true", help="Back up the database, apply migrations and exit")
+    parser.add_argument("--migrate-status", action="store_true", help="Print the schema version and migrations, exit")
+    parser.add_argument(
+        "--migrate-down", type=int, metavar="VERSION", help="Back up, revert numbered migrations above VERSION, exit"
+    )
+    parser.add_argument("--dry-run", action="store_true", help="With --migrate or --migrate-down: only report")
+    parser.add_argument("--restore-archive", metavar="FILE", help="Insert a retention archive file's rows back and exit")
+    parser.add_argument(
+        "--backfill-history", action="store_true", help="Import the broker's trade and cash flow history and exit"
+    )
+    parser.add_argument("--since", metavar="DATE", help="With --backfill-history: start date (YYYY-MM-DD)")
+    parser.add_argument("--encrypt-db", action="store_true", help="Encrypt the database with SENTINEL_DB_PASSPHRASE")
+    parser.add_argument("--decrypt-db", action="store_true", help="Decrypt the database back to plain SQLite")
     args = parser.parse_args()
 
+    if args.migrate_status:
+        from sentinel.database.migrations import migration_status
+
+        print(json.dumps(migration_status(Database()._path)))
+        sys.exit(0)
+    if args.migrate_check or args.migrate or args.migrate_down is not None:
+        sys.exit(migrate(check_only=args.migrate_check, dry_run=args.dry_run, down_to=args.migrate_down))
+    if args.restore_archive:
+        sys.exit(restore(args.restore_archive))
+    if args.backfill_history:
+        sys.exit(backfill_history(args.since))
+    if args.encrypt_db or args.decrypt_db:
+        sys.exit(convert(encrypt=args.encrypt_db))
+
     # Do not run init_services() here when starting the web server: uvicorn uses a
     # different event loop, so a DB connection created here would be invalid in
     # request handlers. The app's lifespan (sentinel.app) connects the DB in the
@@ -77,6 +198,10 @@ def main():
         logger.info(f"Running web server on {args.host}:{args.port}")
         uvicorn.run("sentinel.app:app", host=args.host, port=args.port, log_level="info")
 
+    if restart_requested():
+        logger.info("Exiting for restart")
+        sys.exit(RESTART_EXIT_CODE)
+
 
 if __name__ == "__main__":
     main()
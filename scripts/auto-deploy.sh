@@ -1,7 +1,24 @@
 #!/bin/bash
 # Auto-deploy script for Sentinel.
-# Polls git for new commits on main, pulls, updates deps if needed, restarts.
+# Polls git for a new release, checks it out, updates deps if needed, restarts.
 # Designed to run via systemd timer on the target device.
+#
+# The running API decides the deploy policy (GET /api/deployments/policy):
+# the "beta" channel tracks main, "stable" the newest v* release tag, and a
+# pending update waits until every market the universe trades on is closed
+# and the local deploy window (if set) is open. The last policy is cached so
+# the channel is known while the service is down; a down service is updated
+# without waiting for the window.
+#
+# Before the restart, the new code lists its pending schema migrations and
+# applies them after backing up the database, while the old version keeps
+# serving; a failed migration restores the backup and the deploy is rolled
+# back without a restart. Every deploy ends with a canary phase: the API is
+# health-checked and smoke-tested for CANARY_MINUTES. If that fails, the
+# numbered migrations the deploy applied are reverted, the previous commit (still
+# in the local git history) is checked out and reinstalled, and the failed
+# commit is skipped until a newer one lands. Outcomes are appended to
+# deploy-history.jsonl in the data directory (GET /api/deployments).
 
 set -euo pipefail
 
@@ -14,6 +31,17 @@ LOG_FILE="$LOG_DIR/auto-deploy.log"
 MAX_LOG_SIZE=$((10 * 1024 * 1024))
 MAX_LOG_FILES=3
 BRANCH="main"
+DATA_DIR="${SENTINEL_DATA_DIR:-$REPO_DIR/data}"
+DEPLOY_HISTORY="$DATA_DIR/deploy-history.jsonl"
+FAILED_COMMIT_FILE="$DATA_DIR/deploy-failed-commit"
+POLICY_FILE="$DATA_DIR/deploy-policy.json"
+WAITING_FILE="$DATA_DIR/deploy-waiting"
+API_URL="http://127.0.0.1:8000/api"
+CANARY_MINUTES="${SENTINEL_CANARY_MINUTES:-3}"
+CANARY_INTERVAL=15
+# A restart may take this long before the API answers at all.
+STARTUP_GRACE=120
+SMOKE_ENDPOINTS=("/readyz" "/health" "/version" "/settings" "/portfolio" "/jobs")
 
 # SSH multiplexing to prevent connection exhaustion
 # Uses a control socket that auto-closes after 30s idle
@@ -49,7 +77,121 @@ rotate_logs() {
     mv "$LOG_FILE" "$LOG_FILE.1"
 }
 
-mkdir -p "$LOG_DIR" "$SSH_CONTROL_DIR"
+# Installs the checked-out commit: deps, systemd units and the LED app when
+# they differ from the commit being replaced.
+install_release() {
+    local from="$1" to="$2"
+
+    # The forecasting service is optional and carries heavy model dependencies,
+    # so only install its extra when that systemd unit is already enabled or
+    # running on the device.
+    if ! git diff --quiet "$from" "$to" -- pyproject.toml; then
+        log "pyproject.toml changed, updating dependencies..."
+        if systemctl is-enabled --quiet sentinel-forecasting 2>/dev/null || systemctl is-active --quiet sentinel-forecasting 2>/dev/null; then
+            "$VENV_DIR/bin/pip" install '.[forecasting]' --quiet
+        else
+            "$VENV_DIR/bin/pip" install . --quiet
+        fi
+        log "Dependencies updated"
+    fi
+
+    # Update systemd units if changed
+    local units_changed=false
+    for unit in sentinel.service sentinel-forecasting.service sentinel-deploy.service sentinel-deploy.timer; do
+        if ! diff -q "$REPO_DIR/systemd/$unit" "/etc/systemd/system/$unit" &>/dev/null; then
+            sudo cp "$REPO_DIR/systemd/$unit" "/etc/systemd/system/$unit"
+            units_changed=true
+            log "Updated $unit"
+        fi
+    done
+    if [ "$units_changed" = true ]; then
+        sudo systemctl daemon-reload
+        log "Systemd daemon reloaded"
+    fi
+
+    # Update LED app if changed
+    if git diff --name-only "$from" "$to" -- arduino-app/sentinel/ | grep -q .; then
+        log "LED app changed, updating..."
+        mkdir -p "$LED_APP_DEST"
+        rm -rf "$LED_APP_DEST/python" "$LED_APP_DEST/sketch"
+        cp "$LED_APP_SRC/app.yaml" "$LED_APP_DEST/"
+        cp -R "$LED_APP_SRC/python" "$LED_APP_DEST/"
+        cp -R "$LED_APP_SRC/sketch" "$LED_APP_DEST/"
+        # On-device, the running app id shows up as "user:sentinel".
+        # Stop by id first (most reliable), then fall back to the short name.
+        arduino-app-cli app stop user:sentinel 2>/dev/null || arduino-app-cli app stop sentinel 2>/dev/null || true
+        cd "$LED_APP_DEST" && arduino-app-cli app start .
+        cd "$REPO_DIR"
+        log "LED app updated and restarted"
+    fi
+}
+
+restart_services() {
+    log "Restarting sentinel..."
+    # Blocks until the app reports READY=1; a failed start is left to the canary.
+    sudo systemctl restart sentinel || log "sentinel did not start cleanly"
+    if systemctl is-active --quiet sentinel-forecasting 2>/dev/null; then
+        log "Restarting sentinel-forecasting..."
+        sudo systemctl restart sentinel-forecasting
+    fi
+}
+
+# Health-checks and smoke-tests the running API for CANARY_MINUTES.
+# Sets CANARY_ERROR and returns 1 on the first failure after startup.
+canary() {
+    local started deadline now healthy=false endpoint
+    started=$(date +%s)
+    deadline=$((started + CANARY_MINUTES * 60))
+    CANARY_ERROR=""
+    while true; do
+        sleep "$CANARY_INTERVAL"
+        now=$(date +%s)
+        CANARY_ERROR=""
+        if ! systemctl is-active --quiet sentinel; then
+            CANARY_ERROR="sentinel service is not running"
+        else
+            for endpoint in "${SMOKE_ENDPOINTS[@]}"; do
+                if ! curl -fsS --max-time 30 -o /dev/null "$API_URL$endpoint" 2>/dev/null; then
+                    CANARY_ERROR="GET /api$endpoint failed"
+                    break
+                fi
+            done
+        fi
+        if [ -n "$CANARY_ERROR" ]; then
+            # Failures are expected while the app is still starting up.
+            if [ "$healthy" = false ] && [ "$now" -lt $((started + STARTUP_GRACE)) ]; then
+                continue
+            fi
+            return 1
+        fi
+        healthy=true
+        [ "$now" -ge "$deadline" ] && return 0
+    done
+}
+
+# Appends one deploy outcome to the history read by GET /api/deployments.
+record_deploy() {
+    local status="$1" from="$2" to="$3" detail="$4"
+    "$VENV_DIR/bin/python" -c 'import json, sys, time
+print(json.dumps({"ts": int(time.time()), "status": sys.argv[1], "from_commit": sys.argv[2],
+                  "to_commit": sys.argv[3], "detail": sys.argv[4] or None, "canary_minutes": int(sys.argv[5]),
+                  "channel": sys.argv[6]}))' \
+        "$status" "$from" "$to" "$detail" "$CANARY_MINUTES" "$CHANNEL" >> "$DEPLOY_HISTORY"
+}
+
+# Prints one field of a JSON object; null prints nothing.
+json_field() {
+    "$VENV_DIR/bin/python" -c 'import json, sys
+value = json.loads(sys.argv[1]).get(sys.argv[2])
+print("" if value is None else str(value).lower() if isinstance(value, bool) else value)' "$1" "$2"
+}
+
+# Prints one field of the deploy policy JSON; null prints nothing.
+policy_field() {
+    json_field "$POLICY" "$1"
+}
+
+mkdir -p "$LOG_DIR" "$SSH_CONTROL_DIR" "$DATA_DIR"
 chmod 700 "$SSH_CONTROL_DIR"
 rotate_logs
 cd "$REPO_DIR"
@@ -67,71 +209,96 @@ if [ ! -d "$VENV_DIR" ]; then
     log "Virtual environment created and dependencies installed"
 fi
 
+API_UP=false
+if POLICY=$(curl -fsS --max-time 30 "$API_URL/deployments/policy" 2>/dev/null); then
+    API_UP=true
+    echo "$POLICY" > "$POLICY_FILE"
+elif [ -f "$POLICY_FILE" ]; then
+    POLICY=$(cat "$POLICY_FILE")
+else
+    POLICY='{"channel": "beta"}'
+fi
+CHANNEL=$(policy_field channel)
+
 # Fetch and compare
 git fetch origin "$BRANCH" --quiet
 
 LOCAL=$(git rev-parse HEAD)
-REMOTE=$(git rev-parse "origin/$BRANCH")
+if [ "$CHANNEL" = "stable" ]; then
+    git fetch origin --tags --force --quiet
+    RELEASE=$(git tag -l 'v*' --sort=-v:refname | head -n 1)
+    [ -z "$RELEASE" ] && exit 0
+    REMOTE=$(git rev-parse "$RELEASE^{commit}")
+    # After switching from beta, stay on the newer main commit until a
+    # release passes it.
+    git merge-base --is-ancestor "$REMOTE" "$LOCAL" && exit 0
+else
+    REMOTE=$(git rev-parse "origin/$BRANCH")
+fi
 
 [ "$LOCAL" = "$REMOTE" ] && exit 0
+# A commit that failed its canary stays rolled back until a newer one lands.
+[ -f "$FAILED_COMMIT_FILE" ] && [ "$(cat "$FAILED_COMMIT_FILE")" = "$REMOTE" ] && exit 0
+
+if [ "$API_UP" = true ] && [ "$(policy_field allowed)" != "true" ]; then
+    # Log once per pending commit and reason, not on every timer tick.
+    WAITING="${REMOTE:0:7} ($(policy_field reason))"
+    if [ ! -f "$WAITING_FILE" ] || [ "$(cat "$WAITING_FILE")" != "$WAITING" ]; then
+        echo "$WAITING" > "$WAITING_FILE"
+        log "Holding back $WAITING"
+    fi
+    exit 0
+fi
+rm -f "$WAITING_FILE"
+[ "$API_UP" = false ] && log "API not answering; deploying without waiting for the deploy window"
 
-log "New commits: ${LOCAL:0:7} -> ${REMOTE:0:7}"
+log "New commits ($CHANNEL): ${LOCAL:0:7} -> ${REMOTE:0:7}"
 
-# Snapshot dependency file before pull
-DEPS_HASH_BEFORE=$(md5sum pyproject.toml | cut -d' ' -f1)
+git reset --quiet --hard "$REMOTE"
+log "Checked out ${RELEASE:-origin/$BRANCH}"
 
-git pull origin "$BRANCH" --quiet
-log "Pulled latest changes"
+install_release "$LOCAL" "$REMOTE"
 
-# Update deps if pyproject.toml changed. The forecasting service is optional
-# and carries heavy model dependencies, so only install its extra when that
-# systemd unit is already enabled or running on the device.
-DEPS_HASH_AFTER=$(md5sum pyproject.toml | cut -d' ' -f1)
-if [ "$DEPS_HASH_BEFORE" != "$DEPS_HASH_AFTER" ]; then
-    log "pyproject.toml changed, updating dependencies..."
-    if systemctl is-enabled --quiet sentinel-forecasting 2>/dev/null || systemctl is-active --quiet sentinel-forecasting 2>/dev/null; then
-        "$VENV_DIR/bin/pip" install '.[forecasting]' --quiet
-    else
-        "$VENV_DIR/bin/pip" install . --quiet
-    fi
-    log "Dependencies updated"
+# Migration gate. Logs from main.py go to the deploy log; stdout is JSON.
+log "Migration check: $("$VENV_DIR/bin/python" main.py --migrate-check 2>>"$LOG_FILE")"
+if ! MIGRATION=$("$VENV_DIR/bin/python" main.py --migrate 2>>"$LOG_FILE"); then
+    log "Migration failed: $MIGRATION. Rolling back to ${LOCAL:0:7} without restarting..."
+    echo "$REMOTE" > "$FAILED_COMMIT_FILE"
+    git reset --quiet --hard "$LOCAL"
+    install_release "$REMOTE" "$LOCAL"
+    record_deploy "migration_failed" "$LOCAL" "$REMOTE" "$MIGRATION"
+    exit 1
 fi
+log "Migrations: $MIGRATION"
+restart_services
 
-# Update systemd units if changed
-UNITS_CHANGED=false
-for unit in sentinel.service sentinel-forecasting.service sentinel-deploy.service sentinel-deploy.timer; do
-    if ! diff -q "$REPO_DIR/systemd/$unit" "/etc/systemd/system/$unit" &>/dev/null; then
-        sudo cp "$REPO_DIR/systemd/$unit" "/etc/systemd/system/$unit"
-        UNITS_CHANGED=true
-        log "Updated $unit"
-    fi
-done
-if [ "$UNITS_CHANGED" = true ]; then
-    sudo systemctl daemon-reload
-    log "Systemd daemon reloaded"
+log "Canary: checking the API for ${CANARY_MINUTES}m..."
+if canary; then
+    rm -f "$FAILED_COMMIT_FILE"
+    record_deploy "success" "$LOCAL" "$REMOTE" ""
+    log "Deploy complete ($(git rev-parse --short HEAD))"
+    exit 0
 fi
 
-# Update LED app if changed
-if git diff --name-only "$LOCAL" "$REMOTE" -- arduino-app/sentinel/ | grep -q .; then
-    log "LED app changed, updating..."
-    mkdir -p "$LED_APP_DEST"
-    rm -rf "$LED_APP_DEST/python" "$LED_APP_DEST/sketch"
-    cp "$LED_APP_SRC/app.yaml" "$LED_APP_DEST/"
-    cp -R "$LED_APP_SRC/python" "$LED_APP_DEST/"
-    cp -R "$LED_APP_SRC/sketch" "$LED_APP_DEST/"
-    # On-device, the running app id shows up as "user:sentinel".
-    # Stop by id first (most reliable), then fall back to the short name.
-    arduino-app-cli app stop user:sentinel 2>/dev/null || arduino-app-cli app stop sentinel 2>/dev/null || true
-    cd "$LED_APP_DEST" && arduino-app-cli app start .
-    cd "$REPO_DIR"
-    log "LED app updated and restarted"
+FAILURE="$CANARY_ERROR"
+log "Canary failed: $FAILURE. Rolling back to ${LOCAL:0:7}..."
+echo "$REMOTE" > "$FAILED_COMMIT_FILE"
+# Baseline migrations only add tables and columns, which the previous version
+# ignores; numbered migrations it does not know are reverted first, while the
+# new code that has their down scripts is still checked out.
+FROM_VERSION=$(json_field "$MIGRATION" from_version || true)
+if [ -n "$FROM_VERSION" ]; then
+    log "Reverting migrations to version $FROM_VERSION: $("$VENV_DIR/bin/python" main.py --migrate-down "$FROM_VERSION" 2>>"$LOG_FILE" || true)"
 fi
+git reset --quiet --hard "$LOCAL"
+install_release "$REMOTE" "$LOCAL"
+restart_services
 
-# Restart the app
-log "Restarting sentinel..."
-sudo systemctl restart sentinel
-if systemctl is-active --quiet sentinel-forecasting 2>/dev/null; then
-    log "Restarting sentinel-forecasting..."
-    sudo systemctl restart sentinel-forecasting
+if canary; then
+    record_deploy "rolled_back" "$LOCAL" "$REMOTE" "$FAILURE"
+    log "Rolled back to ${LOCAL:0:7}"
+else
+    record_deploy "rollback_failed" "$LOCAL" "$REMOTE" "$FAILURE; after rollback: $CANARY_ERROR"
+    log "Rollback to ${LOCAL:0:7} is unhealthy too: $CANARY_ERROR"
+    exit 1
 fi
-log "Deploy complete ($(git rev-parse --short HEAD))"
@@ -0,0 +1,314 @@
+#!/usr/bin/env python3
+"""Load and soak test for the sync and planning pipeline.
+
+Seeds a synthetic universe (N securities with M years of random-walk daily
+prices and a portfolio holding part of it) in a throwaway data directory,
+then runs repeated sync cycles (portfolio, prices, quotes) and planning
+cycles (ideal portfolio + recommendations) against an in-process fake
+broker. Reports per-phase timings and memory so regressions on Pi-class
+hardware show up before a release. No network access or credentials are
+needed.
+
+Usage (from repo root with venv activated):
+    python scripts/loadtest.py
+    python scripts/loadtest.py --securities 200 --years 20 --cycles 5
+    python scripts/loadtest.py --json > loadtest.json
+    python scripts/loadtest.py --data-dir /tmp/sentinel-load --keep
+"""
+
+import argparse
+import asyncio
+import json
+import logging
+import os
+import random
+import resource
+import shutil
+import statistics
+import sys
+import tempfile
+import time
+import tracemalloc
+from datetime import date, timedelta
+from pathlib import Path
+
+# Ensure project root is on path
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent))
+
+logging.basicConfig(level=logging.WARNING, format="%(asctime)s - %(levelname)s - %(message)s")
+logger = logging.getLogger("loadtest")
+
+# (suffix, market id, currency) cycled through when naming synthetic symbols.
+MARKETS = [
+    (".US", "NASDAQ", "USD"),
+    (".US", "NYSE", "USD"),
+    (".GR", "XETRA", "EUR"),
+    (".EU", "EU", "EUR"),
+    (".UK", "LSE", "GBP"),
+    (".AS", "HKEX", "HKD"),
+]
+EXCHANGE_RATES = {"EUR": 1.0, "USD": 0.92, "GBP": 1.17, "HKD": 0.118}
+
+
+def _trading_days(years: int) -> list[str]:
+    end = date.today() - timedelta(days=1)
+    day = end - timedelta(days=365 * years)
+    days = []
+    while day <= end:
+        if day.weekday() < 5:
+            days.append(day.isoformat())
+        day += timedelta(days=1)
+    return days
+
+
+def _random_walk(rng: random.Random, days: list[str]) -> list[dict]:
+    drift = rng.uniform(-0.0002, 0.0006)
+    volatility = rng.uniform(0.008, 0.03)
+    close = rng.uniform(5, 500)
+    rows = []
+    for day in days:
+        open_ = close
+        close = max(0.5, close * (1 + rng.gauss(drift, volatility)))
+        rows.append(
+            {
+                "date": day,
+                "open": round(open_, 4),
+                "high": round(max(open_, close) * (1 + rng.uniform(0, volatility)), 4),
+                "low": round(min(open_, close) * (1 - rng.uniform(0, volatility)), 4),
+                "close": round(close, 4),
+                "volume": rng.randint(10_000, 5_000_000),
+            }
+        )
+    return rows
+
+
+class SyntheticBroker:
+    """In-memory broker serving the seeded universe.
+
+    Implements the parts of the Broker interface the sync tasks and the
+    planner use.
+    """
+
+    def __init__(self, prices: dict[str, list[dict]], positions: list[dict], cash: dict[str, float]):
+        self._prices = prices
+        self._positions = positions
+        self._cash = cash
+
+    @property
+    def connected(self) -> bool:
+        return True
+
+    async def connect(self) -> bool:
+        return True
+
+    async def get_portfolio(self) -> dict:
+        return {"positions": [dict(pos) for pos in self._positions], "cash": dict(self._cash)}
+
+    async def get_quote(self, symbol: str) -> dict | None:
+        rows = self._prices.get(symbol)
+        if not rows:
+            return None
+        last, previous = rows[-1], rows[-2] if len(rows) > 1 else rows[-1]
+        change = last["close"] - previous["close"]
+        return {
+            "symbol": symbol,
+            "price": last["close"],
+            "bid": last["close"],
+            "ask": last["close"],
+            "change": change,
+            "change_percent": change / previous["close"] * 100 if previous["close"] else 0,
+        }
+
+    async def get_quotes(self, symbols: list[str]) -> dict[str, dict]:
+        quotes = {}
+        for symbol in symbols:
+            quote = await self.get_quote(symbol)
+            if quote:
+                quotes[symbol] = quote
+        return quotes
+
+    async def get_historical_prices_bulk(
+        self,
+        symbols: list[str],
+        years: int = 20,
+        *,
+        raise_on_error: bool = False,
+    ) -> dict[str, list[dict]]:
+        return {symbol: list(self._prices.get(symbol, [])) for symbol in symbols}
+
+    async def has_pending_orders(self) -> bool:
+        return False
+
+
+async def seed(db, rng: random.Random, securities: int, years: int) -> SyntheticBroker:
+    """Seed securities, prices, positions and cash; return a broker serving them."""
+    days = _trading_days(years)
+    prices: dict[str, list[dict]] = {}
+    positions = []
+
+    for i in range(securities):
+        suffix, market, currency = MARKETS[i % len(MARKETS)]
+        symbol = f"SYN{i:04d}{suffix}"
+        await db.upsert_security(
+            symbol,
+            name=f"Synthetic {i}",
+            currency=currency,
+            active=1,
+            data=json.dumps({"mrkt": {"mkt_id": market}}),
+        )
+        prices[symbol] = _random_walk(rng, days)
+        await db.save_prices(symbol, prices[symbol])
+
+        # Hold roughly a third of the universe.
+        if i % 3 == 0:
+            price = prices[symbol][-1]["close"]
+            positions.append(
+                {
+                    "symbol": symbol,
+                    "quantity": rng.randint(1, 50),
+                    "avg_cost": round(price * rng.uniform(0.7, 1.3), 4),
+                    "current_price": price,
+                    "currency": currency,
+                }
+            )
+            await db.upsert_position(symbol, **positions[-1])
+
+    cash = {"EUR": 5000.0, "USD": 1500.0}
+    await db.set_cash_balances(cash)
+    return SyntheticBroker(prices, positions, cash)
+
+
+async def _timed(timings: dict[str, list[float]], phase: str, coro):
+    start = time.perf_counter()
+    result = await coro
+    timings.setdefault(phase, []).append(time.perf_counter() - start)
+    return result
+
+
+def _max_rss_mb() -> float:
+    # ru_maxrss is KiB on Linux, bytes on macOS.
+    rss = resource.getrusage(resource.RUSAGE_SELF).ru_maxrss
+    return rss / (1024 * 1024) if sys.platform == "darwin" else rss / 1024
+
+
+async def run(args: argparse.Namespace, data_dir: Path) -> dict:
+    from sentinel.cache import Cache
+    from sentinel.currency import Currency
+    from sentinel.database import Database
+    from sentinel.jobs import tasks
+    from sentinel.planner import Planner
+    from sentinel.portfolio import Portfolio
+    from sentinel.settings import Settings
+
+    db = Database()
+    await db.connect()
+    settings = Settings()
+    await settings.init_defaults()
+    await settings.set("exchange_rates", EXCHANGE_RATES)
+    Currency().clear_cache()
+
+    timings: dict[str, list[float]] = {}
+    rng = random.Random(args.seed)
+    tracemalloc.start()
+
+    start = time.perf_counter()
+    broker = await seed(db, rng, args.securities, args.years)
+    seed_seconds = time.perf_counter() - start
+
+    portfolio = Portfolio(db=db, broker=broker)
+    planner = Planner(db=db, broker=broker, portfolio=portfolio)
+    cache = Cache("motion")
+    recommendations = 0
+
+    try:
+        for cycle in range(1, args.cycles + 1):
+            await _timed(timings, "sync:portfolio", tasks.sync_portfolio(portfolio))
+            await _timed(timings, "sync:prices", tasks.sync_prices(db, broker, cache))
+            await _timed(timings, "sync:quotes", tasks.sync_quotes(db, broker))
+
+            await db.cache_clear("planner:")
+            await _timed(timings, "planner:ideal", planner.calculate_ideal_portfolio())
+            recommendations = len(await _timed(timings, "planner:recommendations", planner.get_recommendations()))
+            logger.warning("Cycle %d/%d done", cycle, args.cycles)
+    finally:
+        _, peak = tracemalloc.get_traced_memory()
+        tracemalloc.stop()
+        await db.close()
+        db.remove_from_cache()
+
+    return {
+        "securities": args.securities,
+        "years": args.years,
+        "cycles": args.cycles,
+        "price_rows": sum(len(rows) for rows in broker._prices.values()),
+        "seed_seconds": round(seed_seconds, 3),
+        "recommendations": recommendations,
+        "phases": {
+            phase: {
+                "mean": round(statistics.mean(values), 3),
+                "min": round(min(values), 3),
+                "max": round(max(values), 3),
+            }
+            for phase, values in timings.items()
+        },
+        "python_peak_mb": round(peak / (1024 * 1024), 1),
+        "max_rss_mb": round(_max_rss_mb(), 1),
+        "db_size_mb": round((data_dir / "sentinel.db").stat().st_size / (1024 * 1024), 1),
+    }
+
+
+def print_report(report: dict) -> None:
+    print(
+        f"{report['securities']} securities x {report['years']} years "
+        f"({report['price_rows']} price rows), {report['cycles']} cycles"
+    )
+    print(f"Seeding: {report['seed_seconds']:.2f}s")
+    print()
+    print(f"{'phase':<26}{'mean':>9}{'min':>9}{'max':>9}")
+    for phase, stats in report["phases"].items():
+        print(f"{phase:<26}{stats['mean']:>8.2f}s{stats['min']:>8.2f}s{stats['max']:>8.2f}s")
+    print()
+    print(f"Recommendations (last cycle): {report['recommendations']}")
+    print(f"Python heap peak: {report['python_peak_mb']} MB")
+    print(f"Max RSS: {report['max_rss_mb']} MB")
+    print(f"Database size: {report['db_size_mb']} MB")
+
+
+def main() -> None:
+    parser = argparse.ArgumentParser(description="Load test sync and planning cycles on synthetic data")
+    parser.add_argument("--securities", type=int, default=100, help="Number of securities (default: 100)")
+    parser.add_argument("--years", type=int, default=10, help="Years of daily prices (default: 10)")
+    parser.add_argument("--cycles", type=int, default=3, help="Sync + planning cycles to run (default: 3)")
+    parser.add_argument("--seed", type=int, default=42, help="Random seed (default: 42)")
+    parser.add_argument("--data-dir", type=str, help="Data directory to use (default: a new temp dir)")
+    parser.add_argument("--keep", action="store_true", help="Keep the data directory afterwards")
+    parser.add_argument("--json", action="store_true", help="Print the report as JSON")
+    args = parser.parse_args()
+
+    if args.securities < 1 or args.years < 1 or args.cycles < 1:
+        parser.error("--securities, --years and --cycles must be at least 1")
+
+    data_dir = Path(args.data_dir or tempfile.mkdtemp(prefix="sentinel-loadtest-"))
+    data_dir.mkdir(parents=True, exist_ok=True)
+    if (data_dir / "sentinel.db").exists():
+        parser.error(f"{data_dir} already has a sentinel.db; use an empty directory")
+    # Everything that reads DATA_DIR (default database path, caches) must land
+    # in the scratch directory, so set it before any sentinel import.
+    os.environ["SENTINEL_DATA_DIR"] = str(data_dir)
+
+    try:
+        report = asyncio.run(run(args, data_dir))
+    finally:
+        if args.keep:
+            logger.warning("Data kept in %s", data_dir)
+        else:
+            shutil.rmtree(data_dir, ignore_errors=True)
+
+    if args.json:
+        print(json.dumps(report, indent=2))
+    else:
+        print_report(report)
+
+
+if __name__ == "__main__":
+    main()
@@ -16,16 +16,19 @@ from __future__ import annotations
 import asyncio
 import logging
 import os
+import time
 from typing import Any
 
 from sentinel.database import Database
-from sentinel.led.arduino_router_rpc import UnixMsgpackRpc, serve_forever
+from sentinel.led.arduino_router_rpc import RpcError, UnixMsgpackRpc, serve_forever
 from sentinel.led.heatmap_parts import SecurityScore, build_sorted_parts, clamp_score
 from sentinel.planner import Planner
 
 logging.basicConfig(level=logging.INFO, format="%(asctime)s %(levelname)s %(message)s")
 logger = logging.getLogger("uno_q_heatmap_router_server")
 
+RECONNECT_DELAY_SEC = 2
+
 
 async def compute_before_after() -> list[list[float]]:
     db = Database()
@@ -90,14 +93,6 @@ def main() -> int:
     sock_path = os.environ.get("ARDUINO_ROUTER_SOCK", "/var/run/arduino-router.sock")
     method = os.environ.get("HEATMAP_METHOD", "heatmap/get")
 
-    rpc = UnixMsgpackRpc(sock_path)
-    rpc.connect()
-    logger.info(f"Connected to arduino-router socket {sock_path}")
-
-    # Register the method name so the router can route calls to this connection.
-    rpc.call("$/register", method)
-    logger.info(f"Registered method {method!r}")
-
     loop = asyncio.new_event_loop()
     asyncio.set_event_loop(loop)
 
@@ -106,11 +101,23 @@ def main() -> int:
         # (router calls are synchronous per request).
         return loop.run_until_complete(compute_before_after())
 
-    try:
-        serve_forever(rpc, {method: handle_get})
-    finally:
-        rpc.close()
-    return 0
+    # Reconnect forever: the router may come up after us or restart underneath us.
+    while True:
+        rpc = UnixMsgpackRpc(sock_path)
+        try:
+            rpc.connect_with_retry()
+            logger.info(f"Connected to arduino-router socket {sock_path}")
+
+            # Register the method name so the router can route calls to this connection.
+            rpc.call("$/register", method)
+            logger.info(f"Registered method {method!r}")
+
+            serve_forever(rpc, {method: handle_get})
+        except (OSError, RpcError) as e:
+            logger.warning(f"arduino-router connection lost: {e}; reconnecting")
+            time.sleep(RECONNECT_DELAY_SEC)
+        finally:
+            rpc.close()
 
 
 if __name__ == "__main__":
@@ -3,8 +3,12 @@
 Provides common dependencies that can be injected into route handlers.
 """
 
+import hmac
 from dataclasses import dataclass
 
+from fastapi import Depends, Header, HTTPException
+from typing_extensions import Annotated
+
 from sentinel.broker import Broker
 from sentinel.currency import Currency
 from sentinel.database import Database
@@ -39,3 +43,30 @@ async def get_common_deps() -> CommonDependencies:
         broker=Broker(),
         currency=Currency(),
     )
+
+
+def reader(db: Database) -> Database:
+    """The read-only replica of `db` for analytics and reports.
+
+    Long SELECTs there never hold up sync or ledger writes. Anything that is
+    not a Database (e.g. a test double) is returned as is.
+    """
+    return db.replica if isinstance(db, Database) else db
+
+
+ADMIN_TOKEN_KEY = "admin_token"
+
+
+async def require_admin(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    x_admin_token: Annotated[str | None, Header()] = None,
+) -> None:
+    """Allow the request only if its X-Admin-Token header matches the `admin_token` setting.
+
+    Admin endpoints are disabled (403) while the setting is empty.
+    """
+    token = await deps.settings.get(ADMIN_TOKEN_KEY, "") or ""
+    if not token:
+        raise HTTPException(status_code=403, detail="Admin endpoints are disabled; set admin_token to enable them")
+    if not x_admin_token or not hmac.compare_digest(x_admin_token.encode(), str(token).encode()):
+        raise HTTPException(status_code=401, detail="Invalid or missing X-Admin-Token")
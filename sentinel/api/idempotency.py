@@ -0,0 +1,113 @@
+"""Idempotency-Key support for side-effecting endpoints.
+
+A client that sends an `Idempotency-Key` header gets the original result back
+when it retries with the same key, instead of placing a second order or
+running a job twice. Only successful results are stored, so a request that
+failed can be retried with the same key. Keys are scoped per endpoint and
+kept for `idempotency_key_retention_hours`.
+
+Usage:
+    @router.post("/thing")
+    async def do_thing(
+        data: dict,
+        deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+        response: Response,
+        idempotency_key: IdempotencyKey = None,
+    ) -> dict:
+        return await run_idempotent(deps, response, "thing", idempotency_key, data, lambda: _do_thing(data))
+"""
+
+from __future__ import annotations
+
+import asyncio
+import hashlib
+import json
+import time
+from typing import Any, Awaitable, Callable
+
+from fastapi import Header, HTTPException, Response
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies
+
+RETENTION_HOURS_KEY = "idempotency_key_retention_hours"
+DEFAULT_RETENTION_HOURS = 24
+MAX_KEY_LENGTH = 255
+REPLAY_HEADER = "Idempotent-Replay"
+
+IdempotencyKey = Annotated[str | None, Header(alias="Idempotency-Key")]
+
+# Serializes requests sharing a key so a concurrent retry waits for the first
+# result instead of executing alongside it.
+_locks: dict[tuple[str, str], asyncio.Lock] = {}
+# Requests holding or waiting for each lock; the lock is dropped at zero.
+_waiters: dict[tuple[str, str], int] = {}
+
+
+def request_hash(payload: Any) -> str:
+    """Stable hash of the request parameters a key is used with."""
+    encoded = json.dumps(payload, sort_keys=True, default=str).encode()
+    return hashlib.sha256(encoded).hexdigest()
+
+
+async def _retention_seconds(settings) -> int:
+    raw = await settings.get(RETENTION_HOURS_KEY, DEFAULT_RETENTION_HOURS)
+    try:
+        hours = float(raw)
+    except (TypeError, ValueError):
+        hours = DEFAULT_RETENTION_HOURS
+    return int(max(1.0, hours) * 3600)
+
+
+async def run_idempotent(
+    deps: CommonDependencies,
+    response: Response,
+    scope: str,
+    key: str | None,
+    payload: Any,
+    handler: Callable[[], Awaitable[Any]],
+    should_store: Callable[[Any], bool] | None = None,
+) -> Any:
+    """Run `handler` once per (scope, key); replays return the stored result.
+
+    A handler that raises is not stored; `should_store` can also reject a
+    result that reports failure without raising.
+
+    Raises:
+        HTTPException: 400 for a malformed key, 422 when the key was already
+            used with different request parameters.
+    """
+    if key is None:
+        return await handler()
+    key = key.strip()
+    if not key or len(key) > MAX_KEY_LENGTH:
+        raise HTTPException(status_code=400, detail=f"Idempotency-Key must be 1-{MAX_KEY_LENGTH} characters")
+
+    fingerprint = request_hash(payload)
+    lock_key = (scope, key)
+    lock = _locks.setdefault(lock_key, asyncio.Lock())
+    _waiters[lock_key] = _waiters.get(lock_key, 0) + 1
+    try:
+        async with lock:
+            cutoff = int(time.time()) - await _retention_seconds(deps.settings)
+            await deps.db.prune_idempotency_keys(cutoff)
+
+            record = await deps.db.get_idempotency_record(scope, key)
+            if record is not None:
+                if record["request_hash"] != fingerprint:
+                    raise HTTPException(
+                        status_code=422,
+                        detail="Idempotency-Key was already used with different request parameters",
+                    )
+                response.headers[REPLAY_HEADER] = "true"
+                return record["response"]
+
+            result = await handler()
+            if should_store is None or should_store(result):
+                await deps.db.save_idempotency_record(scope, key, fingerprint, result)
+            return result
+    finally:
+        _waiters[lock_key] -= 1
+        if not _waiters[lock_key]:
+            del _waiters[lock_key]
+            del _locks[lock_key]
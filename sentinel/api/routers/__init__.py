@@ -4,11 +4,21 @@ Each router handles a specific domain of the API.
 """
 
 from sentinel.api.routers.backup import router as backup_router
+from sentinel.api.routers.broker_symbols import router as broker_symbols_router
+from sentinel.api.routers.exclusions import router as exclusions_router
 from sentinel.api.routers.forecasts import router as forecasts_router
+from sentinel.api.routers.goals import router as goals_router
+from sentinel.api.routers.groups import router as groups_router
+from sentinel.api.routers.imports import router as imports_router
 from sentinel.api.routers.jobs import router as jobs_router
 from sentinel.api.routers.jobs import set_scheduler
+from sentinel.api.routers.orders import router as orders_router
+from sentinel.api.routers.planner import planning_router
 from sentinel.api.routers.planner import router as planner_router
+from sentinel.api.routers.portfolio import analytics_router
 from sentinel.api.routers.portfolio import router as portfolio_router
+from sentinel.api.routers.projections import router as projections_router
+from sentinel.api.routers.reports import router as reports_router
 from sentinel.api.routers.securities import prices_router, unified_router
 from sentinel.api.routers.securities import router as securities_router
 from sentinel.api.routers.settings import led_router
@@ -19,25 +29,40 @@ from sentinel.api.routers.system import (
     exchange_rates_router,
     markets_router,
     meta_router,
+    profile_router,
     pulse_router,
 )
 from sentinel.api.routers.system import (
     router as system_router,
 )
-from sentinel.api.routers.trading import cashflows_router, trading_actions_router
+from sentinel.api.routers.temperament import router as temperament_router
+from sentinel.api.routers.trading import cash_router, cashflows_router, trading_actions_router, trading_pause_router
 from sentinel.api.routers.trading import router as trading_router
 
 __all__ = [
     "settings_router",
     "led_router",
     "portfolio_router",
+    "analytics_router",
+    "reports_router",
     "securities_router",
     "prices_router",
     "unified_router",
+    "exclusions_router",
+    "groups_router",
+    "goals_router",
+    "temperament_router",
+    "projections_router",
+    "broker_symbols_router",
     "trading_router",
     "cashflows_router",
+    "imports_router",
+    "cash_router",
     "trading_actions_router",
+    "trading_pause_router",
+    "orders_router",
     "planner_router",
+    "planning_router",
     "jobs_router",
     "set_scheduler",
     "backup_router",
@@ -49,4 +74,5 @@ __all__ = [
     "markets_router",
     "meta_router",
     "pulse_router",
+    "profile_router",
 ]
@@ -0,0 +1,85 @@
+"""Broker symbol mapping API routes."""
+
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.broker_symbols import (
+    delete_mapping,
+    detect_broker_symbols,
+    mapping_report,
+    set_manual_mapping,
+    validate_mapping,
+)
+from sentinel.identifiers import normalize_isin, security_isin
+
+router = APIRouter(prefix="/broker-symbols", tags=["broker-symbols"])
+
+
+def _isin(value: str) -> str:
+    isin = normalize_isin(value)
+    if isin is None:
+        raise HTTPException(status_code=400, detail=f"{value!r} is not an ISIN")
+    return isin
+
+
+@router.get("")
+async def get_broker_symbols(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List ISIN -> broker symbol mappings and active securities without a usable one."""
+    return await mapping_report(deps.db)
+
+
+@router.post("/detect")
+async def detect_broker_symbols_endpoint(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Map ISINs from stored broker metadata now instead of waiting for metadata sync."""
+    return await detect_broker_symbols(deps.db)
+
+
+@router.get("/{isin}")
+async def get_broker_symbol(
+    isin: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """One ISIN's mapping and the stored securities listed under it."""
+    isin = _isin(isin)
+    mapping = await deps.db.get_broker_symbol(isin)
+    listings = sorted(
+        security["symbol"]
+        for security in await deps.db.get_all_securities(active_only=False)
+        if security_isin(security) == isin
+    )
+    if mapping is None and not listings:
+        raise HTTPException(status_code=404, detail="ISIN not found")
+    return {"isin": isin, "mapping": mapping, "listings": listings}
+
+
+@router.put("/{isin}")
+async def put_broker_symbol(
+    isin: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Pin an ISIN to a stored security's symbol. Body: {"broker_symbol": str}."""
+    try:
+        isin, broker_symbol = validate_mapping(isin, data)
+        mapping = await set_manual_mapping(deps.db, isin, broker_symbol)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"status": "ok", "mapping": mapping}
+
+
+@router.delete("/{isin}")
+async def delete_broker_symbol(
+    isin: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Remove a mapping; metadata sync may map the ISIN again automatically."""
+    if not await delete_mapping(deps.db, _isin(isin)):
+        raise HTTPException(status_code=404, detail="Mapping not found")
+    return {"status": "ok"}
@@ -0,0 +1,80 @@
+"""Exclusion list API routes."""
+
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.exclusions import describe_exclusion, get_excluded_securities, validate_exclusion_list
+
+router = APIRouter(prefix="/exclusions", tags=["exclusions"])
+
+
+@router.get("")
+async def get_exclusion_lists(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List exclusion lists, active or not."""
+    return {"lists": await deps.db.get_exclusion_lists()}
+
+
+@router.get("/securities")
+async def get_excluded_securities_endpoint(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List securities excluded by an active list, and why."""
+    excluded = await get_excluded_securities(deps.db)
+    return {
+        "securities": [
+            {"symbol": symbol, "excluded_by": matches, "reason": describe_exclusion(matches)}
+            for symbol, matches in sorted(excluded.items())
+        ]
+    }
+
+
+@router.post("")
+async def create_exclusion_list(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Create an exclusion list.
+
+    Body: {"name", "reason"?, "symbols"?, "isins"?, "industries"?}; at least one of the lists is required.
+    """
+    try:
+        fields = validate_exclusion_list(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    list_id = await deps.db.create_exclusion_list(**fields)
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok", "id": list_id}
+
+
+@router.put("/{list_id}")
+async def update_exclusion_list(
+    list_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Update some fields of an exclusion list, including `active`."""
+    try:
+        fields = validate_exclusion_list(data, partial=True)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if not await deps.db.update_exclusion_list(list_id, **fields):
+        raise HTTPException(status_code=404, detail="Exclusion list not found")
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok"}
+
+
+@router.delete("/{list_id}")
+async def delete_exclusion_list(
+    list_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Delete an exclusion list."""
+    if not await deps.db.delete_exclusion_list(list_id):
+        raise HTTPException(status_code=404, detail="Exclusion list not found")
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok"}
@@ -0,0 +1,78 @@
+"""Investment goal API routes."""
+
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.planner import Planner
+from sentinel.planner.goals import validate_goal
+
+router = APIRouter(prefix="/goals", tags=["goals"])
+
+
+@router.get("")
+async def get_goals(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List investment goals, active or not, most important first."""
+    return {"goals": await deps.db.get_goals()}
+
+
+@router.get("/projection")
+async def get_goal_projection() -> dict:
+    """Project every active goal: probability of reaching it and the planner tilt."""
+    planner = Planner()
+    return await planner.get_goal_projection()
+
+
+@router.get("/income")
+async def get_income_projection() -> dict:
+    """Project the next twelve months of dividend income against the income target."""
+    planner = Planner()
+    return await planner.get_income_projection()
+
+
+@router.post("")
+async def create_goal(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Create an investment goal.
+
+    Body: {"name", "target_amount_eur", "target_date", "priority"?}
+    """
+    try:
+        goal = validate_goal(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    goal_id = await deps.db.create_goal(**goal)
+    return {"status": "ok", "id": goal_id}
+
+
+@router.put("/{goal_id}")
+async def update_goal(
+    goal_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Update some fields of a goal, including `active`."""
+    try:
+        fields = validate_goal(data, partial=True)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if not await deps.db.update_goal(goal_id, **fields):
+        raise HTTPException(status_code=404, detail="Goal not found")
+    return {"status": "ok"}
+
+
+@router.delete("/{goal_id}")
+async def delete_goal(
+    goal_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Delete an investment goal."""
+    if not await deps.db.delete_goal(goal_id):
+        raise HTTPException(status_code=404, detail="Goal not found")
+    return {"status": "ok"}
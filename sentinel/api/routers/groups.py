@@ -0,0 +1,134 @@
+"""Security group API routes."""
+
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.planner import Planner
+from sentinel.planner.groups import group_target_total, validate_members, validate_security_group
+
+router = APIRouter(prefix="/groups", tags=["groups"])
+
+
+async def _check_group_fields(deps: CommonDependencies, fields: dict, group_id: int | None = None) -> None:
+    if "name" in fields:
+        existing = await deps.db.get_security_group_by_name(fields["name"])
+        if existing and existing["id"] != group_id:
+            raise HTTPException(status_code=409, detail=f"A group named {existing['name']} already exists")
+    if fields.get("target_pct") is not None:
+        others = [group for group in await deps.db.get_security_groups() if group["id"] != group_id]
+        total = group_target_total(others) + fields["target_pct"]
+        if total > 100:
+            raise HTTPException(status_code=400, detail=f"group targets add up to {total:g}%, more than 100%")
+
+
+@router.get("")
+async def get_security_groups(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List security groups with their members."""
+    return {"groups": await deps.db.get_security_groups()}
+
+
+@router.get("/allocations")
+async def get_group_allocations() -> dict:
+    """Get every group's current and ideal weight against its target."""
+    planner = Planner()
+    return await planner.get_group_allocations()
+
+
+@router.post("")
+async def create_security_group(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Create a security group.
+
+    Body: {"name", "description"?, "target_pct"?}
+    """
+    try:
+        fields = validate_security_group(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    await _check_group_fields(deps, fields)
+    group_id = await deps.db.create_security_group(**fields)
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok", "id": group_id}
+
+
+@router.put("/{group_id}")
+async def update_security_group(
+    group_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Update some fields of a security group; a null `target_pct` clears the target."""
+    try:
+        fields = validate_security_group(data, partial=True)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if await deps.db.get_security_group(group_id) is None:
+        raise HTTPException(status_code=404, detail="Group not found")
+    await _check_group_fields(deps, fields, group_id)
+    await deps.db.update_security_group(group_id, **fields)
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok"}
+
+
+@router.delete("/{group_id}")
+async def delete_security_group(
+    group_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Delete a security group; its members become ungrouped."""
+    if not await deps.db.delete_security_group(group_id):
+        raise HTTPException(status_code=404, detail="Group not found")
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok"}
+
+
+@router.post("/{group_id}/members")
+async def add_group_members(
+    group_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Add securities to a group, moving them out of any other group.
+
+    Body: {"symbols": ["KO.US", "PEP.US"]}
+    """
+    try:
+        symbols = validate_members(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if await deps.db.get_security_group(group_id) is None:
+        raise HTTPException(status_code=404, detail="Group not found")
+    known = {security["symbol"] for security in await deps.db.get_all_securities(active_only=False)}
+    unknown = [symbol for symbol in symbols if symbol not in known]
+    if unknown:
+        raise HTTPException(status_code=400, detail=f"unknown securities: {', '.join(unknown)}")
+    moved = [
+        {"symbol": symbol, "from": group["name"]}
+        for group in await deps.db.get_security_groups()
+        if group["id"] != group_id
+        for symbol in group["symbols"]
+        if symbol in symbols
+    ]
+    await deps.db.add_security_group_members(group_id, symbols)
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok", "moved": moved}
+
+
+@router.delete("/{group_id}/members/{symbol}")
+async def remove_group_member(
+    group_id: int,
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Take a security out of a group."""
+    if not await deps.db.remove_security_group_members(group_id, [symbol.upper()]):
+        raise HTTPException(status_code=404, detail=f"{symbol.upper()} is not in this group")
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok"}
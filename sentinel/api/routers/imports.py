@@ -0,0 +1,71 @@
+"""Statement import API routes."""
+
+from __future__ import annotations
+
+from typing import Any
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.statement_import import StatementImporter, load_templates, validate_template
+
+router = APIRouter(prefix="/imports", tags=["imports"])
+
+
+async def _request(data: dict, deps: CommonDependencies) -> tuple[str, dict[str, Any], str]:
+    """The statement text, its template and the import's source name from a request body."""
+    text = data.get("csv")
+    if not isinstance(text, str) or not text.strip():
+        raise ValueError("csv must be the statement's text")
+    template = data.get("template")
+    if isinstance(template, str):
+        templates = await load_templates(deps.settings)
+        if template not in templates:
+            raise ValueError(f"unknown template {template!r}")
+        source = data.get("source") or template
+        template = templates[template]
+    else:
+        template = validate_template(template)
+        source = data.get("source") or "inline"
+    if not isinstance(source, str) or not source.strip() or ":" in source:
+        raise ValueError("source must be a name without ':'")
+    return text, template, source.strip()
+
+
+@router.get("/templates")
+async def get_templates(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Built-in and configured statement templates."""
+    return {"templates": await load_templates(deps.settings)}
+
+
+@router.post("/statements/preview")
+async def preview_statement(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Parse and validate a CSV statement without importing it.
+
+    Body: {"csv", "template": name or template, "source"?}
+    """
+    try:
+        text, template, source = await _request(data, deps)
+        return await StatementImporter(deps.db).preview(text, template, source)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.post("/statements")
+async def import_statement(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Import a CSV statement's valid rows; rows imported before are skipped.
+
+    Body: {"csv", "template": name or template, "source"?}
+    """
+    try:
+        text, template, source = await _request(data, deps)
+        return await StatementImporter(deps.db).import_statement(text, template, source)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
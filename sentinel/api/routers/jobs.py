@@ -1,13 +1,17 @@
 """Jobs API routes for job management and scheduling."""
 
-from datetime import datetime
+from datetime import datetime, timezone
 from typing import Optional
 
-from fastapi import APIRouter, Depends, HTTPException
+from fastapi import APIRouter, Depends, HTTPException, Query, Response
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
-from sentinel.jobs import get_status, reschedule, run_now
+from sentinel.api.idempotency import IdempotencyKey, run_idempotent
+from sentinel.artifacts import CONTENT_TYPES, download_name, read_artifact
+from sentinel.jobs import get_status, reschedule, run_now, schedule_audit
+from sentinel.job_pause import JobPause
+from sentinel.jobs.runner import MARKET_TIMING_EACH_MARKET_CLOSE, PER_MARKET_JOBS, TASK_REGISTRY
 
 router = APIRouter(prefix="/jobs", tags=["jobs"])
 
@@ -16,6 +20,7 @@ MARKET_TIMING_LABELS = {
     1: "After market close",
     2: "During market open",
     3: "All markets closed",
+    4: "After each market close",
 }
 
 # Global scheduler reference - set from app.py
@@ -35,15 +40,116 @@ async def get_jobs() -> dict:
     return status
 
 
-@router.post("/{job_type:path}/run")
-async def run_job_endpoint(job_type: str) -> dict:
-    """Manually trigger a job by type. Executes immediately."""
+@router.get("/schedule-audit")
+async def get_schedule_audit() -> dict:
+    """Next eligible run of each job in UTC and exchange-local time (also logged)."""
+    return {"jobs": await schedule_audit()}
+
+
+def _pause_job_type(data: dict) -> str | None:
+    job_type = data.get("job_type")
+    if job_type is not None and job_type not in TASK_REGISTRY:
+        raise HTTPException(status_code=404, detail=f"Unknown job type: {job_type}")
+    return job_type
+
+
+@router.get("/pause")
+async def get_job_pause(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Paused jobs and the maintenance window in effect."""
+    return await JobPause(deps.settings).status()
+
+
+@router.post("/pause")
+async def pause_jobs(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: Optional[dict] = None,
+) -> dict:
+    """Hold scheduled runs back until the pause expires or is lifted.
+
+    Body: {"job_type": "forecast:run" | null, "hours": 6 | null, "reason": "..."};
+    without job_type every scheduled job is paused, null hours pause until resumed.
+    """
+    data = data or {}
+    job_type = _pause_job_type(data)
+    try:
+        await JobPause(deps.settings).pause(job_type, data.get("hours"), data.get("reason"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return await JobPause(deps.settings).status()
+
+
+@router.post("/resume")
+async def resume_jobs(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: Optional[dict] = None,
+) -> dict:
+    """Lift the pause of one job type, or every pause without a job_type."""
+    await JobPause(deps.settings).resume(_pause_job_type(data or {}))
+    return await JobPause(deps.settings).status()
+
+
+@router.get("/artifacts")
+async def list_job_artifacts(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    job_type: Optional[str] = None,
+    run_id: Optional[int] = None,
+    limit: Annotated[int, Query(ge=1, le=500)] = 50,
+) -> dict:
+    """Artifacts left by job runs, newest first, without their content."""
+    artifacts = await deps.db.get_job_artifacts(run_id=run_id, job_type=job_type, limit=limit)
+    return {"artifacts": [{**a, "download_name": download_name(a)} for a in artifacts]}
+
+
+@router.get("/artifacts/{artifact_id}")
+async def download_job_artifact(
+    artifact_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> Response:
+    """Download one artifact."""
+    artifact = await deps.db.get_job_artifact(artifact_id)
+    if artifact is None:
+        raise HTTPException(status_code=404, detail="Artifact not found")
+    content = read_artifact(artifact)
+    if content is None:
+        raise HTTPException(status_code=404, detail="Artifact file is missing")
+    return Response(
+        content=content,
+        media_type=CONTENT_TYPES.get(artifact["kind"], "application/octet-stream"),
+        headers={"Content-Disposition": f'attachment; filename="{download_name(artifact)}"'},
+    )
+
+
+async def _run_job(job_type: str) -> dict:
     result = await run_now(job_type)
     if result.get("status") == "failed" and "Unknown job type" in result.get("error", ""):
         raise HTTPException(status_code=404, detail=result["error"])
     return result
 
 
+@router.post("/{job_type:path}/run")
+async def run_job_endpoint(
+    job_type: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+    idempotency_key: IdempotencyKey = None,
+) -> dict:
+    """Manually trigger a job by type. Executes immediately.
+
+    With an Idempotency-Key header, a retry returns the original run's result.
+    """
+    return await run_idempotent(
+        deps,
+        response,
+        "jobs:run",
+        idempotency_key,
+        {"job_type": job_type},
+        lambda: _run_job(job_type),
+        should_store=lambda result: result.get("status") != "failed",
+    )
+
+
 @router.post("/refresh-all")
 async def refresh_all(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
@@ -79,7 +185,7 @@ async def get_job_schedules(
         # Get most recent execution (not just successful ones)
         history = await deps.db.get_job_history_for_type(job_type, limit=1)
         if history:
-            last_run = datetime.fromtimestamp(history[0]["executed_at"]).isoformat()
+            last_run = datetime.fromtimestamp(history[0]["executed_at"], tz=timezone.utc).isoformat()
             last_status = history[0]["status"]
         else:
             last_run = None
@@ -130,8 +236,13 @@ async def update_job_schedule(
     # Validate market_timing
     if "market_timing" in data:
         val = data["market_timing"]
-        if not isinstance(val, int) or val < 0 or val > 3:
-            raise HTTPException(status_code=400, detail="market_timing must be 0, 1, 2, or 3")
+        if not isinstance(val, int) or val < 0 or val > 4:
+            raise HTTPException(status_code=400, detail="market_timing must be 0, 1, 2, 3, or 4")
+        if val == MARKET_TIMING_EACH_MARKET_CLOSE and job_type not in PER_MARKET_JOBS:
+            raise HTTPException(
+                status_code=400,
+                detail=f"market_timing 4 is only supported for: {', '.join(sorted(PER_MARKET_JOBS))}",
+            )
 
     await deps.db.upsert_job_schedule(
         job_type,
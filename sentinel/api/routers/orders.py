@@ -0,0 +1,101 @@
+"""Order management API routes."""
+
+from fastapi import APIRouter, Depends, HTTPException, Response
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.api.idempotency import IdempotencyKey, run_idempotent
+from sentinel.orders import BrokerRefused, OrderNotFound, OrderService, OrderStateError
+from sentinel.trading_pause import TradingPause, describe
+
+router = APIRouter(prefix="/orders", tags=["trading"])
+
+
+async def _require_broker(deps: CommonDependencies) -> None:
+    trading_mode = await deps.settings.get("trading_mode", "research")
+    if trading_mode != "live":
+        raise HTTPException(status_code=409, detail=f"Trading mode is '{trading_mode}'; orders are only sent in live")
+    if not deps.broker.connected:
+        raise HTTPException(status_code=409, detail="Broker not connected")
+
+
+async def _order_action(action) -> dict:
+    try:
+        return await action()
+    except OrderNotFound as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except OrderStateError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except BrokerRefused as e:
+        raise HTTPException(status_code=502, detail=str(e)) from e
+
+
+@router.get("")
+async def get_orders(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    status: str = "open",
+    limit: int = 100,
+    sync: bool = True,
+) -> dict:
+    """List recorded orders, newest first, after syncing their status from the broker.
+
+    Query params:
+        status: open (default), all, or one order status
+        limit: Max orders to return (default 100, max 1000)
+        sync: Refresh statuses from the broker first when connected (default true)
+    """
+    service = OrderService(deps.db, deps.broker)
+    synced = await service.sync() if sync and deps.broker.connected else None
+    try:
+        orders = await service.list_orders(status, limit=max(1, min(limit, 1000)))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"orders": orders, "sync": synced}
+
+
+@router.post("/{order_id}/cancel")
+async def cancel_order(
+    order_id: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Cancel a pending or partially filled order. Allowed while trading is paused."""
+    await _require_broker(deps)
+    service = OrderService(deps.db, deps.broker)
+    return {"order": await _order_action(lambda: service.cancel(order_id))}
+
+
+async def _modify_order(deps: CommonDependencies, order_id: str, data: dict) -> dict:
+    pause = await TradingPause(deps.settings).status()
+    if pause["paused"]:
+        raise HTTPException(status_code=409, detail=describe(pause))
+    await _require_broker(deps)
+    service = OrderService(deps.db, deps.broker)
+    replacement = await _order_action(
+        lambda: service.modify(order_id, price=data.get("price"), quantity=data.get("quantity"))
+    )
+    return {"cancelled": order_id, "order": replacement}
+
+
+@router.post("/{order_id}/modify")
+async def modify_order(
+    order_id: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+    idempotency_key: IdempotencyKey = None,
+) -> dict:
+    """Replace a pending order with a new price and/or quantity.
+
+    Body: {"price"?, "quantity"?}. With an Idempotency-Key header, a retry
+    returns the original replacement instead of placing another order.
+    """
+    return await run_idempotent(
+        deps,
+        response,
+        "orders:modify",
+        idempotency_key,
+        {"order_id": order_id, **data},
+        lambda: _modify_order(deps, order_id, data),
+    )
@@ -5,17 +5,42 @@ import json
 from datetime import datetime, timezone
 from typing import Optional
 
-from fastapi import APIRouter, Depends
+from fastapi import APIRouter, Depends, HTTPException, Query, Response
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.api.idempotency import IdempotencyKey, run_idempotent
+from sentinel.exposure import AccountExposureError
+from sentinel.identifiers import IdentifierService
 from sentinel.markets import get_open_market_symbols
 from sentinel.planner import Planner
+from sentinel.planner.allocation_history import compare_runs, weight_drift
+from sentinel.planner.comparison import metric_delta, run_comparison, validate_comparison
+from sentinel.planner.dry_run import DryRunOverrides, run_dry_run
 from sentinel.planner.models import LongTermPlan
+from sentinel.planner.review import (
+    DEFAULT_REJECT_HOURS,
+    clear_rejection,
+    filter_rejected,
+    get_rejections,
+    recommendation_key,
+    reject_recommendation,
+    validate_target,
+)
+from sentinel.planner.snapshots import diff_snapshots
+from sentinel.planner.preferences import utc_now_iso
+from sentinel.planner.targets import (
+    manual_target_total,
+    position_target,
+    validate_target_changes,
+    with_target_changes,
+)
 from sentinel.portfolio import Portfolio
+from sentinel.trading_pause import TradingPause, describe
 from sentinel.utils.fees import FeeCalculator
 
 router = APIRouter(prefix="/planner", tags=["planner"])
+planning_router = APIRouter(prefix="/planning", tags=["planner"])
 
 
 def _serialize_recommendation(r) -> dict:
@@ -45,6 +70,41 @@ def _serialize_recommendation(r) -> dict:
         "target_gap_ratio": r.target_gap_ratio,
         "is_fallback": r.is_fallback,
         "execution_rank": r.execution_rank,
+        "robustness": r.robustness,
+        "spread_bps": r.spread_bps,
+    }
+
+
+async def _fee_trades(db, recommendations) -> list[dict]:
+    """Fee calculator input with each trade's exchange, currency and spread, for exchange-specific costs."""
+    securities = await db.get_all_securities(active_only=False)
+    market_ids = {s["symbol"]: s.get("market_id") for s in securities if s.get("symbol")}
+    return [
+        {
+            "action": r.action,
+            "value_eur": abs(r.value_delta_eur),
+            "currency": r.currency,
+            "market_id": market_ids.get(r.symbol),
+            "spread_bps": r.spread_bps,
+        }
+        for r in recommendations
+    ]
+
+
+async def _dry_run_summary(db, recommendations, state) -> dict:
+    """Trade totals, fees and the cash left after a dry run's recommendations."""
+    fee_summary = await FeeCalculator().calculate_batch(await _fee_trades(db, recommendations))
+    cash = state.cash_eur()
+    return {
+        "simulated_cash": cash,
+        "total_sell_value": fee_summary["total_sell_value"],
+        "total_buy_value": fee_summary["total_buy_value"],
+        "total_fees": fee_summary["total_fees"],
+        "cash_after_plan": cash
+        + fee_summary["total_sell_value"]
+        - fee_summary["sell_fees"]
+        - fee_summary["total_buy_value"]
+        - fee_summary["buy_fees"],
     }
 
 
@@ -112,10 +172,14 @@ async def get_recommendations(
         min_value = await deps.settings.get("min_trade_value", default=100.0)
 
     open_symbols = await get_open_market_symbols(deps.broker, deps.db)
-    recommendations, long_term_plan = await planner.get_recommendations_with_plan(
-        min_trade_value=min_value,
-        eligible_symbols=open_symbols,
-    )
+    try:
+        recommendations, long_term_plan = await planner.get_recommendations_with_plan(
+            min_trade_value=min_value,
+            eligible_symbols=open_symbols,
+        )
+    except AccountExposureError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+    recommendations = filter_rejected(recommendations, await get_rejections(deps.db))
 
     schedule = await deps.db.get_job_schedule("trading:execute")
     valid_for_minutes = None
@@ -125,8 +189,7 @@ async def get_recommendations(
     # Calculate summary with transaction fees
     current_cash = long_term_plan.current_cash_eur
     fee_calc = FeeCalculator()
-    trades = [{"action": r.action, "value_eur": abs(r.value_delta_eur)} for r in recommendations]
-    fee_summary = await fee_calc.calculate_batch(trades)
+    fee_summary = await fee_calc.calculate_batch(await _fee_trades(deps.db, recommendations))
 
     total_sell_value = fee_summary["total_sell_value"]
     total_buy_value = fee_summary["total_buy_value"]
@@ -144,6 +207,7 @@ async def get_recommendations(
             "total_sell_value": total_sell_value,
             "total_buy_value": total_buy_value,
             "total_fees": total_fees,
+            "total_spread_cost": fee_summary.get("spread_costs", 0.0),
             "cash_after_plan": cash_after_plan,
             "current_total_value_eur": long_term_plan.current_total_value_eur,
             "avg_monthly_net_deposit_6m": long_term_plan.avg_monthly_net_deposit_eur,
@@ -156,6 +220,101 @@ async def get_recommendations(
     }
 
 
+@router.post("/recommendations/reject")
+async def reject_recommendation_endpoint(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Hide a recommendation from the list and from trading:execute for a while.
+
+    Body: {"symbol": str, "action": "buy" | "sell", "hours": int (default 24)}
+    """
+    try:
+        symbol, action = validate_target(data.get("symbol"), data.get("action"))
+        symbol = await IdentifierService(deps.db).canonical(symbol)
+        expires_at_ts = await reject_recommendation(
+            deps.db, symbol, action, hours=data.get("hours", DEFAULT_REJECT_HOURS)
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {
+        "status": "rejected",
+        "symbol": symbol,
+        "action": action,
+        "expires_at": datetime.fromtimestamp(expires_at_ts, tz=timezone.utc).isoformat(),
+    }
+
+
+@router.post("/recommendations/approve")
+async def approve_recommendation_endpoint(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+    idempotency_key: IdempotencyKey = None,
+) -> dict:
+    """Submit one current recommendation now (live trading mode only).
+
+    Body: {"symbol": str, "action": "buy" | "sell"}
+
+    With an Idempotency-Key header, a retry returns the original submission.
+    """
+    try:
+        symbol, action = validate_target(data.get("symbol"), data.get("action"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+    return await run_idempotent(
+        deps,
+        response,
+        "planner:approve",
+        idempotency_key,
+        {"symbol": symbol, "action": action},
+        lambda: _approve_recommendation(deps, symbol, action),
+    )
+
+
+async def _approve_recommendation(deps: CommonDependencies, symbol: str, action: str) -> dict:
+    from sentinel.jobs.tasks import SUBMITTED_TRADE_STATE_KEY, liquidity_block_reason, submit_trade
+
+    pause = await TradingPause(deps.settings).status()
+    if pause["paused"]:
+        raise HTTPException(status_code=409, detail=describe(pause))
+    trading_mode = await deps.settings.get("trading_mode", "research")
+    if trading_mode != "live":
+        raise HTTPException(status_code=409, detail=f"Trading mode is '{trading_mode}'; orders are only sent in live")
+    if not deps.broker.connected:
+        raise HTTPException(status_code=409, detail="Broker not connected")
+    if await deps.db.get_planner_state(SUBMITTED_TRADE_STATE_KEY) is not None:
+        raise HTTPException(status_code=409, detail="Previous submitted trade is awaiting broker confirmation")
+    if await deps.broker.has_pending_orders():
+        raise HTTPException(status_code=409, detail="Broker has pending orders")
+
+    symbol = await IdentifierService(deps.db).canonical(symbol)
+    open_symbols = await get_open_market_symbols(deps.broker, deps.db)
+    planner = Planner(db=deps.db, broker=deps.broker)
+    recommendations = await planner.get_recommendations(eligible_symbols=open_symbols)
+    key = recommendation_key(symbol, action)
+    rec = next((r for r in recommendations if recommendation_key(r.symbol, r.action) == key), None)
+    if rec is None or rec.symbol not in open_symbols:
+        raise HTTPException(status_code=404, detail=f"No current {action} recommendation for {symbol}")
+
+    portfolio = Portfolio(
+        db=deps.db,
+        broker=deps.broker,
+        settings=deps.settings,
+        currency=deps.currency,
+    )
+    blocked = await liquidity_block_reason(portfolio, rec)
+    if blocked:
+        raise HTTPException(status_code=409, detail=f"Buy of {symbol} blocked: {blocked}")
+
+    await clear_rejection(deps.db, symbol, action)
+    order_id = await submit_trade(deps.db, deps.broker, rec, throttled=False)
+    if not order_id:
+        raise HTTPException(status_code=502, detail=f"Broker did not accept the {action} order for {symbol}")
+    return {"status": "submitted", "order_id": order_id, "recommendation": _serialize_recommendation(rec)}
+
+
 @router.get("/ideal")
 async def get_ideal_portfolio(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
@@ -185,3 +344,289 @@ async def get_rebalance_summary() -> dict:
     """Get summary of portfolio alignment with ideal allocations."""
     planner = Planner()
     return await planner.get_rebalance_summary()
+
+
+@router.get("/drift")
+async def get_drift_bands() -> dict:
+    """Get current drift per configured drift band and which bands are breached."""
+    planner = Planner()
+    return await planner.get_drift_bands()
+
+
+@router.get("/targets")
+async def get_position_targets() -> dict:
+    """Get every position target with its source, mode and current drift."""
+    planner = Planner()
+    return await planner.get_position_targets()
+
+
+async def _target_changes(deps: CommonDependencies, data: dict) -> tuple[list[dict], dict]:
+    securities = await deps.db.get_all_securities(active_only=False)
+    try:
+        return securities, validate_target_changes(data, securities)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+def _target_diff(securities: list[dict], changes: dict) -> list[dict]:
+    before = {s["symbol"]: position_target(s) for s in securities if s["symbol"] in changes}
+    return [
+        {"symbol": symbol, "before": before.get(symbol), "after": target}
+        for symbol, target in sorted(changes.items())
+    ]
+
+
+@router.put("/targets")
+async def put_position_targets(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: dict,
+) -> dict:
+    """Set or clear several manual position targets at once.
+
+    Body: {"targets": {"AAPL.US": {"target_pct": 8, "mode": "hard"}, "KO.US": null}}
+    """
+    securities, changes = await _target_changes(deps, data)
+    now = utc_now_iso()
+    for symbol, target in changes.items():
+        if target is None:
+            await deps.db.clear_position_target(symbol)
+        else:
+            await deps.db.set_position_target(symbol, **target, source="manual", updated_at=now)
+    await deps.db.invalidate_planner_cache()
+    return {
+        "status": "ok",
+        "changes": _target_diff(securities, changes),
+        "manual_total_pct": manual_target_total(with_target_changes(securities, changes, now)),
+    }
+
+
+@router.post("/targets/preview")
+async def preview_position_targets(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: dict,
+) -> dict:
+    """Trades and costs a bulk target edit would lead to, without storing it.
+
+    Body: as for PUT /targets.
+    """
+    securities, changes = await _target_changes(deps, data)
+    overrides = DryRunOverrides(position_targets=changes)
+    try:
+        recommendations, _plan, state = await run_dry_run(overrides, db=deps.db, broker=deps.broker)
+    except AccountExposureError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+    return {
+        "dry_run": True,
+        "changes": _target_diff(securities, changes),
+        "manual_total_pct": manual_target_total(with_target_changes(securities, changes)),
+        "recommendations": [_serialize_recommendation(r) for r in recommendations],
+        "summary": await _dry_run_summary(deps.db, recommendations, state),
+    }
+
+
+@router.post("/targets/adopt")
+async def adopt_ideal_targets(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Store today's ideal weights as optimizer targets for drift tracking.
+
+    Manual targets are left alone; optimizer targets of securities no longer in
+    the ideal portfolio are removed.
+    """
+    planner = Planner()
+    ideal = await planner.calculate_ideal_portfolio()
+    now = datetime.now(timezone.utc).isoformat()
+    adopted = 0
+    cleared = 0
+    for sec in await deps.db.get_all_securities(active_only=False):
+        symbol = sec["symbol"]
+        target = position_target(sec)
+        if target and target["source"] == "manual":
+            continue
+        if ideal.get(symbol, 0.0) > 0:
+            await deps.db.set_position_target(
+                symbol,
+                target_pct=round(ideal[symbol] * 100, 4),
+                mode="soft",
+                source="optimizer",
+                updated_at=now,
+            )
+            adopted += 1
+        elif target and await deps.db.clear_position_target(symbol):
+            cleared += 1
+    return {"status": "ok", "adopted": adopted, "cleared": cleared}
+
+
+@planning_router.post("/dry-run")
+async def planning_dry_run(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: dict | None = None,
+) -> dict:
+    """Run the full planner with what-if overrides without persisting anything.
+
+    Body (all optional): {"extra_cash_eur": 5000, "exclude_symbols": ["AAPL.US"],
+    "settings": {"strategy_min_opp_score": 0.3}, "min_trade_value": 250}
+    """
+    try:
+        overrides = DryRunOverrides.from_payload(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+    try:
+        recommendations, plan, state = await run_dry_run(overrides, db=deps.db, broker=deps.broker)
+    except AccountExposureError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+    return {
+        "dry_run": True,
+        "overrides": {
+            "extra_cash_eur": overrides.extra_cash_eur,
+            "exclude_symbols": overrides.exclude_symbols,
+            "settings": overrides.settings,
+            "min_trade_value": overrides.min_trade_value,
+            "position_targets": overrides.position_targets,
+        },
+        "recommendations": [_serialize_recommendation(r) for r in recommendations],
+        "plan": _serialize_plan(plan),
+        "summary": await _dry_run_summary(deps.db, recommendations, state),
+    }
+
+
+async def _trade_costs(db, recommendations) -> list[dict]:
+    """Serialized recommendations with each trade's fee under the cost model."""
+    model = await FeeCalculator().get_cost_model()
+    rows = []
+    for rec, trade in zip(recommendations, await _fee_trades(db, recommendations), strict=True):
+        fee = model.cost(trade["value_eur"], market_id=trade["market_id"], currency=trade["currency"])
+        rows.append({**_serialize_recommendation(rec), "fee_eur": round(fee, 2)})
+    return rows
+
+
+@planning_router.post("/compare")
+async def compare_allocations(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: dict,
+) -> dict:
+    """Compare two position target sets before adopting one; nothing is persisted.
+
+    Body: {"current": {...}?, "proposed": {"AAPL.US": {"target_pct": 8, "mode": "hard"}, "KO.US": null},
+    "min_trade_value"?: 250}
+    """
+    try:
+        current, proposed = validate_comparison(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+    try:
+        scenarios = await run_comparison(deps.db, deps.broker, current, proposed)
+    except AccountExposureError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+    result: dict = {"dry_run": True}
+    for name, scenario in scenarios.items():
+        recommendations = scenario["recommendations"]
+        result[name] = {
+            "position_targets": scenario["overrides"].position_targets,
+            "allocation": [
+                {"symbol": t.symbol, "target_pct": round(t.target_allocation * 100, 4)}
+                for t in sorted(scenario["plan"].targets, key=lambda t: -t.target_allocation)
+                if t.target_allocation > 0
+            ],
+            "metrics": scenario["metrics"],
+            "recommendations": await _trade_costs(deps.db, recommendations),
+            "summary": await _dry_run_summary(deps.db, recommendations, scenario["state"]),
+        }
+    result["delta"] = metric_delta(scenarios["current"]["metrics"], scenarios["proposed"]["metrics"])
+    return result
+
+
+@planning_router.get("/snapshots")
+async def get_planning_snapshots(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 50,
+) -> dict:
+    """List recorded planning cycles, newest first."""
+    limit = max(1, min(limit, 500))
+    return {"snapshots": await deps.db.get_planner_snapshots(limit=limit)}
+
+
+@planning_router.get("/diff")
+async def get_planning_diff(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    from_id: Annotated[Optional[int], Query(alias="from")] = None,
+    to_id: Annotated[Optional[int], Query(alias="to")] = None,
+) -> dict:
+    """Explain which planner inputs changed between two cycles and how the top sequence moved.
+
+    Defaults to the two most recent snapshots; `from`/`to` are snapshot ids.
+    """
+    if to_id is None:
+        latest = await deps.db.get_planner_snapshots(limit=1)
+        if not latest:
+            raise HTTPException(status_code=404, detail="No planner snapshots recorded yet")
+        to_id = latest[0]["id"]
+    if from_id is None:
+        previous = await deps.db.get_planner_snapshots(limit=1, before_id=to_id)
+        if not previous:
+            raise HTTPException(status_code=404, detail=f"No planner snapshot recorded before {to_id}")
+        from_id = previous[0]["id"]
+
+    before = await deps.db.get_planner_snapshot(from_id)
+    after = await deps.db.get_planner_snapshot(to_id)
+    for snapshot_id, snapshot in ((from_id, before), (to_id, after)):
+        if snapshot is None:
+            raise HTTPException(status_code=404, detail=f"Planner snapshot {snapshot_id} not found")
+    return diff_snapshots(before, after)
+
+
+@planning_router.get("/allocations")
+async def get_allocation_runs(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 50,
+) -> dict:
+    """List recorded ideal-portfolio runs with their target weights, newest first."""
+    limit = max(1, min(limit, 500))
+    return {"runs": await deps.db.get_allocation_runs(limit=limit)}
+
+
+@planning_router.get("/allocations/compare")
+async def compare_allocation_runs(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    from_id: Annotated[Optional[int], Query(alias="from")] = None,
+    to_id: Annotated[Optional[int], Query(alias="to")] = None,
+) -> dict:
+    """Explain how target weights and constraints changed between two allocation runs.
+
+    Defaults to the two most recent runs; `from`/`to` are run ids.
+    """
+    if to_id is None:
+        latest = await deps.db.get_allocation_runs(limit=1)
+        if not latest:
+            raise HTTPException(status_code=404, detail="No allocation runs recorded yet")
+        to_id = latest[0]["id"]
+    if from_id is None:
+        previous = await deps.db.get_allocation_runs(limit=1, before_id=to_id)
+        if not previous:
+            raise HTTPException(status_code=404, detail=f"No allocation run recorded before {to_id}")
+        from_id = previous[0]["id"]
+
+    before = await deps.db.get_allocation_run(from_id)
+    after = await deps.db.get_allocation_run(to_id)
+    for run_id, run in ((from_id, before), (to_id, after)):
+        if run is None:
+            raise HTTPException(status_code=404, detail=f"Allocation run {run_id} not found")
+    return compare_runs(before, after)
+
+
+@planning_router.get("/allocations/drift")
+async def get_allocation_drift(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    symbols: Optional[str] = None,
+    limit: int = 100,
+) -> dict:
+    """Target weight of each symbol across the most recent allocation runs, oldest first.
+
+    `symbols` is a comma-separated list; every symbol held in those runs by default.
+    """
+    limit = max(1, min(limit, 1000))
+    runs = list(reversed(await deps.db.get_allocation_runs(limit=limit)))
+    wanted = [symbol.strip() for symbol in symbols.split(",") if symbol.strip()] if symbols else None
+    return weight_drift(runs, wanted)
@@ -9,7 +9,7 @@ from typing import Any
 from fastapi import APIRouter, Depends, HTTPException
 from typing_extensions import Annotated
 
-from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.api.dependencies import CommonDependencies, get_common_deps, reader
 from sentinel.freedom24_web import Freedom24WebClient
 from sentinel.services.portfolio import PortfolioService
 from sentinel.services.valuation import PortfolioValuationService
@@ -17,6 +17,7 @@ from sentinel.services.valuation import PortfolioValuationService
 logger = logging.getLogger(__name__)
 
 router = APIRouter(prefix="/portfolio", tags=["portfolio"])
+analytics_router = APIRouter(prefix="/analytics", tags=["analytics"])
 
 PERIOD_WINDOWS = {"1D": 1, "1W": 7, "1M": 30, "3M": 90, "6M": 180, "1Y": 365}
 PNL_HISTORY_WINDOWS = {"3M": 90, "6M": 180, "1Y": 365, "ALL": None}
@@ -420,6 +421,115 @@ async def sync_portfolio() -> dict[str, str]:
     return await service.sync_portfolio()
 
 
+@router.get("/ledger/replay")
+async def replay_ledger(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    as_of: str | None = None,
+) -> dict[str, Any]:
+    """Positions, cost basis and cash rebuilt purely from trades and cash flows.
+
+    Pass ?as_of=YYYY-MM-DD to replay only events up to that date.
+    """
+    from sentinel.ledger import LedgerService
+
+    if as_of is not None:
+        try:
+            as_of = date_type.fromisoformat(as_of).isoformat()
+        except ValueError as e:
+            raise HTTPException(status_code=400, detail="as_of must be YYYY-MM-DD") from e
+    return await LedgerService(deps.db).replay(as_of=as_of)
+
+
+@router.get("/ledger/consistency")
+async def check_ledger_consistency(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Compare the replayed ledger with the live positions and cash balances."""
+    from sentinel.ledger import LedgerService
+
+    return await LedgerService(deps.db).check_consistency()
+
+
+@router.get("/ledger/negative-balance")
+async def get_negative_balance_analysis(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    currency: str | None = None,
+) -> dict[str, Any]:
+    """The latest negative-balance event with its root causes, or a fresh analysis of one currency."""
+    from sentinel.ledger import NEGATIVE_BALANCE_STATE_KEY, LedgerService
+
+    if currency is None:
+        return {"event": await deps.db.get_planner_state(NEGATIVE_BALANCE_STATE_KEY)}
+    currency = currency.strip().upper()
+    if not currency:
+        raise HTTPException(status_code=400, detail="currency must be a currency code")
+    balances = await deps.db.get_cash_balances()
+    return await LedgerService(reader(deps.db)).explain_negative_balance(currency, live_balance=balances.get(currency))
+
+
+@router.get("/earnings")
+async def get_upcoming_earnings(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    days: int = 30,
+) -> dict[str, Any]:
+    """Upcoming earnings dates of held positions within `days` days.
+
+    Each entry says whether the security is frozen by `earnings_freeze_days`.
+    """
+    from sentinel.earnings import upcoming_earnings
+
+    if not 1 <= days <= 365:
+        raise HTTPException(status_code=400, detail="days must be between 1 and 365")
+    return await upcoming_earnings(deps.db, deps.settings, days=days)
+
+
+@router.get("/concentration")
+async def get_concentration_breaches(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    status: str = "open",
+    limit: int = 100,
+) -> dict[str, Any]:
+    """Positions above the concentration alert levels, with how long each breach lasted.
+
+    Breaches are tracked after every planning:refresh; see `sentinel.concentration`.
+    """
+    from sentinel.concentration import ConcentrationMonitor, with_duration
+
+    if status not in ("open", "resolved", "all"):
+        raise HTTPException(status_code=400, detail="status must be open, resolved or all")
+    if not 1 <= limit <= 1000:
+        raise HTTPException(status_code=400, detail="limit must be between 1 and 1000")
+    db = reader(deps.db)
+    breaches = await db.get_concentration_breaches(status=status, limit=limit)
+    return {
+        "levels": await ConcentrationMonitor(db).levels(),
+        "breaches": [with_duration(breach) for breach in breaches],
+    }
+
+
+@router.get("/history")
+async def get_portfolio_history(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    resolution: str = "1d",
+    days: int | None = None,
+    positions: bool = False,
+) -> dict[str, Any]:
+    """Portfolio value series for charts, one point per hour, day or week.
+
+    Built from live valuation snapshots (`snapshot:valuation`), falling back to
+    reconstructed daily snapshots for older days.
+    """
+    from sentinel.services.history import PortfolioHistoryService
+
+    service = PortfolioHistoryService(
+        db=reader(deps.db), broker=deps.broker, currency=deps.currency, settings=deps.settings
+    )
+    try:
+        return await service.history(resolution, days, include_positions=positions)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
 @router.get("/composition")
 async def get_portfolio_composition(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
@@ -434,7 +544,7 @@ async def get_portfolio_composition(
     """
     from sentinel.portfolio_composition import build_composition
 
-    return await build_composition(deps.db, deps.currency, deps.settings)
+    return await build_composition(reader(deps.db), deps.currency, deps.settings)
 
 
 @router.get("/cagr")
@@ -442,7 +552,8 @@ async def get_portfolio_cagr(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
 ) -> dict[str, Any]:
     """Lightweight CAGR from inception for ambient display."""
-    snapshots = await deps.db.get_portfolio_snapshots()
+    db = reader(deps.db)
+    snapshots = await db.get_portfolio_snapshots()
     if not snapshots:
         return {"cagr": 0.0, "years": 0.0, "target": 11.0}
 
@@ -453,7 +564,7 @@ async def get_portfolio_cagr(
     final_value = positions_value + (data.get("cash_eur", 0.0) or 0.0)
 
     # Net deposits from card cash flows
-    cash_flows = await deps.db.get_cash_flows()
+    cash_flows = await db.get_cash_flows()
     total_deposits = 0.0
     for cf in cash_flows:
         if cf["type_id"] in ("card", "card_payout"):
@@ -501,14 +612,15 @@ async def get_portfolio_pnl_history(
     # ALL deliberately reads the complete snapshot history.
     # Snapshot maintenance is handled by scheduled jobs; avoid backfill work on request path.
     snapshot_days = days + 365 if days is not None else None
-    snapshots = await deps.db.get_portfolio_snapshots(snapshot_days)
+    db = reader(deps.db)
+    snapshots = await db.get_portfolio_snapshots(snapshot_days)
 
     if not snapshots:
         return {"snapshots": [], "summary": None}
 
     # Cumulative net-deposits lookup keyed by ISO date. Card deposits +
     # withdrawals (card_payout) only — that's what funds the account.
-    cash_flows = await deps.db.get_cash_flows()
+    cash_flows = await db.get_cash_flows()
     cf_sorted = sorted(
         [cf for cf in cash_flows if cf["type_id"] in ("card", "card_payout")],
         key=lambda cf: cf["date"],
@@ -614,7 +726,7 @@ async def get_portfolio_pnl_history(
     benchmark_symbol = await deps.settings.get("performance_benchmark_symbol", "VWCE.EU")
     # The benchmark is an investable ETF held in the `prices` table (e.g. VWCE.EU).
     benchmark_days = days + 365 + 10 if days is not None else None
-    benchmark_rows = await deps.db.get_prices(benchmark_symbol, days=benchmark_days)
+    benchmark_rows = await db.get_prices(benchmark_symbol, days=benchmark_days)
     benchmark_returns = benchmark_rolling_returns(
         benchmark_rows or [],
         [s["date"] for s in result_snapshots],
@@ -653,11 +765,12 @@ async def get_portfolio_period_stats(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
 ) -> dict[str, Any]:
     """Table-only portfolio period stats using live current value as the endpoint."""
-    cash_flows = await deps.db.get_cash_flows()
+    db = reader(deps.db)
+    cash_flows = await db.get_cash_flows()
 
     current_net_deposits = await _current_net_deposits_eur(deps)
     benchmark_symbol = await deps.settings.get("performance_benchmark_symbol", "VWCE.EU")
-    benchmark_rows = await deps.db.get_prices(benchmark_symbol)
+    benchmark_rows = await db.get_prices(benchmark_symbol)
     valuation = await PortfolioValuationService(db=deps.db, broker=deps.broker, currency=deps.currency).current()
     current_value = valuation["total_value_eur"]
 
@@ -688,3 +801,47 @@ async def get_portfolio_period_stats(
         "benchmark_symbol": benchmark_symbol,
         "period_stats": period_stats,
     }
+
+
+@analytics_router.get("/benchmark")
+async def get_benchmark_analytics(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    window_days: int = 365,
+    rolling_days: int = 90,
+) -> dict[str, Any]:
+    """Portfolio performance relative to each configured benchmark index.
+
+    Tracking difference, tracking error and beta over `window_days`, plus YTD
+    and a trailing `rolling_days` relative-performance series. See
+    `sentinel.benchmark_analytics` for the math.
+    """
+    from sentinel.benchmark_analytics import build_benchmark_analytics
+
+    if not 30 <= window_days <= 1825:
+        raise HTTPException(status_code=400, detail="window_days must be between 30 and 1825")
+    if not 5 <= rolling_days <= 365:
+        raise HTTPException(status_code=400, detail="rolling_days must be between 5 and 365")
+    return await build_benchmark_analytics(
+        reader(deps.db),
+        deps.currency,
+        deps.settings,
+        window_days=window_days,
+        rolling_days=rolling_days,
+    )
+
+
+@analytics_router.get("/fees")
+async def get_fee_analytics(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    trades_limit: int = 50,
+) -> dict[str, Any]:
+    """Trade commissions and account fees per month, year and symbol, and the annual cost projection.
+
+    `trades_limit` caps the per-trade attribution list (newest first). See
+    `sentinel.fee_analytics`.
+    """
+    from sentinel.fee_analytics import build_fee_analytics
+
+    if not 0 <= trades_limit <= 1000:
+        raise HTTPException(status_code=400, detail="trades_limit must be between 0 and 1000")
+    return await build_fee_analytics(reader(deps.db), deps.currency, trades_limit=trades_limit)
@@ -0,0 +1,57 @@
+"""Portfolio projection API routes."""
+
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException, Query
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.factors import build_factor_report
+from sentinel.projections import (
+    DEFAULT_PATHS,
+    DEFAULT_YEARS,
+    build_covariance_report,
+    build_projection,
+    validate_projection_params,
+)
+
+router = APIRouter(prefix="/projections", tags=["projections"])
+
+
+@router.get("/monte-carlo")
+async def get_monte_carlo_projection(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    years: int = Query(DEFAULT_YEARS),
+    paths: int = Query(DEFAULT_PATHS),
+    target_eur: float | None = Query(None),
+    monthly_deposit_eur: float | None = Query(None),
+) -> dict:
+    """Simulate the portfolio `years` ahead: yearly percentile bands and the odds of reaching a target."""
+    try:
+        validate_projection_params(years, paths, target_eur, monthly_deposit_eur)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return await build_projection(
+        deps.db,
+        deps.currency,
+        years=years,
+        paths=paths,
+        target_eur=target_eur,
+        monthly_deposit_eur=monthly_deposit_eur,
+    )
+
+
+@router.get("/covariance")
+async def get_covariance_diagnostics(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Compare the covariance estimators on the live portfolio's returns."""
+    return await build_covariance_report(deps.db, deps.currency)
+
+
+@router.get("/factors")
+async def get_factor_exposures(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """The live portfolio's exposures to the index factors."""
+    return await build_factor_report(deps.db, deps.currency)
@@ -0,0 +1,82 @@
+"""Report export API routes."""
+
+from __future__ import annotations
+
+import json
+from datetime import date
+
+from fastapi import APIRouter, Depends, HTTPException, Response
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps, reader
+from sentinel.localization import load_locale
+from sentinel.notes import journal_markdown
+from sentinel.services.exports import LedgerExportService
+from sentinel.services.reports import REPORTS, ReportService
+
+router = APIRouter(prefix="/reports", tags=["reports"])
+
+
+def _download(content: str | bytes, filename: str, media_type: str) -> Response:
+    return Response(
+        content=content,
+        media_type=media_type,
+        headers={"Content-Disposition": f'attachment; filename="{filename}"'},
+    )
+
+
+@router.get("/export")
+async def export_report(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    report: str,
+    year: int | None = None,
+    month: int | None = None,
+) -> Response:
+    """
+    Download a report.
+
+    Query params:
+        report: positions (CSV), trades (CSV for a tax year), monthly (PDF),
+            ghostfolio (JSON), portfolio-performance (CSV) or journal (markdown research notes)
+        year: Tax year for trades (default: this year), or the monthly report's year (default: last month's)
+        month: Monthly report month (default: last month)
+
+    The monthly PDF is written in the `locale` setting; CSVs and JSON are not localized.
+    """
+    if report not in REPORTS:
+        raise HTTPException(status_code=400, detail=f"report must be one of {', '.join(REPORTS)}")
+    today = date.today()
+    db = reader(deps.db)
+
+    if report == "ghostfolio":
+        exported = await LedgerExportService(db=db, currency=deps.currency).ghostfolio()
+        return _download(json.dumps(exported, indent=2), f"ghostfolio-{today.isoformat()}.json", "application/json")
+
+    if report == "portfolio-performance":
+        content = await LedgerExportService(db=db, currency=deps.currency).portfolio_performance_csv()
+        return _download(content, f"portfolio-performance-{today.isoformat()}.csv", "text/csv")
+
+    if report == "journal":
+        return _download(await journal_markdown(db), f"journal-{today.isoformat()}.md", "text/markdown")
+
+    service = ReportService(db=db, broker=deps.broker, currency=deps.currency, locale=await load_locale(deps.settings))
+
+    if report == "positions":
+        return _download(await service.positions_csv(), f"positions-{today.isoformat()}.csv", "text/csv")
+
+    if report == "trades":
+        year = today.year if year is None else year
+        try:
+            content = await service.trades_csv(year)
+        except ValueError as e:
+            raise HTTPException(status_code=400, detail=str(e)) from e
+        return _download(content, f"trades-{year}.csv", "text/csv")
+
+    last_month = date.fromordinal(today.replace(day=1).toordinal() - 1)
+    year = last_month.year if year is None else year
+    month = last_month.month if month is None else month
+    try:
+        pdf = await service.monthly_pdf(year, month)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return _download(pdf, f"summary-{year}-{month:02d}.pdf", "application/pdf")
@@ -2,17 +2,32 @@
 
 import inspect
 import math
+import time
 from typing import Any
 
-from fastapi import APIRouter, Depends, HTTPException
+from fastapi import APIRouter, Depends, HTTPException, Response
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.duplicates import find_duplicates, merge_securities
+from sentinel.exclusions import exclusion_matches, load_exclusion_lists
+from sentinel.identifiers import IdentifierService
 from sentinel.markets import get_open_market_symbols
+from sentinel.notes import (
+    MAX_ATTACHMENTS_PER_SECURITY,
+    load_note_summaries,
+    note_history,
+    validate_attachment,
+    validate_note,
+)
 from sentinel.planner.preferences import preference_snapshot, utc_now_iso
+from sentinel.planner.targets import position_target, validate_position_target
+from sentinel.price_sanity import STATUSES as QUARANTINE_STATUSES
+from sentinel.price_sanity import resolve_quarantine
 from sentinel.security import Security
 from sentinel.strategy import classify_lot_size, compute_contrarian_signal
 from sentinel.universe import apply_removed_from_favorites_rule, import_security_from_broker
+from sentinel.utils.fees import FeeCalculator
 
 router = APIRouter(prefix="/securities", tags=["securities"])
 prices_router = APIRouter(prefix="/prices", tags=["prices"])
@@ -62,6 +77,7 @@ async def _security_payload(symbol: str, deps: CommonDependencies) -> dict[str,
         raise HTTPException(status_code=404, detail="Security not found")
     position = await deps.db.get_position(symbol)
     pref = preference_snapshot(sec)
+    excluded_by = exclusion_matches(sec, await load_exclusion_lists(deps.db))
     return {
         "symbol": sec.get("symbol"),
         "name": sec.get("name"),
@@ -73,6 +89,10 @@ async def _security_payload(symbol: str, deps: CommonDependencies) -> dict[str,
         "active": sec.get("active", 1),
         "allow_buy": sec.get("allow_buy", 1),
         "allow_sell": sec.get("allow_sell", 1),
+        "archived_at": sec.get("archived_at"),
+        "archive_reason": sec.get("archive_reason"),
+        "excluded_by": excluded_by,
+        "position_target": position_target(sec),
         "user_multiplier": pref["user_multiplier"],
         "user_multiplier_age_weeks": pref["user_multiplier_age_weeks"],
         "user_multiplier_updated_at": sec.get("user_multiplier_updated_at"),
@@ -80,6 +100,12 @@ async def _security_payload(symbol: str, deps: CommonDependencies) -> dict[str,
         "user_multiplier_analysis": sec.get("user_multiplier_analysis"),
         "universe_source": sec.get("universe_source"),
         "universe_last_seen_at": sec.get("universe_last_seen_at"),
+        "volume": {
+            "adv_volume": sec.get("adv_volume"),
+            "adv_turnover_eur": sec.get("adv_turnover_eur"),
+            "illiquid": bool(sec.get("illiquid")),
+            "updated_at": sec.get("adv_updated_at"),
+        },
         "quantity": position.get("quantity", 0) if position else 0,
         "current_price": position.get("current_price") if position else None,
     }
@@ -182,6 +208,60 @@ async def get_all_aliases(
     ]
 
 
+@router.get("/archived")
+async def get_archived_securities(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> list[dict]:
+    """Securities that left the universe, most recently archived first; their history is kept."""
+    # Only what archive_security took out; securities deactivated by hand are not archived.
+    securities = [s for s in await deps.db.get_all_securities(active_only=False) if s.get("archived_at")]
+    securities.sort(key=lambda s: s["symbol"])
+    securities.sort(key=lambda s: s.get("archived_at") or "", reverse=True)
+    return [
+        {
+            "symbol": sec["symbol"],
+            "name": sec.get("name"),
+            "currency": sec.get("currency", "EUR"),
+            "archived_at": sec.get("archived_at"),
+            "archive_reason": sec.get("archive_reason"),
+        }
+        for sec in securities
+    ]
+
+
+@router.get("/duplicates")
+async def get_duplicate_securities(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Securities that look stored twice, grouped under a suggested canonical record."""
+    return {"groups": await find_duplicates(deps.db)}
+
+
+@router.post("/duplicates/merge")
+async def merge_duplicate_security(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Fold a duplicate security into its canonical record (`dry_run` only counts the rows)."""
+    canonical, duplicate = data.get("canonical"), data.get("duplicate")
+    if not isinstance(canonical, str) or not canonical.strip():
+        raise HTTPException(status_code=400, detail="'canonical' is required")
+    if not isinstance(duplicate, str) or not duplicate.strip():
+        raise HTTPException(status_code=400, detail="'duplicate' is required")
+    dry_run = data.get("dry_run", False)
+    if not isinstance(dry_run, bool):
+        raise HTTPException(status_code=400, detail="'dry_run' must be a boolean")
+    try:
+        result = await merge_securities(deps.db, canonical.strip(), duplicate.strip(), dry_run=dry_run)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    if not dry_run:
+        await _invalidate_planner_cache(deps)
+    return result
+
+
 @router.post("/preference")
 async def update_security_preference(
     data: dict,
@@ -316,6 +396,276 @@ async def sync_prices(
     return {"synced": count}
 
 
+@router.put("/{symbol}/target")
+async def put_position_target(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Set a manual position target: {"target_pct": 0-100, "mode": "hard" | "soft"}."""
+    if not await deps.db.get_security(symbol):
+        raise HTTPException(status_code=404, detail="Security not found")
+    try:
+        target = validate_position_target(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+    await deps.db.set_position_target(symbol, **target, source="manual", updated_at=utc_now_iso())
+    await _invalidate_planner_cache(deps)
+    return await _security_payload(symbol, deps)
+
+
+@router.delete("/{symbol}/target")
+async def delete_position_target(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Remove a security's position target, manual or optimizer."""
+    if not await deps.db.clear_position_target(symbol):
+        raise HTTPException(status_code=404, detail="Position target not found")
+    await _invalidate_planner_cache(deps)
+    return {"status": "ok"}
+
+
+@router.get("/{symbol}/earnings")
+async def get_security_earnings(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> list[dict]:
+    """Get recorded earnings dates for a security, oldest first."""
+    return await deps.db.get_earnings_dates(symbol=symbol)
+
+
+@router.put("/{symbol}/earnings")
+async def put_security_earnings(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> list[dict]:
+    """Record an earnings date: {"date": "YYYY-MM-DD", "source": "..."}."""
+    from sentinel.earnings import validate_earnings_date, validate_source
+
+    if not await deps.db.get_security(symbol):
+        raise HTTPException(status_code=404, detail="Security not found")
+    try:
+        earnings_date = validate_earnings_date(data.get("date"))
+        source = validate_source(data.get("source"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+    await deps.db.upsert_earnings_date(symbol, earnings_date, source)
+    await _invalidate_planner_cache(deps)
+    return await deps.db.get_earnings_dates(symbol=symbol)
+
+
+@router.delete("/{symbol}/earnings/{earnings_date}")
+async def delete_security_earnings(
+    symbol: str,
+    earnings_date: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Remove a recorded earnings date."""
+    if not await deps.db.delete_earnings_date(symbol, earnings_date):
+        raise HTTPException(status_code=404, detail="Earnings date not found")
+    await _invalidate_planner_cache(deps)
+    return {"status": "ok"}
+
+
+@router.get("/{symbol}/peers")
+async def get_security_peers(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    match: str = "industry",
+    limit: int = 5,
+) -> dict[str, Any]:
+    """Top-scored securities in the same industry and/or geography, side by side.
+
+    `symbol` may also be an ISIN.
+    """
+    from sentinel.services.peers import PeerService
+
+    service = PeerService(deps.db)
+    security = await service.find_security(symbol)
+    if not security:
+        raise HTTPException(status_code=404, detail="Security not found")
+    try:
+        return await service.peers(security, match=match, limit=limit)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.get("/{symbol}/news")
+async def get_security_news(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 20,
+) -> dict[str, Any]:
+    """Get a security's news sentiment and its most recent headlines."""
+    if not 1 <= limit <= 200:
+        raise HTTPException(status_code=400, detail="limit must be between 1 and 200")
+    sentiment = await deps.db.get_news_sentiment([symbol])
+    return {
+        "symbol": symbol,
+        "sentiment": sentiment.get(symbol),
+        "headlines": await deps.db.get_news_headlines(symbol, limit=limit),
+    }
+
+
+@router.get("/{symbol}/indicators")
+async def get_security_indicators(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    days: int = 60,
+) -> dict[str, Any]:
+    """Technical indicators computed by the security:technical job, newest first.
+
+    `symbol` may also be an ISIN.
+    """
+    if not 1 <= days <= 1000:
+        raise HTTPException(status_code=400, detail="days must be between 1 and 1000")
+    resolved = await IdentifierService(deps.db).resolve(symbol)
+    if resolved is None:
+        raise HTTPException(status_code=404, detail="Security not found")
+    history = [
+        {key: value for key, value in row.items() if key not in ("symbol", "state")}
+        for row in await deps.db.get_indicators(resolved, days=days)
+    ]
+    return {"symbol": resolved, "latest": history[0] if history else None, "history": history}
+
+
+@router.get("/{symbol}/factors")
+async def get_security_factors(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Factor exposures stored by the security:factors job.
+
+    `symbol` may also be an ISIN.
+    """
+    resolved = await IdentifierService(deps.db).resolve(symbol)
+    if resolved is None:
+        raise HTTPException(status_code=404, detail="Security not found")
+    row = (await deps.db.get_factor_exposures([resolved])).get(resolved)
+    return {"symbol": resolved, "exposures": {k: v for k, v in row.items() if k != "symbol"} if row else None}
+
+
+async def _notes_symbol(symbol: str, deps: CommonDependencies) -> str:
+    resolved = await IdentifierService(deps.db).resolve(symbol)
+    if resolved is None:
+        raise HTTPException(status_code=404, detail="Security not found")
+    return resolved
+
+
+async def _notes_payload(symbol: str, deps: CommonDependencies) -> dict[str, Any]:
+    revisions = await deps.db.get_security_note_history(symbol)
+    current = revisions[0] if revisions and revisions[0]["body"] else None
+    return {
+        "symbol": symbol,
+        "note": {"body": current["body"], "updated_at": current["created_at"]} if current else None,
+        "revisions": len(revisions),
+        "attachments": await deps.db.get_security_attachments(symbol),
+    }
+
+
+@router.get("/{symbol}/notes")
+async def get_security_notes(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """The security's research note and attachments. `symbol` may also be an ISIN."""
+    return await _notes_payload(await _notes_symbol(symbol, deps), deps)
+
+
+@router.put("/{symbol}/notes")
+async def put_security_notes(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Save the note as a new revision; an empty body clears it."""
+    resolved = await _notes_symbol(symbol, deps)
+    try:
+        body = validate_note(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    revisions = await deps.db.get_security_note_history(resolved)
+    if body != (revisions[0]["body"] if revisions else ""):
+        await deps.db.add_security_note(resolved, body, created_at=int(time.time()))
+    return await _notes_payload(resolved, deps)
+
+
+@router.get("/{symbol}/notes/history")
+async def get_security_notes_history(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Note revisions and attachment changes, newest first."""
+    resolved = await _notes_symbol(symbol, deps)
+    history = note_history(
+        await deps.db.get_security_note_history(resolved),
+        await deps.db.get_security_attachments(resolved, include_deleted=True),
+    )
+    return {"symbol": resolved, "history": history}
+
+
+@router.post("/{symbol}/attachments")
+async def add_security_attachment(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Attach a small file (base64 `content`) to the security."""
+    resolved = await _notes_symbol(symbol, deps)
+    try:
+        filename, content_type, content = validate_attachment(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if len(await deps.db.get_security_attachments(resolved)) >= MAX_ATTACHMENTS_PER_SECURITY:
+        raise HTTPException(
+            status_code=400, detail=f"a security has at most {MAX_ATTACHMENTS_PER_SECURITY} attachments"
+        )
+    attachment_id = await deps.db.add_security_attachment(
+        resolved, filename, content_type, content, created_at=int(time.time())
+    )
+    attachment = await deps.db.get_security_attachment(attachment_id)
+    return {k: v for k, v in attachment.items() if k != "content"}
+
+
+async def _attachment(symbol: str, attachment_id: int, deps: CommonDependencies) -> dict:
+    resolved = await _notes_symbol(symbol, deps)
+    attachment = await deps.db.get_security_attachment(attachment_id)
+    if not attachment or attachment["symbol"] != resolved or attachment["deleted_at"] is not None:
+        raise HTTPException(status_code=404, detail="Attachment not found")
+    return attachment
+
+
+@router.get("/{symbol}/attachments/{attachment_id}")
+async def download_security_attachment(
+    symbol: str,
+    attachment_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> Response:
+    """Download an attachment."""
+    attachment = await _attachment(symbol, attachment_id, deps)
+    return Response(
+        content=attachment["content"],
+        media_type=attachment["content_type"],
+        headers={"Content-Disposition": f'attachment; filename="{attachment["filename"]}"'},
+    )
+
+
+@router.delete("/{symbol}/attachments/{attachment_id}")
+async def delete_security_attachment(
+    symbol: str,
+    attachment_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Delete an attachment; it stays listed in the note history."""
+    await _attachment(symbol, attachment_id, deps)
+    await deps.db.delete_security_attachment(attachment_id, deleted_at=int(time.time()))
+    return {"status": "ok"}
+
+
 # Prices router (separate prefix)
 @prices_router.post("/sync-all")
 async def sync_all_prices(
@@ -328,6 +678,66 @@ async def sync_all_prices(
     return {"status": "ok"}
 
 
+@prices_router.get("/quarantine")
+async def get_price_quarantine(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    status: str = "pending",
+    symbol: str | None = None,
+    limit: int = 100,
+) -> list[dict]:
+    """Incoming prices and quotes held back by the sanity check, newest first."""
+    if status not in (*QUARANTINE_STATUSES, "all"):
+        raise HTTPException(status_code=400, detail=f"status must be one of {', '.join(QUARANTINE_STATUSES)} or all")
+    return await deps.db.get_price_quarantine(
+        status=None if status == "all" else status, symbol=symbol, limit=max(1, min(limit, 1000))
+    )
+
+
+@prices_router.post("/quarantine/{entry_id}/accept")
+async def accept_quarantined_price(
+    entry_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Write a quarantined value after all."""
+    entry = await resolve_quarantine(deps.db, entry_id, accept=True)
+    if entry is None:
+        raise HTTPException(status_code=404, detail="Pending quarantine entry not found")
+    return entry
+
+
+@prices_router.post("/quarantine/{entry_id}/reject")
+async def reject_quarantined_price(
+    entry_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Discard a quarantined value; the same value is not quarantined again."""
+    entry = await resolve_quarantine(deps.db, entry_id, accept=False)
+    if entry is None:
+        raise HTTPException(status_code=404, detail="Pending quarantine entry not found")
+    return entry
+
+
+@prices_router.get("/sync-reports")
+async def get_price_sync_reports(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 20,
+) -> list[dict]:
+    """Recent price sync reports, newest first."""
+    return await deps.db.get_price_sync_reports(limit=max(1, min(limit, 100)))
+
+
+@prices_router.get("/sync-reports/{sync_id}")
+async def get_price_sync_report(
+    sync_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """One price sync report with the candles it rejected."""
+    report = await deps.db.get_price_sync_report(sync_id)
+    if report is None:
+        raise HTTPException(status_code=404, detail="Sync report not found")
+    return report
+
+
 # Unified view router (under /api/unified)
 unified_router = APIRouter(prefix="/unified", tags=["unified"])
 
@@ -359,6 +769,8 @@ async def get_unified_view(
         return []
 
     all_symbols = [sec["symbol"] for sec in securities]
+    exclusion_lists = await load_exclusion_lists(deps.db)
+    notes_map = await load_note_summaries(deps.db)
 
     # Fetch all data sources
     portfolio = Portfolio(
@@ -410,18 +822,20 @@ async def get_unified_view(
     for symbol, raw_prices in all_prices_raw.items():
         all_prices_validated[symbol] = validator.validate_price_series_desc(raw_prices)
 
-    fee_fixed_raw = await deps.settings.get("transaction_fee_fixed", 2.0)
-    fee_pct_raw = await deps.settings.get("transaction_fee_percent", 0.2)
+    cost_model = await FeeCalculator(deps.settings).get_cost_model()
+    securities_map = {sec["symbol"]: sec for sec in securities}
     lot_standard_raw = await deps.settings.get("strategy_lot_standard_max_pct", 0.08)
     lot_coarse_raw = await deps.settings.get("strategy_lot_coarse_max_pct", 0.30)
     min_opp_raw = await deps.settings.get("strategy_min_opp_score", 0.55)
-    fee_fixed = float(2.0 if fee_fixed_raw is None else fee_fixed_raw)
-    fee_pct = float(0.2 if fee_pct_raw is None else fee_pct_raw) / 100.0
     lot_standard_max_pct = float(0.08 if lot_standard_raw is None else lot_standard_raw)
     lot_coarse_max_pct = float(0.30 if lot_coarse_raw is None else lot_coarse_raw)
     min_opp_score = float(0.55 if min_opp_raw is None else min_opp_raw)
     total_plan_fees = sum(
-        fee_fixed + abs(float(rec.value_delta_eur)) * fee_pct
+        cost_model.cost(
+            abs(float(rec.value_delta_eur)),
+            market_id=(securities_map.get(rec.symbol) or {}).get("market_id"),
+            currency=rec.currency,
+        )
         for rec in recommendations
         if abs(float(rec.value_delta_eur)) > 0
     )
@@ -510,6 +924,7 @@ async def get_unified_view(
             fx_rate = float(maybe_fx_rate)
         except (TypeError, ValueError):
             fx_rate = 1.0
+        fee_fixed, fee_pct = cost_model.profile_for(sec.get("market_id"), sec_currency).linear_terms(sec_currency)
         lot_profile = classify_lot_size(
             price=current_price,
             lot_size=min_lot,
@@ -557,12 +972,15 @@ async def get_unified_view(
                 "active": sec.get("active", 1),
                 "allow_buy": sec.get("allow_buy", 1),
                 "allow_sell": sec.get("allow_sell", 1),
+                "excluded_by": exclusion_matches(sec, exclusion_lists),
+                "position_target": position_target(sec),
                 "user_multiplier": preference["user_multiplier"],
                 "user_multiplier_age_weeks": preference["user_multiplier_age_weeks"],
                 "user_multiplier_updated_at": sec.get("user_multiplier_updated_at"),
                 "user_multiplier_source": sec.get("user_multiplier_source"),
                 "user_multiplier_analysis": sec.get("user_multiplier_analysis"),
                 "aliases": sec.get("aliases"),
+                "notes": notes_map.get(symbol),
                 # Position data
                 "has_position": has_position,
                 "quantity": quantity,
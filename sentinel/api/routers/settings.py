@@ -2,64 +2,105 @@
 
 import inspect
 import time
-from datetime import datetime, timezone
 from typing import Any
 
 from fastapi import APIRouter, Depends, HTTPException
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.artifacts import ARTIFACT_RETENTION_DAYS_KEY, validate_artifact_retention_days
+from sentinel.benchmark_analytics import BENCHMARK_SYMBOLS_KEY, validate_benchmark_symbols
 from sentinel.broker import Broker
+from sentinel.clock import DRIFT_THRESHOLD_KEY, validate_drift_threshold
+from sentinel.concentration import CONCENTRATION_LEVELS_KEY, validate_concentration_levels
+from sentinel.covariance import ESTIMATOR_KEY, validate_covariance_estimator
+from sentinel.deployments import (
+    DEPLOY_CHANNEL_KEY,
+    DEPLOY_WINDOW_KEY,
+    validate_deploy_channel,
+    validate_deploy_window,
+)
+from sentinel.earnings import FREEZE_DAYS_KEY, validate_freeze_days
+from sentinel.execution import EXECUTION_POLICY_KEY, validate_execution_policy
+from sentinel.faults import FAULT_INJECTION_KEY, validate_fault_injection
+from sentinel.governor import RESOURCE_GOVERNOR_KEY, validate_resource_governor
+from sentinel.job_pause import WINDOWS_KEY, validate_maintenance_windows
 from sentinel.led import LEDController
-from sentinel.settings import REMOVED_SETTINGS
+from sentinel.led.alerts import AlertManager
+from sentinel.led.display_state import build_display_state
+from sentinel.led.modes import MODE_CODES, ModeManager
+from sentinel.led.ticker import (
+    TICKER_TEMPLATE_KEY,
+    TickerContentService,
+    render_template,
+    validate_ticker_template,
+)
+from sentinel.localization import LOCALE_KEY, validate_locale
+from sentinel.performance import BUDGET_FACTOR_KEY, BUDGETS_KEY, validate_budget_factor, validate_budgets
+from sentinel.planner.drift import DRIFT_BANDS_KEY, validate_drift_bands
+from sentinel.planner.income import (
+    INCOME_TARGET_KEY,
+    OBJECTIVE_KEY,
+    validate_income_target,
+    validate_planner_objective,
+)
+from sentinel.planner.liquidity import (
+    CURRENCY_FLOORS_KEY,
+    OBLIGATIONS_KEY,
+    validate_currency_floors,
+    validate_pending_obligations,
+)
+from sentinel.price_sanity import (
+    ALERT_COUNT_KEY,
+    MAX_MOVE_KEY,
+    validate_alert_count,
+    validate_max_move_pct,
+)
+from sentinel.projections import SHORT_HISTORY_KEY, validate_short_history_policy
+from sentinel.retention import RETENTION_POLICIES_KEY, validate_retention_policies
+from sentinel.settings import PLANNER_SETTING_KEYS, REMOVED_SETTINGS, STRATEGY_KEYS
+from sentinel.statement_import import TEMPLATES_KEY, validate_templates
+from sentinel.utils.fees import COST_PROFILES_KEY, validate_cost_profiles
+from sentinel.utils.timestamps import to_iso_utc
 
 router = APIRouter(prefix="/settings", tags=["settings"])
-STRATEGY_KEYS = {
-    "strategy_min_opp_score",
-    "strategy_ideal_qualifying_threshold",
-    "strategy_core_timing_min_score",
-    "strategy_core_timing_min_dip_score",
-    "strategy_fallback_wait_days",
-    "strategy_entry_t1_dd",
-    "strategy_entry_t2_dd",
-    "strategy_entry_t3_dd",
-    "strategy_entry_memory_days",
-    "strategy_memory_max_boost",
-    "strategy_opportunity_addon_threshold",
-    "strategy_max_opportunity_buys_per_cycle",
-    "strategy_max_new_opportunity_buys_per_cycle",
-}
-PLANNER_SETTING_KEYS = {
-    *STRATEGY_KEYS,
-    "clara_preference_strength",
-    "user_multiplier_decay_factor",
-    "user_multiplier_decay_interval_days",
-    "max_position_pct",
-    "min_position_pct",
-    "min_cash_buffer",
-    "target_cash_pct",
-    "min_trade_value",
-    "transaction_fee_fixed",
-    "transaction_fee_percent",
-    "max_dividend_reinvestment_boost",
-    "strategy_lot_standard_max_pct",
-    "strategy_lot_coarse_max_pct",
-    "strategy_coarse_max_new_lots_per_cycle",
-    "cooldown_enabled",
-    "strategy_opportunity_cooloff_days",
-    "strategy_core_cooloff_days",
-    "strategy_same_side_cooloff_days",
-    "strategy_rotation_time_stop_days",
-    "strategy_max_funding_sells_per_cycle",
-    "strategy_max_funding_turnover_pct",
-    "strategy_funding_conviction_bias",
-}
 
 # Global LED controller reference (set by app lifespan)
 _led_controller: LEDController | None = None
 LED_BRIDGE_HEALTH_KEY = "led_bridge_health"
 LED_BRIDGE_STALE_AFTER_SEC = 600
 
+# Structured settings are normalized (or rejected) before they are stored.
+SETTING_VALIDATORS = {
+    COST_PROFILES_KEY: validate_cost_profiles,
+    CURRENCY_FLOORS_KEY: validate_currency_floors,
+    OBLIGATIONS_KEY: validate_pending_obligations,
+    BENCHMARK_SYMBOLS_KEY: validate_benchmark_symbols,
+    DRIFT_BANDS_KEY: validate_drift_bands,
+    FREEZE_DAYS_KEY: validate_freeze_days,
+    DEPLOY_CHANNEL_KEY: validate_deploy_channel,
+    DEPLOY_WINDOW_KEY: validate_deploy_window,
+    DRIFT_THRESHOLD_KEY: validate_drift_threshold,
+    BUDGETS_KEY: validate_budgets,
+    BUDGET_FACTOR_KEY: validate_budget_factor,
+    MAX_MOVE_KEY: validate_max_move_pct,
+    ALERT_COUNT_KEY: validate_alert_count,
+    CONCENTRATION_LEVELS_KEY: validate_concentration_levels,
+    ESTIMATOR_KEY: validate_covariance_estimator,
+    SHORT_HISTORY_KEY: validate_short_history_policy,
+    INCOME_TARGET_KEY: validate_income_target,
+    OBJECTIVE_KEY: validate_planner_objective,
+    EXECUTION_POLICY_KEY: validate_execution_policy,
+    RETENTION_POLICIES_KEY: validate_retention_policies,
+    ARTIFACT_RETENTION_DAYS_KEY: validate_artifact_retention_days,
+    FAULT_INJECTION_KEY: validate_fault_injection,
+    RESOURCE_GOVERNOR_KEY: validate_resource_governor,
+    WINDOWS_KEY: validate_maintenance_windows,
+    TEMPLATES_KEY: validate_templates,
+    LOCALE_KEY: validate_locale,
+    TICKER_TEMPLATE_KEY: validate_ticker_template,
+}
+
 
 def set_led_controller(controller: LEDController | None) -> None:
     """Set the global LED controller reference."""
@@ -67,12 +108,6 @@ def set_led_controller(controller: LEDController | None) -> None:
     _led_controller = controller
 
 
-def _to_iso_utc(ts: int | None) -> str | None:
-    if ts is None:
-        return None
-    return datetime.fromtimestamp(ts, tz=timezone.utc).isoformat()
-
-
 def _to_int(value: Any, default: int | None = None, minimum: int | None = None) -> int | None:
     if value is None:
         return default
@@ -105,6 +140,9 @@ def _normalize_led_bridge_health(raw: Any) -> dict[str, Any]:
     last_error = data.get("last_error")
     watchdog_action = data.get("watchdog_action")
     app_instance = data.get("app_instance")
+    api_ok = data.get("api_ok")
+    api_failures = _to_int(data.get("api_failures"), default=0, minimum=0) or 0
+    last_api_success_ts = _to_int(data.get("last_api_success_ts"))
 
     stale_seconds: int | None = None
     if last_success_ts is not None:
@@ -116,23 +154,27 @@ def _normalize_led_bridge_health(raw: Any) -> dict[str, Any]:
         "bridge_ok": bridge_ok,
         "consecutive_failures": consecutive_failures,
         "last_attempt_ts": last_attempt_ts,
-        "last_attempt_at": _to_iso_utc(last_attempt_ts),
+        "last_attempt_at": to_iso_utc(last_attempt_ts),
         "last_success_ts": last_success_ts,
-        "last_success_at": _to_iso_utc(last_success_ts),
+        "last_success_at": to_iso_utc(last_success_ts),
         "last_error_ts": last_error_ts,
-        "last_error_at": _to_iso_utc(last_error_ts),
+        "last_error_at": to_iso_utc(last_error_ts),
         "last_error": str(last_error) if last_error else None,
         "watchdog_action": str(watchdog_action) if watchdog_action else None,
         "app_instance": str(app_instance) if app_instance else None,
+        "api_ok": bool(api_ok) if api_ok is not None else None,
+        "api_failures": api_failures,
+        "last_api_success_ts": last_api_success_ts,
+        "last_api_success_at": to_iso_utc(last_api_success_ts),
         "updated_at_ts": updated_at_ts,
-        "updated_at": _to_iso_utc(updated_at_ts),
+        "updated_at": to_iso_utc(updated_at_ts),
         "stale_seconds": stale_seconds,
         "stale_threshold_seconds": LED_BRIDGE_STALE_AFTER_SEC,
         "is_stale": is_stale,
     }
 
 
-async def _get_led_bridge_health() -> dict[str, Any]:
+async def load_led_bridge_health() -> dict[str, Any]:
     from sentinel.settings import Settings
 
     settings = Settings()
@@ -157,7 +199,14 @@ async def set_setting(
     """Set a setting value."""
     if key in REMOVED_SETTINGS:
         raise HTTPException(status_code=400, detail=f"Setting '{key}' has been removed")
-    await deps.settings.set(key, value.get("value"))
+    new_value = value.get("value")
+    validator = SETTING_VALIDATORS.get(key)
+    if validator is not None:
+        try:
+            new_value = validator(new_value)
+        except ValueError as e:
+            raise HTTPException(status_code=400, detail=str(e)) from e
+    await deps.settings.set(key, new_value)
     if key in PLANNER_SETTING_KEYS:
         invalidator = getattr(deps.db, "invalidate_planner_cache", None)
         if callable(invalidator):
@@ -250,10 +299,12 @@ async def get_led_status() -> dict[str, Any]:
     settings = Settings()
     enabled = await settings.get("led_display_enabled", False)
     broker = Broker()
-    bridge_health = await _get_led_bridge_health()
+    bridge_health = await load_led_bridge_health()
+    mode = await ModeManager(settings).resolve()
     return {
         "enabled": enabled,
         "running": _led_controller.is_running if _led_controller else False,
+        "mode": mode["mode"],
         "trade_count": _led_controller.trade_count if _led_controller else 0,
         "broker_connected": broker.connected,
         "bridge": bridge_health,
@@ -283,7 +334,7 @@ async def refresh_led_display() -> dict[str, Any]:
 @led_router.get("/bridge/health")
 async def get_led_bridge_health() -> dict[str, Any]:
     """Get health telemetry for the UNO Q hm.u bridge."""
-    return await _get_led_bridge_health()
+    return await load_led_bridge_health()
 
 
 @led_router.post("/bridge/health")
@@ -303,8 +354,148 @@ async def set_led_bridge_health(data: dict[str, Any]) -> dict[str, Any]:
         "last_error": normalized["last_error"],
         "watchdog_action": normalized["watchdog_action"],
         "app_instance": normalized["app_instance"],
+        "api_ok": normalized["api_ok"],
+        "api_failures": normalized["api_failures"],
+        "last_api_success_ts": normalized["last_api_success_ts"],
         "updated_at_ts": int(time.time()),
     }
 
     await settings.set(LED_BRIDGE_HEALTH_KEY, stored)
     return _normalize_led_bridge_health(stored)
+
+
+@led_router.get("/mode")
+async def get_led_mode() -> dict[str, Any]:
+    """Get the active display mode and whether it comes from the schedule or an override."""
+    mode = await ModeManager().resolve()
+    return {
+        **mode,
+        "mode_code": MODE_CODES[mode["mode"]],
+        "override_expires_at": to_iso_utc(mode["override_expires_at_ts"]),
+    }
+
+
+@led_router.put("/mode/override")
+async def set_led_mode_override(data: dict[str, Any]) -> dict[str, Any]:
+    """Force a display mode until the override expires.
+
+    Body: {"mode": "ticker" | "health" | "stats", "minutes": int}
+    """
+    try:
+        override = await ModeManager().set_override(data.get("mode"), data.get("minutes", 60))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {**override, "expires_at": to_iso_utc(override["expires_at_ts"])}
+
+
+@led_router.delete("/mode/override")
+async def clear_led_mode_override() -> dict[str, str]:
+    """Drop the manual override so the schedule applies again."""
+    await ModeManager().clear_override()
+    return {"status": "ok"}
+
+
+@led_router.get("/display-state")
+async def get_led_display_state(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Get the full desired display state as one versioned applyState frame."""
+    return await build_display_state(db=deps.db, broker=deps.broker, settings=deps.settings, currency=deps.currency)
+
+
+@led_router.get("/schedule")
+async def get_led_schedule() -> dict[str, Any]:
+    """Get the time-of-day display mode schedule."""
+    return {"slots": await ModeManager().get_schedule()}
+
+
+@led_router.put("/schedule")
+async def set_led_schedule(data: dict[str, Any]) -> dict[str, Any]:
+    """Replace the display mode schedule.
+
+    Body: {"slots": [{"mode": "ticker", "start": "09:00", "end": "17:30"}, ...]}
+    """
+    try:
+        slots = await ModeManager().set_schedule(data.get("slots"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"slots": slots}
+
+
+@led_router.get("/ticker/template")
+async def get_led_ticker_template() -> dict[str, Any]:
+    """Get the ticker template; an empty list means the built-in ticker lines."""
+    return {"template": await TickerContentService().template()}
+
+
+@led_router.put("/ticker/template")
+async def set_led_ticker_template(data: dict[str, Any]) -> dict[str, Any]:
+    """Replace the ticker template.
+
+    Body: {"template": [{"text": "PORTFOLIO {total_value}", "when": null}, ...]}
+    """
+    from sentinel.settings import Settings
+
+    try:
+        template = validate_ticker_template(data.get("template"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    await Settings().set(TICKER_TEMPLATE_KEY, template)
+    return {"template": template}
+
+
+@led_router.post("/ticker/preview")
+async def preview_led_ticker(data: dict[str, Any] | None = None) -> dict[str, Any]:
+    """Render a ticker template with live values without saving it.
+
+    Body: {"template": [...]} (optional; defaults to the stored template)
+    """
+    content = TickerContentService()
+    try:
+        if data and "template" in data:
+            template = validate_ticker_template(data["template"])
+        else:
+            template = await content.template()
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    locale = await content.locale()
+    trades = await content.trades()
+    values = await content.values(locale, trades)
+    lines = render_template(template, values) if template else await content.ticker_lines(locale, trades, template)
+    return {"template": template, "values": values, "lines": lines}
+
+
+@led_router.get("/buzzer")
+async def get_led_buzzer() -> dict[str, Any]:
+    """Get buzzer alert settings and the last queued alert."""
+    manager = AlertManager()
+    return {**(await manager.get_config()), **(await manager.pending())}
+
+
+@led_router.put("/buzzer")
+async def set_led_buzzer(data: dict[str, Any]) -> dict[str, Any]:
+    """Update buzzer settings.
+
+    Body: {"enabled": bool, "quiet_hours": {"start": "22:00", "end": "07:00"} | null}
+    """
+    manager = AlertManager()
+    if "quiet_hours" in data:
+        try:
+            await manager.set_quiet_hours(data["quiet_hours"])
+        except ValueError as e:
+            raise HTTPException(status_code=400, detail=str(e)) from e
+    if "enabled" in data:
+        await manager.set_enabled(data["enabled"])
+    return await manager.get_config()
+
+
+@led_router.post("/buzzer/test")
+async def trigger_led_buzzer_test(data: dict[str, Any]) -> dict[str, Any]:
+    """Queue a buzzer pattern regardless of quiet hours.
+
+    Body: {"pattern": "trade_executed" | "critical"}
+    """
+    try:
+        return await AlertManager().trigger(data.get("pattern"), force=True)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
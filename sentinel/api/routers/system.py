@@ -4,11 +4,12 @@ import json
 from dataclasses import asdict
 from typing import Any
 
-from fastapi import APIRouter, Depends
-from fastapi.responses import StreamingResponse
+from fastapi import APIRouter, Depends, HTTPException, Response
+from fastapi.responses import PlainTextResponse, StreamingResponse
 from typing_extensions import Annotated
 
-from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.api.dependencies import CommonDependencies, get_common_deps, require_admin
+from sentinel.api.routers.settings import load_led_bridge_health
 from sentinel.backtester import (
     BacktestConfig,
     Backtester,
@@ -19,6 +20,21 @@ from sentinel.backtester import (
 )
 from sentinel.cache import Cache
 from sentinel.currency import Currency
+from sentinel.health import HealthService, liveness
+from sentinel.jobs import current_job
+from sentinel.jobs import is_running as scheduler_running
+from sentinel.jobs.market import universe_market_status
+from sentinel.performance import (
+    budgets,
+    cpu_profile,
+    heap_profile,
+    recent_overruns,
+    runtime_metrics,
+    start_heap_trace,
+    stop_heap_trace,
+)
+from sentinel.systemd import RESTART_EXIT_CODE, request_restart, supervised
+from sentinel.trading_pause import TradingPause
 from sentinel.version import VERSION
 
 router = APIRouter(tags=["system"])
@@ -28,6 +44,17 @@ exchange_rates_router = APIRouter(prefix="/exchange-rates", tags=["exchange-rate
 markets_router = APIRouter(prefix="/markets", tags=["markets"])
 meta_router = APIRouter(prefix="/meta", tags=["meta"])
 pulse_router = APIRouter(prefix="/pulse", tags=["pulse"])
+profile_router = APIRouter(prefix="/system/profile", tags=["profiling"], dependencies=[Depends(require_admin)])
+
+
+async def _led_bridge_summary() -> dict[str, Any]:
+    bridge = await load_led_bridge_health()
+    return {
+        "bridge_ok": bridge["bridge_ok"] and not bridge["is_stale"],
+        "api_ok": bridge["api_ok"],
+        "is_stale": bridge["is_stale"],
+        "last_success_at": bridge["last_success_at"],
+    }
 
 
 @router.get("/health")
@@ -37,19 +64,204 @@ async def health(
     """Health check endpoint."""
     broker = deps.broker
     trading_mode = await deps.settings.get("trading_mode", "research")
+    pause = await TradingPause(deps.settings).status()
     return {
         "status": "healthy",
         "broker_connected": broker.connected,
         "trading_mode": trading_mode,
+        "trading_paused": pause["paused"],
+        "trading_paused_until_ts": pause["expires_at_ts"],
+        "led_bridge": await _led_bridge_summary(),
     }
 
 
+@router.get("/livez")
+async def livez() -> dict[str, Any]:
+    """Liveness: the process is up."""
+    return liveness()
+
+
+@router.get("/readyz")
+async def readyz(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+) -> dict[str, Any]:
+    """Readiness: database open and migrated, scheduler running. 503 when not ready."""
+    result = await HealthService(deps.db, deps.broker).readiness(scheduler_running())
+    if not result["ready"]:
+        response.status_code = 503
+    return result
+
+
+@router.get("/healthz")
+async def healthz(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+) -> dict[str, Any]:
+    """Detailed health report with dependency checks. 503 when unhealthy."""
+    report = await HealthService(deps.db, deps.broker).report(scheduler_running())
+    report["led_bridge"] = await _led_bridge_summary()
+    if report["status"] == "unhealthy":
+        response.status_code = 503
+    return report
+
+
+@router.post("/restart")
+async def restart(force: bool = False) -> dict[str, Any]:
+    """Shut down gracefully and exit with RESTART_EXIT_CODE so systemd restarts the service.
+
+    Refused while a job runs (e.g. mid trade execution) unless `force` is set.
+    """
+    job = current_job()
+    if job and not force:
+        raise HTTPException(status_code=409, detail=f"Job {job} is running; retry when it finishes or pass force=true")
+    request_restart()
+    return {"status": "restarting", "exit_code": RESTART_EXIT_CODE, "supervised": supervised()}
+
+
 @router.get("/version")
 async def version() -> dict[str, str]:
     """Return the application version."""
     return {"version": VERSION}
 
 
+@router.get("/deployments")
+async def deployments(limit: int = 50) -> dict[str, Any]:
+    """Auto-deploy outcomes, newest first, and the commit held back after a rollback."""
+    from sentinel.deployments import blocked_commit, load_deploy_history
+
+    if not 1 <= limit <= 500:
+        raise HTTPException(status_code=400, detail="limit must be between 1 and 500")
+    return {"deployments": load_deploy_history(limit), "blocked_commit": blocked_commit()}
+
+
+@router.get("/deployments/policy")
+async def deployment_policy(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Release channel auto-deploy tracks and whether a deploy may land now."""
+    from sentinel.deployments import deploy_policy
+
+    return await deploy_policy(deps.settings, await universe_market_status(deps.db, deps.broker))
+
+
+@router.get("/system/performance")
+async def performance(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Runtime metrics, database lock contention, job duration budgets and recent budget overruns."""
+    job_budgets, factor = await budgets(deps.settings)
+    return {
+        "runtime": runtime_metrics(),
+        "database": {
+            "config": asdict(deps.db.config) if deps.db.config else None,
+            "encrypted": deps.db.encrypted,
+            "contention": deps.db.contention_stats(),
+        },
+        "budgets": {"factor": factor, "jobs": job_budgets},
+        "overruns": recent_overruns(),
+    }
+
+
+@router.get("/system/doctor")
+async def doctor(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Self-test: credentials, broker, database, market hours, disk, clock and display."""
+    from sentinel.doctor import Doctor
+
+    return await Doctor(deps.db, deps.broker).run()
+
+
+@router.get("/system/retention")
+async def retention(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Retention policy per table and the result of the last system:retention run."""
+    from sentinel.retention import retention_overview
+
+    return await retention_overview(deps.db)
+
+
+@router.get("/system/archive")
+async def archive_files() -> dict[str, Any]:
+    """Retention archive files in data/archive, newest first."""
+    from sentinel.archive import list_archives
+
+    return {"files": list_archives()}
+
+
+@router.get("/system/migrations")
+async def migrations(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Schema version of the database, its numbered migrations and any pending schema changes."""
+    from sentinel.database.migrations import migration_status
+
+    return migration_status(deps.db._path)
+
+
+@router.get("/system/faults")
+async def faults(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Fault injection configuration, whether it is active, and faults injected so far."""
+    from sentinel.faults import injector
+
+    await injector.load(deps.settings.get)
+    return injector.status()
+
+
+@router.get("/system/governor")
+async def resource_governor(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Resource governor state, the latest CPU/memory sample, and runs deferred or skipped so far."""
+    from sentinel.governor import governor
+
+    await governor.load(deps.settings.get)
+    return governor.status()
+
+
+# Profiling router endpoints (admin only)
+
+
+@profile_router.get("/cpu", response_class=PlainTextResponse)
+async def profile_cpu(seconds: float = 10, limit: int = 40, sort: str = "cumulative") -> str:
+    """Profile the event loop for `seconds` and return the pstats report."""
+    if not 1 <= limit <= 500:
+        raise HTTPException(status_code=400, detail="limit must be between 1 and 500")
+    try:
+        return await cpu_profile(seconds, limit=limit, sort=sort)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except RuntimeError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+
+
+@profile_router.post("/heap/start")
+async def profile_heap_start() -> dict[str, Any]:
+    """Start tracing Python allocations."""
+    return {"tracing": True, "started": start_heap_trace()}
+
+
+@profile_router.post("/heap/stop")
+async def profile_heap_stop() -> dict[str, Any]:
+    """Stop tracing Python allocations and free the trace."""
+    return {"tracing": False, "stopped": stop_heap_trace()}
+
+
+@profile_router.get("/heap")
+async def profile_heap(limit: int = 25) -> dict[str, Any]:
+    """Top allocation sites since tracing started. 409 when not tracing."""
+    if not 1 <= limit <= 500:
+        raise HTTPException(status_code=400, detail="limit must be between 1 and 500")
+    try:
+        return {"top": heap_profile(limit)}
+    except RuntimeError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+
+
 # Cache router endpoints
 
 
@@ -257,54 +469,7 @@ async def get_markets_status(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
 ) -> dict:
     """Get market status for markets that have securities in our universe."""
-    import json as _json
-
-    broker = deps.broker
-
-    # Get all active securities and extract their market IDs from metadata
-    securities = await deps.db.get_all_securities(active_only=True)
-
-    market_ids_needed = set()
-    for sec in securities:
-        data = sec.get("data")
-        if data:
-            try:
-                sec_data = _json.loads(data) if isinstance(data, str) else data
-                mkt_id = sec_data.get("mrkt", {}).get("mkt_id")
-                if mkt_id is not None:
-                    market_ids_needed.add(str(mkt_id))
-            except (_json.JSONDecodeError, KeyError, TypeError, ValueError):
-                # Silently skip securities with malformed or missing market data
-                pass
-
-    # Get market status from broker
-    market_data = await broker.get_market_status("*")
-    if not market_data:
-        return {"markets": [], "any_open": False}
-
-    # Filter to only markets that have securities in our universe
-    markets_list = market_data.get("m", [])
-    filtered_markets = []
-    seen = set()
-    for m in markets_list:
-        mkt_id = str(m.get("i", ""))
-        market_name = m.get("n2", mkt_id)
-        if mkt_id in market_ids_needed and market_name not in seen:
-            seen.add(market_name)
-            filtered_markets.append(
-                {
-                    "name": market_name,
-                    "status": m.get("s", "UNKNOWN"),
-                    "is_open": m.get("s") == "OPEN",
-                }
-            )
-
-    any_open = any(m["is_open"] for m in filtered_markets)
-
-    return {
-        "markets": filtered_markets,
-        "any_open": any_open,
-    }
+    return await universe_market_status(deps.db, deps.broker)
 
 
 # Meta router endpoints
@@ -0,0 +1,113 @@
+"""Temperament questionnaire API routes."""
+
+from __future__ import annotations
+
+import inspect
+import time
+
+from fastapi import APIRouter, Depends, HTTPException, Query
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.temperament import (
+    QUESTIONNAIRE_REVISION,
+    QUESTIONS,
+    derive_settings,
+    diff_profiles,
+    temperament_scores,
+    validate_answers,
+)
+
+router = APIRouter(prefix="/temperament", tags=["temperament"])
+
+
+async def _apply(deps: CommonDependencies, profile: dict) -> int:
+    await deps.db.set_settings_batch(profile["settings"])
+    applied_at = int(time.time())
+    await deps.db.mark_temperament_profile_applied(profile["version"], applied_at)
+    invalidator = getattr(deps.db, "invalidate_planner_cache", None)
+    if callable(invalidator):
+        maybe = invalidator()
+        if inspect.isawaitable(maybe):
+            await maybe
+    return applied_at
+
+
+async def _get_profile(deps: CommonDependencies, version: int | None) -> dict:
+    profile = await deps.db.get_temperament_profile(version)
+    if profile is None:
+        raise HTTPException(status_code=404, detail="Temperament profile not found")
+    return profile
+
+
+@router.get("/questionnaire")
+async def get_questionnaire() -> dict:
+    """Get the questions and their options."""
+    return {"revision": QUESTIONNAIRE_REVISION, "questions": QUESTIONS}
+
+
+@router.get("")
+async def get_temperament(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Get the latest profile with an explanation of each derived setting."""
+    profile = await _get_profile(deps, None)
+    return {**profile, "derived": derive_settings(profile["scores"])}
+
+
+@router.post("/answers")
+async def submit_answers(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Store a questionnaire take as a new version and derive settings from it.
+
+    Body: {"answers": {question id: option id}, "apply"?: bool}
+    """
+    try:
+        answers = validate_answers(data.get("answers"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if not isinstance(data.get("apply", False), bool):
+        raise HTTPException(status_code=400, detail="apply must be a boolean")
+
+    previous = await deps.db.get_temperament_profile()
+    scores = temperament_scores(answers)
+    derived = derive_settings(scores)
+    settings = {row["key"]: row["value"] for row in derived}
+    version = await deps.db.create_temperament_profile(QUESTIONNAIRE_REVISION, answers, scores, settings)
+    profile = await deps.db.get_temperament_profile(version)
+    if data.get("apply"):
+        profile["applied_at"] = await _apply(deps, profile)
+    return {**profile, "derived": derived, "diff": diff_profiles(previous, profile)}
+
+
+@router.get("/versions")
+async def get_versions(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List every questionnaire take, newest first."""
+    return {"versions": await deps.db.get_temperament_profiles()}
+
+
+@router.get("/diff")
+async def get_diff(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    from_version: int = Query(...),
+    to_version: int | None = Query(None),
+) -> dict:
+    """Compare two versions; `to_version` defaults to the latest."""
+    old = await _get_profile(deps, from_version)
+    new = await _get_profile(deps, to_version)
+    return diff_profiles(old, new)
+
+
+@router.post("/versions/{version}/apply")
+async def apply_version(
+    version: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Write a version's derived settings."""
+    profile = await _get_profile(deps, version)
+    applied_at = await _apply(deps, profile)
+    return {"status": "ok", "version": version, "applied_at": applied_at, "settings": profile["settings"]}
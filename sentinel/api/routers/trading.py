@@ -1,17 +1,30 @@
 """Trading API routes."""
 
+import logging
 from typing import Optional
 
-from fastapi import APIRouter, Depends, HTTPException
+from fastapi import APIRouter, Depends, HTTPException, Response
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.api.idempotency import IdempotencyKey, run_idempotent
+from sentinel.broker_symbols import order_warnings
+from sentinel.cash_flow_rules import BUILTIN_RULES, CashFlowClassifier, validate_rule
+from sentinel.identifiers import IdentifierService
+from sentinel.orders import OrderService
+from sentinel.planner.savings import NEW_MONEY_DAYS_KEY, get_new_money_eur, validate_savings_plan
 from sentinel.portfolio import Portfolio
 from sentinel.security import Security
+from sentinel.trading_pause import DEFAULT_PAUSE_HOURS, TradingPause, describe
+from sentinel.utils.timestamps import to_iso_utc
+
+logger = logging.getLogger(__name__)
 
 router = APIRouter(prefix="/trades", tags=["trades"])
 cashflows_router = APIRouter(prefix="/cashflows", tags=["cashflows"])
+cash_router = APIRouter(prefix="/cash", tags=["cashflows"])
 trading_actions_router = APIRouter(prefix="/securities", tags=["trading"])
+trading_pause_router = APIRouter(prefix="/trading", tags=["trading"])
 
 
 @router.get("")
@@ -142,23 +155,318 @@ async def sync_cashflows_endpoint() -> dict:
     return result
 
 
-@trading_actions_router.post("/{symbol}/buy")
-async def buy_security(symbol: str, quantity: int) -> dict:
-    """Buy a security."""
+@cashflows_router.get("/review")
+async def get_cashflow_review(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Cash flows of a generic type no rule classified, newest first."""
+    return {"cash_flows": await CashFlowClassifier(deps.db).review_queue()}
+
+
+@cashflows_router.post("/classify")
+async def classify_cashflows(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Run the classification rules over the review queue."""
+    return await CashFlowClassifier(deps.db).classify_pending()
+
+
+@cashflows_router.post("/{cash_flow_id}/classify")
+async def classify_cashflow(
+    cash_flow_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Classify one cash flow by hand and learn a rule for the next ones.
+
+    Body: {"type_id", "symbol"?, "learn"?: true, "pattern"?}
+    """
+    try:
+        result = await CashFlowClassifier(deps.db).classify(
+            cash_flow_id,
+            type_id=data.get("type_id"),
+            symbol=data.get("symbol"),
+            learn=bool(data.get("learn", True)),
+            pattern=data.get("pattern"),
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if result is None:
+        raise HTTPException(status_code=404, detail="Cash flow not found")
+    return result
+
+
+@cashflows_router.get("/rules")
+async def get_cashflow_rules(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Learned and built-in classification rules."""
+    return {"rules": await deps.db.get_cash_flow_rules(), "builtin": BUILTIN_RULES}
+
+
+@cashflows_router.post("/rules")
+async def create_cashflow_rule(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Add a classification rule and apply it to the review queue.
+
+    Body: {"pattern", "type_id", "broker"?}
+    """
+    try:
+        rule = validate_rule(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    rule_id = await deps.db.create_cash_flow_rule(**rule)
+    result = await CashFlowClassifier(deps.db).classify_pending()
+    return {"status": "ok", "id": rule_id, **result}
+
+
+@cashflows_router.delete("/rules/{rule_id}")
+async def delete_cashflow_rule(
+    rule_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Delete a learned rule. Flows it classified keep their type."""
+    if not await deps.db.delete_cash_flow_rule(rule_id):
+        raise HTTPException(status_code=404, detail="Rule not found")
+    return {"status": "ok"}
+
+
+@cashflows_router.get("/savings-plans")
+async def get_savings_plans(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List savings plans and the matched deposit value not yet invested."""
+    window_days = await deps.settings.get(NEW_MONEY_DAYS_KEY, 30)
+    return {
+        "plans": await deps.db.get_savings_plans(),
+        "new_money_eur": round(await get_new_money_eur(deps.db, float(window_days or 0)), 2),
+    }
+
+
+@cashflows_router.get("/savings-plans/deposits")
+async def get_savings_deposits(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    plan_id: Optional[int] = None,
+    limit: int = 100,
+) -> dict:
+    """List cash flows matched to savings plans, newest first."""
+    return {"deposits": await deps.db.get_savings_deposits(plan_id=plan_id, limit=max(1, min(limit, 1000)))}
+
+
+@cashflows_router.post("/savings-plans")
+async def create_savings_plan(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Declare a recurring deposit.
+
+    Body: {"name", "amount", "day_of_month", "currency"?, "tolerance_pct"?, "window_days"?, "start_date"?}
+    """
+    try:
+        plan = validate_savings_plan(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    plan_id = await deps.db.create_savings_plan(**plan)
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok", "id": plan_id}
+
+
+@cashflows_router.put("/savings-plans/{plan_id}")
+async def update_savings_plan(
+    plan_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Update some fields of a savings plan, including `active`."""
+    try:
+        fields = validate_savings_plan(data, partial=True)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if not await deps.db.update_savings_plan(plan_id, **fields):
+        raise HTTPException(status_code=404, detail="Savings plan not found")
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok"}
+
+
+@cashflows_router.delete("/savings-plans/{plan_id}")
+async def delete_savings_plan(
+    plan_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Delete a savings plan and its matched deposits."""
+    if not await deps.db.delete_savings_plan(plan_id):
+        raise HTTPException(status_code=404, detail="Savings plan not found")
+    await deps.db.invalidate_planner_cache()
+    return {"status": "ok"}
+
+
+async def _cash_analytics(deps: CommonDependencies) -> dict:
+    from sentinel.cash_analytics import cash_analytics
+    from sentinel.planner import Planner
+
+    portfolio = Portfolio(db=deps.db, broker=deps.broker, settings=deps.settings, currency=deps.currency)
+    recommendations = await Planner(db=deps.db, broker=deps.broker).get_recommendations()
+    return await cash_analytics(
+        deps.db, deps.settings.get, deps.currency.to_eur, recommendations, await portfolio.total_value()
+    )
+
+
+@cash_router.get("/analytics")
+async def get_cash_analytics(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Cash per currency against its planned need, idle durations and FX conversion suggestions."""
+    return await _cash_analytics(deps)
+
+
+@cash_router.post("/conversions/approve")
+async def approve_cash_conversion(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+    idempotency_key: IdempotencyKey = None,
+) -> dict:
+    """Execute a current conversion suggestion (up to its amount) through the broker.
+
+    Body: {"from": "USD", "to": "EUR", "amount": 1500}
+
+    With an Idempotency-Key header, a retry returns the original exchange.
+    """
+    source, target, amount = data.get("from"), data.get("to"), data.get("amount")
+    if not isinstance(source, str) or not isinstance(target, str):
+        raise HTTPException(status_code=400, detail="from and to must be currency codes")
+    if isinstance(amount, bool) or not isinstance(amount, int | float) or amount <= 0:
+        raise HTTPException(status_code=400, detail="amount must be a positive number")
+
+    return await run_idempotent(
+        deps,
+        response,
+        "cash:convert",
+        idempotency_key,
+        {"from": source.upper(), "to": target.upper(), "amount": amount},
+        lambda: _approve_cash_conversion(deps, source.upper(), target.upper(), float(amount)),
+    )
+
+
+async def _approve_cash_conversion(deps: CommonDependencies, source: str, target: str, amount: float) -> dict:
+    from sentinel.cash_analytics import APPROVALS_KEY, matching_suggestion
+    from sentinel.currency_exchange import CurrencyExchangeService
+
+    if not await deps.settings.get(APPROVALS_KEY, False):
+        raise HTTPException(status_code=409, detail=f"Conversion approvals are disabled ({APPROVALS_KEY})")
+    pause = await TradingPause(deps.settings).status()
+    if pause["paused"]:
+        raise HTTPException(status_code=409, detail=describe(pause))
+    trading_mode = await deps.settings.get("trading_mode", "research")
+    if trading_mode != "live":
+        raise HTTPException(status_code=409, detail=f"Trading mode is '{trading_mode}'; orders are only sent in live")
+    if not deps.broker.connected:
+        raise HTTPException(status_code=409, detail="Broker not connected")
+
+    analytics = await _cash_analytics(deps)
+    suggestion = matching_suggestion(analytics["suggestions"], source, target, amount)
+    if suggestion is None:
+        raise HTTPException(status_code=404, detail=f"No current suggestion covers {amount:g} {source} -> {target}")
+    result = await CurrencyExchangeService().exchange(source, target, amount)
+    if not result:
+        raise HTTPException(status_code=502, detail=f"Broker did not accept the {source} -> {target} exchange")
+    return {"status": "submitted", "result": result, "suggestion": suggestion}
+
+
+async def _place_order(deps: CommonDependencies, symbol: str, side: str, quantity: int) -> dict:
+    pause = await TradingPause(deps.settings).status()
+    if pause["paused"]:
+        raise HTTPException(status_code=409, detail=describe(pause))
+    symbol = await IdentifierService(deps.db).canonical(symbol)
+    warnings = await order_warnings(deps.db, symbol)
     security = Security(symbol)
     await security.load()
-    order_id = await security.buy(quantity)
+    try:
+        order_id = await (security.buy(quantity) if side == "buy" else security.sell(quantity))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
     if not order_id:
-        raise HTTPException(status_code=400, detail="Buy order failed")
-    return {"order_id": order_id}
+        raise HTTPException(status_code=400, detail=f"{side.capitalize()} order failed")
+    try:
+        await OrderService(deps.db, deps.broker).record(order_id, symbol, side, quantity, source="api")
+    except Exception as e:
+        logger.warning(f"Failed to record order {order_id}: {e}")
+    return {"order_id": order_id, "warnings": warnings}
+
+
+@trading_actions_router.post("/{symbol}/buy")
+async def buy_security(
+    symbol: str,
+    quantity: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+    idempotency_key: IdempotencyKey = None,
+) -> dict:
+    """Buy a security. With an Idempotency-Key header, a retry returns the original order."""
+    return await run_idempotent(
+        deps,
+        response,
+        "securities:buy",
+        idempotency_key,
+        {"symbol": symbol, "quantity": quantity},
+        lambda: _place_order(deps, symbol, "buy", quantity),
+    )
 
 
 @trading_actions_router.post("/{symbol}/sell")
-async def sell_security(symbol: str, quantity: int) -> dict:
-    """Sell a security."""
-    security = Security(symbol)
-    await security.load()
-    order_id = await security.sell(quantity)
-    if not order_id:
-        raise HTTPException(status_code=400, detail="Sell order failed")
-    return {"order_id": order_id}
+async def sell_security(
+    symbol: str,
+    quantity: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    response: Response,
+    idempotency_key: IdempotencyKey = None,
+) -> dict:
+    """Sell a security. With an Idempotency-Key header, a retry returns the original order."""
+    return await run_idempotent(
+        deps,
+        response,
+        "securities:sell",
+        idempotency_key,
+        {"symbol": symbol, "quantity": quantity},
+        lambda: _place_order(deps, symbol, "sell", quantity),
+    )
+
+
+def _pause_response(status: dict) -> dict:
+    return {**status, "expires_at": to_iso_utc(status["expires_at_ts"])}
+
+
+@trading_pause_router.get("/pause")
+async def get_trading_pause(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Get the global trading pause."""
+    return _pause_response(await TradingPause(deps.settings).status())
+
+
+@trading_pause_router.post("/pause")
+async def pause_trading(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Stop all order placement until the pause expires or is lifted.
+
+    Body: {"hours": 24 | null, "reason": "..."}; omitted hours default to 24,
+    null pauses until resumed.
+    """
+    try:
+        await TradingPause(deps.settings).pause(data.get("hours", DEFAULT_PAUSE_HOURS), data.get("reason"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return _pause_response(await TradingPause(deps.settings).status())
+
+
+@trading_pause_router.delete("/pause")
+async def resume_trading(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Lift the trading pause."""
+    await TradingPause(deps.settings).resume()
+    return _pause_response(await TradingPause(deps.settings).status())
@@ -16,25 +16,39 @@ from fastapi.staticfiles import StaticFiles
 
 # API routers
 from sentinel.api.routers import (
+    analytics_router,
     backtest_router,
     backup_router,
+    broker_symbols_router,
     cache_router,
+    cash_router,
     cashflows_router,
     exchange_rates_router,
+    exclusions_router,
     forecasts_router,
+    goals_router,
+    groups_router,
+    imports_router,
     jobs_router,
     led_router,
     markets_router,
     meta_router,
+    orders_router,
     planner_router,
+    planning_router,
     portfolio_router,
     prices_router,
+    profile_router,
+    projections_router,
     pulse_router,
+    reports_router,
     securities_router,
     set_scheduler,
     settings_router,
     system_router,
+    temperament_router,
     trading_actions_router,
+    trading_pause_router,
     trading_router,
     unified_router,
 )
@@ -43,11 +57,16 @@ from sentinel.broker import Broker
 from sentinel.cache import Cache
 from sentinel.currency import Currency
 from sentinel.database import Database
+from sentinel.demo import demo_enabled, seed_demo
+from sentinel.doctor import startup_check
 from sentinel.jobs import init as init_jobs
+from sentinel.jobs import is_running as scheduler_running
 from sentinel.jobs import stop as stop_jobs
 from sentinel.jobs.market import BrokerMarketChecker
+from sentinel.performance import GCTimer
 from sentinel.portfolio import Portfolio
 from sentinel.settings import Settings
+from sentinel.systemd import Watchdog, notify
 from sentinel.version import VERSION
 
 logger = logging.getLogger(__name__)
@@ -56,27 +75,33 @@ logger = logging.getLogger(__name__)
 _scheduler = None  # APScheduler instance
 _led_controller = None
 _led_task: asyncio.Task | None = None
+_doctor_task: asyncio.Task | None = None
 
 
 @asynccontextmanager
 async def lifespan(app: FastAPI):
     """Initialize services on startup, cleanup on shutdown."""
-    global _scheduler, _led_controller, _led_task
+    global _scheduler, _led_controller, _led_task, _doctor_task
 
     # Startup
+    GCTimer.install()
     db = Database()
     await db.connect()
 
     settings = Settings()
     await settings.init_defaults()
 
+    if demo_enabled():
+        await seed_demo(db)
+
     broker = Broker()
     await broker.connect()
 
-    # Sync exchange rates on startup
+    # Sync exchange rates on startup (demo mode keeps its fixed rates)
     currency = Currency()
-    await currency.sync_rates()
-    logger.info("Exchange rates synced")
+    if not demo_enabled():
+        await currency.sync_rates()
+        logger.info("Exchange rates synced")
 
     # Check if we need to sync historical prices
     await _sync_missing_prices(db, broker)
@@ -117,14 +142,27 @@ async def lifespan(app: FastAPI):
     set_led_controller(_led_controller)
     _led_task = asyncio.create_task(_led_controller.start())
 
+    # Tell systemd (Type=notify) startup is done, then keep its watchdog fed
+    # for as long as the job scheduler runs.
+    notify("READY=1")
+    watchdog = Watchdog(healthy=scheduler_running)
+    watchdog.start()
+
+    # Self-test in the background; findings are logged, see sentinel.doctor.
+    _doctor_task = asyncio.create_task(startup_check(db, broker))
+
     yield
 
     # Shutdown
+    notify("STOPPING=1")
+    await watchdog.stop()
     await stop_jobs()
     logger.info("Job scheduler stopped")
 
     if _led_controller:
         _led_controller.stop()
+    if _doctor_task and not _doctor_task.done():
+        _doctor_task.cancel()
     if _led_task:
         _led_task.cancel()
         try:
@@ -191,13 +229,26 @@ app.add_middleware(
 app.include_router(settings_router, prefix="/api")
 app.include_router(led_router, prefix="/api")
 app.include_router(portfolio_router, prefix="/api")
+app.include_router(analytics_router, prefix="/api")
+app.include_router(reports_router, prefix="/api")
 app.include_router(securities_router, prefix="/api")
 app.include_router(prices_router, prefix="/api")
 app.include_router(unified_router, prefix="/api")
+app.include_router(exclusions_router, prefix="/api")
+app.include_router(groups_router, prefix="/api")
+app.include_router(goals_router, prefix="/api")
+app.include_router(temperament_router, prefix="/api")
+app.include_router(projections_router, prefix="/api")
+app.include_router(broker_symbols_router, prefix="/api")
 app.include_router(trading_router, prefix="/api")
 app.include_router(cashflows_router, prefix="/api")
+app.include_router(imports_router, prefix="/api")
+app.include_router(cash_router, prefix="/api")
 app.include_router(trading_actions_router, prefix="/api")
+app.include_router(trading_pause_router, prefix="/api")
+app.include_router(orders_router, prefix="/api")
 app.include_router(planner_router, prefix="/api")
+app.include_router(planning_router, prefix="/api")
 app.include_router(jobs_router, prefix="/api")
 app.include_router(forecasts_router, prefix="/api")
 app.include_router(backup_router, prefix="/api")
@@ -208,6 +259,7 @@ app.include_router(exchange_rates_router, prefix="/api")
 app.include_router(markets_router, prefix="/api")
 app.include_router(meta_router, prefix="/api")
 app.include_router(pulse_router, prefix="/api")
+app.include_router(profile_router, prefix="/api")
 
 # -----------------------------------------------------------------------------
 # Static Files (Web UI)
@@ -0,0 +1,129 @@
+"""
+Cold archive - aged rows exported to compressed CSV files before retention
+deletes them.
+
+Each export is one gzip-compressed CSV under `data/archive/<table>/`, named
+`<table>-<YYYYMMDDTHHMMSSZ>.csv.gz`: a header row of column names, then the
+rows as stored. NULL is written as `\\N` so it survives the round trip. With
+the `archive_r2_upload` setting on, new files are also uploaded to the R2
+backup bucket under `archive/`; a failed upload is logged and the local file
+is kept either way.
+
+`restore_archive` inserts a file's rows back into its table, skipping rows
+that are still present, so restoring for an audit is safe to repeat:
+
+    python main.py --restore-archive data/archive/job_history/job_history-20261016T040000Z.csv.gz
+"""
+
+from __future__ import annotations
+
+import csv
+import gzip
+import logging
+import time
+from pathlib import Path
+from typing import Any
+
+from sentinel.paths import DATA_DIR
+
+logger = logging.getLogger(__name__)
+
+ARCHIVE_DIR = DATA_DIR / "archive"
+ARCHIVE_R2_UPLOAD_KEY = "archive_r2_upload"
+NULL = "\\N"
+SUFFIX = ".csv.gz"
+
+
+def archive_table(path: Path) -> str:
+    """Table an archive file belongs to, from its name."""
+    if not path.name.endswith(SUFFIX) or "-" not in path.name:
+        raise ValueError(f"Not an archive file: {path.name}")
+    return path.name.split("-", 1)[0]
+
+
+def write_archive(
+    table: str,
+    columns: list[str],
+    rows: list[tuple],
+    directory: Path | None = None,
+    now: int | None = None,
+) -> Path:
+    """Write rows to a new compressed CSV file; returns its path."""
+    stamp = time.strftime("%Y%m%dT%H%M%SZ", time.gmtime(now if now is not None else time.time()))
+    folder = (directory or ARCHIVE_DIR) / table
+    folder.mkdir(parents=True, exist_ok=True)
+    path = folder / f"{table}-{stamp}{SUFFIX}"
+    n = 1
+    while path.exists():
+        n += 1
+        path = folder / f"{table}-{stamp}.{n}{SUFFIX}"
+    with gzip.open(path, "wt", newline="", encoding="utf-8") as f:
+        writer = csv.writer(f)
+        writer.writerow(columns)
+        writer.writerows([NULL if value is None else value for value in row] for row in rows)
+    return path
+
+
+def read_archive(path: Path) -> tuple[list[str], list[tuple]]:
+    """Columns and rows of an archive file. Values come back as text; SQLite's
+    column affinity restores numbers on insert."""
+    with gzip.open(path, "rt", newline="", encoding="utf-8") as f:
+        reader = csv.reader(f)
+        columns = next(reader)
+        rows = [tuple(None if value == NULL else value for value in row) for row in reader]
+    return columns, rows
+
+
+def list_archives(directory: Path | None = None) -> list[dict[str, Any]]:
+    """Archive files, newest first."""
+    root = directory or ARCHIVE_DIR
+    if not root.exists():
+        return []
+    files = [
+        {
+            "table": archive_table(path),
+            "file": str(path.relative_to(root)),
+            "bytes": path.stat().st_size,
+            "created_at": int(path.stat().st_mtime),
+        }
+        for path in root.glob(f"*/*{SUFFIX}")
+    ]
+    return sorted(files, key=lambda f: f["created_at"], reverse=True)
+
+
+async def upload_archive(db, path: Path) -> bool:
+    """Upload an archive file to R2 when `archive_r2_upload` is on; returns whether it was uploaded."""
+    if not await db.get_setting(ARCHIVE_R2_UPLOAD_KEY, False):
+        return False
+    account_id = await db.get_setting("r2_account_id", "")
+    access_key = await db.get_setting("r2_access_key", "")
+    secret_key = await db.get_setting("r2_secret_key", "")
+    bucket = await db.get_setting("r2_bucket_name", "")
+    if not all([account_id, access_key, secret_key, bucket]):
+        logger.warning(f"Archive upload skipped for {path.name}: R2 credentials not configured")
+        return False
+    from sentinel.jobs.tasks import _get_r2_client, _upload_archive
+
+    try:
+        client = _get_r2_client(account_id, access_key, secret_key)
+        _upload_archive(client, bucket, f"archive/{path.parent.name}/{path.name}", str(path))
+    except Exception as e:
+        logger.warning(f"Archive upload failed for {path.name}: {e}")
+        return False
+    return True
+
+
+async def restore_archive(db, path: Path) -> dict[str, Any]:
+    """Insert an archive file's rows back into its table.
+
+    Raises:
+        ValueError: If the file is not an archive of a governed table.
+    """
+    from sentinel.retention import TABLES
+
+    table = archive_table(path)
+    if table not in TABLES:
+        raise ValueError(f"Archive of unknown table '{table}'")
+    columns, rows = read_archive(path)
+    restored = await db.restore_retention_rows(table, columns, rows)
+    return {"table": table, "file": str(path), "rows": len(rows), "restored": restored}
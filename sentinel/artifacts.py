@@ -0,0 +1,186 @@
+"""
+Job artifacts - results a job run leaves behind besides its log lines.
+
+A job adds an artifact while it runs (the retention report, the ideal
+portfolio weights, the rebalance recommendations); the scheduler stores the
+run's artifacts under its `job_history` id once the run is logged, whether it
+completed or failed. Outside a scheduled or manual run (a task called from a
+script or a test) `add_artifact` is a no-op.
+
+Values are stored as JSON (dicts, lists and dataclasses), text (str) or bytes. Up to
+INLINE_MAX_BYTES they are kept in the `job_artifacts` table; larger ones go
+to a file under `data/artifacts/<run id>/` and the row keeps its path.
+Artifacts older than `job_artifact_retention_days` are deleted, files
+included, by the `system:retention` job.
+
+Usage:
+    add_artifact("weights", {"AAPL.US": 0.12, ...})
+
+    collected = begin_run()
+    ...  # run the job
+    await store_artifacts(db, run_id, job_type, collected)
+    end_run()
+"""
+
+from __future__ import annotations
+
+import dataclasses
+import json
+import logging
+import re
+import time
+from contextvars import ContextVar
+from pathlib import Path
+from typing import Any
+
+from sentinel.paths import DATA_DIR
+
+logger = logging.getLogger(__name__)
+
+ARTIFACTS_DIR = DATA_DIR / "artifacts"
+ARTIFACT_RETENTION_DAYS_KEY = "job_artifact_retention_days"
+DEFAULT_RETENTION_DAYS = 30
+INLINE_MAX_BYTES = 64 * 1024
+MAX_ARTIFACTS_PER_RUN = 20
+
+CONTENT_TYPES = {"json": "application/json", "text": "text/plain", "bytes": "application/octet-stream"}
+EXTENSIONS = {"json": ".json", "text": ".txt", "bytes": ".bin"}
+_NAME_RE = re.compile(r"^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$")
+
+_collected: ContextVar[list[dict[str, Any]] | None] = ContextVar("job_artifacts", default=None)
+
+
+def validate_artifact_retention_days(value: Any) -> int:
+    """Validate `job_artifact_retention_days`.
+
+    Raises:
+        ValueError: If it is not a whole number of at least 1.
+    """
+    if isinstance(value, bool) or not isinstance(value, int) or value < 1:
+        raise ValueError(f"{ARTIFACT_RETENTION_DAYS_KEY} must be a whole number of at least 1")
+    return value
+
+
+def _json_default(value: Any) -> Any:
+    if dataclasses.is_dataclass(value) and not isinstance(value, type):
+        return dataclasses.asdict(value)
+    return str(value)
+
+
+def _encode(data: Any) -> tuple[str, bytes]:
+    if isinstance(data, bytes):
+        return "bytes", data
+    if isinstance(data, str):
+        return "text", data.encode("utf-8")
+    return "json", json.dumps(data, default=_json_default, indent=2).encode("utf-8")
+
+
+def add_artifact(name: str, data: Any) -> None:
+    """Attach an artifact to the running job; a no-op outside a job run.
+
+    Raises:
+        ValueError: For a name that is not 1-100 letters, digits, `.`, `_` or `-`.
+    """
+    if not _NAME_RE.match(name):
+        raise ValueError(f"Invalid artifact name: {name!r}")
+    collected = _collected.get()
+    if collected is None:
+        return
+    kind, payload = _encode(data)
+    collected[:] = [a for a in collected if a["name"] != name]
+    if len(collected) >= MAX_ARTIFACTS_PER_RUN:
+        logger.warning(f"Dropping artifact {name}: a run keeps at most {MAX_ARTIFACTS_PER_RUN}")
+        return
+    collected.append({"name": name, "kind": kind, "payload": payload})
+
+
+def begin_run() -> list[dict[str, Any]]:
+    """Start collecting artifacts for the job run in the current task."""
+    collected: list[dict[str, Any]] = []
+    _collected.set(collected)
+    return collected
+
+
+def end_run() -> None:
+    """Stop collecting; later `add_artifact` calls in this task are no-ops."""
+    _collected.set(None)
+
+
+def download_name(artifact: dict[str, Any]) -> str:
+    """File name offered for download, e.g. `system-retention-42-report.json`."""
+    job = artifact["job_type"].replace(":", "-")
+    return f"{job}-{artifact['run_id']}-{artifact['name']}{EXTENSIONS.get(artifact['kind'], '')}"
+
+
+async def store_artifacts(
+    db,
+    run_id: int | None,
+    job_type: str,
+    collected: list[dict[str, Any]],
+    directory: Path | None = None,
+) -> int:
+    """Store a run's collected artifacts; returns how many were stored.
+
+    A failure is logged and does not fail the run.
+    """
+    if not collected or not isinstance(run_id, int):
+        return 0
+    stored = 0
+    for artifact in collected:
+        try:
+            payload = artifact["payload"]
+            path = None
+            if len(payload) > INLINE_MAX_BYTES:
+                folder = (directory or ARTIFACTS_DIR) / str(run_id)
+                folder.mkdir(parents=True, exist_ok=True)
+                target = folder / f"{artifact['name']}{EXTENSIONS[artifact['kind']]}"
+                target.write_bytes(payload)
+                path, payload = str(target), None
+            await db.create_job_artifact(
+                run_id=run_id,
+                job_type=job_type,
+                name=artifact["name"],
+                kind=artifact["kind"],
+                size_bytes=len(artifact["payload"]),
+                data=payload,
+                path=path,
+            )
+            stored += 1
+        except Exception as e:
+            logger.warning(f"Failed to store artifact {artifact['name']} of {job_type} run {run_id}: {e}")
+    collected.clear()
+    return stored
+
+
+def read_artifact(artifact: dict[str, Any]) -> bytes | None:
+    """The artifact's content, or None when its file is gone."""
+    if artifact.get("path") is None:
+        return artifact.get("data") or b""
+    try:
+        return Path(artifact["path"]).read_bytes()
+    except OSError:
+        return None
+
+
+async def prune_artifacts(db, keep_days: int | None = None, now: int | None = None) -> int:
+    """Delete artifacts older than `keep_days` (the setting by default), files included."""
+    if keep_days is None:
+        try:
+            raw = await db.get_setting(ARTIFACT_RETENTION_DAYS_KEY, DEFAULT_RETENTION_DAYS)
+            keep_days = validate_artifact_retention_days(raw)
+        except ValueError as e:
+            logger.warning(f"Ignoring {ARTIFACT_RETENTION_DAYS_KEY}: {e}")
+            keep_days = DEFAULT_RETENTION_DAYS
+    cutoff = (int(time.time()) if now is None else now) - keep_days * 86400
+    removed = await db.delete_job_artifacts_before(cutoff)
+    folders = set()
+    for artifact in removed:
+        if artifact.get("path"):
+            Path(artifact["path"]).unlink(missing_ok=True)
+            folders.add(Path(artifact["path"]).parent)
+    for folder in folders:
+        if folder.is_dir() and not any(folder.iterdir()):
+            folder.rmdir()
+    if removed:
+        logger.info(f"Deleted {len(removed)} job artifact(s) older than {keep_days} days")
+    return len(removed)
@@ -29,10 +29,14 @@ from typing import AsyncGenerator, Optional, cast
 import numpy as np
 
 from sentinel.broker import Broker
+from sentinel.cache import BoundedCache
 from sentinel.database import Database
 from sentinel.database.simulation import SimulationDatabase
 from sentinel.price_validator import PriceValidator
 
+VALIDATED_PRICES_MAX_SYMBOLS = 300
+VALIDATED_PRICES_MAX_BYTES = 96 * 1024 * 1024
+
 
 def _calculate_max_drawdown(values: np.ndarray) -> float:
     if len(values) == 0:
@@ -383,8 +387,15 @@ class BacktestBroker:
     def __init__(self, sim_db: SimulationDatabase):
         self._db = sim_db
         self._simulation_date: str = ""
-        # Cache validated prices per symbol: {symbol: {date: close_price}}
-        self._validated_prices: dict[str, dict[str, float]] = {}
+        # Validated prices per symbol: {symbol: {date: close_price}}. Bounded so
+        # a backtest over a large universe does not hold every series at once;
+        # evicted symbols are reloaded from the simulation database.
+        self._validated_prices: BoundedCache[dict[str, float]] = BoundedCache(
+            "backtest-prices",
+            ttl_seconds=None,
+            max_entries=VALIDATED_PRICES_MAX_SYMBOLS,
+            max_bytes=VALIDATED_PRICES_MAX_BYTES,
+        )
         self._price_validator = PriceValidator()
 
     def set_simulation_date(self, date_str: str):
@@ -427,10 +438,9 @@ class BacktestBroker:
         price data, exactly like the production app does.
         """
         # Lazily load and validate all prices for this symbol
-        if symbol not in self._validated_prices:
-            await self._load_and_validate_prices(symbol)
-
-        prices = self._validated_prices.get(symbol, {})
+        prices = self._validated_prices.get(symbol)
+        if prices is None:
+            prices = await self._load_and_validate_prices(symbol)
         if not prices:
             return None
 
@@ -446,7 +456,7 @@ class BacktestBroker:
 
         return None
 
-    async def _load_and_validate_prices(self, symbol: str):
+    async def _load_and_validate_prices(self, symbol: str) -> dict[str, float]:
         """
         Load all prices for a symbol and run them through PriceValidator.
 
@@ -462,8 +472,8 @@ class BacktestBroker:
         rows = await cursor.fetchall()
 
         if not rows:
-            self._validated_prices[symbol] = {}
-            return
+            self._validated_prices.set(symbol, {})
+            return {}
 
         # Convert to list of dicts (oldest first, as validator expects)
         raw_prices = [dict(row) for row in rows]
@@ -472,7 +482,9 @@ class BacktestBroker:
         validated = self._price_validator.validate_and_interpolate(raw_prices)
 
         # Cache as date -> close price mapping
-        self._validated_prices[symbol] = {p["date"]: p["close"] for p in validated if p.get("close")}
+        prices = {p["date"]: p["close"] for p in validated if p.get("close")}
+        self._validated_prices.set(symbol, prices)
+        return prices
 
     async def get_portfolio(self) -> dict:
         """Return simulated portfolio state."""
@@ -0,0 +1,291 @@
+"""
+Benchmark tracking - the portfolio's performance relative to market indices.
+
+Benchmarks are index symbols from the `benchmarks` table (synced by the
+`sync:benchmarks` job), configured via the `benchmark_symbols` setting. For
+each one we compare the deposit-adjusted portfolio TWR from snapshots with
+the index's price return:
+
+  - tracking difference: portfolio return minus benchmark return over the window
+  - tracking error: annualized standard deviation of daily excess returns
+  - beta: OLS beta of daily portfolio returns against the index
+  - rolling relative performance: trailing `rolling_days` return of both, per day
+
+Index closes are matched to snapshot dates with the most recent close on or
+before each date, so differing trading calendars do not misalign the series.
+"""
+
+from __future__ import annotations
+
+import bisect
+import math
+from datetime import date, timedelta
+from typing import Any, Callable
+
+from sentinel.localization import LOCALES, Locale
+from sentinel.portfolio_composition import (
+    HPR_RECONSTRUCTION_OUTLIER,
+    MIN_SAMPLES_FOR_BETA,
+    TRADING_DAYS_PER_YEAR,
+    beta,
+    build_daily_pnl,
+)
+
+BENCHMARK_SYMBOLS_KEY = "benchmark_symbols"
+DEFAULT_WINDOW_DAYS = 365
+DEFAULT_ROLLING_DAYS = 90
+BENCHMARK_MAX_STALENESS_DAYS = 5
+
+
+def validate_benchmark_symbols(raw: Any) -> list[str]:
+    """Validate a `benchmark_symbols` value.
+
+    Raises:
+        ValueError: If it is not a list of non-empty symbols.
+    """
+    if raw is None:
+        return []
+    if not isinstance(raw, list):
+        raise ValueError("benchmark symbols must be a list")
+    symbols: list[str] = []
+    for symbol in raw:
+        if not isinstance(symbol, str) or not symbol.strip():
+            raise ValueError("benchmark symbols must be non-empty strings")
+        if symbol.strip() not in symbols:
+            symbols.append(symbol.strip())
+    return symbols
+
+
+def display_name(symbol: str) -> str:
+    """Short label for the ticker: "SP500.IDX" -> "SP500"."""
+    return symbol[: -len(".IDX")] if symbol.endswith(".IDX") else symbol
+
+
+def _close_lookup(benchmark_rows: list[dict]) -> Callable[[str], float | None]:
+    points = sorted(
+        ((row["date"], float(row["close"])) for row in benchmark_rows if row.get("close") is not None),
+        key=lambda point: point[0],
+    )
+    dates = [point[0] for point in points]
+    closes = [point[1] for point in points]
+
+    def close_on_or_before(target: str) -> float | None:
+        index = bisect.bisect_right(dates, target) - 1
+        if index < 0:
+            return None
+        if (date.fromisoformat(target) - date.fromisoformat(dates[index])).days > BENCHMARK_MAX_STALENESS_DAYS:
+            return None
+        return closes[index] if closes[index] > 0 else None
+
+    return close_on_or_before
+
+
+def _hpr(previous: dict, current: dict) -> float | None:
+    prev_value = previous["total_value_eur"]
+    if not prev_value or prev_value <= 0:
+        return None
+    cash_flow = current["net_deposits_eur"] - previous["net_deposits_eur"]
+    return (current["total_value_eur"] - prev_value - cash_flow) / prev_value
+
+
+def _window_returns(
+    daily: list[dict],
+    close_on_or_before: Callable[[str], float | None],
+    start_index: int,
+    end_index: int,
+) -> tuple[float, float] | None:
+    """Portfolio TWR and benchmark return between two points of the daily series."""
+    start_close = close_on_or_before(daily[start_index]["date"])
+    end_close = close_on_or_before(daily[end_index]["date"])
+    if start_close is None or end_close is None:
+        return None
+    cumulative = 1.0
+    for i in range(start_index + 1, end_index + 1):
+        hpr = _hpr(daily[i - 1], daily[i])
+        if hpr is None:
+            return None
+        cumulative *= 1.0 + hpr
+    return cumulative - 1.0, end_close / start_close - 1.0
+
+
+def _index_on_or_before(dates: list[str], target: str) -> int | None:
+    index = bisect.bisect_right(dates, target) - 1
+    return index if index >= 0 else None
+
+
+def _pct(value: float | None) -> float | None:
+    return round(value * 100, 2) if value is not None else None
+
+
+def relative_performance(
+    daily: list[dict],
+    benchmark_rows: list[dict],
+    *,
+    as_of: date,
+    window_days: int = DEFAULT_WINDOW_DAYS,
+    rolling_days: int = DEFAULT_ROLLING_DAYS,
+) -> dict[str, Any]:
+    """Tracking difference, tracking error, beta and rolling relative performance.
+
+    Args:
+        daily: Daily P&L series from `build_daily_pnl` (ascending dates)
+        benchmark_rows: ``[{"date": "YYYY-MM-DD", "close": float}, ...]`` (any order)
+        as_of: Last date considered
+        window_days: Lookback for the window return, tracking and beta metrics
+        rolling_days: Trailing window of the rolling relative performance series
+    """
+    as_of_iso = as_of.isoformat()
+    daily = [point for point in daily if point["date"] <= as_of_iso]
+    close_on_or_before = _close_lookup(benchmark_rows)
+    dates = [point["date"] for point in daily]
+    result: dict[str, Any] = {
+        "portfolio_pct": None,
+        "benchmark_pct": None,
+        "tracking_difference_pct": None,
+        "tracking_error_pct": None,
+        "beta": None,
+        "samples": 0,
+        "ytd": {"portfolio_pct": None, "benchmark_pct": None, "relative_pct": None},
+        "rolling": [],
+    }
+    if len(daily) < 2:
+        return result
+    end_index = len(daily) - 1
+
+    def period(start: date) -> tuple[float, float] | None:
+        start_index = _index_on_or_before(dates, start.isoformat())
+        if start_index is None or start_index >= end_index:
+            return None
+        return _window_returns(daily, close_on_or_before, start_index, end_index)
+
+    window = period(as_of - timedelta(days=window_days))
+    if window is not None:
+        result["portfolio_pct"] = _pct(window[0])
+        result["benchmark_pct"] = _pct(window[1])
+        result["tracking_difference_pct"] = _pct(window[0] - window[1])
+
+    ytd = period(as_of.replace(month=1, day=1))
+    if ytd is not None:
+        result["ytd"] = {
+            "portfolio_pct": _pct(ytd[0]),
+            "benchmark_pct": _pct(ytd[1]),
+            "relative_pct": _pct(ytd[0] - ytd[1]),
+        }
+
+    # Day-over-day returns for beta and tracking error. Like the composition
+    # metrics, snapshot reconstruction outliers are dropped (see
+    # HPR_RECONSTRUCTION_OUTLIER).
+    window_start = (as_of - timedelta(days=window_days)).isoformat()
+    portfolio_returns: list[float] = []
+    benchmark_returns: list[float] = []
+    for i in range(1, len(daily)):
+        if daily[i - 1]["date"] < window_start:
+            continue
+        hpr = _hpr(daily[i - 1], daily[i])
+        prev_close = close_on_or_before(daily[i - 1]["date"])
+        close = close_on_or_before(daily[i]["date"])
+        if hpr is None or prev_close is None or close is None or abs(hpr) > HPR_RECONSTRUCTION_OUTLIER:
+            continue
+        portfolio_returns.append(hpr)
+        benchmark_returns.append(close / prev_close - 1.0)
+
+    samples = len(portfolio_returns)
+    result["samples"] = samples
+    if samples >= MIN_SAMPLES_FOR_BETA:
+        result["beta"] = round(beta(portfolio_returns, benchmark_returns), 3)
+        excess = [p - b for p, b in zip(portfolio_returns, benchmark_returns)]
+        mean = sum(excess) / samples
+        variance = sum((x - mean) ** 2 for x in excess) / (samples - 1)
+        result["tracking_error_pct"] = _pct(math.sqrt(variance) * math.sqrt(TRADING_DAYS_PER_YEAR))
+
+    rolling: list[dict[str, Any]] = []
+    for i in range(end_index + 1):
+        if dates[i] < window_start:
+            continue
+        rolling_start = date.fromisoformat(dates[i]) - timedelta(days=rolling_days)
+        start_index = _index_on_or_before(dates, rolling_start.isoformat())
+        if start_index is None or start_index >= i:
+            continue
+        returns = _window_returns(daily, close_on_or_before, start_index, i)
+        if returns is None:
+            continue
+        rolling.append(
+            {
+                "date": dates[i],
+                "portfolio_pct": _pct(returns[0]),
+                "benchmark_pct": _pct(returns[1]),
+                "relative_pct": _pct(returns[0] - returns[1]),
+            }
+        )
+    result["rolling"] = rolling
+    return result
+
+
+async def _daily_series(db, currency, days: int) -> list[dict]:
+    snapshots = await db.get_portfolio_snapshots(days)
+    if not snapshots:
+        return []
+    cash_flows = await db.get_cash_flows()
+    deposits_by_date: dict[str, float] = {}
+    running = 0.0
+    for cf in sorted([cf for cf in cash_flows if cf["type_id"] in ("card", "card_payout")], key=lambda cf: cf["date"]):
+        running += await currency.to_eur_for_date(cf["amount"], cf["currency"], cf["date"])
+        deposits_by_date[cf["date"]] = running
+    return build_daily_pnl(snapshots, deposits_by_date)
+
+
+async def build_benchmark_analytics(
+    db,
+    currency,
+    settings,
+    *,
+    as_of: date | None = None,
+    window_days: int = DEFAULT_WINDOW_DAYS,
+    rolling_days: int = DEFAULT_ROLLING_DAYS,
+) -> dict[str, Any]:
+    """Relative performance against every configured benchmark."""
+    as_of = as_of or date.today()
+    try:
+        symbols = validate_benchmark_symbols(await settings.get(BENCHMARK_SYMBOLS_KEY, []))
+    except ValueError:
+        symbols = []
+
+    # YTD can reach further back than the window early in a year.
+    history_days = max(window_days, (as_of - as_of.replace(month=1, day=1)).days) + rolling_days + 10
+    daily = await _daily_series(db, currency, history_days) if symbols else []
+    names = {row["symbol"]: row.get("name") for row in await db.get_benchmarks()} if symbols else {}
+
+    benchmarks = []
+    for symbol in symbols:
+        rows = await db.get_benchmark_prices(symbol, days=history_days)
+        benchmarks.append(
+            {
+                "symbol": symbol,
+                "name": names.get(symbol) or symbol,
+                "available": bool(rows),
+                **relative_performance(
+                    daily,
+                    rows or [],
+                    as_of=as_of,
+                    window_days=window_days,
+                    rolling_days=rolling_days,
+                ),
+            }
+        )
+    return {
+        "as_of_date": as_of.isoformat(),
+        "window_days": window_days,
+        "rolling_days": rolling_days,
+        "benchmarks": benchmarks,
+    }
+
+
+def ticker_text(analytics: dict[str, Any], locale: Locale | None = None) -> str | None:
+    """LED ticker line for the first benchmark with a YTD comparison, e.g. "vs SP500: +1.2% YTD"."""
+    locale = locale or LOCALES["en"]
+    for benchmark in analytics.get("benchmarks", []):
+        relative = benchmark["ytd"]["relative_pct"]
+        if relative is not None:
+            change = locale.percent(relative, 1, sign=True)
+            return locale.ticker_text("benchmark", benchmark=display_name(benchmark["symbol"]), change=change)
+    return None
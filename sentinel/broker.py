@@ -12,10 +12,12 @@ import asyncio
 import json
 import logging
 from datetime import datetime, timedelta
-from typing import Any, Optional
+from typing import Any, Optional, Protocol
 
 from sentinel.database import Database
+from sentinel.faults import FaultyClient
 from sentinel.settings import Settings
+from sentinel.trading_pause import TradingPause, describe
 from sentinel.utils.decorators import singleton
 
 logger = logging.getLogger(__name__)
@@ -79,6 +81,50 @@ def _normalize_non_trade_commission(row: dict) -> dict | None:
     }
 
 
+class BrokerClient(Protocol):
+    """What the trading and sync paths need from a broker.
+
+    Implemented by Broker (Tradernet) and by sentinel.mock_broker.MockBroker.
+    """
+
+    @property
+    def connected(self) -> bool: ...
+
+    async def connect(self) -> bool: ...
+
+    async def get_quote(self, symbol: str) -> Optional[dict]: ...
+
+    async def get_quotes(self, symbols: list[str]) -> dict[str, dict]: ...
+
+    async def get_historical_prices_bulk(
+        self, symbols: list[str], years: int = 20, *, raise_on_error: bool = False
+    ) -> dict[str, list[dict]]: ...
+
+    async def get_portfolio(self) -> dict: ...
+
+    async def buy(self, symbol: str, quantity: int, price: float | None = None) -> Optional[str]: ...
+
+    async def sell(self, symbol: str, quantity: int, price: float | None = None) -> Optional[str]: ...
+
+    async def has_pending_orders(self) -> bool: ...
+
+    async def get_orders(self, active_only: bool = True) -> list[dict] | None: ...
+
+    async def cancel_order(self, order_id: str) -> bool: ...
+
+    async def get_security_info(self, symbol: str) -> Optional[dict]: ...
+
+    async def get_market_status(self, market: str = "*") -> Optional[dict]: ...
+
+    async def get_trades_history(self, start_date: str = "2020-01-01", end_date: str | None = None) -> list[dict]: ...
+
+    async def get_cash_flows(self, start_date: str = "2020-01-01", end_date: str | None = None) -> list[dict]: ...
+
+    async def get_corporate_actions(
+        self, start_date: str = "2020-01-01", end_date: str | None = None
+    ) -> list[dict]: ...
+
+
 @singleton
 class Broker:
     """Single source of truth for broker operations."""
@@ -118,6 +164,12 @@ class Broker:
         if self._api is not None:
             return True
 
+        from sentinel.demo import demo_enabled
+
+        if demo_enabled():
+            logger.info("Demo mode: not connecting to Tradernet")
+            return False
+
         api_key = await self._settings.get("tradernet_api_key")
         api_secret = await self._settings.get("tradernet_api_secret")
 
@@ -127,8 +179,9 @@ class Broker:
         try:
             from tradernet import TraderNetAPI, Trading
 
-            self._api = TraderNetAPI(public=api_key, private=api_secret)
-            self._trading = Trading(public=api_key, private=api_secret)
+            # Research-mode fault injection can time these calls out (sentinel.faults)
+            self._api = FaultyClient(TraderNetAPI(public=api_key, private=api_secret))
+            self._trading = FaultyClient(Trading(public=api_key, private=api_secret))
             return True
         except Exception as e:
             logger.error(f"Failed to connect to Tradernet: {e}")
@@ -456,6 +509,13 @@ class Broker:
         mode = await self._settings.get("trading_mode", "research")
         return mode == "live"
 
+    async def _refuse_if_paused(self, side: str, symbol: str) -> bool:
+        """Log and return True when the global trading pause is active."""
+        status = await TradingPause(self._settings).status()
+        if status["paused"]:
+            logger.warning(f"{describe(status)}; not placing {side} order for {symbol}")
+        return status["paused"]
+
     async def buy(self, symbol: str, quantity: int, price: float | None = None) -> Optional[str]:
         """Place a buy order. Returns order ID if successful.
 
@@ -465,7 +525,10 @@ class Broker:
             price: Limit price (optional). If provided, places a limit order.
 
         In research mode, returns a simulated order ID without executing.
+        While trading is paused, refuses the order and returns None.
         """
+        if await self._refuse_if_paused("buy", symbol):
+            return None
         if not await self._is_live_mode():
             price_info = f" @ {price}" if price else ""
             logger.debug(f"[RESEARCH MODE] Would buy {quantity} of {symbol}{price_info}")
@@ -493,7 +556,10 @@ class Broker:
             price: Limit price (optional). If provided, places a limit order.
 
         In research mode, returns a simulated order ID without executing.
+        While trading is paused, refuses the order and returns None.
         """
+        if await self._refuse_if_paused("sell", symbol):
+            return None
         if not await self._is_live_mode():
             price_info = f" @ {price}" if price else ""
             logger.debug(f"[RESEARCH MODE] Would sell {quantity} of {symbol}{price_info}")
@@ -527,52 +593,106 @@ class Broker:
         errors — it just logs them and returns ``{"errMsg": ..., "code": ...}``
         — so we must inspect the payload, not rely on exceptions.
         """
+        orders = self._placed_orders(active=True)
+        return orders is None or len(orders) > 0
+
+    async def get_orders(self, active_only: bool = True) -> list[dict] | None:
+        """Orders of the current period, normalized; None when the broker could not be read.
+
+        Each order: order_id, symbol, side ("buy"/"sell"), quantity, remaining,
+        price, currency, type (Tradernet type code), status (Tradernet status
+        code, see docs/tradernet/miscellaneous/order-statuses.md) and date.
+        """
+        orders = self._placed_orders(active=active_only)
+        if orders is None:
+            return None
+        return [
+            {
+                "order_id": str(order.get("order_id") or order.get("id") or ""),
+                "symbol": order.get("instr"),
+                "side": "sell" if order.get("oper") in (3, 4) else "buy",
+                "quantity": order.get("q"),
+                "remaining": order.get("leaves_qty"),
+                "price": order.get("p"),
+                "currency": order.get("cur"),
+                "type": order.get("type"),
+                "status": order.get("stat"),
+                "date": order.get("date"),
+            }
+            for order in orders
+            if isinstance(order, dict)
+        ]
+
+    async def cancel_order(self, order_id: str) -> bool:
+        """Ask the broker to cancel an order; True if the request was accepted.
+
+        In research mode there are no real orders, so this only logs and
+        returns True. Cancelling is allowed while trading is paused.
+        """
+        if not await self._is_live_mode():
+            logger.debug(f"[RESEARCH MODE] Would cancel order {order_id}")
+            return True
+        if not self._trading:
+            return False
+        try:
+            response = self._trading.cancel(int(order_id))
+        except Exception as e:
+            logger.error(f"Failed to cancel order {order_id}: {e}")
+            return False
+        if not isinstance(response, dict) or "errMsg" in response or "error" in response:
+            logger.error(f"Broker refused to cancel order {order_id}: {response!r}")
+            return False
+        logger.info(f"Cancel order {order_id} response: {response}")
+        return True
+
+    def _placed_orders(self, active: bool) -> list[dict] | None:
+        """Raw `get_placed` orders; None when the response cannot be trusted."""
         if not self._trading:
             # Broker not connected. trading_execute already gates on
             # broker.connected upstream, so reaching here means another caller
             # invoked us without a live trading client. We can't query, so
             # fail safe.
-            logger.warning("has_pending_orders called without trading client; failing safe")
-            return True
+            logger.warning("Order query without trading client; failing safe")
+            return None
         try:
-            placed = self._trading.get_placed(active=True)
+            placed = self._trading.get_placed(active=active)
         except Exception as e:
-            logger.error(f"Failed to fetch active orders: {e}")
-            return True
+            logger.error(f"Failed to fetch {'active ' if active else ''}orders: {e}")
+            return None
 
         # Defensively validate every level of the response. Any deviation from
         # the documented shape is treated as an error and fails safe.
         if not isinstance(placed, dict):
             logger.error(f"Unexpected get_placed response type: {type(placed).__name__}; failing safe")
-            return True
+            return None
         if "errMsg" in placed:
             logger.error(
                 f"Broker returned error from get_placed: {placed.get('errMsg')!r} "
                 f"(code={placed.get('code')!r}); failing safe"
             )
-            return True
+            return None
 
         result = placed.get("result")
         if not isinstance(result, dict):
             logger.error("get_placed response missing 'result' dict; failing safe")
-            return True
+            return None
 
         orders = result.get("orders")
         if not isinstance(orders, dict):
             logger.error("get_placed response missing 'result.orders' dict; failing safe")
-            return True
+            return None
 
         order_field = orders.get("order")
         if order_field is None:
-            return False
+            return []
         # The API normally returns a list. Defensively treat a single-order
-        # dict as "pending" too. Any other type is unexpected -> fail safe.
+        # dict as one order. Any other type is unexpected -> fail safe.
         if isinstance(order_field, dict):
-            return True
+            return [order_field]
         if isinstance(order_field, list):
-            return len(order_field) > 0
+            return order_field
         logger.error(f"Unexpected get_placed 'order' field type: {type(order_field).__name__}; failing safe")
-        return True
+        return None
 
     # -------------------------------------------------------------------------
     # Metadata
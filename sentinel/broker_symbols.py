@@ -0,0 +1,168 @@
+"""
+Broker symbols - which Tradernet symbol trades each ISIN.
+
+Securities are stored under their Tradernet symbol ("ASML.EU"), but a
+listing can be known by other tickers and one ISIN can be stored under
+several suffixes (".EU", ".GR"). The `broker_symbols` table pins the
+symbol orders and quotes should use per ISIN:
+
+- Metadata sync calls `detect_broker_symbols` to map every ISIN with a
+  single active listing ('auto' rows). ISINs with several active listings
+  are reported as conflicts and left for the user.
+- The API sets 'manual' rows, which sync never overwrites.
+
+`order_warnings` lists problems with a symbol an order is about to use
+(no ISIN, no mapping, mapped to another listing). Orders still go through;
+the warnings are logged and returned to API callers.
+"""
+
+from __future__ import annotations
+
+import logging
+from typing import Any
+
+from sentinel.identifiers import IdentifierService, normalize_isin, security_isin
+
+logger = logging.getLogger(__name__)
+
+
+def validate_mapping(isin: Any, data: Any) -> tuple[str, str]:
+    """Validate an ISIN path parameter and a {"broker_symbol"} body.
+
+    Raises:
+        ValueError: If the ISIN is malformed or the symbol is missing.
+    """
+    normalized = normalize_isin(isin) if isinstance(isin, str) else None
+    if normalized is None:
+        raise ValueError(f"{isin!r} is not an ISIN")
+    if not isinstance(data, dict):
+        raise ValueError("body must be an object")
+    broker_symbol = data.get("broker_symbol")
+    if not isinstance(broker_symbol, str) or not broker_symbol.strip():
+        raise ValueError("broker_symbol is required")
+    return normalized, broker_symbol.strip()
+
+
+def _listings(securities: list[dict]) -> dict[str, list[dict]]:
+    """Securities grouped by ISIN; securities without one are left out."""
+    by_isin: dict[str, list[dict]] = {}
+    for security in securities:
+        isin = security_isin(security)
+        if isin:
+            by_isin.setdefault(isin, []).append(security)
+    return by_isin
+
+
+def _active(security: dict) -> bool:
+    return bool(int(security.get("active", 1) or 0))
+
+
+async def detect_broker_symbols(db) -> dict[str, Any]:
+    """Map each ISIN with one active (or only one) listing to its symbol.
+
+    Manual mappings are kept as they are; auto mappings to symbols that are
+    no longer stored are removed.
+
+    Returns:
+        {"added": [isin], "updated": [isin], "removed": [isin],
+         "conflicts": [{"isin", "symbols"}]}
+    """
+    securities = await db.get_all_securities(active_only=False)
+    stored = {security["symbol"] for security in securities}
+    mappings = {row["isin"]: row for row in await db.get_broker_symbols()}
+    result: dict[str, Any] = {"added": [], "updated": [], "removed": [], "conflicts": []}
+
+    for isin, listings in sorted(_listings(securities).items()):
+        active = [security["symbol"] for security in listings if _active(security)]
+        if len(active) > 1:
+            result["conflicts"].append({"isin": isin, "symbols": sorted(active)})
+            continue
+        if active:
+            symbol = active[0]
+        elif len(listings) == 1:
+            symbol = listings[0]["symbol"]
+        else:
+            continue
+        existing = mappings.get(isin)
+        if existing and (existing["source"] == "manual" or existing["broker_symbol"] == symbol):
+            continue
+        await db.set_broker_symbol(isin, symbol, "auto")
+        result["updated" if existing else "added"].append(isin)
+
+    for isin, mapping in mappings.items():
+        if mapping["source"] == "auto" and mapping["broker_symbol"] not in stored:
+            await db.delete_broker_symbol(isin)
+            result["removed"].append(isin)
+
+    for conflict in result["conflicts"]:
+        if conflict["isin"] not in mappings:
+            logger.warning(f"ISIN {conflict['isin']} has active listings {conflict['symbols']}; map one manually")
+    IdentifierService(db).invalidate()
+    return result
+
+
+async def set_manual_mapping(db, isin: str, broker_symbol: str) -> dict:
+    """Pin `isin` to a stored security's symbol.
+
+    Raises:
+        ValueError: If `broker_symbol` is not a stored security.
+    """
+    identifiers = IdentifierService(db)
+    symbol = await identifiers.resolve(broker_symbol)
+    if symbol is None:
+        raise ValueError(f"{broker_symbol} is not a known security")
+    await db.set_broker_symbol(isin, symbol, "manual")
+    identifiers.invalidate()
+    return await db.get_broker_symbol(isin)
+
+
+async def delete_mapping(db, isin: str) -> bool:
+    """Remove a mapping; the next metadata sync may detect it again."""
+    deleted = await db.delete_broker_symbol(isin)
+    if deleted:
+        IdentifierService(db).invalidate()
+    return deleted
+
+
+async def mapping_report(db) -> dict[str, Any]:
+    """All mappings with their listings, plus active securities lacking a usable mapping."""
+    securities = await db.get_all_securities(active_only=False)
+    listings = _listings(securities)
+    mappings = await db.get_broker_symbols()
+    mapped = {row["isin"]: row["broker_symbol"] for row in mappings}
+    unmapped = []
+    for security in securities:
+        if not _active(security):
+            continue
+        warning = _warning(security["symbol"], security_isin(security), mapped)
+        if warning:
+            unmapped.append({"symbol": security["symbol"], "isin": security_isin(security), "warning": warning})
+    return {
+        "mappings": [
+            {**row, "listings": sorted(s["symbol"] for s in listings.get(row["isin"], []))} for row in mappings
+        ],
+        "unmapped": unmapped,
+    }
+
+
+def _warning(symbol: str, isin: str | None, mapped: dict[str, str]) -> str | None:
+    if not isin:
+        return f"{symbol} has no ISIN, so its broker symbol cannot be checked"
+    if isin not in mapped:
+        return f"No broker symbol is mapped for {isin} ({symbol})"
+    if mapped[isin] != symbol:
+        return f"{isin} trades as {mapped[isin]} at the broker, not {symbol}"
+    return None
+
+
+async def order_warnings(db, symbol: str) -> list[str]:
+    """Problems with the broker symbol an order for `symbol` would use."""
+    security = await db.get_security(symbol)
+    if not security:
+        return [f"{symbol} is not a known security"]
+    isin = security_isin(security)
+    mapping = await db.get_broker_symbol(isin) if isin else None
+    warning = _warning(symbol, isin, {isin: mapping["broker_symbol"]} if mapping else {})
+    if warning:
+        logger.warning(f"Order for {symbol}: {warning}")
+    return [warning] if warning else []
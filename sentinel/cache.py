@@ -1,82 +1,116 @@
 """
-Cache - Simple in-memory TTL cache for expensive computations.
+Cache - Memory-bounded in-memory LRU/TTL cache for expensive computations.
+
+Every cache has a cap on entries and, optionally, on the approximate bytes
+its values hold. When a new value would exceed a cap the least recently used
+entries are evicted, so long-running processes keep a stable RSS.
 
 Usage:
-    from sentinel.cache import Cache
+    from sentinel.cache import BoundedCache, Cache
 
     cache = Cache('motion', ttl_seconds=86400)
     cache.set('AAPL.US', motion_object)
-    cached = cache.get('AAPL.US')  # Returns None if expired
+    cached = cache.get('AAPL.US')  # Returns None if expired or evicted
     cache.invalidate('AAPL.US')    # Remove single entry
     cache.clear()                   # Remove all entries
+
+    # Unnamed, per-object cache (not listed in Cache.get_all_stats())
+    prices = BoundedCache('backtest-prices', ttl_seconds=None, max_entries=500)
 """
 
+import sys
 import time
+from collections import OrderedDict
 from dataclasses import dataclass
-from typing import Generic, Optional, TypeVar
+from typing import Any, Generic, Optional, TypeVar
 
 T = TypeVar("T")
 
+DEFAULT_MAX_ENTRIES = 1000
+
 
 @dataclass
 class CacheEntry(Generic[T]):
-    """A cached value with expiration timestamp."""
+    """A cached value with expiration timestamp and approximate size in bytes."""
 
     value: T
     expires_at: float
     created_at: float
+    size: int = 0
 
 
-class Cache(Generic[T]):
-    """
-    Simple in-memory TTL cache with named singleton instances.
+def estimate_size(value: Any, _seen: set[int] | None = None) -> int:
+    """Approximate memory held by `value`, following containers (bytes)."""
+    seen = _seen if _seen is not None else set()
+    if id(value) in seen:
+        return 0
+    seen.add(id(value))
 
-    Each cache name gets a single shared instance, so Cache('motion')
-    returns the same cache object throughout the application.
-    """
+    size = sys.getsizeof(value)
+    if isinstance(value, dict):
+        size += sum(estimate_size(k, seen) + estimate_size(v, seen) for k, v in value.items())
+    elif isinstance(value, (list, tuple, set, frozenset)):
+        size += sum(estimate_size(item, seen) for item in value)
+    elif hasattr(value, "__dict__"):
+        size += estimate_size(vars(value), seen)
+    return size
 
-    _instances: dict[str, "Cache"] = {}
 
-    def __new__(cls, name: str, ttl_seconds: int = 86400):
-        """Named singleton pattern - one cache instance per name."""
-        if name not in cls._instances:
-            instance = super().__new__(cls)
-            instance._initialized = False
-            cls._instances[name] = instance
-        return cls._instances[name]
+class BoundedCache(Generic[T]):
+    """
+    In-memory cache with TTL expiry and LRU eviction.
 
-    def __init__(self, name: str, ttl_seconds: int = 86400):
-        if self._initialized:
-            return
-        self._initialized = True
+    Args:
+        name: Name shown in stats
+        ttl_seconds: Default time to live; None keeps entries until evicted
+        max_entries: Maximum number of entries
+        max_bytes: Optional cap on the estimated size of all values
+    """
+
+    def __init__(
+        self,
+        name: str,
+        ttl_seconds: Optional[int] = 86400,
+        max_entries: int = DEFAULT_MAX_ENTRIES,
+        max_bytes: Optional[int] = None,
+    ):
+        if max_entries < 1:
+            raise ValueError("max_entries must be at least 1")
         self._name = name
         self._ttl = ttl_seconds
-        self._data: dict[str, CacheEntry[T]] = {}
+        self._max_entries = max_entries
+        self._max_bytes = max_bytes
+        self._data: OrderedDict[str, CacheEntry[T]] = OrderedDict()
+        self._bytes = 0
         self._hits = 0
         self._misses = 0
+        self._evictions = 0
+        self._expirations = 0
 
     def get(self, key: str) -> Optional[T]:
         """
-        Get a cached value by key.
+        Get a cached value by key and mark it as recently used.
 
         Returns None if key doesn't exist or has expired.
         """
-        if key not in self._data:
+        entry = self._data.get(key)
+        if entry is None:
             self._misses += 1
             return None
 
-        entry = self._data[key]
         if time.time() > entry.expires_at:
-            del self._data[key]
+            self._remove(key)
+            self._expirations += 1
             self._misses += 1
             return None
 
+        self._data.move_to_end(key)
         self._hits += 1
         return entry.value
 
     def set(self, key: str, value: T, ttl_seconds: Optional[int] = None) -> None:
         """
-        Store a value in the cache.
+        Store a value in the cache, evicting least recently used entries if full.
 
         Args:
             key: Cache key
@@ -85,11 +119,32 @@ class Cache(Generic[T]):
         """
         ttl = ttl_seconds if ttl_seconds is not None else self._ttl
         now = time.time()
+        size = estimate_size(value) if self._max_bytes is not None else 0
+
+        if key in self._data:
+            self._remove(key)
         self._data[key] = CacheEntry(
             value=value,
-            expires_at=now + ttl,
+            expires_at=now + ttl if ttl is not None else float("inf"),
             created_at=now,
+            size=size,
         )
+        self._bytes += size
+        self._evict(keep=key)
+
+    def _remove(self, key: str) -> None:
+        entry = self._data.pop(key)
+        self._bytes -= entry.size
+
+    def _evict(self, keep: str) -> None:
+        while len(self._data) > self._max_entries or (
+            self._max_bytes is not None and self._bytes > self._max_bytes and len(self._data) > 1
+        ):
+            oldest = next(iter(self._data))
+            if oldest == keep:
+                break
+            self._remove(oldest)
+            self._evictions += 1
 
     def invalidate(self, key: str) -> bool:
         """
@@ -98,7 +153,7 @@ class Cache(Generic[T]):
         Returns True if the key existed, False otherwise.
         """
         if key in self._data:
-            del self._data[key]
+            self._remove(key)
             return True
         return False
 
@@ -110,13 +165,18 @@ class Cache(Generic[T]):
         """
         count = len(self._data)
         self._data.clear()
+        self._bytes = 0
         return count
 
+    def __len__(self) -> int:
+        return len(self._data)
+
     def stats(self) -> dict:
         """
         Get cache statistics.
 
-        Returns dict with entries, hits, misses, and hit rate.
+        Returns dict with entries, limits, size, hits, misses, hit rate,
+        evictions and expirations.
         """
         now = time.time()
         valid_entries = sum(1 for e in self._data.values() if e.expires_at > now)
@@ -126,15 +186,54 @@ class Cache(Generic[T]):
             "name": self._name,
             "entries": valid_entries,
             "ttl_seconds": self._ttl,
+            "max_entries": self._max_entries,
+            "max_bytes": self._max_bytes,
+            "bytes": self._bytes if self._max_bytes is not None else None,
             "hits": self._hits,
             "misses": self._misses,
             "hit_rate": self._hits / total_requests if total_requests > 0 else 0.0,
+            "evictions": self._evictions,
+            "expirations": self._expirations,
         }
 
     def reset_stats(self) -> None:
-        """Reset hit/miss counters."""
+        """Reset hit/miss/eviction counters."""
         self._hits = 0
         self._misses = 0
+        self._evictions = 0
+        self._expirations = 0
+
+
+class Cache(BoundedCache[T]):
+    """
+    BoundedCache with named singleton instances.
+
+    Each cache name gets a single shared instance, so Cache('motion')
+    returns the same cache object throughout the application. Limits are
+    taken from the first construction.
+    """
+
+    _instances: dict[str, "Cache"] = {}
+
+    def __new__(cls, name: str, *args, **kwargs):
+        """Named singleton pattern - one cache instance per name."""
+        if name not in cls._instances:
+            instance = super().__new__(cls)
+            instance._initialized = False
+            cls._instances[name] = instance
+        return cls._instances[name]
+
+    def __init__(
+        self,
+        name: str,
+        ttl_seconds: Optional[int] = 86400,
+        max_entries: int = DEFAULT_MAX_ENTRIES,
+        max_bytes: Optional[int] = None,
+    ):
+        if self._initialized:
+            return
+        self._initialized = True
+        super().__init__(name, ttl_seconds, max_entries, max_bytes)
 
     @classmethod
     def get_all_stats(cls) -> dict[str, dict]:
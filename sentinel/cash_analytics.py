@@ -0,0 +1,177 @@
+"""
+Cash analytics - idle cash per currency and FX conversion suggestions.
+
+Each currency's planned need is what the account is expected to spend or
+keep in it:
+
+- its `cash_currency_floors` entry and `pending_obligations` in it
+- the planner's current buy recommendations priced in it
+- for EUR, the cash buffer (`min_cash_buffer` / `min_cash_buffer_eur`)
+
+Cash above the need is excess; excess worth at least `cash_idle_min_eur` is
+idle. Its idle duration comes from the valuation snapshots: how long the
+balance has stayed above today's need without a break (within
+LOOKBACK_DAYS).
+
+Conversions are suggested to cover currencies short of their need from
+currencies with excess (reason `shortfall`), then to bring foreign cash that
+has been idle for `cash_idle_days` home to EUR (reason `idle`). EUR has no
+cash sweep target, so idle EUR is reported but not converted. Suggestions
+are only executed when approved through POST /api/cash/conversions/approve,
+which is off unless `cash_conversion_approvals` is enabled.
+"""
+
+from __future__ import annotations
+
+import logging
+import time
+from typing import Any, Awaitable, Callable
+
+from sentinel.planner.liquidity import SettingGetter, load_liquidity_policy
+
+logger = logging.getLogger(__name__)
+
+IDLE_MIN_EUR_KEY = "cash_idle_min_eur"
+IDLE_DAYS_KEY = "cash_idle_days"
+APPROVALS_KEY = "cash_conversion_approvals"
+DEFAULT_IDLE_MIN_EUR = 100.0
+DEFAULT_IDLE_DAYS = 7
+
+LOOKBACK_DAYS = 365
+BASE_CURRENCY = "EUR"
+
+ToEur = Callable[[float, str], Awaitable[float]]
+
+
+def _non_negative(value: Any, default: float) -> float:
+    if isinstance(value, bool) or not isinstance(value, int | float) or value < 0:
+        return default
+    return float(value)
+
+
+def planned_needs(
+    recommendations: list,
+    floors: dict[str, float],
+    obligations: list[dict[str, Any]],
+    buffer_eur: float = 0.0,
+) -> dict[str, dict[str, float]]:
+    """Planned need per currency, broken down into floor, obligations, buys and buffer."""
+    needs: dict[str, dict[str, float]] = {}
+
+    def add(currency: str, part: str, amount: float) -> None:
+        need = needs.setdefault(currency, {"floor": 0.0, "obligations": 0.0, "buys": 0.0, "buffer": 0.0})
+        need[part] += amount
+
+    for currency, amount in floors.items():
+        add(currency, "floor", amount)
+    for item in obligations:
+        add(item["currency"], "obligations", item["amount"])
+    for rec in recommendations:
+        if rec.action == "buy":
+            add((rec.currency or BASE_CURRENCY).upper(), "buys", rec.quantity * rec.price)
+    if buffer_eur:
+        add(BASE_CURRENCY, "buffer", buffer_eur)
+    for need in needs.values():
+        need["total"] = sum(need.values())
+    return needs
+
+
+def idle_since(snapshots: list[dict], currency: str, need: float, min_excess: float) -> int | None:
+    """Start of the latest unbroken run of snapshots with at least `min_excess` above `need`."""
+    since = None
+    for snapshot in reversed(snapshots):
+        balance = (snapshot["data"].get("cash") or {}).get(currency, 0.0)
+        if balance - need < min_excess:
+            break
+        since = snapshot["ts"]
+    return since
+
+
+def suggest_conversions(currencies: dict[str, dict], min_eur: float, idle_days: float) -> list[dict]:
+    """Conversions that cover shortfalls from excess, then bring long-idle foreign cash home."""
+    surplus = {c: info["excess_eur"] for c, info in currencies.items() if info["excess_eur"] >= min_eur}
+    suggestions = []
+
+    def suggest(source: str, target: str, amount_eur: float, reason: str) -> None:
+        rate = currencies[source]["rate_to_eur"]
+        suggestions.append(
+            {
+                "from": source,
+                "to": target,
+                "amount": round(amount_eur / rate, 2),
+                "amount_eur": round(amount_eur, 2),
+                "reason": reason,
+            }
+        )
+        surplus[source] -= amount_eur
+
+    shortfalls = sorted(
+        ((c, -info["excess_eur"]) for c, info in currencies.items() if info["excess_eur"] < 0),
+        key=lambda item: -item[1],
+    )
+    for target, missing in shortfalls:
+        for source in sorted(surplus, key=lambda c: -surplus[c]):
+            if missing <= 0:
+                break
+            amount = min(missing, surplus[source])
+            if source == target or amount < min_eur:
+                continue
+            suggest(source, target, amount, "shortfall")
+            missing -= amount
+
+    for source, info in sorted(currencies.items()):
+        left = surplus.get(source, 0.0)
+        idle = info["idle_days"]
+        if source != BASE_CURRENCY and left >= min_eur and idle is not None and idle >= idle_days:
+            suggest(source, BASE_CURRENCY, left, "idle")
+    return suggestions
+
+
+async def cash_analytics(
+    db,
+    get: SettingGetter,
+    to_eur: ToEur,
+    recommendations: list,
+    total_value_eur: float,
+    now: int | None = None,
+) -> dict[str, Any]:
+    """Per-currency balance, planned need, idle duration and conversion suggestions."""
+    now = int(time.time()) if now is None else now
+    min_eur = _non_negative(await get(IDLE_MIN_EUR_KEY, DEFAULT_IDLE_MIN_EUR), DEFAULT_IDLE_MIN_EUR)
+    idle_days = _non_negative(await get(IDLE_DAYS_KEY, DEFAULT_IDLE_DAYS), DEFAULT_IDLE_DAYS)
+    policy = await load_liquidity_policy(get)
+    reserve = await policy.reserve(total_value_eur, to_eur, include_account=False)
+    needs = planned_needs(recommendations, policy.currency_floors, policy.open_obligations(), reserve["buffer_eur"])
+    balances = await db.get_cash_balances()
+    snapshots = await db.get_valuation_snapshots(start_ts=now - LOOKBACK_DAYS * 86400)
+
+    currencies: dict[str, dict] = {}
+    for currency in sorted(set(balances) | set(needs)):
+        balance = float(balances.get(currency, 0.0))
+        need = needs.get(currency, {"total": 0.0})
+        rate = await to_eur(1.0, currency) or 1.0
+        excess = balance - need["total"]
+        since = idle_since(snapshots, currency, need["total"], min_eur / rate) if excess * rate >= min_eur else None
+        currencies[currency] = {
+            "balance": balance,
+            "need": need,
+            "excess": excess,
+            "excess_eur": excess * rate,
+            "rate_to_eur": rate,
+            "idle_since": since,
+            "idle_days": round((now - since) / 86400, 1) if since is not None else None,
+        }
+    return {
+        "idle_min_eur": min_eur,
+        "idle_days_threshold": idle_days,
+        "currencies": currencies,
+        "suggestions": suggest_conversions(currencies, min_eur, idle_days),
+    }
+
+
+def matching_suggestion(suggestions: list[dict], source: str, target: str, amount: float) -> dict | None:
+    """The current suggestion covering a conversion of `amount` from `source` to `target`."""
+    for suggestion in suggestions:
+        if suggestion["from"] == source and suggestion["to"] == target and 0 < amount <= suggestion["amount"]:
+            return suggestion
+    return None
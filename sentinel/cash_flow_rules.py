@@ -0,0 +1,252 @@
+"""
+Cash flow classification - dividends and taxes behind generic cash flow types.
+
+The ledger, the cash flow summary and cash analytics go by a cash flow's
+`type_id`. Most dividends arrive typed `dividend`, but some come with a
+generic type and the dividend only in the description, and were counted as
+deposits. After every `sync:cashflows` run, and on demand, each flow whose
+type is not one of KNOWN_TYPES is matched against the rules: learned rules,
+newest first, then BUILTIN_RULES. A rule is a case-insensitive regex over
+the description (`comment`), optionally limited to one broker - the
+`source` in the flow's raw data, "tradernet" for synced flows - and the
+type it stands for.
+
+A match rewrites `type_id`, keeps the broker's type in `broker_type_id`
+and the rule in `classified_by`. An ISIN in the description is stored too,
+with its symbol when the security is in the universe. Flows no rule matches
+wait in the review queue; classifying one by hand can learn a rule from its
+description (numbers, dates and ISINs become wildcards), so the next one
+like it is classified automatically.
+
+Usage:
+    classifier = CashFlowClassifier(db)
+    await classifier.classify_pending()
+    await classifier.classify(cash_flow_id, "dividend", learn=True)
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import re
+from typing import Any
+
+from sentinel.identifiers import IdentifierService
+
+logger = logging.getLogger(__name__)
+
+# Types the ledger replays by name (besides *tax* and *commission*).
+KNOWN_TYPES = {
+    "card",
+    "card_payout",
+    "dividend",
+    "block",
+    "unblock",
+    "block_commission",
+    "unblock_commission",
+}
+# Types a flow can be classified as, by a rule or by hand.
+CLASSIFY_TYPES = ("dividend", "tax", "commission", "card", "card_payout")
+DEFAULT_BROKER = "tradernet"
+MAX_PATTERN_LENGTH = 500
+
+# Order matters: "withholding tax on dividend" is a tax.
+BUILTIN_RULES: list[dict[str, Any]] = [
+    {
+        "name": "withholding_tax",
+        "broker": None,
+        "pattern": r"\b(withholding|quellensteuer|retenue)\b",
+        "type_id": "tax",
+    },
+    {
+        "name": "dividend",
+        "broker": None,
+        "pattern": r"\b(dividends?|dividende[n]?|dividendo|div\.|cash distribution)",
+        "type_id": "dividend",
+    },
+]
+
+_ISIN_RE = re.compile(r"\b[A-Z]{2}[A-Z0-9]{9}[0-9]\b")
+# Parts of a description that change from one flow to the next.
+_VARIABLE_RE = re.compile(r"[A-Z]{2}[A-Z0-9]{9}[0-9]\b|\S*\d\S*")
+
+
+def known_type(type_id: str) -> bool:
+    """Whether the ledger replays this cash flow type by name."""
+    return type_id in KNOWN_TYPES or "tax" in type_id or "commission" in type_id
+
+
+def _isin_checksum(isin: str) -> bool:
+    digits = "".join(str(int(c, 36)) for c in isin[:-1])
+    total = 0
+    for i, digit in enumerate(reversed(digits)):
+        value = int(digit) * (2 if i % 2 == 0 else 1)
+        total += value - 9 if value > 9 else value
+    return (10 - total % 10) % 10 == int(isin[-1])
+
+
+def extract_isin(description: str) -> str | None:
+    """The first valid ISIN in a description, or None."""
+    for match in _ISIN_RE.finditer((description or "").upper()):
+        if _isin_checksum(match.group()):
+            return match.group()
+    return None
+
+
+def learn_pattern(description: str) -> str:
+    """A regex matching descriptions like this one: words kept, numbers, dates and ISINs as wildcards."""
+    parts = []
+    position = 0
+    text = " ".join((description or "").split())
+    for match in _VARIABLE_RE.finditer(text):
+        parts.append(re.escape(text[position : match.start()]))
+        parts.append(r"[A-Z]{2}[A-Z0-9]{9}[0-9]" if _ISIN_RE.fullmatch(match.group()) else r"\S+")
+        position = match.end()
+    parts.append(re.escape(text[position:]))
+    return "^" + r"\s+".join("".join(parts).split(r"\ ")) + "$"
+
+
+def validate_rule(data: dict) -> dict[str, Any]:
+    """Validate a rule: {"pattern", "type_id", "broker"?}.
+
+    Raises:
+        ValueError: For an empty, too long or invalid regex, or a type outside CLASSIFY_TYPES.
+    """
+    pattern = data.get("pattern")
+    if not isinstance(pattern, str) or not pattern.strip() or len(pattern) > MAX_PATTERN_LENGTH:
+        raise ValueError(f"pattern must be a regex of at most {MAX_PATTERN_LENGTH} characters")
+    try:
+        re.compile(pattern, re.IGNORECASE)
+    except re.error as e:
+        raise ValueError(f"pattern is not a valid regex: {e}") from e
+    type_id = data.get("type_id")
+    if type_id not in CLASSIFY_TYPES:
+        raise ValueError(f"type_id must be one of {', '.join(CLASSIFY_TYPES)}")
+    broker = data.get("broker")
+    if broker is not None and (not isinstance(broker, str) or not broker.strip()):
+        raise ValueError("broker must be a name or null for any broker")
+    return {"pattern": pattern, "type_id": type_id, "broker": broker.strip() if broker else None}
+
+
+def flow_broker(flow: dict) -> str:
+    """The broker a cash flow came from: its raw data's `source`, else the synced broker."""
+    try:
+        raw = json.loads(flow.get("raw_data") or "{}")
+    except (TypeError, ValueError):
+        raw = {}
+    source = raw.get("source") if isinstance(raw, dict) else None
+    return source if isinstance(source, str) and source else DEFAULT_BROKER
+
+
+def match_rule(flow: dict, rules: list[dict]) -> dict | None:
+    """The first rule whose broker and description pattern match the flow."""
+    description = flow.get("comment") or ""
+    broker = flow_broker(flow)
+    for rule in rules:
+        if rule.get("broker") and rule["broker"] != broker:
+            continue
+        if re.search(rule["pattern"], description, re.IGNORECASE):
+            return rule
+    return None
+
+
+def _rule_label(rule: dict) -> str:
+    return f"rule:{rule['id']}" if "id" in rule else f"builtin:{rule['name']}"
+
+
+class CashFlowClassifier:
+    """Applies classification rules to cash flows and keeps the review queue."""
+
+    def __init__(self, db, identifiers: IdentifierService | None = None):
+        self._db = db
+        self._identifiers = identifiers or IdentifierService(db)
+
+    async def rules(self) -> list[dict]:
+        """Learned rules, newest first, then the built-in ones, in the order they are tried."""
+        return await self._db.get_cash_flow_rules() + BUILTIN_RULES
+
+    async def review_queue(self) -> list[dict]:
+        """Cash flows of a type the ledger does not know, newest first."""
+        flows = await self._db.get_cash_flows()
+        return [
+            {**flow, "broker": flow_broker(flow), "isin": flow.get("isin") or extract_isin(flow.get("comment") or "")}
+            for flow in flows
+            if not known_type(str(flow.get("type_id") or ""))
+        ]
+
+    async def _apply(self, flow: dict, type_id: str, classified_by: str, symbol: str | None = None) -> None:
+        isin = extract_isin(flow.get("comment") or "")
+        if symbol is None and isin:
+            symbol = await self._identifiers.resolve(isin)
+        await self._db.classify_cash_flow(
+            flow["id"], type_id=type_id, classified_by=classified_by, isin=isin, symbol=symbol
+        )
+
+    async def classify_pending(self) -> dict[str, int]:
+        """Match every flow in the review queue against the rules.
+
+        Returns:
+            {"classified": N, "pending": M}: flows classified now, and left in the queue.
+        """
+        rules = await self.rules()
+        classified = 0
+        pending = 0
+        for flow in await self.review_queue():
+            rule = match_rule(flow, rules)
+            if rule is None:
+                pending += 1
+                continue
+            await self._apply(flow, rule["type_id"], _rule_label(rule))
+            classified += 1
+        if classified:
+            logger.info(f"Classified {classified} cash flow(s) by rule, {pending} left for review")
+            await self._db.invalidate_planner_cache()
+        return {"classified": classified, "pending": pending}
+
+    async def classify(
+        self,
+        cash_flow_id: int,
+        type_id: str,
+        symbol: str | None = None,
+        learn: bool = True,
+        pattern: str | None = None,
+    ) -> dict[str, Any] | None:
+        """Classify one cash flow by hand, optionally learning a rule for its broker.
+
+        Without `pattern`, the rule is learned from the flow's description.
+        The new rule is applied to the rest of the review queue at once.
+
+        Returns:
+            {"cash_flow", "rule", "classified"}: the updated flow, the learned
+            rule (or None) and how many other flows it classified; None when
+            the cash flow does not exist.
+
+        Raises:
+            ValueError: For a type outside CLASSIFY_TYPES, an unknown symbol, an
+                invalid pattern, or learning from a flow without a description.
+        """
+        if type_id not in CLASSIFY_TYPES:
+            raise ValueError(f"type_id must be one of {', '.join(CLASSIFY_TYPES)}")
+        flow = await self._db.get_cash_flow(cash_flow_id)
+        if flow is None:
+            return None
+        if symbol is not None:
+            resolved = await self._identifiers.resolve(symbol)
+            if resolved is None:
+                raise ValueError(f"unknown symbol {symbol}")
+            symbol = resolved
+
+        rule = None
+        if learn:
+            if pattern is None and not (flow.get("comment") or "").strip():
+                raise ValueError("the cash flow has no description to learn a rule from; pass a pattern")
+            rule = validate_rule(
+                {"pattern": pattern or learn_pattern(flow["comment"]), "type_id": type_id, "broker": flow_broker(flow)}
+            )
+        await self._apply(flow, type_id, "manual", symbol)
+        classified = 0
+        if rule is not None:
+            rule["id"] = await self._db.create_cash_flow_rule(**rule, created_from=cash_flow_id)
+            classified = (await self.classify_pending())["classified"]
+        await self._db.invalidate_planner_cache()
+        return {"cash_flow": await self._db.get_cash_flow(cash_flow_id), "rule": rule, "classified": classified}
@@ -0,0 +1,237 @@
+"""
+Time source for scheduling, and the system clock sanity check.
+
+Code that makes time-based decisions (the job runner, market checkers,
+the schedule audit) reads the time from a Clock instead of calling
+datetime.now(). SystemClock is the real one; FakeClock only moves when told
+to, so tests can step through days of market opens and closes.
+
+SystemClock is only as right as the host: small boards without a
+battery-backed RTC can boot with (or drift to) the wrong time. The sanity
+check measures the local clock's offset against an SNTP server
+(`clock_ntp_server`) and, if that is unreachable, against the Date header of
+the broker's website (1 s resolution). An offset beyond
+`clock_drift_threshold_seconds` marks the clock as suspect: a warning is
+logged and the critical buzzer alert sounds once when the drift is first
+seen. The runner annotates market timing decisions made while the clock is
+suspect.
+
+The last result is kept in planner state under CLOCK_STATE_KEY. A failed
+check keeps the previous verdict.
+
+Usage:
+    now = SystemClock().now()
+    state = await ClockMonitor(db).check()
+    offset = await suspected_drift(db)  # None unless the clock is suspect
+"""
+
+from __future__ import annotations
+
+import asyncio
+import logging
+import socket
+import struct
+import time
+from datetime import datetime, timedelta, timezone
+from email.utils import parsedate_to_datetime
+from typing import Any, Protocol
+
+import httpx
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+CLOCK_STATE_KEY = "clock:drift"
+DRIFT_THRESHOLD_KEY = "clock_drift_threshold_seconds"
+NTP_SERVER_KEY = "clock_ntp_server"
+DEFAULT_THRESHOLD_SECONDS = 30.0
+DEFAULT_NTP_SERVER = "pool.ntp.org"
+
+# Fallback time source: any HTTPS server returns its clock in the Date header.
+HTTP_TIME_URL = "https://tradernet.com"
+
+NTP_TIMEOUT_SEC = 3.0
+# Seconds between the NTP epoch (1900) and the Unix epoch (1970).
+_NTP_EPOCH_DELTA = 2208988800
+
+
+class Clock(Protocol):
+    """Source of the current time."""
+
+    def now(self) -> datetime:
+        """Current time, timezone-aware UTC."""
+        ...
+
+
+class SystemClock:
+    """The host's clock."""
+
+    def now(self) -> datetime:
+        return datetime.now(timezone.utc)
+
+
+class FakeClock:
+    """A clock that stands still until advanced."""
+
+    def __init__(self, start: datetime):
+        if start.tzinfo is None:
+            raise ValueError("FakeClock needs a timezone-aware start time")
+        self._now = start.astimezone(timezone.utc)
+
+    def now(self) -> datetime:
+        return self._now
+
+    def advance(self, delta: timedelta) -> datetime:
+        """Move forward by `delta` and return the new time."""
+        if delta < timedelta(0):
+            raise ValueError("FakeClock cannot go backwards")
+        self._now += delta
+        return self._now
+
+    def set(self, moment: datetime) -> None:
+        """Jump to an aware `moment` (forwards or backwards)."""
+        if moment.tzinfo is None:
+            raise ValueError("FakeClock needs a timezone-aware time")
+        self._now = moment.astimezone(timezone.utc)
+
+
+def validate_drift_threshold(value: Any) -> float:
+    """Validate the drift threshold in seconds.
+
+    Raises:
+        ValueError: If it is not a positive number.
+    """
+    if isinstance(value, bool) or not isinstance(value, int | float) or value <= 0:
+        raise ValueError("clock_drift_threshold_seconds must be a positive number")
+    return float(value)
+
+
+def _ntp_time(data: bytes) -> float:
+    seconds, fraction = struct.unpack("!II", data)
+    return seconds - _NTP_EPOCH_DELTA + fraction / 2**32
+
+
+def sntp_offset(server: str, port: int = 123, timeout: float = NTP_TIMEOUT_SEC) -> float:
+    """Offset of the NTP server's clock from the local one, in seconds (blocking).
+
+    Positive means the local clock is behind.
+
+    Raises:
+        OSError: If the server cannot be reached.
+        ValueError: If the reply is malformed.
+    """
+    request = b"\x1b" + 47 * b"\0"  # LI 0, version 3, client mode
+    with socket.socket(socket.AF_INET, socket.SOCK_DGRAM) as sock:
+        sock.settimeout(timeout)
+        sent = time.time()
+        sock.sendto(request, (server, port))
+        data, _ = sock.recvfrom(512)
+        received = time.time()
+    if len(data) < 48:
+        raise ValueError(f"short NTP reply ({len(data)} bytes)")
+    server_received = _ntp_time(data[32:40])
+    server_sent = _ntp_time(data[40:48])
+    if server_sent <= 0:
+        raise ValueError("NTP reply has no transmit timestamp")
+    return ((server_received - sent) + (server_sent - received)) / 2
+
+
+async def http_date_offset(url: str = HTTP_TIME_URL) -> float:
+    """Offset of a web server's Date header from the local clock, in seconds.
+
+    Raises:
+        httpx.HTTPError: If the request fails.
+        ValueError: If the response has no usable Date header.
+    """
+    async with httpx.AsyncClient(timeout=NTP_TIMEOUT_SEC, follow_redirects=False) as client:
+        sent = time.time()
+        response = await client.head(url)
+        received = time.time()
+    date = response.headers.get("date")
+    if not date:
+        raise ValueError(f"no Date header from {url}")
+    return parsedate_to_datetime(date).timestamp() - (sent + received) / 2
+
+
+class ClockMonitor:
+    """Measures clock drift and records whether the clock is suspect."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def state(self) -> dict[str, Any] | None:
+        """The last check's result, or None if the clock was never checked."""
+        return await self._db.get_planner_state(CLOCK_STATE_KEY)
+
+    async def check(self) -> dict[str, Any]:
+        """Measure the offset, persist the verdict and alert on new drift.
+
+        Returns:
+            {"offset_seconds", "source", "threshold_seconds", "suspect",
+             "checked_at", "error"}. `offset_seconds` and `source` are None
+            when no time source answered.
+        """
+        try:
+            threshold = validate_drift_threshold(
+                await self._settings.get(DRIFT_THRESHOLD_KEY, DEFAULT_THRESHOLD_SECONDS)
+            )
+        except ValueError:
+            threshold = DEFAULT_THRESHOLD_SECONDS
+        server = await self._settings.get(NTP_SERVER_KEY, DEFAULT_NTP_SERVER) or DEFAULT_NTP_SERVER
+        offset, source, errors = await self._measure(server)
+
+        previous = await self.state() or {}
+        if offset is None:
+            logger.warning(f"Clock check failed: {'; '.join(errors)}")
+            suspect = bool(previous.get("suspect"))
+        else:
+            suspect = abs(offset) > threshold
+
+        state = {
+            "offset_seconds": round(offset, 3) if offset is not None else None,
+            "source": source,
+            "threshold_seconds": threshold,
+            "suspect": suspect,
+            "checked_at": int(time.time()),
+            "error": "; ".join(errors) or None,
+        }
+        await self._db.set_planner_state(CLOCK_STATE_KEY, state)
+
+        if offset is not None and suspect:
+            logger.warning(f"System clock is off by {offset:+.1f}s per {source} (threshold {threshold:.0f}s)")
+            if not previous.get("suspect"):
+                await self._alert()
+        elif offset is not None and previous.get("suspect"):
+            logger.info(f"System clock back in sync ({offset:+.1f}s per {source})")
+        return state
+
+    async def _measure(self, server: str) -> tuple[float | None, str | None, list[str]]:
+        errors = []
+        try:
+            return await asyncio.to_thread(sntp_offset, server), f"ntp:{server}", errors
+        except (OSError, ValueError) as e:
+            errors.append(f"ntp {server}: {e}")
+        try:
+            return await http_date_offset(), f"http:{HTTP_TIME_URL}", errors
+        except (httpx.HTTPError, ValueError, TypeError) as e:
+            errors.append(f"http {HTTP_TIME_URL}: {e}")
+        return None, None, errors
+
+    async def _alert(self) -> None:
+        try:
+            from sentinel.led.alerts import ALERT_CRITICAL, AlertManager
+
+            await AlertManager(self._settings).trigger(ALERT_CRITICAL)
+        except Exception as e:
+            logger.warning(f"Failed to queue clock drift alert: {e}")
+
+
+async def suspected_drift(db) -> float | None:
+    """The measured offset in seconds while the clock is suspect, else None."""
+    state = await db.get_planner_state(CLOCK_STATE_KEY)
+    if not isinstance(state, dict) or not state.get("suspect"):
+        return None
+    return state.get("offset_seconds") or 0.0
@@ -0,0 +1,181 @@
+"""
+Concentration breaches - single positions that grow too large a share of the portfolio.
+
+`concentration_alert_levels` sets two weight levels in percent of the
+portfolio, {"warn_pct": 15, "critical_pct": 20} by default. After each
+planning:refresh, ConcentrationMonitor compares the current position weights
+with them and keeps one open row per breaching security in
+`concentration_breaches`:
+
+- a position reaching warn_pct opens a breach (a warning is logged)
+- reaching critical_pct escalates it: the critical LED alert sounds once per
+  check that escalates anything, and a reduction plan is attached (see below)
+- falling back between the levels de-escalates it without an alert
+- falling below warn_pct resolves it; started_at..resolved_at is how long the
+  breach lasted
+
+The reduction plan is a planner dry run with a hard target of warn_pct on the
+breaching security, keeping only that security's trades. It is stored on the
+breach for review (GET /api/portfolio/concentration); nothing is executed.
+
+Usage:
+    result = await check_concentration(db, planner, broker)
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import math
+import time
+from typing import Any
+
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+CONCENTRATION_LEVELS_KEY = "concentration_alert_levels"
+DEFAULT_LEVELS = {"warn_pct": 15.0, "critical_pct": 20.0}
+
+
+def validate_concentration_levels(raw: Any) -> dict[str, float]:
+    """Validate a `concentration_alert_levels` value into {"warn_pct", "critical_pct"}.
+
+    Raises:
+        ValueError: If a level is missing or malformed, or warn_pct is not below critical_pct.
+    """
+    if not isinstance(raw, dict) or set(raw) != set(DEFAULT_LEVELS):
+        raise ValueError(f"{CONCENTRATION_LEVELS_KEY} must be an object with warn_pct and critical_pct")
+    levels = {}
+    for key, value in raw.items():
+        if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value):
+            raise ValueError(f"{CONCENTRATION_LEVELS_KEY}.{key} must be a number")
+        if not 0 < value <= 100:
+            raise ValueError(f"{CONCENTRATION_LEVELS_KEY}.{key} must be between 0 and 100")
+        levels[key] = float(value)
+    if levels["warn_pct"] >= levels["critical_pct"]:
+        raise ValueError(f"{CONCENTRATION_LEVELS_KEY}.warn_pct must be below critical_pct")
+    return levels
+
+
+def level_for(weight_pct: float, levels: dict[str, float]) -> str | None:
+    """The breach level of a position weight, or None below warn_pct."""
+    if weight_pct >= levels["critical_pct"]:
+        return "critical"
+    if weight_pct >= levels["warn_pct"]:
+        return "warn"
+    return None
+
+
+def with_duration(breach: dict, now: int | None = None) -> dict:
+    """A breach row with `duration_seconds` (up to now while it is open)."""
+    end = breach.get("resolved_at") or (now if now is not None else int(time.time()))
+    plan = json.loads(breach["plan"]) if breach.get("plan") else None
+    return {**breach, "plan": plan, "duration_seconds": end - breach["started_at"]}
+
+
+class ConcentrationMonitor:
+    """Opens, escalates and resolves concentration breaches from position weights."""
+
+    def __init__(self, db, settings: Settings | None = None):
+        self._db = db
+        self._settings = settings
+
+    async def levels(self) -> dict[str, float]:
+        value = await self._db.get_setting(CONCENTRATION_LEVELS_KEY)
+        try:
+            return dict(DEFAULT_LEVELS) if value is None else validate_concentration_levels(value)
+        except ValueError:
+            return dict(DEFAULT_LEVELS)
+
+    async def check(self, weights: dict[str, float], now: int | None = None) -> dict[str, list[str]]:
+        """Update breaches from weights (symbol -> fraction 0-1).
+
+        Returns the symbols whose breach was opened, escalated to critical or
+        resolved by this check.
+        """
+        now = now if now is not None else int(time.time())
+        levels = await self.levels()
+        open_breaches = {breach["symbol"]: breach for breach in await self._db.get_concentration_breaches()}
+        result: dict[str, list[str]] = {"opened": [], "escalated": [], "resolved": []}
+
+        for symbol, weight in sorted(weights.items()):
+            pct = weight * 100
+            level = level_for(pct, levels)
+            breach = open_breaches.pop(symbol, None)
+            if level is None:
+                if breach:
+                    open_breaches[symbol] = breach
+                continue
+            if breach is None:
+                await self._db.open_concentration_breach(symbol, level, pct, now)
+                result["opened"].append(symbol)
+                logger.warning(f"{symbol} is {pct:.1f}% of the portfolio ({level} level)")
+            else:
+                escalated_at = now if level == "critical" and breach["level"] != "critical" else None
+                await self._db.update_concentration_breach(breach["id"], level, pct, escalated_at)
+            if level == "critical" and (breach is None or breach["level"] != "critical"):
+                result["escalated"].append(symbol)
+
+        for symbol, breach in open_breaches.items():
+            await self._db.resolve_concentration_breach(breach["id"], weights.get(symbol, 0.0) * 100, now)
+            result["resolved"].append(symbol)
+            logger.info(f"{symbol} concentration breach resolved after {now - breach['started_at']}s")
+
+        if result["escalated"]:
+            await self._alert(result["escalated"], levels)
+        return result
+
+    async def _alert(self, symbols: list[str], levels: dict[str, float]) -> None:
+        logger.error(
+            f"{', '.join(symbols)} above the critical concentration of {levels['critical_pct']:g}%; "
+            "review /api/portfolio/concentration"
+        )
+        try:
+            from sentinel.led.alerts import ALERT_CRITICAL, AlertManager
+
+            await AlertManager(self._settings or Settings()).trigger(ALERT_CRITICAL)
+        except Exception as e:
+            logger.warning(f"Failed to queue concentration alert: {e}")
+
+
+async def plan_reduction(db, broker, symbol: str, target_pct: float) -> dict:
+    """Dry-run the planner with `symbol` pinned at target_pct; returns its trades for that symbol."""
+    from sentinel.planner.dry_run import DryRunOverrides, run_dry_run
+
+    overrides = DryRunOverrides(position_targets={symbol: {"target_pct": target_pct, "mode": "hard"}})
+    recommendations, _plan, _state = await run_dry_run(overrides, db=db, broker=broker)
+    return {
+        "target_pct": target_pct,
+        "trades": [
+            {
+                "action": r.action,
+                "quantity": r.quantity,
+                "price": r.price,
+                "currency": r.currency,
+                "value_delta_eur": r.value_delta_eur,
+                "current_allocation": r.current_allocation,
+                "target_allocation": r.target_allocation,
+            }
+            for r in recommendations
+            if r.symbol == symbol
+        ],
+    }
+
+
+async def check_concentration(db, planner, broker, settings: Settings | None = None) -> dict[str, list[str]]:
+    """Check the live weights and attach a reduction plan to newly critical breaches."""
+    monitor = ConcentrationMonitor(db, settings)
+    result = await monitor.check(await planner.get_current_allocations())
+    if not result["escalated"]:
+        return result
+    levels = await monitor.levels()
+    breaches = {breach["symbol"]: breach for breach in await db.get_concentration_breaches()}
+    for symbol in result["escalated"]:
+        try:
+            plan = await plan_reduction(db, broker, symbol, levels["warn_pct"])
+        except Exception as e:
+            logger.warning(f"Failed to plan concentration reduction for {symbol}: {e}")
+            continue
+        await db.set_concentration_breach_plan(breaches[symbol]["id"], json.dumps(plan))
+    return result
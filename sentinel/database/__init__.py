@@ -5,7 +5,8 @@ Provides database access for Sentinel application.
 """
 
 from sentinel.database.base import BaseDatabase
+from sentinel.database.config import PROFILES, Config
 from sentinel.database.main import Database
 from sentinel.database.simulation import SimulationDatabase
 
-__all__ = ["Database", "BaseDatabase", "SimulationDatabase"]
+__all__ = ["Database", "BaseDatabase", "SimulationDatabase", "Config", "PROFILES"]
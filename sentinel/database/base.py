@@ -9,6 +9,42 @@ from typing import Optional
 
 import aiosqlite
 
+SAVINGS_PLAN_FIELDS = ("name", "amount", "currency", "day_of_month", "tolerance_pct", "window_days", "start_date")
+GOAL_FIELDS = ("name", "target_amount_eur", "target_date", "priority")
+# Tables whose rows belong to one security through their `symbol` column,
+# re-keyed when a duplicate is merged (see merge_security).
+SECURITY_HISTORY_TABLES = (
+    "prices",
+    "trades",
+    "dividends",
+    "orders",
+    "earnings_dates",
+    "security_indicators",
+    "price_quarantine",
+    "price_staging",
+    "concentration_breaches",
+    "forecast_points",
+    "forecast_scores",
+    "forecast_evaluations",
+    "news_headlines",
+    "news_sentiment",
+    "strategy_state",
+    "factor_exposures",
+    "security_group_members",
+    "security_notes",
+    "security_attachments",
+)
+EXCLUSION_LIST_FIELDS = ("name", "reason", "symbols", "isins", "industries")
+EXCLUSION_LIST_JSON_FIELDS = ("symbols", "isins", "industries")
+
+
+def cash_flow_hash(raw_data: dict) -> str:
+    """The key upsert_cash_flow deduplicates a cash flow on."""
+    import hashlib
+    import json
+
+    return hashlib.sha256(json.dumps(raw_data, sort_keys=True).encode()).hexdigest()[:32]
+
 
 class BaseDatabase:
     """Base class with shared database operations."""
@@ -76,6 +112,11 @@ class BaseDatabase:
         rows = await cursor.fetchall()
         return [dict(row) for row in rows]
 
+    async def get_short_positions(self) -> list[dict]:
+        """Get positions with a negative quantity (see sentinel.exposure)."""
+        cursor = await self.conn.execute("SELECT * FROM positions WHERE quantity < 0")
+        return [dict(row) for row in await cursor.fetchall()]
+
     async def upsert_position(self, symbol: str, **data) -> None:
         """Insert or update a position."""
         existing = await self.get_position(symbol)
@@ -177,7 +218,8 @@ class BaseDatabase:
             ),
         )
         await self.conn.commit()
-        return cursor.lastrowid or 0
+        # lastrowid keeps the previous insert's id when the row is ignored
+        return (cursor.lastrowid or 0) if cursor.rowcount else 0
 
     def _build_trades_where(
         self,
@@ -286,6 +328,19 @@ class BaseDatabase:
         row = await cursor.fetchone()
         return row[0] if row else 0
 
+    async def existing_trade_ids(self, broker_trade_ids: list[str]) -> set[str]:
+        """Which of these broker trade IDs are already in the ledger."""
+        found: set[str] = set()
+        for i in range(0, len(broker_trade_ids), 500):
+            chunk = broker_trade_ids[i : i + 500]
+            placeholders = ",".join("?" * len(chunk))
+            cursor = await self.conn.execute(
+                f"SELECT broker_trade_id FROM trades WHERE broker_trade_id IN ({placeholders})",  # noqa: S608
+                chunk,
+            )
+            found.update(row[0] for row in await cursor.fetchall())
+        return found
+
     async def get_latest_trades_for_symbols(self, symbols: list[str]) -> dict[str, dict]:
         """Get latest trade row per symbol.
 
@@ -358,11 +413,10 @@ class BaseDatabase:
 
         Returns row id if inserted, 0 if already exists.
         """
-        import hashlib
         import json
 
         raw_json = json.dumps(raw_data, sort_keys=True)
-        content_hash = hashlib.sha256(raw_json.encode()).hexdigest()[:32]
+        content_hash = cash_flow_hash(raw_data)
 
         cursor = await self.conn.execute(
             """INSERT OR IGNORE INTO cash_flows
@@ -371,7 +425,20 @@ class BaseDatabase:
             (content_hash, date, type_id, amount, currency, comment, raw_json),
         )
         await self.conn.commit()
-        return cursor.lastrowid or 0
+        return (cursor.lastrowid or 0) if cursor.rowcount else 0
+
+    async def existing_cash_flow_hashes(self, hashes: list[str]) -> set[str]:
+        """Which of these cash flow content hashes (cash_flow_hash) are already stored."""
+        found: set[str] = set()
+        for i in range(0, len(hashes), 500):
+            chunk = hashes[i : i + 500]
+            placeholders = ",".join("?" * len(chunk))
+            cursor = await self.conn.execute(
+                f"SELECT content_hash FROM cash_flows WHERE content_hash IN ({placeholders})",  # noqa: S608
+                chunk,
+            )
+            found.update(row[0] for row in await cursor.fetchall())
+        return found
 
     async def get_cash_flows(
         self,
@@ -436,6 +503,172 @@ class BaseDatabase:
 
         return summary
 
+    async def get_cash_flow(self, cash_flow_id: int) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM cash_flows WHERE id = ?", (cash_flow_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def classify_cash_flow(
+        self,
+        cash_flow_id: int,
+        type_id: str,
+        classified_by: str,
+        isin: str | None = None,
+        symbol: str | None = None,
+    ) -> bool:
+        """Set a cash flow's type, keeping the broker's original type in broker_type_id."""
+        cursor = await self.conn.execute(
+            """UPDATE cash_flows
+               SET broker_type_id = COALESCE(broker_type_id, type_id),
+                   type_id = ?, classified_by = ?, isin = ?, symbol = ?
+               WHERE id = ?""",
+            (type_id, classified_by, isin, symbol, cash_flow_id),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # Cash Flow Rules
+    # -------------------------------------------------------------------------
+
+    async def create_cash_flow_rule(
+        self,
+        pattern: str,
+        type_id: str,
+        broker: str | None = None,
+        created_from: int | None = None,
+    ) -> int:
+        cursor = await self.conn.execute(
+            "INSERT INTO cash_flow_rules (pattern, type_id, broker, created_from) VALUES (?, ?, ?, ?)",
+            (pattern, type_id, broker, created_from),
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_cash_flow_rules(self) -> list[dict]:
+        """Learned cash flow rules, newest first."""
+        cursor = await self.conn.execute("SELECT * FROM cash_flow_rules ORDER BY id DESC")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def delete_cash_flow_rule(self, rule_id: int) -> bool:
+        cursor = await self.conn.execute("DELETE FROM cash_flow_rules WHERE id = ?", (rule_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # Savings Plans
+    # -------------------------------------------------------------------------
+
+    async def create_savings_plan(self, **fields) -> int:
+        """Create a savings plan from SAVINGS_PLAN_FIELDS values; returns its id."""
+        columns = [key for key in SAVINGS_PLAN_FIELDS if key in fields]
+        cursor = await self.conn.execute(
+            f"INSERT INTO savings_plans ({', '.join(columns)}) VALUES ({', '.join('?' for _ in columns)})",
+            [fields[key] for key in columns],
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_savings_plans(self, active_only: bool = False) -> list[dict]:
+        """Get savings plans, oldest first."""
+        query = "SELECT * FROM savings_plans"
+        if active_only:
+            query += " WHERE active = 1"
+        cursor = await self.conn.execute(query + " ORDER BY id")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_savings_plan(self, plan_id: int) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM savings_plans WHERE id = ?", (plan_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def update_savings_plan(self, plan_id: int, **fields) -> bool:
+        """Update SAVINGS_PLAN_FIELDS and `active`; returns False if the plan does not exist."""
+        columns = [key for key in (*SAVINGS_PLAN_FIELDS, "active") if key in fields]
+        if not columns:
+            return await self.get_savings_plan(plan_id) is not None
+        cursor = await self.conn.execute(
+            f"UPDATE savings_plans SET {', '.join(f'{key} = ?' for key in columns)} WHERE id = ?",
+            [*(fields[key] for key in columns), plan_id],
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def delete_savings_plan(self, plan_id: int) -> bool:
+        """Delete a savings plan and its matched deposits."""
+        await self.conn.execute("DELETE FROM savings_plan_deposits WHERE plan_id = ?", (plan_id,))
+        cursor = await self.conn.execute("DELETE FROM savings_plans WHERE id = ?", (plan_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def record_savings_deposit(
+        self,
+        cash_flow_id: int,
+        plan_id: int,
+        period: str,
+        date: str,
+        amount_eur: float,
+    ) -> bool:
+        """Match a cash flow to a plan period; returns False if either is already matched."""
+        cursor = await self.conn.execute(
+            """INSERT OR IGNORE INTO savings_plan_deposits (cash_flow_id, plan_id, period, date, amount_eur)
+               VALUES (?, ?, ?, ?, ?)""",
+            (cash_flow_id, plan_id, period, date, amount_eur),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def get_savings_deposits(
+        self,
+        plan_id: int | None = None,
+        start_date: str | None = None,
+        limit: int = 100,
+    ) -> list[dict]:
+        """Get matched savings deposits, newest first."""
+        query = "SELECT * FROM savings_plan_deposits WHERE 1=1"
+        params: list = []
+        if plan_id is not None:
+            query += " AND plan_id = ?"
+            params.append(plan_id)
+        if start_date:
+            query += " AND date >= ?"
+            params.append(start_date)
+        query += " ORDER BY date DESC, cash_flow_id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_savings_new_money_eur(self, start_date: str) -> float:
+        """Matched deposit value on or after start_date not yet spent by new-money buys."""
+        cursor = await self.conn.execute(
+            "SELECT COALESCE(SUM(MAX(amount_eur - invested_eur, 0)), 0) FROM savings_plan_deposits WHERE date >= ?",
+            (start_date,),
+        )
+        row = await cursor.fetchone()
+        return float(row[0] or 0.0)
+
+    async def mark_savings_invested(self, value_eur: float, start_date: str) -> float:
+        """Spend value_eur against uninvested deposits, oldest first; returns the amount applied."""
+        cursor = await self.conn.execute(
+            """SELECT cash_flow_id, amount_eur - invested_eur AS remaining
+               FROM savings_plan_deposits
+               WHERE date >= ? AND invested_eur < amount_eur
+               ORDER BY date, cash_flow_id""",
+            (start_date,),
+        )
+        applied = 0.0
+        for row in await cursor.fetchall():
+            if applied >= value_eur:
+                break
+            portion = min(float(row["remaining"]), value_eur - applied)
+            await self.conn.execute(
+                "UPDATE savings_plan_deposits SET invested_eur = invested_eur + ? WHERE cash_flow_id = ?",
+                (portion, row["cash_flow_id"]),
+            )
+            applied += portion
+        await self.conn.commit()
+        return applied
+
     # -------------------------------------------------------------------------
     # Dividends
     # -------------------------------------------------------------------------
@@ -474,7 +707,7 @@ class BaseDatabase:
             (id, symbol, date, amount, currency, value, json.dumps(data)),
         )
         await self.conn.commit()
-        return cursor.lastrowid or 0
+        return (cursor.lastrowid or 0) if cursor.rowcount else 0
 
     async def get_dividends(
         self,
@@ -534,6 +767,637 @@ class BaseDatabase:
         rows = await cursor.fetchall()
         return {row["symbol"]: row["pool"] for row in rows}
 
+    # -------------------------------------------------------------------------
+    # Investment Goals
+    # -------------------------------------------------------------------------
+
+    async def create_goal(self, **fields) -> int:
+        """Create an investment goal from GOAL_FIELDS values; returns its id."""
+        columns = [key for key in GOAL_FIELDS if key in fields]
+        cursor = await self.conn.execute(
+            f"INSERT INTO investment_goals ({', '.join(columns)}) VALUES ({', '.join('?' for _ in columns)})",
+            [fields[key] for key in columns],
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_goals(self, active_only: bool = False) -> list[dict]:
+        """Get investment goals, most important first, then by date."""
+        query = "SELECT * FROM investment_goals"
+        if active_only:
+            query += " WHERE active = 1"
+        cursor = await self.conn.execute(query + " ORDER BY priority, target_date, id")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_goal(self, goal_id: int) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM investment_goals WHERE id = ?", (goal_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def update_goal(self, goal_id: int, **fields) -> bool:
+        """Update GOAL_FIELDS and `active`; returns False if the goal does not exist."""
+        columns = [key for key in (*GOAL_FIELDS, "active") if key in fields]
+        if not columns:
+            return await self.get_goal(goal_id) is not None
+        cursor = await self.conn.execute(
+            f"UPDATE investment_goals SET {', '.join(f'{key} = ?' for key in columns)} WHERE id = ?",
+            [*(fields[key] for key in columns), goal_id],
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def delete_goal(self, goal_id: int) -> bool:
+        cursor = await self.conn.execute("DELETE FROM investment_goals WHERE id = ?", (goal_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # Temperament Profiles
+    # -------------------------------------------------------------------------
+
+    @staticmethod
+    def _temperament_row(row) -> dict:
+        import json
+
+        item = dict(row)
+        for key in ("answers", "scores", "settings"):
+            item[key] = json.loads(item[key]) if item.get(key) else {}
+        return item
+
+    async def create_temperament_profile(self, revision: int, answers: dict, scores: dict, settings: dict) -> int:
+        """Store a questionnaire take; returns its version."""
+        import json
+
+        cursor = await self.conn.execute(
+            "INSERT INTO temperament_profiles (revision, answers, scores, settings) VALUES (?, ?, ?, ?)",
+            (revision, json.dumps(answers), json.dumps(scores), json.dumps(settings)),
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_temperament_profiles(self) -> list[dict]:
+        """Get every questionnaire take, newest first."""
+        cursor = await self.conn.execute("SELECT * FROM temperament_profiles ORDER BY version DESC")
+        return [self._temperament_row(row) for row in await cursor.fetchall()]
+
+    async def get_temperament_profile(self, version: int | None = None) -> dict | None:
+        """Get one take by version, or the latest one."""
+        if version is None:
+            cursor = await self.conn.execute("SELECT * FROM temperament_profiles ORDER BY version DESC LIMIT 1")
+        else:
+            cursor = await self.conn.execute("SELECT * FROM temperament_profiles WHERE version = ?", (version,))
+        row = await cursor.fetchone()
+        return self._temperament_row(row) if row else None
+
+    async def mark_temperament_profile_applied(self, version: int, applied_at: int) -> None:
+        await self.conn.execute(
+            "UPDATE temperament_profiles SET applied_at = ? WHERE version = ?", (applied_at, version)
+        )
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Exclusion Lists
+    # -------------------------------------------------------------------------
+
+    @staticmethod
+    def _exclusion_row(row) -> dict:
+        import json
+
+        item = dict(row)
+        for key in EXCLUSION_LIST_JSON_FIELDS:
+            item[key] = json.loads(item[key]) if item.get(key) else []
+        return item
+
+    @staticmethod
+    def _exclusion_values(fields: dict, columns: list[str]) -> list:
+        import json
+
+        return [json.dumps(fields[key]) if key in EXCLUSION_LIST_JSON_FIELDS else fields[key] for key in columns]
+
+    async def create_exclusion_list(self, **fields) -> int:
+        """Create an exclusion list from EXCLUSION_LIST_FIELDS values; returns its id."""
+        columns = [key for key in EXCLUSION_LIST_FIELDS if key in fields]
+        cursor = await self.conn.execute(
+            f"INSERT INTO exclusion_lists ({', '.join(columns)}) VALUES ({', '.join('?' for _ in columns)})",
+            self._exclusion_values(fields, columns),
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_exclusion_lists(self, active_only: bool = False) -> list[dict]:
+        """Get exclusion lists with decoded symbols/isins/industries, oldest first."""
+        query = "SELECT * FROM exclusion_lists"
+        if active_only:
+            query += " WHERE active = 1"
+        cursor = await self.conn.execute(query + " ORDER BY id")
+        return [self._exclusion_row(row) for row in await cursor.fetchall()]
+
+    async def get_exclusion_list(self, list_id: int) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM exclusion_lists WHERE id = ?", (list_id,))
+        row = await cursor.fetchone()
+        return self._exclusion_row(row) if row else None
+
+    async def update_exclusion_list(self, list_id: int, **fields) -> bool:
+        """Update EXCLUSION_LIST_FIELDS and `active`; returns False if the list does not exist."""
+        columns = [key for key in (*EXCLUSION_LIST_FIELDS, "active") if key in fields]
+        if not columns:
+            return await self.get_exclusion_list(list_id) is not None
+        cursor = await self.conn.execute(
+            f"UPDATE exclusion_lists SET {', '.join(f'{key} = ?' for key in columns)} WHERE id = ?",
+            [*self._exclusion_values(fields, columns), list_id],
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def delete_exclusion_list(self, list_id: int) -> bool:
+        cursor = await self.conn.execute("DELETE FROM exclusion_lists WHERE id = ?", (list_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # Security Groups
+    # -------------------------------------------------------------------------
+
+    async def get_security_groups(self) -> list[dict]:
+        """Get security groups with their member symbols, by name."""
+        cursor = await self.conn.execute("SELECT * FROM security_groups ORDER BY name COLLATE NOCASE")
+        groups = [dict(row) for row in await cursor.fetchall()]
+        cursor = await self.conn.execute("SELECT symbol, group_id FROM security_group_members ORDER BY symbol")
+        members: dict[int, list[str]] = {}
+        for row in await cursor.fetchall():
+            members.setdefault(row["group_id"], []).append(row["symbol"])
+        for group in groups:
+            group["symbols"] = members.get(group["id"], [])
+        return groups
+
+    async def get_security_group(self, group_id: int) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM security_groups WHERE id = ?", (group_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        group = dict(row)
+        cursor = await self.conn.execute(
+            "SELECT symbol FROM security_group_members WHERE group_id = ? ORDER BY symbol", (group_id,)
+        )
+        group["symbols"] = [member["symbol"] for member in await cursor.fetchall()]
+        return group
+
+    async def get_security_group_by_name(self, name: str) -> dict | None:
+        cursor = await self.conn.execute("SELECT id FROM security_groups WHERE name = ? COLLATE NOCASE", (name,))
+        row = await cursor.fetchone()
+        return await self.get_security_group(row["id"]) if row else None
+
+    async def create_security_group(
+        self, name: str, description: str | None = None, target_pct: float | None = None
+    ) -> int:
+        """Create a security group; returns its id."""
+        cursor = await self.conn.execute(
+            "INSERT INTO security_groups (name, description, target_pct) VALUES (?, ?, ?)",
+            (name, description, target_pct),
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def update_security_group(self, group_id: int, **fields) -> bool:
+        """Update name, description and target_pct; returns False if the group does not exist."""
+        columns = [key for key in ("name", "description", "target_pct") if key in fields]
+        if not columns:
+            return await self.get_security_group(group_id) is not None
+        cursor = await self.conn.execute(
+            f"UPDATE security_groups SET {', '.join(f'{key} = ?' for key in columns)} WHERE id = ?",
+            [*(fields[key] for key in columns), group_id],
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def delete_security_group(self, group_id: int) -> bool:
+        """Delete a group and its memberships."""
+        await self.conn.execute("DELETE FROM security_group_members WHERE group_id = ?", (group_id,))
+        cursor = await self.conn.execute("DELETE FROM security_groups WHERE id = ?", (group_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def add_security_group_members(self, group_id: int, symbols: list[str]) -> None:
+        """Put securities in a group, moving them out of any group they were in."""
+        await self.conn.executemany(
+            "INSERT OR REPLACE INTO security_group_members (symbol, group_id) VALUES (?, ?)",
+            [(symbol, group_id) for symbol in symbols],
+        )
+        await self.conn.commit()
+
+    async def remove_security_group_members(self, group_id: int, symbols: list[str]) -> int:
+        """Take securities out of a group; returns how many were members."""
+        removed = 0
+        for symbol in symbols:
+            cursor = await self.conn.execute(
+                "DELETE FROM security_group_members WHERE group_id = ? AND symbol = ?", (group_id, symbol)
+            )
+            removed += cursor.rowcount
+        await self.conn.commit()
+        return removed
+
+    # -------------------------------------------------------------------------
+    # Broker Symbols
+    # -------------------------------------------------------------------------
+
+    async def get_broker_symbols(self) -> list[dict]:
+        """Get every ISIN -> broker symbol mapping, by ISIN."""
+        cursor = await self.conn.execute("SELECT * FROM broker_symbols ORDER BY isin")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_broker_symbol(self, isin: str) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM broker_symbols WHERE isin = ?", (isin,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def set_broker_symbol(self, isin: str, broker_symbol: str, source: str = "auto") -> None:
+        """Insert or replace the broker symbol for an ISIN."""
+        await self.conn.execute(
+            """INSERT INTO broker_symbols (isin, broker_symbol, source, updated_at)
+               VALUES (?, ?, ?, ?)
+               ON CONFLICT(isin) DO UPDATE SET
+                   broker_symbol = excluded.broker_symbol,
+                   source = excluded.source,
+                   updated_at = excluded.updated_at""",
+            (isin, broker_symbol, source, int(time.time())),
+        )
+        await self.conn.commit()
+
+    async def delete_broker_symbol(self, isin: str) -> bool:
+        cursor = await self.conn.execute("DELETE FROM broker_symbols WHERE isin = ?", (isin,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # Price Quarantine
+    # -------------------------------------------------------------------------
+
+    async def add_price_quarantine(
+        self,
+        symbol: str,
+        kind: str,
+        date: str | None,
+        value: float | None,
+        reference: float | None,
+        reason: str,
+        payload: str,
+    ) -> int:
+        """Quarantine a value; returns its id, or 0 if it is already pending or was rejected."""
+        cursor = await self.conn.execute(
+            """SELECT id FROM price_quarantine
+               WHERE symbol = ? AND kind = ? AND date IS ? AND value IS ? AND status IN ('pending', 'rejected')""",
+            (symbol, kind, date, value),
+        )
+        if await cursor.fetchone():
+            return 0
+        cursor = await self.conn.execute(
+            """INSERT INTO price_quarantine (symbol, kind, date, value, reference, reason, payload, created_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+            (symbol, kind, date, value, reference, reason, payload, int(time.time())),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_price_quarantine(self, status: str | None = "pending", symbol: str | None = None, limit: int = 100):
+        """Get quarantined values, newest first."""
+        query = "SELECT * FROM price_quarantine WHERE 1=1"
+        params: list = []
+        if status:
+            query += " AND status = ?"
+            params.append(status)
+        if symbol:
+            query += " AND symbol = ?"
+            params.append(symbol)
+        query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_price_quarantine_entry(self, entry_id: int) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM price_quarantine WHERE id = ?", (entry_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def resolve_price_quarantine(self, entry_id: int, status: str) -> bool:
+        """Mark a pending entry accepted or rejected."""
+        cursor = await self.conn.execute(
+            "UPDATE price_quarantine SET status = ?, resolved_at = ? WHERE id = ? AND status = 'pending'",
+            (status, int(time.time()), entry_id),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def count_price_quarantine(self, symbol: str, since: int) -> int:
+        """Number of values quarantined for a symbol since a unix timestamp."""
+        cursor = await self.conn.execute(
+            "SELECT COUNT(*) FROM price_quarantine WHERE symbol = ? AND created_at >= ?", (symbol, since)
+        )
+        row = await cursor.fetchone()
+        return row[0]
+
+    # -------------------------------------------------------------------------
+    # Price Staging
+    # -------------------------------------------------------------------------
+
+    async def create_price_sync_report(self) -> int:
+        cursor = await self.conn.execute("INSERT INTO price_sync_reports (started_at) VALUES (?)", (int(time.time()),))
+        await self.conn.commit()
+        return cursor.lastrowid
+
+    async def stage_prices(self, sync_id: int, symbol: str, verdicts: list[tuple[dict, str | None]]) -> None:
+        """Stage candles with their verdict: None to merge, or the reason they were rejected."""
+        await self.conn.executemany(
+            """INSERT INTO price_staging (sync_id, symbol, date, open, high, low, close, volume, status, reason)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            [
+                (
+                    sync_id,
+                    symbol,
+                    row.get("date"),
+                    row.get("open"),
+                    row.get("high"),
+                    row.get("low"),
+                    row.get("close"),
+                    row.get("volume"),
+                    "rejected" if reason else "staged",
+                    reason,
+                )
+                for row, reason in verdicts
+            ],
+        )
+        await self.conn.commit()
+
+    async def clear_staged_prices(self, sync_id: int, symbol: str) -> None:
+        """Drop a symbol's merged rows; rejected rows are kept for the report."""
+        await self.conn.execute(
+            "DELETE FROM price_staging WHERE sync_id = ? AND symbol = ? AND status = 'staged'", (sync_id, symbol)
+        )
+        await self.conn.commit()
+
+    async def discard_staged_prices(self) -> int:
+        """Drop rows still staged by syncs that never merged them."""
+        cursor = await self.conn.execute("DELETE FROM price_staging WHERE status = 'staged'")
+        await self.conn.commit()
+        return cursor.rowcount
+
+    async def finish_price_sync_report(self, sync_id: int, counts: dict, gaps: dict, keep: int = 30) -> None:
+        """Store a sync's totals, then drop all but the newest `keep` reports."""
+        import json
+
+        await self.conn.execute(
+            """UPDATE price_sync_reports
+               SET finished_at = ?, symbols = ?, fetched = ?, rejected = ?, quarantined = ?, merged = ?, gaps = ?
+               WHERE id = ?""",
+            (
+                int(time.time()),
+                counts.get("symbols", 0),
+                counts.get("fetched", 0),
+                counts.get("rejected", 0),
+                counts.get("quarantined", 0),
+                counts.get("merged", 0),
+                json.dumps(gaps),
+                sync_id,
+            ),
+        )
+        stale = "SELECT id FROM price_sync_reports ORDER BY id DESC LIMIT -1 OFFSET ?"
+        await self.conn.execute(f"DELETE FROM price_staging WHERE sync_id IN ({stale})", (keep,))
+        await self.conn.execute(f"DELETE FROM price_sync_reports WHERE id IN ({stale})", (keep,))
+        await self.conn.commit()
+
+    async def get_price_sync_reports(self, limit: int = 20) -> list[dict]:
+        """Get price sync reports, newest first (without rejected rows)."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM price_sync_reports ORDER BY id DESC LIMIT ?", (limit,))
+        return [{**dict(row), "gaps": json.loads(row["gaps"])} for row in await cursor.fetchall()]
+
+    async def get_price_sync_report(self, sync_id: int) -> dict | None:
+        """Get one price sync report with its rejected rows."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM price_sync_reports WHERE id = ?", (sync_id,))
+        row = await cursor.fetchone()
+        if row is None:
+            return None
+        cursor = await self.conn.execute(
+            """SELECT symbol, date, open, high, low, close, volume, reason FROM price_staging
+               WHERE sync_id = ? AND status = 'rejected' ORDER BY symbol, date""",
+            (sync_id,),
+        )
+        rejected = [dict(r) for r in await cursor.fetchall()]
+        return {**dict(row), "gaps": json.loads(row["gaps"]), "rejected_rows": rejected}
+
+    # -------------------------------------------------------------------------
+    # Security Indicators
+    # -------------------------------------------------------------------------
+
+    async def get_latest_indicators(self, symbol: str) -> dict | None:
+        cursor = await self.conn.execute(
+            "SELECT * FROM security_indicators WHERE symbol = ? ORDER BY date DESC LIMIT 1", (symbol,)
+        )
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def get_indicators(self, symbol: str, days: int | None = None) -> list[dict]:
+        """Get indicator rows for a security, newest first."""
+        query = "SELECT * FROM security_indicators WHERE symbol = ? ORDER BY date DESC"
+        params: list[str | int] = [symbol]
+        if days:
+            query += " LIMIT ?"
+            params.append(days)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def save_indicators(self, symbol: str, rows: list[dict]) -> None:
+        """Insert or replace indicator rows (as built by sentinel.indicators)."""
+        await self.conn.executemany(
+            """INSERT OR REPLACE INTO security_indicators
+               (symbol, date, close, rsi14, ema20, sma50, ema_sma_cross, macd, macd_signal, macd_hist,
+                bollinger_pct_b, atr14, state)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            [
+                (
+                    symbol,
+                    row["date"],
+                    row["close"],
+                    row["rsi14"],
+                    row["ema20"],
+                    row["sma50"],
+                    row["ema_sma_cross"],
+                    row["macd"],
+                    row["macd_signal"],
+                    row["macd_hist"],
+                    row["bollinger_pct_b"],
+                    row["atr14"],
+                    row["state"],
+                )
+                for row in rows
+            ],
+        )
+        await self.conn.commit()
+
+    async def delete_indicators(self, symbol: str) -> None:
+        await self.conn.execute("DELETE FROM security_indicators WHERE symbol = ?", (symbol,))
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Concentration Breaches
+    # -------------------------------------------------------------------------
+
+    async def get_concentration_breaches(self, status: str = "open", limit: int = 100) -> list[dict]:
+        """Get breaches by status (open, resolved or all), newest first."""
+        query = "SELECT * FROM concentration_breaches"
+        if status == "open":
+            query += " WHERE resolved_at IS NULL"
+        elif status == "resolved":
+            query += " WHERE resolved_at IS NOT NULL"
+        query += " ORDER BY started_at DESC, id DESC LIMIT ?"
+        cursor = await self.conn.execute(query, (limit,))
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def open_concentration_breach(self, symbol: str, level: str, pct: float, now: int) -> int:
+        cursor = await self.conn.execute(
+            """INSERT INTO concentration_breaches (symbol, level, started_at, escalated_at, peak_pct, last_pct)
+               VALUES (?, ?, ?, ?, ?, ?)""",
+            (symbol, level, now, now if level == "critical" else None, pct, pct),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid
+
+    async def update_concentration_breach(
+        self, breach_id: int, level: str, pct: float, escalated_at: int | None = None
+    ) -> None:
+        """Record an open breach's latest level and weight."""
+        await self.conn.execute(
+            """UPDATE concentration_breaches
+               SET level = ?, last_pct = ?, peak_pct = MAX(peak_pct, ?), escalated_at = COALESCE(?, escalated_at)
+               WHERE id = ?""",
+            (level, pct, pct, escalated_at, breach_id),
+        )
+        await self.conn.commit()
+
+    async def resolve_concentration_breach(self, breach_id: int, pct: float, now: int) -> None:
+        await self.conn.execute(
+            "UPDATE concentration_breaches SET last_pct = ?, resolved_at = ? WHERE id = ? AND resolved_at IS NULL",
+            (pct, now, breach_id),
+        )
+        await self.conn.commit()
+
+    async def set_concentration_breach_plan(self, breach_id: int, plan: str) -> None:
+        await self.conn.execute("UPDATE concentration_breaches SET plan = ? WHERE id = ?", (plan, breach_id))
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Orders
+    # -------------------------------------------------------------------------
+
+    async def insert_order(self, **fields) -> None:
+        """Record an order (columns of the `orders` table)."""
+        now = int(time.time())
+        fields = {"created_at": now, "updated_at": now, **fields}
+        columns = list(fields)
+        await self.conn.execute(
+            f"INSERT OR IGNORE INTO orders ({', '.join(columns)}) VALUES ({', '.join('?' for _ in columns)})",
+            [fields[column] for column in columns],
+        )
+        await self.conn.commit()
+
+    async def get_order(self, order_id: str) -> dict | None:
+        cursor = await self.conn.execute("SELECT * FROM orders WHERE order_id = ?", (order_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def get_orders(
+        self,
+        statuses: list[str] | tuple[str, ...] | None = None,
+        limit: int = 100,
+        since: int | None = None,
+    ) -> list[dict]:
+        """Get orders, newest first, optionally only those in `statuses` or created at/after `since`."""
+        conditions = []
+        params: list = []
+        if statuses:
+            conditions.append(f"status IN ({', '.join('?' for _ in statuses)})")
+            params.extend(statuses)
+        if since is not None:
+            conditions.append("created_at >= ?")
+            params.append(since)
+        query = "SELECT * FROM orders"
+        if conditions:
+            query += " WHERE " + " AND ".join(conditions)
+        query += " ORDER BY created_at DESC, order_id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def update_order(self, order_id: str, **fields) -> None:
+        """Update an order's status, broker_status or replaced_by."""
+        fields["updated_at"] = int(time.time())
+        await self.conn.execute(
+            f"UPDATE orders SET {', '.join(f'{column} = ?' for column in fields)} WHERE order_id = ?",
+            [*fields.values(), order_id],
+        )
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Earnings Dates
+    # -------------------------------------------------------------------------
+
+    async def upsert_earnings_date(self, symbol: str, date: str, source: str = "manual") -> None:
+        """Record an earnings date for a security (re-recording updates the source)."""
+        await self.conn.execute(
+            """INSERT INTO earnings_dates (symbol, date, source) VALUES (?, ?, ?)
+               ON CONFLICT(symbol, date) DO UPDATE SET
+                 source = excluded.source,
+                 updated_at = strftime('%s', 'now')""",
+            (symbol, date, source),
+        )
+        await self.conn.commit()
+
+    async def delete_earnings_date(self, symbol: str, date: str) -> bool:
+        """Delete an earnings date; returns False if it did not exist."""
+        cursor = await self.conn.execute(
+            "DELETE FROM earnings_dates WHERE symbol = ? AND date = ?",
+            (symbol, date),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def get_earnings_dates(
+        self,
+        symbol: Optional[str] = None,
+        start_date: Optional[str] = None,
+        end_date: Optional[str] = None,
+    ) -> list[dict]:
+        """
+        Get earnings dates with optional filters, ordered by date then symbol.
+
+        Args:
+            symbol: Filter by ticker symbol
+            start_date: Filter dates on or after (YYYY-MM-DD)
+            end_date: Filter dates on or before (YYYY-MM-DD)
+        """
+        query = "SELECT * FROM earnings_dates WHERE 1=1"
+        params: list[str] = []
+
+        if symbol:
+            query += " AND symbol = ?"
+            params.append(symbol)
+
+        if start_date:
+            query += " AND date >= ?"
+            params.append(start_date)
+
+        if end_date:
+            query += " AND date <= ?"
+            params.append(end_date)
+
+        query += " ORDER BY date, symbol"
+
+        cursor = await self.conn.execute(query, params)
+        rows = await cursor.fetchall()
+        return [dict(row) for row in rows]
+
     # -------------------------------------------------------------------------
     # Prices (base implementation, can be overridden)
     # -------------------------------------------------------------------------
@@ -567,6 +1431,13 @@ class BaseDatabase:
         rows = await cursor.fetchall()
         return [dict(row) for row in rows]
 
+    async def get_prices_after(self, symbol: str, after_date: str) -> list[dict]:
+        """Get prices dated after `after_date`, oldest first."""
+        cursor = await self.conn.execute(
+            "SELECT * FROM prices WHERE symbol = ? AND date > ? ORDER BY date", (symbol, after_date)
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
     async def get_prices_for_symbols(
         self,
         symbols: list[str],
@@ -793,3 +1664,410 @@ class BaseDatabase:
                 tuple(data.values()),
             )
         await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Planner Snapshots
+    # -------------------------------------------------------------------------
+
+    async def insert_planner_snapshot(self, source: str, context: dict, recommendations: list[dict]) -> int:
+        """Persist one planning cycle's inputs and recommendations; returns the snapshot id."""
+        import json
+
+        cursor = await self.conn.execute(
+            "INSERT INTO planner_snapshots (source, context, recommendations) VALUES (?, ?, ?)",
+            (source, json.dumps(context), json.dumps(recommendations)),
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_planner_snapshot(self, snapshot_id: int) -> dict | None:
+        """Get a planner snapshot with decoded context and recommendations."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM planner_snapshots WHERE id = ?", (snapshot_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        return {
+            "id": row["id"],
+            "created_at": row["created_at"],
+            "source": row["source"],
+            "context": json.loads(row["context"]),
+            "recommendations": json.loads(row["recommendations"]),
+        }
+
+    async def get_planner_snapshots(self, limit: int = 50, before_id: int | None = None) -> list[dict]:
+        """List recent planner snapshots (newest first) without their payloads."""
+        cursor = await self.conn.execute(
+            """SELECT id, created_at, source, json_array_length(recommendations) AS recommendation_count
+               FROM planner_snapshots
+               WHERE ? IS NULL OR id < ?
+               ORDER BY id DESC LIMIT ?""",
+            (before_id, before_id, limit),
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def prune_planner_snapshots(self, keep: int) -> int:
+        """Delete all but the newest `keep` planner snapshots; returns rows deleted."""
+        cursor = await self.conn.execute(
+            "DELETE FROM planner_snapshots WHERE id NOT IN (SELECT id FROM planner_snapshots ORDER BY id DESC LIMIT ?)",
+            (keep,),
+        )
+        await self.conn.commit()
+        return cursor.rowcount
+
+    # -------------------------------------------------------------------------
+    # Allocation Runs
+    # -------------------------------------------------------------------------
+
+    @staticmethod
+    def _decode_allocation_run(row) -> dict:
+        import json
+
+        run = dict(row)
+        run["weights"] = json.loads(run["weights"])
+        run["constraints"] = json.loads(run["constraints"])
+        return run
+
+    async def insert_allocation_run(
+        self,
+        model: str,
+        weights: dict,
+        constraints: dict,
+        effective_holdings: float | None,
+        turnover: float | None,
+        created_at: int,
+    ) -> int:
+        """Persist one ideal-portfolio result; returns the run id."""
+        import json
+
+        cursor = await self.conn.execute(
+            """INSERT INTO allocation_runs
+               (created_at, last_seen_at, model, weights, constraints, effective_holdings, turnover)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            (
+                created_at,
+                created_at,
+                model,
+                json.dumps(weights),
+                json.dumps(constraints, default=str),
+                effective_holdings,
+                turnover,
+            ),
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def touch_allocation_run(self, run_id: int, seen_at: int) -> None:
+        """Mark an allocation run as produced again at `seen_at`."""
+        await self.conn.execute("UPDATE allocation_runs SET last_seen_at = ? WHERE id = ?", (seen_at, run_id))
+        await self.conn.commit()
+
+    async def get_latest_allocation_run(self) -> dict | None:
+        """Get the newest allocation run with decoded weights and constraints."""
+        cursor = await self.conn.execute("SELECT * FROM allocation_runs ORDER BY id DESC LIMIT 1")
+        row = await cursor.fetchone()
+        return self._decode_allocation_run(row) if row else None
+
+    async def get_allocation_run(self, run_id: int) -> dict | None:
+        """Get an allocation run with decoded weights and constraints."""
+        cursor = await self.conn.execute("SELECT * FROM allocation_runs WHERE id = ?", (run_id,))
+        row = await cursor.fetchone()
+        return self._decode_allocation_run(row) if row else None
+
+    async def get_allocation_runs(self, limit: int = 50, before_id: int | None = None) -> list[dict]:
+        """List allocation runs (newest first) with decoded weights and constraints."""
+        cursor = await self.conn.execute(
+            """SELECT * FROM allocation_runs
+               WHERE ? IS NULL OR id < ?
+               ORDER BY id DESC LIMIT ?""",
+            (before_id, before_id, limit),
+        )
+        return [self._decode_allocation_run(row) for row in await cursor.fetchall()]
+
+    async def prune_allocation_runs(self, keep: int) -> int:
+        """Delete all but the newest `keep` allocation runs; returns rows deleted."""
+        cursor = await self.conn.execute(
+            "DELETE FROM allocation_runs WHERE id NOT IN (SELECT id FROM allocation_runs ORDER BY id DESC LIMIT ?)",
+            (keep,),
+        )
+        await self.conn.commit()
+        return cursor.rowcount
+
+    # -------------------------------------------------------------------------
+    # Factor Exposures
+    # -------------------------------------------------------------------------
+
+    async def upsert_factor_exposure(
+        self,
+        symbol: str,
+        region: str,
+        betas: dict,
+        alpha: float,
+        residual_vol: float,
+        r_squared: float,
+        samples: int,
+        updated_at: int,
+    ) -> None:
+        """Store a security's latest factor regression, replacing the previous one."""
+        import json
+
+        await self.conn.execute(
+            """INSERT OR REPLACE INTO factor_exposures
+               (symbol, region, betas, alpha, residual_vol, r_squared, samples, updated_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+            (symbol, region, json.dumps(betas), alpha, residual_vol, r_squared, samples, updated_at),
+        )
+        await self.conn.commit()
+
+    async def get_factor_exposures(self, symbols: list[str] | None = None) -> dict[str, dict]:
+        """Get stored factor exposures with decoded betas, keyed by symbol."""
+        import json
+
+        if symbols is None:
+            cursor = await self.conn.execute("SELECT * FROM factor_exposures ORDER BY symbol")
+        elif not symbols:
+            return {}
+        else:
+            placeholders = ",".join("?" * len(symbols))
+            cursor = await self.conn.execute(
+                f"SELECT * FROM factor_exposures WHERE symbol IN ({placeholders}) ORDER BY symbol",  # noqa: S608
+                tuple(symbols),
+            )
+        return {row["symbol"]: {**dict(row), "betas": json.loads(row["betas"])} for row in await cursor.fetchall()}
+
+    # -------------------------------------------------------------------------
+    # Security Notes
+    # -------------------------------------------------------------------------
+
+    async def add_security_note(self, symbol: str, body: str, created_at: int) -> int:
+        """Store a new revision of a security's note; returns its id."""
+        cursor = await self.conn.execute(
+            "INSERT INTO security_notes (symbol, body, created_at) VALUES (?, ?, ?)", (symbol, body, created_at)
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_security_note_history(self, symbol: str) -> list[dict]:
+        """Get every revision of a security's note, newest first."""
+        cursor = await self.conn.execute("SELECT * FROM security_notes WHERE symbol = ? ORDER BY id DESC", (symbol,))
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_security_notes(self) -> dict[str, dict]:
+        """Get the latest note revision of every security with a note, keyed by symbol."""
+        cursor = await self.conn.execute(
+            """SELECT n.* FROM security_notes n
+               JOIN (SELECT MAX(id) AS id FROM security_notes GROUP BY symbol) latest ON latest.id = n.id
+               ORDER BY n.symbol"""
+        )
+        return {row["symbol"]: dict(row) for row in await cursor.fetchall()}
+
+    async def add_security_attachment(
+        self, symbol: str, filename: str, content_type: str, content: bytes, created_at: int
+    ) -> int:
+        """Store an attachment; returns its id."""
+        cursor = await self.conn.execute(
+            """INSERT INTO security_attachments (symbol, filename, content_type, size, content, created_at)
+               VALUES (?, ?, ?, ?, ?, ?)""",
+            (symbol, filename, content_type, len(content), content, created_at),
+        )
+        await self.conn.commit()
+        return int(cursor.lastrowid or 0)
+
+    async def get_security_attachments(self, symbol: str | None = None, include_deleted: bool = False) -> list[dict]:
+        """Get attachment metadata (without content), oldest first."""
+        query = "SELECT id, symbol, filename, content_type, size, created_at, deleted_at FROM security_attachments"
+        conditions, params = [], []
+        if symbol is not None:
+            conditions.append("symbol = ?")
+            params.append(symbol)
+        if not include_deleted:
+            conditions.append("deleted_at IS NULL")
+        if conditions:
+            query += " WHERE " + " AND ".join(conditions)
+        cursor = await self.conn.execute(query + " ORDER BY id", tuple(params))
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_security_attachment(self, attachment_id: int) -> dict | None:
+        """Get one attachment with its content."""
+        cursor = await self.conn.execute("SELECT * FROM security_attachments WHERE id = ?", (attachment_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def delete_security_attachment(self, attachment_id: int, deleted_at: int) -> bool:
+        """Mark an attachment deleted; it stays in the history. Returns False if missing or already deleted."""
+        cursor = await self.conn.execute(
+            "UPDATE security_attachments SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+            (deleted_at, attachment_id),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # Security Merges
+    # -------------------------------------------------------------------------
+
+    async def count_security_references(self, symbol: str) -> dict[str, int]:
+        """Rows per table that merge_security would move for `symbol`."""
+        counts = {}
+        for table in (*SECURITY_HISTORY_TABLES, "positions"):
+            cursor = await self.conn.execute(f"SELECT COUNT(*) FROM {table} WHERE symbol = ?", (symbol,))  # noqa: S608
+            counts[table] = (await cursor.fetchone())[0]
+        counts["exclusion_lists"] = sum(symbol in e["symbols"] for e in await self.get_exclusion_lists())
+        cursor = await self.conn.execute("SELECT COUNT(*) FROM broker_symbols WHERE broker_symbol = ?", (symbol,))
+        counts["broker_symbols"] = (await cursor.fetchone())[0]
+        return {table: count for table, count in counts.items() if count}
+
+    async def merge_security(self, duplicate: str, canonical: str, overrides: dict, merged_at: int) -> dict[str, int]:
+        """Fold `duplicate` into `canonical` in one transaction and delete it.
+
+        History rows are re-keyed, keeping the canonical row where both have
+        one; positions are added up (cost-weighted average cost); exclusion
+        lists and broker symbol mappings follow. `overrides` are written to
+        the canonical security. Returns the rows moved per table.
+        """
+        import json
+
+        moved: dict[str, int] = {}
+        try:
+            for table in SECURITY_HISTORY_TABLES:
+                cursor = await self.conn.execute(
+                    f"UPDATE OR IGNORE {table} SET symbol = ? WHERE symbol = ?",  # noqa: S608
+                    (canonical, duplicate),
+                )
+                moved[table] = cursor.rowcount
+                await self.conn.execute(f"DELETE FROM {table} WHERE symbol = ?", (duplicate,))  # noqa: S608
+
+            old = await self.get_position(duplicate)
+            if old:
+                kept = await self.get_position(canonical)
+                if kept is None:
+                    await self.conn.execute("UPDATE positions SET symbol = ? WHERE symbol = ?", (canonical, duplicate))
+                else:
+                    quantity = (kept["quantity"] or 0) + (old["quantity"] or 0)
+                    cost = (kept["quantity"] or 0) * (kept["avg_cost"] or 0) + (old["quantity"] or 0) * (
+                        old["avg_cost"] or 0
+                    )
+                    await self.conn.execute(
+                        "UPDATE positions SET quantity = ?, avg_cost = ? WHERE symbol = ?",
+                        (quantity, cost / quantity if quantity else kept["avg_cost"], canonical),
+                    )
+                    await self.conn.execute("DELETE FROM positions WHERE symbol = ?", (duplicate,))
+                moved["positions"] = 1
+
+            for exclusion in await self.get_exclusion_lists():
+                if duplicate in exclusion["symbols"]:
+                    symbols = list(dict.fromkeys(canonical if s == duplicate else s for s in exclusion["symbols"]))
+                    await self.conn.execute(
+                        "UPDATE exclusion_lists SET symbols = ? WHERE id = ?", (json.dumps(symbols), exclusion["id"])
+                    )
+                    moved["exclusion_lists"] = moved.get("exclusion_lists", 0) + 1
+
+            cursor = await self.conn.execute(
+                "UPDATE broker_symbols SET broker_symbol = ?, updated_at = ? WHERE broker_symbol = ?",
+                (canonical, merged_at, duplicate),
+            )
+            moved["broker_symbols"] = cursor.rowcount
+            moved = {table: count for table, count in moved.items() if count}
+
+            if overrides:
+                await self.conn.execute(
+                    f"UPDATE securities SET {', '.join(f'{k} = ?' for k in overrides)} WHERE symbol = ?",  # noqa: S608
+                    (*overrides.values(), canonical),
+                )
+            await self.conn.execute("DELETE FROM securities WHERE symbol = ?", (duplicate,))
+            # Symbols merged into the duplicate earlier now point at the canonical record.
+            await self.conn.execute(
+                "UPDATE security_merges SET canonical = ? WHERE canonical = ?", (canonical, duplicate)
+            )
+            await self.conn.execute(
+                "INSERT OR REPLACE INTO security_merges (symbol, canonical, rows, merged_at) VALUES (?, ?, ?, ?)",
+                (duplicate, canonical, json.dumps(moved), merged_at),
+            )
+        except Exception:
+            await self.conn.rollback()
+            raise
+        await self.conn.commit()
+        return moved
+
+    async def get_security_merges(self) -> list[dict]:
+        """Get merged-away symbols with their canonical symbol, newest first."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM security_merges ORDER BY merged_at DESC, symbol")
+        return [{**dict(row), "rows": json.loads(row["rows"])} for row in await cursor.fetchall()]
+
+    # -------------------------------------------------------------------------
+    # Idempotency Keys
+    # -------------------------------------------------------------------------
+
+    async def get_idempotency_record(self, scope: str, key: str) -> dict | None:
+        """Get a stored idempotent result with its decoded response."""
+        import json
+
+        cursor = await self.conn.execute(
+            "SELECT * FROM idempotency_keys WHERE scope = ? AND key = ?",
+            (scope, key),
+        )
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        return {**dict(row), "response": json.loads(row["response"])}
+
+    async def save_idempotency_record(self, scope: str, key: str, request_hash: str, response) -> None:
+        """Store the result of the first request made with an idempotency key."""
+        import json
+
+        await self.conn.execute(
+            """INSERT OR REPLACE INTO idempotency_keys (scope, key, request_hash, response, created_at)
+               VALUES (?, ?, ?, ?, strftime('%s', 'now'))""",
+            (scope, key, request_hash, json.dumps(response, default=str)),
+        )
+        await self.conn.commit()
+
+    async def prune_idempotency_keys(self, older_than_ts: int) -> int:
+        """Delete idempotency keys created before a timestamp; returns rows deleted."""
+        cursor = await self.conn.execute("DELETE FROM idempotency_keys WHERE created_at < ?", (older_than_ts,))
+        await self.conn.commit()
+        return cursor.rowcount
+
+    # -------------------------------------------------------------------------
+    # Retention
+    # -------------------------------------------------------------------------
+
+    async def get_retention_rows(self, table: str, where: str, params: list) -> tuple[list[str], list[tuple]]:
+        """Rows of `table` matching a retention condition, as (columns, rows)."""
+        cursor = await self.conn.execute(f"SELECT * FROM {table} WHERE {where}", params)  # noqa: S608
+        rows = await cursor.fetchall()
+        return [col[0] for col in cursor.description], [tuple(row) for row in rows]
+
+    async def delete_retention_rows(self, table: str, where: str, params: list) -> dict:
+        """Delete rows of `table` matching a retention condition.
+
+        Returns:
+            {"rows", "bytes_freed"}; bytes_freed counts pages SQLite moved to
+            its freelist
+        """
+        cursor = await self.conn.execute("PRAGMA page_size")
+        page_size = (await cursor.fetchone())[0]
+        cursor = await self.conn.execute("PRAGMA freelist_count")
+        free_before = (await cursor.fetchone())[0]
+        cursor = await self.conn.execute(f"DELETE FROM {table} WHERE {where}", params)  # noqa: S608
+        rows = cursor.rowcount
+        await self.conn.commit()
+        cursor = await self.conn.execute("PRAGMA freelist_count")
+        free_after = (await cursor.fetchone())[0]
+        return {"rows": rows, "bytes_freed": max(0, free_after - free_before) * page_size}
+
+    async def restore_retention_rows(self, table: str, columns: list[str], rows: list[tuple]) -> int:
+        """Insert archived rows back, skipping any that are still present; returns rows inserted."""
+        placeholders = ", ".join("?" * len(columns))
+        restored = 0
+        for row in rows:
+            cursor = await self.conn.execute(
+                f"INSERT OR IGNORE INTO {table} ({', '.join(columns)}) VALUES ({placeholders})",  # noqa: S608
+                row,
+            )
+            restored += cursor.rowcount
+        await self.conn.commit()
+        return restored
@@ -0,0 +1,211 @@
+"""
+Database connection tuning profiles and lock contention metrics.
+
+Each Database holds one serialized aiosqlite connection per file, so there
+is no pool to size; what can be tuned is how that connection waits for
+locks held by other processes (backups, scripts, migrations) and how hard
+it syncs to disk. A Config bundles those PRAGMAs; PROFILES names the
+supported combinations. The profile comes from SENTINEL_DB_PROFILE, and
+SENTINEL_DB_BUSY_TIMEOUT_MS / SENTINEL_DB_SYNCHRONOUS override single
+values. Both are read at connect time because the settings live in the
+database itself.
+
+ContentionStats counts "database is locked" errors that survived the busy
+timeout, and times write statements and commits. With a single connection
+per process, a write that takes longer than `lock_wait_threshold_ms` is
+almost always waiting for another process's lock, so that time is reported
+as lock wait.
+
+Usage:
+    config = config_from_env()          # or PROFILES["sync_heavy"]
+    await Database().connect(config)
+    stats = Database().contention_stats()
+"""
+
+from __future__ import annotations
+
+import dataclasses
+import logging
+import os
+import time
+from dataclasses import dataclass
+from typing import Any, Callable
+
+from sentinel.database.encryption import OPERATIONAL_ERRORS
+from sentinel.faults import injector
+
+logger = logging.getLogger(__name__)
+
+PROFILE_ENV = "SENTINEL_DB_PROFILE"
+BUSY_TIMEOUT_ENV = "SENTINEL_DB_BUSY_TIMEOUT_MS"
+SYNCHRONOUS_ENV = "SENTINEL_DB_SYNCHRONOUS"
+
+SYNCHRONOUS_LEVELS = ("OFF", "NORMAL", "FULL", "EXTRA")
+_WRITE_PREFIXES = ("INSERT", "UPDATE", "DELETE", "REPLACE", "BEGIN", "COMMIT", "CREATE", "ALTER", "DROP")
+
+
+@dataclass(frozen=True)
+class Config:
+    """SQLite connection settings applied on connect."""
+
+    name: str = "default"
+    busy_timeout_ms: int = 30000
+    synchronous: str = "FULL"
+    # Page cache per connection in KiB (SQLite's own default is 2000).
+    cache_size_kib: int = 2000
+    # WAL pages before an automatic checkpoint (SQLite default 1000).
+    wal_autocheckpoint: int = 1000
+    lock_wait_threshold_ms: int = 20
+
+    def __post_init__(self):
+        if self.busy_timeout_ms < 0:
+            raise ValueError("busy_timeout_ms must not be negative")
+        if self.synchronous not in SYNCHRONOUS_LEVELS:
+            raise ValueError(f"synchronous must be one of {', '.join(SYNCHRONOUS_LEVELS)}")
+        if self.cache_size_kib < 1 or self.wal_autocheckpoint < 1:
+            raise ValueError("cache_size_kib and wal_autocheckpoint must be positive")
+
+    def pragmas(self) -> list[str]:
+        return [
+            "PRAGMA journal_mode=WAL",
+            f"PRAGMA busy_timeout={self.busy_timeout_ms}",
+            f"PRAGMA synchronous={self.synchronous}",
+            f"PRAGMA cache_size=-{self.cache_size_kib}",
+            f"PRAGMA wal_autocheckpoint={self.wal_autocheckpoint}",
+        ]
+
+
+PROFILES: dict[str, Config] = {
+    # What the app has always used.
+    "default": Config(),
+    # 1-2 GB boards: smaller page cache; NORMAL sync is safe with WAL and
+    # spares the SD card.
+    "low_memory": Config(name="low_memory", synchronous="NORMAL", cache_size_kib=1024),
+    # Long price syncs next to backups or scripts: wait longer for locks and
+    # checkpoint less often.
+    "sync_heavy": Config(
+        name="sync_heavy",
+        busy_timeout_ms=60000,
+        synchronous="NORMAL",
+        cache_size_kib=8192,
+        wal_autocheckpoint=4000,
+    ),
+    # SQLCipher (SENTINEL_DB_PASSPHRASE): pages are decrypted on their way
+    # into the cache, so a bigger cache saves most of the cipher cost, and
+    # NORMAL sync saves an encrypted page write per commit.
+    "encrypted": Config(name="encrypted", synchronous="NORMAL", cache_size_kib=8192),
+}
+
+
+def config_from_env() -> Config:
+    """The profile named by SENTINEL_DB_PROFILE with env overrides applied.
+
+    Raises:
+        ValueError: For an unknown profile or an invalid override.
+    """
+    name = os.environ.get(PROFILE_ENV, "default").strip() or "default"
+    if name not in PROFILES:
+        raise ValueError(f"Unknown {PROFILE_ENV} {name!r}; expected one of {', '.join(PROFILES)}")
+    config = PROFILES[name]
+
+    overrides: dict[str, Any] = {}
+    if os.environ.get(BUSY_TIMEOUT_ENV):
+        try:
+            overrides["busy_timeout_ms"] = int(os.environ[BUSY_TIMEOUT_ENV])
+        except ValueError as e:
+            raise ValueError(f"{BUSY_TIMEOUT_ENV} must be a whole number of milliseconds") from e
+    if os.environ.get(SYNCHRONOUS_ENV):
+        overrides["synchronous"] = os.environ[SYNCHRONOUS_ENV].strip().upper()
+    return dataclasses.replace(config, **overrides) if overrides else config
+
+
+def _is_busy(error: Exception) -> bool:
+    message = str(error).lower()
+    return "locked" in message or "busy" in message
+
+
+class ContentionStats:
+    """Busy errors and lock wait time seen by one connection."""
+
+    def __init__(self, lock_wait_threshold_ms: int):
+        self.lock_wait_threshold_ms = lock_wait_threshold_ms
+        self.reset()
+
+    def reset(self) -> None:
+        self.statements = 0
+        self.writes = 0
+        self.busy_errors = 0
+        self.last_busy_error: dict[str, Any] | None = None
+        self.lock_waits = 0
+        self.lock_wait_ms_total = 0.0
+        self.lock_wait_ms_max = 0.0
+
+    def record(self, write: bool, elapsed_ms: float, error: Exception | None = None, sql: str = "") -> None:
+        self.statements += 1
+        if write:
+            self.writes += 1
+            if elapsed_ms >= self.lock_wait_threshold_ms:
+                self.lock_waits += 1
+                self.lock_wait_ms_total += elapsed_ms
+                self.lock_wait_ms_max = max(self.lock_wait_ms_max, elapsed_ms)
+        if error is not None and _is_busy(error):
+            self.busy_errors += 1
+            self.last_busy_error = {"at": int(time.time()), "error": str(error), "sql": sql[:200]}
+            logger.warning(f"SQLite busy after {elapsed_ms:.0f}ms: {error} ({sql[:80]})")
+
+    def snapshot(self) -> dict[str, Any]:
+        return {
+            "statements": self.statements,
+            "writes": self.writes,
+            "busy_errors": self.busy_errors,
+            "last_busy_error": self.last_busy_error,
+            "lock_waits": self.lock_waits,
+            "lock_wait_ms_total": round(self.lock_wait_ms_total, 1),
+            "lock_wait_ms_max": round(self.lock_wait_ms_max, 1),
+            "lock_wait_threshold_ms": self.lock_wait_threshold_ms,
+        }
+
+
+class InstrumentedConnection:
+    """Wraps an aiosqlite connection and feeds ContentionStats.
+
+    Only execute, executemany, executescript and commit are measured (and
+    may get an injected "database is locked", see sentinel.faults); every
+    other attribute is passed through.
+    """
+
+    def __init__(self, connection, stats: ContentionStats):
+        self._connection = connection
+        self.stats = stats
+
+    def __getattr__(self, name: str) -> Any:
+        return getattr(self._connection, name)
+
+    async def _measure(self, write: bool, sql: str, call: Callable[[], Any]):
+        start = time.perf_counter()
+        try:
+            injector.maybe_fail("sqlite_busy", sql.split(None, 1)[0] if sql.strip() else "query")
+            result = await call()
+        except OPERATIONAL_ERRORS as e:
+            self.stats.record(write, (time.perf_counter() - start) * 1000, e, sql)
+            raise
+        self.stats.record(write, (time.perf_counter() - start) * 1000)
+        return result
+
+    async def execute(self, sql: str, parameters=None):
+        if parameters is None:
+            return await self._measure(_is_write(sql), sql, lambda: self._connection.execute(sql))
+        return await self._measure(_is_write(sql), sql, lambda: self._connection.execute(sql, parameters))
+
+    async def executemany(self, sql: str, parameters):
+        return await self._measure(True, sql, lambda: self._connection.executemany(sql, parameters))
+
+    async def executescript(self, sql: str):
+        return await self._measure(True, sql, lambda: self._connection.executescript(sql))
+
+    async def commit(self) -> None:
+        await self._measure(True, "COMMIT", lambda: self._connection.commit())
+
+
+def _is_write(sql: str) -> bool:
+    return sql.lstrip().upper().startswith(_WRITE_PREFIXES)
@@ -0,0 +1,187 @@
+"""
+Optional encryption at rest with SQLCipher.
+
+Setting SENTINEL_DB_PASSPHRASE turns it on. Each database file gets its own
+256-bit key, derived from the passphrase and the file's name with
+PBKDF2-HMAC-SHA256, and passed to SQLCipher as a raw key so opening a
+connection does not run SQLCipher's own key derivation again. The
+passphrase is never stored; lose it and the data is gone (R2 backups of an
+encrypted database are encrypted too). Encryption needs the `sqlcipher3`
+driver (`pip install sqlcipher3-binary`); without the variable the standard
+sqlite3 driver is used and nothing changes.
+
+An existing database is converted with the service stopped:
+
+    SENTINEL_DB_PASSPHRASE=... python main.py --encrypt-db
+    SENTINEL_DB_PASSPHRASE=... python main.py --decrypt-db
+
+Both keep the original next to it (`sentinel.db.unencrypted` /
+`sentinel.db.encrypted`) for the operator to delete once the service runs.
+
+Cost: every page is encrypted on write and decrypted when it is read into
+the page cache, which on a Raspberry Pi class board adds roughly 5-15% to
+query time. Decrypted pages stay cached, so the `encrypted` connection
+profile (SENTINEL_DB_PROFILE, see sentinel.database.config) pairs it with a
+larger cache and NORMAL sync.
+"""
+
+from __future__ import annotations
+
+import functools
+import hashlib
+import os
+import sqlite3
+from pathlib import Path
+from typing import Any
+
+import aiosqlite
+
+try:
+    from sqlcipher3 import dbapi2 as sqlcipher
+except ImportError:
+    sqlcipher = None
+
+PASSPHRASE_ENV = "SENTINEL_DB_PASSPHRASE"
+KDF_ITERATIONS = 200_000
+UNENCRYPTED_SUFFIX = ".unencrypted"
+ENCRYPTED_SUFFIX = ".encrypted"
+
+# What a busy or locked database raises, whichever driver opened it.
+OPERATIONAL_ERRORS: tuple[type[Exception], ...] = (sqlite3.OperationalError,) + (
+    (sqlcipher.OperationalError,) if sqlcipher else ()
+)
+
+
+def passphrase() -> str:
+    return os.environ.get(PASSPHRASE_ENV, "")
+
+
+@functools.lru_cache(maxsize=8)
+def _derive(secret: str, name: str) -> str:
+    salt = f"sentinel:{name}".encode()
+    return hashlib.pbkdf2_hmac("sha256", secret.encode(), salt, KDF_ITERATIONS).hex()
+
+
+def database_key(path: Path | str, secret: str | None = None) -> str | None:
+    """Hex key for the database file at `path`; None when encryption is off."""
+    secret = passphrase() if secret is None else secret
+    if not secret:
+        return None
+    return _derive(secret, Path(path).name)
+
+
+def _driver():
+    if sqlcipher is None:
+        raise RuntimeError(f"{PASSPHRASE_ENV} is set but sqlcipher3 is not installed (pip install sqlcipher3-binary)")
+    return sqlcipher
+
+
+def _key_literal(key: str) -> str:
+    return f"x'{key}'"
+
+
+def _check(connection: Any, path: Path | str) -> None:
+    """Fail clearly when the key does not open the file."""
+    try:
+        connection.execute("SELECT count(*) FROM sqlite_master").fetchall()
+    except _driver().DatabaseError as e:
+        raise ValueError(
+            f"Cannot open {path} with {PASSPHRASE_ENV}: wrong passphrase, "
+            "or the database is not encrypted yet (python main.py --encrypt-db)"
+        ) from e
+
+
+def connect(path: Path | str, key: str | None, **kwargs: Any) -> Any:
+    """A blocking connection to `path`, keyed when `key` is set."""
+    if key is None:
+        return sqlite3.connect(path, **kwargs)
+    connection = _driver().connect(str(path), **kwargs)
+    connection.execute(f'PRAGMA key = "{_key_literal(key)}"')
+    try:
+        _check(connection, path)
+    except ValueError:
+        connection.close()
+        raise
+    return connection
+
+
+async def open_connection(path: Path, key: str | None) -> aiosqlite.Connection:
+    """An aiosqlite connection to `path` with row access by column name, keyed when `key` is set."""
+    if key is None:
+        connection = await aiosqlite.connect(path)
+        connection.row_factory = aiosqlite.Row
+        return connection
+    driver = _driver()
+    connection = await aiosqlite.Connection(functools.partial(connect, path, key), iter_chunk_size=64)
+    connection.row_factory = driver.Row
+    return connection
+
+
+def _in_use(path: Path) -> bool:
+    """Whether another connection holds the database (its WAL outlives ours)."""
+    return Path(f"{path}-wal").exists()
+
+
+def _is_plaintext(path: Path) -> bool:
+    with open(path, "rb") as f:
+        return f.read(16) == b"SQLite format 3\x00"
+
+
+def _convert(path: Path, key: str | None, target_key: str | None, keep_suffix: str) -> dict[str, str]:
+    """Copy the database at `path` into a file keyed with `target_key` and swap it in."""
+    driver = _driver()
+    if _in_use(path):
+        raise RuntimeError(f"{path} is in use; stop the service first")
+    target = path.with_name(path.name + ".converting")
+    target.unlink(missing_ok=True)
+    try:
+        source = connect(path, key) if key else driver.connect(str(path))
+        try:
+            source.execute("PRAGMA wal_checkpoint(TRUNCATE)")
+            target_literal = _key_literal(target_key) if target_key else ""
+            source.execute("ATTACH DATABASE ? AS target KEY ?", (str(target), target_literal))
+            source.execute("SELECT sqlcipher_export('target')")
+            source.execute("DETACH DATABASE target")
+        finally:
+            source.close()
+        check = connect(target, target_key)
+        try:
+            check.execute("SELECT count(*) FROM sqlite_master").fetchall()
+        finally:
+            check.close()
+    except Exception:
+        target.unlink(missing_ok=True)
+        raise
+    kept = path.with_name(path.name + keep_suffix)
+    path.rename(kept)
+    target.rename(path)
+    return {"database": str(path), "original": str(kept)}
+
+
+def encrypt_database(path: Path) -> dict[str, str]:
+    """Encrypt a plaintext database in place with its derived key.
+
+    Returns:
+        {"database", "original"}: where the database is, and where the
+        unencrypted original was kept.
+
+    Raises:
+        RuntimeError: Without a passphrase or the driver, or while the database is in use.
+        ValueError: If there is no plaintext database at `path`.
+    """
+    key = database_key(path)
+    if key is None:
+        raise RuntimeError(f"Set {PASSPHRASE_ENV} to encrypt the database")
+    if not path.exists() or not _is_plaintext(path):
+        raise ValueError(f"{path} is missing or already encrypted")
+    return _convert(path, None, key, UNENCRYPTED_SUFFIX)
+
+
+def decrypt_database(path: Path) -> dict[str, str]:
+    """Decrypt a database back to plaintext in place; the mirror of encrypt_database."""
+    key = database_key(path)
+    if key is None:
+        raise RuntimeError(f"Set {PASSPHRASE_ENV} to decrypt the database")
+    if not path.exists() or _is_plaintext(path):
+        raise ValueError(f"{path} is missing or not encrypted")
+    return _convert(path, key, None, ENCRYPTED_SUFFIX)
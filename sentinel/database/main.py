@@ -14,12 +14,55 @@ from datetime import datetime, timezone
 from pathlib import Path
 from typing import Any, Optional
 
-import aiosqlite
-
 from sentinel.database.base import BaseDatabase
+from sentinel.database.config import Config, ContentionStats, InstrumentedConnection, config_from_env
+from sentinel.database.encryption import database_key, open_connection
+from sentinel.database.versions import pending, up_script
 
 logger = logging.getLogger(__name__)
 
+# Columns added to `securities` after the first release: column -> statement
+# that adds it to an older database.
+SECURITY_COLUMN_MIGRATIONS = {
+    "user_multiplier_updated_at": "ALTER TABLE securities ADD COLUMN user_multiplier_updated_at TEXT",
+    "user_multiplier_source": (
+        "ALTER TABLE securities ADD COLUMN user_multiplier_source TEXT NOT NULL DEFAULT 'migration'"
+    ),
+    "user_multiplier_analysis": "ALTER TABLE securities ADD COLUMN user_multiplier_analysis TEXT",
+    "universe_source": "ALTER TABLE securities ADD COLUMN universe_source TEXT NOT NULL DEFAULT 'migration'",
+    "universe_last_seen_at": "ALTER TABLE securities ADD COLUMN universe_last_seen_at TEXT",
+    # Tradernet instrument-kind code (1 = stock, 7 = ETF, 10 = depositary
+    # receipt, …). Persisted as a first-class column so any future query
+    # that groups or filters by asset class can do so in SQL without
+    # parsing JSON. Populated by `sync_metadata`.
+    "instr_kind_c": "ALTER TABLE securities ADD COLUMN instr_kind_c INTEGER",
+    "target_weight_pct": "ALTER TABLE securities ADD COLUMN target_weight_pct REAL",
+    "target_weight_mode": "ALTER TABLE securities ADD COLUMN target_weight_mode TEXT",
+    "target_weight_source": "ALTER TABLE securities ADD COLUMN target_weight_source TEXT",
+    "target_weight_updated_at": "ALTER TABLE securities ADD COLUMN target_weight_updated_at TEXT",
+    "adv_volume": "ALTER TABLE securities ADD COLUMN adv_volume REAL",
+    "adv_turnover_eur": "ALTER TABLE securities ADD COLUMN adv_turnover_eur REAL",
+    "illiquid": "ALTER TABLE securities ADD COLUMN illiquid INTEGER NOT NULL DEFAULT 0",
+    "adv_updated_at": "ALTER TABLE securities ADD COLUMN adv_updated_at TEXT",
+    "archived_at": "ALTER TABLE securities ADD COLUMN archived_at TEXT",
+    "archive_reason": "ALTER TABLE securities ADD COLUMN archive_reason TEXT",
+}
+
+# Columns added to `cash_flows` after the first release, as above. The
+# classification columns are written by sentinel.cash_flow_rules.
+CASH_FLOW_COLUMN_MIGRATIONS = {
+    "broker_type_id": "ALTER TABLE cash_flows ADD COLUMN broker_type_id TEXT",
+    "classified_by": "ALTER TABLE cash_flows ADD COLUMN classified_by TEXT",
+    "isin": "ALTER TABLE cash_flows ADD COLUMN isin TEXT",
+    "symbol": "ALTER TABLE cash_flows ADD COLUMN symbol TEXT",
+}
+
+# Additive column migrations by table, applied by Database._migrate_schema.
+COLUMN_MIGRATIONS = {
+    "securities": SECURITY_COLUMN_MIGRATIONS,
+    "cash_flows": CASH_FLOW_COLUMN_MIGRATIONS,
+}
+
 
 class Database(BaseDatabase):
     """Single source of truth for all database operations."""
@@ -27,7 +70,10 @@ class Database(BaseDatabase):
     _instances: dict[str, "Database"] = {}  # path -> instance
     _default_path: str | None = None
     _path: Path
-    _connection: aiosqlite.Connection | None
+    _connection: InstrumentedConnection | None
+    _config: Config | None
+    _key: str | None
+    _replica: "Database | None"
 
     def __new__(cls, path: str | None = None):
         """
@@ -47,6 +93,9 @@ class Database(BaseDatabase):
             instance = super().__new__(cls)
             instance._path = Path(path)
             instance._connection = None
+            instance._config = None
+            instance._key = None
+            instance._replica = None
             cls._instances[path] = instance
 
         return cls._instances[path]
@@ -55,19 +104,78 @@ class Database(BaseDatabase):
         # Path is already set in __new__, nothing to do here
         pass
 
-    async def connect(self) -> "Database":
-        """Connect to database and initialize schema."""
+    async def connect(self, config: Config | None = None) -> "Database":
+        """Connect to database and initialize schema.
+
+        Args:
+            config: Connection tuning; defaults to the SENTINEL_DB_PROFILE profile.
+        """
         if self._connection is None:
+            self._config = config or config_from_env()
             self._path.parent.mkdir(parents=True, exist_ok=True)
-            self._connection = await aiosqlite.connect(self._path)
-            self._connection.row_factory = aiosqlite.Row
-            await self._connection.execute("PRAGMA journal_mode=WAL")
-            await self._connection.execute("PRAGMA busy_timeout=30000")
+            self._key = database_key(self._path)
+            connection = await open_connection(self._path, self._key)
+            for pragma in self._config.pragmas():
+                await connection.execute(pragma)
+            self._connection = InstrumentedConnection(
+                connection, ContentionStats(self._config.lock_wait_threshold_ms)
+            )
             await self._init_schema()
+            self._replica = await self._open_replica()
         return self
 
+    async def _open_replica(self) -> "Database":
+        """Open the read-only connection behind `replica`."""
+        connection = await open_connection(self._path, self._key)
+        await connection.execute(f"PRAGMA busy_timeout={self._config.busy_timeout_ms}")
+        await connection.execute(f"PRAGMA cache_size=-{self._config.cache_size_kib}")
+        await connection.execute("PRAGMA query_only=ON")
+
+        # A second view of the same file: not registered as a singleton, and
+        # sharing every read method with this instance.
+        replica = object.__new__(Database)
+        replica._path = self._path
+        replica._key = self._key
+        replica._config = self._config
+        replica._replica = None
+        replica._connection = InstrumentedConnection(connection, ContentionStats(self._config.lock_wait_threshold_ms))
+        return replica
+
+    @property
+    def replica(self) -> "Database":
+        """Read-only view on a separate connection, for long analytical queries.
+
+        In WAL mode its SELECTs read the last committed data without holding
+        up writes on the main connection. Writes through it fail
+        (`query_only`). Falls back to this instance when there is no replica
+        (not connected, or already a replica).
+        """
+        return self._replica or self
+
+    @property
+    def encrypted(self) -> bool:
+        """Whether the file is opened with a SQLCipher key (see database.encryption)."""
+        return self._key is not None
+
+    @property
+    def config(self) -> Config | None:
+        """The tuning applied on connect, or None before connecting."""
+        return self._config
+
+    def contention_stats(self) -> dict[str, Any] | None:
+        """Busy errors and lock wait time since connecting, or None if not connected."""
+        if self._connection is None:
+            return None
+        stats = self._connection.stats.snapshot()
+        if self._replica is not None and self._replica._connection is not None:
+            stats["replica"] = self._replica._connection.stats.snapshot()
+        return stats
+
     async def close(self):
         """Close database connection."""
+        if self._replica is not None:
+            await self._replica.close()
+            self._replica = None
         if self._connection:
             await self._connection.close()
             self._connection = None
@@ -75,7 +183,7 @@ class Database(BaseDatabase):
     def remove_from_cache(self):
         """Remove this instance from the singleton cache. Use for temporary databases."""
         path_str = str(self._path)
-        if path_str in self._instances:
+        if self._instances.get(path_str) is self:
             del self._instances[path_str]
 
     # -------------------------------------------------------------------------
@@ -177,6 +285,43 @@ class Database(BaseDatabase):
         await self.conn.commit()
         return await self.get_security(symbol)
 
+    async def set_position_target(
+        self,
+        symbol: str,
+        *,
+        target_pct: float,
+        mode: str,
+        source: str,
+        updated_at: str | None = None,
+    ) -> dict | None:
+        """Set one security's position target weight (percent of portfolio)."""
+        updated_at = updated_at or datetime.now(timezone.utc).isoformat()
+        await self.conn.execute(
+            """UPDATE securities
+               SET target_weight_pct = ?,
+                   target_weight_mode = ?,
+                   target_weight_source = ?,
+                   target_weight_updated_at = ?
+               WHERE symbol = ?""",
+            (target_pct, mode, source, updated_at, symbol),
+        )
+        await self.conn.commit()
+        return await self.get_security(symbol)
+
+    async def clear_position_target(self, symbol: str) -> bool:
+        """Remove a security's position target. Returns False if it had none."""
+        cursor = await self.conn.execute(
+            """UPDATE securities
+               SET target_weight_pct = NULL,
+                   target_weight_mode = NULL,
+                   target_weight_source = NULL,
+                   target_weight_updated_at = NULL
+               WHERE symbol = ? AND target_weight_pct IS NOT NULL""",
+            (symbol,),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
     # -------------------------------------------------------------------------
     # Prices (extended methods beyond BaseDatabase)
     # -------------------------------------------------------------------------
@@ -533,15 +678,16 @@ class Database(BaseDatabase):
         error: Optional[str],
         duration_ms: int,
         retry_count: int,
-    ) -> None:
-        """Log a job execution to the job history."""
-        await self.conn.execute(
+    ) -> int:
+        """Log a job execution to the job history; returns the run's id."""
+        cursor = await self.conn.execute(
             """INSERT INTO job_history
                (job_id, job_type, status, error, duration_ms, executed_at, retry_count)
                VALUES (?, ?, ?, ?, ?, ?, ?)""",
             (job_id, job_type, status, error, duration_ms, int(datetime.now().timestamp()), retry_count),
         )
         await self.conn.commit()
+        return cursor.lastrowid
 
     async def get_last_job_completion(self, job_type: str) -> Optional[datetime]:
         """Get the timestamp of the last successful completion for a job type."""
@@ -556,6 +702,15 @@ class Database(BaseDatabase):
             return datetime.fromtimestamp(row["executed_at"])
         return None
 
+    async def get_last_job_completions(self) -> dict[str, int]:
+        """Timestamp of the last successful completion of each job type."""
+        cursor = await self.conn.execute(
+            """SELECT job_type, MAX(executed_at) AS executed_at FROM job_history
+               WHERE status = 'completed'
+               GROUP BY job_type"""
+        )
+        return {row["job_type"]: row["executed_at"] for row in await cursor.fetchall()}
+
     async def get_last_job_completion_by_id(self, job_id: str) -> Optional[datetime]:
         """Get the timestamp of the last successful completion for a specific job ID.
 
@@ -584,7 +739,7 @@ class Database(BaseDatabase):
     async def get_job_history(self, limit: int = 50) -> list[dict]:
         """Get recent job execution history."""
         cursor = await self.conn.execute(
-            """SELECT job_id, job_type, status, error, duration_ms,
+            """SELECT id, job_id, job_type, status, error, duration_ms,
                       executed_at, retry_count
                FROM job_history
                ORDER BY executed_at DESC LIMIT ?""",
@@ -593,6 +748,67 @@ class Database(BaseDatabase):
         rows = await cursor.fetchall()
         return [dict(row) for row in rows]
 
+    # -------------------------------------------------------------------------
+    # Job Artifacts
+    # -------------------------------------------------------------------------
+
+    async def create_job_artifact(
+        self,
+        run_id: int,
+        job_type: str,
+        name: str,
+        kind: str,
+        size_bytes: int,
+        data: bytes | None = None,
+        path: str | None = None,
+    ) -> int:
+        """Store one artifact of a job run, inline (`data`) or as a file (`path`)."""
+        cursor = await self.conn.execute(
+            """INSERT INTO job_artifacts (run_id, job_type, name, kind, size_bytes, data, path, created_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+            (run_id, job_type, name, kind, size_bytes, data, path, int(datetime.now().timestamp())),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid
+
+    async def get_job_artifacts(
+        self,
+        run_id: int | None = None,
+        job_type: str | None = None,
+        limit: int = 50,
+    ) -> list[dict]:
+        """Artifact metadata (no content), newest first."""
+        clauses, params = [], []
+        if run_id is not None:
+            clauses.append("run_id = ?")
+            params.append(run_id)
+        if job_type is not None:
+            clauses.append("job_type = ?")
+            params.append(job_type)
+        where = f"WHERE {' AND '.join(clauses)}" if clauses else ""
+        cursor = await self.conn.execute(
+            f"""SELECT id, run_id, job_type, name, kind, size_bytes, path IS NOT NULL AS in_file, created_at
+                FROM job_artifacts {where}
+                ORDER BY created_at DESC, id DESC LIMIT ?""",  # noqa: S608
+            (*params, limit),
+        )
+        return [{**dict(row), "in_file": bool(row["in_file"])} for row in await cursor.fetchall()]
+
+    async def get_job_artifact(self, artifact_id: int) -> dict | None:
+        """One artifact with its inline content or file path."""
+        cursor = await self.conn.execute("SELECT * FROM job_artifacts WHERE id = ?", (artifact_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def delete_job_artifacts_before(self, cutoff_ts: int) -> list[dict]:
+        """Delete artifacts created before `cutoff_ts`; returns their ids and file paths."""
+        cursor = await self.conn.execute("SELECT id, path FROM job_artifacts WHERE created_at < ?", (cutoff_ts,))
+        rows = [dict(row) for row in await cursor.fetchall()]
+        if rows:
+            await self.conn.execute("DELETE FROM job_artifacts WHERE created_at < ?", (cutoff_ts,))
+            await self.conn.commit()
+        return rows
+
     # -------------------------------------------------------------------------
     # Job Schedules
     # -------------------------------------------------------------------------
@@ -747,6 +963,10 @@ class Database(BaseDatabase):
             ("sync:cashflows", 1440, 1440, 0, "sync", "Sync cash flows from broker"),
             ("sync:dividends", 1440, 1440, 0, "sync", "Sync dividends from broker"),
             ("sync:benchmarks", 1440, 1440, 0, "sync", "Refresh benchmark indices roster + prices"),
+            ("sync:news", 360, 360, 0, "sync", "Ingest news headlines and refresh sentiment"),
+            ("security:technical", 1440, 1440, 0, "sync", "Update technical indicators from stored prices"),
+            ("security:liquidity", 1440, 1440, 0, "sync", "Measure average daily volume and flag illiquid securities"),
+            ("security:factors", 1440, 1440, 0, "sync", "Regress security returns on the index factors"),
             # Runs daily, but only touches rows whose slider is >= 7 days old.
             ("decay:user_multipliers", 1440, 1440, 0, "sync", "Step stored user_multiplier values toward neutral"),
             (
@@ -757,6 +977,8 @@ class Database(BaseDatabase):
                 "sync",
                 "Maintain portfolio snapshots by filling missing dates",
             ),
+            # Daily while markets are closed, every 30 minutes while any is open.
+            ("snapshot:valuation", 1440, 30, 0, "sync", "Record a live portfolio valuation snapshot"),
             ("trading:check_markets", 30, 30, 2, "trading", "Check which markets are open"),
             ("trading:execute", 30, 15, 2, "trading", "Execute pending trade recommendations"),
             ("trading:rebalance", 60, 60, 0, "trading", "Check portfolio rebalance needs"),
@@ -765,6 +987,8 @@ class Database(BaseDatabase):
             ("forecast:run", 10080, 10080, 3, "forecast", "Generate weekly time-series forecasts"),
             ("forecast:evaluate", 1440, 1440, 0, "forecast", "Evaluate matured time-series forecasts"),
             ("backup:r2", 1440, 1440, 0, "backup", "Backup data folder to Cloudflare R2"),
+            ("system:clock_check", 60, 60, 0, "system", "Check the system clock for drift against NTP"),
+            ("system:retention", 1440, 1440, 0, "system", "Apply data retention policies"),
         ]
 
         for job_type, interval, interval_open, timing, cat, desc in defaults:
@@ -794,7 +1018,7 @@ class Database(BaseDatabase):
     async def get_job_history_for_type(self, job_type: str, limit: int = 50) -> list[dict]:
         """Get job history for jobs matching type prefix."""
         cursor = await self.conn.execute(
-            """SELECT job_id, job_type, status, error, duration_ms, executed_at, retry_count
+            """SELECT id, job_id, job_type, status, error, duration_ms, executed_at, retry_count
                FROM job_history
                WHERE job_id LIKE ?
                ORDER BY executed_at DESC LIMIT ?""",
@@ -1057,6 +1281,125 @@ class Database(BaseDatabase):
         )
         await self.conn.commit()
 
+    # -------------------------------------------------------------------------
+    # News
+    # -------------------------------------------------------------------------
+
+    async def save_news_headlines(self, headlines: list[dict]) -> None:
+        """Store scored headlines; already-stored ids are left untouched."""
+        await self.conn.executemany(
+            """INSERT OR IGNORE INTO news_headlines
+               (id, symbol, title, url, source, published_at, sentiment)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            [
+                (h["id"], h["symbol"], h["title"], h.get("url"), h.get("source"), h["published_at"], h["sentiment"])
+                for h in headlines
+            ],
+        )
+        await self.conn.commit()
+
+    async def get_news_headlines(
+        self,
+        symbol: str,
+        since_ts: int | None = None,
+        limit: int | None = None,
+    ) -> list[dict]:
+        """Get a security's headlines, newest first."""
+        query = "SELECT * FROM news_headlines WHERE symbol = ?"
+        params: list[Any] = [symbol]
+        if since_ts is not None:
+            query += " AND published_at >= ?"
+            params.append(since_ts)
+        query += " ORDER BY published_at DESC"
+        if limit is not None:
+            query += " LIMIT ?"
+            params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def prune_news_headlines(self, before_ts: int) -> int:
+        """Delete headlines published before `before_ts`."""
+        cursor = await self.conn.execute("DELETE FROM news_headlines WHERE published_at < ?", (before_ts,))
+        await self.conn.commit()
+        return cursor.rowcount
+
+    async def upsert_news_sentiment(
+        self,
+        symbol: str,
+        *,
+        score: float,
+        weight: float,
+        headline_count: int,
+        updated_at: int,
+    ) -> None:
+        await self.conn.execute(
+            """INSERT OR REPLACE INTO news_sentiment (symbol, score, weight, headline_count, updated_at)
+               VALUES (?, ?, ?, ?, ?)""",
+            (symbol, score, weight, headline_count, updated_at),
+        )
+        await self.conn.commit()
+
+    async def get_news_sentiment(
+        self,
+        symbols: list[str] | None = None,
+        *,
+        max_age_seconds: int | None = None,
+    ) -> dict[str, dict]:
+        """Get stored sentiment by symbol, optionally only scores refreshed recently."""
+        where = ["1=1"]
+        params: list[Any] = []
+        if symbols:
+            placeholders = ",".join("?" for _ in symbols)
+            where.append(f"symbol IN ({placeholders})")
+            params.extend(symbols)
+        if max_age_seconds is not None:
+            where.append("updated_at >= ?")
+            params.append(int(datetime.now(timezone.utc).timestamp()) - int(max_age_seconds))
+        cursor = await self.conn.execute(
+            f"SELECT * FROM news_sentiment WHERE {' AND '.join(where)}",  # noqa: S608
+            params,
+        )
+        return {row["symbol"]: dict(row) for row in await cursor.fetchall()}
+
+    # -------------------------------------------------------------------------
+    # Valuation Snapshots
+    # -------------------------------------------------------------------------
+
+    async def save_valuation_snapshot(self, ts: int, data: dict) -> None:
+        """Store a live portfolio valuation taken at `ts` (unix seconds)."""
+        await self.conn.execute(
+            "INSERT OR REPLACE INTO valuation_snapshots (ts, data) VALUES (?, ?)",
+            (ts, json.dumps(data)),
+        )
+        await self.conn.commit()
+
+    async def get_valuation_snapshots(self, start_ts: int | None = None, end_ts: int | None = None) -> list[dict]:
+        """Get valuation snapshots in a time range, oldest first, with `data` decoded."""
+        where = ["1=1"]
+        params: list[Any] = []
+        if start_ts is not None:
+            where.append("ts >= ?")
+            params.append(start_ts)
+        if end_ts is not None:
+            where.append("ts <= ?")
+            params.append(end_ts)
+        cursor = await self.conn.execute(
+            f"SELECT ts, data FROM valuation_snapshots WHERE {' AND '.join(where)} ORDER BY ts ASC",  # noqa: S608
+            params,
+        )
+        return [{"ts": row["ts"], "data": json.loads(row["data"])} for row in await cursor.fetchall()]
+
+    async def thin_valuation_snapshots(self, before_ts: int) -> int:
+        """Keep only the last snapshot of each UTC day before `before_ts`. Returns rows deleted."""
+        cursor = await self.conn.execute(
+            """DELETE FROM valuation_snapshots
+               WHERE ts < ?
+                 AND ts NOT IN (SELECT MAX(ts) FROM valuation_snapshots GROUP BY ts / 86400)""",
+            (before_ts,),
+        )
+        await self.conn.commit()
+        return cursor.rowcount
+
     # -------------------------------------------------------------------------
     # Schema
     # -------------------------------------------------------------------------
@@ -1066,28 +1409,16 @@ class Database(BaseDatabase):
         await self.conn.executescript(SCHEMA)
         await self.conn.commit()
         await self._migrate_schema()
+        await self._apply_versioned_migrations()
 
     async def _migrate_schema(self) -> None:
         """Apply lightweight schema migrations for existing local databases."""
-        cursor = await self.conn.execute("PRAGMA table_info(securities)")
-        security_columns = {row["name"] for row in await cursor.fetchall()}
-        migrations = {
-            "user_multiplier_updated_at": "ALTER TABLE securities ADD COLUMN user_multiplier_updated_at TEXT",
-            "user_multiplier_source": (
-                "ALTER TABLE securities ADD COLUMN user_multiplier_source TEXT NOT NULL DEFAULT 'migration'"
-            ),
-            "user_multiplier_analysis": "ALTER TABLE securities ADD COLUMN user_multiplier_analysis TEXT",
-            "universe_source": "ALTER TABLE securities ADD COLUMN universe_source TEXT NOT NULL DEFAULT 'migration'",
-            "universe_last_seen_at": "ALTER TABLE securities ADD COLUMN universe_last_seen_at TEXT",
-            # Tradernet instrument-kind code (1 = stock, 7 = ETF, 10 = depositary
-            # receipt, …). Persisted as a first-class column so any future query
-            # that groups or filters by asset class can do so in SQL without
-            # parsing JSON. Populated by `sync_metadata`.
-            "instr_kind_c": "ALTER TABLE securities ADD COLUMN instr_kind_c INTEGER",
-        }
-        for column, statement in migrations.items():
-            if column not in security_columns:
-                await self.conn.execute(statement)
+        for table, migrations in COLUMN_MIGRATIONS.items():
+            cursor = await self.conn.execute(f"PRAGMA table_info({table})")
+            columns = {row["name"] for row in await cursor.fetchall()}
+            for column, statement in migrations.items():
+                if column not in columns:
+                    await self.conn.execute(statement)
 
         now_iso = datetime.now(timezone.utc).isoformat()
         await self.conn.execute("UPDATE securities SET user_multiplier = 0.5 WHERE user_multiplier IS NULL")
@@ -1118,6 +1449,21 @@ class Database(BaseDatabase):
         )
         await self.conn.commit()
 
+    async def _apply_versioned_migrations(self) -> None:
+        """Run the numbered migrations this file has not applied yet, in order."""
+        for migration in pending(set(await self.schema_versions())):
+            logger.info(f"Applying schema migration {migration.label}")
+            try:
+                await self.conn.executescript(up_script(migration))
+            except Exception:
+                await self.conn.rollback()
+                raise
+
+    async def schema_versions(self) -> list[int]:
+        """Versions of the numbered migrations applied to this file, ascending."""
+        cursor = await self.conn.execute("SELECT version FROM schema_migrations ORDER BY version")
+        return [row["version"] for row in await cursor.fetchall()]
+
 
 SCHEMA = """
 -- Settings (key-value store)
@@ -1145,6 +1491,16 @@ CREATE TABLE IF NOT EXISTS securities (
     user_multiplier_analysis TEXT,
     universe_source TEXT NOT NULL DEFAULT 'migration',
     universe_last_seen_at TEXT,
+    target_weight_pct REAL,  -- Position target (% of portfolio), NULL when untargeted
+    target_weight_mode TEXT,  -- 'hard' (pinned) or 'soft' (scaled with the rest)
+    target_weight_source TEXT,  -- 'manual' (respected by the planner) or 'optimizer' (adopted ideal)
+    target_weight_updated_at TEXT,
+    adv_volume REAL,  -- Average daily volume in shares (security:liquidity)
+    adv_turnover_eur REAL,  -- Average daily turnover in EUR (security:liquidity)
+    illiquid INTEGER NOT NULL DEFAULT 0,  -- 1 while turnover is below min_adv_turnover_eur
+    adv_updated_at TEXT,
+    archived_at TEXT,  -- When the security left the universe (see sentinel.universe), NULL while in it
+    archive_reason TEXT,  -- 'removed' or 'position_closed'
     aliases TEXT,  -- Comma-separated alternative names for news/sentiment search
     data TEXT,  -- Raw Tradernet API response (JSON)
     last_synced INTEGER,
@@ -1235,8 +1591,217 @@ CREATE TABLE IF NOT EXISTS cash_flows (
     amount REAL NOT NULL,
     currency TEXT NOT NULL,
     comment TEXT,
-    raw_data TEXT NOT NULL
+    raw_data TEXT NOT NULL,
+    broker_type_id TEXT,  -- The broker's type, kept when sentinel.cash_flow_rules reclassifies
+    classified_by TEXT,  -- builtin:<name>, rule:<id> or manual; NULL while unclassified
+    isin TEXT,
+    symbol TEXT
+);
+
+-- Learned cash flow classification rules: a description regex and the type
+-- it stands for, optionally for one broker. See sentinel.cash_flow_rules.
+CREATE TABLE IF NOT EXISTS cash_flow_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pattern TEXT NOT NULL,
+    type_id TEXT NOT NULL,
+    broker TEXT,                            -- NULL = any broker
+    created_from INTEGER,                   -- cash flow the rule was learned from
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+-- Savings plans: declared recurring deposits matched against card cash flows
+CREATE TABLE IF NOT EXISTS savings_plans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    amount REAL NOT NULL,                   -- expected deposit, in the plan currency
+    currency TEXT NOT NULL DEFAULT 'EUR',
+    day_of_month INTEGER NOT NULL,          -- 1-28
+    tolerance_pct REAL NOT NULL DEFAULT 10, -- accepted deviation from amount
+    window_days INTEGER NOT NULL DEFAULT 5, -- accepted distance from day_of_month
+    start_date TEXT NOT NULL,               -- YYYY-MM-DD; earlier deposits are not matched
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+-- Investment goals: an amount to reach by a date. The planner projects the
+-- portfolio against each one and leans in when the most important goal falls
+-- behind. See sentinel.planner.goals.
+CREATE TABLE IF NOT EXISTS investment_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    target_amount_eur REAL NOT NULL,
+    target_date TEXT NOT NULL,              -- YYYY-MM-DD
+    priority INTEGER NOT NULL DEFAULT 1,    -- 1 = most important
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+-- Temperament questionnaire answers, one row per (re)take. Each version keeps
+-- the answers, the derived scores and settings, and when it was applied.
+-- See sentinel.temperament.
+CREATE TABLE IF NOT EXISTS temperament_profiles (
+    version INTEGER PRIMARY KEY AUTOINCREMENT,
+    revision INTEGER NOT NULL,              -- questionnaire revision answered
+    answers TEXT NOT NULL,                  -- JSON: question id -> option id
+    scores TEXT NOT NULL,                   -- JSON: risk_tolerance, aggression (0-1)
+    settings TEXT NOT NULL,                 -- JSON: derived setting key -> value
+    applied_at INTEGER,
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+-- Exclusion lists: securities the planner must not buy, by symbol, ISIN or
+-- industry (JSON arrays). See sentinel.exclusions.
+CREATE TABLE IF NOT EXISTS exclusion_lists (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    reason TEXT,
+    symbols TEXT NOT NULL DEFAULT '[]',
+    isins TEXT NOT NULL DEFAULT '[]',
+    industries TEXT NOT NULL DEFAULT '[]',
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+-- Security groups: structural portfolio buckets (e.g. "Dividend core") with
+-- an optional target weight. A security belongs to at most one group.
+-- See sentinel.groups.
+CREATE TABLE IF NOT EXISTS security_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    target_pct REAL,                        -- percent of the portfolio; NULL = no target
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS security_group_members (
+    symbol TEXT PRIMARY KEY,
+    group_id INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_group_members_group ON security_group_members(group_id);
+
+-- Broker (Tradernet) symbol per ISIN, used for orders and quotes. 'auto' rows
+-- come from metadata sync; 'manual' rows are set through the API and are
+-- never overwritten by sync. See sentinel.broker_symbols.
+CREATE TABLE IF NOT EXISTS broker_symbols (
+    isin TEXT PRIMARY KEY,
+    broker_symbol TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT 'auto',
+    updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+-- Incoming prices/quotes held back by the sanity check instead of being
+-- written. See sentinel.price_sanity.
+CREATE TABLE IF NOT EXISTS price_quarantine (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    kind TEXT NOT NULL,                     -- 'price' (daily row) or 'quote'
+    date TEXT,                              -- YYYY-MM-DD for daily rows
+    value REAL,
+    reference REAL,                         -- last known price it was checked against
+    reason TEXT NOT NULL,
+    payload TEXT NOT NULL,                  -- JSON row/quote as received
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, accepted, rejected
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+    resolved_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_price_quarantine_symbol ON price_quarantine(symbol, created_at);
+
+-- Technical indicators per security and date (security:technical). `state`
+-- is the engine's running state as JSON, so the next run resumes from the
+-- latest row. See sentinel.indicators.
+CREATE TABLE IF NOT EXISTS security_indicators (
+    symbol TEXT NOT NULL,
+    date TEXT NOT NULL,
+    close REAL NOT NULL,
+    rsi14 REAL,
+    ema20 REAL,
+    sma50 REAL,
+    ema_sma_cross INTEGER,                  -- 1 while EMA20 > SMA50, -1 below
+    macd REAL,
+    macd_signal REAL,
+    macd_hist REAL,
+    bollinger_pct_b REAL,
+    atr14 REAL,
+    state TEXT NOT NULL,
+    PRIMARY KEY (symbol, date)
+);
+
+-- One row per price sync: what was fetched, rejected, quarantined and merged.
+CREATE TABLE IF NOT EXISTS price_sync_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at INTEGER NOT NULL,
+    finished_at INTEGER,                    -- NULL while running (or if the sync died)
+    symbols INTEGER NOT NULL DEFAULT 0,
+    fetched INTEGER NOT NULL DEFAULT 0,
+    rejected INTEGER NOT NULL DEFAULT 0,
+    quarantined INTEGER NOT NULL DEFAULT 0,
+    merged INTEGER NOT NULL DEFAULT 0,
+    gaps TEXT NOT NULL DEFAULT '{}'         -- JSON {symbol: [{from, to, days}]}
+);
+
+-- Write-ahead staging for fetched daily candles. Merged rows are cleared;
+-- rejected rows stay as their sync's report. See sentinel.price_staging.
+CREATE TABLE IF NOT EXISTS price_staging (
+    sync_id INTEGER NOT NULL REFERENCES price_sync_reports(id),
+    symbol TEXT NOT NULL,
+    date TEXT,
+    open REAL,
+    high REAL,
+    low REAL,
+    close REAL,
+    volume INTEGER,
+    status TEXT NOT NULL DEFAULT 'staged',  -- staged, rejected
+    reason TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_price_staging_sync ON price_staging(sync_id, symbol);
+
+-- Positions above the concentration alert levels, one row per breach from
+-- the check that opened it until it resolved. See sentinel.concentration.
+CREATE TABLE IF NOT EXISTS concentration_breaches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    level TEXT NOT NULL,                    -- warn, critical (current level)
+    started_at INTEGER NOT NULL,
+    escalated_at INTEGER,                   -- last time it reached critical
+    resolved_at INTEGER,                    -- NULL while open
+    peak_pct REAL NOT NULL,
+    last_pct REAL NOT NULL,
+    plan TEXT                               -- JSON reduction plan (dry run)
 );
+CREATE INDEX IF NOT EXISTS idx_concentration_breaches_open ON concentration_breaches(resolved_at, symbol);
+
+-- Orders placed through Sentinel, and broker orders picked up by the order
+-- sync, with their local state machine status. See sentinel.orders.
+CREATE TABLE IF NOT EXISTS orders (
+    order_id TEXT PRIMARY KEY,              -- broker order ID (RESEARCH-... in research mode)
+    symbol TEXT NOT NULL,
+    isin TEXT,
+    side TEXT NOT NULL,                     -- buy, sell
+    quantity REAL NOT NULL,
+    price REAL,                             -- limit price; NULL for market orders
+    status TEXT NOT NULL DEFAULT 'pending',
+    broker_status INTEGER,                  -- last Tradernet status code seen
+    source TEXT NOT NULL,                   -- planner, api, broker
+    replaces TEXT,                          -- order this one replaced (modify)
+    replaced_by TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status, isin);
+
+-- Cash flows matched to a savings plan (at most one per plan and month)
+CREATE TABLE IF NOT EXISTS savings_plan_deposits (
+    cash_flow_id INTEGER PRIMARY KEY REFERENCES cash_flows(id),
+    plan_id INTEGER NOT NULL REFERENCES savings_plans(id),
+    period TEXT NOT NULL,                   -- YYYY-MM of the expected deposit
+    date TEXT NOT NULL,                     -- YYYY-MM-DD the deposit landed
+    amount_eur REAL NOT NULL,
+    invested_eur REAL NOT NULL DEFAULT 0,   -- spent by new-money buys
+    matched_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+    UNIQUE (plan_id, period)
+);
+CREATE INDEX IF NOT EXISTS idx_savings_plan_deposits_date ON savings_plan_deposits(date);
 
 -- Job schedules (runtime cadence configuration)
 CREATE TABLE IF NOT EXISTS job_schedules (
@@ -1264,8 +1829,23 @@ CREATE TABLE IF NOT EXISTS job_history (
     retry_count INTEGER NOT NULL DEFAULT 0
 );
 
+-- Results left by job runs (reports, weights, diffs), keyed by job_history.id.
+-- Small ones inline in `data`, large ones in a file under data/artifacts.
+CREATE TABLE IF NOT EXISTS job_artifacts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    job_type TEXT NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    data BLOB,
+    path TEXT,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_job_artifacts_run_id ON job_artifacts(run_id);
+CREATE INDEX IF NOT EXISTS idx_job_artifacts_created_at ON job_artifacts(created_at);
+
 -- Create indexes
-CREATE INDEX IF NOT EXISTS idx_prices_symbol_date ON prices(symbol, date);
 CREATE INDEX IF NOT EXISTS idx_trades_broker_id ON trades(broker_trade_id);
 CREATE INDEX IF NOT EXISTS idx_trades_symbol ON trades(symbol);
 CREATE INDEX IF NOT EXISTS idx_trades_executed_at ON trades(executed_at);
@@ -1355,12 +1935,40 @@ CREATE INDEX IF NOT EXISTS idx_forecast_points_symbol_scope ON forecast_points(s
 CREATE INDEX IF NOT EXISTS idx_forecast_scores_symbol_scope ON forecast_scores(symbol, scope);
 CREATE INDEX IF NOT EXISTS idx_forecast_evaluations_symbol ON forecast_evaluations(symbol, evaluated_at DESC);
 
+-- News headlines per security (sync:news) and their decayed sentiment. The
+-- sentiment is an optional timing factor next to forecast scores.
+CREATE TABLE IF NOT EXISTS news_headlines (
+    id TEXT PRIMARY KEY,                 -- sha1 of symbol + url (or title)
+    symbol TEXT NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT,
+    source TEXT,
+    published_at INTEGER NOT NULL,
+    sentiment REAL NOT NULL,             -- -1..1
+    fetched_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_news_headlines_symbol ON news_headlines(symbol, published_at DESC);
+
+CREATE TABLE IF NOT EXISTS news_sentiment (
+    symbol TEXT PRIMARY KEY,
+    score REAL NOT NULL,                 -- -1..1, decayed
+    weight REAL NOT NULL,                -- sum of decay weights behind the score
+    headline_count INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
 -- Portfolio snapshots (daily composition tracking — JSON blob)
 CREATE TABLE IF NOT EXISTS portfolio_snapshots (
     date INTEGER PRIMARY KEY,  -- unix timestamp, midnight UTC
     data TEXT NOT NULL          -- JSON: {positions: {symbol: {quantity, value_eur}}, cash_eur}
 );
 
+-- Live valuation snapshots (daily, plus intra-day while markets are open)
+CREATE TABLE IF NOT EXISTS valuation_snapshots (
+    ts INTEGER PRIMARY KEY,  -- unix timestamp of the valuation
+    data TEXT NOT NULL       -- JSON: totals, positions, cash and FX rates to EUR
+);
+
 -- Strategy state (deterministic contrarian tranche/rotation state per symbol)
 CREATE TABLE IF NOT EXISTS strategy_state (
     symbol TEXT PRIMARY KEY,
@@ -1383,6 +1991,97 @@ CREATE TABLE IF NOT EXISTS planner_state (
     updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
 );
 
+-- Planner snapshots: the inputs and resulting trade sequence of one planning
+-- cycle, kept so consecutive cycles can be diffed.
+CREATE TABLE IF NOT EXISTS planner_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+    source TEXT NOT NULL,            -- job that produced the batch (planning:refresh, trading:execute)
+    context TEXT NOT NULL,           -- JSON: {cash_eur, total_value_eur, settings, securities: {symbol: {...}}}
+    recommendations TEXT NOT NULL    -- JSON: recommendations in execution order
+);
+CREATE INDEX IF NOT EXISTS idx_planner_snapshots_created ON planner_snapshots(created_at DESC);
+
+-- Allocation runs: each distinct live ideal-portfolio result, for tracing
+-- how target weights drift (sentinel.planner.allocation_history).
+CREATE TABLE IF NOT EXISTS allocation_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    last_seen_at INTEGER NOT NULL,   -- last time the same result was computed again
+    model TEXT NOT NULL,             -- target model (clara_risk)
+    weights TEXT NOT NULL,           -- JSON: {symbol: weight}
+    constraints TEXT NOT NULL,       -- JSON: caps, cash target, manual targets, groups
+    effective_holdings REAL,         -- 1 / sum of squared weights
+    turnover REAL                    -- one-way turnover against the previous run
+);
+
+-- Factor exposures: each security's regression on the index factors (security:factors)
+CREATE TABLE IF NOT EXISTS factor_exposures (
+    symbol TEXT PRIMARY KEY,
+    region TEXT NOT NULL,            -- home-market basket of the region factor
+    betas TEXT NOT NULL,             -- JSON: {factor: beta}
+    alpha REAL,                      -- annualized intercept
+    residual_vol REAL,               -- annualized volatility the factors leave unexplained
+    r_squared REAL,
+    samples INTEGER NOT NULL,        -- trading days in the regression
+    updated_at INTEGER NOT NULL
+);
+
+-- Research notes per security (sentinel.notes): every save is a revision,
+-- the latest one is the current note.
+CREATE TABLE IF NOT EXISTS security_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    body TEXT NOT NULL,              -- markdown; '' when the note was cleared
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_security_notes_symbol ON security_notes(symbol, id);
+
+-- Small research files per security; deleted ones stay for the history.
+CREATE TABLE IF NOT EXISTS security_attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,           -- bytes
+    content BLOB NOT NULL,
+    created_at INTEGER NOT NULL,
+    deleted_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_security_attachments_symbol ON security_attachments(symbol, id);
+
+-- Securities merged into another record (sentinel.duplicates); the old symbol
+-- keeps resolving to the canonical one.
+CREATE TABLE IF NOT EXISTS security_merges (
+    symbol TEXT PRIMARY KEY,         -- merged-away duplicate
+    canonical TEXT NOT NULL,
+    rows TEXT NOT NULL,              -- JSON: {table: rows moved}
+    merged_at INTEGER NOT NULL
+);
+
+-- Idempotency keys: the first successful result of a side-effecting request,
+-- returned again when a client retries with the same Idempotency-Key header.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope TEXT NOT NULL,             -- endpoint the key was used on (e.g. planner:approve)
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,      -- hash of the request parameters the key was first used with
+    response TEXT NOT NULL,          -- JSON response body
+    created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY (scope, key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
+
+-- Earnings calendar: announced earnings dates per security, used for the
+-- optional pre-earnings trade freeze (earnings_freeze_days).
+CREATE TABLE IF NOT EXISTS earnings_dates (
+    symbol TEXT NOT NULL,
+    date TEXT NOT NULL,                  -- YYYY-MM-DD
+    source TEXT NOT NULL DEFAULT 'manual',
+    updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY (symbol, date)
+);
+CREATE INDEX IF NOT EXISTS idx_earnings_dates_date ON earnings_dates(date);
+
 -- Dividends (synced from broker corporate actions)
 CREATE TABLE IF NOT EXISTS dividends (
     id TEXT PRIMARY KEY,  -- corporate_action_id from broker API
@@ -1404,4 +2103,10 @@ CREATE TABLE IF NOT EXISTS fx_rates_history (
     PRIMARY KEY (date, currency)
 );
 
+-- Versioned migrations applied to this file (sentinel.database.versions)
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at INTEGER NOT NULL
+);
 """
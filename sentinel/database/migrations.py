@@ -0,0 +1,199 @@
+"""
+Schema migration gating for deploys.
+
+`Database.connect()` brings any database up to the current schema: missing
+tables and indexes come from `SCHEMA`, missing columns from
+`COLUMN_MIGRATIONS`, and everything else from the numbered
+migrations in `sentinel.database.versions`. The deploy script runs the new
+code in migrate-check mode first (`python main.py --migrate-check`) to list
+what would change, then `python main.py --migrate` to take a backup and
+apply it before the service is restarted. A failed migration restores the
+backup. `--migrate-down VERSION` runs the numbered migrations' down scripts
+back to VERSION, also behind a backup; `--dry-run` makes either only report.
+
+Usage:
+    pending = pending_migrations(path)
+    result = await apply_migrations(path, backup_dir)
+    result = rollback_migrations(path, backup_dir, target=0)
+"""
+
+from __future__ import annotations
+
+import logging
+import re
+import time
+from pathlib import Path
+from typing import Any
+
+from sentinel.database.encryption import connect, database_key
+from sentinel.database.main import COLUMN_MIGRATIONS, SCHEMA, Database
+from sentinel.database.versions import MIGRATIONS, down_script, latest_version, pending, to_undo
+
+logger = logging.getLogger(__name__)
+
+_CREATE_TABLE = re.compile(r"CREATE TABLE IF NOT EXISTS (\w+)")
+
+
+def schema_tables() -> list[str]:
+    """Tables the current schema defines, in definition order."""
+    return _CREATE_TABLE.findall(SCHEMA)
+
+
+def pending_migrations(path: Path) -> list[str]:
+    """Describe the schema changes connecting to the database at `path` would make.
+
+    Only reads the database. A missing file is reported as one pending change.
+    """
+    if not path.exists():
+        return ["create database"]
+    conn = connect(f"file:{path}?mode=ro", database_key(path), uri=True)
+    try:
+        existing = {row[0] for row in conn.execute("SELECT name FROM sqlite_master WHERE type = 'table'")}
+        changes = [f"create table {table}" for table in schema_tables() if table not in existing]
+        for table, migrations in COLUMN_MIGRATIONS.items():
+            if table not in existing:
+                continue
+            columns = {row[1] for row in conn.execute(f"PRAGMA table_info({table})")}
+            changes += [f"add column {table}.{column}" for column in migrations if column not in columns]
+        applied = _applied(conn, existing)
+        return changes + [f"migration {migration.label}" for migration in pending(set(applied))]
+    finally:
+        conn.close()
+
+
+def _applied(conn: Any, tables: set[str]) -> dict[int, int]:
+    """Applied numbered migrations: version -> applied_at."""
+    if "schema_migrations" not in tables:
+        return {}
+    return dict(conn.execute("SELECT version, applied_at FROM schema_migrations ORDER BY version").fetchall())
+
+
+def migration_status(path: Path) -> dict[str, Any]:
+    """The schema version of the database at `path` and its numbered migrations, applied or not.
+
+    Only reads the database.
+    """
+    applied: dict[int, int] = {}
+    if path.exists():
+        conn = connect(f"file:{path}?mode=ro", database_key(path), uri=True)
+        try:
+            tables = {row[0] for row in conn.execute("SELECT name FROM sqlite_master WHERE type = 'table'")}
+            applied = _applied(conn, tables)
+        finally:
+            conn.close()
+    return {
+        "database": str(path),
+        "version": max(applied, default=0),
+        "latest": latest_version(),
+        "migrations": [
+            {"version": m.version, "name": m.name, "applied_at": applied.get(m.version)} for m in MIGRATIONS
+        ],
+        "pending": pending_migrations(path),
+    }
+
+
+def backup_database(path: Path, backup_dir: Path) -> Path:
+    """Copy the database with SQLite's online backup, safe while the service is running.
+
+    An encrypted database's backup is encrypted with the same key.
+    """
+    backup_dir.mkdir(parents=True, exist_ok=True)
+    target = backup_dir / f"pre-migration-{time.strftime('%Y%m%d-%H%M%S')}.db"
+    key = database_key(path)
+    source = connect(path, key)
+    dest = connect(target, key)
+    try:
+        source.backup(dest)
+    finally:
+        dest.close()
+        source.close()
+    return target
+
+
+def restore_database(backup: Path, path: Path) -> None:
+    """Write a backup back over the database, page by page under SQLite's locks."""
+    key = database_key(path)
+    source = connect(backup, key)
+    dest = connect(path, key)
+    try:
+        source.backup(dest)
+    finally:
+        dest.close()
+        source.close()
+
+
+async def apply_migrations(path: Path, backup_dir: Path, dry_run: bool = False) -> dict[str, Any]:
+    """Back up the database and apply pending migrations.
+
+    Returns:
+        {"applied": [...], "backup": path or None, "from_version": N}; nothing
+        is backed up when nothing is pending. With `dry_run`, {"pending": [...],
+        "from_version": N, "dry_run": true} and nothing is touched.
+
+    Raises:
+        Exception: Whatever the migration raised, after the backup is restored.
+    """
+    changes = pending_migrations(path)
+    from_version = migration_status(path)["version"]
+    if dry_run:
+        return {"pending": changes, "from_version": from_version, "dry_run": True}
+    if not changes:
+        return {"applied": [], "backup": None, "from_version": from_version}
+    backup = backup_database(path, backup_dir) if path.exists() else None
+    if backup:
+        logger.info("Backed up %s to %s before migrating", path, backup)
+
+    db = Database(str(path))
+    try:
+        await db.connect()
+    except Exception:
+        logger.exception("Migration failed")
+        if backup:
+            restore_database(backup, path)
+            logger.info("Restored %s from %s", path, backup)
+        raise
+    finally:
+        await db.close()
+        db.remove_from_cache()
+    return {"applied": changes, "backup": str(backup) if backup else None, "from_version": from_version}
+
+
+def rollback_migrations(path: Path, backup_dir: Path, target: int, dry_run: bool = False) -> dict[str, Any]:
+    """Back up the database and undo its numbered migrations above `target`, newest first.
+
+    Run it with the code that knows the migrations, then switch to the older
+    code; the newer code's Database.connect() would apply them again.
+
+    Returns:
+        {"reverted": [...], "backup": path or None, "version": N}; with
+        `dry_run`, nothing is touched and `reverted` lists what would be.
+
+    Raises:
+        ValueError: For a negative target or a database that does not exist.
+        Exception: Whatever a down script raised, after the backup is restored.
+    """
+    if target < 0:
+        raise ValueError("target version must not be negative")
+    if not path.exists():
+        raise ValueError(f"{path} does not exist")
+    undo = to_undo({m["version"] for m in migration_status(path)["migrations"] if m["applied_at"] is not None}, target)
+    labels = [migration.label for migration in undo]
+    if dry_run or not undo:
+        return {"reverted": labels, "backup": None, "version": migration_status(path)["version"], "dry_run": dry_run}
+
+    backup = backup_database(path, backup_dir)
+    logger.info("Backed up %s to %s before reverting migrations", path, backup)
+    conn = connect(path, database_key(path))
+    try:
+        for migration in undo:
+            logger.info("Reverting schema migration %s", migration.label)
+            conn.executescript(down_script(migration))
+    except Exception:
+        logger.exception("Reverting migrations failed")
+        conn.rollback()
+        conn.close()
+        restore_database(backup, path)
+        logger.info("Restored %s from %s", path, backup)
+        raise
+    conn.close()
+    return {"reverted": labels, "backup": str(backup), "version": migration_status(path)["version"], "dry_run": False}
@@ -50,7 +50,16 @@ class SimulationDatabase(BaseDatabase):
                     pass
 
         # Copy read-only reference data only
-        for table in ["settings", "securities", "prices"]:
+        for table in [
+            "settings",
+            "securities",
+            "prices",
+            "earnings_dates",
+            "exclusion_lists",
+            "security_groups",
+            "security_group_members",
+            "broker_symbols",
+        ]:
             await self._copy_table(source_db, table)
 
         await self._connection.commit()
@@ -264,4 +273,4 @@ class SimulationDatabase(BaseDatabase):
             ),
         )
         await self._maybe_commit()
-        return cursor.lastrowid or 0
+        return (cursor.lastrowid or 0) if cursor.rowcount else 0
@@ -0,0 +1,79 @@
+"""
+Versioned schema migrations.
+
+`SCHEMA` (CREATE ... IF NOT EXISTS) and `COLUMN_MIGRATIONS` cover additive
+changes and stay the baseline. Anything else - dropping or rebuilding a
+table or index, moving data - is a numbered Migration with an `up` script
+and a `down` script that undoes it. Every database file records
+what it has applied in `schema_migrations`, so each Database is migrated on
+its own; `Database.connect()` applies whatever is pending, in order, after
+the baseline.
+
+Each step runs in one transaction together with its bookkeeping row, so a
+failing script leaves the database at the previous version. To add one,
+append a Migration with the next version number; never edit or renumber a
+released one.
+
+Usage:
+    await db.conn.executescript(up_script(MIGRATIONS[0]))
+    python main.py --migrate-status / --migrate --dry-run / --migrate-down VERSION
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+
+
+@dataclass(frozen=True)
+class Migration:
+    """One schema change and its inverse, as SQL scripts."""
+
+    version: int
+    name: str
+    up: str
+    down: str
+
+    @property
+    def label(self) -> str:
+        return f"{self.version:03d}_{self.name}"
+
+
+MIGRATIONS: list[Migration] = [
+    # `prices` has PRIMARY KEY (symbol, date), whose automatic index already
+    # serves every lookup; the copy doubled the write cost of price syncs.
+    Migration(
+        1,
+        "drop_redundant_prices_index",
+        up="DROP INDEX IF EXISTS idx_prices_symbol_date;",
+        down="CREATE INDEX IF NOT EXISTS idx_prices_symbol_date ON prices(symbol, date);",
+    ),
+]
+
+
+def latest_version() -> int:
+    return MIGRATIONS[-1].version if MIGRATIONS else 0
+
+
+def up_script(migration: Migration) -> str:
+    """`up` plus its bookkeeping row, as one transaction for executescript."""
+    return (
+        f"BEGIN;\n{migration.up}\n"
+        f"INSERT INTO schema_migrations (version, name, applied_at) "
+        f"VALUES ({migration.version}, '{migration.name}', strftime('%s', 'now'));\nCOMMIT;"
+    )
+
+
+def down_script(migration: Migration) -> str:
+    """`down` plus removing its bookkeeping row, as one transaction for executescript."""
+    return f"BEGIN;\n{migration.down}\nDELETE FROM schema_migrations WHERE version = {migration.version};\nCOMMIT;"
+
+
+def pending(applied: set[int], target: int | None = None) -> list[Migration]:
+    """Migrations to run up to `target` (default: latest), in order."""
+    target = latest_version() if target is None else target
+    return [m for m in MIGRATIONS if m.version not in applied and m.version <= target]
+
+
+def to_undo(applied: set[int], target: int) -> list[Migration]:
+    """Applied migrations above `target`, newest first."""
+    return [m for m in reversed(MIGRATIONS) if m.version in applied and m.version > target]
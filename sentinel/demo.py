@@ -0,0 +1,337 @@
+"""
+Demo mode - a synthetic portfolio for demos and development without a broker.
+
+With SENTINEL_DEMO=1 the data directory moves to `data/demo` (see
+sentinel.paths), the broker never connects, and on startup an empty database
+is seeded with:
+- DEMO_UNIVERSE: fictional stocks and an ETF in EUR, USD, GBP and HKD
+- DEMO_YEARS of daily prices: correlated geometric Brownian motion
+- a ledger of monthly card deposits, FX conversions, buys, occasional sells
+  and quarterly dividends, with positions and cash balances replayed from it
+  (so the ledger consistency check passes)
+
+Everything comes from one random.Random(SENTINEL_DEMO_SEED), so the same
+seed and end date give the same data. Trading mode is set to research. The
+sync jobs skip while the broker is disconnected, so the data stays as seeded.
+Delete `data/demo` to start over.
+
+Usage:
+    if demo_enabled():
+        await seed_demo(db)
+"""
+
+from __future__ import annotations
+
+import logging
+import math
+import os
+import random
+from dataclasses import dataclass
+from datetime import date, datetime, timedelta, timezone
+from typing import Any
+
+logger = logging.getLogger(__name__)
+
+DEMO_ENV = "SENTINEL_DEMO"
+DEMO_SEED_ENV = "SENTINEL_DEMO_SEED"
+DEFAULT_SEED = 42
+DEMO_YEARS = 5
+
+# EUR per unit, held fixed for the whole history.
+DEMO_RATES = {"EUR": 1.0, "USD": 0.92, "GBP": 1.17, "HKD": 0.118}
+
+INITIAL_DEPOSIT_EUR = 10_000.0
+MONTHLY_DEPOSIT_EUR = 500.0
+MARKET_CORRELATION = 0.6
+COMMISSION_PCT = 0.0015
+MIN_COMMISSION_EUR = 1.0
+DIVIDEND_TAX = 0.15
+# The ledger starts two months (in business days) into the price history.
+LEDGER_START_DAYS = 42
+
+
+@dataclass(frozen=True)
+class DemoSecurity:
+    symbol: str
+    name: str
+    currency: str
+    geography: str
+    industry: str
+    start_price: float
+    drift: float  # annual
+    volatility: float  # annual
+    dividend_yield: float  # annual, paid quarterly
+    instr_kind_c: int = 1
+
+
+DEMO_UNIVERSE = (
+    DemoSecurity("AURA.EU", "Aurelia Energy", "EUR", "FR", "Electric Utilities", 18.0, 0.05, 0.18, 0.045),
+    DemoSecurity("BRKN.GR", "Brueckner Maschinenbau", "EUR", "DE", "Industrial Machinery", 64.0, 0.07, 0.24, 0.025),
+    DemoSecurity("HELX.US", "Helix Therapeutics", "USD", "US", "Biotechnology", 41.0, 0.09, 0.42, 0.0),
+    DemoSecurity("NVLT.US", "Novalight Semiconductor", "USD", "US", "Semiconductors", 120.0, 0.16, 0.38, 0.004),
+    DemoSecurity("QRTZ.US", "Quartz Cloud Software", "USD", "US", "Software", 88.0, 0.12, 0.32, 0.0),
+    DemoSecurity("TMSR.L", "Thames River Insurance", "GBP", "GB", "Insurance", 7.5, 0.06, 0.2, 0.05),
+    DemoSecurity("0777.HK", "Kowloon Harbour Logistics", "HKD", "HK", "Marine Logistics", 23.0, 0.04, 0.3, 0.06),
+    DemoSecurity("WRLD.EU", "Global Equity UCITS ETF", "EUR", "IE", "ETF", 95.0, 0.08, 0.15, 0.0, instr_kind_c=7),
+)
+
+
+def demo_enabled() -> bool:
+    """Whether SENTINEL_DEMO is set to a true value."""
+    return os.environ.get(DEMO_ENV, "").strip().lower() in ("1", "true", "yes", "on")
+
+
+def demo_seed() -> int:
+    """SENTINEL_DEMO_SEED, or DEFAULT_SEED when unset or not a number."""
+    try:
+        return int(os.environ.get(DEMO_SEED_ENV, DEFAULT_SEED))
+    except ValueError:
+        return DEFAULT_SEED
+
+
+def _business_days(start: date, end: date) -> list[date]:
+    days = []
+    day = start
+    while day <= end:
+        if day.weekday() < 5:
+            days.append(day)
+        day += timedelta(days=1)
+    return days
+
+
+def generate_prices(rng: random.Random, days: list[date]) -> dict[str, list[dict]]:
+    """Daily OHLCV per security, correlated through one market factor."""
+    prices: dict[str, list[dict]] = {s.symbol: [] for s in DEMO_UNIVERSE}
+    last = {s.symbol: s.start_price for s in DEMO_UNIVERSE}
+    idio = math.sqrt(1 - MARKET_CORRELATION**2)
+    for day in days:
+        market = rng.gauss(0, 1)
+        for sec in DEMO_UNIVERSE:
+            shock = MARKET_CORRELATION * market + idio * rng.gauss(0, 1)
+            daily_vol = sec.volatility / math.sqrt(252)
+            ret = sec.drift / 252 - daily_vol**2 / 2 + daily_vol * shock
+            prev = last[sec.symbol]
+            close = prev * math.exp(ret)
+            open_ = prev * math.exp(rng.gauss(0, daily_vol / 4))
+            high = max(open_, close) * (1 + abs(rng.gauss(0, daily_vol / 3)))
+            low = min(open_, close) * (1 - abs(rng.gauss(0, daily_vol / 3)))
+            volume = int(2_000_000 / sec.start_price * rng.lognormvariate(0, 0.4))
+            prices[sec.symbol].append(
+                {
+                    "date": day.isoformat(),
+                    "open": round(open_, 4),
+                    "high": round(high, 4),
+                    "low": round(low, 4),
+                    "close": round(close, 4),
+                    "volume": volume,
+                }
+            )
+            last[sec.symbol] = close
+    return prices
+
+
+class _Ledger:
+    """Synthetic trades and cash flows, with the positions and cash they add up to."""
+
+    def __init__(self):
+        self.trades: list[dict] = []
+        self.cash_flows: list[dict] = []
+        self.dividends: list[dict] = []
+        self.cash: dict[str, float] = {}
+        self.positions: dict[str, dict[str, float]] = {}
+
+    def _move(self, currency: str, amount: float) -> None:
+        self.cash[currency] = self.cash.get(currency, 0.0) + amount
+
+    def _ts(self, day: date) -> int:
+        return int(datetime(day.year, day.month, day.day, 15, tzinfo=timezone.utc).timestamp())
+
+    def _trade(self, day: date, symbol: str, side: str, quantity: float, price: float, summ: float, currency: str):
+        commission = 0.0
+        if "/" not in symbol:
+            commission = round(max(MIN_COMMISSION_EUR, summ * DEMO_RATES[currency] * COMMISSION_PCT), 2)
+        self.trades.append(
+            {
+                "broker_trade_id": f"demo-{len(self.trades) + 1}",
+                "symbol": symbol,
+                "side": side,
+                "quantity": quantity,
+                "price": price,
+                "executed_at": self._ts(day),
+                "raw_data": {"summ": round(summ, 2), "curr_c": currency},
+                "commission": commission,
+                "commission_currency": "EUR",
+            }
+        )
+        self._move("EUR", -commission)
+
+    def deposit(self, day: date, amount: float) -> None:
+        self.cash_flows.append(
+            {"date": day.isoformat(), "type_id": "card", "amount": amount, "currency": "EUR", "comment": "Deposit"}
+        )
+        self._move("EUR", amount)
+
+    def buy(self, day: date, sec: DemoSecurity, price: float, budget_eur: float) -> None:
+        need = budget_eur / DEMO_RATES[sec.currency]
+        quantity = math.floor(need / price)
+        if quantity < 1:
+            return
+        cost = round(quantity * price, 2)
+        if sec.currency != "EUR":
+            shortfall = cost - max(0.0, self.cash.get(sec.currency, 0.0))
+            if shortfall > 0:
+                eur = round(shortfall * DEMO_RATES[sec.currency] * 1.001, 2)
+                received = round(eur / DEMO_RATES[sec.currency], 2)
+                self._trade(day, f"EUR/{sec.currency}", "SELL", eur, round(received / eur, 6), received, "EUR")
+                self._move("EUR", -eur)
+                self._move(sec.currency, received)
+        self._trade(day, sec.symbol, "BUY", quantity, price, cost, sec.currency)
+        self._move(sec.currency, -cost)
+        pos = self.positions.setdefault(sec.symbol, {"quantity": 0.0, "avg_cost": 0.0})
+        pos["avg_cost"] = (pos["avg_cost"] * pos["quantity"] + cost) / (pos["quantity"] + quantity)
+        pos["quantity"] += quantity
+
+    def sell(self, day: date, sec: DemoSecurity, price: float, quantity: float) -> None:
+        proceeds = round(quantity * price, 2)
+        self._trade(day, sec.symbol, "SELL", quantity, price, proceeds, sec.currency)
+        self._move(sec.currency, proceeds)
+        pos = self.positions[sec.symbol]
+        pos["quantity"] -= quantity
+        if pos["quantity"] <= 0:
+            del self.positions[sec.symbol]
+
+    def dividend(self, day: date, sec: DemoSecurity, price: float) -> None:
+        quantity = self.positions.get(sec.symbol, {}).get("quantity", 0)
+        amount = round(quantity * price * sec.dividend_yield / 4 * (1 - DIVIDEND_TAX), 2)
+        if amount <= 0:
+            return
+        flow_id = f"demo-div-{len(self.dividends) + 1}"
+        self.cash_flows.append(
+            {
+                "date": day.isoformat(),
+                "type_id": "dividend",
+                "amount": amount,
+                "currency": sec.currency,
+                "comment": f"Dividend {sec.symbol}",
+            }
+        )
+        self.dividends.append(
+            {
+                "id": flow_id,
+                "symbol": sec.symbol,
+                "date": day.isoformat(),
+                "amount": amount,
+                "currency": sec.currency,
+                "value": round(amount * DEMO_RATES[sec.currency], 2),
+            }
+        )
+        self._move(sec.currency, amount)
+
+
+def generate_ledger(rng: random.Random, days: list[date], prices: dict[str, list[dict]]) -> _Ledger:
+    """Deposits, buys, sells and dividends on the first business day of each month."""
+    ledger = _Ledger()
+    by_symbol = {s.symbol: s for s in DEMO_UNIVERSE}
+    month = None
+    for i, day in enumerate(days):
+        if i < LEDGER_START_DAYS or (day.year, day.month) == month:
+            continue
+        month = (day.year, day.month)
+        closes = {symbol: rows[i]["close"] for symbol, rows in prices.items()}
+        ledger.deposit(day, INITIAL_DEPOSIT_EUR if not ledger.cash_flows else MONTHLY_DEPOSIT_EUR)
+
+        if day.month % 3 == 0:
+            for symbol in list(ledger.positions):
+                if by_symbol[symbol].dividend_yield:
+                    ledger.dividend(day, by_symbol[symbol], closes[symbol])
+
+        if ledger.positions and rng.random() < 0.1:
+            symbol = rng.choice(sorted(ledger.positions))
+            quantity = math.floor(ledger.positions[symbol]["quantity"] / 2) or ledger.positions[symbol]["quantity"]
+            ledger.sell(day, by_symbol[symbol], closes[symbol], quantity)
+
+        buys = 3 if len(ledger.trades) == 0 else 1
+        for sec in rng.sample(DEMO_UNIVERSE, buys):
+            available = ledger.cash.get("EUR", 0.0) + ledger.cash.get(sec.currency, 0.0) * DEMO_RATES[sec.currency]
+            ledger.buy(day, sec, closes[sec.symbol], available / buys * 0.9)
+    return ledger
+
+
+def _quote(symbol: str, rows: list[dict]) -> dict[str, Any]:
+    last, prev = rows[-1]["close"], rows[-2]["close"]
+    change = round(last - prev, 4)
+    quote = {
+        "c": symbol,
+        "ltp": last,
+        "bbp": round(last * 0.999, 4),
+        "bap": round(last * 1.001, 4),
+        "chg": change,
+        "pcp": round(change / prev * 100, 2),
+    }
+    return {
+        **quote,
+        "symbol": symbol,
+        "price": quote["ltp"],
+        "bid": quote["bbp"],
+        "ask": quote["bap"],
+        "change": quote["chg"],
+        "change_percent": quote["pcp"],
+    }
+
+
+async def seed_demo(db, seed: int | None = None, today: date | None = None) -> dict[str, int]:
+    """Fill an empty database with the synthetic portfolio; does nothing if it has securities.
+
+    Returns:
+        {"securities", "prices", "trades", "cash_flows"} rows written
+    """
+    if await db.get_all_securities(active_only=False):
+        return {"securities": 0, "prices": 0, "trades": 0, "cash_flows": 0}
+    seed = demo_seed() if seed is None else seed
+    rng = random.Random(seed)
+    today = today or date.today()
+    days = _business_days(today - timedelta(days=365 * DEMO_YEARS), today)
+    prices = generate_prices(rng, days)
+    ledger = generate_ledger(rng, days, prices)
+
+    for sec in DEMO_UNIVERSE:
+        await db.upsert_security(
+            sec.symbol,
+            name=sec.name,
+            currency=sec.currency,
+            geography=sec.geography,
+            industry=sec.industry,
+            instr_kind_c=sec.instr_kind_c,
+            universe_source="demo",
+        )
+        await db.save_prices(sec.symbol, prices[sec.symbol])
+    await db.update_quotes_bulk({symbol: _quote(symbol, rows) for symbol, rows in prices.items()})
+
+    for trade in ledger.trades:
+        await db.upsert_trade(**trade)
+    for flow in ledger.cash_flows:
+        await db.upsert_cash_flow(**flow, raw_data={**flow, "demo": True})
+    for div in ledger.dividends:
+        await db.upsert_dividend(**div, data={"demo": True})
+    for symbol, pos in ledger.positions.items():
+        sec = next(s for s in DEMO_UNIVERSE if s.symbol == symbol)
+        await db.upsert_position(
+            symbol,
+            quantity=pos["quantity"],
+            avg_cost=round(pos["avg_cost"], 4),
+            current_price=prices[symbol][-1]["close"],
+            currency=sec.currency,
+            updated_at=datetime.now(timezone.utc).isoformat(),
+        )
+    await db.set_cash_balances({c: round(amount, 2) for c, amount in ledger.cash.items() if abs(amount) >= 0.01})
+    await db.set_setting("exchange_rates", DEMO_RATES)
+    await db.set_setting("trading_mode", "research")
+
+    counts = {
+        "securities": len(DEMO_UNIVERSE),
+        "prices": sum(len(rows) for rows in prices.values()),
+        "trades": len(ledger.trades),
+        "cash_flows": len(ledger.cash_flows),
+    }
+    logger.info(f"Demo data seeded (seed {seed}): {counts}")
+    return counts
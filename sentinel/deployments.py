@@ -0,0 +1,133 @@
+"""
+Deployment history recorded by `scripts/auto-deploy.sh`.
+
+Each deploy ends with a canary phase; the script appends its outcome to a
+JSON-lines file in the data directory and, after a rollback, remembers the
+failed commit so it is not deployed again until a newer commit lands.
+
+Before deploying, the script asks the running API for the deploy policy:
+the release channel to track (`stable` follows the newest `v*` release tag,
+`beta` follows main) and whether now is a deploy window. Deploys wait until
+every market the universe trades on is closed and, when `deploy_window` is
+set, until the local time falls inside it.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+from datetime import datetime
+from pathlib import Path
+from typing import Any
+
+from sentinel.led.modes import in_window, parse_hhmm
+from sentinel.paths import DATA_DIR
+
+logger = logging.getLogger(__name__)
+
+DEPLOY_HISTORY_FILE = DATA_DIR / "deploy-history.jsonl"
+FAILED_COMMIT_FILE = DATA_DIR / "deploy-failed-commit"
+
+DEPLOY_CHANNELS = ("stable", "beta")
+DEPLOY_CHANNEL_KEY = "deploy_channel"
+DEPLOY_WINDOW_KEY = "deploy_window"
+MARKETS_CLOSED_KEY = "deploy_when_markets_closed"
+
+
+def load_deploy_history(limit: int = 50, path: Path | None = None) -> list[dict[str, Any]]:
+    """Recorded deploys, newest first. Unreadable lines are skipped."""
+    path = path or DEPLOY_HISTORY_FILE
+    try:
+        lines = path.read_text().splitlines()
+    except FileNotFoundError:
+        return []
+    entries = []
+    for line in reversed(lines):
+        if len(entries) >= limit:
+            break
+        try:
+            entry = json.loads(line)
+        except json.JSONDecodeError:
+            logger.warning("Skipping malformed deploy history line: %r", line[:200])
+            continue
+        if isinstance(entry, dict):
+            entries.append(entry)
+    return entries
+
+
+def blocked_commit(path: Path | None = None) -> str | None:
+    """The commit that failed its canary and is held back, if any."""
+    path = path or FAILED_COMMIT_FILE
+    try:
+        commit = path.read_text().strip()
+    except FileNotFoundError:
+        return None
+    return commit or None
+
+
+def validate_deploy_channel(value: Any) -> str:
+    """Validate a release channel.
+
+    Raises:
+        ValueError: If the channel is unknown.
+    """
+    if value not in DEPLOY_CHANNELS:
+        raise ValueError(f"deploy_channel must be one of {list(DEPLOY_CHANNELS)}")
+    return value
+
+
+def validate_deploy_window(value: Any) -> dict[str, str] | None:
+    """Validate a deploy window; None allows deploys at any hour.
+
+    Raises:
+        ValueError: If the window is malformed.
+    """
+    if value is None:
+        return None
+    if not isinstance(value, dict):
+        raise ValueError("deploy_window must be an object with 'start' and 'end', or null")
+    start, end = value.get("start"), value.get("end")
+    if parse_hhmm(start) == parse_hhmm(end):
+        raise ValueError("deploy_window start and end must differ")
+    return {"start": start.strip(), "end": end.strip()}
+
+
+async def deploy_policy(settings, market_status: dict[str, Any], now: datetime | None = None) -> dict[str, Any]:
+    """Release channel and whether a deploy may land now.
+
+    Args:
+        settings: Settings to read `deploy_channel`, `deploy_window` and
+            `deploy_when_markets_closed` from.
+        market_status: Universe market status (see universe_market_status).
+        now: Local time for the window check (defaults to now).
+
+    Returns:
+        {"channel", "window", "when_markets_closed", "allowed", "reason"};
+        reason says why a deploy has to wait and is None when allowed.
+    """
+    now = now or datetime.now()
+    channel = await settings.get(DEPLOY_CHANNEL_KEY, "beta")
+    when_markets_closed = bool(await settings.get(MARKETS_CLOSED_KEY, True))
+    reasons = []
+    try:
+        validate_deploy_channel(channel)
+    except ValueError as e:
+        reasons.append(str(e))
+    try:
+        window = validate_deploy_window(await settings.get(DEPLOY_WINDOW_KEY))
+    except ValueError as e:
+        window = None
+        reasons.append(str(e))
+    if window and not in_window(window["start"], window["end"], now):
+        reasons.append(f"outside deploy window {window['start']}-{window['end']}")
+    if when_markets_closed:
+        open_markets = [m["name"] for m in market_status.get("markets", []) if m["is_open"]]
+        if open_markets:
+            reasons.append(f"markets open: {', '.join(open_markets)}")
+    return {
+        "channel": channel,
+        "window": window,
+        "when_markets_closed": when_markets_closed,
+        "allowed": not reasons,
+        "reason": "; ".join(reasons) or None,
+    }
@@ -0,0 +1,180 @@
+"""
+Configuration doctor - a self-test of what Sentinel needs to run.
+
+Each check returns {"name", "status", "detail", "hint"}: `status` is `pass`,
+`warn` or `fail`, and `hint` says what to do about anything but a pass.
+
+| Check | Fails / warns when |
+|---|---|
+| credentials | Only one Tradernet key is set (fail); none is set (warn) |
+| broker | The broker does not answer an authenticated call (fail) |
+| database | The database does not answer or has pending migrations (fail) |
+| market_hours | The broker has no market data, or a universe market has no session hours (warn) |
+| disk | Free space in the data directory is below DISK_FAIL_BYTES (fail) or DISK_WARN_BYTES (warn) |
+| clock | The last drift check found the clock suspect (warn) |
+| display | The LED display is enabled but its bridge is failing or silent (warn) |
+
+The report's `status` is its worst check. It runs once at startup, where
+anything but a pass is logged, and on demand from GET /api/system/doctor.
+
+Usage:
+    report = await Doctor(db, broker).run()
+"""
+
+from __future__ import annotations
+
+import logging
+import shutil
+import time
+from pathlib import Path
+from typing import Any
+
+from sentinel.clock import CLOCK_STATE_KEY
+from sentinel.health import HealthService
+from sentinel.jobs.market import EXCHANGE_HOURS, universe_market_status
+from sentinel.paths import DATA_DIR
+
+logger = logging.getLogger(__name__)
+
+DISK_WARN_BYTES = 1024**3
+DISK_FAIL_BYTES = 200 * 1024**2
+STATUS_ORDER = ("pass", "warn", "fail")
+
+
+def _result(name: str, status: str, detail: str | None = None, hint: str | None = None) -> dict[str, Any]:
+    return {"name": name, "status": status, "detail": detail, "hint": hint}
+
+
+class Doctor:
+    """Runs the startup self-test."""
+
+    def __init__(self, db, broker, data_dir: Path | None = None):
+        self._db = db
+        self._broker = broker
+        self._data_dir = data_dir or DATA_DIR
+
+    async def run(self, now_ts: int | None = None) -> dict[str, Any]:
+        """Run every check.
+
+        Returns:
+            {"status", "checked_at", "checks": [{"name", "status", "detail", "hint"}]}
+        """
+        checks = [await self._credentials()]
+        checks.append(await self._broker_check(checks[0]["status"] == "pass"))
+        checks.append(await self._database())
+        checks.append(await self._market_hours(checks[1]["status"] == "pass"))
+        checks.append(self._disk())
+        checks.append(await self._clock())
+        checks.append(await self._display())
+        status = max((c["status"] for c in checks), key=STATUS_ORDER.index)
+        return {"status": status, "checked_at": now_ts or int(time.time()), "checks": checks}
+
+    async def _credentials(self) -> dict[str, Any]:
+        key = await self._db.get_setting("tradernet_api_key")
+        secret = await self._db.get_setting("tradernet_api_secret")
+        if key and secret:
+            return _result("credentials", "pass")
+        if key or secret:
+            missing = "tradernet_api_secret" if key else "tradernet_api_key"
+            return _result("credentials", "fail", f"{missing} is not set", f"Set {missing} in Settings")
+        return _result(
+            "credentials",
+            "warn",
+            "No Tradernet credentials; running without a broker",
+            "Set tradernet_api_key and tradernet_api_secret in Settings to sync and trade",
+        )
+
+    async def _broker_check(self, credentials: bool) -> dict[str, Any]:
+        if not credentials:
+            return _result("broker", "warn", "Skipped: no credentials")
+        if not self._broker.connected:
+            await self._broker.connect()
+        if self._broker.connected and await self._broker.get_market_status("*"):
+            return _result("broker", "pass")
+        return _result(
+            "broker",
+            "fail",
+            "Tradernet did not answer",
+            "Check the API keys are valid and the device can reach tradernet.com",
+        )
+
+    async def _database(self) -> dict[str, Any]:
+        checks = (await HealthService(self._db, self._broker).readiness(scheduler_running=True))["checks"]
+        for name in ("database", "migrations"):
+            if not checks[name]["ok"]:
+                hint = "Run python main.py --migrate" if name == "migrations" else "Check the data directory"
+                return _result("database", "fail", checks[name]["detail"], hint)
+        return _result("database", "pass")
+
+    async def _market_hours(self, broker_ok: bool) -> dict[str, Any]:
+        if not broker_ok:
+            return _result("market_hours", "warn", "Skipped: broker unavailable")
+        status = await universe_market_status(self._db, self._broker)
+        if not status["markets"]:
+            return _result(
+                "market_hours",
+                "warn",
+                "No market data for the universe",
+                "Run sync:metadata so securities know their market",
+            )
+        unknown = sorted(m["name"] for m in status["markets"] if m["name"] not in EXCHANGE_HOURS)
+        if unknown:
+            return _result(
+                "market_hours",
+                "warn",
+                f"No session hours for {', '.join(unknown)}",
+                "Add them to EXCHANGE_HOURS in sentinel/jobs/market.py; the schedule audit cannot project them",
+            )
+        return _result("market_hours", "pass")
+
+    def _disk(self) -> dict[str, Any]:
+        try:
+            free = shutil.disk_usage(self._data_dir).free
+        except OSError as e:
+            return _result("disk", "fail", str(e), f"Check that {self._data_dir} exists")
+        detail = f"{free / 1024**3:.1f} GB free"
+        hint = "Free up space, or shorten retention policies (GET /api/system/retention)"
+        if free < DISK_FAIL_BYTES:
+            return _result("disk", "fail", detail, hint)
+        if free < DISK_WARN_BYTES:
+            return _result("disk", "warn", detail, hint)
+        return _result("disk", "pass", detail)
+
+    async def _clock(self) -> dict[str, Any]:
+        clock = await self._db.get_planner_state(CLOCK_STATE_KEY)
+        if not isinstance(clock, dict):
+            return _result("clock", "pass", "Not checked yet")
+        if clock.get("suspect"):
+            return _result(
+                "clock",
+                "warn",
+                f"Clock off by {clock.get('offset_seconds') or 0:+.0f}s",
+                "Enable NTP sync on the device (timedatectl set-ntp true)",
+            )
+        return _result("clock", "pass")
+
+    async def _display(self) -> dict[str, Any]:
+        if not await self._db.get_setting("led_display_enabled", False):
+            return _result("display", "pass", "Disabled")
+        from sentinel.api.routers.settings import load_led_bridge_health
+
+        bridge = await load_led_bridge_health()
+        if bridge["bridge_ok"] and not bridge["is_stale"]:
+            return _result("display", "pass")
+        detail = bridge["last_error"] or ("No report from the bridge" if bridge["is_stale"] else "Bridge failing")
+        return _result("display", "warn", detail, "Check the Arduino bridge app is running on the board")
+
+
+async def startup_check(db, broker) -> dict[str, Any]:
+    """Run the doctor at startup and log anything but a pass."""
+    try:
+        report = await Doctor(db, broker).run()
+    except Exception as e:
+        logger.warning(f"Doctor failed: {e}")
+        return {}
+    for check in report["checks"]:
+        if check["status"] != "pass":
+            log = logger.error if check["status"] == "fail" else logger.warning
+            log(f"Doctor {check['name']}: {check['detail']} ({check['hint'] or 'no hint'})")
+    logger.info(f"Doctor: {report['status']}")
+    return report
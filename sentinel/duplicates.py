@@ -0,0 +1,276 @@
+"""
+Duplicates - securities stored twice under different symbols.
+
+Broker and API quirks can store one instrument under two symbols (a second
+exchange suffix, a renamed ticker). `find_duplicates` groups the candidates:
+
+  - `isin`: the same ISIN, after stripping spaces and dashes, in the same
+    currency. The same ISIN in another currency is a separate listing and
+    is not reported.
+  - `name`: names at least NAME_SIMILARITY alike once case, punctuation and
+    legal-form words ("Inc", "AG", "plc") are dropped, in the same currency
+    and kind, unless both carry ISINs that differ.
+
+Each group names a canonical record: the broker-mapped listing for its ISIN
+first, then active, held and in the Freedom24 universe. Merging is always
+requested explicitly. `merge_securities` folds a duplicate into the
+canonical record:
+
+  - aliases are joined; the more recently updated user multiplier (with its
+    source and analysis) wins; the canonical position target wins, the
+    duplicate's fills a gap; buys and sells stay allowed only if both
+    allowed them; the record stays active if either was
+  - prices, trades, dividends, orders and the other per-symbol history move
+    to the canonical symbol, keeping the canonical row where both have one
+  - positions are added up, group membership moves unless the canonical
+    record has one, exclusion lists and broker symbol mappings follow
+  - the duplicate record is deleted and its symbol kept in `security_merges`,
+    so broker positions, trades and Favorites still using it resolve to the
+    canonical record (see sentinel.identifiers)
+
+Usage:
+    groups = await find_duplicates(db)
+    result = await merge_securities(db, "SAP.EU", "SAP.GR")
+"""
+
+from __future__ import annotations
+
+import re
+import time
+from difflib import SequenceMatcher
+from typing import Any
+
+from sentinel.identifiers import IdentifierService, normalize_isin, security_isin
+from sentinel.universe import FREEDOM24_UNIVERSE_SOURCE
+
+NAME_SIMILARITY = 0.92
+# Legal-form and share-class words that don't tell two companies apart.
+_NAME_NOISE = {
+    "ab",
+    "adr",
+    "ag",
+    "asa",
+    "class",
+    "co",
+    "corp",
+    "corporation",
+    "group",
+    "holding",
+    "holdings",
+    "inc",
+    "incorporated",
+    "limited",
+    "ltd",
+    "nv",
+    "oyj",
+    "plc",
+    "sa",
+    "se",
+    "spa",
+    "the",
+}
+_ISIN_SEPARATORS = re.compile(r"[\s\-./]")
+_NAME_TOKENS = re.compile(r"[a-z0-9]+")
+
+
+def canonical_isin(value: Any) -> str | None:
+    """An ISIN with spaces, dashes, dots and slashes removed; None if not shaped like one."""
+    if not isinstance(value, str):
+        return None
+    return normalize_isin(_ISIN_SEPARATORS.sub("", value))
+
+
+def normalize_name(name: Any) -> str:
+    """Lowercased name words without punctuation and legal-form words."""
+    if not isinstance(name, str):
+        return ""
+    # Dots go first so that "S.p.A." reads as "spa".
+    words = [w for w in _NAME_TOKENS.findall(name.lower().replace(".", "")) if w not in _NAME_NOISE]
+    return " ".join(words)
+
+
+def name_similarity(a: Any, b: Any) -> float:
+    """Similarity (0..1) of two normalized names; 0 when either is empty."""
+    left, right = normalize_name(a), normalize_name(b)
+    if not left or not right:
+        return 0.0
+    return SequenceMatcher(None, left, right).ratio()
+
+
+def _flag(security: dict, column: str, default: int = 1) -> int:
+    value = security.get(column)
+    return default if value is None else int(bool(int(value)))
+
+
+def _currency(security: dict) -> str:
+    return str(security.get("currency") or "EUR").upper()
+
+
+def duplicate_pairs(securities: list[dict]) -> list[dict[str, Any]]:
+    """Every candidate pair, as {"symbols": (a, b), "match", "isin", "similarity"}."""
+    entries = [(s, canonical_isin(security_isin(s) or "")) for s in securities if s.get("symbol")]
+    pairs = []
+    for i, (a, isin_a) in enumerate(entries):
+        for b, isin_b in entries[i + 1 :]:
+            if _currency(a) != _currency(b):
+                continue
+            symbols = (a["symbol"], b["symbol"])
+            if isin_a and isin_a == isin_b:
+                pairs.append({"symbols": symbols, "match": "isin", "isin": isin_a, "similarity": 1.0})
+                continue
+            if isin_a and isin_b:
+                continue
+            if a.get("instr_kind_c") != b.get("instr_kind_c"):
+                continue
+            similarity = name_similarity(a.get("name"), b.get("name"))
+            if similarity >= NAME_SIMILARITY:
+                pairs.append(
+                    {
+                        "symbols": symbols,
+                        "match": "name",
+                        "isin": isin_a or isin_b,
+                        "similarity": round(similarity, 3),
+                    }
+                )
+    return pairs
+
+
+def _rank(security: dict, mapped: set[str], held: set[str]) -> tuple:
+    """Sort key putting the best canonical record first; shorter, then lower symbols win ties."""
+    symbol = security["symbol"]
+    preferred = (
+        symbol in mapped,
+        bool(_flag(security, "active")),
+        symbol in held,
+        security.get("universe_source") == FREEDOM24_UNIVERSE_SOURCE,
+    )
+    return (*(not p for p in preferred), len(symbol), symbol)
+
+
+def _strength(pair: dict) -> tuple[bool, float]:
+    return pair["match"] == "isin", pair["similarity"]
+
+
+def group_duplicates(
+    securities: list[dict], mapped: set[str] | None = None, held: set[str] | None = None
+) -> list[dict[str, Any]]:
+    """Connected candidate pairs as groups with a canonical record.
+
+    Args:
+        securities: Every stored security, active or not
+        mapped: Symbols a broker symbol mapping points at
+        held: Symbols with an open position
+
+    Returns:
+        [{"canonical", "isin", "duplicates": [{"symbol", "match", "similarity"}]}]
+        sorted by canonical symbol
+    """
+    by_symbol = {s["symbol"]: s for s in securities if s.get("symbol")}
+    parent = {symbol: symbol for symbol in by_symbol}
+
+    def root(symbol: str) -> str:
+        while parent[symbol] != symbol:
+            parent[symbol] = parent[parent[symbol]]
+            symbol = parent[symbol]
+        return symbol
+
+    pairs = duplicate_pairs(list(by_symbol.values()))
+    for pair in pairs:
+        a, b = pair["symbols"]
+        parent[root(a)] = root(b)
+
+    members: dict[str, list[str]] = {}
+    for symbol in by_symbol:
+        members.setdefault(root(symbol), []).append(symbol)
+
+    groups = []
+    for symbols in members.values():
+        if len(symbols) < 2:
+            continue
+        canonical = min(symbols, key=lambda s: _rank(by_symbol[s], mapped or set(), held or set()))
+        # Each duplicate reports its strongest match with any other member.
+        best: dict[str, dict] = {}
+        for pair in pairs:
+            if pair["symbols"][0] not in symbols:
+                continue
+            for symbol in pair["symbols"]:
+                if symbol not in best or _strength(pair) > _strength(best[symbol]):
+                    best[symbol] = pair
+        groups.append(
+            {
+                "canonical": canonical,
+                "isin": next((p["isin"] for p in best.values() if p["isin"]), None),
+                "duplicates": [
+                    {"symbol": s, "match": best[s]["match"], "similarity": best[s]["similarity"]}
+                    for s in sorted(symbols)
+                    if s != canonical
+                ],
+            }
+        )
+    return sorted(groups, key=lambda g: g["canonical"])
+
+
+async def find_duplicates(db) -> list[dict[str, Any]]:
+    """Duplicate groups over every stored security (see group_duplicates)."""
+    securities = await db.get_all_securities(active_only=False)
+    mapped = {m["broker_symbol"] for m in await db.get_broker_symbols()}
+    held = {p["symbol"] for p in await db.get_all_positions() + await db.get_short_positions()}
+    return group_duplicates(securities, mapped, held)
+
+
+def _aliases(*values: Any) -> str | None:
+    joined: list[str] = []
+    for value in values:
+        for alias in str(value or "").split(","):
+            alias = alias.strip()
+            if alias and alias.lower() not in {a.lower() for a in joined}:
+                joined.append(alias)
+    return ", ".join(joined) or None
+
+
+def merged_overrides(canonical: dict, duplicate: dict) -> dict[str, Any]:
+    """Security columns the canonical record takes on when `duplicate` is folded into it."""
+    updates: dict[str, Any] = {"aliases": _aliases(canonical.get("aliases"), duplicate.get("aliases"))}
+    if (duplicate.get("user_multiplier_updated_at") or "") > (canonical.get("user_multiplier_updated_at") or ""):
+        for column in (
+            "user_multiplier",
+            "user_multiplier_updated_at",
+            "user_multiplier_source",
+            "user_multiplier_analysis",
+        ):
+            updates[column] = duplicate.get(column)
+    if canonical.get("target_weight_pct") is None and duplicate.get("target_weight_pct") is not None:
+        for column in ("target_weight_pct", "target_weight_mode", "target_weight_source", "target_weight_updated_at"):
+            updates[column] = duplicate.get(column)
+    updates["allow_buy"] = min(_flag(canonical, "allow_buy"), _flag(duplicate, "allow_buy"))
+    updates["allow_sell"] = min(_flag(canonical, "allow_sell"), _flag(duplicate, "allow_sell"))
+    updates["active"] = max(_flag(canonical, "active"), _flag(duplicate, "active"))
+    return {column: value for column, value in updates.items() if canonical.get(column) != value}
+
+
+async def merge_securities(db, canonical: str, duplicate: str, dry_run: bool = False) -> dict[str, Any]:
+    """Fold `duplicate` into `canonical` (see the module docstring).
+
+    Returns:
+        {"canonical", "duplicate", "dry_run", "overrides": {column: value},
+        "rows": {table: rows moved or, in a dry run, rows to move}}
+
+    Raises:
+        ValueError: If the symbols are the same.
+        LookupError: If either security does not exist.
+    """
+    if canonical == duplicate:
+        raise ValueError("canonical and duplicate must be different securities")
+    kept = await db.get_security(canonical)
+    merged = await db.get_security(duplicate)
+    if kept is None or merged is None:
+        raise LookupError(f"Security not found: {duplicate if kept else canonical}")
+
+    overrides = merged_overrides(kept, merged)
+    if dry_run:
+        rows = await db.count_security_references(duplicate)
+    else:
+        rows = await db.merge_security(duplicate, canonical, overrides, merged_at=int(time.time()))
+        IdentifierService(db).invalidate()
+    return {"canonical": canonical, "duplicate": duplicate, "dry_run": dry_run, "overrides": overrides, "rows": rows}
+
@@ -0,0 +1,139 @@
+"""
+Earnings calendar - announced earnings dates and the pre-earnings freeze.
+
+Earnings dates are stored per security in the `earnings_dates` table with the
+source they came from; the broker metadata does not carry them, so they are
+recorded through the API (`source` "manual" unless the caller names a feed).
+
+With `earnings_freeze_days` > 0 the planner blocks buys and sells of a
+security from that many days before an earnings date up to the date itself,
+the same way it blocks a security with a price anomaly. Deficit-fix sells
+that restore a negative cash balance are not frozen.
+"""
+
+from __future__ import annotations
+
+import inspect
+from datetime import date, timedelta
+from typing import Any
+
+FREEZE_DAYS_KEY = "earnings_freeze_days"
+MAX_FREEZE_DAYS = 30
+DEFAULT_UPCOMING_DAYS = 30
+MAX_SOURCE_LENGTH = 50
+
+
+def validate_freeze_days(raw: Any) -> int:
+    """Validate an `earnings_freeze_days` value (0 disables the freeze).
+
+    Raises:
+        ValueError: If it is not a whole number of days in range.
+    """
+    if isinstance(raw, bool) or not isinstance(raw, int | float) or int(raw) != raw:
+        raise ValueError("earnings freeze days must be a whole number")
+    if not 0 <= raw <= MAX_FREEZE_DAYS:
+        raise ValueError(f"earnings freeze days must be between 0 and {MAX_FREEZE_DAYS}")
+    return int(raw)
+
+
+def validate_earnings_date(raw: Any) -> str:
+    """Validate an earnings date as YYYY-MM-DD.
+
+    Raises:
+        ValueError: If it is not an ISO date.
+    """
+    if not isinstance(raw, str):
+        raise ValueError("date must be a YYYY-MM-DD string")
+    try:
+        return date.fromisoformat(raw.strip()).isoformat()
+    except ValueError:
+        raise ValueError("date must be a YYYY-MM-DD string") from None
+
+
+def validate_source(raw: Any) -> str:
+    """Validate the name of the source an earnings date came from."""
+    if raw is None:
+        return "manual"
+    if not isinstance(raw, str) or not raw.strip() or len(raw.strip()) > MAX_SOURCE_LENGTH:
+        raise ValueError(f"source must be a non-empty string of at most {MAX_SOURCE_LENGTH} characters")
+    return raw.strip()
+
+
+def frozen_symbols(earnings_rows: list[dict], as_of: date, freeze_days: int) -> dict[str, str]:
+    """Securities inside their pre-earnings freeze window on `as_of`.
+
+    Returns:
+        symbol -> the earliest earnings date in the window (YYYY-MM-DD)
+    """
+    if freeze_days <= 0:
+        return {}
+    start = as_of.isoformat()
+    end = (as_of + timedelta(days=freeze_days)).isoformat()
+    frozen: dict[str, str] = {}
+    for row in earnings_rows:
+        if start <= row["date"] <= end and (row["symbol"] not in frozen or row["date"] < frozen[row["symbol"]]):
+            frozen[row["symbol"]] = row["date"]
+    return frozen
+
+
+async def get_earnings_freeze(db, as_of: date, freeze_days: int) -> dict[str, str]:
+    """Load the freeze map from the database; tolerates databases without an earnings calendar."""
+    if freeze_days <= 0:
+        return {}
+    getter = getattr(db, "get_earnings_dates", None)
+    if not callable(getter):
+        return {}
+    rows = getter(
+        start_date=as_of.isoformat(),
+        end_date=(as_of + timedelta(days=freeze_days)).isoformat(),
+    )
+    if inspect.isawaitable(rows):
+        rows = await rows
+    if not isinstance(rows, list):
+        return {}
+    return frozen_symbols(rows, as_of, freeze_days)
+
+
+async def upcoming_earnings(
+    db,
+    settings,
+    *,
+    as_of: date | None = None,
+    days: int = DEFAULT_UPCOMING_DAYS,
+) -> dict[str, Any]:
+    """Upcoming earnings dates of held positions, with their freeze status."""
+    as_of = as_of or date.today()
+    try:
+        freeze_days = validate_freeze_days(await settings.get(FREEZE_DAYS_KEY, 0))
+    except ValueError:
+        freeze_days = 0
+
+    positions = {p["symbol"]: p for p in await db.get_all_positions() if (p.get("quantity") or 0) > 0}
+    names = {s["symbol"]: s.get("name") for s in await db.get_all_securities(active_only=False)}
+    rows = await db.get_earnings_dates(
+        start_date=as_of.isoformat(),
+        end_date=(as_of + timedelta(days=days)).isoformat(),
+    )
+    frozen = frozen_symbols(rows, as_of, freeze_days)
+
+    earnings = []
+    for row in rows:
+        if row["symbol"] not in positions:
+            continue
+        earnings.append(
+            {
+                "symbol": row["symbol"],
+                "name": names.get(row["symbol"]),
+                "date": row["date"],
+                "days_until": (date.fromisoformat(row["date"]) - as_of).days,
+                "source": row["source"],
+                "quantity": positions[row["symbol"]]["quantity"],
+                "frozen": frozen.get(row["symbol"]) == row["date"],
+            }
+        )
+    return {
+        "as_of_date": as_of.isoformat(),
+        "days": days,
+        "freeze_days": freeze_days,
+        "earnings": earnings,
+    }
@@ -0,0 +1,160 @@
+"""
+Exclusion lists - user-managed lists of securities the planner must not buy.
+
+A list names securities by symbol, by ISIN or by industry (e.g. "No tobacco"
+with industries ["Tobacco"]). An active list that matches a security keeps it
+out of the ideal portfolio, blocks planner buys (existing holdings can still
+be sold) and makes `Security.buy` refuse the order, so manual buys and
+approved recommendations are stopped too.
+
+Lists live in the `exclusion_lists` table; the API reports which lists
+exclude a security and why.
+"""
+
+from __future__ import annotations
+
+import inspect
+from typing import Any
+
+from sentinel.identifiers import normalize_isin, security_isin
+
+MAX_NAME_LENGTH = 100
+MAX_REASON_LENGTH = 200
+MAX_ENTRIES = 500
+
+
+def _string_list(data: dict, key: str, normalize) -> list[str]:
+    raw = data.get(key)
+    if not isinstance(raw, list) or len(raw) > MAX_ENTRIES:
+        raise ValueError(f"{key} must be a list of at most {MAX_ENTRIES} strings")
+    values: list[str] = []
+    for item in raw:
+        if not isinstance(item, str) or not item.strip():
+            raise ValueError(f"{key} must be a list of non-empty strings")
+        value = normalize(item.strip())
+        if value not in values:
+            values.append(value)
+    return values
+
+
+def _isin(value: str) -> str:
+    isin = normalize_isin(value)
+    if isin is None:
+        raise ValueError(f"{value!r} is not an ISIN")
+    return isin
+
+
+def validate_exclusion_list(data: Any, partial: bool = False) -> dict[str, Any]:
+    """Validate an exclusion list payload.
+
+    Args:
+        data: Request body
+        partial: Allow missing fields (for updates)
+
+    Raises:
+        ValueError: If a field is missing or malformed.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("exclusion list must be an object")
+    fields: dict[str, Any] = {}
+
+    if "name" in data or not partial:
+        name = data.get("name")
+        if not isinstance(name, str) or not name.strip() or len(name.strip()) > MAX_NAME_LENGTH:
+            raise ValueError(f"name must be a non-empty string of at most {MAX_NAME_LENGTH} characters")
+        fields["name"] = name.strip()
+
+    if "reason" in data:
+        reason = data.get("reason")
+        if reason is not None and (not isinstance(reason, str) or len(reason.strip()) > MAX_REASON_LENGTH):
+            raise ValueError(f"reason must be a string of at most {MAX_REASON_LENGTH} characters")
+        fields["reason"] = (reason.strip() or None) if reason is not None else None
+
+    if "symbols" in data:
+        fields["symbols"] = _string_list(data, "symbols", str.upper)
+    if "isins" in data:
+        fields["isins"] = _string_list(data, "isins", _isin)
+    if "industries" in data:
+        fields["industries"] = _string_list(data, "industries", lambda value: value)
+
+    if "active" in data:
+        active = data.get("active")
+        if not isinstance(active, bool):
+            raise ValueError("active must be a boolean")
+        fields["active"] = int(active)
+
+    if not partial and not any(fields.get(key) for key in ("symbols", "isins", "industries")):
+        raise ValueError("exclusion list needs at least one symbol, ISIN or industry")
+    return fields
+
+
+def exclusion_matches(security: dict, exclusion_lists: list[dict]) -> list[dict[str, Any]]:
+    """Active lists that exclude `security`, with the rule that matched.
+
+    Returns:
+        [{"id", "name", "reason", "matched": "symbol" | "isin" | "industry"}]
+    """
+    symbol = str(security.get("symbol") or "").upper()
+    industry = str(security.get("industry") or "").strip().casefold()
+    isin: str | None = None
+    isin_loaded = False
+    matches = []
+    for exclusion in exclusion_lists:
+        if not exclusion.get("active", 1):
+            continue
+        matched = None
+        if symbol and symbol in exclusion.get("symbols", []):
+            matched = "symbol"
+        elif exclusion.get("isins"):
+            if not isin_loaded:
+                isin, isin_loaded = security_isin(security), True
+            if isin and isin in exclusion["isins"]:
+                matched = "isin"
+        if matched is None and industry:
+            if industry in {value.casefold() for value in exclusion.get("industries", [])}:
+                matched = "industry"
+        if matched:
+            matches.append(
+                {
+                    "id": exclusion.get("id"),
+                    "name": exclusion.get("name"),
+                    "reason": exclusion.get("reason"),
+                    "matched": matched,
+                }
+            )
+    return matches
+
+
+def describe_exclusion(matches: list[dict[str, Any]]) -> str:
+    """Human-readable reason used in recommendations, errors and the UI."""
+    parts = []
+    for match in matches:
+        text = f"{match['name']} ({match['matched']})"
+        if match.get("reason"):
+            text += f": {match['reason']}"
+        parts.append(text)
+    return "Excluded by " + "; ".join(parts)
+
+
+async def load_exclusion_lists(db) -> list[dict]:
+    """Active exclusion lists; tolerates databases without exclusion support."""
+    getter = getattr(db, "get_exclusion_lists", None)
+    if not callable(getter):
+        return []
+    lists = getter(active_only=True)
+    if inspect.isawaitable(lists):
+        lists = await lists
+    return lists if isinstance(lists, list) else []
+
+
+async def get_excluded_securities(db) -> dict[str, list[dict[str, Any]]]:
+    """Map every excluded security (active or not) to the lists that exclude it."""
+    exclusion_lists = await load_exclusion_lists(db)
+    if not exclusion_lists:
+        return {}
+    excluded = {}
+    for security in await db.get_all_securities(active_only=False):
+        matches = exclusion_matches(security, exclusion_lists)
+        if matches:
+            excluded[security["symbol"]] = matches
+    return excluded
@@ -0,0 +1,226 @@
+"""
+Execution policy - throttles when and how large planner orders go out.
+
+`execution_policy` holds the limits, all off by default:
+
+- max_orders_per_minute: orders placed on one venue (broker market id) in
+  the last 60 seconds before further orders to it wait
+- avoid_open_minutes / avoid_close_minutes: no orders within the first/last
+  N minutes of the venue's session
+- twap_slices / twap_min_value_eur: a recommendation worth at least
+  twap_min_value_eur is spread over twap_slices child orders, evenly spaced
+  over what is left of the session (simple TWAP). Each child is a share of
+  the recommendation as the planner sees it now, so fills of earlier children
+  shrink the later ones; the last child takes the whole remainder.
+
+Session times come from the broker's market status (`o`/`c`, with `t` as
+the current time, all on the exchange's MSK clock). A venue with unknown
+session times is only rate limited.
+
+submit_trade asks `ExecutionThrottle.check()` before placing a planner order
+and calls `record()` after the broker accepted it. Orders placed by hand
+(buy/sell endpoints, approved recommendations) are not throttled.
+
+Usage:
+    throttle = ExecutionThrottle(db, broker, await load_execution_policy(settings.get))
+    blocked, quantity = await throttle.check(rec)
+"""
+
+from __future__ import annotations
+
+import logging
+import math
+import time
+from dataclasses import asdict, dataclass
+from datetime import datetime, timezone
+from typing import Any
+
+from sentinel.markets import security_market_id
+from sentinel.planner.liquidity import SettingGetter
+
+logger = logging.getLogger(__name__)
+
+EXECUTION_POLICY_KEY = "execution_policy"
+TWAP_STATE_PREFIX = "execution:twap:"
+DEFAULT_TWAP_INTERVAL_MINUTES = 60
+MINUTES_PER_DAY = 24 * 60
+
+
+@dataclass(frozen=True)
+class ExecutionPolicy:
+    max_orders_per_minute: int = 0
+    avoid_open_minutes: int = 0
+    avoid_close_minutes: int = 0
+    twap_slices: int = 1
+    twap_min_value_eur: float = 0.0
+
+    @property
+    def active(self) -> bool:
+        limits = (self.max_orders_per_minute, self.avoid_open_minutes, self.avoid_close_minutes)
+        return any(limits) or self.twap_slices > 1
+
+
+DEFAULT_POLICY = asdict(ExecutionPolicy())
+
+
+def validate_execution_policy(raw: Any) -> dict[str, int | float]:
+    """Validate an `execution_policy` value; omitted fields keep their defaults.
+
+    Raises:
+        ValueError: On unknown fields, or values that are not non-negative
+            whole numbers (twap_min_value_eur: non-negative number, twap_slices: at least 1).
+    """
+    if not isinstance(raw, dict):
+        raise ValueError(f"{EXECUTION_POLICY_KEY} must be an object")
+    unknown = set(raw) - set(DEFAULT_POLICY)
+    if unknown:
+        raise ValueError(f"{EXECUTION_POLICY_KEY} has unknown fields: {', '.join(sorted(unknown))}")
+    policy = dict(DEFAULT_POLICY)
+    for key, value in raw.items():
+        if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value) or value < 0:
+            raise ValueError(f"{EXECUTION_POLICY_KEY}.{key} must be a non-negative number")
+        if key == "twap_min_value_eur":
+            policy[key] = float(value)
+            continue
+        if value != int(value):
+            raise ValueError(f"{EXECUTION_POLICY_KEY}.{key} must be a whole number")
+        policy[key] = int(value)
+    if policy["twap_slices"] < 1:
+        raise ValueError(f"{EXECUTION_POLICY_KEY}.twap_slices must be at least 1")
+    if policy["avoid_open_minutes"] >= MINUTES_PER_DAY or policy["avoid_close_minutes"] >= MINUTES_PER_DAY:
+        raise ValueError(f"{EXECUTION_POLICY_KEY} session margins must be shorter than a day")
+    return policy
+
+
+async def load_execution_policy(get: SettingGetter) -> ExecutionPolicy:
+    value = await get(EXECUTION_POLICY_KEY, DEFAULT_POLICY)
+    try:
+        return ExecutionPolicy(**validate_execution_policy(value or {}))
+    except ValueError as e:
+        logger.warning(f"Ignoring invalid {EXECUTION_POLICY_KEY}: {e}")
+        return ExecutionPolicy()
+
+
+def _minutes(clock: Any) -> int | None:
+    """Minutes after midnight of an "HH:MM[:SS]" time (or the time part of "YYYY-MM-DD HH:MM:SS")."""
+    if not isinstance(clock, str):
+        return None
+    try:
+        hours, minutes = clock.strip().split(" ")[-1].split(":")[:2]
+        return int(hours) * 60 + int(minutes)
+    except ValueError:
+        return None
+
+
+def session_position(market_status: dict | None, market_id: str | None) -> tuple[int, int] | None:
+    """(minutes since the session opened, minutes until it closes) for a venue; None if unknown."""
+    if not isinstance(market_status, dict) or market_id is None:
+        return None
+    now = _minutes(market_status.get("t"))
+    for market in market_status.get("m") or []:
+        if str(market.get("i")) != str(market_id):
+            continue
+        opens, closes = _minutes(market.get("o")), _minutes(market.get("c"))
+        if now is None or opens is None or closes is None:
+            return None
+        # Modular arithmetic also covers sessions that run past midnight.
+        return (now - opens) % MINUTES_PER_DAY, (closes - now) % MINUTES_PER_DAY
+    return None
+
+
+def child_quantity(quantity: int, slices_left: int, min_lot: int = 1) -> int:
+    """One TWAP child of `quantity` with `slices_left` children to go, in whole lots."""
+    if slices_left <= 1:
+        return quantity
+    lot = max(1, min_lot)
+    child = math.ceil(quantity / slices_left / lot) * lot
+    return min(quantity, child)
+
+
+class ExecutionThrottle:
+    """Applies an ExecutionPolicy to one planner order at a time."""
+
+    def __init__(self, db, broker, policy: ExecutionPolicy):
+        self._db = db
+        self._broker = broker
+        self._policy = policy
+        self._market_status: dict | None = None
+
+    async def _venue(self, symbol: str) -> str | None:
+        security = await self._db.get_security(symbol)
+        return security_market_id(security) if security else None
+
+    async def _session(self, venue: str | None) -> tuple[int, int] | None:
+        if self._market_status is None:
+            self._market_status = await self._broker.get_market_status("*") or {}
+        return session_position(self._market_status, venue)
+
+    async def _recent_orders(self, venue: str, now: int) -> int:
+        orders = await self._db.get_orders(since=now - 60, limit=1000)
+        venues = {order["symbol"]: None for order in orders}
+        for symbol in venues:
+            venues[symbol] = await self._venue(symbol)
+        return sum(1 for order in orders if venues[order["symbol"]] == venue)
+
+    def _twap_key(self, rec) -> str:
+        return f"{TWAP_STATE_PREFIX}{rec.symbol}:{rec.action}"
+
+    def _is_twap(self, rec) -> bool:
+        value = abs(rec.value_delta_eur or rec.quantity * rec.price)
+        return self._policy.twap_slices > 1 and value >= self._policy.twap_min_value_eur
+
+    async def _twap_state(self, rec, now: int) -> dict:
+        state = await self._db.get_planner_state(self._twap_key(rec))
+        today = datetime.fromtimestamp(now, tz=timezone.utc).date().isoformat()
+        if not isinstance(state, dict) or state.get("day") != today:
+            return {"day": today, "slices_done": 0, "next_at": 0}
+        return state
+
+    async def check(self, rec, now: int | None = None) -> tuple[str | None, int]:
+        """(reason the order must wait, or None; quantity to place now)."""
+        now = int(time.time()) if now is None else now
+        policy = self._policy
+        if not policy.active:
+            return None, rec.quantity
+        venue = await self._venue(rec.symbol)
+        if venue and policy.max_orders_per_minute:
+            placed = await self._recent_orders(venue, now)
+            if placed >= policy.max_orders_per_minute:
+                return f"{placed} orders on venue {venue} in the last minute", rec.quantity
+
+        session = None
+        if policy.avoid_open_minutes or policy.avoid_close_minutes or policy.twap_slices > 1:
+            session = await self._session(venue)
+        if session is not None:
+            since_open, to_close = session
+            if since_open < policy.avoid_open_minutes:
+                return f"within the first {policy.avoid_open_minutes} minutes of the session", rec.quantity
+            if to_close < policy.avoid_close_minutes:
+                return f"within the last {policy.avoid_close_minutes} minutes of the session", rec.quantity
+
+        if not self._is_twap(rec):
+            return None, rec.quantity
+        state = await self._twap_state(rec, now)
+        if now < state["next_at"]:
+            return f"next TWAP slice of {rec.symbol} due at {state['next_at']}", rec.quantity
+        slices_left = max(1, policy.twap_slices - state["slices_done"])
+        return None, child_quantity(rec.quantity, slices_left, rec.lot_size or 1)
+
+    async def record(self, rec, now: int | None = None) -> None:
+        """Advance the TWAP schedule after a child order of `rec` was accepted."""
+        now = int(time.time()) if now is None else now
+        if not self._policy.active or not self._is_twap(rec):
+            return
+        state = await self._twap_state(rec, now)
+        state["slices_done"] += 1
+        slices_left = self._policy.twap_slices - state["slices_done"]
+        if slices_left <= 0:
+            await self._db.set_planner_state(self._twap_key(rec), {**state, "next_at": 0, "slices_done": 0})
+            return
+        session = await self._session(await self._venue(rec.symbol))
+        if session is None:
+            interval = DEFAULT_TWAP_INTERVAL_MINUTES
+        else:
+            interval = max(1, (session[1] - self._policy.avoid_close_minutes) // slices_left)
+        state["next_at"] = now + interval * 60
+        await self._db.set_planner_state(self._twap_key(rec), state)
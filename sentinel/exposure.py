@@ -0,0 +1,102 @@
+"""
+Account exposure - short positions and margin, which Sentinel never plans around.
+
+Sentinel only buys with cash and sells what it holds, so a negative position
+(e.g. left behind by a corporate action) or a negative net cash balance
+(borrowed on margin) means the account is in a state the planner's model does
+not describe. Both are tracked explicitly instead of being dropped:
+
+- positions keep their negative quantity and count at their (negative) value
+  in the portfolio valuation
+- the planner refuses to produce recommendations while either exists
+- the first check that finds one logs an error and raises a critical alert;
+  the health report stays degraded until the account is flat again
+
+A negative balance in one currency that other currencies cover is not
+margin; `trading:balance_fix` converts those.
+
+Usage:
+    exposure = account_exposure(state.short_positions(), state.margin_eur())
+    if not exposure["ok"]:
+        raise AccountExposureError(describe_exposure(exposure))
+"""
+
+from __future__ import annotations
+
+import logging
+import time
+from typing import Any
+
+logger = logging.getLogger(__name__)
+
+EXPOSURE_STATE_KEY = "account:exposure"
+
+
+class AccountExposureError(RuntimeError):
+    """The account holds shorts or is on margin, so planning is refused."""
+
+
+def _quantity(position: dict) -> float:
+    try:
+        return float(position.get("quantity") or 0)
+    except (TypeError, ValueError):
+        return 0.0
+
+
+def account_exposure(shorts: list[dict], margin_eur: float) -> dict[str, Any]:
+    """Summarize shorts and borrowed cash; `ok` is False when either exists."""
+    margin_eur = max(0.0, float(margin_eur or 0.0))
+    return {
+        "ok": not shorts and margin_eur <= 0,
+        "shorts": [
+            {
+                "symbol": position.get("symbol"),
+                "quantity": _quantity(position),
+                "value_eur": position.get("value_eur"),
+            }
+            for position in shorts
+        ],
+        "margin_eur": round(margin_eur, 2),
+    }
+
+
+def describe_exposure(exposure: dict[str, Any]) -> str:
+    parts = [f"short {-short['quantity']:g} {short['symbol']}" for short in exposure["shorts"]]
+    if exposure["margin_eur"] > 0:
+        parts.append(f"{exposure['margin_eur']:.2f} EUR borrowed on margin")
+    if not parts:
+        return "account holds no shorts and no margin"
+    return "Refusing to plan: account is " + ", ".join(parts)
+
+
+async def record_exposure(db, settings, exposure: dict[str, Any], now: int | None = None) -> None:
+    """Store the latest check; alert once when the account becomes exposed."""
+    previous = await db.get_planner_state(EXPOSURE_STATE_KEY)
+    was_ok = not isinstance(previous, dict) or previous.get("ok", True)
+    await db.set_planner_state(EXPOSURE_STATE_KEY, {**exposure, "checked_at": now or int(time.time())})
+    if exposure["ok"]:
+        if not was_ok:
+            logger.info("Account exposure cleared: no shorts and no margin")
+        return
+    logger.error(describe_exposure(exposure))
+    if was_ok:
+        try:
+            from sentinel.led.alerts import ALERT_CRITICAL, AlertManager
+
+            await AlertManager(settings).trigger(ALERT_CRITICAL)
+        except Exception as e:
+            logger.warning(f"Failed to raise account exposure alert: {e}")
+
+
+async def check_account_exposure(db, currency, settings) -> dict[str, Any]:
+    """Check the synced account (positions and cash in the database) and record the result."""
+    shorts = await db.get_short_positions()
+    for position in shorts:
+        price = float(position.get("current_price") or 0.0)
+        position["value_eur"] = await currency.to_eur(_quantity(position) * price, position.get("currency") or "EUR")
+    cash_eur = 0.0
+    for code, amount in (await db.get_cash_balances()).items():
+        cash_eur += await currency.to_eur(float(amount or 0.0), code)
+    exposure = account_exposure(shorts, -cash_eur)
+    await record_exposure(db, settings, exposure)
+    return exposure
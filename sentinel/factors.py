@@ -0,0 +1,236 @@
+"""
+Factor model - security returns explained by the tracked market indices.
+
+The factors are daily returns of the benchmark indices kept by
+`sync:benchmarks`, grouped into the baskets of sentinel.portfolio_composition:
+
+  - `market`: the mean of every tracked equity index (the ALL basket)
+  - `size`: small caps over large caps, the Russell 2000 less the S&P 500
+  - `region:<basket>`: a home-market basket (US, EUROPE, UK, DE, ...) less
+    the market
+
+The daily `security:factors` job regresses each active security's daily
+returns over the last FACTOR_LOOKBACK_DAYS price rows on `market`, `size`
+and the region factor of its own basket (resolved from its geography, as for
+the home-market benchmark) by ordinary least squares. The betas, the
+annualized alpha and residual volatility (the risk the factors leave
+unexplained) and r² are stored per security in `factor_exposures`. A
+security with fewer than MIN_FACTOR_SAMPLES days shared with the market
+factor gets no exposures. A day an index did not quote counts as no move.
+
+FactorModel turns stored exposures into a covariance matrix, B Σ_F B' + D,
+with Σ_F the covariance of the factors and D the residual variances. It is
+the `factor` option of `covariance_estimator` (see sentinel.covariance).
+
+Usage:
+    factors = await load_factor_returns(db)
+    await update_security_factors(db, security, factors)
+    model = await load_factor_model(db)
+"""
+
+from __future__ import annotations
+
+import inspect
+import math
+import time
+from dataclasses import dataclass
+from typing import Any
+
+import numpy as np
+
+from sentinel.portfolio_composition import (
+    BENCHMARK_GROUPS,
+    TRADING_DAYS_PER_YEAR,
+    _returns_by_date_from_prices,
+    all_equity_index_symbols,
+    basket_daily_returns,
+    resolve_benchmark_group,
+)
+
+FACTOR_LOOKBACK_DAYS = 756
+MIN_FACTOR_SAMPLES = 120
+MARKET_FACTOR = "market"
+SIZE_FACTOR = "size"
+SMALL_CAP_INDEX = "RUT.IDX"
+LARGE_CAP_INDEX = "SP500.IDX"
+
+
+def region_factor(basket: str) -> str | None:
+    """Name of a basket's region factor; None for the ALL basket, which is the market."""
+    return None if basket == "ALL" else f"region:{basket}"
+
+
+def factor_returns(index_prices: dict[str, list[dict]]) -> dict[str, dict[str, float]]:
+    """Daily return of every factor by date, from benchmark price rows per index symbol."""
+    market = basket_daily_returns(all_equity_index_symbols(), index_prices)
+    if not market:
+        return {}
+    factors = {MARKET_FACTOR: market}
+    small = basket_daily_returns([SMALL_CAP_INDEX], index_prices)
+    large = basket_daily_returns([LARGE_CAP_INDEX], index_prices)
+    size = {d: small[d] - large[d] for d in sorted(small.keys() & large.keys())}
+    if size:
+        factors[SIZE_FACTOR] = size
+    for basket, members in BENCHMARK_GROUPS.items():
+        home = basket_daily_returns(members, index_prices)
+        spread = {d: r - market[d] for d, r in home.items() if d in market}
+        if spread:
+            factors[f"region:{basket}"] = spread
+    return factors
+
+
+async def load_factor_returns(db, days: int = FACTOR_LOOKBACK_DAYS) -> dict[str, dict[str, float]]:
+    """Factor returns over about the last `days` trading days."""
+    # Benchmark prices are capped by calendar days, about 7 for every 5 trading days.
+    calendar_days = days * 7 // 5 + 14
+    prices = {s: await db.get_benchmark_prices(s, days=calendar_days) for s in all_equity_index_symbols()}
+    return factor_returns(prices)
+
+
+def regress_security(
+    returns: dict[str, float],
+    factors: dict[str, dict[str, float]],
+    basket: str,
+    lookback: int = FACTOR_LOOKBACK_DAYS,
+) -> dict[str, Any] | None:
+    """OLS of a security's daily returns on the market, size and its region factor.
+
+    Args:
+        returns: Daily returns by date
+        factors: Factor returns by date, from factor_returns()
+        basket: The security's home-market basket
+        lookback: Most recent days to fit
+
+    Returns:
+        {"region", "betas": {factor: beta}, "alpha", "residual_vol",
+        "r_squared", "samples"} with alpha and residual_vol annualized, or
+        None with fewer than MIN_FACTOR_SAMPLES days
+    """
+    market = factors.get(MARKET_FACTOR) or {}
+    dates = sorted(d for d in returns if d in market)[-lookback:]
+    if len(dates) < MIN_FACTOR_SAMPLES:
+        return None
+    names = [
+        name
+        for name in (MARKET_FACTOR, SIZE_FACTOR, region_factor(basket))
+        if name in factors and any(d in factors[name] for d in dates)
+    ]
+    y = np.array([returns[d] for d in dates], dtype=float)
+    design = np.array([[1.0] + [factors[n].get(d, 0.0) for n in names] for d in dates], dtype=float)
+    coef, *_ = np.linalg.lstsq(design, y, rcond=None)
+    residuals = y - design @ coef
+    sse = float(residuals @ residuals)
+    total = float(((y - y.mean()) ** 2).sum())
+    residual_var = sse / max(1, len(dates) - design.shape[1])
+    return {
+        "region": basket,
+        "betas": {n: round(float(b), 4) for n, b in zip(names, coef[1:], strict=True)},
+        "alpha": round(float(coef[0]) * TRADING_DAYS_PER_YEAR, 6),
+        "residual_vol": round(math.sqrt(residual_var * TRADING_DAYS_PER_YEAR), 6),
+        "r_squared": round(1 - sse / total, 4) if total > 0 else 0.0,
+        "samples": len(dates),
+    }
+
+
+async def update_security_factors(
+    db, security: dict, factors: dict[str, dict[str, float]], now: int | None = None
+) -> dict[str, Any] | None:
+    """Fit and store one security's exposures; returns them, or None with too little history."""
+    symbol = security["symbol"]
+    rows = await db.get_prices(symbol, days=FACTOR_LOOKBACK_DAYS + 1)
+    basket = resolve_benchmark_group((security.get("geography") or "").strip())
+    exposure = regress_security(_returns_by_date_from_prices(rows), factors, basket)
+    if exposure is None:
+        return None
+    await db.upsert_factor_exposure(symbol, updated_at=int(time.time()) if now is None else now, **exposure)
+    return exposure
+
+
+@dataclass
+class FactorModel:
+    """Stored exposures and the daily factor covariance they apply to."""
+
+    names: list[str]
+    factor_covariance: np.ndarray
+    exposures: dict[str, dict[str, Any]]
+
+    def covariance(self, symbols: list[str]) -> np.ndarray | None:
+        """Daily covariance B Σ_F B' + D of `symbols`; None when one of them has no exposures."""
+        if not symbols or any(s not in self.exposures for s in symbols):
+            return None
+        betas = np.array([[self.exposures[s]["betas"].get(n, 0.0) for n in self.names] for s in symbols])
+        residual = np.array([(self.exposures[s]["residual_vol"] or 0.0) ** 2 for s in symbols])
+        return betas @ self.factor_covariance @ betas.T + np.diag(residual / TRADING_DAYS_PER_YEAR)
+
+
+def factor_covariance(factors: dict[str, dict[str, float]]) -> tuple[list[str], np.ndarray]:
+    """Names and daily sample covariance of the factors, over the market factor's days."""
+    names = list(factors)
+    dates = sorted(factors.get(MARKET_FACTOR) or {})
+    if len(dates) < 2:
+        return names, np.zeros((len(names), len(names)))
+    matrix = np.array([[factors[n].get(d, 0.0) for n in names] for d in dates], dtype=float)
+    return names, np.atleast_2d(np.cov(matrix, rowvar=False))
+
+
+async def load_factor_model(db) -> FactorModel | None:
+    """The factor model from stored exposures; None before the job has run or without benchmark prices."""
+    getter = getattr(db, "get_factor_exposures", None)
+    if not callable(getter):
+        return None
+    exposures = getter()
+    if inspect.isawaitable(exposures):
+        exposures = await exposures
+    if not isinstance(exposures, dict) or not exposures:
+        return None
+    factors = await load_factor_returns(db)
+    if not factors:
+        return None
+    names, cov = factor_covariance(factors)
+    return FactorModel(names=names, factor_covariance=cov, exposures=exposures)
+
+
+async def build_factor_report(db, currency) -> dict[str, Any]:
+    """The live portfolio's factor exposures, for risk reports.
+
+    Betas are value-weighted over the whole portfolio, cash counting as 0;
+    `systematic_share_pct` is the part of the positions' variance the factors
+    explain.
+
+    Returns:
+        {"betas": {factor: beta}, "coverage_pct", "annual_volatility_pct",
+        "systematic_share_pct", "positions": [{"symbol", "weight_pct", ...exposure}]}
+    """
+    from sentinel.projections import _live_weights
+
+    _total_value, weights = await _live_weights(db, currency)
+    model = await load_factor_model(db)
+    exposures = model.exposures if model else {}
+    covered = sorted(s for s in weights if s in exposures)
+    invested = sum(weights.values())
+    betas: dict[str, float] = {}
+    for symbol in covered:
+        for name, beta in exposures[symbol]["betas"].items():
+            betas[name] = betas.get(name, 0.0) + weights[symbol] * beta
+    volatility = systematic = None
+    if model and covered:
+        w = np.array([weights[s] for s in covered])
+        total = float(w @ model.covariance(covered) @ w)
+        residual = sum(weights[s] ** 2 * (exposures[s]["residual_vol"] or 0.0) ** 2 for s in covered)
+        residual /= TRADING_DAYS_PER_YEAR
+        volatility = round(math.sqrt(max(0.0, total) * TRADING_DAYS_PER_YEAR) * 100, 2)
+        systematic = round((1 - residual / total) * 100, 2) if total > 0 else None
+    return {
+        "betas": {name: round(beta, 4) for name, beta in betas.items()},
+        "coverage_pct": round(sum(weights[s] for s in covered) / invested * 100, 2) if invested > 0 else 0.0,
+        "annual_volatility_pct": volatility,
+        "systematic_share_pct": systematic,
+        "positions": [
+            {
+                "symbol": symbol,
+                "weight_pct": round(weights[symbol] * 100, 2),
+                **{k: v for k, v in exposures[symbol].items() if k != "symbol"},
+            }
+            for symbol in sorted(covered, key=lambda s: -weights[s])
+        ],
+    }
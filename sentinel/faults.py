@@ -0,0 +1,145 @@
+"""
+Fault injection - deliberately failing broker calls, database queries and
+jobs, to exercise the failure handling (job backoff, fail-safe broker
+fallbacks, failure alerts) on a development device.
+
+`fault_injection` holds a rate per fault, each the chance (0-1) that one
+call fails. All zero and off by default:
+
+| Fault | Where | Raises |
+|---|---|---|
+| broker_timeout | every Tradernet SDK call made by Broker | TimeoutError |
+| sqlite_busy | every query and commit on a Database connection | sqlite3.OperationalError ("database is locked") |
+| job_failure | the start of every scheduled or manual job | FaultInjected |
+
+Faults are only injected while `enabled` is on and `trading_mode` is
+`research`; in live mode nothing is injected whatever the setting says.
+`seed` makes a sequence of faults reproducible. The configuration is
+reloaded at the start of every job; injections are counted per fault and
+reported by GET /api/system/faults.
+"""
+
+from __future__ import annotations
+
+import logging
+import math
+import random
+import sqlite3
+from typing import Any, Awaitable, Callable
+
+logger = logging.getLogger(__name__)
+
+FAULT_INJECTION_KEY = "fault_injection"
+FAULTS = ("broker_timeout", "sqlite_busy", "job_failure")
+DEFAULT_CONFIG: dict[str, Any] = {"enabled": False, "seed": None, **{fault: 0.0 for fault in FAULTS}}
+
+SettingGetter = Callable[..., Awaitable[Any]]
+
+
+class FaultInjected(Exception):
+    """A failure raised on purpose by the fault injector."""
+
+
+def validate_fault_injection(raw: Any) -> dict[str, Any]:
+    """Validate a `fault_injection` value; omitted fields keep their defaults.
+
+    Raises:
+        ValueError: On unknown fields, rates outside 0-1, a non-boolean
+            `enabled` or a `seed` that is not a whole number.
+    """
+    if not isinstance(raw, dict):
+        raise ValueError(f"{FAULT_INJECTION_KEY} must be an object")
+    unknown = set(raw) - set(DEFAULT_CONFIG)
+    if unknown:
+        raise ValueError(f"{FAULT_INJECTION_KEY} has unknown fields: {', '.join(sorted(unknown))}")
+    config = dict(DEFAULT_CONFIG)
+    for key, value in raw.items():
+        if key == "enabled":
+            if not isinstance(value, bool):
+                raise ValueError(f"{FAULT_INJECTION_KEY}.enabled must be true or false")
+            config[key] = value
+        elif key == "seed":
+            if value is not None and (isinstance(value, bool) or not isinstance(value, int)):
+                raise ValueError(f"{FAULT_INJECTION_KEY}.seed must be a whole number or null")
+            config[key] = value
+        else:
+            if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value):
+                raise ValueError(f"{FAULT_INJECTION_KEY}.{key} must be a number between 0 and 1")
+            if not 0 <= value <= 1:
+                raise ValueError(f"{FAULT_INJECTION_KEY}.{key} must be a number between 0 and 1")
+            config[key] = float(value)
+    return config
+
+
+class FaultInjector:
+    """Decides, call by call, whether to inject a fault."""
+
+    def __init__(self):
+        self._config = dict(DEFAULT_CONFIG)
+        self._active = False
+        self._rng = random.Random()  # noqa: S311
+        self.counts = {fault: 0 for fault in FAULTS}
+
+    @property
+    def active(self) -> bool:
+        return self._active
+
+    def configure(self, config: dict[str, Any], trading_mode: str) -> None:
+        """Apply a validated configuration; faults only fire in research mode."""
+        if config.get("seed") != self._config.get("seed") or not self._active:
+            self._rng.seed(config.get("seed"))
+        self._config = config
+        self._active = bool(config["enabled"]) and trading_mode == "research"
+
+    async def load(self, get: SettingGetter) -> None:
+        """Reload the configuration from settings."""
+        value = await get(FAULT_INJECTION_KEY, DEFAULT_CONFIG)
+        try:
+            config = validate_fault_injection(value or {})
+        except ValueError as e:
+            logger.warning(f"Ignoring invalid {FAULT_INJECTION_KEY}: {e}")
+            config = dict(DEFAULT_CONFIG)
+        was_active = self._active
+        self.configure(config, await get("trading_mode", "research"))
+        if self._active and not was_active:
+            rates = ", ".join(f"{fault}={config[fault]:g}" for fault in FAULTS)
+            logger.warning(f"Fault injection is ON ({rates})")
+
+    def maybe_fail(self, fault: str, where: str) -> None:
+        """Raise `fault` at its configured rate; a no-op while inactive."""
+        if not self._active:
+            return
+        rate = self._config.get(fault, 0.0)
+        if rate <= 0 or self._rng.random() >= rate:
+            return
+        self.counts[fault] += 1
+        logger.warning(f"Injecting {fault} into {where}")
+        if fault == "broker_timeout":
+            raise TimeoutError(f"Injected broker timeout in {where}")
+        if fault == "sqlite_busy":
+            raise sqlite3.OperationalError("database is locked (injected)")
+        raise FaultInjected(f"Injected failure in {where}")
+
+    def status(self) -> dict[str, Any]:
+        return {"active": self._active, "config": dict(self._config), "injected": dict(self.counts)}
+
+
+class FaultyClient:
+    """Proxy around a Tradernet SDK client that injects broker timeouts into its calls."""
+
+    def __init__(self, client: Any):
+        self._client = client
+
+    def __getattr__(self, name: str) -> Any:
+        attr = getattr(self._client, name)
+        if not callable(attr):
+            return attr
+
+        def call(*args, **kwargs):
+            injector.maybe_fail("broker_timeout", name)
+            return attr(*args, **kwargs)
+
+        return call
+
+
+injector = FaultInjector()
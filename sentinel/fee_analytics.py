@@ -0,0 +1,205 @@
+"""
+Fee analytics - what trading and the account cost, and what they will cost.
+
+Two kinds of fees are counted, in EUR at each fee's date:
+
+  - trade commissions: the `commission` on every trade
+  - account fees: cash flows of a commission type (custody, platform, FX and
+    other broker charges, including flows classified as `commission` by
+    sentinel.cash_flow_rules); block/unblock holds are not fees
+
+They are totalled per month and per year, as a share of the traded volume
+(trade commissions over the EUR value of the trades) and of the portfolio
+(all fees over the average snapshot value in the period). Each trade's
+commission is attributed to it and its symbol. The annual cost projection
+is the trailing twelve months of fees, scaled up when the history is
+shorter, against the latest snapshot value.
+"""
+
+from __future__ import annotations
+
+from datetime import date, datetime, timedelta, timezone
+from typing import Any
+
+from sentinel.portfolio_composition import daily_value_series
+
+TRADE_HISTORY_LIMIT = 100000
+DEFAULT_TRADES_LIMIT = 50
+# Less history than this is not projected to a year.
+MIN_PROJECTION_DAYS = 30
+
+
+def is_fee_flow(type_id: str) -> bool:
+    """Whether a cash flow type is a fee rather than a commission hold."""
+    return "commission" in type_id and not type_id.startswith(("block", "unblock"))
+
+
+def _trade_date(trade: dict) -> str:
+    return datetime.fromtimestamp(int(trade["executed_at"]), tz=timezone.utc).strftime("%Y-%m-%d")
+
+
+def _trade_currency(trade: dict, security_currencies: dict[str, str]) -> str:
+    raw = trade.get("raw_data") if isinstance(trade.get("raw_data"), dict) else {}
+    return security_currencies.get(trade["symbol"]) or raw.get("curr_c") or "EUR"
+
+
+def _pct(part: float, whole: float | None) -> float | None:
+    return round(part / whole * 100, 4) if whole else None
+
+
+def _bucket() -> dict[str, Any]:
+    return {"trade_commissions_eur": 0.0, "account_fees_eur": 0.0, "traded_volume_eur": 0.0, "trades": 0}
+
+
+def summarize(
+    trades: list[dict[str, Any]],
+    fees: list[dict[str, Any]],
+    values: list[tuple[str, float]],
+    today: date,
+    trades_limit: int = DEFAULT_TRADES_LIMIT,
+) -> dict[str, Any]:
+    """Aggregate EUR-converted trades and account fees.
+
+    Args:
+        trades: {"id", "symbol", "side", "date", "value_eur", "commission_eur"}, newest first
+        fees: {"date", "fee_eur"} per account fee cash flow
+        values: (date, total value in EUR) per snapshot, oldest first
+    """
+    months: dict[str, dict[str, Any]] = {}
+    years: dict[str, dict[str, Any]] = {}
+    symbols: dict[str, dict[str, Any]] = {}
+    totals = _bucket()
+    for trade in trades:
+        for bucket in (months.setdefault(trade["date"][:7], _bucket()), years.setdefault(trade["date"][:4], _bucket())):
+            bucket["trade_commissions_eur"] += trade["commission_eur"]
+            bucket["traded_volume_eur"] += trade["value_eur"]
+            bucket["trades"] += 1
+        totals["trade_commissions_eur"] += trade["commission_eur"]
+        totals["traded_volume_eur"] += trade["value_eur"]
+        totals["trades"] += 1
+        by_symbol = symbols.setdefault(trade["symbol"], {"commissions_eur": 0.0, "traded_volume_eur": 0.0, "trades": 0})
+        by_symbol["commissions_eur"] += trade["commission_eur"]
+        by_symbol["traded_volume_eur"] += trade["value_eur"]
+        by_symbol["trades"] += 1
+    for fee in fees:
+        for bucket in (months.setdefault(fee["date"][:7], _bucket()), years.setdefault(fee["date"][:4], _bucket())):
+            bucket["account_fees_eur"] += fee["fee_eur"]
+        totals["account_fees_eur"] += fee["fee_eur"]
+
+    def average_value(prefix: str) -> float | None:
+        period = [value for day, value in values if day.startswith(prefix) and value > 0]
+        return sum(period) / len(period) if period else None
+
+    def period_row(period: str, bucket: dict[str, Any], with_portfolio: bool) -> dict[str, Any]:
+        total = bucket["trade_commissions_eur"] + bucket["account_fees_eur"]
+        row = {
+            "period": period,
+            "trade_commissions_eur": round(bucket["trade_commissions_eur"], 2),
+            "account_fees_eur": round(bucket["account_fees_eur"], 2),
+            "total_eur": round(total, 2),
+            "traded_volume_eur": round(bucket["traded_volume_eur"], 2),
+            "trades": bucket["trades"],
+            "pct_of_volume": _pct(bucket["trade_commissions_eur"], bucket["traded_volume_eur"]),
+        }
+        if with_portfolio:
+            avg = average_value(period)
+            row["avg_portfolio_eur"] = round(avg, 2) if avg else None
+            row["pct_of_portfolio"] = _pct(total, avg)
+        return row
+
+    # Trailing twelve months, scaled to a year when the history is shorter.
+    start = (today - timedelta(days=364)).isoformat()
+    dates = [t["date"] for t in trades] + [f["date"] for f in fees]
+    trailing = sum(t["commission_eur"] for t in trades if t["date"] >= start) + sum(
+        f["fee_eur"] for f in fees if f["date"] >= start
+    )
+    days_covered = min(365, (today - date.fromisoformat(min(dates))).days + 1) if dates else 0
+    annual = trailing * 365 / days_covered if days_covered >= MIN_PROJECTION_DAYS else None
+    current_value = values[-1][1] if values else None
+
+    total = totals["trade_commissions_eur"] + totals["account_fees_eur"]
+    return {
+        "as_of_date": today.isoformat(),
+        "totals": {
+            "trade_commissions_eur": round(totals["trade_commissions_eur"], 2),
+            "account_fees_eur": round(totals["account_fees_eur"], 2),
+            "total_eur": round(total, 2),
+            "traded_volume_eur": round(totals["traded_volume_eur"], 2),
+            "trades": totals["trades"],
+            "pct_of_volume": _pct(totals["trade_commissions_eur"], totals["traded_volume_eur"]),
+        },
+        "by_year": [period_row(year, years[year], True) for year in sorted(years)],
+        "by_month": [period_row(month, months[month], False) for month in sorted(months)],
+        "by_symbol": sorted(
+            (
+                {
+                    "symbol": symbol,
+                    "trades": row["trades"],
+                    "commissions_eur": round(row["commissions_eur"], 2),
+                    "traded_volume_eur": round(row["traded_volume_eur"], 2),
+                    "pct_of_volume": _pct(row["commissions_eur"], row["traded_volume_eur"]),
+                }
+                for symbol, row in symbols.items()
+            ),
+            key=lambda row: -row["commissions_eur"],
+        ),
+        "trades": [
+            {
+                **trade,
+                "value_eur": round(trade["value_eur"], 2),
+                "commission_eur": round(trade["commission_eur"], 2),
+                "pct_of_value": _pct(trade["commission_eur"], trade["value_eur"]),
+            }
+            for trade in trades[:trades_limit]
+        ],
+        "projection": {
+            "trailing_12m_eur": round(trailing, 2),
+            "days_covered": days_covered,
+            "annual_eur": round(annual, 2) if annual is not None else None,
+            "portfolio_value_eur": round(current_value, 2) if current_value else None,
+            "annual_pct_of_portfolio": _pct(annual, current_value) if annual is not None else None,
+        },
+    }
+
+
+async def build_fee_analytics(
+    db,
+    currency,
+    today: date | None = None,
+    trades_limit: int = DEFAULT_TRADES_LIMIT,
+) -> dict[str, Any]:
+    """Fee totals per period, per symbol and per trade, and the annual cost projection."""
+    today = today or date.today()
+    securities = await db.get_all_securities(active_only=False)
+    security_currencies = {s["symbol"]: s.get("currency") or "EUR" for s in securities}
+
+    trades = []
+    for trade in await db.get_trades(limit=TRADE_HISTORY_LIMIT):
+        day = _trade_date(trade)
+        value = float(trade["quantity"]) * float(trade["price"])
+        value_eur = await currency.to_eur_for_date(value, _trade_currency(trade, security_currencies), day)
+        commission = float(trade.get("commission") or 0)
+        commission_eur = 0.0
+        if commission:
+            commission_eur = await currency.to_eur_for_date(commission, trade.get("commission_currency") or "EUR", day)
+        trades.append(
+            {
+                "id": trade["id"],
+                "symbol": trade["symbol"],
+                "side": trade["side"],
+                "date": day,
+                "value_eur": value_eur,
+                "commission_eur": commission_eur,
+            }
+        )
+
+    fees = []
+    for flow in await db.get_cash_flows():
+        if not is_fee_flow(str(flow.get("type_id") or "")):
+            continue
+        day = str(flow["date"])[:10]
+        amount = await currency.to_eur_for_date(float(flow["amount"] or 0), flow.get("currency") or "EUR", day)
+        fees.append({"date": day, "fee_eur": -amount})
+
+    values = daily_value_series(await db.get_portfolio_snapshots())
+    return summarize(trades, fees, values, today, trades_limit=trades_limit)
@@ -0,0 +1,317 @@
+"""
+Resource governor - keeps low-priority background work from starving the API.
+
+On a small board the heavy jobs (forecasts, indicators, backfills) can use
+every core and most of the memory while the HTTP server and the trading jobs
+wait. The governor samples system CPU (from /proc/stat) and memory (from
+/proc/meminfo) and puts the machine in one of three states:
+
+| State | Entered when | Low-priority work |
+|---|---|---|
+| normal | CPU below `cpu_resume_pct` and memory below `memory_resume_pct` | runs freely |
+| throttled | CPU at `cpu_throttle_pct` or memory at `memory_throttle_pct` | sleeps at every checkpoint |
+| paused | CPU at `cpu_pause_pct` or memory at `memory_pause_pct` | waits for the pressure to ease |
+
+Leaving a state takes the load to drop below the resume thresholds, so the
+state does not flap around a single threshold. A scheduled run of one of
+`low_priority_jobs` waits while the governor is paused, up to
+`max_defer_seconds`, and is skipped (reason `resource_pressure`) if the
+pressure has not eased by then; manual runs are never held back. Long loops
+in those jobs call `checkpoint()` between items, which slows them down while
+throttled and holds them while paused.
+
+Where /proc is not available (development on macOS) there is no sample and
+the state stays normal. The configuration is reloaded at the start of every
+scheduled job; GET /api/system/governor reports the latest sample, the state
+and how many runs were deferred or skipped.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import logging
+import math
+import os
+import time
+from typing import Any, Awaitable, Callable
+
+logger = logging.getLogger(__name__)
+
+RESOURCE_GOVERNOR_KEY = "resource_governor"
+LOW_PRIORITY_JOBS = [
+    "forecast:run",
+    "forecast:evaluate",
+    "security:technical",
+    "security:liquidity",
+    "security:factors",
+    "snapshot:backfill",
+    "sync:news",
+    "system:retention",
+]
+DEFAULT_CONFIG: dict[str, Any] = {
+    "enabled": True,
+    "cpu_throttle_pct": 75.0,
+    "cpu_pause_pct": 92.0,
+    "cpu_resume_pct": 50.0,
+    "memory_throttle_pct": 85.0,
+    "memory_pause_pct": 93.0,
+    "memory_resume_pct": 75.0,
+    "max_defer_seconds": 600,
+    "throttle_sleep_ms": 250,
+    "low_priority_jobs": LOW_PRIORITY_JOBS,
+}
+PCT_FIELDS = (
+    "cpu_throttle_pct",
+    "cpu_pause_pct",
+    "cpu_resume_pct",
+    "memory_throttle_pct",
+    "memory_pause_pct",
+    "memory_resume_pct",
+)
+
+# A sample is reused for this long; CPU usage is measured over the interval since the previous one.
+SAMPLE_INTERVAL_SECONDS = 1.0
+# How often a deferred job looks at the load again.
+POLL_SECONDS = 5.0
+
+NORMAL = "normal"
+THROTTLED = "throttled"
+PAUSED = "paused"
+
+SettingGetter = Callable[..., Awaitable[Any]]
+Sample = dict[str, float]
+
+
+def validate_resource_governor(raw: Any) -> dict[str, Any]:
+    """Validate a `resource_governor` value; omitted fields keep their defaults.
+
+    Raises:
+        ValueError: On unknown fields, percentages outside 1-100, resume
+            thresholds not below the throttle ones or throttle thresholds
+            above the pause ones, negative durations, a non-boolean `enabled`
+            or `low_priority_jobs` that is not a list of job types.
+    """
+    if not isinstance(raw, dict):
+        raise ValueError(f"{RESOURCE_GOVERNOR_KEY} must be an object")
+    unknown = set(raw) - set(DEFAULT_CONFIG)
+    if unknown:
+        raise ValueError(f"{RESOURCE_GOVERNOR_KEY} has unknown fields: {', '.join(sorted(unknown))}")
+    config = {**DEFAULT_CONFIG, "low_priority_jobs": list(LOW_PRIORITY_JOBS)}
+    for key, value in raw.items():
+        if key == "enabled":
+            if not isinstance(value, bool):
+                raise ValueError(f"{RESOURCE_GOVERNOR_KEY}.enabled must be true or false")
+            config[key] = value
+        elif key == "low_priority_jobs":
+            if not isinstance(value, list) or not all(isinstance(job, str) and job for job in value):
+                raise ValueError(f"{RESOURCE_GOVERNOR_KEY}.low_priority_jobs must be a list of job types")
+            config[key] = list(dict.fromkeys(value))
+        elif key in PCT_FIELDS:
+            if isinstance(value, bool) or not isinstance(value, int | float) or not 1 <= value <= 100:
+                raise ValueError(f"{RESOURCE_GOVERNOR_KEY}.{key} must be a number between 1 and 100")
+            config[key] = float(value)
+        else:
+            if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value) or value < 0:
+                raise ValueError(f"{RESOURCE_GOVERNOR_KEY}.{key} must be a non-negative number")
+            config[key] = value
+    for resource in ("cpu", "memory"):
+        resume, throttle, pause = (config[f"{resource}_{level}_pct"] for level in ("resume", "throttle", "pause"))
+        if not resume < throttle <= pause:
+            raise ValueError(
+                f"{RESOURCE_GOVERNOR_KEY} needs {resource}_resume_pct < {resource}_throttle_pct "
+                f"<= {resource}_pause_pct"
+            )
+    return config
+
+
+def _read_cpu_times() -> tuple[int, int] | None:
+    """(idle, total) jiffies across all CPUs, or None without /proc/stat."""
+    try:
+        with open("/proc/stat") as f:
+            fields = f.readline().split()
+    except OSError:
+        return None
+    if not fields or fields[0] != "cpu":
+        return None
+    values = [int(v) for v in fields[1:]]
+    idle = values[3] + (values[4] if len(values) > 4 else 0)
+    return idle, sum(values)
+
+
+def _read_memory_pct() -> float | None:
+    """Share of memory in use (MemTotal minus MemAvailable), or None without /proc/meminfo."""
+    info = {}
+    try:
+        with open("/proc/meminfo") as f:
+            for line in f:
+                name, _, rest = line.partition(":")
+                info[name] = int(rest.split()[0])
+    except (OSError, ValueError, IndexError):
+        return None
+    total = info.get("MemTotal")
+    available = info.get("MemAvailable")
+    if not total or available is None:
+        return None
+    return (total - available) / total * 100
+
+
+class ProcSampler:
+    """Reads system CPU and memory use from /proc."""
+
+    def __init__(self):
+        self._last_cpu: tuple[int, int] | None = None
+
+    def __call__(self) -> Sample | None:
+        cpu_times = _read_cpu_times()
+        memory_pct = _read_memory_pct()
+        if cpu_times is None or memory_pct is None:
+            return None
+        previous, self._last_cpu = self._last_cpu, cpu_times
+        if previous is None:
+            # First reading: no interval yet, so fall back to the load average.
+            cpu_pct = min(100.0, _load_average() / _cpu_count() * 100)
+        else:
+            idle = cpu_times[0] - previous[0]
+            total = cpu_times[1] - previous[1]
+            cpu_pct = (1 - idle / total) * 100 if total > 0 else 0.0
+        return {"cpu_pct": round(cpu_pct, 1), "memory_pct": round(memory_pct, 1), "load_1m": round(_load_average(), 2)}
+
+
+def _load_average() -> float:
+    try:
+        return os.getloadavg()[0]
+    except (OSError, AttributeError):
+        return 0.0
+
+
+def _cpu_count() -> int:
+    return os.cpu_count() or 1
+
+
+class ResourceGovernor:
+    """Tracks system load and holds low-priority work back while it is high."""
+
+    def __init__(
+        self,
+        sampler: Callable[[], Sample | None] | None = None,
+        clock: Callable[[], float] = time.monotonic,
+        sleep: Callable[[float], Awaitable[Any]] = asyncio.sleep,
+    ):
+        self._config = {**DEFAULT_CONFIG, "low_priority_jobs": list(LOW_PRIORITY_JOBS)}
+        self._sampler = sampler or ProcSampler()
+        self._clock = clock
+        self._sleep = sleep
+        self._sample: Sample | None = None
+        self._sampled_at: float | None = None
+        self._state = NORMAL
+        self._state_since = clock()
+        self.counts = {"deferred": 0, "skipped": 0, "throttled_checkpoints": 0}
+
+    def configure(self, config: dict[str, Any]) -> None:
+        """Apply a validated configuration."""
+        self._config = config
+        if not config["enabled"]:
+            self._set_state(NORMAL)
+
+    async def load(self, get: SettingGetter) -> None:
+        """Reload the configuration from settings."""
+        value = await get(RESOURCE_GOVERNOR_KEY, DEFAULT_CONFIG)
+        try:
+            config = validate_resource_governor(value or {})
+        except ValueError as e:
+            logger.warning(f"Ignoring invalid {RESOURCE_GOVERNOR_KEY}: {e}")
+            config = validate_resource_governor({})
+        self.configure(config)
+
+    def is_low_priority(self, job_type: str) -> bool:
+        return self._config["enabled"] and job_type in self._config["low_priority_jobs"]
+
+    def _set_state(self, state: str) -> None:
+        if state == self._state:
+            return
+        if state != NORMAL or self._state == PAUSED:
+            logger.info(f"Resource governor: {self._state} -> {state} ({self._describe()})")
+        self._state = state
+        self._state_since = self._clock()
+
+    def _describe(self) -> str:
+        if self._sample is None:
+            return "no sample"
+        return f"cpu {self._sample['cpu_pct']:.0f}%, memory {self._sample['memory_pct']:.0f}%"
+
+    def state(self) -> str:
+        """Sample the load if the last sample is stale and return the current state."""
+        if not self._config["enabled"]:
+            return NORMAL
+        now = self._clock()
+        if self._sampled_at is not None and now - self._sampled_at < SAMPLE_INTERVAL_SECONDS:
+            return self._state
+        self._sampled_at = now
+        self._sample = self._sampler()
+        if self._sample is None:
+            self._set_state(NORMAL)
+            return self._state
+
+        cpu, memory = self._sample["cpu_pct"], self._sample["memory_pct"]
+        config = self._config
+        if cpu >= config["cpu_pause_pct"] or memory >= config["memory_pause_pct"]:
+            self._set_state(PAUSED)
+        elif cpu >= config["cpu_throttle_pct"] or memory >= config["memory_throttle_pct"]:
+            self._set_state(THROTTLED)
+        elif cpu < config["cpu_resume_pct"] and memory < config["memory_resume_pct"]:
+            self._set_state(NORMAL)
+        elif self._state == PAUSED:
+            # Below the pause thresholds but not yet idle: keep slowing down.
+            self._set_state(THROTTLED)
+        return self._state
+
+    async def _wait_while_paused(self) -> bool:
+        """Wait up to max_defer_seconds for the pause to lift; True when it did."""
+        deadline = self._clock() + float(self._config["max_defer_seconds"])
+        while self.state() == PAUSED:
+            remaining = deadline - self._clock()
+            if remaining <= 0:
+                return False
+            await self._sleep(min(POLL_SECONDS, remaining))
+        return True
+
+    async def admit(self, job_type: str) -> bool:
+        """Hold a scheduled low-priority job back while paused.
+
+        Returns:
+            True to run the job now, False to skip this run.
+        """
+        if not self.is_low_priority(job_type) or self.state() != PAUSED:
+            return True
+        self.counts["deferred"] += 1
+        logger.info(f"Deferring {job_type}: system under load ({self._describe()})")
+        if await self._wait_while_paused():
+            return True
+        self.counts["skipped"] += 1
+        logger.warning(
+            f"Skipping {job_type}: system still under load after {self._config['max_defer_seconds']}s "
+            f"({self._describe()})"
+        )
+        return False
+
+    async def checkpoint(self) -> None:
+        """Yield between items of a long low-priority loop: slower while throttled, held while paused."""
+        state = self.state()
+        if state == THROTTLED:
+            self.counts["throttled_checkpoints"] += 1
+            await self._sleep(self._config["throttle_sleep_ms"] / 1000)
+        elif state == PAUSED:
+            await self._wait_while_paused()
+
+    def status(self) -> dict[str, Any]:
+        state = self.state()
+        return {
+            "state": state,
+            "since_seconds": round(self._clock() - self._state_since, 1),
+            "sample": dict(self._sample) if self._sample else None,
+            "config": dict(self._config),
+            "counts": dict(self.counts),
+        }
+
+
+governor = ResourceGovernor()
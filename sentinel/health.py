@@ -0,0 +1,154 @@
+"""
+Health checks for process supervisors and external monitoring.
+
+- Liveness: the process is up and answering requests.
+- Readiness: the database is open, its schema is fully migrated and the job
+  scheduler is running. Until then the app should not receive traffic.
+- Health report: readiness plus broker connectivity, how long ago each
+  sync job last succeeded, the last clock drift check and whether the
+  account holds shorts or is on margin (sentinel.exposure). A sync is stale
+  once it has not succeeded for SYNC_STALE_FACTOR times its (market-closed)
+  interval.
+
+Each check is {"ok": bool, "detail": str | None}.
+
+Usage:
+    health = HealthService(db, broker)
+    ready = await health.readiness(scheduler_running=True)
+    report = await health.report(scheduler_running=True)
+"""
+
+from __future__ import annotations
+
+import time
+from typing import Any
+
+from sentinel.clock import CLOCK_STATE_KEY
+from sentinel.database import Database
+from sentinel.exposure import EXPOSURE_STATE_KEY, describe_exposure
+from sentinel.version import VERSION
+
+STARTED_AT = int(time.time())
+
+SYNC_STALE_FACTOR = 3
+
+
+def liveness(now_ts: int | None = None) -> dict[str, Any]:
+    """The process is up; nothing else is checked."""
+    now_ts = now_ts or int(time.time())
+    return {"status": "alive", "version": VERSION, "uptime_seconds": now_ts - STARTED_AT}
+
+
+def _check(ok: bool, detail: str | None = None) -> dict[str, Any]:
+    return {"ok": ok, "detail": detail}
+
+
+class HealthService:
+    """Readiness and dependency checks for the running app."""
+
+    def __init__(self, db: Database | None = None, broker=None):
+        self._db = db or Database()
+        self._broker = broker
+
+    async def readiness(self, scheduler_running: bool) -> dict[str, Any]:
+        """Whether the app can serve: database, migrations and scheduler.
+
+        Returns:
+            {"ready": bool, "checks": {"database", "migrations", "scheduler"}}
+        """
+        checks = {"database": await self._database_check()}
+        if checks["database"]["ok"]:
+            checks["migrations"] = self._migrations_check()
+        else:
+            checks["migrations"] = _check(False, "database not open")
+        checks["scheduler"] = _check(scheduler_running, None if scheduler_running else "scheduler not running")
+        return {"ready": all(c["ok"] for c in checks.values()), "checks": checks}
+
+    async def report(self, scheduler_running: bool, now_ts: int | None = None) -> dict[str, Any]:
+        """Readiness plus broker connectivity, sync freshness and clock drift.
+
+        Returns:
+            {"status": "ok" | "degraded" | "unhealthy", "ready", "checks", "syncs",
+            "clock"}. Not ready is unhealthy; a disconnected broker, a stale
+            sync, a suspect clock or shorts/margin in the account is degraded.
+        """
+        now_ts = now_ts or int(time.time())
+        readiness = await self.readiness(scheduler_running)
+        checks = dict(readiness["checks"])
+        connected = bool(self._broker and self._broker.connected)
+        checks["broker"] = _check(connected, None if connected else "broker not connected")
+        syncs = await self.sync_freshness(now_ts) if checks["database"]["ok"] else []
+        stale = [s["job_type"] for s in syncs if s["stale"]]
+        checks["syncs"] = _check(not stale, f"stale: {', '.join(stale)}" if stale else None)
+        clock = await self._db.get_planner_state(CLOCK_STATE_KEY) if checks["database"]["ok"] else None
+        checks["clock"] = self._clock_check(clock)
+        exposure = await self._db.get_planner_state(EXPOSURE_STATE_KEY) if checks["database"]["ok"] else None
+        checks["account"] = self._account_check(exposure)
+
+        if not readiness["ready"]:
+            status = "unhealthy"
+        elif all(c["ok"] for c in checks.values()):
+            status = "ok"
+        else:
+            status = "degraded"
+        return {
+            **liveness(now_ts),
+            "status": status,
+            "ready": readiness["ready"],
+            "checks": checks,
+            "syncs": syncs,
+            "clock": clock,
+        }
+
+    async def sync_freshness(self, now_ts: int) -> list[dict[str, Any]]:
+        """Last success of each sync job and whether it is overdue.
+
+        Returns:
+            [{"job_type", "last_success_ts", "age_seconds", "threshold_seconds", "stale"}]
+        """
+        completions = await self._db.get_last_job_completions()
+        syncs = []
+        for schedule in await self._db.get_job_schedules():
+            if schedule["category"] != "sync":
+                continue
+            last_success = completions.get(schedule["job_type"])
+            threshold = schedule["interval_minutes"] * 60 * SYNC_STALE_FACTOR
+            age = now_ts - last_success if last_success else None
+            syncs.append(
+                {
+                    "job_type": schedule["job_type"],
+                    "last_success_ts": last_success,
+                    "age_seconds": age,
+                    "threshold_seconds": threshold,
+                    "stale": age is None or age > threshold,
+                }
+            )
+        return syncs
+
+    @staticmethod
+    def _clock_check(clock: dict[str, Any] | None) -> dict[str, Any]:
+        if not isinstance(clock, dict) or not clock.get("suspect"):
+            return _check(True)
+        return _check(False, f"clock off by {clock.get('offset_seconds') or 0:+.0f}s")
+
+    @staticmethod
+    def _account_check(exposure: dict[str, Any] | None) -> dict[str, Any]:
+        if not isinstance(exposure, dict) or exposure.get("ok", True):
+            return _check(True)
+        return _check(False, describe_exposure(exposure))
+
+    async def _database_check(self) -> dict[str, Any]:
+        try:
+            await self._db.conn.execute("SELECT 1")
+        except Exception as e:  # noqa: BLE001
+            return _check(False, str(e))
+        return _check(True)
+
+    def _migrations_check(self) -> dict[str, Any]:
+        from sentinel.database.migrations import pending_migrations
+
+        try:
+            pending = pending_migrations(self._db._path)
+        except Exception as e:  # noqa: BLE001
+            return _check(False, str(e))
+        return _check(not pending, f"pending: {', '.join(pending)}" if pending else None)
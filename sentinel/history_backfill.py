@@ -0,0 +1,229 @@
+"""
+History backfill - import the broker's full trade and cash flow history.
+
+The scheduled syncs (`sync:trades`, `sync:cashflows`) start in 2020 and
+fetch a single page, so a new install misses older history and a busy
+account can lose trades past the page limit. The backfill pages through
+history window by window from `since` (by default BACKFILL_SINCE, before any
+Tradernet account) up to today. A window that returns a full page of trades
+is split in half until it fits, down to a single day.
+
+Rows go through the same upserts as the syncs, so existing ledger rows are
+skipped: trades by broker trade ID, cash flows by content hash. It is safe
+to run again, and while the service is running.
+
+Afterwards the ledger is replayed into positions and weighted average cost
+(sentinel.ledger) and compared with the broker's live positions and cash.
+The report lists what it could not classify: trades without an ID, symbol,
+side, date, quantity or price (skipped), trades in symbols outside the
+universe (imported), and cash flows of a type the ledger does not know
+(imported, replayed as deposits or withdrawals until
+sentinel.cash_flow_rules classifies them). Portfolio snapshots are
+rebuilt from the new history by the next `snapshot:backfill`.
+
+    python main.py --backfill-history [--since 2015-01-01]
+"""
+
+from __future__ import annotations
+
+import logging
+from datetime import date, timedelta
+from typing import Any
+
+from sentinel.cash_flow_rules import known_type
+from sentinel.identifiers import IdentifierService
+from sentinel.jobs.tasks import _parse_broker_timestamp
+from sentinel.ledger import LedgerService, compare_states
+
+logger = logging.getLogger(__name__)
+
+BACKFILL_SINCE = "2010-01-01"
+WINDOW_DAYS = 365
+# Broker.get_trades_history asks for at most this many trades per call.
+TRADES_PAGE_LIMIT = 1000
+UNCLASSIFIED_LIMIT = 100
+
+
+def windows(since: date, until: date, days: int = WINDOW_DAYS) -> list[tuple[date, date]]:
+    """Consecutive inclusive date ranges of at most `days` days covering since..until."""
+    ranges = []
+    start = since
+    while start <= until:
+        end = min(start + timedelta(days=days - 1), until)
+        ranges.append((start, end))
+        start = end + timedelta(days=1)
+    return ranges
+
+
+def _trade_problem(trade: dict) -> str | None:
+    """Why a broker trade cannot be imported, or None."""
+    if not str(trade.get("id", "") or ""):
+        return "no trade id"
+    if not (trade.get("symbol") or trade.get("instr_nm")):
+        return "no symbol"
+    if str(trade.get("type", "")) not in ("1", "2"):
+        return f"unknown side type {trade.get('type')!r}"
+    if not _parse_broker_timestamp(trade.get("date", "")):
+        return f"unparseable date {trade.get('date')!r}"
+    try:
+        if float(trade.get("q", 0)) <= 0 or float(trade.get("p", 0)) <= 0:
+            return "quantity or price not positive"
+    except (TypeError, ValueError):
+        return "quantity or price not a number"
+    return None
+
+
+class HistoryBackfill:
+    """Pages the broker history into the ledger and reports what it found."""
+
+    def __init__(self, db, broker, identifiers: IdentifierService | None = None):
+        self._db = db
+        self._broker = broker
+        self._identifiers = identifiers or IdentifierService(db)
+
+    async def _fetch_trades(self, start: date, end: date) -> list[dict]:
+        trades = await self._broker.get_trades_history(start_date=start.isoformat(), end_date=end.isoformat())
+        if len(trades) < TRADES_PAGE_LIMIT or start == end:
+            if len(trades) >= TRADES_PAGE_LIMIT:
+                logger.warning(f"{start} has {len(trades)} trades, the page limit; some may be missing")
+            return trades
+        middle = start + (end - start) // 2
+        return await self._fetch_trades(start, middle) + await self._fetch_trades(middle + timedelta(days=1), end)
+
+    async def _import_trades(self, trades: list[dict], report: dict[str, Any]) -> None:
+        counts = report["trades"]
+        seen: set[str] = set()
+        unknown: set[str] = set()
+        for trade in trades:
+            trade_id = str(trade.get("id", "") or "")
+            if trade_id in seen:
+                continue
+            if trade_id:
+                seen.add(trade_id)
+            counts["fetched"] += 1
+            problem = _trade_problem(trade)
+            if problem:
+                counts["unclassified"] += 1
+                _note(report, {"kind": "trade", "id": trade_id or None, "date": trade.get("date"), "reason": problem})
+                continue
+
+            raw_symbol = str(trade.get("symbol") or trade.get("instr_nm"))
+            symbol = await self._identifiers.resolve(raw_symbol)
+            if symbol is None:
+                symbol = raw_symbol.strip()
+                unknown.add(symbol)
+            row_id = await self._db.upsert_trade(
+                broker_trade_id=trade_id,
+                symbol=symbol,
+                side="BUY" if str(trade["type"]) == "1" else "SELL",
+                quantity=float(trade["q"]),
+                price=float(trade["p"]),
+                executed_at=_parse_broker_timestamp(trade["date"]),
+                raw_data=trade,
+                commission=float(trade.get("commission", 0) or 0),
+                commission_currency=trade.get("commission_currency", "EUR"),
+            )
+            counts["imported" if row_id and row_id > 0 else "existing"] += 1
+        report["unknown_symbols"] = sorted(unknown)
+
+    async def _import_cash_flows(self, flows: list[dict], report: dict[str, Any]) -> None:
+        counts = report["cash_flows"]
+        for flow in flows:
+            counts["fetched"] += 1
+            type_id = str(flow.get("type_id", "") or "")
+            try:
+                amount = float(flow.get("amount", 0) or 0)
+            except (TypeError, ValueError):
+                amount = None
+            if not flow.get("date") or not type_id or amount is None:
+                counts["unclassified"] += 1
+                _note(report, {"kind": "cash_flow", "date": flow.get("date"), "reason": "no date, type or amount"})
+                continue
+
+            row_id = await self._db.upsert_cash_flow(
+                date=flow["date"],
+                type_id=type_id,
+                amount=amount,
+                currency=flow.get("currency", "EUR"),
+                comment=flow.get("comment", ""),
+                raw_data=flow,
+            )
+            counts["imported" if row_id and row_id > 0 else "existing"] += 1
+            if not known_type(type_id):
+                counts["unclassified"] += 1
+                replayed_as = "deposit" if amount > 0 else "withdrawal"
+                _note(
+                    report,
+                    {
+                        "kind": "cash_flow",
+                        "date": flow["date"],
+                        "reason": f"unknown type {type_id!r}, replayed as a {replayed_as}",
+                        "amount": amount,
+                        "currency": flow.get("currency", "EUR"),
+                    },
+                )
+
+    async def run(self, since: str = BACKFILL_SINCE, until: str | None = None) -> dict[str, Any]:
+        """Import the history between `since` and `until` (default today, both YYYY-MM-DD).
+
+        Returns:
+            {"since", "until", "windows", "trades", "cash_flows",
+             "unknown_symbols", "unclassified", "ledger"}: counts of fetched,
+            imported, existing and unclassified rows, the rows it could not
+            classify (at most UNCLASSIFIED_LIMIT), and the ledger replay:
+            positions with average cost, cash, and its differences from the
+            live snapshot.
+
+        Raises:
+            RuntimeError: When the broker is not connected.
+            ValueError: For dates that are not YYYY-MM-DD or out of order.
+        """
+        if not self._broker.connected:
+            raise RuntimeError("Broker not connected (missing credentials?)")
+        start = date.fromisoformat(since)
+        end = date.fromisoformat(until) if until else date.today()
+        if start > end:
+            raise ValueError(f"since {since} is after until {end}")
+
+        report: dict[str, Any] = {
+            "since": start.isoformat(),
+            "until": end.isoformat(),
+            "windows": 0,
+            "trades": {"fetched": 0, "imported": 0, "existing": 0, "unclassified": 0},
+            "cash_flows": {"fetched": 0, "imported": 0, "existing": 0, "unclassified": 0},
+            "unknown_symbols": [],
+            "unclassified": [],
+        }
+        trades: list[dict] = []
+        flows: list[dict] = []
+        for window_start, window_end in windows(start, end):
+            report["windows"] += 1
+            trades += await self._fetch_trades(window_start, window_end)
+            flows += await self._broker.get_cash_flows(
+                start_date=window_start.isoformat(), end_date=window_end.isoformat()
+            )
+        logger.info(f"Backfill fetched {len(trades)} trades and {len(flows)} cash flows since {start}")
+
+        await self._import_trades(trades, report)
+        await self._import_cash_flows(flows, report)
+        if report["trades"]["imported"] or report["cash_flows"]["imported"]:
+            await self._db.invalidate_planner_cache()
+
+        replayed = await LedgerService(self._db).replay()
+        consistency = compare_states(replayed, await self._db.get_all_positions(), await self._db.get_cash_balances())
+        report["ledger"] = {
+            "positions": {s: p for s, p in replayed["positions"].items() if p["quantity"] > 0},
+            "cash": replayed["cash"],
+            "consistent": consistency["consistent"],
+            "differences": {"positions": consistency["positions"], "cash": consistency["cash"]},
+        }
+        logger.info(
+            f"Backfill complete: {report['trades']['imported']} trades and "
+            f"{report['cash_flows']['imported']} cash flows imported"
+        )
+        return report
+
+
+def _note(report: dict[str, Any], entry: dict[str, Any]) -> None:
+    if len(report["unclassified"]) < UNCLASSIFIED_LIMIT:
+        report["unclassified"].append(entry)
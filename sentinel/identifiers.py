@@ -0,0 +1,198 @@
+"""
+Identifiers - canonical symbols and ISINs for securities.
+
+Symbols reach Sentinel in several spellings: as stored ("ASML.EU"), from
+users and scripts ("asml.eu", "ASML:EU", "ASML EU"), without an exchange
+suffix ("ASML") or as the ISIN ("NL0010273215"). IdentifierService maps
+any of them to the symbol stored in the securities table, from an index
+built with one query and cached per database. An ISIN resolves to its
+mapped broker symbol (see sentinel.broker_symbols) when one is set, and the
+symbol of a merged duplicate to the record it was merged into (see
+sentinel.duplicates).
+
+The index is dropped after INDEX_TTL_SECONDS, and callers that add or
+remove securities call `invalidate()` so new rows resolve immediately.
+
+Usage:
+    ids = IdentifierService(db)
+    symbol = await ids.resolve("nl0010273215")   # -> "ASML.EU"
+    isin = await ids.isin_for("ASML.EU")
+    security = await ids.get_security("ASML")
+"""
+
+from __future__ import annotations
+
+import inspect
+import json
+import re
+from dataclasses import dataclass, field
+from typing import Any
+
+from sentinel.cache import Cache
+from sentinel.database import Database
+
+ISIN_RE = re.compile(r"^[A-Z]{2}[A-Z0-9]{9}[0-9]$")
+# Separators users and other tools put between ticker and exchange suffix.
+_SUFFIX_SEPARATORS = re.compile(r"\s*[:\s]\s*")
+
+INDEX_TTL_SECONDS = 600
+
+
+def normalize_symbol(value: str) -> str:
+    """Uppercase a symbol and write the exchange suffix as ".SUFFIX"."""
+    return _SUFFIX_SEPARATORS.sub(".", value.strip()).upper()
+
+
+def normalize_isin(value: str) -> str | None:
+    """The uppercased ISIN, or None if `value` is not shaped like one."""
+    isin = value.strip().upper()
+    return isin if ISIN_RE.match(isin) else None
+
+
+def security_isin(security: dict) -> str | None:
+    """ISIN (`issue_nb`) from a security's stored broker data or quote."""
+    for column in ("data", "quote_data"):
+        raw = security.get(column)
+        if not raw:
+            continue
+        try:
+            data = json.loads(raw) if isinstance(raw, str) else raw
+        except (json.JSONDecodeError, TypeError, ValueError):
+            continue
+        if not isinstance(data, dict):
+            continue
+        isin = data.get("issue_nb") or data.get("isin")
+        if isinstance(isin, str) and isin.strip():
+            return isin.strip().upper()
+    return None
+
+
+async def load_security_merges(db) -> list[dict]:
+    """Merged-away symbols with their canonical symbol; empty for databases without merges."""
+    getter = getattr(db, "get_security_merges", None)
+    merges = getter() if callable(getter) else None
+    if inspect.isawaitable(merges):
+        merges = await merges
+    return merges if isinstance(merges, list) else []
+
+
+@dataclass
+class IdentifierIndex:
+    """Lookup tables over every security, active or not."""
+
+    by_symbol: dict[str, str] = field(default_factory=dict)
+    isin_by_symbol: dict[str, str] = field(default_factory=dict)
+    symbol_by_isin: dict[str, str] = field(default_factory=dict)
+    # Ticker without exchange suffix -> stored symbols sharing it.
+    by_base: dict[str, list[str]] = field(default_factory=dict)
+    active: set[str] = field(default_factory=set)
+
+    @classmethod
+    def build(
+        cls,
+        securities: list[dict[str, Any]],
+        mappings: list[dict[str, Any]] | None = None,
+        merges: list[dict[str, Any]] | None = None,
+    ) -> IdentifierIndex:
+        index = cls()
+        listings: dict[str, list[str]] = {}
+        for security in securities:
+            symbol = security.get("symbol")
+            if not isinstance(symbol, str) or not symbol:
+                continue
+            index.by_symbol[normalize_symbol(symbol)] = symbol
+            index.by_base.setdefault(normalize_symbol(symbol).split(".")[0], []).append(symbol)
+            if int(security.get("active", 1) or 0):
+                index.active.add(symbol)
+            isin = security_isin(security)
+            if isin:
+                index.isin_by_symbol[symbol] = isin
+                listings.setdefault(isin, []).append(symbol)
+        # An ISIN resolves to its only active listing (or only listing);
+        # with several, it needs a broker symbol mapping.
+        for isin, symbols in listings.items():
+            active = [symbol for symbol in symbols if symbol in index.active]
+            if len(active) == 1 or (not active and len(symbols) == 1):
+                index.symbol_by_isin[isin] = (active or symbols)[0]
+        stored = set(index.by_symbol.values())
+        for mapping in mappings or []:
+            if mapping["broker_symbol"] in stored:
+                index.symbol_by_isin[mapping["isin"]] = mapping["broker_symbol"]
+        # Symbols merged into another record (sentinel.duplicates) resolve to it.
+        for merge in merges or []:
+            old = normalize_symbol(merge["symbol"])
+            if old not in index.by_symbol and merge["canonical"] in stored:
+                index.by_symbol[old] = merge["canonical"]
+        return index
+
+    def resolve(self, value: str) -> str | None:
+        symbol = normalize_symbol(value)
+        if symbol in self.by_symbol:
+            return self.by_symbol[symbol]
+        isin = normalize_isin(value)
+        if isin and isin in self.symbol_by_isin:
+            return self.symbol_by_isin[isin]
+        if "." not in symbol:
+            listings = self.by_base.get(symbol, [])
+            active = [listing for listing in listings if listing in self.active]
+            if len(listings) == 1:
+                return listings[0]
+            if len(active) == 1:
+                return active[0]
+        return None
+
+
+_cache: Cache[IdentifierIndex] = Cache("identifiers", ttl_seconds=INDEX_TTL_SECONDS, max_entries=16)
+
+
+class IdentifierService:
+    """Resolves symbols, symbol variants and ISINs to stored securities."""
+
+    def __init__(self, db: Database | None = None):
+        self._db = db or Database()
+
+    @property
+    def _key(self) -> str:
+        return str(getattr(self._db, "_path", id(self._db)))
+
+    async def index(self) -> IdentifierIndex:
+        index = _cache.get(self._key)
+        if index is None:
+            index = IdentifierIndex.build(
+                await self._db.get_all_securities(active_only=False),
+                await self._db.get_broker_symbols(),
+                await load_security_merges(self._db),
+            )
+            _cache.set(self._key, index)
+        return index
+
+    def invalidate(self) -> None:
+        """Drop the cached index after securities were added, removed or re-keyed."""
+        _cache.invalidate(self._key)
+
+    async def resolve(self, symbol_or_isin: str) -> str | None:
+        """Stored symbol for a symbol, symbol variant or ISIN; None if unknown or ambiguous."""
+        if not symbol_or_isin or not symbol_or_isin.strip():
+            return None
+        return (await self.index()).resolve(symbol_or_isin)
+
+    async def canonical(self, symbol: str) -> str:
+        """`resolve(symbol)`, falling back to the input as given for unknown symbols."""
+        return await self.resolve(symbol) or symbol.strip()
+
+    async def isin_for(self, symbol: str) -> str | None:
+        resolved = await self.resolve(symbol)
+        return (await self.index()).isin_by_symbol.get(resolved) if resolved else None
+
+    async def symbol_for(self, isin: str) -> str | None:
+        normalized = normalize_isin(isin)
+        return (await self.index()).symbol_by_isin.get(normalized) if normalized else None
+
+    async def is_active(self, symbol: str) -> bool:
+        """Whether `symbol` is a stored, active security (no database round-trip)."""
+        return symbol in (await self.index()).active
+
+    async def get_security(self, symbol_or_isin: str) -> dict | None:
+        """The security row for a symbol, symbol variant or ISIN."""
+        symbol = await self.resolve(symbol_or_isin)
+        return await self._db.get_security(symbol) if symbol else None
@@ -0,0 +1,163 @@
+"""
+Technical indicators - per security and date, carried forward incrementally.
+
+IndicatorEngine steps through daily bars oldest first and keeps the running
+state the indicators need (Wilder averages, EMAs, the previous close, the
+last SMA_PERIOD closes). Each computed row stores that state as JSON, so
+`update_indicators` resumes from the last stored row and only steps through
+newer prices. It recomputes from scratch when there is no stored row yet, or
+when the price of the last stored row has changed since (history rewritten
+by a price sync or an accepted quarantine entry).
+
+Per row:
+- rsi14: Wilder RSI
+- ema20, sma50, ema_sma_cross: +1 while EMA20 is above SMA50, -1 below
+- macd, macd_signal, macd_hist: EMA12 - EMA26, its EMA9 and the difference
+- bollinger_pct_b: position of the close in the 20-day, 2-sigma bands
+  (0 = lower band, 1 = upper band)
+- atr14: Wilder average true range
+
+Values stay None until there are enough bars for them.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import math
+from collections import deque
+from dataclasses import asdict, dataclass
+
+logger = logging.getLogger(__name__)
+
+RSI_PERIOD = 14
+ATR_PERIOD = 14
+EMA_FAST = 12
+EMA_SLOW = 26
+MACD_SIGNAL_PERIOD = 9
+EMA_TREND = 20
+SMA_PERIOD = 50
+BOLLINGER_PERIOD = 20
+BOLLINGER_WIDTH = 2.0
+INDICATOR_FIELDS = (
+    "rsi14",
+    "ema20",
+    "sma50",
+    "ema_sma_cross",
+    "macd",
+    "macd_signal",
+    "macd_hist",
+    "bollinger_pct_b",
+    "atr14",
+)
+
+
+def _smooth(previous: float | None, value: float, samples: int, period: int, wilder: bool = False) -> float:
+    """Mean of the first `period` samples, then EMA (or Wilder) smoothing."""
+    if previous is None:
+        return value
+    if samples <= period:
+        return previous + (value - previous) / samples
+    alpha = 1 / period if wilder else 2 / (period + 1)
+    return previous + alpha * (value - previous)
+
+
+@dataclass
+class IndicatorState:
+    """Everything needed to step the indicators one more bar."""
+
+    count: int = 0
+    prev_close: float | None = None
+    avg_gain: float | None = None
+    avg_loss: float | None = None
+    ema_fast: float | None = None
+    ema_slow: float | None = None
+    ema_trend: float | None = None
+    macd_signal: float | None = None
+    atr: float | None = None
+
+
+class IndicatorEngine:
+    """Steps indicators through daily bars, oldest first."""
+
+    def __init__(self, state: IndicatorState | None = None, closes: list[float] | None = None):
+        self.state = state or IndicatorState()
+        self._closes: deque[float] = deque(closes or [], maxlen=SMA_PERIOD)
+
+    def step(self, bar: dict) -> dict:
+        """Advance by one bar; returns its indicator row (with the state as JSON)."""
+        s = self.state
+        close = float(bar["close"])
+        high = float(bar.get("high") or close)
+        low = float(bar.get("low") or close)
+        s.count += 1
+        self._closes.append(close)
+
+        if s.prev_close is None:
+            true_range = high - low
+        else:
+            true_range = max(high - low, abs(high - s.prev_close), abs(low - s.prev_close))
+            delta = close - s.prev_close
+            deltas = s.count - 1
+            s.avg_gain = _smooth(s.avg_gain, max(delta, 0.0), deltas, RSI_PERIOD, wilder=True)
+            s.avg_loss = _smooth(s.avg_loss, max(-delta, 0.0), deltas, RSI_PERIOD, wilder=True)
+        s.atr = _smooth(s.atr, true_range, s.count, ATR_PERIOD, wilder=True)
+        s.ema_fast = _smooth(s.ema_fast, close, s.count, EMA_FAST)
+        s.ema_slow = _smooth(s.ema_slow, close, s.count, EMA_SLOW)
+        s.ema_trend = _smooth(s.ema_trend, close, s.count, EMA_TREND)
+        s.prev_close = close
+
+        row = {"date": bar["date"], "close": close, **dict.fromkeys(INDICATOR_FIELDS)}
+        if s.count > RSI_PERIOD:
+            if s.avg_loss <= 1e-12:
+                row["rsi14"] = 100.0 if s.avg_gain > 1e-12 else 50.0
+            else:
+                row["rsi14"] = 100.0 - 100.0 / (1.0 + s.avg_gain / s.avg_loss)
+        if s.count >= ATR_PERIOD:
+            row["atr14"] = s.atr
+        if s.count >= EMA_TREND:
+            row["ema20"] = s.ema_trend
+        if s.count >= EMA_SLOW:
+            macd = s.ema_fast - s.ema_slow
+            s.macd_signal = _smooth(s.macd_signal, macd, s.count - EMA_SLOW + 1, MACD_SIGNAL_PERIOD)
+            row["macd"] = macd
+            if s.count >= EMA_SLOW + MACD_SIGNAL_PERIOD - 1:
+                row["macd_signal"] = s.macd_signal
+                row["macd_hist"] = macd - s.macd_signal
+        if len(self._closes) >= BOLLINGER_PERIOD:
+            window = list(self._closes)[-BOLLINGER_PERIOD:]
+            mean = sum(window) / BOLLINGER_PERIOD
+            width = BOLLINGER_WIDTH * math.sqrt(sum((c - mean) ** 2 for c in window) / BOLLINGER_PERIOD)
+            row["bollinger_pct_b"] = (close - (mean - width)) / (2 * width) if width > 0 else 0.5
+        if len(self._closes) == SMA_PERIOD:
+            row["sma50"] = sum(self._closes) / SMA_PERIOD
+            if row["ema20"] is not None:
+                row["ema_sma_cross"] = 1 if row["ema20"] > row["sma50"] else -1
+        row["state"] = json.dumps(asdict(s))
+        return row
+
+
+async def update_indicators(db, symbol: str) -> int:
+    """Compute indicator rows for prices newer than the last stored row.
+
+    Returns the number of rows written.
+    """
+    last = await db.get_latest_indicators(symbol)
+    engine = None
+    if last is not None:
+        stored = await db.get_prices(symbol, days=SMA_PERIOD, end_date=last["date"])
+        if stored and stored[0]["date"] == last["date"] and stored[0]["close"] == last["close"]:
+            state = IndicatorState(**json.loads(last["state"]))
+            engine = IndicatorEngine(state, [row["close"] for row in reversed(stored)])
+            bars = await db.get_prices_after(symbol, last["date"])
+        else:
+            logger.info(f"Price history for {symbol} changed; recomputing indicators")
+    if engine is None:
+        await db.delete_indicators(symbol)
+        engine = IndicatorEngine()
+        bars = list(reversed(await db.get_prices(symbol)))
+
+    rows = [engine.step(bar) for bar in bars if bar.get("close") and bar["close"] > 0]
+    if rows:
+        await db.save_indicators(symbol, rows)
+    return len(rows)
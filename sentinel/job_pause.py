@@ -0,0 +1,196 @@
+"""
+Job pause and maintenance windows - holding scheduled jobs back.
+
+Scheduled jobs can be paused all at once or per job type, e.g. while the
+database is being repaired or the broker API is known to be down. A pause
+either expires after `hours` or lasts until resumed. It is kept in the
+`job_pause` setting, so a restart does not quietly resume paused work; the
+scheduler logs what is still paused when it starts.
+
+Maintenance windows (`maintenance_windows` setting) are recurring periods of
+local time, on every day or on some days of the week, during which only
+MAINTENANCE_JOBS run. A window may end the next day when `end` is before
+`start`; it then belongs to the day it starts on.
+
+Both only hold back scheduled runs: the scheduler skips the job (reason
+`jobs_paused` or `maintenance_window`) and tries again at its next interval.
+A manual run from POST /api/jobs/{job_type}/run always goes through.
+
+Usage:
+    pause = JobPause()
+    await pause.pause("forecast:run", hours=None, reason="Model upgrade")
+    reason = await pause.blocks("forecast:run")  # "jobs_paused"
+    await pause.resume("forecast:run")
+"""
+
+from __future__ import annotations
+
+import math
+import time
+from datetime import datetime, timedelta
+from typing import Any
+
+from sentinel.led.modes import in_window, parse_hhmm
+from sentinel.settings import Settings
+
+PAUSE_KEY = "job_pause"
+WINDOWS_KEY = "maintenance_windows"
+MAX_PAUSE_HOURS = 30 * 24
+MAX_REASON_LENGTH = 200
+MAX_WINDOWS = 20
+WEEKDAYS = ("mon", "tue", "wed", "thu", "fri", "sat", "sun")
+
+# Jobs that keep running during a maintenance window.
+MAINTENANCE_JOBS = frozenset({"backup:r2", "system:retention", "system:clock_check"})
+
+
+def validate_maintenance_windows(value: Any) -> list[dict[str, Any]]:
+    """Validate a list of maintenance windows: {"start", "end", "days"?, "reason"?}.
+
+    Raises:
+        ValueError: If a window is malformed.
+    """
+    if not isinstance(value, list):
+        raise ValueError(f"{WINDOWS_KEY} must be a list of windows")
+    if len(value) > MAX_WINDOWS:
+        raise ValueError(f"{WINDOWS_KEY} may have at most {MAX_WINDOWS} windows")
+    windows = []
+    for i, window in enumerate(value):
+        if not isinstance(window, dict):
+            raise ValueError(f"{WINDOWS_KEY}[{i}] must be an object with 'start' and 'end'")
+        unknown = set(window) - {"start", "end", "days", "reason"}
+        if unknown:
+            raise ValueError(f"{WINDOWS_KEY}[{i}] has unknown fields: {', '.join(sorted(unknown))}")
+        start, end = window.get("start"), window.get("end")
+        try:
+            if parse_hhmm(start) == parse_hhmm(end):
+                raise ValueError("start and end must differ")
+        except ValueError as e:
+            raise ValueError(f"{WINDOWS_KEY}[{i}]: {e}") from e
+        days = window.get("days")
+        if days is not None:
+            if not isinstance(days, list) or not days or any(day not in WEEKDAYS for day in days):
+                raise ValueError(f"{WINDOWS_KEY}[{i}].days must be a non-empty list of {', '.join(WEEKDAYS)}")
+            days = [day for day in WEEKDAYS if day in days]
+        reason = window.get("reason")
+        if reason is not None:
+            if not isinstance(reason, str):
+                raise ValueError(f"{WINDOWS_KEY}[{i}].reason must be a string")
+            reason = reason.strip()[:MAX_REASON_LENGTH] or None
+        windows.append({"start": start.strip(), "end": end.strip(), "days": days, "reason": reason})
+    return windows
+
+
+def active_window(windows: list[dict[str, Any]], now: datetime) -> dict[str, Any] | None:
+    """The first window covering `now` (local time), or None."""
+    for window in windows:
+        if not in_window(window["start"], window["end"], now):
+            continue
+        if window.get("days") is None:
+            return window
+        # After midnight, a window that wraps belongs to the day it started on.
+        start, end = parse_hhmm(window["start"]), parse_hhmm(window["end"])
+        day = now - timedelta(days=1) if end < start and now.hour * 60 + now.minute < end else now
+        if WEEKDAYS[day.weekday()] in window["days"]:
+            return window
+    return None
+
+
+def _entry_active(entry: Any, now_ts: int) -> bool:
+    if not isinstance(entry, dict):
+        return False
+    expires_at_ts = entry.get("expires_at_ts")
+    return expires_at_ts is None or (isinstance(expires_at_ts, int) and expires_at_ts > now_ts)
+
+
+class JobPause:
+    """Reads and changes job pauses and maintenance windows."""
+
+    def __init__(self, settings: Settings | None = None):
+        self._settings = settings or Settings()
+
+    async def _stored(self) -> dict[str, Any]:
+        raw = await self._settings.get(PAUSE_KEY)
+        if not isinstance(raw, dict):
+            return {"all": None, "jobs": {}}
+        jobs = raw.get("jobs") if isinstance(raw.get("jobs"), dict) else {}
+        return {"all": raw.get("all"), "jobs": dict(jobs)}
+
+    async def windows(self) -> list[dict[str, Any]]:
+        """The configured maintenance windows; a malformed setting counts as none."""
+        try:
+            return validate_maintenance_windows(await self._settings.get(WINDOWS_KEY, []) or [])
+        except ValueError:
+            return []
+
+    async def status(self, now_ts: int | None = None, now: datetime | None = None) -> dict[str, Any]:
+        """Active pauses, expired ones left out, and the maintenance window in effect."""
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        stored = await self._stored()
+        windows = await self.windows()
+        return {
+            "all": stored["all"] if _entry_active(stored["all"], now_ts) else None,
+            "jobs": {job: entry for job, entry in sorted(stored["jobs"].items()) if _entry_active(entry, now_ts)},
+            "maintenance": {
+                "active_window": active_window(windows, now or datetime.now()),
+                "windows": windows,
+                "maintenance_jobs": sorted(MAINTENANCE_JOBS),
+            },
+        }
+
+    async def blocks(self, job_type: str, now_ts: int | None = None, now: datetime | None = None) -> str | None:
+        """Why a scheduled run of `job_type` has to wait, or None when it may run."""
+        status = await self.status(now_ts=now_ts, now=now)
+        if status["all"] is not None or job_type in status["jobs"]:
+            return "jobs_paused"
+        if status["maintenance"]["active_window"] is not None and job_type not in MAINTENANCE_JOBS:
+            return "maintenance_window"
+        return None
+
+    async def pause(
+        self,
+        job_type: str | None = None,
+        hours: float | None = None,
+        reason: str | None = None,
+        now_ts: int | None = None,
+    ) -> dict[str, Any]:
+        """Pause one job type, or every scheduled job when `job_type` is None.
+
+        Lasts `hours` hours, or until resumed when `hours` is None.
+
+        Raises:
+            ValueError: If hours or reason is malformed.
+        """
+        if hours is not None:
+            if isinstance(hours, bool) or not isinstance(hours, int | float) or not math.isfinite(hours):
+                raise ValueError("hours must be a number or null")
+            if not 0 < hours <= MAX_PAUSE_HOURS:
+                raise ValueError(f"hours must be in (0, {MAX_PAUSE_HOURS}]")
+        if reason is not None:
+            if not isinstance(reason, str):
+                raise ValueError("reason must be a string")
+            reason = reason.strip()[:MAX_REASON_LENGTH] or None
+
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        entry = {
+            "paused_at_ts": now_ts,
+            "expires_at_ts": None if hours is None else now_ts + int(round(hours * 3600)),
+            "reason": reason,
+        }
+        stored = await self._stored()
+        stored["jobs"] = {job: e for job, e in stored["jobs"].items() if _entry_active(e, now_ts)}
+        if job_type is None:
+            stored["all"] = entry
+        else:
+            stored["jobs"][job_type] = entry
+        await self._settings.set(PAUSE_KEY, stored)
+        return entry
+
+    async def resume(self, job_type: str | None = None) -> None:
+        """Lift the pause of one job type, or every pause when `job_type` is None."""
+        if job_type is None:
+            await self._settings.set(PAUSE_KEY, None)
+            return
+        stored = await self._stored()
+        stored["jobs"].pop(job_type, None)
+        await self._settings.set(PAUSE_KEY, stored)
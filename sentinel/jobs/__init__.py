@@ -1,7 +1,16 @@
 """APScheduler-based job system."""
 
 from sentinel.jobs.market import BrokerMarketChecker, MarketChecker
-from sentinel.jobs.runner import get_status, init, reschedule, run_now, stop
+from sentinel.jobs.runner import (
+    current_job,
+    get_status,
+    init,
+    is_running,
+    reschedule,
+    run_now,
+    schedule_audit,
+    stop,
+)
 
 __all__ = [
     "BrokerMarketChecker",
@@ -11,4 +20,7 @@ __all__ = [
     "reschedule",
     "run_now",
     "get_status",
+    "is_running",
+    "current_job",
+    "schedule_audit",
 ]
@@ -1,16 +1,99 @@
-"""Market timing checks for jobs."""
+"""Market timing checks for jobs.
+
+Most market timings are global (any market open, all markets closed). The
+per-market close timing instead tracks each broker market on its own: a job
+runs for the securities of every market that has closed since the job last
+ran for it, so HK-listed securities are synced after the HKEX close rather
+than after the US close.
+
+Whether a market is open always comes from the broker. EXCHANGE_HOURS only
+projects future sessions (for the schedule audit): hours are wall-clock
+times in each exchange's own timezone and converted through zoneinfo, so
+DST is applied per exchange. In the weeks where the US and Europe switch
+on different dates, the New York open moves an hour closer to the European
+close. Holidays and half days are not modelled.
+"""
 
 from __future__ import annotations
 
+import json
 import logging
-from datetime import datetime, timedelta
+from dataclasses import dataclass
+from datetime import date, datetime, time, timedelta, timezone
 from typing import Optional, Protocol
+from zoneinfo import ZoneInfo
+
+from sentinel.clock import Clock, SystemClock
 
 logger = logging.getLogger(__name__)
 
 # How often to refresh market data (5 minutes)
 MARKET_DATA_TTL = timedelta(minutes=5)
 
+# Stand-in market id for securities whose broker market is unknown.
+UNKNOWN_MARKET = "*"
+
+# Broker market code (`n2`) for a symbol's exchange suffix.
+SYMBOL_SUFFIX_MARKETS = {"US": "NASDAQ", "GR": "XETRA", "L": "LSE"}
+
+
+@dataclass(frozen=True)
+class ExchangeHours:
+    """Regular session of an exchange in its local time, Monday to Friday."""
+
+    timezone: str
+    open: time
+    close: time
+
+    def sessions(self, start: date, days: int) -> list[tuple[datetime, datetime]]:
+        """(open, close) in UTC for each weekday from `start` on."""
+        zone = ZoneInfo(self.timezone)
+        sessions = []
+        for offset in range(days):
+            day = start + timedelta(days=offset)
+            if day.weekday() >= 5:
+                continue
+            opens = datetime.combine(day, self.open, tzinfo=zone).astimezone(timezone.utc)
+            closes = datetime.combine(day, self.close, tzinfo=zone).astimezone(timezone.utc)
+            sessions.append((opens, closes))
+        return sessions
+
+
+# Keyed by broker market code (`n2`).
+EXCHANGE_HOURS = {
+    "NYSE": ExchangeHours("America/New_York", time(9, 30), time(16, 0)),
+    "NASDAQ": ExchangeHours("America/New_York", time(9, 30), time(16, 0)),
+    "XETRA": ExchangeHours("Europe/Berlin", time(9, 0), time(17, 30)),
+    "EU": ExchangeHours("Europe/Paris", time(9, 0), time(17, 30)),
+    "LSE": ExchangeHours("Europe/London", time(8, 0), time(16, 30)),
+    "ATHEX": ExchangeHours("Europe/Athens", time(10, 0), time(17, 20)),
+    "HKEX": ExchangeHours("Asia/Hong_Kong", time(9, 30), time(16, 0)),
+}
+
+
+def session_at(market: str, now: datetime) -> tuple[datetime, datetime] | None:
+    """The regular session of `market` in progress at `now` (aware), if any."""
+    hours = EXCHANGE_HOURS[market]
+    local_day = now.astimezone(ZoneInfo(hours.timezone)).date()
+    for opens, closes in hours.sessions(local_day, 1):
+        if opens <= now < closes:
+            return opens, closes
+    return None
+
+
+def next_open(market: str, now: datetime) -> datetime:
+    """The next regular open of `market` strictly after `now`, in UTC."""
+    hours = EXCHANGE_HOURS[market]
+    local_day = now.astimezone(ZoneInfo(hours.timezone)).date()
+    return next(opens for opens, _ in hours.sessions(local_day, 8) if opens > now)
+
+
+def next_close(market: str, now: datetime) -> datetime:
+    """The next regular close of `market` strictly after `now`, in UTC."""
+    hours = EXCHANGE_HOURS[market]
+    local_day = now.astimezone(ZoneInfo(hours.timezone)).date()
+    return next(closes for _, closes in hours.sessions(local_day, 8) if closes > now)
+
 
 class MarketChecker(Protocol):
     """Protocol for checking market status."""
@@ -27,22 +110,27 @@ class MarketChecker(Protocol):
         """Check if all markets are closed."""
         ...
 
+    def market_states(self) -> dict[str, bool]:
+        """Map each broker market id to whether it is open."""
+        ...
+
 
 class BrokerMarketChecker:
     """Real market checker using broker API with automatic refresh."""
 
-    def __init__(self, broker, ttl: timedelta = MARKET_DATA_TTL):
+    def __init__(self, broker, ttl: timedelta = MARKET_DATA_TTL, clock: Clock | None = None):
         self._broker = broker
         self._market_data: dict = {}
         self._last_fetch: Optional[datetime] = None
         self._ttl = ttl
+        self._clock = clock or SystemClock()
         self._refresh_in_progress = False
 
     def _is_stale(self) -> bool:
         """Check if market data needs refresh."""
         if self._last_fetch is None:
             return True
-        return datetime.now() - self._last_fetch > self._ttl
+        return self._clock.now() - self._last_fetch > self._ttl
 
     async def refresh(self) -> None:
         """Fetch current market status from broker."""
@@ -53,7 +141,7 @@ class BrokerMarketChecker:
             data = await self._broker.get_market_status("*")
             if data:
                 self._market_data = {m.get("n2"): m for m in data.get("m", [])}
-                self._last_fetch = datetime.now()
+                self._last_fetch = self._clock.now()
                 logger.debug(f"Market data refreshed: {len(self._market_data)} markets")
         except Exception as e:
             logger.warning(f"Failed to refresh market data: {e}")
@@ -73,9 +161,7 @@ class BrokerMarketChecker:
         """Check if the market for a specific security is open."""
         if "." not in symbol:
             return False
-        suffix = symbol.split(".")[-1]
-        market_map = {"US": "NASDAQ", "GR": "XETRA", "L": "LSE"}
-        market_name = market_map.get(suffix)
+        market_name = SYMBOL_SUFFIX_MARKETS.get(symbol.split(".")[-1])
         if not market_name:
             return False
         market = self._market_data.get(market_name)
@@ -86,3 +172,105 @@ class BrokerMarketChecker:
         if not self._market_data:
             return True
         return all(m.get("s") != "OPEN" for m in self._market_data.values())
+
+    def market_states(self) -> dict[str, bool]:
+        """Map each broker market id to whether it is open."""
+        return {str(m["i"]): m.get("s") == "OPEN" for m in self._market_data.values() if m.get("i") is not None}
+
+
+class CalendarMarketChecker:
+    """Market checker driven by EXCHANGE_HOURS and a clock instead of the broker.
+
+    Market ids are the exchange codes. Used to simulate market opens and
+    closes with a FakeClock; holidays are not modelled.
+    """
+
+    def __init__(self, clock: Clock, markets: list[str] | None = None):
+        self._clock = clock
+        self._markets = list(markets or EXCHANGE_HOURS)
+
+    async def refresh(self) -> None:
+        pass
+
+    async def ensure_fresh(self) -> None:
+        pass
+
+    def is_any_market_open(self) -> bool:
+        return any(self.market_states().values())
+
+    def is_security_market_open(self, symbol: str) -> bool:
+        market = SYMBOL_SUFFIX_MARKETS.get(symbol.split(".")[-1]) if "." in symbol else None
+        return market in self._markets and session_at(market, self._clock.now()) is not None
+
+    def are_all_markets_closed(self) -> bool:
+        return not self.is_any_market_open()
+
+    def market_states(self) -> dict[str, bool]:
+        now = self._clock.now()
+        return {market: session_at(market, now) is not None for market in self._markets}
+
+
+def market_close_state_key(job_type: str) -> str:
+    """Planner-state key holding the markets a job already ran for since they closed."""
+    return f"market_close_runs:{job_type}"
+
+
+async def markets_due_after_close(db, market_checker: MarketChecker, job_type: str) -> tuple[set[str], set[str]]:
+    """Markets that closed since the job last ran for them.
+
+    Securities without a known market are tracked as UNKNOWN_MARKET, which
+    counts as closed once every market is closed.
+
+    Returns:
+        (due, done): market ids to run for now, and market ids already run
+        for that are still closed. A market drops out of `done` as soon as it
+        opens again, so it becomes due after its next close.
+    """
+    states = dict(market_checker.market_states())
+    if states:
+        states[UNKNOWN_MARKET] = any(states.values())
+    handled = set(await db.get_planner_state(market_close_state_key(job_type), []) or [])
+    done = {market_id for market_id in handled if states.get(market_id) is False}
+    due = {market_id for market_id, is_open in states.items() if not is_open} - done
+    return due, done
+
+
+async def universe_market_status(db, broker) -> dict:
+    """Status of the broker markets that securities in the universe trade on.
+
+    Returns:
+        {"markets": [{"name", "status", "is_open"}], "any_open": bool}; no
+        markets when the broker has no market data.
+    """
+    market_ids_needed = set()
+    for sec in await db.get_all_securities(active_only=True):
+        data = sec.get("data")
+        if data:
+            try:
+                sec_data = json.loads(data) if isinstance(data, str) else data
+                mkt_id = sec_data.get("mrkt", {}).get("mkt_id")
+                if mkt_id is not None:
+                    market_ids_needed.add(str(mkt_id))
+            except (json.JSONDecodeError, KeyError, TypeError, ValueError):
+                # Silently skip securities with malformed or missing market data
+                pass
+
+    market_data = await broker.get_market_status("*")
+    if not market_data:
+        return {"markets": [], "any_open": False}
+
+    markets = []
+    seen = set()
+    for m in market_data.get("m", []):
+        mkt_id = str(m.get("i", ""))
+        market_name = m.get("n2", mkt_id)
+        if mkt_id in market_ids_needed and market_name not in seen:
+            seen.add(market_name)
+            markets.append(
+                {
+                    "name": market_name,
+                    "status": m.get("s", "UNKNOWN"),
+                    "is_open": m.get("s") == "OPEN",
+                }
+            )
+    return {"markets": markets, "any_open": any(m["is_open"] for m in markets)}
@@ -4,24 +4,49 @@ from __future__ import annotations
 
 import asyncio
 import logging
-from datetime import datetime
+import math
+from datetime import datetime, timedelta, timezone
 from typing import Any, Callable
+from zoneinfo import ZoneInfo
 
 from apscheduler.executors.asyncio import AsyncIOExecutor
 from apscheduler.jobstores.memory import MemoryJobStore
 from apscheduler.schedulers.asyncio import AsyncIOScheduler
 from apscheduler.triggers.interval import IntervalTrigger
 
+from sentinel import artifacts
+from sentinel.clock import Clock, SystemClock, suspected_drift
+from sentinel.faults import injector
+from sentinel.governor import governor
+from sentinel.job_pause import JobPause
 from sentinel.jobs import tasks
+from sentinel.jobs.market import (
+    EXCHANGE_HOURS,
+    UNKNOWN_MARKET,
+    market_close_state_key,
+    markets_due_after_close,
+    next_close,
+    next_open,
+    session_at,
+)
+from sentinel.markets import get_security_market_ids
+from sentinel.performance import check_budget
+from sentinel.settings import Settings
+from sentinel.trading_pause import TRADING_JOBS, TradingPause
 
 logger = logging.getLogger(__name__)
 
 # Module-level state
 _scheduler: AsyncIOScheduler | None = None
 _deps: dict[str, Any] = {}
+# Time source for run durations, drain timing and the schedule audit.
+_clock: Clock = SystemClock()
 _current_job: str | None = None
 _market_check_task: asyncio.Task | None = None
 _startup_catchup_task: asyncio.Task | None = None
+# Running jobs: task -> (job_type, start time); drained on shutdown. Keyed by
+# task so a run_now overlapping a scheduled run of the same job drains both.
+_in_flight: dict[asyncio.Task, tuple[str, datetime]] = {}
 
 # Job timeout in seconds (15 minutes)
 JOB_TIMEOUT = 15 * 60
@@ -29,6 +54,31 @@ JOB_TIMEOUT = 15 * 60
 # How often to check market status and adjust intervals (5 minutes)
 MARKET_CHECK_INTERVAL = 5 * 60
 
+# Jobs whose failure sounds the critical buzzer alert on the LED display
+CRITICAL_ALERT_JOBS = {"trading:execute"}
+
+# How long shutdown waits for a running job before cancelling it (seconds).
+# Jobs that submit orders or write the ledger get longer. Jobs drain in
+# parallel, so the longest timeout must stay below systemd's TimeoutStopSec
+# (90s by default).
+DRAIN_TIMEOUTS = {
+    "trading:execute": 60,
+    "trading:balance_fix": 60,
+    "sync:portfolio": 30,
+    "sync:trades": 30,
+    "sync:cashflows": 30,
+    "sync:dividends": 30,
+    "backup:r2": 30,
+}
+DEFAULT_DRAIN_TIMEOUT = 10
+
+# Job types cancelled by a shutdown drain; rerun after the next startup,
+# except TRADING_JOBS (see _rerun_interrupted).
+INTERRUPTED_JOBS_KEY = "jobs:interrupted"
+
+# When true, the market status loop logs the schedule audit on every check.
+SCHEDULE_AUDIT_KEY = "job_schedule_audit"
+
 # Task registry: job_type -> (task_function, list of dependency keys)
 TASK_REGISTRY: dict[str, tuple[Callable, list[str]]] = {
     "sync:portfolio": (tasks.sync_portfolio, ["portfolio"]),
@@ -42,6 +92,7 @@ TASK_REGISTRY: dict[str, tuple[Callable, list[str]]] = {
     "sync:benchmarks": (tasks.sync_benchmarks, ["db", "broker"]),
     "decay:user_multipliers": (tasks.decay_user_multipliers, ["db"]),
     "snapshot:backfill": (tasks.snapshot_backfill, ["db", "currency"]),
+    "snapshot:valuation": (tasks.snapshot_valuation, ["db", "broker", "currency"]),
     "trading:check_markets": (tasks.trading_check_markets, ["broker", "db", "planner"]),
     "trading:execute": (tasks.trading_execute, ["broker", "db", "planner", "portfolio"]),
     "trading:rebalance": (tasks.trading_rebalance, ["planner"]),
@@ -49,7 +100,13 @@ TASK_REGISTRY: dict[str, tuple[Callable, list[str]]] = {
     "planning:refresh": (tasks.planning_refresh, ["db", "planner", "broker"]),
     "forecast:run": (tasks.forecast_run, ["db"]),
     "forecast:evaluate": (tasks.forecast_evaluate, ["db"]),
+    "sync:news": (tasks.sync_news, ["db"]),
+    "security:technical": (tasks.security_technical, ["db"]),
+    "security:liquidity": (tasks.security_liquidity, ["db"]),
+    "security:factors": (tasks.security_factors, ["db"]),
     "backup:r2": (tasks.backup_r2, ["db"]),
+    "system:clock_check": (tasks.system_clock_check, ["db"]),
+    "system:retention": (tasks.system_retention, ["db"]),
 }
 
 # Market timing constants (matching database values)
@@ -57,6 +114,12 @@ MARKET_TIMING_ANY_TIME = 0
 MARKET_TIMING_AFTER_MARKET_CLOSE = 1
 MARKET_TIMING_DURING_MARKET_OPEN = 2
 MARKET_TIMING_ALL_MARKETS_CLOSED = 3
+MARKET_TIMING_EACH_MARKET_CLOSE = 4
+
+# Jobs that accept a `symbols` subset and can therefore run per market with
+# MARKET_TIMING_EACH_MARKET_CLOSE. Other jobs treat that timing as
+# MARKET_TIMING_AFTER_MARKET_CLOSE.
+PER_MARKET_JOBS = frozenset({"sync:prices", "sync:quotes"})
 
 
 async def init(
@@ -67,6 +130,7 @@ async def init(
     cache,
     market_checker,
     currency,
+    clock: Clock | None = None,
 ) -> AsyncIOScheduler:
     """Create scheduler, load schedules from DB, add all jobs, start.
 
@@ -77,11 +141,14 @@ async def init(
         planner: Planner instance
         cache: Cache instance
         market_checker: MarketChecker instance
+        clock: Time source (defaults to the system clock)
 
     Returns:
         The running AsyncIOScheduler instance
     """
-    global _scheduler, _deps, _current_job, _market_check_task
+    global _scheduler, _deps, _current_job, _market_check_task, _clock
+
+    _clock = clock or SystemClock()
 
     # Store dependencies for task execution
     _deps = {
@@ -104,10 +171,12 @@ async def init(
         "misfire_grace_time": 60,  # Allow 60 seconds grace for missed runs
     }
 
+    # Interval triggers run in UTC so DST changes neither skip nor repeat runs.
     _scheduler = AsyncIOScheduler(
         jobstores=jobstores,
         executors=executors,
         job_defaults=job_defaults,
+        timezone=timezone.utc,
     )
 
     # Load schedules from database
@@ -130,6 +199,7 @@ async def init(
     # Start scheduler
     _scheduler.start()
     logger.info(f"APScheduler started with {len(TASK_REGISTRY)} jobs")
+    await _log_job_pauses()
 
     # Start background task to periodically check market status and adjust intervals
     _market_check_task = asyncio.create_task(_market_status_loop())
@@ -141,7 +211,7 @@ async def init(
 
 
 async def stop() -> None:
-    """Shutdown the scheduler."""
+    """Shutdown the scheduler, draining running jobs first (see drain)."""
     global _scheduler, _current_job, _market_check_task, _startup_catchup_task
 
     # Stop startup catch-up task
@@ -163,6 +233,9 @@ async def stop() -> None:
         _market_check_task = None
 
     if _scheduler:
+        # No new runs while draining; shutdown then cancels whatever is left.
+        _scheduler.pause()
+        await drain()
         _scheduler.shutdown(wait=False)
         _scheduler = None
         logger.info("APScheduler stopped")
@@ -170,6 +243,66 @@ async def stop() -> None:
     _current_job = None
 
 
+async def drain(timeouts: dict[str, float] | None = None) -> dict[str, list[dict]]:
+    """Let running jobs finish, then cancel the rest and queue them to rerun.
+
+    Each job gets its DRAIN_TIMEOUTS entry (DEFAULT_DRAIN_TIMEOUT otherwise).
+    Cancelled jobs are logged as interrupted and persisted under
+    INTERRUPTED_JOBS_KEY; the startup catch-up reruns them. Jobs only
+    persist their own progress on completion (e.g. the markets a per-market
+    sync has covered), so a rerun starts from the last completed run.
+
+    Returns:
+        {"drained": [...], "abandoned": [...]}, each entry
+        {"job_type", "elapsed_ms"} with the time spent before shutdown.
+    """
+    timeouts = DRAIN_TIMEOUTS if timeouts is None else timeouts
+    running = dict(_in_flight)
+    report: dict[str, list[dict]] = {"drained": [], "abandoned": []}
+    if not running:
+        return report
+    logger.info(f"Draining {len(running)} running job(s): {sorted(job_type for job_type, _ in running.values())}")
+
+    async def wait(job_type: str, task: asyncio.Task) -> bool:
+        done, _ = await asyncio.wait({task}, timeout=timeouts.get(job_type, DEFAULT_DRAIN_TIMEOUT))
+        return bool(done)
+
+    finished = await asyncio.gather(*(wait(job_type, task) for task, (job_type, _) in running.items()))
+    now = _clock.now()
+    cancelled = []
+    for (task, (job_type, started)), done in zip(running.items(), finished):
+        entry = {"job_type": job_type, "elapsed_ms": int((now - started).total_seconds() * 1000)}
+        report["drained" if done else "abandoned"].append(entry)
+        if not done:
+            task.cancel()
+            cancelled.append(task)
+
+    abandoned = [entry["job_type"] for entry in report["abandoned"]]
+    if abandoned:
+        await asyncio.gather(*cancelled, return_exceptions=True)
+        db = _deps.get("db")
+        if db:
+            queued = await db.get_planner_state(INTERRUPTED_JOBS_KEY, []) or []
+            await db.set_planner_state(INTERRUPTED_JOBS_KEY, sorted(set(queued) | set(abandoned)))
+
+    logger.info(
+        "Shutdown drain: drained %s, abandoned %s",
+        [e["job_type"] for e in report["drained"]] or "none",
+        abandoned or "none",
+    )
+    return report
+
+
+def is_running() -> bool:
+    """Whether the scheduler is started and dispatching jobs."""
+    return _scheduler is not None and _scheduler.running
+
+
+def current_job() -> str | None:
+    """The job type executing right now, if any."""
+    return _current_job
+
+
 async def reschedule(job_type: str, db) -> None:
     """Reload schedule from DB and update APScheduler.
 
@@ -224,17 +357,17 @@ async def run_now(job_type: str) -> dict:
     if not schedule:
         schedule = {"job_type": job_type, "market_timing": 0}
 
-    start = datetime.now()
+    start = _clock.now()
     try:
         result = await _run_task(job_type, schedule, skip_timing_check=True)
-        duration_ms = int((datetime.now() - start).total_seconds() * 1000)
+        duration_ms = int((_clock.now() - start).total_seconds() * 1000)
 
         if result and result.get("skipped"):
             return {"status": "skipped", "reason": result.get("reason", ""), "duration_ms": duration_ms}
 
         return {"status": "completed", "duration_ms": duration_ms}
     except Exception as e:
-        duration_ms = int((datetime.now() - start).total_seconds() * 1000)
+        duration_ms = int((_clock.now() - start).total_seconds() * 1000)
         return {"status": "failed", "error": str(e), "duration_ms": duration_ms}
 
 
@@ -289,7 +422,7 @@ async def get_status() -> dict:
                     {
                         "job_type": job_type,
                         "status": entry["status"],
-                        "executed_at": datetime.fromtimestamp(entry["executed_at"]).isoformat(),
+                        "executed_at": datetime.fromtimestamp(entry["executed_at"], tz=timezone.utc).isoformat(),
                     }
                 )
                 if len(recent) >= 3:
@@ -300,6 +433,95 @@ async def get_status() -> dict:
     return result
 
 
+async def schedule_audit(now: datetime | None = None) -> list[dict]:
+    """Project and log when each job may next run, in UTC and exchange-local time.
+
+    Market timing is projected from EXCHANGE_HOURS, so markets missing there,
+    holidays and half days are not accounted for; `eligible_now` is the
+    broker's live answer. Per-market jobs are projected to the next close of
+    any market.
+
+    Returns:
+        [{"job_type", "market_timing", "eligible_now", "next_run_utc",
+          "next_eligible_run_utc", "market", "market_timezone",
+          "next_eligible_run_local"}]; `market` is the exchange whose open or
+        close the projection waits for (None when none does).
+    """
+    now = now or _clock.now()
+    db = _deps.get("db")
+    market_checker = _deps.get("market_checker")
+    schedules = await db.get_job_schedules() if db else []
+    next_runs = {job.id: job.next_run_time for job in _scheduler.get_jobs()} if _scheduler else {}
+
+    entries = []
+    for schedule in schedules:
+        job_type = schedule["job_type"]
+        timing = schedule.get("market_timing", 0)
+        eligible, market = _eligible_at(job_type, timing, now)
+        interval = timedelta(minutes=_get_interval(schedule, _any_session_at(eligible)))
+        next_run = next_runs.get(job_type)
+        next_run = next_run.astimezone(timezone.utc) if next_run else None
+        run_at = _first_run_from(next_run, interval, eligible) if next_run else eligible
+        zone = EXCHANGE_HOURS[market].timezone if market else None
+        entry = {
+            "job_type": job_type,
+            "market_timing": timing,
+            "eligible_now": _check_market_timing(timing, market_checker) if market_checker else None,
+            "next_run_utc": next_run.isoformat() if next_run else None,
+            "next_eligible_run_utc": run_at.isoformat(),
+            "market": market,
+            "market_timezone": zone,
+            "next_eligible_run_local": run_at.astimezone(ZoneInfo(zone)).isoformat() if zone else None,
+        }
+        entries.append(entry)
+        logger.info(
+            "Schedule audit: %s next eligible %s UTC%s",
+            job_type,
+            run_at.strftime("%Y-%m-%d %H:%M"),
+            f" ({entry['next_eligible_run_local'][:16].replace('T', ' ')} {market} local)" if zone else "",
+        )
+    return entries
+
+
+def _any_session_at(moment: datetime) -> bool:
+    return any(session_at(market, moment) for market in EXCHANGE_HOURS)
+
+
+def _eligible_at(job_type: str, timing: int, now: datetime) -> tuple[datetime, str | None]:
+    """When `timing` next allows a run per EXCHANGE_HOURS, and the market deciding it."""
+    sessions = {market: session for market in EXCHANGE_HOURS if (session := session_at(market, now))}
+    if timing == MARKET_TIMING_DURING_MARKET_OPEN:
+        if sessions:
+            return now, min(sessions, key=lambda m: sessions[m][1])
+        market = min(EXCHANGE_HOURS, key=lambda m: next_open(m, now))
+        return next_open(market, now), market
+    if timing == MARKET_TIMING_EACH_MARKET_CLOSE and job_type in PER_MARKET_JOBS:
+        market = min(EXCHANGE_HOURS, key=lambda m: next_close(m, now))
+        return next_close(market, now), market
+    if timing in (
+        MARKET_TIMING_AFTER_MARKET_CLOSE,
+        MARKET_TIMING_EACH_MARKET_CLOSE,
+        MARKET_TIMING_ALL_MARKETS_CLOSED,
+    ):
+        # Wait for the last open market to close; another may open meanwhile.
+        moment, market = now, None
+        for _ in range(len(EXCHANGE_HOURS) + 1):
+            sessions = {m: session for m in EXCHANGE_HOURS if (session := session_at(m, moment))}
+            if not sessions:
+                break
+            market = max(sessions, key=lambda m: sessions[m][1])
+            moment = sessions[market][1]
+        return moment, market
+    return now, None
+
+
+def _first_run_from(next_run: datetime, interval: timedelta, eligible: datetime) -> datetime:
+    """The first interval tick from `next_run` on that is not before `eligible`."""
+    if next_run >= eligible:
+        return next_run
+    return next_run + interval * math.ceil((eligible - next_run) / interval)
+
+
 def _get_interval(schedule: dict, market_open: bool) -> int:
     """Determine the appropriate interval based on market status.
 
@@ -369,18 +591,50 @@ async def _run_task(job_type: str, schedule: dict, skip_timing_check: bool = Fal
         await market_checker.ensure_fresh()
 
     # Check market timing (unless skipped)
+    market_scope = None
+    drift_note = None
     if not skip_timing_check:
         market_timing = schedule.get("market_timing", 0)
-
-        if market_checker and not _check_market_timing(market_timing, market_checker):
+        drift_note = await _clock_drift_note(market_timing)
+
+        skip = False
+        if market_timing == MARKET_TIMING_EACH_MARKET_CLOSE and job_type in PER_MARKET_JOBS:
+            market_scope = await _market_close_scope(job_type, market_checker)
+            if market_scope is None:
+                logger.debug(f"Skipping {job_type}: no market closed since its last run")
+                skip = True
+        elif market_checker and not _check_market_timing(market_timing, market_checker):
             logger.debug(f"Skipping {job_type}: market timing not satisfied")
-            return {"skipped": True, "reason": "market_timing"}
+            skip = True
+
+        if drift_note:
+            logger.warning(f"{'Skipping' if skip else 'Running'} {job_type} on market timing with {drift_note}")
+        if skip:
+            result = {"skipped": True, "reason": "market_timing"}
+            return {**result, "clock_drift": drift_note} if drift_note else result
 
     # Get task function and dependencies
     if job_type not in TASK_REGISTRY:
         logger.error(f"Unknown job type: {job_type}")
         return {"skipped": True, "reason": "unknown_job_type"}
 
+    if job_type in TRADING_JOBS and await TradingPause().is_paused():
+        logger.info(f"Skipping {job_type}: trading is paused")
+        return {"skipped": True, "reason": "trading_paused"}
+
+    # Scheduled runs wait out job pauses and maintenance windows (see sentinel.job_pause)
+    if not skip_timing_check:
+        hold = await _job_hold(job_type)
+        if hold:
+            logger.info(f"Skipping {job_type}: {hold.replace('_', ' ')}")
+            return {"skipped": True, "reason": hold}
+
+    # Scheduled low-priority work waits for the load to ease (see sentinel.governor)
+    if not skip_timing_check:
+        await _load_governor()
+        if not await governor.admit(job_type):
+            return {"skipped": True, "reason": "resource_pressure"}
+
     task_func, dep_keys = TASK_REGISTRY[job_type]
 
     # Build arguments from dependencies
@@ -394,56 +648,191 @@ async def _run_task(job_type: str, schedule: dict, skip_timing_check: bool = Fal
 
     # Set current job
     _current_job = job_type
-    start = datetime.now()
+    start = _clock.now()
     db = _deps.get("db")
+    task = asyncio.current_task()
+    if task:
+        _in_flight[task] = (job_type, start)
+    collected = artifacts.begin_run()
 
     try:
+        await _load_faults()
+        injector.maybe_fail("job_failure", job_type)
+
         # Execute with timeout
-        await asyncio.wait_for(task_func(*args), timeout=JOB_TIMEOUT)
+        kwargs = {"symbols": market_scope[0]} if market_scope else {}
+        await asyncio.wait_for(task_func(*args, **kwargs), timeout=JOB_TIMEOUT)
 
-        duration_ms = int((datetime.now() - start).total_seconds() * 1000)
+        duration_ms = int((_clock.now() - start).total_seconds() * 1000)
 
         # Log success to DB
         if db:
+            if market_scope:
+                await db.set_planner_state(market_close_state_key(job_type), sorted(market_scope[1]))
             await db.mark_job_completed(job_type)
-            await db.log_job_execution(job_type, job_type, "completed", None, duration_ms, 0)
+            run_id = await db.log_job_execution(job_type, job_type, "completed", drift_note, duration_ms, 0)
+            await artifacts.store_artifacts(db, run_id, job_type, collected)
 
         logger.info(f"Job {job_type} completed in {duration_ms}ms")
+        await _check_budget(job_type, duration_ms)
         return {"status": "completed", "duration_ms": duration_ms}
 
     except asyncio.TimeoutError:
-        duration_ms = int((datetime.now() - start).total_seconds() * 1000)
+        duration_ms = int((_clock.now() - start).total_seconds() * 1000)
         error_msg = f"Job {job_type} timed out after {JOB_TIMEOUT}s"
         logger.error(error_msg)
 
         if db:
             await db.mark_job_failed(job_type)
-            await db.log_job_execution(job_type, job_type, "failed", error_msg, duration_ms, 0)
+            run_id = await db.log_job_execution(job_type, job_type, "failed", error_msg, duration_ms, 0)
+            await artifacts.store_artifacts(db, run_id, job_type, collected)
 
+        await _alert_job_failure(job_type)
         return {"status": "failed", "error": error_msg, "duration_ms": duration_ms}
 
     except Exception as e:
-        duration_ms = int((datetime.now() - start).total_seconds() * 1000)
+        duration_ms = int((_clock.now() - start).total_seconds() * 1000)
         error_msg = str(e)
         logger.error(f"Job {job_type} failed: {error_msg}")
 
         if db:
             await db.mark_job_failed(job_type)
-            await db.log_job_execution(job_type, job_type, "failed", error_msg, duration_ms, 0)
+            run_id = await db.log_job_execution(job_type, job_type, "failed", error_msg, duration_ms, 0)
+            await artifacts.store_artifacts(db, run_id, job_type, collected)
 
+        await _alert_job_failure(job_type)
         return {"status": "failed", "error": error_msg, "duration_ms": duration_ms}
 
+    except asyncio.CancelledError:
+        duration_ms = int((_clock.now() - start).total_seconds() * 1000)
+        logger.warning(f"Job {job_type} interrupted after {duration_ms}ms")
+        if db:
+            await db.log_job_execution(job_type, job_type, "interrupted", "cancelled", duration_ms, 0)
+        raise
+
     finally:
+        artifacts.end_run()
+        if task:
+            _in_flight.pop(task, None)
         _current_job = None
 
 
+async def _market_close_scope(job_type: str, market_checker) -> tuple[list[str], set[str]] | None:
+    """Securities to run a per-market job for, and the markets that covers.
+
+    Returns None when no market closed since the job last ran for it.
+    """
+    db = _deps.get("db")
+    if db is None or market_checker is None:
+        return None
+    due, done = await markets_due_after_close(db, market_checker, job_type)
+    known = set(market_checker.market_states())
+    symbols = sorted(
+        symbol
+        for symbol, market_id in (await get_security_market_ids(db)).items()
+        if (market_id if market_id in known else UNKNOWN_MARKET) in due
+    )
+    if not symbols:
+        # Nothing to sync for these markets; record them so they aren't re-checked until they reopen.
+        await db.set_planner_state(market_close_state_key(job_type), sorted(done | due))
+        return None
+    logger.info(f"{job_type}: running for {len(symbols)} securities in markets closed since last run: {sorted(due)}")
+    return symbols, done | due
+
+
+async def _clock_drift_note(market_timing: int) -> str | None:
+    """Annotation for a market timing decision made while the clock is suspect.
+
+    Jobs that run at any time do not depend on the clock and get no note.
+    """
+    db = _deps.get("db")
+    if db is None or market_timing == MARKET_TIMING_ANY_TIME:
+        return None
+    try:
+        offset = await suspected_drift(db)
+    except Exception as e:
+        logger.debug(f"Clock drift state unavailable: {e}")
+        return None
+    return None if offset is None else f"suspected clock drift ({offset:+.0f}s)"
+
+
+async def _alert_job_failure(job_type: str) -> None:
+    """Queue the critical buzzer alert for failures of critical jobs."""
+    if job_type not in CRITICAL_ALERT_JOBS:
+        return
+    try:
+        from sentinel.led.alerts import ALERT_CRITICAL, AlertManager
+
+        await AlertManager().trigger(ALERT_CRITICAL)
+    except Exception as e:
+        logger.warning(f"Failed to queue critical alert for {job_type}: {e}")
+
+
+async def _check_budget(job_type: str, duration_ms: int) -> None:
+    """Warn when a completed run exceeded its duration budget."""
+    try:
+        await check_budget(job_type, duration_ms)
+    except Exception as e:
+        logger.debug(f"Duration budget check for {job_type} failed: {e}")
+
+
+async def _load_faults() -> None:
+    """Reload the fault injection settings (see sentinel.faults)."""
+    try:
+        await injector.load(Settings().get)
+    except Exception as e:
+        logger.debug(f"Failed to load fault injection settings: {e}")
+
+
+async def _job_hold(job_type: str) -> str | None:
+    """Why a scheduled run has to wait (a job pause or maintenance window), or None."""
+    try:
+        return await JobPause().blocks(job_type)
+    except Exception as e:
+        logger.debug(f"Failed to read job pauses: {e}")
+        return None
+
+
+async def _log_job_pauses() -> None:
+    """Warn at startup about pauses that survived the restart."""
+    try:
+        status = await JobPause().status()
+    except Exception as e:
+        logger.debug(f"Failed to read job pauses: {e}")
+        return
+    if status["all"] is not None:
+        logger.warning(f"All scheduled jobs are paused ({status['all'].get('reason') or 'no reason given'})")
+    if status["jobs"]:
+        logger.warning(f"Paused jobs: {', '.join(status['jobs'])}")
+
+
+async def _load_governor() -> None:
+    """Reload the resource governor settings (see sentinel.governor)."""
+    try:
+        await governor.load(Settings().get)
+    except Exception as e:
+        logger.debug(f"Failed to load resource governor settings: {e}")
+
+
 async def _startup_catchup() -> None:
-    """Run snapshot backfill shortly after startup to catch up on missed days.
+    """Check the clock, then run snapshot backfill to catch up on missed days.
 
+    The clock check runs straight away so market timing decisions made soon
+    after a boot with a wrong clock are already flagged (see sentinel.clock).
     IntervalTrigger with 1440-min intervals won't fire until 24h after startup,
     so if the app restarts frequently, the daily backfill never gets a chance to run.
     This ensures missing snapshots are filled promptly after each restart.
+
+    Jobs the last shutdown interrupted (see drain) are rerun next, subject to
+    their market timing and the trading pause like a scheduled run. Trading
+    jobs are not rerun; their next scheduled run starts by reconciling the
+    order that may have been submitted.
     """
+    try:
+        result = await run_now("system:clock_check")
+        logger.info("Startup clock check: %s", result.get("status", "unknown"))
+    except Exception as e:
+        logger.error("Startup clock check failed: %s", e)
     await asyncio.sleep(30)  # Let other services stabilize
     logger.info("Startup catch-up: running snapshot:backfill")
     try:
@@ -451,6 +840,34 @@ async def _startup_catchup() -> None:
         logger.info("Startup snapshot backfill: %s", result.get("status", "unknown"))
     except Exception as e:
         logger.error("Startup snapshot backfill failed: %s", e)
+    await _rerun_interrupted()
+
+
+async def _rerun_interrupted() -> None:
+    """Rerun the jobs the last shutdown drain cancelled, except TRADING_JOBS.
+
+    A trading job cancelled after the broker accepted its order but before the
+    submission was recorded would send the order again if rerun blindly.
+    """
+    db = _deps.get("db")
+    if not db:
+        return
+    interrupted = await db.get_planner_state(INTERRUPTED_JOBS_KEY, []) or []
+    if not interrupted:
+        return
+    await db.set_planner_state(INTERRUPTED_JOBS_KEY, [])
+    for job_type in interrupted:
+        if job_type not in TASK_REGISTRY:
+            continue
+        if job_type in TRADING_JOBS:
+            logger.warning(f"Startup catch-up: not rerunning interrupted {job_type}; it runs again on schedule")
+            continue
+        schedule = await db.get_job_schedule(job_type) or {"job_type": job_type, "market_timing": 0}
+        logger.info(f"Startup catch-up: rerunning interrupted {job_type}")
+        try:
+            await _run_task(job_type, schedule)
+        except Exception as e:
+            logger.error(f"Rerun of interrupted {job_type} failed: {e}")
 
 
 async def _market_status_loop() -> None:
@@ -478,6 +895,9 @@ async def _market_status_loop() -> None:
 
             market_open = market_checker.is_any_market_open()
 
+            if await Settings().get(SCHEDULE_AUDIT_KEY, False):
+                await schedule_audit()
+
             # If market status changed, reschedule all jobs
             if last_market_open is not None and market_open != last_market_open:
                 logger.info(f"Market status changed: {'OPEN' if market_open else 'CLOSED'}, adjusting job intervals")
@@ -542,7 +962,7 @@ def _check_market_timing(timing: int, market_checker) -> bool:
     """
     if timing == MARKET_TIMING_ANY_TIME:
         return True
-    elif timing == MARKET_TIMING_AFTER_MARKET_CLOSE:
+    elif timing in (MARKET_TIMING_AFTER_MARKET_CLOSE, MARKET_TIMING_EACH_MARKET_CLOSE):
         return not market_checker.is_any_market_open()
     elif timing == MARKET_TIMING_DURING_MARKET_OPEN:
         return market_checker.is_any_market_open()
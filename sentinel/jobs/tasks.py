@@ -9,14 +9,29 @@ import os
 import tarfile
 import tempfile
 import time
-from dataclasses import asdict
+from dataclasses import asdict, replace
 from datetime import datetime, timedelta, timezone
 from pathlib import Path
 from typing import Any
 
+from sentinel.artifacts import add_artifact
+from sentinel.broker_symbols import detect_broker_symbols, order_warnings
+from sentinel.governor import governor
+from sentinel.identifiers import IdentifierService
 from sentinel.markets import get_open_market_symbols
+from sentinel.planner.liquidity import load_liquidity_policy
 from sentinel.planner.models import TradeRecommendation
 from sentinel.planner.rebalance_rules import buy_rank_key
+from sentinel.planner.review import filter_rejected, get_rejections
+from sentinel.planner.savings import (
+    NEW_MONEY_DAYS_KEY,
+    NEW_MONEY_REASON_CODE,
+    book_new_money_fill,
+    process_savings_deposits,
+)
+from sentinel.planner.snapshots import record_snapshot
+from sentinel.price_sanity import PriceSanityChecker
+from sentinel.price_staging import PriceStaging
 
 logger = logging.getLogger(__name__)
 
@@ -86,28 +101,49 @@ async def _fetch_historical_prices_in_chunks(
 
 
 async def sync_portfolio(portfolio) -> None:
-    """Sync portfolio positions from broker."""
+    """Sync portfolio positions from broker, then check for shorts and margin."""
     await portfolio.sync()
     logger.info("Portfolio sync complete")
+    await portfolio.check_exposure()
 
 
-async def sync_prices(db, broker, cache) -> None:
-    """Sync historical prices for all securities."""
+async def _active_symbols(db, only: list[str] | None) -> list[str]:
+    securities = await db.get_all_securities(active_only=True)
+    symbols = [s["symbol"] for s in securities]
+    if only is None:
+        return symbols
+    wanted = set(only)
+    return [symbol for symbol in symbols if symbol in wanted]
+
+
+async def sync_prices(db, broker, cache, symbols: list[str] | None = None) -> None:
+    """Sync historical prices for all securities, or only `symbols`.
+
+    The scheduler passes `symbols` when the job runs per market close.
+    """
     # Clear analysis cache since prices are changing
     cleared = cache.clear()
     logger.info(f"Cleared {cleared} cached analyses before price sync")
 
-    securities = await db.get_all_securities(active_only=True)
-    symbols = [s["symbol"] for s in securities]
+    symbols = await _active_symbols(db, symbols)
 
     prices = await _fetch_historical_prices_in_chunks(broker, symbols, years=20, label="security")
+    checker = PriceSanityChecker(db)
+    staging = PriceStaging(db)
+    await staging.begin()
     synced = 0
 
     for symbol in symbols:
         data = prices.get(symbol) or []
+        if not data:
+            continue
+        data = await staging.stage(symbol, data)
+        if data:
+            data = await checker.check_prices(symbol, data)
+        await staging.merge(symbol, data)
         if data:
-            await db.save_prices(symbol, data)
             synced += 1
+    await staging.finish()
 
     if symbols and synced == 0:
         raise RuntimeError(f"Price sync returned no usable prices for {len(symbols)} securities")
@@ -115,21 +151,22 @@ async def sync_prices(db, broker, cache) -> None:
     logger.info(f"Price sync complete: {synced}/{len(symbols)} securities updated")
 
 
-async def sync_quotes(db, broker) -> None:
-    """Sync quote data for all securities."""
-    securities = await db.get_all_securities(active_only=True)
-    symbols = [s["symbol"] for s in securities]
+async def sync_quotes(db, broker, symbols: list[str] | None = None) -> None:
+    """Sync quote data for all securities, or only `symbols`."""
+    symbols = await _active_symbols(db, symbols)
 
     if not symbols:
         logger.info("No securities to sync quotes for")
         return
 
     quotes = await broker.get_quotes(symbols)
+    if not quotes:
+        logger.warning("No quotes returned from broker")
+        return
+    quotes = await PriceSanityChecker(db).check_quotes(quotes)
     if quotes:
         await db.update_quotes_bulk(quotes)
-        logger.info(f"Quote sync complete: {len(quotes)} securities")
-    else:
-        logger.warning("No quotes returned from broker")
+    logger.info(f"Quote sync complete: {len(quotes)} securities")
 
 
 ETF_INSTR_KIND_C = 7  # Tradernet instr_kind_c for ETF/fund units.
@@ -200,6 +237,16 @@ async def sync_metadata(db, broker) -> None:
         finally:
             await asyncio.sleep(SYNC_METADATA_PACING_S)
 
+    # Metadata carries the ISIN (`issue_nb`), so new listings can be mapped now.
+    try:
+        mapped = await detect_broker_symbols(db)
+        if mapped["added"] or mapped["updated"] or mapped["removed"]:
+            logger.info(
+                f"Broker symbols: {len(mapped['added'])} added, {len(mapped['updated'])} updated, "
+                f"{len(mapped['removed'])} removed"
+            )
+    except Exception as e:
+        logger.warning(f"sync_metadata: broker symbol detection failed: {e}")
     logger.info(f"Metadata sync complete: {synced} securities")
 
 
@@ -390,10 +437,13 @@ async def sync_trades(db, broker) -> None:
 
     new_count = 0
     skipped_count = 0
+    identifiers = IdentifierService(db)
 
     for trade in trades:
         trade_id = str(trade.get("id", ""))
         symbol = trade.get("symbol", trade.get("instr_nm", ""))
+        if symbol:
+            symbol = await identifiers.canonical(symbol)
         side = trade.get("side", "BUY")
         quantity = float(trade.get("q", 0))
         price = float(trade.get("p", 0))
@@ -436,6 +486,8 @@ async def sync_cashflows(db, broker) -> None:
 
     Fetches all cash flows from Tradernet since 2020-01-01 and upserts them.
     Existing entries are deduplicated using a content hash of the raw data.
+    Entries of a generic type are then classified by description rules
+    (sentinel.cash_flow_rules).
     """
     if not broker.connected:
         logger.warning("Broker not connected, skipping cashflows sync")
@@ -481,6 +533,23 @@ async def sync_cashflows(db, broker) -> None:
 
     logger.info(f"Cash flows sync complete: {new_count} new, {skipped_count} existing")
 
+    try:
+        from sentinel.cash_flow_rules import CashFlowClassifier
+
+        await CashFlowClassifier(db).classify_pending()
+    except Exception as e:
+        logger.warning(f"Failed to classify cash flows: {e}")
+
+    try:
+        from sentinel.currency import Currency
+
+        matched = await process_savings_deposits(db, Currency())
+        if matched:
+            logger.info(f"Matched {matched} savings plan deposit(s)")
+            await db.invalidate_planner_cache()
+    except Exception as e:
+        logger.warning(f"Failed to match savings plan deposits: {e}")
+
 
 async def sync_dividends(db, broker) -> None:
     """
@@ -557,6 +626,14 @@ async def snapshot_backfill(db, currency) -> None:
     await service.backfill()
 
 
+async def snapshot_valuation(db, broker, currency) -> None:
+    """Record a live valuation snapshot for the portfolio history charts."""
+    from sentinel.services.history import PortfolioHistoryService
+
+    data = await PortfolioHistoryService(db=db, broker=broker, currency=currency).record()
+    logger.info("Valuation snapshot recorded: %.2f EUR", data["total_value_eur"])
+
+
 # -----------------------------------------------------------------------------
 # Forecast Tasks
 # -----------------------------------------------------------------------------
@@ -792,6 +869,7 @@ async def forecast_evaluate(db) -> None:
     price_rows = await db.get_prices_bulk(symbols, days=36500)
     evaluated = 0
     for row in candidates:
+        await governor.checkpoint()
         actual = realized_return_after_weeks(
             price_rows.get(row["symbol"], []),
             start_ts=int(row["started_at"]),
@@ -811,6 +889,111 @@ async def forecast_evaluate(db) -> None:
     logger.info("Forecast evaluation complete: %s/%s evaluated", evaluated, len(candidates))
 
 
+async def sync_news(db) -> None:
+    """Ingest news headlines for active securities and refresh their sentiment."""
+    from sentinel.news import RssNewsSource, ingest_news
+    from sentinel.settings import Settings
+
+    settings = Settings()
+    if not bool(await settings.get("news_enabled", False)):
+        logger.info("News ingestion disabled")
+        return
+    url_template = str(await settings.get("news_feed_url_template", "") or "").strip()
+    if not url_template:
+        raise RuntimeError("news_feed_url_template is empty")
+
+    securities = await db.get_all_securities(active_only=True)
+    result = await ingest_news(
+        db,
+        RssNewsSource(url_template),
+        securities,
+        now_ts=int(time.time()),
+        half_life_days=float(await settings.get("news_half_life_days", 3) or 3),
+        max_age_days=int(await settings.get("news_max_age_days", 30) or 30),
+    )
+    if securities and result["failed"] == len(securities):
+        raise RuntimeError(f"News feed failed for all {len(securities)} securities")
+    logger.info(
+        "News sync complete: %s headlines for %s securities (%s failed)",
+        result["headlines"],
+        result["securities"],
+        result["failed"],
+    )
+
+
+async def security_technical(db) -> None:
+    """Bring every active security's technical indicators up to its latest price."""
+    from sentinel.indicators import update_indicators
+
+    securities = await db.get_all_securities(active_only=True)
+    rows = 0
+    failed = []
+    for security in securities:
+        await governor.checkpoint()
+        try:
+            rows += await update_indicators(db, security["symbol"])
+        except Exception as e:
+            logger.warning(f"Indicator update failed for {security['symbol']}: {e}")
+            failed.append(security["symbol"])
+    if securities and len(failed) == len(securities):
+        raise RuntimeError(f"Indicator update failed for all {len(securities)} securities")
+    logger.info(f"Indicators updated: {rows} rows for {len(securities) - len(failed)} securities")
+
+
+async def security_liquidity(db) -> None:
+    """Measure each active security's average daily volume and flag illiquid ones."""
+    from sentinel.currency import Currency
+    from sentinel.planner.volume import ADV_LOOKBACK_DAYS_KEY, MIN_ADV_TURNOVER_KEY, update_security_volume
+    from sentinel.settings import Settings
+
+    settings = Settings()
+    lookback = max(1, int(await settings.get(ADV_LOOKBACK_DAYS_KEY, 20) or 20))
+    min_turnover = float(await settings.get(MIN_ADV_TURNOVER_KEY, 0.0) or 0.0)
+    currency = Currency()
+
+    securities = await db.get_all_securities(active_only=True)
+    measured = 0
+    newly_illiquid = []
+    for security in securities:
+        await governor.checkpoint()
+        try:
+            fields = await update_security_volume(db, security, lookback, min_turnover, currency.to_eur)
+        except Exception as e:
+            logger.warning(f"Volume update failed for {security['symbol']}: {e}")
+            continue
+        if fields is None:
+            continue
+        measured += 1
+        if fields["illiquid"] and not security.get("illiquid"):
+            newly_illiquid.append(f"{security['symbol']} ({fields['adv_turnover_eur']:.0f} EUR/day)")
+    if newly_illiquid:
+        logger.warning(
+            f"Securities below the {min_turnover:.0f} EUR daily turnover floor, no longer bought: "
+            + ", ".join(newly_illiquid)
+        )
+    logger.info(f"Average daily volume updated for {measured}/{len(securities)} securities")
+
+
+async def security_factors(db) -> None:
+    """Regress each active security's returns on the index factors and store its exposures."""
+    from sentinel.factors import load_factor_returns, update_security_factors
+
+    factors = await load_factor_returns(db)
+    if not factors:
+        logger.info("No benchmark prices, factor exposures not updated")
+        return
+    securities = await db.get_all_securities(active_only=True)
+    measured = 0
+    for security in securities:
+        await governor.checkpoint()
+        try:
+            if await update_security_factors(db, security, factors) is not None:
+                measured += 1
+        except Exception as e:
+            logger.warning(f"Factor regression failed for {security['symbol']}: {e}")
+    logger.info(f"Factor exposures updated for {measured}/{len(securities)} securities")
+
+
 # Trading Tasks
 # -----------------------------------------------------------------------------
 
@@ -901,38 +1084,163 @@ async def trading_execute(broker, db, planner, portfolio) -> None:
         eligible_symbols=open_symbols,
         track_fallback_state=is_live,
     )
+    await _record_planner_snapshot(db, planner, recommendations, "trading:execute")
     if not recommendations:
         logger.info("No trade recommendations")
         return
 
-    # Filter to actionable (open markets only)
-    actionable = [r for r in recommendations if r.symbol in open_symbols]
+    # Filter to actionable (open markets only, not manually rejected)
+    rejections = await get_rejections(db)
+    actionable = filter_rejected([r for r in recommendations if r.symbol in open_symbols], rejections)
     if not actionable:
         logger.info("No actionable trades for open markets")
         return
 
     next_trade = min(actionable, key=_execution_order_key)
+    blocked = await liquidity_block_reason(portfolio, next_trade)
     if not is_live:
         logger.info(
             f"Trading mode is '{trading_mode}', would {next_trade.action.upper()}: "
             f"{next_trade.quantity} x {next_trade.symbol} @ {next_trade.price:.2f} {next_trade.currency}"
+            + (f" (blocked in live mode: {blocked})" if blocked else "")
         )
         return
+    if blocked:
+        logger.warning(f"Not submitting BUY {next_trade.symbol}: {blocked}")
+        return
+
+    await submit_trade(db, broker, next_trade)
+
+
+async def liquidity_block_reason(portfolio, rec: TradeRecommendation) -> str | None:
+    """Re-check the liquidity policy against live cash right before a buy.
+
+    Returns why the buy would breach the cash reserve, or None when it may go
+    ahead. Sells always pass; so do buys when the account state is unavailable.
+    """
+    if rec.action != "buy":
+        return None
+    cash = await portfolio.total_cash_eur()
+    total_value = await portfolio.total_value()
+    if not isinstance(cash, int | float) or not isinstance(total_value, int | float):
+        return None
+
+    from sentinel.currency import Currency
+    from sentinel.settings import Settings
+    from sentinel.utils.fees import FeeCalculator
+
+    async def to_eur(amount: float, currency: str) -> float:
+        return await Currency().to_eur(amount, currency)
+
+    settings = Settings()
+    policy = await load_liquidity_policy(settings.get)
+    reserve = await policy.reserve(float(total_value), to_eur)
+    value = abs(float(rec.value_delta_eur))
+    cost = value + await FeeCalculator(settings).calculate(value, currency=rec.currency)
+    cash_after = float(cash) - cost
+    if cash_after >= reserve["total_eur"]:
+        return None
+    return (
+        f"cash after trade {cash_after:.2f} EUR would fall below the "
+        f"{reserve['total_eur']:.2f} EUR liquidity reserve"
+    )
 
-    order_id = await _execute_trade(broker, next_trade)
+
+async def _open_order_for(db, broker, symbol: str) -> dict | None:
+    """A recorded open order for the same ISIN as `symbol`, after syncing order statuses."""
+    from sentinel.orders import OrderService
+
+    try:
+        service = OrderService(db, broker)
+        if broker.connected:
+            await service.sync()
+        pending = await service.open_order_for(symbol)
+    except Exception as e:
+        logger.warning(f"Failed to check open orders for {symbol}: {e}")
+        return None
+    return pending if isinstance(pending, dict) else None
+
+
+async def _execution_throttle(db, broker, rec: TradeRecommendation):
+    """(throttle or None, reason the order must wait or None, quantity to place now)."""
+    from sentinel.execution import ExecutionThrottle, load_execution_policy
+    from sentinel.settings import Settings
+
+    try:
+        policy = await load_execution_policy(Settings().get)
+        if not policy.active:
+            return None, None, rec.quantity
+        throttle = ExecutionThrottle(db, broker, policy)
+        blocked, quantity = await throttle.check(rec)
+    except Exception as e:
+        logger.warning(f"Failed to apply the execution policy to {rec.symbol}: {e}")
+        return None, None, rec.quantity
+    return throttle, blocked, quantity
+
+
+async def submit_trade(db, broker, rec: TradeRecommendation, throttled: bool = True) -> str | None:
+    """Submit one recommendation and record it for broker reconciliation.
+
+    With `throttled`, the execution policy may hold the order back or submit
+    only a TWAP slice of it (see sentinel.execution).
+
+    Returns the broker order ID, or None if the order was not accepted.
+    """
+    try:
+        await order_warnings(db, rec.symbol)
+    except Exception as e:
+        logger.warning(f"Failed to check the broker symbol of {rec.symbol}: {e}")
+    pending = await _open_order_for(db, broker, rec.symbol)
+    if pending:
+        logger.warning(
+            f"Not submitting {rec.action.upper()} {rec.symbol}: order {pending['order_id']} "
+            f"for the same security is still {pending['status']}"
+        )
+        return None
+    throttle, blocked, quantity = (None, None, rec.quantity)
+    if throttled:
+        throttle, blocked, quantity = await _execution_throttle(db, broker, rec)
+    if blocked:
+        logger.info(f"Holding {rec.action.upper()} {rec.symbol} back: {blocked}")
+        return None
+    parent = rec
+    if quantity < rec.quantity:
+        rec = replace(rec, quantity=quantity, value_delta_eur=rec.value_delta_eur * quantity / rec.quantity)
+        logger.info(f"Submitting TWAP slice of {quantity}/{parent.quantity} x {rec.symbol}")
+    order_id = await _execute_trade(broker, rec)
     if not order_id:
-        return
+        return None
+    if throttle is not None:
+        try:
+            await throttle.record(parent)
+        except Exception as e:
+            logger.warning(f"Failed to advance the TWAP schedule of {rec.symbol}: {e}")
+    try:
+        from sentinel.orders import OrderService
+
+        await OrderService(db, broker).record(order_id, rec.symbol, rec.action, rec.quantity, source="planner")
+    except Exception as e:
+        logger.warning(f"Failed to record order {order_id}: {e}")
 
     await db.set_planner_state(
         SUBMITTED_TRADE_STATE_KEY,
         {
             "order_id": str(order_id),
             "submitted_at": int(time.time()),
-            "recommendation": asdict(next_trade),
+            "recommendation": asdict(rec),
         },
     )
     await db.invalidate_planner_cache()
 
+    try:
+        from sentinel.led.alerts import ALERT_TRADE_EXECUTED, AlertManager
+        from sentinel.settings import Settings
+
+        await AlertManager(Settings()).trigger(ALERT_TRADE_EXECUTED)
+    except Exception as e:
+        logger.warning(f"Failed to queue trade alert: {e}")
+    return order_id
+
 
 async def trading_rebalance(planner) -> None:
     """Check if portfolio needs rebalancing and generate recommendations."""
@@ -948,6 +1256,7 @@ async def trading_rebalance(planner) -> None:
         recommendations = await planner.get_recommendations()
         for rec in recommendations:
             logger.warning(f"  {rec.action.upper()} {rec.symbol}: EUR {abs(rec.value_delta_eur):.0f} ({rec.reason})")
+        add_artifact("rebalance", {"summary": summary, "recommendations": recommendations})
     else:
         logger.info("Portfolio is balanced")
 
@@ -978,9 +1287,11 @@ async def trading_balance_fix(db, broker) -> None:
 
     if not negative:
         logger.info("All currency balances are non-negative")
+        await _resolve_negative_balance_event(db)
         return
 
     logger.warning(f"Found negative balances: {negative}")
+    await _explain_negative_balances(db, negative)
 
     if not positive:
         logger.error("No positive currency balances available for conversion")
@@ -1051,6 +1362,68 @@ async def trading_balance_fix(db, broker) -> None:
             logger.warning(f"Could not fully cover {neg_currency} deficit. Remaining: {deficit_eur:.2f} EUR")
 
 
+async def _explain_negative_balances(db, negative: dict[str, float]) -> None:
+    """Record the negative-balance event with a ledger root cause per currency; never blocks the fix.
+
+    A new event (first seen, or a different set of currencies) sounds the
+    critical alert; the causes are logged with it and kept under
+    NEGATIVE_BALANCE_STATE_KEY for /api/portfolio/ledger/negative-balance.
+    """
+    from sentinel.ledger import NEGATIVE_BALANCE_STATE_KEY, LedgerService
+
+    try:
+        now = int(time.time())
+        previous = await db.get_planner_state(NEGATIVE_BALANCE_STATE_KEY)
+        ongoing = (
+            isinstance(previous, dict)
+            and previous.get("resolved_at") is None
+            and set(previous.get("balances") or {}) == set(negative)
+        )
+        service = LedgerService(db)
+        analyses = {}
+        for currency, amount in negative.items():
+            analysis = await service.explain_negative_balance(currency, live_balance=amount)
+            analyses[currency] = analysis
+            cause = analysis.get("cause")
+            if cause:
+                logger.warning(
+                    f"{currency} negative since {analysis['negative_since']}: {cause['kind']} "
+                    f"{cause['description']} ({cause['amount']:+.2f}, "
+                    f"balance {analysis['balance_before']:.2f} -> {cause['balance']:.2f})"
+                )
+            else:
+                logger.warning(f"{currency} is negative at the broker but not in the ledger replay; cause unknown")
+        await db.set_planner_state(
+            NEGATIVE_BALANCE_STATE_KEY,
+            {
+                "detected_at": previous["detected_at"] if ongoing else now,
+                "updated_at": now,
+                "resolved_at": None,
+                "balances": negative,
+                "analyses": analyses,
+            },
+        )
+        if not ongoing:
+            from sentinel.led.alerts import ALERT_CRITICAL, AlertManager
+            from sentinel.settings import Settings
+
+            await AlertManager(Settings()).trigger(ALERT_CRITICAL)
+    except Exception as e:
+        logger.warning(f"Failed to explain negative balances: {e}")
+
+
+async def _resolve_negative_balance_event(db) -> None:
+    """Mark the open negative-balance event resolved once every balance is back at or above zero."""
+    from sentinel.ledger import NEGATIVE_BALANCE_STATE_KEY
+
+    try:
+        event = await db.get_planner_state(NEGATIVE_BALANCE_STATE_KEY)
+        if isinstance(event, dict) and event.get("resolved_at") is None:
+            await db.set_planner_state(NEGATIVE_BALANCE_STATE_KEY, {**event, "resolved_at": int(time.time())})
+    except Exception as e:
+        logger.warning(f"Failed to resolve negative balance event: {e}")
+
+
 async def planning_refresh(db, planner, broker) -> None:
     """Refresh trading plan by clearing caches and regenerating recommendations."""
     from sentinel.universe import reconcile_universe_from_freedom24_default_list
@@ -1062,6 +1435,9 @@ async def planning_refresh(db, planner, broker) -> None:
     if universe_result.changed:
         logger.info("Freedom24 universe reconciliation changed state: %s", universe_result.as_dict())
 
+    await _refresh_goals(planner)
+    await _refresh_income(planner)
+
     # Clear planner-related caches
     cleared = await db.cache_clear("planner:")
     logger.info(f"Cleared {cleared} planner cache entries")
@@ -1069,12 +1445,81 @@ async def planning_refresh(db, planner, broker) -> None:
     # Regenerate ideal portfolio (this will cache the result)
     ideal = await planner.calculate_ideal_portfolio()
     logger.info(f"Recalculated ideal portfolio with {len(ideal)} securities")
+    add_artifact("ideal_weights", ideal)
 
     # Regenerate recommendations (this will cache the result)
     recommendations = await planner.get_recommendations()
+    await _record_planner_snapshot(db, planner, recommendations, "planning:refresh")
     buys = [r for r in recommendations if r.action == "buy"]
     sells = [r for r in recommendations if r.action == "sell"]
     logger.info(f"Generated {len(recommendations)} recommendations: {len(buys)} buys, {len(sells)} sells")
+    add_artifact("recommendations", recommendations)
+    await _check_concentration(db, planner, broker)
+
+
+async def _refresh_goals(planner) -> None:
+    """Project investment goals for the planner's goal tilt; never blocks planning."""
+    try:
+        projection = await planner.get_goal_projection(store=True)
+    except Exception as e:
+        logger.warning("Failed to project investment goals: %s", e)
+        return
+    behind = [goal["name"] for goal in projection["goals"] if not goal["on_track"]]
+    if behind:
+        logger.info(f"Goals behind schedule: {', '.join(behind)} (planner tilt {projection['tilt']:.2f})")
+
+
+async def _refresh_income(planner) -> None:
+    """Project dividend income for the planner's income objective; never blocks planning."""
+    try:
+        projection = await planner.get_income_projection(store=True)
+    except Exception as e:
+        logger.warning("Failed to project dividend income: %s", e)
+        return
+    if projection["on_track"] is False:
+        logger.info(
+            f"Dividend income {projection['annual_income_eur']:.2f} EUR is short of the "
+            f"{projection['target_annual_income_eur']:.2f} EUR target (planner tilt {projection['tilt']:.2f})"
+        )
+
+
+async def _record_planner_snapshot(db, planner, recommendations, source: str) -> None:
+    """Keep the cycle's inputs and batch for /api/planning/diff; never blocks planning."""
+    try:
+        await record_snapshot(db, planner, recommendations, source)
+    except Exception as e:
+        logger.warning("Failed to record planner snapshot for %s: %s", source, e)
+
+
+async def _check_concentration(db, planner, broker) -> None:
+    """Track concentration breaches for /api/portfolio/concentration; never blocks planning."""
+    from sentinel.concentration import check_concentration
+
+    try:
+        await check_concentration(db, planner, broker)
+    except Exception as e:
+        logger.warning("Failed to check position concentration: %s", e)
+
+
+# -----------------------------------------------------------------------------
+# System Tasks
+# -----------------------------------------------------------------------------
+
+
+async def system_clock_check(db) -> None:
+    """Compare the system clock with NTP and flag drift beyond the threshold."""
+    from sentinel.clock import ClockMonitor
+
+    await ClockMonitor(db).check()
+
+
+async def system_retention(db) -> None:
+    """Delete or archive rows past their table's retention policy, and prune old job artifacts."""
+    from sentinel.artifacts import prune_artifacts
+    from sentinel.retention import run_retention
+
+    add_artifact("report", await run_retention(db))
+    await prune_artifacts(db)
 
 
 # -----------------------------------------------------------------------------
@@ -1198,6 +1643,14 @@ async def _reconcile_submitted_trade(db) -> bool:
             executed_at=executed_at or None,
             executed_price=weighted_price,
         )
+        if rec.reason_code == NEW_MONEY_REASON_CODE:
+            try:
+                from sentinel.settings import Settings
+
+                window_days = await Settings().get(NEW_MONEY_DAYS_KEY, 30)
+                await book_new_money_fill(db, rec, filled_quantity, weighted_price, float(window_days or 0))
+            except Exception as e:
+                logger.warning(f"Failed to book new-money buy of {rec.symbol}: {e}")
         await db.delete_planner_state(SUBMITTED_TRADE_STATE_KEY)
         logger.info("Confirmed broker fill for order %s", order_id)
         return True
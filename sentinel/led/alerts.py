@@ -0,0 +1,132 @@
+"""
+Audible alerts for the LED display's buzzer.
+
+Alerts are queued server-side and delivered with the batched display state
+(see sentinel.led.display_state): each alert gets a new sequence number and
+the MCU plays the pattern once when it sees a sequence it hasn't played yet.
+The beep patterns themselves live in the sketch; only the code crosses the
+wire:
+  - trade_executed: two short beeps
+  - critical:       three long beeps
+
+Non-critical alerts are dropped during quiet hours (`led_buzzer_quiet_hours`,
+local HH:MM, may wrap midnight). Critical alerts always sound unless the
+buzzer is disabled. Alerts not delivered within ALERT_TTL_SEC are discarded so
+a reconnecting bridge doesn't beep about something long past.
+"""
+
+from __future__ import annotations
+
+import time
+from datetime import datetime
+from typing import Any
+
+from sentinel.led.modes import in_window, parse_hhmm
+from sentinel.settings import Settings
+
+ALERT_TRADE_EXECUTED = "trade_executed"
+ALERT_CRITICAL = "critical"
+
+# Wire codes sent to the MCU; 0 means "nothing to play".
+ALERT_CODES = {ALERT_TRADE_EXECUTED: 1, ALERT_CRITICAL: 2}
+
+ENABLED_KEY = "led_buzzer_enabled"
+QUIET_HOURS_KEY = "led_buzzer_quiet_hours"
+LAST_ALERT_KEY = "led_buzzer_last_alert"
+ALERT_TTL_SEC = 300
+
+
+def validate_quiet_hours(value: Any) -> dict[str, str] | None:
+    """Validate a quiet hours window; None disables quiet hours.
+
+    Raises:
+        ValueError: If the window is malformed.
+    """
+    if value is None:
+        return None
+    if not isinstance(value, dict):
+        raise ValueError("quiet_hours must be an object with 'start' and 'end', or null")
+    start, end = value.get("start"), value.get("end")
+    if parse_hhmm(start) == parse_hhmm(end):
+        raise ValueError("quiet_hours start and end must differ")
+    return {"start": start.strip(), "end": end.strip()}
+
+
+class AlertManager:
+    """Queues buzzer alerts, honouring the enabled flag and quiet hours."""
+
+    def __init__(self, settings: Settings | None = None):
+        self._settings = settings or Settings()
+
+    async def get_config(self, now: datetime | None = None) -> dict[str, Any]:
+        """Get buzzer settings and whether quiet hours are currently active."""
+        enabled = bool(await self._settings.get(ENABLED_KEY, True))
+        quiet_hours = await self.get_quiet_hours()
+        return {
+            "enabled": enabled,
+            "quiet_hours": quiet_hours,
+            "in_quiet_hours": self._in_quiet_hours(quiet_hours, now),
+            "patterns": list(ALERT_CODES),
+        }
+
+    async def set_enabled(self, enabled: bool) -> None:
+        await self._settings.set(ENABLED_KEY, bool(enabled))
+
+    async def get_quiet_hours(self) -> dict[str, str] | None:
+        """Get the quiet hours window, or None if unset or invalid."""
+        try:
+            return validate_quiet_hours(await self._settings.get(QUIET_HOURS_KEY))
+        except ValueError:
+            return None
+
+    async def set_quiet_hours(self, value: Any) -> dict[str, str] | None:
+        """Validate and persist the quiet hours window (None disables it)."""
+        normalized = validate_quiet_hours(value)
+        await self._settings.set(QUIET_HOURS_KEY, normalized)
+        return normalized
+
+    async def trigger(self, pattern: str, now: datetime | None = None, force: bool = False) -> dict[str, Any]:
+        """Queue an alert for the next display state push.
+
+        Args:
+            pattern: One of ALERT_CODES.
+            now: Local time used for the quiet hours check (defaults to now).
+            force: Bypass the enabled flag and quiet hours (used by the test API).
+
+        Returns:
+            Dict with `status` ('queued' or 'suppressed'), plus `seq` when
+            queued or `reason` when suppressed.
+        """
+        if pattern not in ALERT_CODES:
+            raise ValueError(f"unknown pattern {pattern!r}; expected one of {list(ALERT_CODES)}")
+        now = now or datetime.now()
+
+        if not force:
+            if not await self._settings.get(ENABLED_KEY, True):
+                return {"status": "suppressed", "reason": "disabled"}
+            if pattern != ALERT_CRITICAL and self._in_quiet_hours(await self.get_quiet_hours(), now):
+                return {"status": "suppressed", "reason": "quiet_hours"}
+
+        previous = await self._settings.get(LAST_ALERT_KEY)
+        seq = int(previous.get("seq", 0)) + 1 if isinstance(previous, dict) else 1
+        alert = {"seq": seq, "pattern": pattern, "ts": int(now.timestamp())}
+        await self._settings.set(LAST_ALERT_KEY, alert)
+        return {"status": "queued", **alert}
+
+    async def pending(self, now_ts: int | None = None) -> dict[str, int]:
+        """Alert fields for the display state: the last sequence and its code.
+
+        The code is 0 once the alert is older than ALERT_TTL_SEC.
+        """
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        alert = await self._settings.get(LAST_ALERT_KEY)
+        if not isinstance(alert, dict) or alert.get("pattern") not in ALERT_CODES:
+            return {"alert_seq": 0, "alert": 0}
+        fresh = now_ts - int(alert.get("ts", 0)) <= ALERT_TTL_SEC
+        return {"alert_seq": int(alert.get("seq", 0)), "alert": ALERT_CODES[alert["pattern"]] if fresh else 0}
+
+    @staticmethod
+    def _in_quiet_hours(quiet_hours: dict[str, str] | None, now: datetime | None) -> bool:
+        if not quiet_hours:
+            return False
+        return in_window(quiet_hours["start"], quiet_hours["end"], now or datetime.now())
@@ -8,7 +8,9 @@ methods by calling `$/register`.
 
 from __future__ import annotations
 
+import logging
 import socket
+import time
 from dataclasses import dataclass
 from typing import Any, Callable, Final
 
@@ -18,6 +20,8 @@ REQUEST: Final[int] = 0
 RESPONSE: Final[int] = 1
 NOTIFY: Final[int] = 2
 
+logger = logging.getLogger(__name__)
+
 
 @dataclass(frozen=True)
 class RpcError(Exception):
@@ -44,6 +48,41 @@ class UnixMsgpackRpc:
         self._sock = s
         self._unpacker = msgpack.Unpacker()
 
+    def connect_with_retry(
+        self,
+        initial_delay: float = 1.0,
+        max_delay: float = 60.0,
+        sleep: Callable[[float], None] = time.sleep,
+    ) -> None:
+        """Connect, retrying with exponential backoff until the socket is available.
+
+        The router socket may not exist yet when the MPU boots (or may be
+        restarted underneath us), so callers should never treat a failed
+        connect as fatal.
+        """
+        delay = initial_delay
+        attempt = 1
+        while True:
+            try:
+                self.connect()
+                return
+            except OSError as e:
+                logger.warning(
+                    "arduino-router socket %s unavailable (attempt %d): %s; retrying in %.0fs",
+                    self._sock_path,
+                    attempt,
+                    e,
+                    delay,
+                )
+                self.close()
+                sleep(delay)
+                delay = min(max_delay, delay * 2)
+                attempt += 1
+
+    @property
+    def connected(self) -> bool:
+        return self._sock is not None
+
     def close(self) -> None:
         if self._sock is not None:
             try:
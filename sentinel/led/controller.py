@@ -1,8 +1,15 @@
 """
-LED Controller - Displays trade recommendations as scrolling text.
+LED Controller - Displays scrolling text on the LED matrix.
 
-Fetches trade recommendations from the Planner and displays them
-one at a time on the LED matrix as scrolling text.
+The active display mode comes from ModeManager (time-of-day schedule or
+manual override):
+  - ticker: trade recommendations from the Planner, one at a time, followed
+            by the YTD performance relative to the first benchmark
+  - health: broker and bridge connectivity
+  - stats:  portfolio value
+
+While trading is paused, the pause notice replaces every mode. The text of
+each line comes from TickerContentService, in the configured locale.
 """
 
 import asyncio
@@ -10,7 +17,10 @@ import logging
 from typing import Optional
 
 from sentinel.led.bridge import LEDBridge
+from sentinel.led.modes import MODE_HEALTH, MODE_STATS, MODE_TICKER, ModeManager
 from sentinel.led.state import Trade
+from sentinel.led.ticker import TickerContentService
+from sentinel.localization import LOCALES, Locale
 from sentinel.planner import Planner
 from sentinel.settings import Settings
 
@@ -18,10 +28,10 @@ logger = logging.getLogger(__name__)
 
 
 class LEDController:
-    """Controller for LED trade display.
+    """Controller for LED text display.
 
-    Fetches trade recommendations and displays them as scrolling
-    text on the Arduino UNO Q LED matrix.
+    Renders the active display mode as scrolling text on the
+    Arduino UNO Q LED matrix.
     """
 
     SYNC_INTERVAL = 300  # Refetch recommendations every 5 minutes
@@ -30,6 +40,10 @@ class LEDController:
         self._planner = Planner()
         self._settings = Settings()
         self._bridge = LEDBridge()
+        self._modes = ModeManager(self._settings)
+        self._content = TickerContentService(self._settings, self._planner)
+        self._locale: Locale = LOCALES["en"]
+        self._mode = MODE_TICKER
         self._trades: list[Trade] = []
         self._running = False
         self._task: Optional[asyncio.Task] = None
@@ -62,49 +76,61 @@ class LEDController:
         logger.info("LED controller stopped")
 
     async def _fetch_and_display(self) -> None:
-        """Fetch trade recommendations and display them."""
+        """Resolve the active display mode and render it."""
+        try:
+            self._mode = await self._modes.current_mode()
+        except Exception as e:
+            logger.warning(f"Failed to resolve display mode, using ticker: {e}")
+            self._mode = MODE_TICKER
+
+        try:
+            self._locale = await self._content.locale()
+        except Exception as e:
+            logger.warning(f"Failed to resolve locale, using English: {e}")
+            self._locale = LOCALES["en"]
+
+        pause_text = await self._content.pause_text(self._locale)
+        if pause_text:
+            await self._display_text(pause_text)
+        elif self._mode == MODE_HEALTH:
+            await self._display_text(await self._content.health_text(self._locale))
+        elif self._mode == MODE_STATS:
+            await self._display_text(await self._content.stats_text(self._locale))
+        else:
+            await self._display_trades()
+
+    async def _display_text(self, text: str) -> None:
+        """Scroll a single status line, then wait for the next sync."""
+        try:
+            await self._bridge.set_text(text)
+            if self._running:
+                await asyncio.sleep(self.SYNC_INTERVAL)
+        except Exception as e:
+            logger.error(f"Error in LED display loop: {e}")
+            await asyncio.sleep(60)
+
+    async def _display_trades(self) -> None:
+        """Fetch trade recommendations and display the ticker lines."""
         try:
-            recommendations = await self._planner.get_recommendations()
+            self._trades = await self._content.trades()
 
-            if not recommendations:
-                logger.debug("No trade recommendations to display")
+            lines = await self._content.ticker_lines(self._locale, self._trades)
+
+            if not lines:
+                logger.debug("No ticker lines to display")
                 await asyncio.sleep(self.SYNC_INTERVAL)
                 return
 
-            # Convert recommendations to Trade objects
-            self._trades = []
-            for rec in recommendations:
-                if rec.action == "sell":
-                    # Calculate sell percentage
-                    if rec.current_value_eur > 0:
-                        sell_pct = (abs(rec.value_delta_eur) / rec.current_value_eur) * 100
-                    else:
-                        sell_pct = 100
-                    trade = Trade(
-                        action="SELL",
-                        amount=abs(rec.value_delta_eur),
-                        symbol=rec.symbol,
-                        sell_pct=sell_pct,
-                    )
-                else:
-                    trade = Trade(
-                        action="BUY",
-                        amount=rec.value_delta_eur,
-                        symbol=rec.symbol,
-                    )
-                self._trades.append(trade)
-
-            logger.info(f"Displaying {len(self._trades)} trade recommendations")
-
-            # Display each trade one at a time
-            for trade in self._trades:
+            logger.info(f"Displaying {len(lines)} ticker lines ({len(self._trades)} trade recommendations)")
+
+            # Display each line one at a time
+            for text in lines:
                 if not self._running:
                     break
 
-                text = trade.to_display_string()
                 await self._bridge.set_text(text)
 
-                # Small delay between trades
+                # Small delay between lines
                 await asyncio.sleep(1)
 
             # Wait before fetching new recommendations
@@ -124,6 +150,11 @@ class LEDController:
         """Check if controller is running."""
         return self._running
 
+    @property
+    def mode(self) -> str:
+        """Get the display mode applied on the last refresh."""
+        return self._mode
+
     @property
     def trade_count(self) -> int:
         """Get number of trades to display."""
@@ -0,0 +1,89 @@
+"""
+Desired LED display state, batched into one versioned frame.
+
+Instead of the bridge polling several endpoints and issuing one MCU call per
+element, the server assembles the complete display state here. The version is
+a content hash, so the bridge can skip the MCU call entirely when nothing
+visible has changed.
+
+Frame layout (MsgPack int array, sent via Bridge.call("applyState", frame)):
+    [version, value_eur, return_pct, has_recs, broker_connected, mode, alert_seq, alert]
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import zlib
+from typing import Any
+
+from sentinel.broker import Broker
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.led.alerts import AlertManager
+from sentinel.led.modes import MODE_CODES, ModeManager
+from sentinel.markets import get_open_market_symbols
+from sentinel.services.valuation import PortfolioValuationService
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+FRAME_FIELDS = ("value", "return_pct", "has_recs", "broker_connected", "mode", "alert_seq", "alert")
+MAX_DISPLAY_VALUE = 99999999
+
+
+def state_version(state: dict[str, int]) -> int:
+    """Content hash of a display state, kept positive to fit an MCU int32."""
+    encoded = json.dumps([state[field] for field in FRAME_FIELDS]).encode()
+    return zlib.crc32(encoded) & 0x7FFFFFFF
+
+
+def to_frame(state: dict[str, int]) -> list[int]:
+    """Flatten a display state into the applyState wire frame."""
+    return [state_version(state), *(int(state[field]) for field in FRAME_FIELDS)]
+
+
+async def build_display_state(
+    db: Database | None = None,
+    broker: Broker | None = None,
+    settings: Settings | None = None,
+    currency: Currency | None = None,
+) -> dict[str, Any]:
+    """Assemble the full desired display state.
+
+    Returns:
+        Dict with `version`, `state` (named fields) and `frame` (wire array).
+    """
+    db = db or Database()
+    broker = broker or Broker()
+    settings = settings or Settings()
+
+    valuation = await PortfolioValuationService(db=db, broker=broker, currency=currency).current()
+    value = max(0, min(MAX_DISPLAY_VALUE, round(float(valuation.get("total_value_eur") or 0.0))))
+    return_pct = max(-99, min(99, round(float(valuation.get("portfolio_return_pct") or 0.0))))
+
+    # Recommendations are optional; a planner failure must not blank the display.
+    has_recs = 0
+    try:
+        from sentinel.planner import Planner
+
+        min_trade_value = await settings.get("min_trade_value", default=100.0)
+        open_symbols = await get_open_market_symbols(broker, db)
+        recommendations = await Planner(db=db, broker=broker).get_recommendations(
+            min_trade_value=min_trade_value,
+            eligible_symbols=open_symbols,
+        )
+        has_recs = 1 if recommendations else 0
+    except Exception as e:
+        logger.warning(f"Failed to load recommendations for display state: {e}")
+
+    mode = await ModeManager(settings).current_mode()
+    state = {
+        "value": value,
+        "return_pct": return_pct,
+        "has_recs": has_recs,
+        "broker_connected": 1 if broker.connected else 0,
+        "mode": MODE_CODES[mode],
+        **(await AlertManager(settings).pending()),
+    }
+    return {"version": state_version(state), "state": state, "frame": to_frame(state)}
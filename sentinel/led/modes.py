@@ -0,0 +1,157 @@
+"""
+Display mode scheduling for the LED display.
+
+The display rotates between modes by time of day:
+  - ticker: scrolling trade recommendations (market hours)
+  - health: system/broker/bridge health (evening)
+  - stats:  portfolio value and return (overnight)
+
+Slots are stored in the `led_display_schedule` setting as a list of
+{"mode", "start", "end"} dicts with local HH:MM times. A slot whose end is
+before its start wraps past midnight. A manual override (with expiry) takes
+precedence over the schedule until it lapses.
+"""
+
+from __future__ import annotations
+
+import time
+from datetime import datetime
+from typing import Any
+
+from sentinel.settings import DEFAULTS, Settings
+
+MODE_TICKER = "ticker"
+MODE_HEALTH = "health"
+MODE_STATS = "stats"
+DISPLAY_MODES = (MODE_TICKER, MODE_HEALTH, MODE_STATS)
+
+# Wire codes sent to the MCU alongside the display payload.
+MODE_CODES = {MODE_TICKER: 0, MODE_HEALTH: 1, MODE_STATS: 2}
+
+SCHEDULE_KEY = "led_display_schedule"
+OVERRIDE_KEY = "led_display_mode_override"
+MAX_OVERRIDE_MINUTES = 7 * 24 * 60
+
+
+def parse_hhmm(value: Any) -> int:
+    """Parse "HH:MM" into minutes after midnight."""
+    if not isinstance(value, str):
+        raise ValueError(f"time must be an 'HH:MM' string, got {value!r}")
+    parts = value.strip().split(":")
+    if len(parts) != 2 or not all(p.isdigit() for p in parts):
+        raise ValueError(f"time must be 'HH:MM', got {value!r}")
+    hours, minutes = int(parts[0]), int(parts[1])
+    if hours > 23 or minutes > 59:
+        raise ValueError(f"time out of range: {value!r}")
+    return hours * 60 + minutes
+
+
+def validate_schedule(slots: Any) -> list[dict[str, str]]:
+    """Validate and normalize a list of schedule slots.
+
+    Raises:
+        ValueError: If the schedule is malformed.
+    """
+    if not isinstance(slots, list) or not slots:
+        raise ValueError("schedule must be a non-empty list of slots")
+
+    normalized: list[dict[str, str]] = []
+    for i, slot in enumerate(slots):
+        if not isinstance(slot, dict):
+            raise ValueError(f"slot {i} must be an object")
+        mode = slot.get("mode")
+        if mode not in DISPLAY_MODES:
+            raise ValueError(f"slot {i} has unknown mode {mode!r}; expected one of {list(DISPLAY_MODES)}")
+        start = parse_hhmm(slot.get("start"))
+        end = parse_hhmm(slot.get("end"))
+        if start == end:
+            raise ValueError(f"slot {i} start and end must differ")
+        normalized.append({"mode": mode, "start": slot["start"].strip(), "end": slot["end"].strip()})
+    return normalized
+
+
+def in_window(start: str, end: str, now: datetime) -> bool:
+    """Whether `now` (local time) falls in [start, end); wraps midnight if end < start."""
+    minute = now.hour * 60 + now.minute
+    start_min = parse_hhmm(start)
+    end_min = parse_hhmm(end)
+    if start_min < end_min:
+        return start_min <= minute < end_min
+    return minute >= start_min or minute < end_min
+
+
+def mode_for_time(slots: list[dict[str, str]], now: datetime, fallback: str = MODE_TICKER) -> str:
+    """Return the mode of the first slot covering `now` (local time)."""
+    for slot in slots:
+        if in_window(slot["start"], slot["end"], now):
+            return slot["mode"]
+    return fallback
+
+
+class ModeManager:
+    """Resolves the active display mode from schedule and manual override."""
+
+    def __init__(self, settings: Settings | None = None):
+        self._settings = settings or Settings()
+
+    async def get_schedule(self) -> list[dict[str, str]]:
+        """Get the configured schedule, falling back to defaults if invalid."""
+        raw = await self._settings.get(SCHEDULE_KEY)
+        try:
+            return validate_schedule(raw)
+        except ValueError:
+            return list(DEFAULTS[SCHEDULE_KEY])
+
+    async def set_schedule(self, slots: Any) -> list[dict[str, str]]:
+        """Validate and persist a new schedule."""
+        normalized = validate_schedule(slots)
+        await self._settings.set(SCHEDULE_KEY, normalized)
+        return normalized
+
+    async def get_override(self, now_ts: int | None = None) -> dict[str, Any] | None:
+        """Get the active manual override, or None if unset or expired."""
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        raw = await self._settings.get(OVERRIDE_KEY)
+        if not isinstance(raw, dict) or raw.get("mode") not in DISPLAY_MODES:
+            return None
+        expires_at_ts = raw.get("expires_at_ts")
+        if not isinstance(expires_at_ts, int) or expires_at_ts <= now_ts:
+            return None
+        return {"mode": raw["mode"], "expires_at_ts": expires_at_ts}
+
+    async def set_override(self, mode: str, minutes: int, now_ts: int | None = None) -> dict[str, Any]:
+        """Force a display mode for `minutes` minutes."""
+        if mode not in DISPLAY_MODES:
+            raise ValueError(f"unknown mode {mode!r}; expected one of {list(DISPLAY_MODES)}")
+        if isinstance(minutes, bool) or not isinstance(minutes, int) or minutes < 1 or minutes > MAX_OVERRIDE_MINUTES:
+            raise ValueError(f"minutes must be an integer in [1, {MAX_OVERRIDE_MINUTES}]")
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        override = {"mode": mode, "expires_at_ts": now_ts + minutes * 60}
+        await self._settings.set(OVERRIDE_KEY, override)
+        return override
+
+    async def clear_override(self) -> None:
+        """Remove any manual override so the schedule applies again."""
+        await self._settings.set(OVERRIDE_KEY, None)
+
+    async def resolve(self, now: datetime | None = None) -> dict[str, Any]:
+        """Resolve the active mode.
+
+        Returns:
+            Dict with `mode`, `source` ('override' or 'schedule') and the
+            override expiry (if any).
+        """
+        now = now or datetime.now()
+        override = await self.get_override(now_ts=int(now.timestamp()))
+        if override:
+            return {
+                "mode": override["mode"],
+                "source": "override",
+                "override_expires_at_ts": override["expires_at_ts"],
+            }
+        schedule = await self.get_schedule()
+        return {"mode": mode_for_time(schedule, now), "source": "schedule", "override_expires_at_ts": None}
+
+    async def current_mode(self, now: datetime | None = None) -> str:
+        """Return just the active mode name."""
+        return (await self.resolve(now))["mode"]
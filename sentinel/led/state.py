@@ -7,6 +7,8 @@ that get sent to the MCU as scrolling text.
 
 from dataclasses import dataclass
 
+from sentinel.localization import Locale
+
 
 @dataclass
 class Trade:
@@ -24,17 +26,25 @@ class Trade:
     symbol: str
     sell_pct: float = 0.0
 
-    def to_display_string(self) -> str:
-        """Format trade for LED display.
+    def to_display_string(self, locale: Locale | None = None) -> str:
+        """Format trade for LED display, in `locale` when one is configured.
 
         Returns:
             Formatted string like:
             - "SELL $1,874.62 (51%) BYD.285.AS"
             - "BUY $645.75 AMD.EU"
+            - "BUY EUR 645.75 AMD.EU" (en)
+            - "KAUFEN 645,75 EUR AMD.EU" (de)
         """
-        amount_str = f"${self.amount:,.2f}"
+        if locale is None:
+            amount_str = f"${self.amount:,.2f}"
+            if self.action == "SELL":
+                return f"SELL {amount_str} ({int(self.sell_pct)}%) {self.symbol}"
+            return f"BUY {amount_str} {self.symbol}"
+
+        amount_str = locale.money(self.amount, ascii_only=True)
 
         if self.action == "SELL":
-            return f"SELL {amount_str} ({int(self.sell_pct)}%) {self.symbol}"
+            return f"{locale.ticker_text('sell')} {amount_str} ({locale.percent(int(self.sell_pct), 0)}) {self.symbol}"
         else:
-            return f"BUY {amount_str} {self.symbol}"
+            return f"{locale.ticker_text('buy')} {amount_str} {self.symbol}"
@@ -0,0 +1,245 @@
+"""
+Ticker content - the scrolling text lines of the LED display modes.
+
+Every line is built in the configured locale (see sentinel.localization)
+and folded to ASCII for the matrix font:
+  - ticker: one line per trade recommendation, then the YTD performance
+            relative to the first benchmark
+  - health: broker and bridge connectivity
+  - stats:  portfolio value
+  - the trading-pause notice, which replaces every mode while trading is paused
+
+The ticker mode's lines can be replaced by a user template (the
+`ticker_template` setting): an ordered list of segments, each scrolled as one
+line, such as
+
+    [{"text": "PORTFOLIO {total_value} {day_change_pct}"},
+     {"text": "{pending_trades} TRADES, NEXT {next_action}", "when": "pending_trades"},
+     {"text": "MARKET {regime}"}]
+
+Placeholders without data show as "-"; a segment with `when` is skipped
+unless that placeholder has data. An empty template keeps the built-in lines.
+"""
+
+from __future__ import annotations
+
+import logging
+import string
+from datetime import datetime
+from typing import Any
+
+from sentinel.benchmark_analytics import (
+    BENCHMARK_SYMBOLS_KEY,
+    build_benchmark_analytics,
+    ticker_text,
+    validate_benchmark_symbols,
+)
+from sentinel.broker import Broker
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.led.state import Trade
+from sentinel.localization import Locale, ascii_text, load_locale
+from sentinel.planner import Planner
+from sentinel.portfolio import Portfolio
+from sentinel.services.valuation import PortfolioValuationService
+from sentinel.settings import Settings
+from sentinel.trading_pause import TradingPause
+
+logger = logging.getLogger(__name__)
+
+TICKER_TEMPLATE_KEY = "ticker_template"
+PLACEHOLDERS = ("total_value", "day_change_pct", "next_action", "pending_trades", "trades", "regime", "benchmark")
+MAX_SEGMENTS = 10
+MAX_SEGMENT_LENGTH = 200
+MISSING = "-"
+# The regime compares the first benchmark's last close with its average over this many closes.
+REGIME_WINDOW = 200
+
+
+def template_placeholders(text: str) -> list[str]:
+    """Placeholder names used in a segment's text.
+
+    Raises:
+        ValueError: If a placeholder is unknown, has a format spec, or braces don't match.
+    """
+    try:
+        fields = [(name, spec, conversion) for _, name, spec, conversion in string.Formatter().parse(text)]
+    except ValueError as e:
+        raise ValueError(f"invalid template text {text!r}: {e}") from e
+    names = []
+    for name, spec, conversion in fields:
+        if name is None:
+            continue
+        if name not in PLACEHOLDERS:
+            raise ValueError(f"unknown placeholder {{{name}}}; use one of {', '.join(PLACEHOLDERS)}")
+        if spec or conversion:
+            raise ValueError(f"placeholder {{{name}}} cannot have a format")
+        names.append(name)
+    return names
+
+
+def validate_ticker_template(raw: Any) -> list[dict[str, Any]]:
+    """Validate `ticker_template`; None or [] keeps the built-in ticker lines.
+
+    Raises:
+        ValueError: If it is not a list of at most MAX_SEGMENTS {"text", "when"} segments.
+    """
+    if raw is None:
+        return []
+    if not isinstance(raw, list) or len(raw) > MAX_SEGMENTS:
+        raise ValueError(f"{TICKER_TEMPLATE_KEY} must be a list of at most {MAX_SEGMENTS} segments")
+    segments = []
+    for segment in raw:
+        if not isinstance(segment, dict) or set(segment) - {"text", "when"}:
+            raise ValueError('each segment must be an object with "text" and an optional "when"')
+        text = segment.get("text")
+        if not isinstance(text, str) or not 1 <= len(text.strip()) <= MAX_SEGMENT_LENGTH:
+            raise ValueError(f"segment text must be 1-{MAX_SEGMENT_LENGTH} characters")
+        template_placeholders(text)
+        when = segment.get("when")
+        if when is not None and when not in PLACEHOLDERS:
+            raise ValueError(f"segment 'when' must be one of {', '.join(PLACEHOLDERS)}")
+        segments.append({"text": text.strip(), "when": when})
+    return segments
+
+
+def render_template(segments: list[dict[str, Any]], values: dict[str, str]) -> list[str]:
+    """The lines of a validated template, folded to ASCII."""
+    filled = {name: values.get(name) or MISSING for name in PLACEHOLDERS}
+    return [
+        ascii_text(segment["text"].format(**filled))
+        for segment in segments
+        if not segment.get("when") or values.get(segment["when"])
+    ]
+
+
+def market_regime(rows: list[dict], window: int = REGIME_WINDOW) -> str | None:
+    """"bull" when the last close is at or above its `window`-close average, else "bear"; None without the history."""
+    closes = [float(row["close"]) for row in sorted(rows, key=lambda r: r["date"]) if row.get("close") is not None]
+    if len(closes) < window:
+        return None
+    return "bull" if closes[-1] >= sum(closes[-window:]) / window else "bear"
+
+
+class TickerContentService:
+    """Builds the LED display's text lines."""
+
+    def __init__(self, settings: Settings | None = None, planner: Planner | None = None):
+        self._settings = settings or Settings()
+        self._planner = planner or Planner()
+
+    async def locale(self) -> Locale:
+        return await load_locale(self._settings)
+
+    async def pause_text(self, locale: Locale) -> str | None:
+        """The trading-pause notice, e.g. "TRADING PAUSED UNTIL 14:00"; None while trading."""
+        try:
+            pause = await TradingPause(self._settings).status()
+        except Exception as e:
+            logger.warning(f"Failed to read trading pause for LED: {e}")
+            return None
+        if not pause["paused"]:
+            return None
+        if pause["expires_at_ts"] is None:
+            return locale.ticker_text("trading_paused")
+        expires = datetime.fromtimestamp(pause["expires_at_ts"])
+        return locale.ticker_text("trading_paused_until", time=locale.time(expires))
+
+    async def health_text(self, locale: Locale) -> str:
+        """The health-mode line (broker + bridge connectivity)."""
+        broker_ok = Broker().connected
+        bridge = await self._settings.get("led_bridge_health", {})
+        bridge_ok = bool(bridge.get("bridge_ok")) if isinstance(bridge, dict) else False
+        return locale.ticker_text(
+            "health",
+            broker=locale.text("ok" if broker_ok else "down"),
+            bridge=locale.text("ok" if bridge_ok else "down"),
+        )
+
+    async def stats_text(self, locale: Locale) -> str:
+        """The stats-mode line (portfolio value)."""
+        try:
+            total = await Portfolio().total_value()
+        except Exception as e:
+            logger.warning(f"Failed to read portfolio value for LED stats: {e}")
+            return locale.ticker_text("stats_unavailable")
+        return locale.ticker_text("portfolio", value=locale.money(total, decimals=0, ascii_only=True))
+
+    async def benchmark_text(self, locale: Locale) -> str | None:
+        """The ticker's relative-performance line, e.g. "vs SP500: +1.2% YTD"."""
+        try:
+            analytics = await build_benchmark_analytics(Database(), Currency(), self._settings)
+        except Exception as e:
+            logger.warning(f"Failed to compute benchmark performance for LED ticker: {e}")
+            return None
+        return ticker_text(analytics, locale)
+
+    async def trades(self) -> list[Trade]:
+        """The planner's trade recommendations as display trades."""
+        trades = []
+        for rec in await self._planner.get_recommendations():
+            if rec.action == "sell":
+                # Calculate sell percentage
+                if rec.current_value_eur > 0:
+                    sell_pct = (abs(rec.value_delta_eur) / rec.current_value_eur) * 100
+                else:
+                    sell_pct = 100
+                trades.append(
+                    Trade(action="SELL", amount=abs(rec.value_delta_eur), symbol=rec.symbol, sell_pct=sell_pct)
+                )
+            else:
+                trades.append(Trade(action="BUY", amount=rec.value_delta_eur, symbol=rec.symbol))
+        return trades
+
+    async def template(self) -> list[dict[str, Any]]:
+        """The stored ticker template; an invalid one is ignored."""
+        try:
+            return validate_ticker_template(await self._settings.get(TICKER_TEMPLATE_KEY, []))
+        except ValueError as e:
+            logger.warning(f"Ignoring invalid {TICKER_TEMPLATE_KEY}: {e}")
+            return []
+
+    async def _regime_text(self, locale: Locale) -> str:
+        try:
+            symbols = validate_benchmark_symbols(await self._settings.get(BENCHMARK_SYMBOLS_KEY, []))
+        except ValueError:
+            symbols = []
+        if not symbols:
+            return ""
+        # Calendar days, enough for REGIME_WINDOW trading days.
+        regime = market_regime(await Database().get_benchmark_prices(symbols[0], days=REGIME_WINDOW * 3 // 2 + 30))
+        return locale.text(f"regime_{regime}") if regime else ""
+
+    async def values(self, locale: Locale, trades: list[Trade]) -> dict[str, str]:
+        """Template placeholder values; "" where there is no data."""
+        values = dict.fromkeys(PLACEHOLDERS, "")
+        try:
+            valuation = await PortfolioValuationService(db=Database(), broker=Broker(), currency=Currency()).current()
+        except Exception as e:
+            logger.warning(f"Failed to value the portfolio for the LED ticker: {e}")
+        else:
+            total = valuation["total_value_eur"]
+            intraday = valuation["intraday_pnl_eur"]
+            values["total_value"] = locale.money(total, decimals=0, ascii_only=True)
+            if intraday is not None and total - intraday > 0:
+                values["day_change_pct"] = locale.percent(intraday / (total - intraday) * 100, 1, sign=True)
+        if trades:
+            lines = [trade.to_display_string(locale) for trade in trades]
+            values.update(next_action=lines[0], pending_trades=str(len(trades)), trades=" / ".join(lines))
+        try:
+            values["regime"] = await self._regime_text(locale)
+        except Exception as e:
+            logger.warning(f"Failed to compute the market regime for the LED ticker: {e}")
+        values["benchmark"] = await self.benchmark_text(locale) or ""
+        return {name: ascii_text(value) for name, value in values.items()}
+
+    async def ticker_lines(
+        self, locale: Locale, trades: list[Trade], template: list[dict[str, Any]] | None = None
+    ) -> list[str]:
+        """The ticker mode's lines: the template's segments, or without one each trade then the benchmark line."""
+        template = await self.template() if template is None else template
+        if template:
+            return render_template(template, await self.values(locale, trades))
+        lines = [trade.to_display_string(locale) for trade in trades]
+        benchmark = await self.benchmark_text(locale)
+        return lines + [benchmark] if benchmark else lines
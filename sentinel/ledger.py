@@ -0,0 +1,357 @@
+"""
+Ledger replay - rebuild portfolio state purely from the broker ledger.
+
+The ledger is the synced event history: trades and cash flows (deposits,
+withdrawals, dividends, taxes, commissions, FX blocks). Positions and cash
+balances in the database are broker snapshots that can drift from it, so the
+replay rebuilds them from events alone:
+
+  - positions: quantities from stock trades
+  - cost basis: weighted average purchase price per position, in the
+    security's currency; sells keep the average, a closed position resets it
+  - cash: per-currency balance from cash flows, trade settlements and
+    trade commissions
+
+Dividends and other corporate-action payouts reach the ledger as cash flows;
+the `dividends` table describes those same credits and is not replayed again.
+
+The same replay explains a negative cash balance: the currency's cash
+timeline (one entry per trade settlement, commission, FX leg or cash flow,
+with the running balance) shows the event that took the balance below zero
+and what moved it within NEGATIVE_WINDOW_DAYS before that.
+
+Usage:
+    service = LedgerService(db)
+    state = await service.replay()
+    report = await service.check_consistency()
+    analysis = await service.explain_negative_balance("USD")
+"""
+
+from __future__ import annotations
+
+from collections.abc import Mapping
+from datetime import date, datetime, timedelta
+from typing import Any
+
+from sentinel.snapshot_service import (
+    POSITION_EPSILON,
+    _apply_cash_balance,
+    _apply_cash_flow,
+    _apply_stock_position_trade,
+    _apply_trade_cash,
+    _as_float,
+    _is_stock_symbol,
+)
+
+QUANTITY_TOLERANCE = 1e-6
+CASH_TOLERANCE = 0.01
+AVG_COST_TOLERANCE_PCT = 0.5
+TRADE_HISTORY_LIMIT = 100000
+NEGATIVE_WINDOW_DAYS = 7
+# Planner state key of the latest negative-balance event (trading:balance_fix).
+NEGATIVE_BALANCE_STATE_KEY = "cash:negative_balance"
+TOP_OUTFLOWS = 3
+
+
+def _trade_date(trade: Mapping[str, Any]) -> str:
+    return datetime.fromtimestamp(int(trade["executed_at"])).date().isoformat()
+
+
+def _dedupe_trades(trades: list[dict]) -> list[dict]:
+    seen: set[str] = set()
+    unique = []
+    for trade in trades:
+        trade_id = trade.get("broker_trade_id")
+        if trade_id is not None and trade_id in seen:
+            continue
+        if trade_id is not None:
+            seen.add(trade_id)
+        unique.append(trade)
+    return unique
+
+
+def _apply_cost_basis(
+    avg_costs: dict[str, float],
+    positions_before: dict[str, float],
+    trade: Mapping[str, Any],
+) -> None:
+    symbol = trade["symbol"]
+    held = positions_before.get(symbol, 0.0)
+    quantity = _as_float(trade.get("quantity"))
+    if trade["side"] == "BUY":
+        if held <= POSITION_EPSILON:
+            avg_costs[symbol] = _as_float(trade.get("price"))
+            return
+        total = held + quantity
+        if total > POSITION_EPSILON:
+            avg_costs[symbol] = (held * avg_costs.get(symbol, 0.0) + quantity * _as_float(trade.get("price"))) / total
+    elif held - quantity <= POSITION_EPSILON:
+        avg_costs.pop(symbol, None)
+
+
+def _ledger_events(trades: list[dict], cash_flows: list[dict]) -> list[tuple[str, int, float, Mapping[str, Any]]]:
+    """(date, kind, timestamp, row) in replay order; kind 0 is a cash flow, 1 a trade."""
+    events: list[tuple[str, int, float, Mapping[str, Any]]] = []
+    for flow in cash_flows:
+        events.append((str(flow["date"])[:10], 0, 0.0, flow))
+    for trade in _dedupe_trades(trades):
+        events.append((_trade_date(trade), 1, float(trade["executed_at"]), trade))
+    events.sort(key=lambda event: event[:3])
+    return events
+
+
+def replay_events(
+    trades: list[dict],
+    cash_flows: list[dict],
+    security_currencies: dict[str, str],
+    as_of: str | None = None,
+) -> dict[str, Any]:
+    """Replay ledger events in date order into positions, cost basis and cash.
+
+    Cash flows are applied before trades on the same day, matching the
+    snapshot backfill.
+
+    Args:
+        trades: Trade rows (any order; duplicates by broker_trade_id are dropped)
+        cash_flows: Cash flow rows (any order)
+        security_currencies: symbol -> trading currency, for trades without one
+        as_of: Replay only events on or before this YYYY-MM-DD date
+    """
+    events = _ledger_events(trades, cash_flows)
+
+    positions: dict[str, float] = {}
+    avg_costs: dict[str, float] = {}
+    cash: dict[str, float] = {}
+    replayed = {"trades": 0, "cash_flows": 0}
+    last_event_date = None
+    for event_date, kind, _ts, row in events:
+        if as_of is not None and event_date > as_of:
+            break
+        last_event_date = event_date
+        if kind == 0:
+            _apply_cash_flow(cash, row)
+            replayed["cash_flows"] += 1
+            continue
+        symbol = str(row.get("symbol") or "")
+        _apply_trade_cash(cash, row, security_currency=security_currencies.get(symbol, "EUR"))
+        if _is_stock_symbol(symbol):
+            _apply_cost_basis(avg_costs, positions, row)
+            _apply_stock_position_trade(positions, symbol, row["side"], _as_float(row.get("quantity")))
+        replayed["trades"] += 1
+
+    return {
+        "as_of_date": as_of,
+        "last_event_date": last_event_date,
+        "events": replayed,
+        "positions": {
+            symbol: {"quantity": quantity, "avg_cost": avg_costs.get(symbol)}
+            for symbol, quantity in sorted(positions.items())
+        },
+        "cash": dict(sorted(cash.items())),
+    }
+
+
+def compare_states(
+    replayed: dict[str, Any],
+    live_positions: list[dict],
+    live_cash: dict[str, float],
+    *,
+    cash_tolerance: float = CASH_TOLERANCE,
+) -> dict[str, Any]:
+    """Differences between a replayed state and the live positions/cash tables."""
+    live_by_symbol = {p["symbol"]: p for p in live_positions if _as_float(p.get("quantity")) > 0}
+    position_diffs = []
+    for symbol in sorted(set(replayed["positions"]) | set(live_by_symbol)):
+        replayed_pos = replayed["positions"].get(symbol) or {}
+        live = live_by_symbol.get(symbol) or {}
+        replayed_qty = _as_float(replayed_pos.get("quantity"))
+        live_qty = _as_float(live.get("quantity"))
+        replayed_cost = replayed_pos.get("avg_cost")
+        live_cost = live.get("avg_cost")
+
+        issues = []
+        if abs(replayed_qty - live_qty) > QUANTITY_TOLERANCE:
+            issues.append("quantity")
+        if replayed_cost is not None and live_cost:
+            if abs(replayed_cost - live_cost) / abs(live_cost) * 100 > AVG_COST_TOLERANCE_PCT:
+                issues.append("avg_cost")
+        if issues:
+            position_diffs.append(
+                {
+                    "symbol": symbol,
+                    "issues": issues,
+                    "replayed_quantity": replayed_qty,
+                    "live_quantity": live_qty,
+                    "replayed_avg_cost": replayed_cost,
+                    "live_avg_cost": live_cost,
+                }
+            )
+
+    cash_diffs = []
+    for currency in sorted(set(replayed["cash"]) | set(live_cash)):
+        replayed_amount = _as_float(replayed["cash"].get(currency))
+        live_amount = _as_float(live_cash.get(currency))
+        if abs(replayed_amount - live_amount) > cash_tolerance:
+            cash_diffs.append(
+                {
+                    "currency": currency,
+                    "replayed": round(replayed_amount, 2),
+                    "live": round(live_amount, 2),
+                    "difference": round(replayed_amount - live_amount, 2),
+                }
+            )
+
+    return {
+        "consistent": not position_diffs and not cash_diffs,
+        "positions": position_diffs,
+        "cash": cash_diffs,
+    }
+
+
+def _cash_flow_kind(flow: Mapping[str, Any], amount: float) -> str:
+    type_id = str(flow.get("type_id") or "")
+    if type_id == "dividend":
+        return "dividend"
+    if "tax" in type_id:
+        return "tax"
+    if "commission" in type_id:
+        return "fee"
+    return "deposit" if amount > 0 else "withdrawal"
+
+
+def cash_timeline(
+    trades: list[dict],
+    cash_flows: list[dict],
+    security_currencies: dict[str, str],
+    currency: str,
+) -> list[dict[str, Any]]:
+    """Every ledger movement of one currency's cash, in replay order, with the running balance.
+
+    Entry kinds: trade, fx, fee (trade commissions and commission cash flows),
+    dividend, tax, deposit, withdrawal.
+    """
+    timeline: list[dict[str, Any]] = []
+    balance = 0.0
+    for event_date, kind, _ts, row in _ledger_events(trades, cash_flows):
+        delta: dict[str, float] = {}
+        if kind == 0:
+            _apply_cash_flow(delta, row)
+            amount = delta.get(currency, 0.0)
+            movements = [(_cash_flow_kind(row, amount), amount, row.get("comment") or row.get("type_id"))]
+            ref = row.get("id")
+        else:
+            symbol = str(row.get("symbol") or "")
+            _apply_trade_cash(delta, row, security_currency=security_currencies.get(symbol, "EUR"))
+            fee = {}
+            if row.get("commission"):
+                _apply_cash_balance(fee, str(row.get("commission_currency") or "EUR"), -_as_float(row["commission"]))
+            fee_amount = fee.get(currency, 0.0)
+            movements = [
+                (
+                    "fx" if "/" in symbol else "trade",
+                    delta.get(currency, 0.0) - fee_amount,
+                    f"{row.get('side')} {_as_float(row.get('quantity')):g} {symbol}",
+                ),
+                ("fee", fee_amount, f"Commission on {symbol}"),
+            ]
+            ref = row.get("broker_trade_id")
+        for movement_kind, amount, description in movements:
+            if abs(amount) < POSITION_EPSILON:
+                continue
+            balance += amount
+            timeline.append(
+                {
+                    "date": event_date,
+                    "kind": movement_kind,
+                    "description": description,
+                    "amount": round(amount, 2),
+                    "balance": round(balance, 2),
+                    "ref": ref,
+                }
+            )
+    return timeline
+
+
+def explain_negative_balance(
+    timeline: list[dict[str, Any]],
+    currency: str,
+    window_days: int = NEGATIVE_WINDOW_DAYS,
+) -> dict[str, Any]:
+    """Why a currency's replayed balance is negative, from its cash timeline.
+
+    The cause is the entry that took the balance below zero for the current
+    negative run. The window covers `window_days` before it up to the latest
+    entry.
+    """
+    balance = timeline[-1]["balance"] if timeline else 0.0
+    if balance >= 0:
+        return {"currency": currency, "negative": False, "balance": balance}
+
+    start = len(timeline) - 1
+    while start > 0 and timeline[start - 1]["balance"] < 0:
+        start -= 1
+    cause = timeline[start]
+    window_start = (date.fromisoformat(cause["date"]) - timedelta(days=window_days)).isoformat()
+    window = [entry for entry in timeline if entry["date"] >= window_start]
+    by_kind: dict[str, float] = {}
+    for entry in window:
+        by_kind[entry["kind"]] = round(by_kind.get(entry["kind"], 0.0) + entry["amount"], 2)
+    outflows = sorted((entry for entry in timeline[start:] if entry["amount"] < 0), key=lambda e: e["amount"])
+    return {
+        "currency": currency,
+        "negative": True,
+        "balance": balance,
+        "negative_since": cause["date"],
+        "balance_before": round(cause["balance"] - cause["amount"], 2),
+        "cause": cause,
+        "largest_outflows": outflows[:TOP_OUTFLOWS],
+        "window_start": window_start,
+        "window_by_kind": by_kind,
+        "timeline": window,
+    }
+
+
+class LedgerService:
+    """Rebuilds portfolio state from the trade and cash-flow ledger."""
+
+    def __init__(self, db):
+        self._db = db
+
+    async def replay(self, as_of: str | None = None) -> dict[str, Any]:
+        """Replay the full ledger, optionally up to an as-of date."""
+        # Filtered by date during replay: stored cash-flow dates can carry a time.
+        trades = await self._db.get_trades(limit=TRADE_HISTORY_LIMIT)
+        cash_flows = await self._db.get_cash_flows()
+        securities = await self._db.get_all_securities(active_only=False)
+        currencies = {s["symbol"]: s.get("currency") or "EUR" for s in securities}
+        return replay_events(trades, cash_flows, currencies, as_of=as_of)
+
+    async def check_consistency(self, cash_tolerance: float = CASH_TOLERANCE) -> dict[str, Any]:
+        """Compare the replayed ledger with the live positions and cash balances."""
+        replayed = await self.replay()
+        report = compare_states(
+            replayed,
+            await self._db.get_all_positions(),
+            await self._db.get_cash_balances(),
+            cash_tolerance=cash_tolerance,
+        )
+        report["events"] = replayed["events"]
+        report["last_event_date"] = replayed["last_event_date"]
+        return report
+
+    async def explain_negative_balance(self, currency: str, live_balance: float | None = None) -> dict[str, Any]:
+        """Root-cause analysis of a negative balance, from the replayed cash timeline.
+
+        With `live_balance`, the report also says whether the ledger agrees
+        with the broker snapshot; when it does not, the cause is incomplete.
+        """
+        trades = await self._db.get_trades(limit=TRADE_HISTORY_LIMIT)
+        cash_flows = await self._db.get_cash_flows()
+        securities = await self._db.get_all_securities(active_only=False)
+        currencies = {s["symbol"]: s.get("currency") or "EUR" for s in securities}
+        currency = currency.upper()
+        analysis = explain_negative_balance(cash_timeline(trades, cash_flows, currencies, currency), currency)
+        if live_balance is not None:
+            analysis["live_balance"] = live_balance
+            analysis["ledger_matches_live"] = abs(analysis["balance"] - live_balance) <= CASH_TOLERANCE
+        return analysis
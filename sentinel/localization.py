@@ -0,0 +1,338 @@
+"""
+Localization - number, date and currency formatting and translated texts.
+
+The `locale` setting picks one of LOCALES (default "en"). It is applied to
+the LED ticker (sentinel.led.ticker) and the monthly PDF summary
+(sentinel.services.reports). CSV and JSON exports are for other programs and
+keep `.` decimals and ISO dates whatever the locale.
+
+The LED matrix only has ASCII glyphs, so ticker lines fold accents away
+(`ticker_text`) and show currency codes instead of symbols.
+
+Usage:
+    locale = await load_locale(settings)
+    locale.money(1874.62)             # "€1,874.62" / "1.874,62 €"
+    locale.text("buy")                # "BUY" / "KAUFEN"
+    locale.ticker_text("portfolio", value=locale.money(12345, decimals=0, ascii_only=True))
+"""
+
+from __future__ import annotations
+
+import inspect
+import unicodedata
+from dataclasses import dataclass, field
+from datetime import date, datetime
+from typing import Any
+
+LOCALE_KEY = "locale"
+DEFAULT_LOCALE = "en"
+CURRENCY_SYMBOLS = {"EUR": "€", "USD": "$", "GBP": "£", "JPY": "¥"}
+
+# English texts; every other locale translates the same keys.
+TEXTS = {
+    # LED ticker
+    "buy": "BUY",
+    "sell": "SELL",
+    "trading_paused": "TRADING PAUSED",
+    "trading_paused_until": "TRADING PAUSED UNTIL {time}",
+    "health": "BROKER {broker} BRIDGE {bridge}",
+    "ok": "OK",
+    "down": "DOWN",
+    "portfolio": "PORTFOLIO {value}",
+    "stats_unavailable": "STATS UNAVAILABLE",
+    "benchmark": "vs {benchmark}: {change} YTD",
+    "regime_bull": "BULL",
+    "regime_bear": "BEAR",
+    # Monthly summary
+    "monthly_title": "Sentinel monthly summary - {month}",
+    "period": "{start} to {end}",
+    "value_start": "Value at start",
+    "value_end": "Value at end",
+    "net_deposits": "Net deposits",
+    "performance": "Performance",
+    "dividends": "Dividends",
+    "bought_sold": "Bought / sold",
+    "trades": "Trades",
+    "none": "None",
+    "not_available": "n/a",
+}
+
+
+@dataclass(frozen=True)
+class Locale:
+    """Formatting conventions and translated texts of one language."""
+
+    code: str
+    name: str
+    decimal_sep: str = "."
+    group_sep: str = ","
+    percent_format: str = "{value}%"
+    # "{symbol}{amount}" or "{amount} {symbol}"; a currency code is always spaced.
+    money_format: str = "{symbol}{amount}"
+    date_format: str = "%Y-%m-%d"
+    month_format: str = "%Y-%m"
+    time_format: str = "%H:%M"
+    texts: dict[str, str] = field(default_factory=dict)
+
+    def number(self, value: float, decimals: int | None = 2, sign: bool = False) -> str:
+        """A grouped number; `decimals=None` drops trailing zeros (quantities, prices)."""
+        text = format(value, f"{'+' if sign else ''},.{10 if decimals is None else decimals}f")
+        if decimals is None and "." in text:
+            text = text.rstrip("0").rstrip(".")
+        return text.translate(str.maketrans({",": self.group_sep, ".": self.decimal_sep}))
+
+    def percent(self, value: float, decimals: int = 1, sign: bool = False) -> str:
+        return self.percent_format.format(value=self.number(value, decimals, sign))
+
+    def money(self, value: float, currency: str = "EUR", decimals: int = 2, ascii_only: bool = False) -> str:
+        """An amount with its currency symbol, or its code when `ascii_only` or without a symbol."""
+        symbol = currency if ascii_only else CURRENCY_SYMBOLS.get(currency, currency)
+        pattern = self.money_format
+        if symbol.isalpha():
+            pattern = pattern.replace("{symbol}{amount}", "{symbol} {amount}")
+        text = pattern.format(symbol=symbol, amount=self.number(abs(value), decimals))
+        return f"-{text}" if value < 0 else text
+
+    def date(self, value: date | str) -> str:
+        """A date (or ISO date string) in the locale's order."""
+        day = date.fromisoformat(value) if isinstance(value, str) else value
+        return day.strftime(self.date_format)
+
+    def month(self, year: int, month: int) -> str:
+        return date(year, month, 1).strftime(self.month_format)
+
+    def time(self, value: datetime) -> str:
+        return value.strftime(self.time_format)
+
+    def text(self, key: str, **values: Any) -> str:
+        """A translated text with its placeholders filled; English when untranslated."""
+        return self.texts.get(key, TEXTS[key]).format(**values)
+
+    def ticker_text(self, key: str, **values: Any) -> str:
+        """A translated text folded to ASCII for the LED matrix."""
+        return ascii_text(self.text(key, **values))
+
+
+def ascii_text(text: str) -> str:
+    """Text with accents dropped ("VERFÜGBAR" -> "VERFUGBAR") and other non-ASCII removed."""
+    return unicodedata.normalize("NFKD", text).encode("ascii", "ignore").decode("ascii")
+
+
+LOCALES = {
+    "en": Locale("en", "English"),
+    "de": Locale(
+        "de",
+        "Deutsch",
+        decimal_sep=",",
+        group_sep=".",
+        percent_format="{value} %",
+        money_format="{amount} {symbol}",
+        date_format="%d.%m.%Y",
+        month_format="%m.%Y",
+        texts={
+            "buy": "KAUFEN",
+            "sell": "VERKAUFEN",
+            "trading_paused": "HANDEL PAUSIERT",
+            "trading_paused_until": "HANDEL PAUSIERT BIS {time}",
+            "down": "AUS",
+            "stats_unavailable": "STATISTIK NICHT VERFÜGBAR",
+            "benchmark": "ggü. {benchmark}: {change} YTD",
+            "regime_bull": "HAUSSE",
+            "regime_bear": "BAISSE",
+            "monthly_title": "Sentinel Monatsübersicht - {month}",
+            "period": "{start} bis {end}",
+            "value_start": "Wert zu Beginn",
+            "value_end": "Wert am Ende",
+            "net_deposits": "Nettoeinzahlungen",
+            "dividends": "Dividenden",
+            "bought_sold": "Gekauft / verkauft",
+            "trades": "Transaktionen",
+            "none": "Keine",
+            "not_available": "k. A.",
+        },
+    ),
+    "fr": Locale(
+        "fr",
+        "Français",
+        decimal_sep=",",
+        group_sep=" ",
+        percent_format="{value} %",
+        money_format="{amount} {symbol}",
+        date_format="%d/%m/%Y",
+        month_format="%m/%Y",
+        texts={
+            "buy": "ACHAT",
+            "sell": "VENTE",
+            "trading_paused": "TRADING SUSPENDU",
+            "trading_paused_until": "TRADING SUSPENDU JUSQU'À {time}",
+            "down": "HS",
+            "portfolio": "PORTEFEUILLE {value}",
+            "stats_unavailable": "STATISTIQUES INDISPONIBLES",
+            "regime_bull": "HAUSSIER",
+            "regime_bear": "BAISSIER",
+            "monthly_title": "Sentinel synthèse mensuelle - {month}",
+            "period": "du {start} au {end}",
+            "value_start": "Valeur au début",
+            "value_end": "Valeur à la fin",
+            "net_deposits": "Dépôts nets",
+            "dividends": "Dividendes",
+            "bought_sold": "Achats / ventes",
+            "trades": "Transactions",
+            "none": "Aucun",
+            "not_available": "n.d.",
+        },
+    ),
+    "it": Locale(
+        "it",
+        "Italiano",
+        decimal_sep=",",
+        group_sep=".",
+        money_format="{amount} {symbol}",
+        date_format="%d/%m/%Y",
+        month_format="%m/%Y",
+        texts={
+            "buy": "COMPRA",
+            "sell": "VENDI",
+            "trading_paused": "TRADING IN PAUSA",
+            "trading_paused_until": "TRADING IN PAUSA FINO ALLE {time}",
+            "down": "GIÙ",
+            "portfolio": "PORTAFOGLIO {value}",
+            "stats_unavailable": "STATISTICHE NON DISPONIBILI",
+            "regime_bull": "RIALZISTA",
+            "regime_bear": "RIBASSISTA",
+            "monthly_title": "Sentinel riepilogo mensile - {month}",
+            "period": "dal {start} al {end}",
+            "value_start": "Valore iniziale",
+            "value_end": "Valore finale",
+            "net_deposits": "Depositi netti",
+            "performance": "Rendimento",
+            "dividends": "Dividendi",
+            "bought_sold": "Acquisti / vendite",
+            "trades": "Operazioni",
+            "none": "Nessuno",
+            "not_available": "n.d.",
+        },
+    ),
+    "es": Locale(
+        "es",
+        "Español",
+        decimal_sep=",",
+        group_sep=".",
+        percent_format="{value} %",
+        money_format="{amount} {symbol}",
+        date_format="%d/%m/%Y",
+        month_format="%m/%Y",
+        texts={
+            "buy": "COMPRAR",
+            "sell": "VENDER",
+            "trading_paused": "TRADING EN PAUSA",
+            "trading_paused_until": "TRADING EN PAUSA HASTA LAS {time}",
+            "down": "CAÍDO",
+            "portfolio": "CARTERA {value}",
+            "stats_unavailable": "ESTADÍSTICAS NO DISPONIBLES",
+            "regime_bull": "ALCISTA",
+            "regime_bear": "BAJISTA",
+            "monthly_title": "Sentinel resumen mensual - {month}",
+            "period": "del {start} al {end}",
+            "value_start": "Valor inicial",
+            "value_end": "Valor final",
+            "net_deposits": "Depósitos netos",
+            "performance": "Rentabilidad",
+            "dividends": "Dividendos",
+            "bought_sold": "Compras / ventas",
+            "trades": "Operaciones",
+            "none": "Ninguno",
+            "not_available": "n/d",
+        },
+    ),
+    "nl": Locale(
+        "nl",
+        "Nederlands",
+        decimal_sep=",",
+        group_sep=".",
+        money_format="{symbol} {amount}",
+        date_format="%d-%m-%Y",
+        month_format="%m-%Y",
+        texts={
+            "buy": "KOPEN",
+            "sell": "VERKOPEN",
+            "trading_paused": "HANDEL GEPAUZEERD",
+            "trading_paused_until": "HANDEL GEPAUZEERD TOT {time}",
+            "down": "UIT",
+            "portfolio": "PORTEFEUILLE {value}",
+            "stats_unavailable": "STATISTIEKEN NIET BESCHIKBAAR",
+            "benchmark": "t.o.v. {benchmark}: {change} YTD",
+            "regime_bull": "STIJGEND",
+            "regime_bear": "DALEND",
+            "monthly_title": "Sentinel maandoverzicht - {month}",
+            "period": "{start} t/m {end}",
+            "value_start": "Waarde bij begin",
+            "value_end": "Waarde aan eind",
+            "net_deposits": "Netto stortingen",
+            "performance": "Rendement",
+            "dividends": "Dividenden",
+            "bought_sold": "Gekocht / verkocht",
+            "trades": "Transacties",
+            "none": "Geen",
+            "not_available": "n.v.t.",
+        },
+    ),
+    "pt": Locale(
+        "pt",
+        "Português",
+        decimal_sep=",",
+        group_sep=".",
+        money_format="{amount} {symbol}",
+        date_format="%d/%m/%Y",
+        month_format="%m/%Y",
+        texts={
+            "buy": "COMPRAR",
+            "sell": "VENDER",
+            "trading_paused": "NEGOCIAÇÃO EM PAUSA",
+            "trading_paused_until": "NEGOCIAÇÃO EM PAUSA ATÉ ÀS {time}",
+            "down": "EM BAIXO",
+            "portfolio": "CARTEIRA {value}",
+            "stats_unavailable": "ESTATÍSTICAS INDISPONÍVEIS",
+            "regime_bull": "ALTA",
+            "regime_bear": "BAIXA",
+            "monthly_title": "Sentinel resumo mensal - {month}",
+            "period": "de {start} a {end}",
+            "value_start": "Valor inicial",
+            "value_end": "Valor final",
+            "net_deposits": "Depósitos líquidos",
+            "performance": "Desempenho",
+            "dividends": "Dividendos",
+            "bought_sold": "Compras / vendas",
+            "trades": "Transações",
+            "none": "Nenhum",
+            "not_available": "n/d",
+        },
+    ),
+}
+
+
+def validate_locale(value: Any) -> str:
+    """Validate `locale`.
+
+    Raises:
+        ValueError: If it is not one of LOCALES.
+    """
+    if value not in LOCALES:
+        raise ValueError(f"{LOCALE_KEY} must be one of {', '.join(LOCALES)}")
+    return value
+
+
+def get_locale(code: Any) -> Locale:
+    """The locale for a code; English for unknown codes."""
+    return LOCALES.get(code, LOCALES[DEFAULT_LOCALE]) if isinstance(code, str) else LOCALES[DEFAULT_LOCALE]
+
+
+async def load_locale(settings) -> Locale:
+    """The configured locale; tolerates settings objects without async access."""
+    getter = getattr(settings, "get", None)
+    if not callable(getter):
+        return LOCALES[DEFAULT_LOCALE]
+    code = getter(LOCALE_KEY, DEFAULT_LOCALE)
+    if inspect.isawaitable(code):
+        code = await code
+    return get_locale(code)
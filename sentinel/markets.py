@@ -5,6 +5,25 @@ from __future__ import annotations
 import json
 
 
+def security_market_id(security: dict) -> str | None:
+    """Broker market id (`mrkt.mkt_id`) from a security's stored metadata."""
+    raw_data = security.get("data")
+    if not raw_data:
+        return None
+    try:
+        data = json.loads(raw_data) if isinstance(raw_data, str) else raw_data
+        market_id = data.get("mrkt", {}).get("mkt_id")
+    except (json.JSONDecodeError, AttributeError, TypeError, ValueError):
+        return None
+    return str(market_id) if market_id is not None else None
+
+
+async def get_security_market_ids(db) -> dict[str, str | None]:
+    """Map every active security to its broker market id (None when unknown)."""
+    securities = await db.get_all_securities(active_only=True)
+    return {security["symbol"]: security_market_id(security) for security in securities}
+
+
 async def get_open_market_symbols(broker, db) -> set[str]:
     """Return active securities whose broker market is currently open."""
     market_data = await broker.get_market_status("*")
@@ -15,16 +34,5 @@ async def get_open_market_symbols(broker, db) -> set[str]:
     if not open_market_ids:
         return set()
 
-    open_symbols: set[str] = set()
-    for security in await db.get_all_securities(active_only=True):
-        raw_data = security.get("data")
-        if not raw_data:
-            continue
-        try:
-            data = json.loads(raw_data) if isinstance(raw_data, str) else raw_data
-            market_id = str(data.get("mrkt", {}).get("mkt_id"))
-        except (json.JSONDecodeError, AttributeError, TypeError, ValueError):
-            continue
-        if market_id in open_market_ids:
-            open_symbols.add(security["symbol"])
-    return open_symbols
+    market_ids = await get_security_market_ids(db)
+    return {symbol for symbol, market_id in market_ids.items() if market_id in open_market_ids}
@@ -0,0 +1,541 @@
+"""
+MockBroker - an in-process broker for deterministic integration tests.
+
+Implements BrokerClient with the same return shapes as Broker, so trading
+and sync code runs against it unchanged. Everything is programmed up front:
+
+    broker = MockBroker(clock=FakeClock(start))
+    broker.add_security("ACME.EU", price=10.0, market="EU", market_id=1)
+    broker.deposit(5000.0)
+    broker.set_fills("ACME.EU", latency=timedelta(minutes=5), slices=2)
+    order_id = await broker.buy("ACME.EU", 100)
+    clock.advance(timedelta(minutes=5))   # half the order fills
+    clock.advance(timedelta(minutes=5))   # the rest fills
+
+Orders fill against the clock: a fill model splits an order into `slices`
+equal fills, one every `latency` after it was placed (all at once with no
+latency). Fills are applied lazily, whenever the broker is next called.
+Each fill is a trade in get_trades_history, moves the position and cash,
+and charges `commission_rate` of its value in the security's currency.
+Orders for a symbol without a quote, or with `reject=True`, are rejected.
+
+Unlike Broker, the mock always acts as in live mode: trading mode and the
+trading pause are left to the caller.
+
+Failure injection: `fail("get_orders", times=2)` makes the next two calls
+return what Broker returns when Tradernet errors (None, [] or {}), or raise
+`error` when one is given. `calls` records every call for assertions.
+"""
+
+from __future__ import annotations
+
+import copy
+import logging
+import math
+from dataclasses import dataclass
+from datetime import datetime, timedelta, timezone
+from typing import Any, Optional
+
+from sentinel.clock import Clock, FakeClock
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_START = datetime(2026, 1, 5, 10, 0, tzinfo=timezone.utc)
+
+# Tradernet order status codes (docs/tradernet/miscellaneous/order-statuses.md)
+ACTIVE = 10
+PARTIAL = 20
+FILLED = 21
+PARTIALLY_CANCELLED = 30
+CANCELLED = 31
+REJECTED = 70
+
+# What Broker returns when the Tradernet call behind a method fails.
+FAILURE_RESULTS: dict[str, Any] = {
+    "connect": False,
+    "get_quote": None,
+    "get_quotes": {},
+    "get_historical_prices": [],
+    "get_historical_prices_bulk": {},
+    "get_portfolio": {"positions": [], "cash": {}},
+    "buy": None,
+    "sell": None,
+    "has_pending_orders": True,
+    "get_orders": None,
+    "cancel_order": False,
+    "get_security_info": None,
+    "get_market_status": None,
+    "get_trades_history": [],
+    "get_cash_flows": [],
+    "get_corporate_actions": [],
+}
+
+
+@dataclass(frozen=True)
+class FillModel:
+    """How orders for a symbol fill."""
+
+    latency: timedelta = timedelta(0)
+    slices: int = 1
+    reject: bool = False
+
+
+@dataclass
+class _Order:
+    order_id: str
+    symbol: str
+    side: str
+    quantity: int
+    price: float | None
+    currency: str
+    placed_at: datetime
+    model: FillModel
+    filled: int = 0
+    fills: int = 0
+    status: int = ACTIVE
+
+    @property
+    def active(self) -> bool:
+        return self.status in (ACTIVE, PARTIAL)
+
+
+class _Failure(Exception):
+    pass
+
+
+class MockBroker:
+    """Programmable in-process broker; see the module docstring."""
+
+    def __init__(
+        self,
+        clock: Clock | None = None,
+        fill_model: FillModel | None = None,
+        commission_rate: float = 0.0,
+        connected: bool = True,
+    ):
+        self.clock = clock or FakeClock(DEFAULT_START)
+        self.commission_rate = commission_rate
+        self.calls: list[tuple[str, tuple]] = []
+        self._connected = connected
+        self._default_fills = fill_model or FillModel()
+        self._fill_models: dict[str, FillModel] = {}
+        self._quotes: dict[str, dict] = {}
+        self._info: dict[str, dict] = {}
+        self._history: dict[str, list[dict]] = {}
+        self._markets: dict[str, dict] = {}
+        self._positions: dict[str, dict] = {}
+        self._cash: dict[str, float] = {}
+        self._orders: dict[str, _Order] = {}
+        self._trades: list[dict] = []
+        self._cash_flows: list[dict] = []
+        self._corporate_actions: list[dict] = []
+        self._failures: dict[str, list[Exception | None]] = {}
+        self._next_id = 1000
+
+    # -------------------------------------------------------------------------
+    # Programming
+    # -------------------------------------------------------------------------
+
+    def add_security(
+        self,
+        symbol: str,
+        price: float,
+        currency: str = "EUR",
+        market: str = "EU",
+        market_id: int = 1,
+        name: str | None = None,
+        lot: int = 1,
+    ) -> None:
+        """A tradeable security with a quote on an (open) market."""
+        self._info[symbol] = {
+            "short_name": name or symbol,
+            "currency": currency,
+            "mrkt": {"mkt_id": market_id},
+            "lot": f"{lot:.8f}",
+        }
+        if market not in self._markets:
+            self.set_market(market, market_id)
+        self.set_quote(symbol, price)
+
+    def set_quote(self, symbol: str, price: float, bid: float | None = None, ask: float | None = None) -> None:
+        """Set the last price (and optionally bid and ask) of a symbol."""
+        self._quotes[symbol] = {
+            "c": symbol,
+            "ltp": price,
+            "bbp": bid if bid is not None else price,
+            "bap": ask if ask is not None else price,
+            "chg": 0.0,
+            "pcp": 0.0,
+        }
+
+    def remove_quote(self, symbol: str) -> None:
+        self._quotes.pop(symbol, None)
+
+    def set_history(self, symbol: str, closes: dict[str, float]) -> None:
+        """Daily closes by date (YYYY-MM-DD) for historical price calls."""
+        self._history[symbol] = [
+            {"date": date, "open": close, "high": close, "low": close, "close": close, "volume": 0}
+            for date, close in sorted(closes.items())
+        ]
+
+    def set_market(self, name: str, market_id: int, open: bool = True) -> None:
+        """Add a market (broker code `n2`) or open / close it."""
+        self._markets[name] = {"n2": name, "i": market_id, "s": "OPEN" if open else "CLOSE"}
+
+    def set_fills(
+        self,
+        symbol: str | None = None,
+        latency: timedelta = timedelta(0),
+        slices: int = 1,
+        reject: bool = False,
+    ) -> None:
+        """Fill model for new orders in `symbol`, or the default for all symbols."""
+        if slices < 1:
+            raise ValueError("slices must be at least 1")
+        model = FillModel(latency=latency, slices=slices, reject=reject)
+        if symbol is None:
+            self._default_fills = model
+        else:
+            self._fill_models[symbol] = model
+
+    def set_position(self, symbol: str, quantity: float, avg_cost: float) -> None:
+        """Hold `quantity` of `symbol` without a trade."""
+        self._positions[symbol] = {"quantity": quantity, "avg_cost": avg_cost}
+
+    def set_cash(self, currency: str, amount: float) -> None:
+        """Set a cash balance without a cash flow."""
+        self._cash[currency] = amount
+
+    def deposit(
+        self,
+        amount: float,
+        currency: str = "EUR",
+        date: str | None = None,
+        type_id: str = "card",
+        comment: str = "Deposit",
+    ) -> dict:
+        """Book a cash flow and apply it to the cash balance.
+
+        `amount` is signed: negative for withdrawals (`card_payout`), taxes and fees.
+        """
+        flow = {
+            "id": self._new_id(),
+            "date": date or self._today(),
+            "type_id": type_id,
+            "amount": amount,
+            "currency": currency,
+            "comment": comment,
+        }
+        self._cash_flows.append(flow)
+        self._cash[currency] = self._cash.get(currency, 0.0) + amount
+        return flow
+
+    def add_corporate_action(self, action: dict) -> None:
+        """Add a raw corporate action row as Tradernet reports it."""
+        self._corporate_actions.append(dict(action))
+
+    def fail(self, method: str, times: int = 1, error: Exception | None = None) -> None:
+        """Make the next `times` calls to `method` fail."""
+        if method not in FAILURE_RESULTS:
+            raise ValueError(f"Cannot inject failures into '{method}'")
+        self._failures.setdefault(method, []).extend([error] * times)
+
+    def disconnect(self) -> None:
+        self._connected = False
+
+    # -------------------------------------------------------------------------
+    # Internals
+    # -------------------------------------------------------------------------
+
+    def _new_id(self) -> str:
+        self._next_id += 1
+        return str(self._next_id)
+
+    def _today(self) -> str:
+        return self.clock.now().strftime("%Y-%m-%d")
+
+    def _enter(self, method: str, *args: Any) -> None:
+        """Record the call, apply due fills, and raise _Failure if one is injected."""
+        self.calls.append((method, args))
+        self._process_fills()
+        queued = self._failures.get(method)
+        if queued:
+            error = queued.pop(0)
+            if error is not None:
+                raise error
+            raise _Failure(method)
+
+    def _process_fills(self) -> None:
+        now = self.clock.now()
+        for order in self._orders.values():
+            while order.active and now >= order.placed_at + order.model.latency * (order.fills + 1):
+                self._fill_slice(order)
+
+    def _fill_slice(self, order: _Order) -> None:
+        quote = self._quotes.get(order.symbol)
+        if quote is None and order.price is None:
+            order.status = REJECTED if order.filled == 0 else PARTIALLY_CANCELLED
+            return
+        order.fills += 1
+        slices = order.model.slices
+        target = order.quantity if order.fills >= slices else order.quantity * order.fills // slices
+        quantity = target - order.filled
+        if quantity <= 0:
+            return
+        price = order.price if order.price is not None else (quote["bap"] if order.side == "buy" else quote["bbp"])
+        value = quantity * price
+        commission = round(value * self.commission_rate, 2)
+        sign = 1 if order.side == "buy" else -1
+        self._cash[order.currency] = self._cash.get(order.currency, 0.0) - sign * value - commission
+        self._apply_position(order.symbol, sign * quantity, price)
+        order.filled += quantity
+        order.status = FILLED if order.filled >= order.quantity else PARTIAL
+        executed_at = order.placed_at + order.model.latency * order.fills
+        self._trades.append(
+            {
+                "id": self._new_id(),
+                "order_id": order.order_id,
+                "instr_nm": order.symbol,
+                "type": "1" if order.side == "buy" else "2",
+                "q": quantity,
+                "p": price,
+                "curr": order.currency,
+                "date": executed_at.strftime("%Y-%m-%dT%H:%M:%S"),
+                "commission": commission,
+                "commission_currency": order.currency,
+            }
+        )
+
+    def _apply_position(self, symbol: str, delta: float, price: float) -> None:
+        position = self._positions.get(symbol, {"quantity": 0.0, "avg_cost": 0.0})
+        held = position["quantity"]
+        quantity = held + delta
+        if delta > 0 and quantity > 0:
+            position["avg_cost"] = (held * position["avg_cost"] + delta * price) / quantity
+        position["quantity"] = quantity
+        if math.isclose(quantity, 0.0, abs_tol=1e-9):
+            self._positions.pop(symbol, None)
+        else:
+            self._positions[symbol] = position
+
+    def _place(self, side: str, symbol: str, quantity: int, price: float | None) -> Optional[str]:
+        model = self._fill_models.get(symbol, self._default_fills)
+        order = _Order(
+            order_id=self._new_id(),
+            symbol=symbol,
+            side=side,
+            quantity=int(quantity),
+            price=price,
+            currency=self._info.get(symbol, {}).get("currency", "EUR"),
+            placed_at=self.clock.now(),
+            model=model,
+        )
+        self._orders[order.order_id] = order
+        if model.reject or quantity <= 0 or (symbol not in self._quotes and price is None):
+            order.status = REJECTED
+            logger.info(f"[MOCK] Rejected {side} {quantity} x {symbol}")
+        else:
+            self._process_fills()
+        return order.order_id
+
+    # -------------------------------------------------------------------------
+    # BrokerClient
+    # -------------------------------------------------------------------------
+
+    @property
+    def connected(self) -> bool:
+        return self._connected
+
+    async def connect(self) -> bool:
+        try:
+            self._enter("connect")
+        except _Failure:
+            return _failed("connect")
+        self._connected = True
+        return True
+
+    async def get_quote(self, symbol: str) -> Optional[dict]:
+        try:
+            self._enter("get_quote", symbol)
+        except _Failure:
+            return _failed("get_quote")
+        quote = self._quotes.get(symbol)
+        return _map_quote(quote) if quote else None
+
+    async def get_quotes(self, symbols: list[str]) -> dict[str, dict]:
+        try:
+            self._enter("get_quotes", symbols)
+        except _Failure:
+            return _failed("get_quotes")
+        return {symbol: _map_quote(self._quotes[symbol]) for symbol in symbols if symbol in self._quotes}
+
+    async def get_historical_prices(self, symbol: str, days: int = 365) -> list[dict]:
+        try:
+            self._enter("get_historical_prices", symbol, days)
+        except _Failure:
+            return _failed("get_historical_prices")
+        start = (self.clock.now() - timedelta(days=days)).strftime("%Y-%m-%d")
+        return [dict(row) for row in self._history.get(symbol, []) if row["date"] >= start]
+
+    async def get_historical_prices_bulk(
+        self,
+        symbols: list[str],
+        years: int = 20,
+        *,
+        raise_on_error: bool = False,
+    ) -> dict[str, list[dict]]:
+        try:
+            self._enter("get_historical_prices_bulk", symbols, years)
+        except _Failure:
+            if raise_on_error:
+                raise RuntimeError("Injected get_historical_prices_bulk failure") from None
+            return _failed("get_historical_prices_bulk")
+        return {symbol: [dict(row) for row in self._history[symbol]] for symbol in symbols if symbol in self._history}
+
+    async def get_portfolio(self) -> dict:
+        try:
+            self._enter("get_portfolio")
+        except _Failure:
+            return _failed("get_portfolio")
+        positions = []
+        for symbol, position in self._positions.items():
+            price = self._quotes.get(symbol, {}).get("ltp", position["avg_cost"])
+            info = self._info.get(symbol, {})
+            positions.append(
+                {
+                    "symbol": symbol,
+                    "quantity": position["quantity"],
+                    "avg_cost": position["avg_cost"],
+                    "current_price": price,
+                    "close_price": price,
+                    "previous_close_price": price,
+                    "currency": info.get("currency", "EUR"),
+                    "name": info.get("short_name", symbol),
+                    "market_value": position["quantity"] * price,
+                    "profit": position["quantity"] * (price - position["avg_cost"]),
+                }
+            )
+        return {"positions": positions, "cash": dict(self._cash)}
+
+    async def buy(self, symbol: str, quantity: int, price: float | None = None) -> Optional[str]:
+        try:
+            self._enter("buy", symbol, quantity, price)
+        except _Failure:
+            return _failed("buy")
+        return self._place("buy", symbol, quantity, price)
+
+    async def sell(self, symbol: str, quantity: int, price: float | None = None) -> Optional[str]:
+        try:
+            self._enter("sell", symbol, quantity, price)
+        except _Failure:
+            return _failed("sell")
+        return self._place("sell", symbol, quantity, price)
+
+    async def has_pending_orders(self) -> bool:
+        try:
+            self._enter("has_pending_orders")
+        except _Failure:
+            return _failed("has_pending_orders")
+        return any(order.active for order in self._orders.values())
+
+    async def get_orders(self, active_only: bool = True) -> list[dict] | None:
+        try:
+            self._enter("get_orders", active_only)
+        except _Failure:
+            return _failed("get_orders")
+        return [
+            {
+                "order_id": order.order_id,
+                "symbol": order.symbol,
+                "side": order.side,
+                "quantity": order.quantity,
+                "remaining": order.quantity - order.filled,
+                "price": order.price,
+                "currency": order.currency,
+                "type": 2 if order.price is not None else 1,
+                "status": order.status,
+                "date": order.placed_at.strftime("%Y-%m-%dT%H:%M:%S"),
+            }
+            for order in self._orders.values()
+            if order.active or not active_only
+        ]
+
+    async def cancel_order(self, order_id: str) -> bool:
+        try:
+            self._enter("cancel_order", order_id)
+        except _Failure:
+            return _failed("cancel_order")
+        order = self._orders.get(str(order_id))
+        if order is None or not order.active:
+            return False
+        order.status = PARTIALLY_CANCELLED if order.filled else CANCELLED
+        return True
+
+    async def get_security_info(self, symbol: str) -> Optional[dict]:
+        try:
+            self._enter("get_security_info", symbol)
+        except _Failure:
+            return _failed("get_security_info")
+        info = self._info.get(symbol)
+        return dict(info) if info else None
+
+    async def get_market_status(self, market: str = "*") -> Optional[dict]:
+        try:
+            self._enter("get_market_status", market)
+        except _Failure:
+            return _failed("get_market_status")
+        markets = [dict(m) for m in self._markets.values() if market in ("*", m["n2"], str(m["i"]))]
+        return {"m": markets}
+
+    async def is_market_open(self, market_id: str) -> bool:
+        status = await self.get_market_status(market_id)
+        return any(m.get("s") == "OPEN" for m in (status or {}).get("m", []))
+
+    async def get_trades_history(self, start_date: str = "2020-01-01", end_date: str | None = None) -> list[dict]:
+        try:
+            self._enter("get_trades_history", start_date, end_date)
+        except _Failure:
+            return _failed("get_trades_history")
+        end_date = end_date or self._today()
+        return [
+            {**trade, "symbol": trade["instr_nm"], "side": "BUY" if trade["type"] == "1" else "SELL"}
+            for trade in self._trades
+            if start_date <= trade["date"][:10] <= end_date
+        ]
+
+    async def get_cash_flows(self, start_date: str = "2020-01-01", end_date: str | None = None) -> list[dict]:
+        try:
+            self._enter("get_cash_flows", start_date, end_date)
+        except _Failure:
+            return _failed("get_cash_flows")
+        end_date = end_date or self._today()
+        return [dict(flow) for flow in self._cash_flows if start_date <= flow["date"] <= end_date]
+
+    async def get_corporate_actions(self, start_date: str = "2020-01-01", end_date: str | None = None) -> list[dict]:
+        try:
+            self._enter("get_corporate_actions", start_date, end_date)
+        except _Failure:
+            return _failed("get_corporate_actions")
+        end_date = end_date or self._today()
+        return [
+            dict(action)
+            for action in self._corporate_actions
+            if start_date <= str(action.get("date", ""))[:10] <= end_date
+        ]
+
+
+def _failed(method: str) -> Any:
+    return copy.deepcopy(FAILURE_RESULTS[method])
+
+
+def _map_quote(raw: dict) -> dict:
+    """Quote with Broker's convenience names next to the raw Tradernet fields."""
+    return {
+        **raw,
+        "symbol": raw["c"],
+        "price": raw["ltp"],
+        "bid": raw["bbp"],
+        "ask": raw["bap"],
+        "change": raw["chg"],
+        "change_percent": raw["pcp"],
+    }
@@ -0,0 +1,17 @@
+"""News headline ingestion and per-security sentiment."""
+
+from sentinel.news.ingest import ingest_news
+from sentinel.news.sentiment import decayed_sentiment, score_headline, sentiment_timing_score
+from sentinel.news.sources import Headline, NewsSource, NewsSourceError, RssNewsSource, parse_rss
+
+__all__ = [
+    "Headline",
+    "NewsSource",
+    "NewsSourceError",
+    "RssNewsSource",
+    "decayed_sentiment",
+    "ingest_news",
+    "parse_rss",
+    "score_headline",
+    "sentiment_timing_score",
+]
@@ -0,0 +1,67 @@
+"""Headline ingestion: fetch, score, store and refresh per-security sentiment."""
+
+from __future__ import annotations
+
+import logging
+
+from sentinel.news.sentiment import decayed_sentiment, score_headline
+from sentinel.news.sources import NewsSource, NewsSourceError
+
+logger = logging.getLogger(__name__)
+
+
+async def ingest_news(
+    db,
+    source: NewsSource,
+    securities: list[dict],
+    *,
+    now_ts: int,
+    half_life_days: float,
+    max_age_days: int,
+) -> dict[str, int]:
+    """Fetch headlines for `securities` and recompute their sentiment scores.
+
+    A security whose feed fails keeps its previous headlines; its score is
+    still recomputed so it keeps decaying.
+    """
+    cutoff_ts = now_ts - max_age_days * 86400
+    stored = failed = 0
+    for security in securities:
+        symbol = security["symbol"]
+        try:
+            headlines = await source.fetch(symbol, security.get("name"))
+        except NewsSourceError as exc:
+            logger.warning("News fetch failed for %s: %s", symbol, exc)
+            failed += 1
+            headlines = []
+
+        rows = [
+            {
+                "id": headline.id,
+                "symbol": symbol,
+                "title": headline.title,
+                "url": headline.url,
+                "source": headline.source or source.name,
+                "published_at": headline.published_at,
+                "sentiment": score_headline(headline.title),
+            }
+            for headline in headlines
+            if cutoff_ts <= headline.published_at <= now_ts
+        ]
+        if rows:
+            await db.save_news_headlines(rows)
+            stored += len(rows)
+
+    await db.prune_news_headlines(before_ts=cutoff_ts)
+    for security in securities:
+        symbol = security["symbol"]
+        recent = await db.get_news_headlines(symbol, since_ts=cutoff_ts)
+        sentiment = decayed_sentiment(recent, now_ts=now_ts, half_life_days=half_life_days)
+        await db.upsert_news_sentiment(
+            symbol,
+            score=float(sentiment["score"]),
+            weight=float(sentiment["weight"]),
+            headline_count=int(sentiment["headline_count"]),
+            updated_at=now_ts,
+        )
+    return {"securities": len(securities), "headlines": stored, "failed": failed}
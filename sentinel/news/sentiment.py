@@ -0,0 +1,139 @@
+"""Headline sentiment scoring with time decay."""
+
+from __future__ import annotations
+
+import math
+import re
+
+# Small finance lexicon. Deliberately plain: the score is a tie-breaker for
+# timing, not a research signal.
+POSITIVE_WORDS = frozenset(
+    {
+        "beat",
+        "beats",
+        "boost",
+        "boosts",
+        "bullish",
+        "buyback",
+        "gain",
+        "gains",
+        "growth",
+        "jump",
+        "jumps",
+        "outperform",
+        "outperforms",
+        "profit",
+        "raise",
+        "raises",
+        "rally",
+        "rallies",
+        "record",
+        "rebound",
+        "rise",
+        "rises",
+        "soar",
+        "soars",
+        "strong",
+        "surge",
+        "surges",
+        "upgrade",
+        "upgraded",
+        "upgrades",
+        "win",
+        "wins",
+    }
+)
+NEGATIVE_WORDS = frozenset(
+    {
+        "bearish",
+        "cut",
+        "cuts",
+        "decline",
+        "declines",
+        "downgrade",
+        "downgraded",
+        "downgrades",
+        "drop",
+        "drops",
+        "fall",
+        "falls",
+        "fraud",
+        "investigation",
+        "lawsuit",
+        "layoffs",
+        "loss",
+        "losses",
+        "miss",
+        "misses",
+        "plunge",
+        "plunges",
+        "probe",
+        "recall",
+        "slump",
+        "slumps",
+        "tumble",
+        "tumbles",
+        "underperform",
+        "warning",
+        "weak",
+    }
+)
+NEGATIONS = frozenset({"no", "not", "never", "without"})
+
+_WORD_RE = re.compile(r"[a-z']+")
+
+
+def score_headline(title: str) -> float:
+    """Score a headline in [-1, 1]: (positive - negative) / matched words.
+
+    A negation directly before a sentiment word flips it ("not strong").
+    Headlines without sentiment words score 0.
+    """
+    words = _WORD_RE.findall(title.lower())
+    positive = negative = 0
+    for i, word in enumerate(words):
+        polarity = 1 if word in POSITIVE_WORDS else -1 if word in NEGATIVE_WORDS else 0
+        if polarity and i > 0 and words[i - 1] in NEGATIONS:
+            polarity = -polarity
+        if polarity > 0:
+            positive += 1
+        elif polarity < 0:
+            negative += 1
+    matched = positive + negative
+    return (positive - negative) / matched if matched else 0.0
+
+
+def decayed_sentiment(
+    headlines: list[dict],
+    *,
+    now_ts: int,
+    half_life_days: float,
+) -> dict[str, float | int]:
+    """Combine scored headlines into one score in [-1, 1].
+
+    Each headline weighs 0.5 ** (age / half_life). The weighted sum is divided
+    by max(total weight, 1), so a single stale headline cannot move the score
+    as much as a fresh cluster of them.
+    """
+    half_life = max(float(half_life_days), 0.01) * 86400
+    weighted = 0.0
+    total_weight = 0.0
+    for headline in headlines:
+        sentiment = headline.get("sentiment")
+        if sentiment is None or not math.isfinite(float(sentiment)):
+            continue
+        age = max(0, now_ts - int(headline["published_at"]))
+        weight = 0.5 ** (age / half_life)
+        weighted += float(sentiment) * weight
+        total_weight += weight
+    score = weighted / max(total_weight, 1.0)
+    return {
+        "score": max(-1.0, min(1.0, score)),
+        "weight": total_weight,
+        "headline_count": len(headlines),
+    }
+
+
+def sentiment_timing_score(sentiment: float) -> float:
+    """Map a sentiment in [-1, 1] onto the 0..1 timing scale used by forecast scores."""
+    return max(0.0, min(1.0, (sentiment + 1.0) / 2.0))
@@ -0,0 +1,105 @@
+"""Headline providers for news ingestion."""
+
+from __future__ import annotations
+
+import hashlib
+import xml.etree.ElementTree as ET  # noqa: S405 - feeds are parsed without entity expansion
+from dataclasses import dataclass
+from email.utils import parsedate_to_datetime
+from typing import Protocol
+from urllib.parse import quote_plus
+
+import httpx
+
+
+class NewsSourceError(RuntimeError):
+    """Raised when a headline provider request fails."""
+
+
+@dataclass(frozen=True)
+class Headline:
+    """One headline about one security."""
+
+    symbol: str
+    title: str
+    published_at: int
+    url: str | None = None
+    source: str | None = None
+
+    @property
+    def id(self) -> str:
+        """Stable id, so re-fetching a feed does not duplicate headlines."""
+        key = f"{self.symbol}|{self.url or self.title}"
+        return hashlib.sha1(key.encode()).hexdigest()  # noqa: S324 - dedup key, not security
+
+
+class NewsSource(Protocol):
+    """Provider interface: headlines for one security, newest or oldest first."""
+
+    name: str
+
+    async def fetch(self, symbol: str, security_name: str | None = None) -> list[Headline]: ...
+
+
+def ticker_of(symbol: str) -> str:
+    """Strip the broker's exchange suffix: AAPL.US -> AAPL."""
+    return symbol.rsplit(".", 1)[0] if "." in symbol else symbol
+
+
+def parse_rss(symbol: str, payload: str, source: str | None = None) -> list[Headline]:
+    """Parse RSS 2.0 `<item>` elements into headlines; items without a date are skipped."""
+    try:
+        root = ET.fromstring(payload)  # noqa: S314
+    except ET.ParseError as exc:
+        raise NewsSourceError(f"Malformed feed for {symbol}: {exc}") from exc
+
+    headlines = []
+    for item in root.iter("item"):
+        title = (item.findtext("title") or "").strip()
+        pub_date = (item.findtext("pubDate") or "").strip()
+        if not title or not pub_date:
+            continue
+        try:
+            published_at = int(parsedate_to_datetime(pub_date).timestamp())
+        except (TypeError, ValueError):
+            continue
+        headlines.append(
+            Headline(
+                symbol=symbol,
+                title=title,
+                published_at=published_at,
+                url=(item.findtext("link") or "").strip() or None,
+                source=source,
+            )
+        )
+    return headlines
+
+
+@dataclass(frozen=True)
+class RssNewsSource:
+    """RSS feed per security, from a URL template.
+
+    The template may use `{symbol}` (AAPL.US), `{ticker}` (AAPL) and
+    `{query}` (the URL-encoded security name, or ticker when unknown).
+    """
+
+    url_template: str
+    timeout_seconds: float = 15.0
+    name: str = "rss"
+
+    def url_for(self, symbol: str, security_name: str | None = None) -> str:
+        ticker = ticker_of(symbol)
+        return self.url_template.format(
+            symbol=quote_plus(symbol),
+            ticker=quote_plus(ticker),
+            query=quote_plus(security_name or ticker),
+        )
+
+    async def fetch(self, symbol: str, security_name: str | None = None) -> list[Headline]:
+        try:
+            async with httpx.AsyncClient(timeout=self.timeout_seconds, follow_redirects=True) as client:
+                response = await client.get(self.url_for(symbol, security_name))
+                response.raise_for_status()
+        except httpx.HTTPError as exc:
+            raise NewsSourceError(str(exc) or exc.__class__.__name__) from exc
+        return parse_rss(symbol, response.text, source=self.name)
@@ -0,0 +1,159 @@
+"""
+Notes - free-form research notes and small attachments per security.
+
+A note is markdown. Every save stores a new revision in `security_notes`
+(the latest is the current note; saving an empty body clears it), so the
+history shows how the research evolved. Attachments (a PDF, a screenshot,
+a spreadsheet) of up to MAX_ATTACHMENT_BYTES live in `security_attachments`;
+deleting one only marks it deleted, keeping it in the history.
+
+The unified view shows each security's note next to its scores, and the
+`journal` report (GET /api/reports/export) exports every note with its
+revisions and attachments as one markdown document.
+"""
+
+from __future__ import annotations
+
+import base64
+import binascii
+import inspect
+import re
+from datetime import datetime, timezone
+from typing import Any
+
+MAX_NOTE_LENGTH = 20_000
+MAX_ATTACHMENT_BYTES = 1_048_576
+MAX_ATTACHMENTS_PER_SECURITY = 20
+EXCERPT_LENGTH = 280
+_FILENAME = re.compile(r"^[^/\\\x00-\x1f]{1,120}$")
+_CONTENT_TYPE = re.compile(r"^[a-z0-9][a-z0-9.+-]*/[a-z0-9][a-z0-9.+-]*$")
+
+
+def validate_note(data: Any) -> str:
+    """Validate a {"body"} payload; returns the body without trailing whitespace.
+
+    Raises:
+        ValueError: If the body is missing, not a string or too long.
+    """
+    body = data.get("body") if isinstance(data, dict) else None
+    if not isinstance(body, str):
+        raise ValueError("'body' must be a string")
+    body = body.rstrip()
+    if len(body) > MAX_NOTE_LENGTH:
+        raise ValueError(f"'body' must be at most {MAX_NOTE_LENGTH} characters")
+    return body
+
+
+def validate_attachment(data: Any) -> tuple[str, str, bytes]:
+    """Validate a {"filename", "content_type", "content" (base64)} payload.
+
+    Returns:
+        (filename, content_type, content)
+
+    Raises:
+        ValueError: If a field is missing or malformed, or the file is empty or too large.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("attachment must be an object")
+    filename = data.get("filename")
+    if not isinstance(filename, str) or not _FILENAME.match(filename.strip()):
+        raise ValueError("'filename' must be 1-120 characters without slashes")
+    content_type = data.get("content_type", "application/octet-stream")
+    if not isinstance(content_type, str) or not _CONTENT_TYPE.match(content_type.strip().lower()):
+        raise ValueError("'content_type' must be a media type such as application/pdf")
+    raw = data.get("content")
+    if not isinstance(raw, str):
+        raise ValueError("'content' must be a base64 string")
+    try:
+        content = base64.b64decode(raw, validate=True)
+    except (binascii.Error, ValueError) as e:
+        raise ValueError("'content' must be a base64 string") from e
+    if not content or len(content) > MAX_ATTACHMENT_BYTES:
+        raise ValueError(f"attachments must be 1 to {MAX_ATTACHMENT_BYTES} bytes")
+    return filename.strip(), content_type.strip().lower(), content
+
+
+def excerpt(body: str, length: int = EXCERPT_LENGTH) -> str:
+    """The start of a note, cut at a word boundary."""
+    if len(body) <= length:
+        return body
+    return body[:length].rsplit(None, 1)[0].rstrip() + "…"
+
+
+def note_summary(note: dict | None, attachments: int = 0) -> dict[str, Any] | None:
+    """Short form of a security's current note for listings; None without note or attachments."""
+    body = (note or {}).get("body") or ""
+    if not body and not attachments:
+        return None
+    return {
+        "excerpt": excerpt(body),
+        "updated_at": (note or {}).get("created_at"),
+        "attachments": attachments,
+    }
+
+
+async def load_note_summaries(db) -> dict[str, dict[str, Any]]:
+    """note_summary of every security with a note or attachment; tolerates databases without notes."""
+    notes_getter = getattr(db, "get_security_notes", None)
+    attachments_getter = getattr(db, "get_security_attachments", None)
+    if not callable(notes_getter) or not callable(attachments_getter):
+        return {}
+    notes, attachments = notes_getter(), attachments_getter()
+    if inspect.isawaitable(notes):
+        notes = await notes
+    if inspect.isawaitable(attachments):
+        attachments = await attachments
+    if not isinstance(notes, dict) or not isinstance(attachments, list):
+        return {}
+    counts: dict[str, int] = {}
+    for attachment in attachments:
+        counts[attachment["symbol"]] = counts.get(attachment["symbol"], 0) + 1
+    summaries = {symbol: note_summary(notes.get(symbol), counts.get(symbol, 0)) for symbol in set(notes) | set(counts)}
+    return {symbol: summary for symbol, summary in summaries.items() if summary}
+
+
+def note_history(revisions: list[dict], attachments: list[dict]) -> list[dict[str, Any]]:
+    """Note revisions and attachment additions and deletions as one timeline, newest first."""
+    events = [{"at": r["created_at"], "type": "note", "id": r["id"], "body": r["body"]} for r in revisions]
+    for a in attachments:
+        file = {"id": a["id"], "filename": a["filename"], "size": a["size"]}
+        events.append({"at": a["created_at"], "type": "attachment_added", **file})
+        if a.get("deleted_at"):
+            events.append({"at": a["deleted_at"], "type": "attachment_deleted", **file})
+    return sorted(events, key=lambda e: (e["at"], e["id"]), reverse=True)
+
+
+def _stamp(ts: int) -> str:
+    return datetime.fromtimestamp(int(ts), timezone.utc).strftime("%Y-%m-%d %H:%M UTC")
+
+
+async def journal_markdown(db) -> str:
+    """Every security's note with its revisions and attachments, as one markdown document."""
+    names = {s["symbol"]: s.get("name") for s in await db.get_all_securities(active_only=False)}
+    attachments: dict[str, list[dict]] = {}
+    for attachment in await db.get_security_attachments(include_deleted=True):
+        attachments.setdefault(attachment["symbol"], []).append(attachment)
+    symbols = sorted(set(await db.get_security_notes()) | set(attachments))
+
+    lines = ["# Research journal", ""]
+    if not symbols:
+        lines.append("No notes yet.")
+    for symbol in symbols:
+        revisions = await db.get_security_note_history(symbol)
+        name = names.get(symbol)
+        lines += [f"## {symbol}" + (f" — {name}" if name and name != symbol else ""), ""]
+        if revisions and revisions[0]["body"]:
+            lines += [f"*Updated {_stamp(revisions[0]['created_at'])}*", "", revisions[0]["body"], ""]
+        files = attachments.get(symbol, [])
+        if files:
+            lines += ["### Attachments", ""]
+            for a in files:
+                deleted = f", deleted {_stamp(a['deleted_at'])}" if a.get("deleted_at") else ""
+                lines.append(f"- {a['filename']} ({a['size']} bytes, added {_stamp(a['created_at'])}{deleted})")
+            lines.append("")
+        if len(revisions) > 1:
+            lines += ["### Earlier revisions", ""]
+            for revision in revisions[1:]:
+                body = revision["body"] or "*(cleared)*"
+                lines += [f"#### {_stamp(revision['created_at'])}", "", body, ""]
+    return "\n".join(lines).rstrip() + "\n"
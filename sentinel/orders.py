@@ -0,0 +1,248 @@
+"""
+Orders - local state of broker orders placed through Sentinel.
+
+Every order Sentinel places (planner submissions and the buy/sell API) is
+recorded in the `orders` table and tracked through a small state machine:
+
+    pending ──────────► partially_filled ──► filled
+       │  ▲                   │
+       ▼  │ (cancel error)    ▼
+    cancel_requested ──► cancelled / expired
+
+plus the terminal `filled`, `cancelled`, `rejected` and `expired` reachable
+from any open status. `sync()` moves orders along with the broker's status
+codes (docs/tradernet/miscellaneous/order-statuses.md); a status the machine
+does not allow (e.g. back from `filled`) is ignored. Open broker orders
+Sentinel did not place are imported with source `broker`.
+
+Tradernet cannot amend an order, so `modify()` cancels it and places a
+replacement with the new price/quantity, linked through replaces/replaced_by.
+The original stays `cancel_requested` until the broker confirms the cancel
+(or a fill that raced it).
+
+`open_order_for()` is the duplicate guard: planner submissions are refused
+while an open order exists for the same ISIN (or symbol when the ISIN is
+unknown). Research-mode orders are simulated and never recorded.
+
+Usage:
+    service = OrderService(db, broker)
+    await service.sync()
+    pending = await service.open_order_for("ASML.EU")
+"""
+
+from __future__ import annotations
+
+import logging
+import math
+import time
+from typing import Any
+
+from sentinel.identifiers import IdentifierService
+
+logger = logging.getLogger(__name__)
+
+PENDING = "pending"
+PARTIALLY_FILLED = "partially_filled"
+CANCEL_REQUESTED = "cancel_requested"
+FILLED = "filled"
+CANCELLED = "cancelled"
+REJECTED = "rejected"
+EXPIRED = "expired"
+
+OPEN_STATUSES = (PENDING, PARTIALLY_FILLED, CANCEL_REQUESTED)
+STATUSES = (*OPEN_STATUSES, FILLED, CANCELLED, REJECTED, EXPIRED)
+
+TRANSITIONS: dict[str, set[str]] = {
+    PENDING: {PARTIALLY_FILLED, CANCEL_REQUESTED, FILLED, CANCELLED, REJECTED, EXPIRED},
+    PARTIALLY_FILLED: {CANCEL_REQUESTED, FILLED, CANCELLED, EXPIRED},
+    CANCEL_REQUESTED: {PENDING, PARTIALLY_FILLED, FILLED, CANCELLED, REJECTED, EXPIRED},
+    FILLED: set(),
+    CANCELLED: set(),
+    REJECTED: set(),
+    EXPIRED: set(),
+}
+
+# Tradernet order status code -> local status
+BROKER_STATUSES = {
+    0: REJECTED,  # ignored
+    1: PENDING,  # received
+    2: CANCEL_REQUESTED,  # processing cancel
+    10: PENDING,  # active
+    11: PENDING,  # sent
+    12: PARTIALLY_FILLED,  # partially completed
+    20: PARTIALLY_FILLED,  # partially performed
+    21: FILLED,
+    30: CANCELLED,  # partially canceled
+    31: CANCELLED,
+    70: REJECTED,
+    71: EXPIRED,
+    72: EXPIRED,  # partially executed and expired
+    74: REJECTED,  # send error
+    75: PENDING,  # cancel error: the order is still live
+}
+
+# Local open orders the broker no longer lists are expired after this long.
+STALE_AFTER_SECONDS = 86400
+RESEARCH_PREFIX = "RESEARCH-"
+
+
+class OrderNotFound(LookupError):
+    pass
+
+
+class OrderStateError(Exception):
+    """The order's status does not allow the requested action."""
+
+
+class BrokerRefused(Exception):
+    """The broker did not accept a cancel or a replacement order."""
+
+
+def status_from_broker(code: Any) -> str | None:
+    """Local status for a Tradernet status code; None if unknown."""
+    try:
+        return BROKER_STATUSES.get(int(code))
+    except (TypeError, ValueError):
+        return None
+
+
+def can_transition(current: str, new: str) -> bool:
+    return new in TRANSITIONS.get(current, set())
+
+
+def _positive(value: Any, name: str) -> float:
+    if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value) or value <= 0:
+        raise ValueError(f"{name} must be a positive number")
+    return float(value)
+
+
+class OrderService:
+    """Records, syncs, cancels and modifies orders."""
+
+    def __init__(self, db, broker):
+        self._db = db
+        self._broker = broker
+
+    async def record(
+        self,
+        order_id: str,
+        symbol: str,
+        side: str,
+        quantity: float,
+        price: float | None = None,
+        source: str = "api",
+        replaces: str | None = None,
+    ) -> dict | None:
+        """Record a newly placed order as pending; research-mode orders are skipped."""
+        order_id = str(order_id)
+        if order_id.startswith(RESEARCH_PREFIX):
+            return None
+        await self._db.insert_order(
+            order_id=order_id,
+            symbol=symbol,
+            isin=await IdentifierService(self._db).isin_for(symbol),
+            side=side.lower(),
+            quantity=quantity,
+            price=price,
+            status=PENDING,
+            source=source,
+            replaces=replaces,
+        )
+        return await self._db.get_order(order_id)
+
+    async def list_orders(self, status: str = "open", limit: int = 100) -> list[dict]:
+        """Orders by status: "open", "all" or one of STATUSES."""
+        if status == "all":
+            statuses = None
+        elif status == "open":
+            statuses = OPEN_STATUSES
+        elif status in STATUSES:
+            statuses = (status,)
+        else:
+            raise ValueError(f"status must be open, all or one of {', '.join(STATUSES)}")
+        return await self._db.get_orders(statuses, limit=limit)
+
+    async def sync(self, now: int | None = None) -> dict[str, int] | None:
+        """Apply the broker's order statuses; None when the broker could not be read."""
+        now = int(time.time()) if now is None else now
+        broker_orders = await self._broker.get_orders(active_only=False)
+        active = await self._broker.get_orders(active_only=True)
+        if not isinstance(broker_orders, list) or not isinstance(active, list):
+            return None
+        by_id = {order["order_id"]: order for order in [*broker_orders, *active] if order.get("order_id")}
+
+        result = {"updated": 0, "imported": 0, "expired": 0}
+        for order_id, order in by_id.items():
+            status = status_from_broker(order.get("status"))
+            local = await self._db.get_order(order_id)
+            if local is None:
+                if status in OPEN_STATUSES and order.get("symbol"):
+                    quantity = order.get("quantity") or 0
+                    await self.record(order_id, order["symbol"], order["side"], quantity, order.get("price"), "broker")
+                    await self._db.update_order(order_id, status=status, broker_status=order.get("status"))
+                    result["imported"] += 1
+                continue
+            if status and status != local["status"] and can_transition(local["status"], status):
+                await self._db.update_order(order_id, status=status, broker_status=order.get("status"))
+                result["updated"] += 1
+                logger.info(f"Order {order_id} ({local['symbol']}): {local['status']} -> {status}")
+
+        for local in await self._db.get_orders(OPEN_STATUSES, limit=1000):
+            if local["order_id"] not in by_id and now - local["updated_at"] >= STALE_AFTER_SECONDS:
+                await self._db.update_order(local["order_id"], status=EXPIRED)
+                result["expired"] += 1
+                logger.warning(f"Order {local['order_id']} ({local['symbol']}) no longer listed by the broker; expired")
+        return result
+
+    async def open_order_for(self, symbol: str) -> dict | None:
+        """The newest open order for the same ISIN as `symbol` (or the same symbol without one)."""
+        orders = await self._db.get_orders(OPEN_STATUSES, limit=1000)
+        if not isinstance(orders, list):
+            return None
+        ids = IdentifierService(self._db)
+        isin = await ids.isin_for(symbol)
+        symbol = await ids.canonical(symbol)
+        for order in orders:
+            if (isin and order["isin"] == isin) or order["symbol"] == symbol:
+                return order
+        return None
+
+    async def _open_order(self, order_id: str, allowed: tuple[str, ...]) -> dict:
+        order = await self._db.get_order(order_id)
+        if order is None:
+            raise OrderNotFound(f"Order {order_id} not found")
+        if order["status"] not in allowed:
+            raise OrderStateError(f"Order {order_id} is {order['status']}")
+        return order
+
+    async def cancel(self, order_id: str) -> dict:
+        """Ask the broker to cancel an open order; it becomes cancel_requested."""
+        order = await self._open_order(order_id, (PENDING, PARTIALLY_FILLED))
+        if not await self._broker.cancel_order(order_id):
+            raise BrokerRefused(f"Broker did not accept cancelling order {order_id}")
+        await self._db.update_order(order_id, status=CANCEL_REQUESTED)
+        return {**order, "status": CANCEL_REQUESTED}
+
+    async def modify(self, order_id: str, price: float | None = None, quantity: int | None = None) -> dict:
+        """Cancel a pending order and place a replacement with a new price and/or quantity."""
+        if price is None and quantity is None:
+            raise ValueError("price or quantity is required")
+        if price is not None:
+            price = _positive(price, "price")
+        if quantity is not None:
+            if isinstance(quantity, bool) or not isinstance(quantity, int) or quantity <= 0:
+                raise ValueError("quantity must be a positive integer")
+        order = await self._open_order(order_id, (PENDING,))
+        price = price if price is not None else order["price"]
+        quantity = quantity if quantity is not None else int(order["quantity"])
+
+        await self.cancel(order_id)
+        place = self._broker.sell if order["side"] == "sell" else self._broker.buy
+        new_id = await place(order["symbol"], quantity, price)
+        if not new_id:
+            raise BrokerRefused(f"Order {order_id} is being cancelled but the replacement was not accepted")
+        replacement = await self.record(
+            str(new_id), order["symbol"], order["side"], quantity, price, order["source"], replaces=order_id
+        )
+        await self._db.update_order(order_id, replaced_by=str(new_id))
+        return replacement or {"order_id": str(new_id)}
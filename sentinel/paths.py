@@ -3,7 +3,13 @@
 import os
 from pathlib import Path
 
+from sentinel.demo import demo_enabled
+
 # Project root is the parent of the sentinel package directory
 _PROJECT_ROOT = Path(__file__).parent.parent
 
 DATA_DIR = Path(os.environ.get("SENTINEL_DATA_DIR", _PROJECT_ROOT / "data"))
+
+# Demo mode keeps its synthetic data apart from the real one.
+if demo_enabled():
+    DATA_DIR = DATA_DIR / "demo"
@@ -0,0 +1,272 @@
+"""
+Runtime metrics, on-demand profiling and job duration budgets.
+
+Runtime metrics cover memory (RSS, Python heap when tracing), the garbage
+collector (collections and pause times, measured through gc.callbacks once
+GCTimer is installed), threads and asyncio tasks.
+
+Profiling is for diagnosing a slow or bloated process in place: a CPU
+profile traces the event loop thread with cProfile for a few seconds, and
+a heap profile lists the top allocation sites from tracemalloc. Both are
+served only to admin requests (`admin_token`, see sentinel.api.dependencies).
+
+Every job type can have a duration budget in seconds (`job_duration_budgets`).
+A run longer than budget x `job_budget_warning_factor` logs a warning and is
+kept in a short in-memory list of overruns.
+
+Usage:
+    GCTimer.install()
+    metrics = runtime_metrics()
+    text = await cpu_profile(seconds=10)
+    overrun = await check_budget("sync:prices", duration_ms)
+"""
+
+from __future__ import annotations
+
+import asyncio
+import cProfile
+import gc
+import io
+import logging
+import os
+import pstats
+import resource
+import sys
+import threading
+import time
+import tracemalloc
+from collections import deque
+from typing import Any
+
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+BUDGETS_KEY = "job_duration_budgets"
+BUDGET_FACTOR_KEY = "job_budget_warning_factor"
+DEFAULT_BUDGET_FACTOR = 1.5
+
+MAX_PROFILE_SECONDS = 60
+HEAP_TRACE_FRAMES = 10
+MAX_OVERRUNS = 50
+
+_started_at = time.time()
+_profile_lock = asyncio.Lock()
+_overruns: deque[dict[str, Any]] = deque(maxlen=MAX_OVERRUNS)
+
+
+class GCTimer:
+    """Measures garbage collector pauses via gc.callbacks."""
+
+    _installed = False
+    _started: float | None = None
+    collections = 0
+    total_pause_ms = 0.0
+    max_pause_ms = 0.0
+    last_pause_ms = 0.0
+
+    @classmethod
+    def install(cls) -> None:
+        if not cls._installed:
+            gc.callbacks.append(cls._callback)
+            cls._installed = True
+
+    @classmethod
+    def uninstall(cls) -> None:
+        if cls._installed:
+            gc.callbacks.remove(cls._callback)
+            cls._installed = False
+
+    @classmethod
+    def _callback(cls, phase: str, info: dict) -> None:
+        if phase == "start":
+            cls._started = time.perf_counter()
+        elif phase == "stop" and cls._started is not None:
+            pause = (time.perf_counter() - cls._started) * 1000
+            cls._started = None
+            cls.collections += 1
+            cls.total_pause_ms += pause
+            cls.max_pause_ms = max(cls.max_pause_ms, pause)
+            cls.last_pause_ms = pause
+
+    @classmethod
+    def stats(cls) -> dict[str, Any]:
+        return {
+            "timed": cls._installed,
+            "timed_collections": cls.collections,
+            "total_pause_ms": round(cls.total_pause_ms, 3),
+            "max_pause_ms": round(cls.max_pause_ms, 3),
+            "last_pause_ms": round(cls.last_pause_ms, 3),
+        }
+
+
+def _rss_mb() -> float | None:
+    try:
+        with open("/proc/self/statm") as f:
+            pages = int(f.read().split()[1])
+    except (OSError, ValueError, IndexError):
+        return None
+    return pages * os.sysconf("SC_PAGE_SIZE") / (1024 * 1024)
+
+
+def _max_rss_mb() -> float:
+    # ru_maxrss is KiB on Linux, bytes on macOS.
+    rss = resource.getrusage(resource.RUSAGE_SELF).ru_maxrss
+    return rss / (1024 * 1024) if sys.platform == "darwin" else rss / 1024
+
+
+def runtime_metrics() -> dict[str, Any]:
+    """Process memory, garbage collector, thread and task counts."""
+    rss = _rss_mb()
+    heap = None
+    if tracemalloc.is_tracing():
+        current, peak = tracemalloc.get_traced_memory()
+        heap = {"current_mb": round(current / (1024 * 1024), 2), "peak_mb": round(peak / (1024 * 1024), 2)}
+    try:
+        tasks = len(asyncio.all_tasks())
+    except RuntimeError:
+        tasks = None
+    return {
+        "uptime_seconds": int(time.time() - _started_at),
+        "memory": {
+            "rss_mb": round(rss, 1) if rss is not None else None,
+            "max_rss_mb": round(_max_rss_mb(), 1),
+            "allocated_blocks": sys.getallocatedblocks(),
+            "heap": heap,
+        },
+        "gc": {
+            "counts": list(gc.get_count()),
+            "thresholds": list(gc.get_threshold()),
+            "generations": gc.get_stats(),
+            **GCTimer.stats(),
+        },
+        "threads": threading.active_count(),
+        "asyncio_tasks": tasks,
+    }
+
+
+async def cpu_profile(seconds: float, limit: int = 40, sort: str = "cumulative") -> str:
+    """Profile the event loop thread for `seconds` and return the pstats report.
+
+    Raises:
+        ValueError: If `seconds` is out of range or `sort` is unknown.
+        RuntimeError: If another profile is running.
+    """
+    if not 0 < seconds <= MAX_PROFILE_SECONDS:
+        raise ValueError(f"seconds must be between 0 and {MAX_PROFILE_SECONDS}")
+    if sort not in pstats.Stats.sort_arg_dict_default:
+        raise ValueError(f"Unknown sort key: {sort}")
+    if _profile_lock.locked():
+        raise RuntimeError("A profile is already running")
+
+    async with _profile_lock:
+        profiler = cProfile.Profile()
+        profiler.enable()
+        try:
+            await asyncio.sleep(seconds)
+        finally:
+            profiler.disable()
+
+    out = io.StringIO()
+    pstats.Stats(profiler, stream=out).sort_stats(sort).print_stats(limit)
+    return out.getvalue()
+
+
+def start_heap_trace() -> bool:
+    """Start tracing allocations. Returns False if already tracing."""
+    if tracemalloc.is_tracing():
+        return False
+    tracemalloc.start(HEAP_TRACE_FRAMES)
+    return True
+
+
+def stop_heap_trace() -> bool:
+    """Stop tracing allocations. Returns False if not tracing."""
+    if not tracemalloc.is_tracing():
+        return False
+    tracemalloc.stop()
+    return True
+
+
+def heap_profile(limit: int = 25) -> list[dict[str, Any]]:
+    """Top allocation sites by size since tracing started.
+
+    Raises:
+        RuntimeError: If allocations are not being traced.
+    """
+    if not tracemalloc.is_tracing():
+        raise RuntimeError("Heap tracing is not running")
+    snapshot = tracemalloc.take_snapshot().filter_traces(
+        [tracemalloc.Filter(False, tracemalloc.__file__), tracemalloc.Filter(False, "<frozen importlib._bootstrap>")]
+    )
+    return [
+        {"location": str(stat.traceback[0]), "size_kb": round(stat.size / 1024, 1), "count": stat.count}
+        for stat in snapshot.statistics("lineno")[:limit]
+    ]
+
+
+def validate_budgets(value: Any) -> dict[str, float]:
+    """Validate {job_type: seconds} budgets.
+
+    Raises:
+        ValueError: If it is not a dict of positive numbers.
+    """
+    if not isinstance(value, dict):
+        raise ValueError("job_duration_budgets must be an object of job type -> seconds")
+    budgets = {}
+    for job_type, seconds in value.items():
+        if isinstance(seconds, bool) or not isinstance(seconds, int | float) or seconds <= 0:
+            raise ValueError(f"Budget for {job_type} must be a positive number of seconds")
+        budgets[str(job_type)] = float(seconds)
+    return budgets
+
+
+def validate_budget_factor(value: Any) -> float:
+    """Validate the budget warning factor.
+
+    Raises:
+        ValueError: If it is not a number of at least 1.
+    """
+    if isinstance(value, bool) or not isinstance(value, int | float) or value < 1:
+        raise ValueError("job_budget_warning_factor must be a number >= 1")
+    return float(value)
+
+
+async def budgets(settings: Settings | None = None) -> tuple[dict[str, float], float]:
+    """Configured budgets and warning factor; invalid settings fall back to none / the default."""
+    settings = settings or Settings()
+    try:
+        job_budgets = validate_budgets(await settings.get(BUDGETS_KEY, {}) or {})
+    except ValueError:
+        job_budgets = {}
+    try:
+        factor = validate_budget_factor(await settings.get(BUDGET_FACTOR_KEY, DEFAULT_BUDGET_FACTOR))
+    except ValueError:
+        factor = DEFAULT_BUDGET_FACTOR
+    return job_budgets, factor
+
+
+async def check_budget(job_type: str, duration_ms: int, settings: Settings | None = None) -> dict[str, Any] | None:
+    """Warn and record an overrun if a run took longer than its budget allows."""
+    job_budgets, factor = await budgets(settings)
+    budget = job_budgets.get(job_type)
+    if budget is None or duration_ms / 1000 <= budget * factor:
+        return None
+
+    overrun = {
+        "job_type": job_type,
+        "duration_seconds": round(duration_ms / 1000, 1),
+        "budget_seconds": budget,
+        "factor": factor,
+        "at": int(time.time()),
+    }
+    _overruns.append(overrun)
+    logger.warning(
+        f"Job {job_type} took {overrun['duration_seconds']}s, over its {budget:.0f}s budget (x{factor:g} allowed)"
+    )
+    return overrun
+
+
+def recent_overruns() -> list[dict[str, Any]]:
+    """Recorded budget overruns, newest first."""
+    return list(reversed(_overruns))
@@ -9,13 +9,19 @@ from datetime import datetime, timezone
 
 from sentinel.currency import Currency
 from sentinel.database import Database
+from sentinel.exclusions import exclusion_matches, load_exclusion_lists
 from sentinel.forecasting.scoring import adjusted_opportunity_score
+from sentinel.planner.allocation_history import allocation_constraints, record_allocation_run
+from sentinel.planner.goals import load_goal_tilt
+from sentinel.planner.groups import apply_group_targets, load_security_groups
+from sentinel.planner.income import load_income_multipliers
 from sentinel.planner.preferences import (
     apply_max_cap,
     normalize_user_multiplier,
     normalize_weights,
     preference_tilt,
 )
+from sentinel.planner.targets import apply_position_targets, position_target
 from sentinel.portfolio import Portfolio
 from sentinel.settings import DEFAULTS, Settings
 from sentinel.strategy import (
@@ -25,7 +31,8 @@ from sentinel.strategy import (
 )
 
 # A security only participates in the ideal allocation if the user has actively
-# endorsed it above the configured threshold and it is buyable. The rebalance
+# endorsed it above the configured threshold (or given it a manual position
+# target), it is buyable and no exclusion list matches it. The rebalance
 # engine still sees non-qualifying securities so it can plan sells / maintenance
 # on legacy holdings; these gates only affect what the *ideal* portfolio holds.
 
@@ -115,6 +122,9 @@ class AllocationCalculator:
             return {}
 
         config = await self._load_strategy_settings()
+        if as_of_date is None:
+            # A goal behind schedule invests more of the cash target.
+            config["target_cash_pct"] *= 1 - await load_goal_tilt(self._db, self._settings)
         entry_t1_dd = config["strategy_entry_t1_dd"]
         entry_t3_dd = config["strategy_entry_t3_dd"]
         entry_memory_days = int(config["strategy_entry_memory_days"])
@@ -128,7 +138,9 @@ class AllocationCalculator:
         rebalance_signals: dict[str, dict[str, float | int | str]] = {}
         clara_raw_weights: dict[str, float] = {}
         preference_details: dict[str, dict[str, float]] = {}
+        manual_targets: dict[str, dict] = {}
         symbols = [sec["symbol"] for sec in securities]
+        exclusion_lists = await load_exclusion_lists(self._db)
         forecast_scores: dict[str, dict] = {}
         if as_of_date is None and forecasting_enabled:
             forecast_getter = getattr(self._db, "get_latest_forecast_scores", None)
@@ -204,15 +216,26 @@ class AllocationCalculator:
             # flows to the securities the user actually wants.
             # Signals stay populated above so the rebalance engine can still
             # plan sells / maintenance on legacy holdings.
-            if stored_preference < ideal_qualifying_threshold:
-                continue
             if not int(sec.get("allow_buy", 1) or 0):
                 continue
+            if exclusion_lists and exclusion_matches(sec, exclusion_lists):
+                continue
+            target = position_target(sec)
+            if target and target["source"] == "manual":
+                manual_targets[symbol] = target
+            if stored_preference < ideal_qualifying_threshold:
+                continue
 
             # Clara defines the destination. Price signals are retained for
             # today's timing decision, but never alter long-term target weights.
             clara_raw_weights[symbol] = preference_tilt(stored_preference, preference_strength)
 
+        if as_of_date is None and clara_raw_weights:
+            # The income objective leans toward held dividend payers while income is short of its target.
+            for symbol, multiplier in (await load_income_multipliers(self._db, self._settings)).items():
+                if symbol in clara_raw_weights:
+                    clara_raw_weights[symbol] *= multiplier
+
         # Apply dividend reinvestment boost
         max_div_boost = config["max_dividend_reinvestment_boost"]
         if max_div_boost > 0:
@@ -268,6 +291,7 @@ class AllocationCalculator:
         max_position = config["max_position_pct"] / 100.0
         target_cash = max(0.0, min(1.0, config["target_cash_pct"] / 100.0))
         target_security_total = 1.0 - target_cash
+        security_groups: list[dict] = []
         if target_security_total <= 0:
             bounded = {}
         else:
@@ -276,6 +300,46 @@ class AllocationCalculator:
                 symbol: weight * target_security_total
                 for symbol, weight in apply_max_cap(allocations, unit_cap).items()
             }
+            if manual_targets:
+                bounded = apply_position_targets(
+                    bounded,
+                    manual_targets,
+                    budget=target_security_total,
+                    max_position=max_position,
+                )
+            security_groups = await load_security_groups(self._db)
+            if security_groups:
+                bounded = apply_group_targets(
+                    bounded,
+                    security_groups,
+                    budget=target_security_total,
+                    max_position=max_position,
+                    pinned={symbol for symbol, target in manual_targets.items() if target["mode"] == "hard"},
+                )
+        for symbol, target in manual_targets.items():
+            if symbol not in decomposition:
+                # Held only because of its manual target.
+                opportunity_score = float(symbol_signals.get(symbol, {}).get("opp_score", 0.0) or 0.0)
+                sleeves[symbol] = "opportunity" if opportunity_score >= min_opp_score else "core"
+                decomposition[symbol] = {
+                    "baseline_target_pct": 0.0,
+                    "clara_target_pct": 0.0,
+                    "opportunity_target_pct": 0.0,
+                    "final_target_pct": 0.0,
+                    "allocation_sleeve": sleeves[symbol],
+                    "user_multiplier": preference_details.get(symbol, {}).get("user_multiplier", 0.5),
+                }
+                signal_update = {"sleeve": sleeves[symbol], "user_multiplier": decomposition[symbol]["user_multiplier"]}
+                rebalance_signals.setdefault(symbol, {}).update(signal_update)
+                symbol_signals.setdefault(symbol, {}).update(signal_update)
+            if target["mode"] == "hard":
+                # Hard targets converge like core holdings, not on dip tranches.
+                sleeves[symbol] = "core"
+                decomposition[symbol]["allocation_sleeve"] = "core"
+                rebalance_signals.setdefault(symbol, {})["sleeve"] = "core"
+                symbol_signals.setdefault(symbol, {})["sleeve"] = "core"
+            decomposition[symbol]["position_target_pct"] = target["target_pct"] / 100.0
+            decomposition[symbol]["position_target_mode"] = target["mode"]
         for symbol, final_weight in bounded.items():
             if symbol in decomposition:
                 original_weight = float(decomposition[symbol].get("final_target_pct", 0.0) or 0.0)
@@ -366,4 +430,10 @@ class AllocationCalculator:
                 )
                 if inspect.isawaitable(maybe_set):
                     await maybe_set
+            await record_allocation_run(
+                self._db,
+                bounded,
+                allocation_decomposition["global"]["target_model"],
+                allocation_constraints(config, target_cash, manual_targets, security_groups),
+            )
         return bounded
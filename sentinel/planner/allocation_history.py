@@ -0,0 +1,207 @@
+"""
+Allocation history - every live ideal-portfolio result, to trace re-allocations.
+
+Each live run of AllocationCalculator.calculate_ideal_portfolio() is recorded
+with its target weights, the target model (`clara_risk`) and the constraints
+it was computed under: position cap, cash target after the goal tilt, the
+qualifying threshold and preference strength, manual position targets and
+security groups. A run whose weights and constraints match the previous one
+only moves that run's `last_seen_at`, so the history holds changes, not the
+ten-minute cache refreshes.
+
+The allocation is rule-based rather than an optimizer, so there is no
+objective value; each run keeps its effective number of holdings (1 / sum of
+squared weights) and its turnover against the previous run instead.
+compare_runs() reports the weight changes and the constraint changes between
+two runs, weight_drift() the weight of each symbol across runs. The planner
+has no market-regime model; regime-like shifts show up as constraint and
+setting changes.
+
+Usage:
+    constraints = allocation_constraints(config, target_cash, manual_targets, groups)
+    await record_allocation_run(db, weights, "clara_risk", constraints)
+    compare_runs(older, newer)
+"""
+
+from __future__ import annotations
+
+import inspect
+import json
+import logging
+import time
+from typing import Any
+
+logger = logging.getLogger(__name__)
+
+MAX_RUNS = 1000
+# Weight changes smaller than this are noise.
+WEIGHT_TOLERANCE = 1e-4
+
+
+def effective_holdings(weights: dict[str, float]) -> float | None:
+    """1 / sum of squared weights over the invested part; None when nothing is held."""
+    total = sum(w for w in weights.values() if w > 0)
+    if total <= 0:
+        return None
+    return 1.0 / sum((w / total) ** 2 for w in weights.values() if w > 0)
+
+
+def turnover(before: dict[str, float], after: dict[str, float]) -> float:
+    """One-way turnover between two weight sets: half the summed absolute changes."""
+    symbols = set(before) | set(after)
+    return sum(abs(after.get(s, 0.0) - before.get(s, 0.0)) for s in symbols) / 2
+
+
+def _same_weights(before: dict[str, float], after: dict[str, float]) -> bool:
+    symbols = set(before) | set(after)
+    return all(abs(after.get(s, 0.0) - before.get(s, 0.0)) <= WEIGHT_TOLERANCE for s in symbols)
+
+
+def allocation_constraints(
+    config: dict[str, float],
+    target_cash: float,
+    manual_targets: dict[str, dict[str, Any]],
+    security_groups: list[dict[str, Any]],
+) -> dict[str, Any]:
+    """The inputs an allocation was bounded by, in a form that compares equal across runs."""
+    return {
+        "max_position_pct": round(float(config["max_position_pct"]), 4),
+        "target_cash_pct": round(target_cash * 100, 2),
+        "qualifying_threshold": round(float(config["strategy_ideal_qualifying_threshold"]), 4),
+        "preference_strength": round(float(config["clara_preference_strength"]), 4),
+        "position_targets": {
+            symbol: {"target_pct": target["target_pct"], "mode": target["mode"]}
+            for symbol, target in sorted(manual_targets.items())
+        },
+        "groups": {
+            str(group.get("name")): {
+                "target_pct": group.get("target_pct"),
+                "symbols": sorted(group.get("symbols") or []),
+            }
+            for group in security_groups
+            if isinstance(group, dict)
+        },
+    }
+
+
+async def _call(db, name: str, *args, **kwargs) -> Any:
+    method = getattr(db, name, None)
+    if not callable(method):
+        raise AttributeError(name)
+    result = method(*args, **kwargs)
+    return await result if inspect.isawaitable(result) else result
+
+
+async def record_allocation_run(
+    db,
+    weights: dict[str, float],
+    model: str,
+    constraints: dict[str, Any],
+    now: int | None = None,
+) -> int | None:
+    """Record a live allocation result; returns the run id, or None when it could not be stored.
+
+    A result equal to the latest run only refreshes that run's `last_seen_at`.
+    """
+    now = int(time.time()) if now is None else now
+    weights = {symbol: round(float(w), 6) for symbol, w in sorted(weights.items()) if w > 0}
+    # Compare what will be stored: JSON turns tuples into lists and keys into strings.
+    constraints = json.loads(json.dumps(constraints, default=str))
+    try:
+        latest = await _call(db, "get_latest_allocation_run")
+        if isinstance(latest, dict):
+            if latest["model"] == model and latest["constraints"] == constraints and _same_weights(
+                latest["weights"], weights
+            ):
+                await _call(db, "touch_allocation_run", latest["id"], now)
+                return latest["id"]
+            change = turnover(latest["weights"], weights)
+        else:
+            change = None
+        run_id = await _call(
+            db,
+            "insert_allocation_run",
+            model=model,
+            weights=weights,
+            constraints=constraints,
+            effective_holdings=effective_holdings(weights),
+            turnover=change,
+            created_at=now,
+        )
+        await _call(db, "prune_allocation_runs", MAX_RUNS)
+    except Exception as e:
+        logger.debug(f"Allocation run not recorded: {e}")
+        return None
+    if change is not None and change > 0.05:
+        logger.info(f"Ideal portfolio re-allocated: {change:.1%} turnover since the previous run")
+    return run_id
+
+
+def _constraint_changes(before: dict[str, Any], after: dict[str, Any]) -> dict[str, Any]:
+    return {
+        key: {"from": before.get(key), "to": after.get(key)}
+        for key in sorted(set(before) | set(after))
+        if before.get(key) != after.get(key)
+    }
+
+
+def compare_runs(before: dict[str, Any], after: dict[str, Any]) -> dict[str, Any]:
+    """How target weights and constraints changed from one run to another.
+
+    Returns:
+        {"from", "to", "turnover", "weights": [...], "added", "removed",
+        "constraints": {key: {"from", "to"}}}; weight rows are sorted by the
+        size of the change.
+    """
+    old, new = before["weights"], after["weights"]
+    rows = []
+    for symbol in sorted(set(old) | set(new)):
+        change = new.get(symbol, 0.0) - old.get(symbol, 0.0)
+        if abs(change) <= WEIGHT_TOLERANCE:
+            continue
+        rows.append(
+            {
+                "symbol": symbol,
+                "from_pct": round(old.get(symbol, 0.0) * 100, 4),
+                "to_pct": round(new.get(symbol, 0.0) * 100, 4),
+                "change_pct": round(change * 100, 4),
+            }
+        )
+    rows.sort(key=lambda row: -abs(row["change_pct"]))
+
+    def header(run: dict[str, Any]) -> dict[str, Any]:
+        return {key: run.get(key) for key in ("id", "created_at", "last_seen_at", "model", "effective_holdings")}
+
+    return {
+        "from": header(before),
+        "to": header(after),
+        "turnover": round(turnover(old, new), 6),
+        "weights": rows,
+        "added": sorted(set(new) - set(old)),
+        "removed": sorted(set(old) - set(new)),
+        "constraints": _constraint_changes(before.get("constraints") or {}, after.get("constraints") or {}),
+    }
+
+
+def weight_drift(runs: list[dict[str, Any]], symbols: list[str] | None = None) -> dict[str, Any]:
+    """Each symbol's target weight across runs (oldest first), with the runs' turnover.
+
+    Args:
+        runs: Runs with weights, oldest first
+        symbols: Only these symbols; all symbols ever held by default
+    """
+    wanted = symbols or sorted({symbol for run in runs for symbol in run["weights"]})
+    return {
+        "runs": [
+            {
+                "id": run["id"],
+                "created_at": run["created_at"],
+                "turnover": run.get("turnover"),
+                "effective_holdings": run.get("effective_holdings"),
+            }
+            for run in runs
+        ],
+        "symbols": {
+            symbol: [round(run["weights"].get(symbol, 0.0) * 100, 4) for run in runs] for symbol in wanted
+        },
+    }
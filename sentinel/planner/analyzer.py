@@ -8,6 +8,9 @@ from datetime import datetime, timezone
 
 from sentinel.currency import Currency
 from sentinel.database import Database
+from sentinel.planner.drift import DRIFT_BANDS_KEY, evaluate_drift_bands, validate_drift_bands
+from sentinel.planner.groups import group_allocations, load_security_groups
+from sentinel.planner.targets import position_target_drift
 from sentinel.portfolio import Portfolio
 from sentinel.settings import Settings
 
@@ -197,26 +200,70 @@ class PortfolioAnalyzer:
             "rebalance_threshold_pct": threshold * 100,
             "needs_rebalance": False,
             "status": "aligned",
+            "breached_bands": 0,
         }
 
-    async def get_rebalance_summary(self) -> dict:
-        """Get summary of portfolio alignment with ideal allocations.
-
-        Returns:
-            dict with alignment metrics and status
-        """
+    async def _ideal_allocations(self) -> dict[str, float]:
         from sentinel.planner.allocation import AllocationCalculator
 
-        threshold = await self._rebalance_threshold()
-        current = await self.get_current_allocations()
-
         calculator = AllocationCalculator(
             db=self._db,
             portfolio=self._portfolio,
             currency=self._currency,
             settings=self._settings,
         )
-        ideal = await calculator.calculate_ideal_portfolio()
+        return await calculator.calculate_ideal_portfolio()
+
+    async def _drift_band_rows(self, current: dict[str, float], ideal: dict[str, float]) -> list[dict]:
+        """Evaluate the configured drift bands; malformed stored bands are ignored."""
+        try:
+            bands = validate_drift_bands(await self._settings.get(DRIFT_BANDS_KEY, {}))
+        except ValueError:
+            bands = {}
+        if not bands or not current or not ideal:
+            return []
+        securities = await self._db.get_all_securities(active_only=False)
+        securities_map = {s["symbol"]: s for s in securities}
+        return evaluate_drift_bands(current, ideal, securities_map, bands)
+
+    async def get_drift_bands(self) -> dict:
+        """Get current drift per configured drift band and which bands are breached."""
+        current = await self.get_current_allocations()
+        ideal = await self._ideal_allocations()
+        rows = await self._drift_band_rows(current, ideal)
+        return {
+            "bands": rows,
+            "breached_count": sum(1 for row in rows if row["breached"]),
+        }
+
+    async def get_position_targets(self) -> dict:
+        """Get every position target with its current drift, largest drift first."""
+        securities = await self._db.get_all_securities(active_only=False)
+        if not any(s.get("target_weight_pct") is not None for s in securities):
+            return {"targets": []}
+        current = await self.get_current_allocations()
+        ideal = await self._ideal_allocations()
+        return {"targets": position_target_drift(securities, current, ideal)}
+
+    async def get_group_allocations(self) -> dict:
+        """Get every security group with its current and ideal weight."""
+        groups = await load_security_groups(self._db)
+        current = await self.get_current_allocations()
+        ideal = await self._ideal_allocations()
+        return {"groups": group_allocations(groups, current, ideal)}
+
+    async def get_rebalance_summary(self) -> dict:
+        """Get summary of portfolio alignment with ideal allocations.
+
+        A breached drift band marks the portfolio as needing a rebalance even
+        when every position is within `rebalance_threshold_pct`.
+
+        Returns:
+            dict with alignment metrics and status
+        """
+        threshold = await self._rebalance_threshold()
+        current = await self.get_current_allocations()
+        ideal = await self._ideal_allocations()
 
         if not current or not ideal:
             return self._empty_rebalance_summary(threshold)
@@ -247,6 +294,10 @@ class PortfolioAnalyzer:
             status = "minor_drift"
         else:
             status = "needs_rebalance"
+
+        breached_bands = sum(1 for row in await self._drift_band_rows(current, ideal) if row["breached"])
+        if breached_bands:
+            status = "needs_rebalance"
         needs_rebalance = status != "aligned"
 
         return {
@@ -259,6 +310,7 @@ class PortfolioAnalyzer:
             "rebalance_threshold_pct": threshold * 100,
             "needs_rebalance": needs_rebalance,
             "status": status,
+            "breached_bands": breached_bands,
         }
 
     async def get_position_details(self) -> list[dict]:
@@ -0,0 +1,148 @@
+"""
+What-if comparison of two position target sets.
+
+Each set is a map of manual position targets (symbol -> {"target_pct",
+"mode"}, or None to clear a target), as in a dry run. The current set is
+usually empty, which leaves today's stored targets in place. Each set runs
+through the full planner as a dry run; the planner's target weights are then
+evaluated:
+
+  - expected return and volatility from the mean and covariance of daily
+    returns (see sentinel.projections), the covariance estimated with the
+    `covariance_estimator` setting and short price histories handled by
+    `short_history_policy`
+  - CVaR 95%: the mean daily return on the worst 5% of days, were the
+    target weights held over the last three years
+  - expected dividend income: each security's trailing twelve-month
+    dividends as a yield on today's holding, applied to its target value.
+    Securities not held today have no dividend history and count as 0.
+
+The dry run's recommendations are the transition trades from today's
+portfolio; nothing is persisted.
+"""
+
+from __future__ import annotations
+
+import math
+from datetime import date, timedelta
+from typing import Any
+
+from sentinel.covariance import load_covariance_estimator
+from sentinel.factors import load_factor_model
+from sentinel.projections import (
+    LOOKBACK_DAYS,
+    historical_cvar,
+    load_industries,
+    load_short_history_policy,
+    portfolio_estimates,
+)
+
+from .dry_run import DryRunOverrides, run_dry_run
+from .models import LongTermPlan
+
+CVAR_LEVEL = 0.95
+METRIC_KEYS = (
+    "expected_annual_return_pct",
+    "annual_volatility_pct",
+    "cvar_95_pct",
+    "expected_dividend_income_eur",
+    "invested_pct",
+)
+
+
+def validate_comparison(data: Any) -> tuple[DryRunOverrides, DryRunOverrides]:
+    """Validate a comparison body into current and proposed dry-run overrides.
+
+    Raises:
+        ValueError: If a target set is malformed.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("body must be an object")
+    unknown = set(data) - {"current", "proposed", "min_trade_value"}
+    if unknown:
+        raise ValueError(f"unknown field(s): {sorted(unknown)}")
+    if "proposed" not in data:
+        raise ValueError("proposed is required")
+    overrides = []
+    for key in ("current", "proposed"):
+        try:
+            overrides.append(
+                DryRunOverrides.from_payload(
+                    {"position_targets": data.get(key) or {}, "min_trade_value": data.get("min_trade_value")}
+                )
+            )
+        except ValueError as e:
+            raise ValueError(f"{key}: {e}") from e
+    return overrides[0], overrides[1]
+
+
+def _dividend_yields(dividends: list[dict], current_values: dict[str, float]) -> dict[str, float]:
+    """Trailing dividends as a yield on today's holding, for held securities."""
+    received: dict[str, float] = {}
+    for row in dividends:
+        received[row["symbol"]] = received.get(row["symbol"], 0.0) + float(row.get("value") or 0.0)
+    return {s: received.get(s, 0.0) / value for s, value in current_values.items() if value > 0}
+
+
+async def allocation_metrics(db, plan: LongTermPlan) -> dict[str, Any]:
+    """Risk, return and income of a plan's target weights."""
+    weights = {t.symbol: t.target_allocation for t in plan.targets if t.target_allocation > 0}
+    prices = {s: await db.get_prices(s, days=LOOKBACK_DAYS + 1) for s in weights}
+    policy = await load_short_history_policy(db)
+    industries = await load_industries(db)
+    estimator = await load_covariance_estimator(db)
+    factor_model = await load_factor_model(db) if estimator == "factor" else None
+    estimates = portfolio_estimates(weights, prices, estimator, policy, industries, factor_model)
+    cvar = historical_cvar(weights, prices, CVAR_LEVEL, policy, industries)
+
+    since = (date.today() - timedelta(days=365)).isoformat()
+    yields = _dividend_yields(
+        await db.get_dividends(start_date=since),
+        {t.symbol: t.current_value_eur for t in plan.targets},
+    )
+    income = sum(yields.get(s, 0.0) * w for s, w in weights.items()) * plan.current_total_value_eur
+    invested = sum(weights.values())
+    return {
+        "expected_annual_return_pct": round(math.expm1(estimates["monthly_mean"] * 12) * 100, 2),
+        "annual_volatility_pct": round(estimates["monthly_volatility"] * math.sqrt(12) * 100, 2),
+        "cvar_95_pct": round(cvar * 100, 3) if cvar is not None else None,
+        "expected_dividend_income_eur": round(income, 2),
+        "invested_pct": round(invested * 100, 2),
+        "coverage_pct": round(estimates["coverage"] * 100, 2),
+        "short_history": estimates["short_history"],
+        "dividend_coverage_pct": (
+            round(sum(w for s, w in weights.items() if s in yields) / invested * 100, 2) if invested > 0 else 0.0
+        ),
+    }
+
+
+async def run_comparison(db, broker, current: DryRunOverrides, proposed: DryRunOverrides) -> dict[str, dict]:
+    """Dry-run both target sets and evaluate their target weights.
+
+    Returns:
+        {"current": scenario, "proposed": scenario} where a scenario holds the
+        "overrides", "recommendations", "plan", "state" and "metrics"
+    """
+    scenarios = {}
+    for name, overrides in (("current", current), ("proposed", proposed)):
+        recommendations, plan, state = await run_dry_run(overrides, db=db, broker=broker)
+        scenarios[name] = {
+            "overrides": overrides,
+            "recommendations": recommendations,
+            "plan": plan,
+            "state": state,
+            "metrics": await allocation_metrics(db, plan),
+        }
+    return scenarios
+
+
+def metric_delta(current: dict[str, Any], proposed: dict[str, Any]) -> dict[str, float | None]:
+    """Proposed minus current for each compared metric; None when either side is unknown."""
+    return {
+        key: (
+            round(proposed[key] - current[key], 4)
+            if current.get(key) is not None and proposed.get(key) is not None
+            else None
+        )
+        for key in METRIC_KEYS
+    }
@@ -0,0 +1,119 @@
+"""
+Drift bands - per-target rebalancing tolerances.
+
+`rebalance_threshold_pct` is a single tolerance for every position. Drift
+bands refine it per scope: single positions, or the geography, industry,
+currency and asset-class groups the positions roll up into. Each scope takes
+a band in percentage points, either one value for every group or a
+`default` plus per-group overrides:
+
+    {"position": 2, "geography": {"default": 3, "US": 5}}
+
+A band is breached when the current weight of its target (sum of position
+weights in the group) differs from the ideal weight by more than the band.
+Scopes without a band are not evaluated.
+"""
+
+from __future__ import annotations
+
+import math
+from typing import Any
+
+from sentinel.portfolio_composition import asset_class_for
+
+DRIFT_BANDS_KEY = "rebalance_drift_bands"
+DEFAULT_BAND = "default"
+SCOPES = ("position", "geography", "industry", "currency", "asset_class")
+
+
+def _band_pct(value: Any, name: str) -> float:
+    if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value):
+        raise ValueError(f"{name} must be a number")
+    if not 0 < value <= 100:
+        raise ValueError(f"{name} must be between 0 and 100")
+    return float(value)
+
+
+def validate_drift_bands(raw: Any) -> dict[str, dict[str, float]]:
+    """Validate a `rebalance_drift_bands` value into {scope: {group|default: band_pct}}.
+
+    Raises:
+        ValueError: If a scope is unknown or a band is malformed.
+    """
+    if raw is None:
+        return {}
+    if not isinstance(raw, dict):
+        raise ValueError("drift bands must be an object keyed by scope")
+    bands: dict[str, dict[str, float]] = {}
+    for scope, value in raw.items():
+        if scope not in SCOPES:
+            raise ValueError(f"unknown drift band scope '{scope}' (expected one of: {', '.join(SCOPES)})")
+        if isinstance(value, dict):
+            scope_bands = {}
+            for group, band in value.items():
+                if not isinstance(group, str) or not group.strip():
+                    raise ValueError(f"{scope} band keys must be non-empty strings")
+                scope_bands[group.strip()] = _band_pct(band, f"{scope} band for {group}")
+        else:
+            scope_bands = {DEFAULT_BAND: _band_pct(value, f"{scope} band")}
+        if scope_bands:
+            bands[scope] = scope_bands
+    return bands
+
+
+def group_for(scope: str, symbol: str, security: dict | None) -> str:
+    """The group a symbol belongs to within a scope ("Unknown" when metadata is blank)."""
+    if scope == "position":
+        return symbol
+    security = security or {}
+    if scope == "asset_class":
+        return asset_class_for(security.get("instr_kind_c"))
+    return (security.get(scope) or "").strip() or "Unknown"
+
+
+def evaluate_drift_bands(
+    current: dict[str, float],
+    ideal: dict[str, float],
+    securities_map: dict[str, dict],
+    bands: dict[str, dict[str, float]],
+) -> list[dict[str, Any]]:
+    """Current drift of every banded target, breached bands first.
+
+    Args:
+        current: symbol -> current allocation (0-1)
+        ideal: symbol -> ideal allocation (0-1)
+        securities_map: symbol -> securities row (for group metadata)
+        bands: Validated drift bands
+    """
+    rows = []
+    for scope in SCOPES:
+        scope_bands = bands.get(scope)
+        if not scope_bands:
+            continue
+        current_by_group: dict[str, float] = {}
+        ideal_by_group: dict[str, float] = {}
+        for symbol in set(current) | set(ideal):
+            group = group_for(scope, symbol, securities_map.get(symbol))
+            current_by_group[group] = current_by_group.get(group, 0.0) + current.get(symbol, 0.0)
+            ideal_by_group[group] = ideal_by_group.get(group, 0.0) + ideal.get(symbol, 0.0)
+
+        for group in current_by_group:
+            band_pct = scope_bands.get(group, scope_bands.get(DEFAULT_BAND))
+            if band_pct is None:
+                continue
+            current_pct = current_by_group[group] * 100
+            target_pct = ideal_by_group[group] * 100
+            drift_pct = current_pct - target_pct
+            rows.append(
+                {
+                    "scope": scope,
+                    "group": group,
+                    "current_pct": round(current_pct, 2),
+                    "target_pct": round(target_pct, 2),
+                    "drift_pct": round(drift_pct, 2),
+                    "band_pct": band_pct,
+                    "breached": abs(drift_pct) > band_pct,
+                }
+            )
+    rows.sort(key=lambda row: (not row["breached"], -abs(row["drift_pct"]), row["scope"], row["group"]))
+    return rows
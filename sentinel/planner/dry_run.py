@@ -0,0 +1,181 @@
+"""
+What-if planning runs that never persist anything.
+
+A dry run executes the full planner pipeline over the live account state with
+request-supplied overrides:
+  - extra_cash_eur:   virtual cash added to the EUR balance
+  - exclude_symbols:  securities the run may not trade
+  - settings:         planner setting overrides (e.g. a more aggressive or
+                      more defensive strategy profile)
+  - position_targets: manual position targets to set (or clear, with None)
+                      in place of the stored ones
+
+Setting overrides are applied in-memory via settings_overrides(), and the
+database is wrapped so planner caches are neither read (they reflect the real
+settings) nor written (they would leak what-if results into live planning).
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass, field
+from typing import Any
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.services.valuation import PortfolioValuationService
+from sentinel.settings import PLANNER_SETTING_KEYS, Settings, settings_overrides
+
+from .models import LongTermPlan, PlannerState, TradeRecommendation
+from .planner import Planner
+from .targets import validate_position_target, with_target_changes
+
+MAX_EXTRA_CASH_EUR = 10_000_000.0
+
+
+@dataclass
+class DryRunOverrides:
+    extra_cash_eur: float = 0.0
+    exclude_symbols: list[str] = field(default_factory=list)
+    settings: dict[str, Any] = field(default_factory=dict)
+    min_trade_value: float | None = None
+    position_targets: dict[str, dict[str, Any] | None] = field(default_factory=dict)
+
+    @classmethod
+    def from_payload(cls, data: Any) -> DryRunOverrides:
+        """Validate a request body.
+
+        Raises:
+            ValueError: If any override is malformed.
+        """
+        if data is None:
+            data = {}
+        if not isinstance(data, dict):
+            raise ValueError("body must be an object")
+        unknown = set(data) - {"extra_cash_eur", "exclude_symbols", "settings", "min_trade_value", "position_targets"}
+        if unknown:
+            raise ValueError(f"unknown override(s): {sorted(unknown)}")
+
+        extra_cash = data.get("extra_cash_eur", 0.0)
+        if isinstance(extra_cash, bool) or not isinstance(extra_cash, int | float):
+            raise ValueError("extra_cash_eur must be a number")
+        if extra_cash < 0 or extra_cash > MAX_EXTRA_CASH_EUR:
+            raise ValueError(f"extra_cash_eur must be in [0, {MAX_EXTRA_CASH_EUR:.0f}]")
+
+        exclude = data.get("exclude_symbols", [])
+        if not isinstance(exclude, list) or not all(isinstance(s, str) and s.strip() for s in exclude):
+            raise ValueError("exclude_symbols must be a list of symbols")
+
+        settings = data.get("settings", {})
+        if not isinstance(settings, dict):
+            raise ValueError("settings must be an object")
+        not_planner = sorted(set(settings) - PLANNER_SETTING_KEYS)
+        if not_planner:
+            raise ValueError(f"settings can only override planner settings; got {not_planner}")
+        for key, value in settings.items():
+            if not isinstance(value, int | float):
+                raise ValueError(f"settings.{key} must be a number or boolean")
+
+        min_trade_value = data.get("min_trade_value")
+        if min_trade_value is not None and (
+            isinstance(min_trade_value, bool) or not isinstance(min_trade_value, int | float) or min_trade_value < 0
+        ):
+            raise ValueError("min_trade_value must be a non-negative number")
+
+        targets = data.get("position_targets", {})
+        if not isinstance(targets, dict):
+            raise ValueError("position_targets must be an object keyed by symbol")
+        position_targets = {}
+        for symbol, target in targets.items():
+            try:
+                position_targets[symbol] = None if target is None else validate_position_target(target)
+            except ValueError as e:
+                raise ValueError(f"position_targets.{symbol}: {e}") from e
+
+        return cls(
+            extra_cash_eur=float(extra_cash),
+            exclude_symbols=sorted({s.strip() for s in exclude}),
+            settings=dict(settings),
+            min_trade_value=float(min_trade_value) if min_trade_value is not None else None,
+            position_targets=position_targets,
+        )
+
+
+class _DryRunDatabase:
+    """Delegates to the real database but bypasses planner caches and state writes.
+
+    Security rows are read with the run's position target overrides applied.
+    """
+
+    def __init__(self, db: Database, position_targets: dict[str, dict[str, Any] | None] | None = None):
+        self._db = db
+        self._position_targets = position_targets or {}
+
+    def __getattr__(self, name: str) -> Any:
+        return getattr(self._db, name)
+
+    async def get_all_securities(self, *args, **kwargs) -> list[dict]:
+        securities = await self._db.get_all_securities(*args, **kwargs)
+        return with_target_changes(securities, self._position_targets)
+
+    async def get_security(self, symbol: str) -> dict | None:
+        security = await self._db.get_security(symbol)
+        return with_target_changes([security], self._position_targets)[0] if security else None
+
+    async def cache_get(self, key: str) -> None:
+        return None
+
+    async def cache_set(self, key: str, value: str, ttl_seconds: int | None = None) -> None:
+        return None
+
+    async def cache_clear(self, prefix: str | None = None) -> int:
+        return 0
+
+    async def invalidate_planner_cache(self) -> int:
+        return 0
+
+    async def set_planner_state(self, key: str, value: Any) -> None:
+        return None
+
+    async def delete_planner_state(self, key: str) -> None:
+        return None
+
+
+async def run_dry_run(
+    overrides: DryRunOverrides,
+    db: Database | None = None,
+    broker: Broker | None = None,
+) -> tuple[list[TradeRecommendation], LongTermPlan, PlannerState]:
+    """Run the planner with overrides applied; nothing is persisted.
+
+    Returns:
+        Recommendations in execution order, the long-term plan, and the
+        simulated account state the run was based on.
+    """
+    db = db or Database()
+    broker = broker or Broker()
+    dry_db = _DryRunDatabase(db, overrides.position_targets)
+    planner = Planner(db=dry_db, broker=broker)  # type: ignore[arg-type]
+
+    valuation = await PortfolioValuationService(db=db, broker=broker).current()
+    cash_eur = float(valuation.get("total_cash_eur", 0.0) or 0.0)
+    state = PlannerState(
+        positions=list(valuation.get("positions") or []),
+        cash_balances={"EUR": cash_eur + overrides.extra_cash_eur},
+    )
+
+    securities = await db.get_all_securities(active_only=True)
+    excluded = set(overrides.exclude_symbols)
+    eligible = {s["symbol"] for s in securities if s.get("symbol") and s["symbol"] not in excluded}
+
+    with settings_overrides(overrides.settings):
+        min_trade_value = overrides.min_trade_value
+        if min_trade_value is None:
+            min_trade_value = await Settings().get("min_trade_value", default=100.0)
+        recommendations, plan = await planner.get_recommendations_with_plan(
+            min_trade_value=min_trade_value,
+            eligible_symbols=eligible,
+            state=state,
+        )
+
+    ranked = sorted(recommendations, key=lambda r: (r.execution_rank is None, r.execution_rank or 0))
+    return ranked, plan, state
@@ -0,0 +1,242 @@
+"""
+Investment goals - an amount to reach by a date, with a priority.
+
+Each active goal is projected against the whole portfolio: the current value
+grows month by month with lognormal returns drawn from
+`goal_expected_return_pct` and `goal_volatility_pct`, and the rolling
+six-month average net deposit is added every month. The share of
+`GOAL_PATHS` simulated paths that end at or above the target is the goal's
+probability. Sampling is seeded per goal, so repeated projections over the
+same inputs agree.
+
+A goal whose probability is below `goal_min_probability` is behind
+schedule. `planning:refresh` stores the projection, and while a goal is
+behind the planner leans in: the cash target and the convergence fallback
+wait both shrink by the tilt, which grows with the shortfall up to
+`goal_max_tilt`. Priority 1 is the most important; a goal of priority n
+tilts at most 1/n as much. Backtests ignore goals.
+"""
+
+from __future__ import annotations
+
+import inspect
+import math
+import time
+from datetime import date, datetime
+from typing import Any
+
+import numpy as np
+
+GOALS_STATE_KEY = "goals:projection"
+GOAL_PATHS = 2000
+MAX_PRIORITY = 10
+
+
+def validate_goal(data: Any, partial: bool = False) -> dict[str, Any]:
+    """Validate an investment goal payload.
+
+    Args:
+        data: Request body
+        partial: Allow missing fields (for updates)
+
+    Raises:
+        ValueError: If a field is missing or malformed.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("goal must be an object")
+    goal: dict[str, Any] = {}
+
+    if "name" in data or not partial:
+        name = data.get("name")
+        if not isinstance(name, str) or not name.strip():
+            raise ValueError("name must be a non-empty string")
+        goal["name"] = name.strip()
+
+    if "target_amount_eur" in data or not partial:
+        amount = data.get("target_amount_eur")
+        if isinstance(amount, bool) or not isinstance(amount, int | float) or not math.isfinite(amount) or amount <= 0:
+            raise ValueError("target_amount_eur must be a positive number")
+        goal["target_amount_eur"] = float(amount)
+
+    if "target_date" in data or not partial:
+        try:
+            target_date = datetime.strptime(str(data.get("target_date")), "%Y-%m-%d").date()
+        except ValueError as e:
+            raise ValueError("target_date must be YYYY-MM-DD") from e
+        if not partial and target_date <= date.today():
+            raise ValueError("target_date must be in the future")
+        goal["target_date"] = target_date.isoformat()
+
+    if "priority" in data:
+        priority = data.get("priority")
+        if isinstance(priority, bool) or not isinstance(priority, int) or not 1 <= priority <= MAX_PRIORITY:
+            raise ValueError(f"priority must be an integer between 1 and {MAX_PRIORITY}")
+        goal["priority"] = priority
+
+    if "active" in data:
+        if not isinstance(data.get("active"), bool):
+            raise ValueError("active must be a boolean")
+        goal["active"] = 1 if data["active"] else 0
+
+    return goal
+
+
+def months_until(target_date: str, today: date | None = None) -> int:
+    """Whole months from today to target_date, rounded up; 0 once it has passed."""
+    days = (datetime.strptime(target_date, "%Y-%m-%d").date() - (today or date.today())).days
+    return max(0, math.ceil(days / 30.4375))
+
+
+def project_goal(
+    current_value: float,
+    monthly_contribution: float,
+    target_amount: float,
+    months: int,
+    expected_return_pct: float,
+    volatility_pct: float,
+    seed: int = 0,
+) -> dict[str, Any]:
+    """Project one goal; amounts in EUR.
+
+    Returns:
+        {"months", "probability", "expected_eur", "p10_eur", "p50_eur",
+         "p90_eur", "required_monthly_eur"}
+    """
+    current_value = max(0.0, float(current_value))
+    monthly_contribution = float(monthly_contribution)
+    monthly_growth = (1 + expected_return_pct / 100) ** (1 / 12) - 1
+    expected = current_value * (1 + monthly_growth) ** months + monthly_contribution * _annuity(monthly_growth, months)
+    if months <= 0:
+        values = np.full(1, current_value)
+    else:
+        sigma = volatility_pct / 100 / math.sqrt(12)
+        drift = math.log1p(monthly_growth) - sigma**2 / 2
+        shocks = np.random.default_rng(seed).normal(drift, sigma, size=(GOAL_PATHS, months))
+        values = np.full(GOAL_PATHS, current_value)
+        for month in range(months):
+            values = np.maximum(0.0, values * np.exp(shocks[:, month]) + monthly_contribution)
+    p10, p50, p90 = (float(q) for q in np.quantile(values, [0.1, 0.5, 0.9]))
+    return {
+        "months": months,
+        "probability": round(float((values >= target_amount).mean()), 4),
+        "expected_eur": round(expected, 2),
+        "p10_eur": round(p10, 2),
+        "p50_eur": round(p50, 2),
+        "p90_eur": round(p90, 2),
+        "required_monthly_eur": _required_contribution(current_value, target_amount, monthly_growth, months),
+    }
+
+
+def _annuity(monthly_growth: float, months: int) -> float:
+    """Future value of 1 EUR deposited at the end of each month."""
+    if months <= 0:
+        return 0.0
+    if monthly_growth == 0:
+        return float(months)
+    return ((1 + monthly_growth) ** months - 1) / monthly_growth
+
+
+def _required_contribution(current_value: float, target: float, monthly_growth: float, months: int) -> float | None:
+    """Monthly deposit that reaches the target at the expected return; None once the date has passed."""
+    if months <= 0:
+        return None
+    missing = target - current_value * (1 + monthly_growth) ** months
+    return round(max(0.0, missing / _annuity(monthly_growth, months)), 2)
+
+
+def project_goals(
+    goals: list[dict],
+    current_value: float,
+    monthly_contribution: float,
+    *,
+    expected_return_pct: float,
+    volatility_pct: float,
+    min_probability: float,
+    today: date | None = None,
+) -> list[dict[str, Any]]:
+    """Project every goal against the whole portfolio, in the given order."""
+    rows = []
+    for goal in goals:
+        projection = project_goal(
+            current_value,
+            monthly_contribution,
+            float(goal["target_amount_eur"]),
+            months_until(goal["target_date"], today),
+            expected_return_pct,
+            volatility_pct,
+            seed=int(goal.get("id") or 0),
+        )
+        rows.append(
+            {
+                "id": goal.get("id"),
+                "name": goal.get("name"),
+                "target_amount_eur": float(goal["target_amount_eur"]),
+                "target_date": goal["target_date"],
+                "priority": int(goal.get("priority") or 1),
+                **projection,
+                "on_track": projection["probability"] >= min_probability,
+            }
+        )
+    return rows
+
+
+def goal_tilt(rows: list[dict], min_probability: float, max_tilt: float) -> float:
+    """How far (0..max_tilt) the planner leans in for the goals that are behind."""
+    if max_tilt <= 0 or min_probability <= 0:
+        return 0.0
+    tilt = 0.0
+    for row in rows:
+        shortfall = max(0.0, min_probability - float(row.get("probability", 1.0))) / min_probability
+        tilt = max(tilt, max_tilt * min(1.0, shortfall) / max(1, int(row.get("priority") or 1)))
+    return round(min(tilt, max_tilt), 4)
+
+
+async def _goal_settings(settings) -> dict[str, float]:
+    from sentinel.settings import DEFAULTS
+
+    keys = ("goal_expected_return_pct", "goal_volatility_pct", "goal_min_probability", "goal_max_tilt")
+    values = {}
+    for key in keys:
+        value = await settings.get(key, DEFAULTS[key])
+        values[key] = float(value if value is not None else DEFAULTS[key])
+    return values
+
+
+async def compute_goal_projection(db, settings, current_value: float, monthly_contribution: float) -> dict[str, Any]:
+    """Project the active goals; returns {"goals", "tilt", "portfolio_value_eur", "monthly_contribution_eur"}."""
+    config = await _goal_settings(settings)
+    rows = project_goals(
+        await db.get_goals(active_only=True),
+        current_value,
+        monthly_contribution,
+        expected_return_pct=config["goal_expected_return_pct"],
+        volatility_pct=config["goal_volatility_pct"],
+        min_probability=config["goal_min_probability"],
+    )
+    return {
+        "goals": rows,
+        "tilt": goal_tilt(rows, config["goal_min_probability"], config["goal_max_tilt"]),
+        "portfolio_value_eur": round(float(current_value), 2),
+        "monthly_contribution_eur": round(float(monthly_contribution), 2),
+    }
+
+
+async def refresh_goal_projection(db, settings, current_value: float, monthly_contribution: float) -> dict[str, Any]:
+    """Project the active goals and store the result for the planner."""
+    projection = await compute_goal_projection(db, settings, current_value, monthly_contribution)
+    await db.set_planner_state(GOALS_STATE_KEY, {**projection, "projected_at": int(time.time())})
+    return projection
+
+
+async def load_goal_tilt(db, settings) -> float:
+    """Tilt from the stored projection, re-derived with the current settings; 0 without goals."""
+    getter = getattr(db, "get_planner_state", None)
+    if not callable(getter):
+        return 0.0
+    state = getter(GOALS_STATE_KEY)
+    if inspect.isawaitable(state):
+        state = await state
+    if not isinstance(state, dict) or not isinstance(state.get("goals"), list):
+        return 0.0
+    config = await _goal_settings(settings)
+    return goal_tilt(state["goals"], config["goal_min_probability"], config["goal_max_tilt"])
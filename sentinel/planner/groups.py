@@ -0,0 +1,210 @@
+"""
+Security groups - structural portfolio buckets such as "Dividend core" or
+"Satellite growth".
+
+A group names a set of securities and may carry a target weight in percent
+of the whole portfolio. A security belongs to at most one group, so groups
+partition the universe and their weights add up.
+
+When a group has a target, the ideal portfolio is rescaled after position
+targets are applied: the group's members share the target in proportion to
+the weights the strategy gave them, and everything outside targeted groups
+shares what is left of the invested budget. Hard position targets stay
+pinned, members the strategy gives no weight are not bought just to fill a
+group, and every position stays capped at `max_position_pct`.
+
+Groups live in the `security_groups` and `security_group_members` tables;
+the API reports each group's current and ideal weight next to its target.
+"""
+
+from __future__ import annotations
+
+import inspect
+import math
+from typing import Any
+
+from sentinel.planner.preferences import apply_max_cap
+
+MAX_NAME_LENGTH = 100
+MAX_DESCRIPTION_LENGTH = 200
+MAX_MEMBERS = 500
+
+
+def validate_security_group(data: Any, partial: bool = False) -> dict[str, Any]:
+    """Validate a security group payload into {"name", "description", "target_pct"}.
+
+    Args:
+        data: Request body
+        partial: Allow missing fields (for updates)
+
+    Raises:
+        ValueError: If a field is missing or malformed.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("group must be an object")
+    fields: dict[str, Any] = {}
+
+    if "name" in data or not partial:
+        name = data.get("name")
+        if not isinstance(name, str) or not name.strip() or len(name.strip()) > MAX_NAME_LENGTH:
+            raise ValueError(f"name must be a non-empty string of at most {MAX_NAME_LENGTH} characters")
+        fields["name"] = name.strip()
+
+    if "description" in data:
+        description = data.get("description")
+        if description is not None and (
+            not isinstance(description, str) or len(description.strip()) > MAX_DESCRIPTION_LENGTH
+        ):
+            raise ValueError(f"description must be a string of at most {MAX_DESCRIPTION_LENGTH} characters")
+        fields["description"] = (description.strip() or None) if description is not None else None
+
+    if "target_pct" in data:
+        target_pct = data.get("target_pct")
+        if target_pct is not None:
+            if (
+                isinstance(target_pct, bool)
+                or not isinstance(target_pct, int | float)
+                or not math.isfinite(target_pct)
+            ):
+                raise ValueError("target_pct must be a number or null")
+            if not 0 <= target_pct <= 100:
+                raise ValueError("target_pct must be between 0 and 100")
+            target_pct = float(target_pct)
+        fields["target_pct"] = target_pct
+    return fields
+
+
+def validate_members(data: Any) -> list[str]:
+    """Validate {"symbols": [...]} into upper-cased, de-duplicated symbols.
+
+    Raises:
+        ValueError: If symbols is missing, empty or malformed.
+    """
+    raw = data.get("symbols") if isinstance(data, dict) else None
+    if not isinstance(raw, list) or not raw or len(raw) > MAX_MEMBERS:
+        raise ValueError(f"symbols must be a non-empty list of at most {MAX_MEMBERS} strings")
+    symbols: list[str] = []
+    for item in raw:
+        if not isinstance(item, str) or not item.strip():
+            raise ValueError("symbols must be a list of non-empty strings")
+        symbol = item.strip().upper()
+        if symbol not in symbols:
+            symbols.append(symbol)
+    return symbols
+
+
+def group_target_total(groups: list[dict]) -> float:
+    """Sum of group targets in percent."""
+    return sum(float(group["target_pct"]) for group in groups if group.get("target_pct") is not None)
+
+
+async def load_security_groups(db) -> list[dict]:
+    """Security groups with members; tolerates databases without group support."""
+    getter = getattr(db, "get_security_groups", None)
+    if not callable(getter):
+        return []
+    groups = getter()
+    if inspect.isawaitable(groups):
+        groups = await groups
+    return groups if isinstance(groups, list) else []
+
+
+def _spread(weights: dict[str, float], total: float, max_position: float) -> dict[str, float]:
+    """Rescale `weights` to add up to `total`, keeping each under `max_position`."""
+    if total <= 0:
+        return {symbol: 0.0 for symbol in weights}
+    return {symbol: weight * total for symbol, weight in apply_max_cap(weights, max_position / total).items()}
+
+
+def apply_group_targets(
+    weights: dict[str, float],
+    groups: list[dict],
+    *,
+    budget: float,
+    max_position: float,
+    pinned: set[str] | frozenset[str] = frozenset(),
+) -> dict[str, float]:
+    """Fit group targets into ideal weights.
+
+    Args:
+        weights: symbol -> weight as a fraction of the portfolio
+        groups: Groups with "symbols" and "target_pct" (percent or None)
+        budget: Portfolio fraction to invest (everything but the cash target)
+        max_position: Per-position cap as a fraction of the portfolio
+        pinned: Symbols whose weight must not change (hard position targets)
+
+    Returns:
+        symbol -> weight as a fraction of the portfolio; zero weights are dropped
+    """
+    targeted = [group for group in groups if group.get("target_pct") is not None]
+    if not targeted or budget <= 0:
+        return dict(weights)
+
+    goals = {group["id"]: min(float(group["target_pct"]) / 100.0, budget) for group in targeted}
+    goal_total = sum(goals.values())
+    if goal_total > budget:
+        goals = {group_id: goal * budget / goal_total for group_id, goal in goals.items()}
+
+    result = {symbol: weight for symbol, weight in weights.items() if weight > 0}
+    in_targeted: set[str] = set()
+    targeted_total = 0.0
+    for group in targeted:
+        members = {symbol: result[symbol] for symbol in group.get("symbols", []) if symbol in result}
+        in_targeted.update(members)
+        fixed = sum(weight for symbol, weight in members.items() if symbol in pinned)
+        free = {symbol: weight for symbol, weight in members.items() if symbol not in pinned}
+        if free:
+            # With nothing free the group keeps what the strategy gave it.
+            result.update(_spread(free, max(0.0, goals[group["id"]] - fixed), max_position))
+        targeted_total += sum(result[symbol] for symbol in members)
+
+    others = {symbol: weight for symbol, weight in result.items() if symbol not in in_targeted}
+    fixed = sum(weight for symbol, weight in others.items() if symbol in pinned)
+    free = {symbol: weight for symbol, weight in others.items() if symbol not in pinned}
+    if free:
+        result.update(_spread(free, max(0.0, budget - targeted_total - fixed), max_position))
+    return {symbol: weight for symbol, weight in result.items() if weight > 0}
+
+
+def group_allocations(
+    groups: list[dict],
+    current: dict[str, float],
+    ideal: dict[str, float],
+) -> list[dict[str, Any]]:
+    """Current and ideal weight of every group, plus the ungrouped remainder.
+
+    Weights are fractions; the rows are in percent. `drift_pct` is current
+    minus target, or None for groups without a target.
+    """
+    rows = []
+    grouped: set[str] = set()
+    for group in groups:
+        symbols = list(group.get("symbols", []))
+        grouped.update(symbols)
+        current_pct = sum(current.get(symbol, 0.0) for symbol in symbols) * 100
+        target_pct = group.get("target_pct")
+        rows.append(
+            {
+                "id": group.get("id"),
+                "name": group.get("name"),
+                "description": group.get("description"),
+                "symbols": symbols,
+                "target_pct": target_pct,
+                "current_pct": current_pct,
+                "ideal_pct": sum(ideal.get(symbol, 0.0) for symbol in symbols) * 100,
+                "drift_pct": current_pct - target_pct if target_pct is not None else None,
+            }
+        )
+    rows.append(
+        {
+            "id": None,
+            "name": "Ungrouped",
+            "description": None,
+            "symbols": sorted(symbol for symbol in {*current, *ideal} if symbol not in grouped),
+            "target_pct": None,
+            "current_pct": sum(weight for symbol, weight in current.items() if symbol not in grouped) * 100,
+            "ideal_pct": sum(weight for symbol, weight in ideal.items() if symbol not in grouped) * 100,
+            "drift_pct": None,
+        }
+    )
+    return rows
@@ -0,0 +1,232 @@
+"""
+Dividend income projection - forward twelve-month income against a target.
+
+Each held position's income over the next twelve months repeats its payout
+history: every dividend of the trailing twelve months, as EUR per share held
+on the payment date (from the trade history; today's quantity when the
+trades don't cover it), paid again on today's quantity a year later. The
+per-share payouts of the twelve months before give each position's payout
+growth, reported but not projected forward. Positions without payouts in the
+last year count as 0.
+
+The total is compared with `income_target_annual_eur` (0 = no target).
+`planning:refresh` stores the projection, and with `planner_objective` set
+to `income` the planner leans toward yield while income is short of the
+target: each qualifying security's preference weight grows by up to the
+tilt times its yield relative to the highest yield held. The tilt is
+INCOME_MAX_TILT times the shortfall (all of it without a target). Securities
+never held have no payout history and are not tilted. Backtests ignore the
+income objective.
+"""
+
+from __future__ import annotations
+
+import inspect
+import math
+import time
+from datetime import date, datetime, timedelta
+from typing import Any
+
+INCOME_STATE_KEY = "income:projection"
+INCOME_TARGET_KEY = "income_target_annual_eur"
+OBJECTIVE_KEY = "planner_objective"
+OBJECTIVES = ("total_return", "income")
+DEFAULT_OBJECTIVE = "total_return"
+# At full shortfall the highest-yielding holding gets twice its preference weight.
+INCOME_MAX_TILT = 1.0
+
+
+def validate_planner_objective(value: Any) -> str:
+    """Validate `planner_objective`.
+
+    Raises:
+        ValueError: If it is not one of OBJECTIVES.
+    """
+    if value not in OBJECTIVES:
+        raise ValueError(f"{OBJECTIVE_KEY} must be one of {', '.join(OBJECTIVES)}")
+    return value
+
+
+def validate_income_target(value: Any) -> float:
+    """Validate `income_target_annual_eur`.
+
+    Raises:
+        ValueError: If it is not a non-negative number.
+    """
+    if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value) or value < 0:
+        raise ValueError(f"{INCOME_TARGET_KEY} must be a non-negative number")
+    return float(value)
+
+
+def quantity_on(trades: list[dict], day: str) -> float:
+    """Shares held at the start of `day` (YYYY-MM-DD) according to the trades."""
+    held = 0.0
+    for trade in trades:
+        executed = datetime.fromtimestamp(int(trade["executed_at"])).date().isoformat()
+        if executed >= day:
+            continue
+        quantity = float(trade.get("quantity") or 0.0)
+        held += quantity if trade.get("side") == "BUY" else -quantity
+    return held
+
+
+def _add_year(day: str) -> str:
+    source = date.fromisoformat(day[:10])
+    try:
+        return source.replace(year=source.year + 1).isoformat()
+    except ValueError:  # 29 February
+        return (source + timedelta(days=365)).isoformat()
+
+
+def project_position_income(
+    dividends: list[dict], trades: list[dict], quantity: float, today: date
+) -> dict[str, Any]:
+    """Forward twelve-month income of one position from its payouts.
+
+    Args:
+        dividends: The security's dividends of the last two years
+        trades: The security's trades
+        quantity: Shares held today
+
+    Returns:
+        {"annual_income_eur", "payments", "growth_pct", "schedule": [(date, eur)]}
+        where schedule holds the expected payments of the next twelve months
+    """
+    year_ago = (today - timedelta(days=365)).isoformat()
+    recent = 0.0
+    earlier = 0.0
+    schedule = []
+    for row in dividends:
+        day = str(row["date"])[:10]
+        held = quantity_on(trades, day)
+        per_share = float(row.get("value") or 0.0) / (held if held > 0 else quantity)
+        if day > year_ago:
+            recent += per_share
+            schedule.append((_add_year(day), per_share * quantity))
+        else:
+            earlier += per_share
+    return {
+        "annual_income_eur": recent * quantity,
+        "payments": len(schedule),
+        "growth_pct": round((recent / earlier - 1) * 100, 2) if earlier > 0 else None,
+        "schedule": sorted(schedule),
+    }
+
+
+def income_tilt(annual_income: float, target: float, objective: str) -> float:
+    """How far (0..INCOME_MAX_TILT) the planner leans toward yield."""
+    if objective != "income":
+        return 0.0
+    shortfall = 1.0 if target <= 0 else max(0.0, 1 - annual_income / target)
+    return round(INCOME_MAX_TILT * min(1.0, shortfall), 4)
+
+
+def income_multipliers(yields: dict[str, float], tilt: float) -> dict[str, float]:
+    """Preference-weight multiplier per security with a yield; empty without a tilt."""
+    top = max(yields.values(), default=0.0)
+    if tilt <= 0 or top <= 0:
+        return {}
+    return {symbol: 1 + tilt * y / top for symbol, y in yields.items() if y > 0}
+
+
+async def _income_settings(settings) -> tuple[float, str]:
+    from sentinel.settings import DEFAULTS
+
+    target = await settings.get(INCOME_TARGET_KEY, DEFAULTS[INCOME_TARGET_KEY])
+    objective = await settings.get(OBJECTIVE_KEY, DEFAULTS[OBJECTIVE_KEY])
+    try:
+        target = validate_income_target(target)
+    except ValueError:
+        target = 0.0
+    try:
+        objective = validate_planner_objective(objective)
+    except ValueError:
+        objective = DEFAULT_OBJECTIVE
+    return target, objective
+
+
+async def compute_income_projection(db, settings, currency, today: date | None = None) -> dict[str, Any]:
+    """Project the next twelve months of dividend income of the held positions.
+
+    Returns:
+        {"annual_income_eur", "target_annual_income_eur", "progress_pct",
+        "shortfall_eur", "on_track", "portfolio_value_eur", "yield_pct",
+        "objective", "tilt", "positions": [...], "months": [{"month", "income_eur"}]}
+    """
+    from sentinel.utils.positions import PositionCalculator
+
+    today = today or date.today()
+    target, objective = await _income_settings(settings)
+    pos_calc = PositionCalculator(currency_converter=currency)
+    since = (today - timedelta(days=730)).isoformat()
+    dividends: dict[str, list[dict]] = {}
+    for row in await db.get_dividends(start_date=since):
+        dividends.setdefault(row["symbol"], []).append(row)
+
+    positions = []
+    months: dict[str, float] = {}
+    horizon = (today + timedelta(days=365)).isoformat()
+    for pos in await db.get_all_positions():
+        quantity = float(pos.get("quantity") or 0.0)
+        if quantity <= 0:
+            continue
+        symbol = pos["symbol"]
+        value = 0.0
+        if pos.get("current_price"):
+            value = await pos_calc.calculate_value_eur(quantity, pos["current_price"], pos.get("currency", "EUR"))
+        trades = await db.get_trades(symbol=symbol, limit=100_000) if symbol in dividends else []
+        income = project_position_income(dividends.get(symbol, []), trades, quantity, today)
+        for day, amount in income["schedule"]:
+            if day <= horizon:
+                months[day[:7]] = months.get(day[:7], 0.0) + amount
+        positions.append(
+            {
+                "symbol": symbol,
+                "quantity": quantity,
+                "value_eur": round(value, 2),
+                "annual_income_eur": round(income["annual_income_eur"], 2),
+                "yield_pct": round(income["annual_income_eur"] / value * 100, 2) if value > 0 else None,
+                "payments": income["payments"],
+                "growth_pct": income["growth_pct"],
+            }
+        )
+    positions.sort(key=lambda p: (-p["annual_income_eur"], p["symbol"]))
+
+    annual = sum(p["annual_income_eur"] for p in positions)
+    total_value = sum(p["value_eur"] for p in positions)
+    return {
+        "annual_income_eur": round(annual, 2),
+        "target_annual_income_eur": target or None,
+        "progress_pct": round(annual / target * 100, 2) if target > 0 else None,
+        "shortfall_eur": round(max(0.0, target - annual), 2) if target > 0 else None,
+        "on_track": annual >= target if target > 0 else None,
+        "portfolio_value_eur": round(total_value, 2),
+        "yield_pct": round(annual / total_value * 100, 2) if total_value > 0 else None,
+        "objective": objective,
+        "tilt": income_tilt(annual, target, objective),
+        "positions": positions,
+        "months": [{"month": month, "income_eur": round(months[month], 2)} for month in sorted(months)],
+    }
+
+
+async def refresh_income_projection(db, settings, currency) -> dict[str, Any]:
+    """Project dividend income and store the result for the planner."""
+    projection = await compute_income_projection(db, settings, currency)
+    await db.set_planner_state(INCOME_STATE_KEY, {**projection, "projected_at": int(time.time())})
+    return projection
+
+
+async def load_income_multipliers(db, settings) -> dict[str, float]:
+    """Yield tilt per security from the stored projection, re-derived with the current settings."""
+    getter = getattr(db, "get_planner_state", None)
+    if not callable(getter):
+        return {}
+    state = getter(INCOME_STATE_KEY)
+    if inspect.isawaitable(state):
+        state = await state
+    if not isinstance(state, dict) or not isinstance(state.get("positions"), list):
+        return {}
+    target, objective = await _income_settings(settings)
+    tilt = income_tilt(float(state.get("annual_income_eur") or 0.0), target, objective)
+    yields = {p["symbol"]: float(p.get("yield_pct") or 0.0) for p in state["positions"]}
+    return income_multipliers(yields, tilt)
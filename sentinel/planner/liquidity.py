@@ -0,0 +1,155 @@
+"""
+Liquidity policy - how much cash the planner must leave untouched.
+
+The reserve is the sum of:
+  - the cash buffer: the larger of `min_cash_buffer` (share of total portfolio
+    value) and `min_cash_buffer_eur` (absolute floor)
+  - per-currency floors from `cash_currency_floors`, e.g. {"USD": 200}
+  - pending obligations from `pending_obligations`, e.g. a known upcoming
+    withdrawal: [{"label": "Tax bill", "amount": 1500, "currency": "EUR"}];
+    an obligation with a `due_date` stops being reserved once that day has passed
+
+Buys are sized so cash after the plan stays at or above the reserve, and the
+reserve is re-checked before a buy is submitted. Currency floors and
+obligations describe the live account, so backtests only apply the buffer.
+"""
+
+from __future__ import annotations
+
+import math
+from dataclasses import dataclass, field
+from datetime import date
+from typing import Any, Awaitable, Callable
+
+BUFFER_PCT_KEY = "min_cash_buffer"
+BUFFER_EUR_KEY = "min_cash_buffer_eur"
+CURRENCY_FLOORS_KEY = "cash_currency_floors"
+OBLIGATIONS_KEY = "pending_obligations"
+
+SettingGetter = Callable[[str, Any], Awaitable[Any]]
+ToEur = Callable[[float, str], Awaitable[float]]
+
+
+def _non_negative(value: Any, name: str) -> float:
+    if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value) or value < 0:
+        raise ValueError(f"{name} must be a non-negative number")
+    return float(value)
+
+
+def validate_currency_floors(raw: Any) -> dict[str, float]:
+    """Validate a `cash_currency_floors` value.
+
+    Raises:
+        ValueError: If a currency or amount is malformed.
+    """
+    if raw is None:
+        return {}
+    if not isinstance(raw, dict):
+        raise ValueError("currency floors must be an object keyed by currency")
+    floors: dict[str, float] = {}
+    for currency, amount in raw.items():
+        if not isinstance(currency, str) or not currency.strip():
+            raise ValueError("currency floor keys must be non-empty currency codes")
+        floors[currency.strip().upper()] = _non_negative(amount, f"floor for {currency}")
+    return floors
+
+
+def validate_pending_obligations(raw: Any) -> list[dict[str, Any]]:
+    """Validate a `pending_obligations` value.
+
+    Raises:
+        ValueError: If an obligation is malformed.
+    """
+    if raw is None:
+        return []
+    if not isinstance(raw, list):
+        raise ValueError("pending obligations must be a list")
+    obligations = []
+    for index, item in enumerate(raw):
+        if not isinstance(item, dict):
+            raise ValueError(f"obligation {index} must be an object")
+        currency = item.get("currency", "EUR")
+        if not isinstance(currency, str) or not currency.strip():
+            raise ValueError(f"obligation {index}: currency must be a currency code")
+        label = item.get("label", "")
+        if not isinstance(label, str):
+            raise ValueError(f"obligation {index}: label must be a string")
+        due_date = item.get("due_date")
+        if due_date is not None:
+            try:
+                due_date = date.fromisoformat(due_date).isoformat()
+            except (TypeError, ValueError) as e:
+                raise ValueError(f"obligation {index}: due_date must be a YYYY-MM-DD date") from e
+        obligation = {
+            "label": label,
+            "amount": _non_negative(item.get("amount"), f"obligation {index} amount"),
+            "currency": currency.strip().upper(),
+        }
+        if due_date is not None:
+            obligation["due_date"] = due_date
+        obligations.append(obligation)
+    return obligations
+
+
+def _number(value: Any, default: float) -> float:
+    if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value):
+        return default
+    return max(0.0, float(value))
+
+
+@dataclass(frozen=True)
+class LiquidityPolicy:
+    buffer_pct: float = 0.0
+    buffer_eur: float = 0.0
+    currency_floors: dict[str, float] = field(default_factory=dict)
+    obligations: list[dict[str, Any]] = field(default_factory=list)
+
+    def open_obligations(self, as_of: date | None = None) -> list[dict[str, Any]]:
+        """Obligations still to be paid: without a due date, or due on or after `as_of` (default today)."""
+        today = (as_of or date.today()).isoformat()
+        return [item for item in self.obligations if not item.get("due_date") or item["due_date"] >= today]
+
+    async def reserve(
+        self, total_value_eur: float, to_eur: ToEur, include_account: bool = True, as_of: date | None = None
+    ) -> dict[str, float]:
+        """Cash reserve in EUR with its components.
+
+        Args:
+            total_value_eur: Portfolio value the percentage buffer applies to
+            to_eur: Converts an amount in a currency to EUR
+            include_account: Whether to add currency floors and obligations
+            as_of: Day obligations are checked against (default today); past-due ones are left out
+        """
+        buffer = max(max(0.0, total_value_eur) * self.buffer_pct, self.buffer_eur)
+        floors = 0.0
+        obligations = 0.0
+        if include_account:
+            for currency, amount in self.currency_floors.items():
+                floors += amount if currency == "EUR" else await to_eur(amount, currency)
+            for item in self.open_obligations(as_of):
+                amount, currency = item["amount"], item["currency"]
+                obligations += amount if currency == "EUR" else await to_eur(amount, currency)
+        return {
+            "buffer_eur": buffer,
+            "currency_floors_eur": floors,
+            "obligations_eur": obligations,
+            "total_eur": buffer + floors + obligations,
+        }
+
+
+async def load_liquidity_policy(get: SettingGetter) -> LiquidityPolicy:
+    """Build the policy from settings; malformed stored values are ignored."""
+    try:
+        floors = validate_currency_floors(await get(CURRENCY_FLOORS_KEY, {}))
+    except ValueError:
+        floors = {}
+    try:
+        obligations = validate_pending_obligations(await get(OBLIGATIONS_KEY, []))
+    except ValueError:
+        obligations = []
+    return LiquidityPolicy(
+        buffer_pct=_number(await get(BUFFER_PCT_KEY, 0.005), 0.005),
+        buffer_eur=_number(await get(BUFFER_EUR_KEY, 0.0), 0.0),
+        currency_floors=floors,
+        obligations=obligations,
+    )
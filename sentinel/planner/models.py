@@ -78,6 +78,8 @@ class TradeRecommendation:
     target_gap_ratio: float = 0.0
     is_fallback: bool = False
     execution_rank: Optional[int] = None
+    robustness: Optional[dict] = None  # Monte Carlo statistics (see planner.monte_carlo)
+    spread_bps: Optional[float] = None  # Quoted bid/ask spread (see planner.spread)
 
 
 @dataclass
@@ -105,6 +107,14 @@ class PlannerState:
         """Return a normalized EUR-only cash balance mapping."""
         return {"EUR": self.cash_eur()}
 
+    def short_positions(self) -> list[dict]:
+        """Positions with a negative quantity, which the planner does not plan around."""
+        return [pos for pos in self.positions if float(pos.get("quantity", 0) or 0) < 0]
+
+    def margin_eur(self) -> float:
+        """EUR borrowed on margin, i.e. the negative part of the cash balance."""
+        return max(0.0, -self.cash_eur())
+
 
 @dataclass
 class RebalanceSummary:
@@ -0,0 +1,107 @@
+"""
+Monte Carlo robustness check for planner recommendations.
+
+The deterministic planner picks and orders trades from point estimates. When
+`planner_monte_carlo_enabled` is on, the top-K recommendations (by execution
+order) are re-scored by resampling each security's historical daily log
+returns into `planner_monte_carlo_paths` price paths of
+`planner_monte_carlo_horizon_days` trading days. The resulting distribution
+is summarised per recommendation:
+
+  - favorable_prob: share of paths that favour the action (a gain for a buy,
+                    a loss avoided for a sell)
+  - expected_return, p05/p50/p95: horizon return quantiles
+  - score: favorable_prob scaled down by the downside tail, in [0, 1]
+
+Ordering is left unchanged (funding sells must still precede their buys);
+the statistics are attached as TradeRecommendation.robustness. Sampling is
+seeded per symbol so repeated cycles over the same history agree.
+"""
+
+from __future__ import annotations
+
+import zlib
+from dataclasses import replace
+from typing import Any
+
+import numpy as np
+
+from .models import TradeRecommendation
+
+MIN_HISTORY_DAYS = 60
+MAX_PATHS = 10_000
+MAX_HORIZON_DAYS = 250
+
+
+def simulate_horizon_returns(closes: list[float], paths: int, horizon_days: int, seed: int) -> np.ndarray | None:
+    """Bootstrap `paths` cumulative simple returns over `horizon_days`.
+
+    Returns None when there is not enough usable history.
+    """
+    prices = np.asarray([c for c in closes if c and c > 0], dtype=float)
+    if prices.size < MIN_HISTORY_DAYS + 1:
+        return None
+    daily = np.diff(np.log(prices))
+    rng = np.random.default_rng(seed)
+    samples = rng.choice(daily, size=(paths, horizon_days), replace=True)
+    return np.expm1(samples.sum(axis=1))
+
+
+def robustness_stats(
+    closes: list[float],
+    action: str,
+    paths: int,
+    horizon_days: int,
+    seed: int,
+) -> dict[str, Any] | None:
+    """Summarise simulated horizon returns from the point of view of `action`."""
+    returns = simulate_horizon_returns(closes, paths, horizon_days, seed)
+    if returns is None:
+        return None
+    favorable = returns > 0 if action == "buy" else returns < 0
+    favorable_prob = float(favorable.mean())
+    p05, p50, p95 = (float(q) for q in np.quantile(returns, [0.05, 0.5, 0.95]))
+    adverse_tail = -p05 if action == "buy" else p95
+    score = favorable_prob * (1.0 - min(1.0, max(0.0, adverse_tail)))
+    return {
+        "paths": paths,
+        "horizon_days": horizon_days,
+        "expected_return": float(returns.mean()),
+        "p05": p05,
+        "p50": p50,
+        "p95": p95,
+        "favorable_prob": favorable_prob,
+        "score": round(score, 4),
+    }
+
+
+def apply_monte_carlo(
+    recommendations: list[TradeRecommendation],
+    closes_by_symbol: dict[str, list[float]],
+    *,
+    top_k: int,
+    paths: int,
+    horizon_days: int,
+) -> list[TradeRecommendation]:
+    """Attach robustness statistics to the top-K recommendations.
+
+    Args:
+        recommendations: Planner output; order is preserved.
+        closes_by_symbol: Oldest-first daily closes per symbol.
+    """
+    paths = max(1, min(int(paths), MAX_PATHS))
+    horizon_days = max(1, min(int(horizon_days), MAX_HORIZON_DAYS))
+    ranked = sorted(
+        range(len(recommendations)),
+        key=lambda i: (recommendations[i].execution_rank is None, recommendations[i].execution_rank or 0),
+    )
+    selected = set(ranked[: max(0, int(top_k))])
+
+    result = []
+    for index, rec in enumerate(recommendations):
+        if index in selected:
+            seed = zlib.crc32(f"{rec.symbol}:{rec.action}".encode())
+            stats = robustness_stats(closes_by_symbol.get(rec.symbol) or [], rec.action, paths, horizon_days, seed)
+            rec = replace(rec, robustness=stats)
+        result.append(rec)
+    return result
@@ -8,6 +8,7 @@ This is a facade that delegates to specialized components:
 
 from __future__ import annotations
 
+import logging
 from calendar import monthrange
 from datetime import date
 from math import ceil
@@ -16,11 +17,15 @@ from typing import Any, Optional
 from sentinel.broker import Broker
 from sentinel.currency import Currency
 from sentinel.database import Database
+from sentinel.exposure import AccountExposureError, account_exposure, describe_exposure
 from sentinel.portfolio import Portfolio
 from sentinel.services.valuation import PortfolioValuationService
 
 from .allocation import AllocationCalculator
 from .analyzer import PortfolioAnalyzer
+from .deposit_history import DepositHistoryHelper
+from .goals import compute_goal_projection, refresh_goal_projection
+from .income import compute_income_projection, refresh_income_projection
 from .models import (
     PLANNING_HORIZON_MONTHS,
     LongTermPlan,
@@ -30,6 +35,8 @@ from .models import (
 )
 from .rebalance import RebalanceEngine
 
+logger = logging.getLogger(__name__)
+
 
 class Planner:
     """Facade over allocation, analysis, and rebalance components."""
@@ -69,6 +76,7 @@ class Planner:
             portfolio=self._portfolio,
             currency=self._currency,
         )
+        self._last_inputs: dict[str, Any] = {}
 
     async def calculate_ideal_portfolio(self, as_of_date: Optional[str] = None) -> dict[str, float]:
         """Calculate ideal portfolio allocations.
@@ -82,6 +90,15 @@ class Planner:
         """Return diagnostics from the most recent allocation run for this as-of context."""
         return self._allocation_calculator.get_last_signal_bundle(as_of_date=as_of_date) or {}
 
+    def get_last_planning_inputs(self) -> dict[str, Any]:
+        """Return the inputs (allocations, cash, signals, market data) of the most recent planning run."""
+        if not self._last_inputs:
+            return {}
+        return {
+            **self._last_inputs,
+            "security_data": self._rebalance_engine.get_last_security_data(),
+        }
+
     async def get_current_allocations(self, as_of_date: Optional[str] = None) -> dict[str, float]:
         """Get current portfolio allocations by symbol.
 
@@ -106,9 +123,14 @@ class Planner:
                 prices and "today" are scoped to this date.
 
         Returns:
-            List of TradeRecommendation, sorted by priority
+            List of TradeRecommendation, sorted by priority; empty while the
+            account holds shorts or is on margin (see sentinel.exposure)
         """
         state = await self._resolve_live_state(as_of_date=as_of_date, state=state)
+        refused = self._exposure_refusal(state)
+        if refused:
+            logger.error(refused)
+            return []
         ideal, current, total_value, signal_bundle = await self._planning_inputs(
             as_of_date=as_of_date,
             state=state,
@@ -134,8 +156,15 @@ class Planner:
         track_fallback_state: bool = False,
         state: PlannerState | None = None,
     ) -> tuple[list[TradeRecommendation], LongTermPlan]:
-        """Generate today's actions and a whole-lot twelve-month deployment target."""
+        """Generate today's actions and a whole-lot twelve-month deployment target.
+
+        Raises:
+            AccountExposureError: If the account holds shorts or is on margin.
+        """
         state = await self._resolve_live_state(as_of_date=as_of_date, state=state)
+        refused = self._exposure_refusal(state)
+        if refused:
+            raise AccountExposureError(refused)
         ideal, current, total_value, signal_bundle = await self._planning_inputs(
             as_of_date=as_of_date,
             state=state,
@@ -193,6 +222,14 @@ class Planner:
             cash_balances={"EUR": float(valuation.get("total_cash_eur", 0.0) or 0.0)},
         )
 
+    @staticmethod
+    def _exposure_refusal(state: PlannerState | None) -> str | None:
+        """Why the account cannot be planned (shorts or margin), or None."""
+        if state is None:
+            return None
+        exposure = account_exposure(state.short_positions(), state.margin_eur())
+        return None if exposure["ok"] else describe_exposure(exposure)
+
     async def _load_security_constraints(self) -> dict[str, dict[str, Any]]:
         securities = await self._db.get_all_securities(active_only=False)
         return {str(sec["symbol"]): sec for sec in securities if sec.get("symbol")}
@@ -211,6 +248,14 @@ class Planner:
             total_value = self._total_value_from_state(state)
             current = self._allocations_from_state(state, total_value)
         signal_bundle = self._allocation_calculator.get_last_signal_bundle(as_of_date=as_of_date) or {}
+        self._last_inputs = {
+            "as_of_date": as_of_date,
+            "ideal": dict(ideal),
+            "current": dict(current),
+            "total_value_eur": total_value,
+            "cash_eur": state.cash_eur() if state is not None else None,
+            "signal_bundle": signal_bundle,
+        }
         return ideal, current, total_value, signal_bundle
 
     @classmethod
@@ -421,6 +466,60 @@ class Planner:
         """
         return await self._portfolio_analyzer.get_rebalance_summary()
 
+    async def get_drift_bands(self) -> dict:
+        """Get current drift per configured drift band.
+
+        Returns:
+            dict with per-band drift rows and the breached count
+        """
+        return await self._portfolio_analyzer.get_drift_bands()
+
+    async def get_position_targets(self) -> dict:
+        """Get position targets with their drift.
+
+        Returns:
+            dict with per-position target rows
+        """
+        return await self._portfolio_analyzer.get_position_targets()
+
+    async def get_goal_projection(self, store: bool = False) -> dict:
+        """Project the active investment goals against the portfolio.
+
+        Args:
+            store: Keep the projection for the planner's goal tilt
+
+        Returns:
+            dict with per-goal projections and the resulting tilt
+        """
+        from sentinel.settings import Settings
+
+        total_value = await self._portfolio.total_value()
+        contribution = await DepositHistoryHelper(self._db, self._currency).get_rolling_6m_avg_net_deposit()
+        project = refresh_goal_projection if store else compute_goal_projection
+        return await project(self._db, Settings(), total_value, contribution)
+
+    async def get_income_projection(self, store: bool = False) -> dict:
+        """Project the next twelve months of dividend income against the income target.
+
+        Args:
+            store: Keep the projection for the planner's income objective
+
+        Returns:
+            dict with per-position income, the monthly schedule and the tilt
+        """
+        from sentinel.settings import Settings
+
+        project = refresh_income_projection if store else compute_income_projection
+        return await project(self._db, Settings(), self._currency)
+
+    async def get_group_allocations(self) -> dict:
+        """Get security groups with their current and ideal weight.
+
+        Returns:
+            dict with per-group rows and an ungrouped remainder
+        """
+        return await self._portfolio_analyzer.get_group_allocations()
+
     @staticmethod
     def _add_months(source: date, months: int) -> date:
         month_index = source.month - 1 + months
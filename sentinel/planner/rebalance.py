@@ -13,7 +13,10 @@ from typing import Any
 from sentinel.broker import Broker
 from sentinel.currency import Currency
 from sentinel.database import Database
+from sentinel.earnings import get_earnings_freeze
+from sentinel.exclusions import describe_exclusion, exclusion_matches, load_exclusion_lists
 from sentinel.forecasting.scoring import adjusted_opportunity_score
+from sentinel.news.sentiment import sentiment_timing_score
 from sentinel.portfolio import Portfolio
 from sentinel.price_validator import PriceValidator, check_trade_blocking
 from sentinel.settings import DEFAULTS, Settings
@@ -23,9 +26,12 @@ from sentinel.strategy import (
     effective_opportunity_score,
     recent_dd252_min,
 )
+from sentinel.utils.fees import FeeCalculator, spread_cost
 
 from .deposit_history import DepositHistoryHelper
+from .goals import load_goal_tilt
 from .models import PLANNING_HORIZON_MONTHS, PlannerState, TradeRecommendation
+from .monte_carlo import apply_monte_carlo
 from .preferences import is_explicit_downgrade
 from .rebalance_cash import apply_cash_constraint, generate_deficit_sells, get_deficit_sells
 from .rebalance_rules import (
@@ -35,6 +41,9 @@ from .rebalance_rules import (
     generate_buy_reason,
     get_forced_opportunity_exit,
 )
+from .savings import allocate_new_money, get_new_money_eur
+from .spread import MAX_SPREAD_BPS_KEY, quote_spread_bps, spread_adjusted_score, spread_too_wide
+from .volume import MAX_ORDER_PCT_ADV_KEY, max_order_quantity
 
 logger = logging.getLogger(__name__)
 
@@ -115,8 +124,6 @@ class RebalanceEngine:
 
     async def _load_runtime_settings(self) -> dict[str, float]:
         defaults: dict[str, float] = {
-            "transaction_fee_fixed": DEFAULTS["transaction_fee_fixed"],
-            "transaction_fee_percent": DEFAULTS["transaction_fee_percent"],
             "strategy_lot_standard_max_pct": DEFAULTS["strategy_lot_standard_max_pct"],
             "strategy_lot_coarse_max_pct": DEFAULTS["strategy_lot_coarse_max_pct"],
             "strategy_min_opp_score": DEFAULTS["strategy_min_opp_score"],
@@ -141,6 +148,16 @@ class RebalanceEngine:
             "forecasting_enabled": DEFAULTS["forecasting_enabled"],
             "forecasting_score_max_age_days": DEFAULTS["forecasting_score_max_age_days"],
             "forecasting_timing_weight": DEFAULTS["forecasting_timing_weight"],
+            "planner_monte_carlo_enabled": DEFAULTS["planner_monte_carlo_enabled"],
+            "planner_monte_carlo_paths": DEFAULTS["planner_monte_carlo_paths"],
+            "planner_monte_carlo_horizon_days": DEFAULTS["planner_monte_carlo_horizon_days"],
+            "planner_monte_carlo_top_k": DEFAULTS["planner_monte_carlo_top_k"],
+            "savings_plan_new_money_days": DEFAULTS["savings_plan_new_money_days"],
+            "earnings_freeze_days": DEFAULTS["earnings_freeze_days"],
+            "news_timing_weight": DEFAULTS["news_timing_weight"],
+            "news_score_max_age_days": DEFAULTS["news_score_max_age_days"],
+            MAX_SPREAD_BPS_KEY: DEFAULTS[MAX_SPREAD_BPS_KEY],
+            MAX_ORDER_PCT_ADV_KEY: DEFAULTS[MAX_ORDER_PCT_ADV_KEY],
         }
         keys = list(defaults.keys())
         values = await asyncio.gather(*[self._settings.get(k, defaults[k]) for k in keys])
@@ -235,6 +252,16 @@ class RebalanceEngine:
                     maybe_scores = await maybe_scores
                 if isinstance(maybe_scores, dict):
                     forecast_scores = maybe_scores
+        news_scores: dict[str, dict] = {}
+        if as_of_date is None and settings_ctx["news_timing_weight"] > 0:
+            news_getter = getattr(self._db, "get_news_sentiment", None)
+            if callable(news_getter):
+                max_age_seconds = int(settings_ctx["news_score_max_age_days"] * 86400)
+                maybe_news = news_getter(all_symbols, max_age_seconds=max_age_seconds)
+                if inspect.isawaitable(maybe_news):
+                    maybe_news = await maybe_news
+                if isinstance(maybe_news, dict):
+                    news_scores = maybe_news
 
         # Fetch all data in parallel for performance
         if as_of_date is not None:
@@ -253,8 +280,7 @@ class RebalanceEngine:
         )
         positions_map = {p["symbol"]: p for p in all_positions}
 
-        fee_fixed = settings_ctx["transaction_fee_fixed"]
-        fee_pct = settings_ctx["transaction_fee_percent"] / 100.0
+        cost_model = await FeeCalculator(self._settings).get_cost_model()
         lot_standard_max_pct = settings_ctx["strategy_lot_standard_max_pct"]
         lot_coarse_max_pct = settings_ctx["strategy_lot_coarse_max_pct"]
         min_opp_score = settings_ctx["strategy_min_opp_score"]
@@ -336,6 +362,12 @@ class RebalanceEngine:
             elif isinstance(maybe_latest, dict):
                 latest_trades_map = maybe_latest
 
+        exclusion_lists = await load_exclusion_lists(self._db)
+        planning_date = datetime.fromtimestamp(self._planning_timestamp(as_of_date), tz=timezone.utc).date()
+        earnings_freeze = await get_earnings_freeze(
+            self._db, planning_date, int(settings_ctx["earnings_freeze_days"])
+        )
+
         currencies = {(securities_map.get(symbol) or {}).get("currency", "EUR") for symbol in all_symbols}
         fx_values = await asyncio.gather(*[self._currency.get_rate(currency) for currency in currencies])
         fx_rates = {currency: rate for currency, rate in zip(currencies, fx_values, strict=False)}
@@ -393,14 +425,35 @@ class RebalanceEngine:
                 signal["forecast_score"] = float(forecast.get("score") or 0.5)
                 signal["forecast_return_4w"] = float(forecast.get("forecast_return_4w") or 0.0)
                 signal["forecast_updated_at"] = int(forecast.get("updated_at") or 0)
+            news = news_scores.get(symbol) or {}
+            if news.get("score") is not None:
+                signal["news_sentiment"] = float(news["score"])
+                if int(signal.get("freefall_block", 0) or 0) != 1:
+                    adjusted_score = adjusted_opportunity_score(
+                        current_opp_score=adjusted_score,
+                        forecast_score=sentiment_timing_score(float(news["score"])),
+                        weight=settings_ctx["news_timing_weight"],
+                    )
+                    signal["opp_score"] = adjusted_score
+            spread_bps = quote_spread_bps(current_quotes.get(symbol))
+            if spread_bps is not None:
+                signal["spread_bps"] = round(spread_bps, 1)
+                adjusted_score = spread_adjusted_score(adjusted_score, spread_bps, settings_ctx[MAX_SPREAD_BPS_KEY])
+                signal["opp_score"] = adjusted_score
+            wide_spread = spread_too_wide(spread_bps, settings_ctx[MAX_SPREAD_BPS_KEY])
             effective_score = adjusted_score
             contrarian_scores[symbol] = effective_score
 
             # Check for price anomaly using already prepared close series.
             trade_blocked, block_reason = self._check_price_anomaly_closes(price, closes, symbol)
+            if not trade_blocked and symbol in earnings_freeze:
+                trade_blocked, block_reason = True, f"Earnings on {earnings_freeze[symbol]}"
 
             symbol_currency = sec.get("currency", "EUR") if sec else "EUR"
             fx_rate = fx_rates.get(symbol_currency, 1.0)
+            market_id = sec.get("market_id") if sec else None
+            fee_fixed, fee_pct = cost_model.profile_for(market_id, symbol_currency).linear_terms(symbol_currency)
+            fee_pct += spread_cost(1.0, spread_bps)
             lot_profile = classify_lot_size(
                 price=price,
                 lot_size=sec.get("min_lot", 1) if sec else 1,
@@ -422,6 +475,11 @@ class RebalanceEngine:
             signal["state_scaleout_stage"] = int((strategy_states.get(symbol) or {}).get("scaleout_stage", 0) or 0)
             symbol_signals[symbol] = signal
 
+            exclusions = exclusion_matches(sec, exclusion_lists) if sec and exclusion_lists else []
+            # Stored volume figures describe today's market, so backtests skip them.
+            live_sec = sec if sec and as_of_date is None else {}
+            illiquid = live_sec.get("illiquid") == 1
+
             security_data[symbol] = {
                 "price": price,
                 "currency": sec.get("currency", "EUR") if sec else "EUR",
@@ -429,8 +487,9 @@ class RebalanceEngine:
                 "lot_size": sec.get("min_lot", 1) if sec else 1,
                 "current_qty": pos.get("quantity", 0) if pos else 0,
                 "avg_cost": pos.get("avg_cost", 0) if pos else 0,
-                "allow_buy": sec.get("allow_buy", 1) if sec else 1,
+                "allow_buy": 0 if exclusions or wide_spread or illiquid else (sec.get("allow_buy", 1) if sec else 1),
                 "allow_sell": sec.get("allow_sell", 1) if sec else 1,
+                "excluded_by": describe_exclusion(exclusions) if exclusions else None,
                 "trade_blocked": trade_blocked,
                 "block_reason": block_reason,
                 "lot_class": lot_profile["lot_class"],
@@ -438,6 +497,8 @@ class RebalanceEngine:
                 "min_ticket_eur": lot_profile["min_ticket_eur"],
                 "state": strategy_states.get(symbol) or {},
                 "is_downgrade": is_explicit_downgrade(sec) if sec else False,
+                "spread_bps": spread_bps,
+                "adv_volume": live_sec.get("adv_volume"),
             }
 
         self._last_security_data = {symbol: dict(data) for symbol, data in security_data.items()}
@@ -524,14 +585,34 @@ class RebalanceEngine:
             eligible_symbols=eligible_symbols,
             state=state,
         )
+        # Matched savings-plan deposits are live account state; simulations have none.
+        new_money_eur = 0.0
+        fallback_wait_days = float(settings_ctx["strategy_fallback_wait_days"])
+        if as_of_date is None and state is None:
+            new_money_eur = await get_new_money_eur(self._db, settings_ctx["savings_plan_new_money_days"])
+            # A goal behind schedule shortens the wait before fallback buys.
+            fallback_wait_days *= 1 - await load_goal_tilt(self._db, self._settings)
         recommendations = await self._select_executable_plan(
             recommendations,
             min_trade_value=float(min_trade_value),
-            fallback_wait_days=float(settings_ctx["strategy_fallback_wait_days"]),
+            fallback_wait_days=fallback_wait_days,
             as_of_date=as_of_date,
             track_fallback_state=track_fallback_state,
             cash_context=cash_context,
+            new_money_eur=new_money_eur,
         )
+        # Robustness check is for live decisions; backtests would pay for it on every simulated day.
+        if as_of_date is None and bool(settings_ctx["planner_monte_carlo_enabled"]) and recommendations:
+            recommendations = apply_monte_carlo(
+                recommendations,
+                {
+                    symbol: [float(r["close"]) for r in reversed(rows) if r.get("close") is not None]
+                    for symbol, rows in hist_prices_map.items()
+                },
+                top_k=int(settings_ctx["planner_monte_carlo_top_k"]),
+                paths=int(settings_ctx["planner_monte_carlo_paths"]),
+                horizon_days=int(settings_ctx["planner_monte_carlo_horizon_days"]),
+            )
 
         # Cache result only when live and DB-backed (not as_of_date / explicit state).
         if as_of_date is None and state is None and eligible_symbols is None and not track_fallback_state:
@@ -556,8 +637,9 @@ class RebalanceEngine:
         as_of_date: str | None,
         track_fallback_state: bool,
         cash_context: dict[str, Any],
+        new_money_eur: float = 0.0,
     ) -> list[TradeRecommendation]:
-        """Choose executable timely buys, then one due convergence fallback."""
+        """Choose executable timely buys, then new-money buys, then one due convergence fallback."""
         sells = sorted((r for r in recommendations if r.action == "sell"), key=lambda rec: -rec.priority)
         buys = sorted((r for r in recommendations if r.action == "buy"), key=buy_rank_key)
         timely = [rec for rec in buys if rec.timing_eligible]
@@ -577,6 +659,17 @@ class RebalanceEngine:
         if not fallback_candidates:
             return self._assign_execution_ranks(sells)
 
+        # Fresh savings-plan deposits are invested now rather than waiting for timing.
+        new_money_buys = allocate_new_money(fallback_candidates, new_money_eur, min_trade_value)
+        if new_money_buys:
+            new_money_plan = await self._apply_cash_constraint(
+                sells + new_money_buys,
+                min_trade_value,
+                **cash_context,
+            )
+            if any(rec.action == "buy" for rec in new_money_plan):
+                return self._assign_execution_ranks(new_money_plan)
+
         fallback_due = await self._fallback_is_due(
             wait_days=fallback_wait_days,
             as_of_date=as_of_date,
@@ -897,6 +990,10 @@ class RebalanceEngine:
             raw_qty = abs(local_value_delta) / price
             rounded_qty = (int(raw_qty) // lot_size) * lot_size
 
+        adv_cap = max_order_quantity(sec_data.get("adv_volume"), settings_ctx[MAX_ORDER_PCT_ADV_KEY], lot_size)
+        if adv_cap is not None:
+            rounded_qty = min(rounded_qty, adv_cap)
+
         if rounded_qty < lot_size:
             return None
 
@@ -1027,6 +1124,7 @@ class RebalanceEngine:
                 if action == "buy" and target_value_eur > 0
                 else 0.0
             ),
+            spread_bps=round(sec_data["spread_bps"], 1) if sec_data.get("spread_bps") is not None else None,
         )
 
     async def _check_cooloff_violation(
@@ -9,10 +9,12 @@ from dataclasses import replace
 from typing import TYPE_CHECKING
 
 from sentinel.strategy import compute_contrarian_signal
+from sentinel.utils.fees import FeeCalculator, spread_cost
 
+from .liquidity import load_liquidity_policy
 from .models import PlannerState, TradeRecommendation
 from .preferences import is_explicit_downgrade, normalize_user_multiplier
-from .rebalance_rules import buy_rank_key, calculate_transaction_cost
+from .rebalance_rules import buy_rank_key
 
 if TYPE_CHECKING:
     from .rebalance import RebalanceEngine
@@ -110,8 +112,12 @@ async def apply_cash_constraint(
     state: PlannerState | None = None,
 ) -> list[TradeRecommendation]:
     """Scale down buy recommendations to fit within available cash."""
-    fixed_fee = await engine._settings.get("transaction_fee_fixed", 2.0)
-    pct_fee = await engine._settings.get("transaction_fee_percent", 0.2) / 100
+    cost_model = await FeeCalculator(engine._settings).get_cost_model()
+    market_ids = {symbol: sec.get("market_id") for symbol, sec in (preloaded_securities_map or {}).items()}
+
+    def trade_cost(rec: TradeRecommendation, value: float) -> float:
+        fees = cost_model.cost(value, market_id=market_ids.get(rec.symbol), currency=rec.currency)
+        return fees + spread_cost(value, rec.spread_bps)
 
     sells = [r for r in recommendations if r.action == "sell"]
     base_sells = list(sells)
@@ -135,18 +141,15 @@ async def apply_cash_constraint(
         if inspect.isawaitable(maybe_total):
             maybe_total = await maybe_total
         reserve_base = float(maybe_total) if isinstance(maybe_total, int | float) else 0.0
-    min_cash_buffer = max(0.0, float(await _setting(engine, "min_cash_buffer", 0.005)))
-    cash_reserve = reserve_base * min_cash_buffer
+    policy = await load_liquidity_policy(lambda key, default: _setting(engine, key, default))
+    reserve = await policy.reserve(reserve_base, engine._currency.to_eur, include_account=as_of_date is None)
+    cash_reserve = reserve["total_eur"]
 
-    net_sell_proceeds = sum(
-        abs(r.value_delta_eur) - calculate_transaction_cost(abs(r.value_delta_eur), fixed_fee, pct_fee) for r in sells
-    )
+    net_sell_proceeds = sum(abs(r.value_delta_eur) - trade_cost(r, abs(r.value_delta_eur)) for r in sells)
     available_budget = max(0.0, current_cash + net_sell_proceeds - cash_reserve)
 
     # Calculate total buy costs
-    total_buy_costs = sum(
-        r.value_delta_eur + calculate_transaction_cost(r.value_delta_eur, fixed_fee, pct_fee) for r in buys
-    )
+    total_buy_costs = sum(r.value_delta_eur + trade_cost(r, r.value_delta_eur) for r in buys)
 
     # When budget is tight, treat lower-conviction names as opportunistic:
     # trim weakest buys first before forcing additional funding sells.
@@ -185,7 +188,7 @@ async def apply_cash_constraint(
     funded_prefix: list[TradeRecommendation] = []
     deficit = 0.0
     for buy in buys:
-        full_cost = buy.value_delta_eur + calculate_transaction_cost(buy.value_delta_eur, fixed_fee, pct_fee)
+        full_cost = buy.value_delta_eur + trade_cost(buy, buy.value_delta_eur)
         funded_prefix.append(buy)
         if full_cost <= remaining_for_ranked_buys:
             remaining_for_ranked_buys -= full_cost
@@ -240,14 +243,9 @@ async def apply_cash_constraint(
             new_sells = [s for s in funding_sells if s.symbol not in existing_sell_symbols]
             if new_sells:
                 sells.extend(new_sells)
-                net_sell_proceeds = sum(
-                    abs(r.value_delta_eur) - calculate_transaction_cost(abs(r.value_delta_eur), fixed_fee, pct_fee)
-                    for r in sells
-                )
+                net_sell_proceeds = sum(abs(r.value_delta_eur) - trade_cost(r, abs(r.value_delta_eur)) for r in sells)
                 available_budget = max(0.0, current_cash + net_sell_proceeds - cash_reserve)
-                total_buy_costs = sum(
-                    r.value_delta_eur + calculate_transaction_cost(r.value_delta_eur, fixed_fee, pct_fee) for r in buys
-                )
+                total_buy_costs = sum(r.value_delta_eur + trade_cost(r, r.value_delta_eur) for r in buys)
                 if total_buy_costs <= available_budget:
                     return sells + buys
 
@@ -260,13 +258,17 @@ async def apply_cash_constraint(
         if one_lot_eur <= 0:
             continue
 
-        cost_for_full_buy = buy.value_delta_eur + calculate_transaction_cost(buy.value_delta_eur, fixed_fee, pct_fee)
-        desired_eur = buy.value_delta_eur if cost_for_full_buy <= remaining_budget else remaining_budget / (1 + pct_fee)
+        cost_for_full_buy = buy.value_delta_eur + trade_cost(buy, buy.value_delta_eur)
+        if cost_for_full_buy <= remaining_budget:
+            desired_eur = buy.value_delta_eur
+        else:
+            _, pct_fee = cost_model.profile_for(market_ids.get(buy.symbol), buy.currency).linear_terms(buy.currency)
+            desired_eur = remaining_budget / (1 + pct_fee + spread_cost(1.0, buy.spread_bps))
         qty, actual_eur = await _value_to_quantity(engine, buy, desired_eur, fx_rates)
         if qty < buy.lot_size or actual_eur < min_trade_value:
             continue
 
-        cost = actual_eur + calculate_transaction_cost(actual_eur, fixed_fee, pct_fee)
+        cost = actual_eur + trade_cost(buy, actual_eur)
         if cost > remaining_budget:
             continue
 
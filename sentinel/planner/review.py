@@ -0,0 +1,75 @@
+"""
+Manual review of trade recommendations.
+
+A rejected recommendation (symbol + action) is hidden from the recommendations
+API and skipped by trading:execute until the rejection expires. Rejections
+live in planner_state so they survive restarts without a schema change.
+"""
+
+from __future__ import annotations
+
+import time
+from typing import Iterable, TypeVar
+
+REJECTED_STATE_KEY = "rejected_recommendations"
+DEFAULT_REJECT_HOURS = 24
+MAX_REJECT_HOURS = 7 * 24
+ACTIONS = ("buy", "sell")
+
+T = TypeVar("T")
+
+
+def recommendation_key(symbol: str, action: str) -> str:
+    return f"{symbol}:{action}"
+
+
+def validate_target(symbol: object, action: object) -> tuple[str, str]:
+    """Validate a (symbol, action) pair from request input.
+
+    Raises:
+        ValueError: If either part is missing or the action is unknown.
+    """
+    if not isinstance(symbol, str) or not symbol.strip():
+        raise ValueError("symbol is required")
+    if action not in ACTIONS:
+        raise ValueError(f"action must be one of {list(ACTIONS)}")
+    return symbol.strip(), action
+
+
+async def get_rejections(db, now_ts: int | None = None) -> dict[str, int]:
+    """Active rejections as {"SYMBOL:action": expires_at_ts}."""
+    now_ts = int(time.time()) if now_ts is None else now_ts
+    raw = await db.get_planner_state(REJECTED_STATE_KEY, default={})
+    if not isinstance(raw, dict):
+        return {}
+    return {key: int(expires) for key, expires in raw.items() if isinstance(expires, int) and expires > now_ts}
+
+
+async def reject_recommendation(
+    db,
+    symbol: str,
+    action: str,
+    hours: int = DEFAULT_REJECT_HOURS,
+    now_ts: int | None = None,
+) -> int:
+    """Reject a recommendation for `hours` hours; returns the expiry timestamp."""
+    if isinstance(hours, bool) or not isinstance(hours, int) or hours < 1 or hours > MAX_REJECT_HOURS:
+        raise ValueError(f"hours must be an integer in [1, {MAX_REJECT_HOURS}]")
+    now_ts = int(time.time()) if now_ts is None else now_ts
+    rejections = await get_rejections(db, now_ts=now_ts)
+    expires_at_ts = now_ts + hours * 3600
+    rejections[recommendation_key(symbol, action)] = expires_at_ts
+    await db.set_planner_state(REJECTED_STATE_KEY, rejections)
+    return expires_at_ts
+
+
+async def clear_rejection(db, symbol: str, action: str) -> None:
+    """Drop a rejection (and any expired ones) so the recommendation shows again."""
+    rejections = await get_rejections(db)
+    rejections.pop(recommendation_key(symbol, action), None)
+    await db.set_planner_state(REJECTED_STATE_KEY, rejections)
+
+
+def filter_rejected(recommendations: Iterable[T], rejections: dict[str, int]) -> list[T]:
+    """Drop recommendations whose (symbol, action) is currently rejected."""
+    return [r for r in recommendations if recommendation_key(r.symbol, r.action) not in rejections]
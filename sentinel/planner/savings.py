@@ -0,0 +1,240 @@
+"""
+Savings plans - recurring deposits and the new-money allocation strategy.
+
+A savings plan declares an expected recurring card deposit (e.g. EUR 500 on
+the 1st of every month). After each cash-flow sync, process_savings_deposits()
+matches incoming card deposits to plans: same currency, amount within
+`tolerance_pct` of the plan amount, and landing within `window_days` of the
+plan's day of month. Each plan matches at most one deposit per month.
+
+Matched deposits are "new money" for `savings_plan_new_money_days`. While any
+of it is unspent, the planner does not wait for entry timing or the
+convergence fallback: allocate_new_money() spreads the new money over the
+remaining buy candidates in gap order, and those buys carry the
+`new_money` reason code. New-money buys are booked against the deposits,
+oldest first, once reconciliation finds their fill in the broker's trades,
+at the filled quantity and price; a cancelled or rejected order books nothing.
+"""
+
+from __future__ import annotations
+
+import inspect
+import logging
+import math
+from dataclasses import replace
+from datetime import date, datetime, timedelta
+from typing import Any
+
+from .models import TradeRecommendation
+
+logger = logging.getLogger(__name__)
+
+NEW_MONEY_REASON_CODE = "new_money"
+NEW_MONEY_DAYS_KEY = "savings_plan_new_money_days"
+MATCH_LOOKBACK_DAYS = 62
+
+
+def validate_savings_plan(data: Any, partial: bool = False) -> dict[str, Any]:
+    """Validate a savings plan payload.
+
+    Args:
+        data: Request body
+        partial: Allow missing fields (for updates)
+
+    Raises:
+        ValueError: If a field is missing or malformed.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("savings plan must be an object")
+    plan: dict[str, Any] = {}
+
+    if "name" in data or not partial:
+        name = data.get("name")
+        if not isinstance(name, str) or not name.strip():
+            raise ValueError("name must be a non-empty string")
+        plan["name"] = name.strip()
+
+    if "amount" in data or not partial:
+        amount = data.get("amount")
+        if isinstance(amount, bool) or not isinstance(amount, int | float) or not math.isfinite(amount) or amount <= 0:
+            raise ValueError("amount must be a positive number")
+        plan["amount"] = float(amount)
+
+    if "currency" in data:
+        currency = data.get("currency")
+        if not isinstance(currency, str) or len(currency.strip()) != 3:
+            raise ValueError("currency must be a 3-letter currency code")
+        plan["currency"] = currency.strip().upper()
+
+    if "day_of_month" in data or not partial:
+        day = data.get("day_of_month")
+        if isinstance(day, bool) or not isinstance(day, int) or not 1 <= day <= 28:
+            raise ValueError("day_of_month must be an integer between 1 and 28")
+        plan["day_of_month"] = day
+
+    if "tolerance_pct" in data:
+        tolerance = data.get("tolerance_pct")
+        if isinstance(tolerance, bool) or not isinstance(tolerance, int | float) or not 0 <= tolerance <= 100:
+            raise ValueError("tolerance_pct must be between 0 and 100")
+        plan["tolerance_pct"] = float(tolerance)
+
+    if "window_days" in data:
+        window = data.get("window_days")
+        if isinstance(window, bool) or not isinstance(window, int) or not 0 <= window <= 14:
+            raise ValueError("window_days must be an integer between 0 and 14")
+        plan["window_days"] = window
+
+    if "start_date" in data:
+        start = data.get("start_date")
+        try:
+            plan["start_date"] = datetime.strptime(str(start), "%Y-%m-%d").date().isoformat()
+        except ValueError as e:
+            raise ValueError("start_date must be YYYY-MM-DD") from e
+    elif not partial:
+        plan["start_date"] = date.today().isoformat()
+
+    if "active" in data:
+        if not isinstance(data.get("active"), bool):
+            raise ValueError("active must be a boolean")
+        plan["active"] = 1 if data["active"] else 0
+
+    return plan
+
+
+def _parse_date(value: Any) -> date | None:
+    try:
+        return datetime.strptime(str(value)[:10], "%Y-%m-%d").date()
+    except ValueError:
+        return None
+
+
+def expected_period(plan: dict[str, Any], deposit_date: date) -> str | None:
+    """The YYYY-MM whose expected deposit lies within the plan window of deposit_date."""
+    window = int(plan.get("window_days", 5) or 0)
+    day = int(plan["day_of_month"])
+    for month_offset in (-1, 0, 1):
+        month_index = deposit_date.year * 12 + deposit_date.month - 1 + month_offset
+        expected = date(month_index // 12, month_index % 12 + 1, day)
+        if abs((deposit_date - expected).days) <= window:
+            return expected.strftime("%Y-%m")
+    return None
+
+
+def match_period(plan: dict[str, Any], cash_flow: dict[str, Any]) -> str | None:
+    """Return the plan period a card cash flow pays for, or None if it does not match."""
+    if cash_flow.get("type_id") != "card":
+        return None
+    if str(cash_flow.get("currency") or "EUR").upper() != str(plan.get("currency") or "EUR").upper():
+        return None
+    deposit_date = _parse_date(cash_flow.get("date"))
+    start_date = _parse_date(plan.get("start_date"))
+    if deposit_date is None or (start_date is not None and deposit_date < start_date):
+        return None
+    expected = float(plan["amount"])
+    tolerance = expected * float(plan.get("tolerance_pct", 10) or 0) / 100
+    if abs(abs(float(cash_flow.get("amount") or 0.0)) - expected) > tolerance:
+        return None
+    return expected_period(plan, deposit_date)
+
+
+async def process_savings_deposits(db, currency, today: date | None = None) -> int:
+    """Match recent card deposits to active savings plans; returns the number newly matched."""
+    plans = await db.get_savings_plans(active_only=True)
+    if not isinstance(plans, list) or not plans:
+        return 0
+    today = today or date.today()
+    start = (today - timedelta(days=MATCH_LOOKBACK_DAYS)).isoformat()
+
+    flows = await db.get_cash_flows(type_id="card", start_date=start)
+    # A period can straddle the lookback edge, so look one extra window back for taken periods.
+    existing_start = (today - timedelta(days=MATCH_LOOKBACK_DAYS + 14)).isoformat()
+    existing = await db.get_savings_deposits(start_date=existing_start, limit=1000)
+    matched_ids = {d["cash_flow_id"] for d in existing}
+    taken = {(d["plan_id"], d["period"]) for d in existing}
+
+    matched = 0
+    for flow in sorted(flows, key=lambda f: (f["date"], f["id"])):
+        if flow["id"] in matched_ids:
+            continue
+        for plan in plans:
+            period = match_period(plan, flow)
+            if period is None or (plan["id"], period) in taken:
+                continue
+            amount_eur = await currency.to_eur_for_date(
+                amount=abs(float(flow["amount"])), currency=flow["currency"], date=flow["date"]
+            )
+            if await db.record_savings_deposit(flow["id"], plan["id"], period, str(flow["date"])[:10], amount_eur):
+                taken.add((plan["id"], period))
+                matched += 1
+                logger.info(f"Matched deposit {flow['id']} to savings plan '{plan['name']}' for {period}")
+            break
+    return matched
+
+
+def new_money_start_date(window_days: float, as_of: date | None = None) -> str:
+    return ((as_of or date.today()) - timedelta(days=max(0, int(window_days)))).isoformat()
+
+
+async def get_new_money_eur(db, window_days: float) -> float:
+    """Unspent matched deposit value inside the new-money window (0 without savings plans)."""
+    getter = getattr(db, "get_savings_new_money_eur", None)
+    if not callable(getter):
+        return 0.0
+    value = getter(new_money_start_date(window_days))
+    if inspect.isawaitable(value):
+        value = await value
+    return float(value) if isinstance(value, int | float) else 0.0
+
+
+def allocate_new_money(
+    candidates: list[TradeRecommendation],
+    new_money_eur: float,
+    min_trade_value: float,
+) -> list[TradeRecommendation]:
+    """Spread new money over buy candidates in the given (gap) order.
+
+    Each candidate is cut down to whole lots of what is left; candidates that
+    would fall below min_trade_value are skipped.
+    """
+    remaining = new_money_eur
+    allocated: list[TradeRecommendation] = []
+    for candidate in candidates:
+        if remaining < min_trade_value:
+            break
+        if candidate.quantity <= 0 or candidate.value_delta_eur <= 0 or candidate.lot_size <= 0:
+            continue
+        lot_value = candidate.value_delta_eur / candidate.quantity * candidate.lot_size
+        lots = min(int(remaining // lot_value), candidate.quantity // candidate.lot_size)
+        value = lots * lot_value
+        if lots <= 0 or value < min_trade_value:
+            continue
+        allocated.append(
+            replace(
+                candidate,
+                quantity=lots * candidate.lot_size,
+                value_delta_eur=value,
+                reason_code=NEW_MONEY_REASON_CODE,
+                reason=f"New money from savings plan deposit: target missing={candidate.target_gap_ratio:.0%}",
+            )
+        )
+        remaining -= value
+    return allocated
+
+
+async def book_new_money_fill(
+    db, rec: TradeRecommendation, filled_quantity: float, fill_price: float, window_days: float
+) -> float:
+    """Count the filled part of a new-money buy against the matched deposits it spends.
+
+    The fill is valued in EUR at the recommendation's own exchange rate.
+    Returns the EUR value booked (0 for other trades).
+    """
+    if rec.action != "buy" or rec.reason_code != NEW_MONEY_REASON_CODE or filled_quantity <= 0:
+        return 0.0
+    planned = float(rec.quantity) * float(rec.price)
+    if planned > 0:
+        value_eur = abs(float(rec.value_delta_eur)) * filled_quantity * fill_price / planned
+    else:
+        value_eur = abs(float(rec.value_delta_eur))
+    await db.mark_savings_invested(value_eur, new_money_start_date(window_days))
+    return value_eur
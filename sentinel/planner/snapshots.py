@@ -0,0 +1,226 @@
+"""
+Planner result snapshots and cycle-to-cycle diffs.
+
+Every planning cycle (planning:refresh, trading:execute) records the inputs it
+planned from — cash, total value, planner settings, and per-security price,
+opportunity score and target/current allocation — next to the recommendations
+it produced, including their Monte Carlo robustness statistics when the planner
+computed them. diff_snapshots() compares two snapshots and reports which inputs
+moved and how the top of the trade sequence changed, so a flip such as "sell
+X" becoming "buy Y" can be traced back to the price, score or cash move that
+caused it.
+
+The planner has no separate market-regime model; regime-like behaviour is
+driven by the strategy settings, which are captured as inputs instead.
+"""
+
+from __future__ import annotations
+
+import inspect
+from typing import Any, Iterable
+
+from sentinel.settings import PLANNER_SETTING_KEYS, Settings
+
+from .models import TradeRecommendation
+
+MAX_SNAPSHOTS = 500
+TOP_SEQUENCE_SIZE = 10
+
+# Changes smaller than these are treated as noise and not reported.
+CASH_TOLERANCE_EUR = 1.0
+PRICE_TOLERANCE_PCT = 0.001
+SCORE_TOLERANCE = 0.01
+ALLOCATION_TOLERANCE = 0.001
+
+
+def _serialize_recommendation(rec: TradeRecommendation) -> dict[str, Any]:
+    return {
+        "symbol": rec.symbol,
+        "action": rec.action,
+        "quantity": rec.quantity,
+        "price": rec.price,
+        "value_delta_eur": rec.value_delta_eur,
+        "priority": rec.priority,
+        "reason_code": rec.reason_code,
+        "execution_rank": rec.execution_rank,
+        "robustness": rec.robustness,
+    }
+
+
+def _ranked(recommendations: Iterable[TradeRecommendation]) -> list[TradeRecommendation]:
+    return sorted(recommendations, key=lambda r: (r.execution_rank is None, r.execution_rank or 0, -r.priority))
+
+
+async def build_context(inputs: dict[str, Any]) -> dict[str, Any]:
+    """Condense Planner.get_last_planning_inputs() into a JSON-serializable snapshot context."""
+    ideal = inputs.get("ideal") or {}
+    current = inputs.get("current") or {}
+    signals = (inputs.get("signal_bundle") or {}).get("rebalance_signals") or {}
+    security_data = inputs.get("security_data") or {}
+
+    securities: dict[str, dict[str, Any]] = {}
+    for symbol in sorted(set(ideal) | set(current) | set(security_data)):
+        data = security_data.get(symbol) or {}
+        signal = signals.get(symbol) or {}
+        securities[symbol] = {
+            "price": data.get("price"),
+            "quantity": data.get("current_qty", 0),
+            "opp_score": signal.get("opp_score"),
+            "target_pct": float(ideal.get(symbol, 0.0) or 0.0),
+            "current_pct": float(current.get(symbol, 0.0) or 0.0),
+            "trade_blocked": bool(data.get("trade_blocked", False)),
+        }
+
+    settings = Settings()
+    planner_settings = {key: await settings.get(key) for key in sorted(PLANNER_SETTING_KEYS)}
+
+    return {
+        "cash_eur": inputs.get("cash_eur"),
+        "total_value_eur": inputs.get("total_value_eur"),
+        "settings": planner_settings,
+        "securities": securities,
+    }
+
+
+async def record_snapshot(db, planner, recommendations: list[TradeRecommendation], source: str) -> int | None:
+    """Persist the planner's most recent inputs with the batch they produced.
+
+    Returns the snapshot id, or None when the planner has no inputs to record.
+    """
+    inputs = planner.get_last_planning_inputs()
+    if inspect.isawaitable(inputs):
+        inputs = await inputs
+    if not isinstance(inputs, dict) or not inputs or inputs.get("as_of_date") is not None:
+        return None
+    context = await build_context(inputs)
+    snapshot_id = await db.insert_planner_snapshot(
+        source,
+        context,
+        [_serialize_recommendation(r) for r in _ranked(recommendations)],
+    )
+    await db.prune_planner_snapshots(MAX_SNAPSHOTS)
+    return snapshot_id
+
+
+def _changed(before: Any, after: Any, tolerance: float, relative: bool = False) -> bool:
+    if before is None or after is None:
+        return before != after
+    delta = abs(float(after) - float(before))
+    if relative:
+        base = abs(float(before))
+        return delta / base > tolerance if base > 0 else delta > 0
+    return delta > tolerance
+
+
+def _input_changes(before: dict[str, Any], after: dict[str, Any]) -> dict[str, Any]:
+    changes: dict[str, Any] = {}
+
+    for key in ("cash_eur", "total_value_eur"):
+        if _changed(before.get(key), after.get(key), CASH_TOLERANCE_EUR):
+            changes[key] = {"from": before.get(key), "to": after.get(key)}
+
+    settings_before = before.get("settings") or {}
+    settings_after = after.get("settings") or {}
+    settings_changed = {
+        key: {"from": settings_before.get(key), "to": settings_after.get(key)}
+        for key in sorted(set(settings_before) | set(settings_after))
+        if settings_before.get(key) != settings_after.get(key)
+    }
+    if settings_changed:
+        changes["settings"] = settings_changed
+
+    securities_before = before.get("securities") or {}
+    securities_after = after.get("securities") or {}
+    per_symbol: dict[str, dict[str, Any]] = {}
+    for symbol in sorted(set(securities_before) | set(securities_after)):
+        a = securities_before.get(symbol)
+        b = securities_after.get(symbol)
+        if a is None or b is None:
+            per_symbol[symbol] = {"universe": {"from": a is not None, "to": b is not None}}
+            continue
+        fields: dict[str, Any] = {}
+        checks = (
+            ("price", PRICE_TOLERANCE_PCT, True),
+            ("opp_score", SCORE_TOLERANCE, False),
+            ("target_pct", ALLOCATION_TOLERANCE, False),
+            ("current_pct", ALLOCATION_TOLERANCE, False),
+        )
+        for field_name, tolerance, relative in checks:
+            if _changed(a.get(field_name), b.get(field_name), tolerance, relative=relative):
+                fields[field_name] = {"from": a.get(field_name), "to": b.get(field_name)}
+        for field_name in ("quantity", "trade_blocked"):
+            if a.get(field_name) != b.get(field_name):
+                fields[field_name] = {"from": a.get(field_name), "to": b.get(field_name)}
+        if fields:
+            per_symbol[symbol] = fields
+    if per_symbol:
+        changes["securities"] = per_symbol
+
+    return changes
+
+
+def _sequence_changes(before: list[dict], after: list[dict], top: int) -> dict[str, Any]:
+    def keyed(recs: list[dict]) -> dict[tuple[str, str], tuple[int, dict]]:
+        return {(r["symbol"], r["action"]): (rank, r) for rank, r in enumerate(recs[:top], start=1)}
+
+    old = keyed(before)
+    new = keyed(after)
+
+    added = [{"symbol": s, "action": a, "rank": rank} for (s, a), (rank, _) in new.items() if (s, a) not in old]
+    removed = [{"symbol": s, "action": a, "rank": rank} for (s, a), (rank, _) in old.items() if (s, a) not in new]
+    moved = []
+    resized = []
+    for key, (rank, rec) in new.items():
+        if key not in old:
+            continue
+        old_rank, old_rec = old[key]
+        if old_rank != rank:
+            moved.append({"symbol": key[0], "action": key[1], "from": old_rank, "to": rank})
+        if old_rec.get("quantity") != rec.get("quantity"):
+            resized.append(
+                {"symbol": key[0], "action": key[1], "from": old_rec.get("quantity"), "to": rec.get("quantity")}
+            )
+
+    return {
+        "from": [f"{r['action']} {r['symbol']}" for r in before[:top]],
+        "to": [f"{r['action']} {r['symbol']}" for r in after[:top]],
+        "unchanged": not (added or removed or moved or resized),
+        "added": sorted(added, key=lambda r: r["rank"]),
+        "removed": sorted(removed, key=lambda r: r["rank"]),
+        "moved": sorted(moved, key=lambda r: r["to"]),
+        "resized": resized,
+    }
+
+
+def diff_snapshots(before: dict[str, Any], after: dict[str, Any], top: int = TOP_SEQUENCE_SIZE) -> dict[str, Any]:
+    """Explain how the planner's inputs and top trade sequence changed between two snapshots.
+
+    Each sequence change is annotated with the input changes for its symbol; the
+    portfolio-wide changes (cash, total value, settings) apply to all of them.
+    """
+    inputs = _input_changes(before.get("context") or {}, after.get("context") or {})
+    sequence = _sequence_changes(before.get("recommendations") or [], after.get("recommendations") or [], top)
+
+    per_symbol = inputs.get("securities") or {}
+    global_keys = [key for key in ("cash_eur", "total_value_eur", "settings") if key in inputs]
+    explanations = []
+    for kind in ("added", "removed", "moved", "resized"):
+        for entry in sequence[kind]:
+            symbol = entry["symbol"]
+            explanations.append(
+                {
+                    "change": kind,
+                    "symbol": symbol,
+                    "action": entry["action"],
+                    "symbol_inputs": per_symbol.get(symbol, {}),
+                    "portfolio_inputs": global_keys,
+                }
+            )
+
+    return {
+        "from": {"id": before.get("id"), "created_at": before.get("created_at"), "source": before.get("source")},
+        "to": {"id": after.get("id"), "created_at": after.get("created_at"), "source": after.get("source")},
+        "inputs": inputs,
+        "sequence": sequence,
+        "explanations": explanations,
+    }
@@ -0,0 +1,53 @@
+"""Bid/ask spread awareness for planning.
+
+The spread comes from the live quote's best bid/ask (`bbp`/`bap`). It is in
+basis points of the mid price. Crossing it costs half of it on each trade, which
+the cost estimates add to fees (see `sentinel.utils.fees.spread_cost`).
+
+With `planner_max_spread_bps` above 0:
+
+- a security quoted wider than it is not bought this cycle (sells still go
+  through, so deficit and exit sells are never stuck on an illiquid name)
+- narrower spreads scale the opportunity score down linearly, by up to
+  SPREAD_SCORE_DAMPING at the threshold, so liquid candidates rank first
+
+Quotes without both sides (and backtests, which have no quotes) leave
+planning unchanged.
+"""
+
+from __future__ import annotations
+
+from typing import Any
+
+MAX_SPREAD_BPS_KEY = "planner_max_spread_bps"
+SPREAD_SCORE_DAMPING = 0.5
+
+
+def _positive(value: Any) -> float | None:
+    try:
+        number = float(value)
+    except (TypeError, ValueError):
+        return None
+    return number if number > 0 else None
+
+
+def quote_spread_bps(quote: dict | None) -> float | None:
+    """Bid/ask spread of a quote in basis points of the mid; None without a valid bid and ask."""
+    if not quote:
+        return None
+    bid = _positive(quote.get("bid", quote.get("bbp")))
+    ask = _positive(quote.get("ask", quote.get("bap")))
+    if bid is None or ask is None or ask < bid:
+        return None
+    return (ask - bid) / ((ask + bid) / 2) * 10000
+
+
+def spread_too_wide(spread_bps: float | None, max_bps: float) -> bool:
+    return spread_bps is not None and max_bps > 0 and spread_bps > max_bps
+
+
+def spread_adjusted_score(score: float, spread_bps: float | None, max_bps: float) -> float:
+    """Opportunity score scaled down by the spread's share of the threshold."""
+    if spread_bps is None or max_bps <= 0:
+        return score
+    return score * (1 - SPREAD_SCORE_DAMPING * min(1.0, spread_bps / max_bps))
@@ -0,0 +1,205 @@
+"""
+Position targets - explicit per-security target weights.
+
+A target is a weight in percent of the whole portfolio, stored on the
+security with its source and mode:
+
+- `manual` targets are set by the user and constrain the ideal portfolio.
+  A `hard` target pins the position at its weight; the other positions share
+  what is left of the invested budget. A `soft` target replaces the weight the
+  strategy would give the position, but is scaled together with the other
+  positions when the budget cannot fit them all.
+- `optimizer` targets are the planner's own ideal weights, adopted as a
+  reference point for drift. The planner does not read them back.
+
+A manual target counts as an endorsement: the position is held even when its
+preference is below the qualifying threshold. Buy blocks and exclusion lists
+still win over any target, and every target is capped at `max_position_pct`.
+"""
+
+from __future__ import annotations
+
+import math
+from typing import Any
+
+from sentinel.planner.preferences import apply_max_cap
+
+TARGET_MODES = ("hard", "soft")
+TARGET_SOURCES = ("manual", "optimizer")
+
+
+def validate_position_target(data: Any) -> dict[str, Any]:
+    """Validate a position target payload into {"target_pct", "mode"}.
+
+    Raises:
+        ValueError: If target_pct or mode is missing or malformed.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("target must be an object")
+    target_pct = data.get("target_pct")
+    if isinstance(target_pct, bool) or not isinstance(target_pct, int | float) or not math.isfinite(target_pct):
+        raise ValueError("target_pct must be a number")
+    if not 0 <= target_pct <= 100:
+        raise ValueError("target_pct must be between 0 and 100")
+    mode = data.get("mode", "soft")
+    if mode not in TARGET_MODES:
+        raise ValueError(f"mode must be one of {', '.join(TARGET_MODES)}")
+    return {"target_pct": float(target_pct), "mode": mode}
+
+
+def validate_target_changes(data: Any, securities: list[dict]) -> dict[str, dict[str, Any] | None]:
+    """Validate a bulk edit {"targets": {symbol: {"target_pct", "mode"} | null}}.
+
+    A null entry clears the security's target. Returns symbol -> validated
+    target or None.
+
+    Raises:
+        ValueError: If a symbol is unknown, a target is malformed, or the
+            manual targets after the edit add up to more than 100%.
+    """
+    if not isinstance(data, dict) or not isinstance(data.get("targets"), dict) or not data["targets"]:
+        raise ValueError("targets must be a non-empty object keyed by symbol")
+    known = {security["symbol"] for security in securities}
+    changes: dict[str, dict[str, Any] | None] = {}
+    for symbol, target in data["targets"].items():
+        if symbol not in known:
+            raise ValueError(f"unknown security {symbol}")
+        try:
+            changes[symbol] = None if target is None else validate_position_target(target)
+        except ValueError as e:
+            raise ValueError(f"{symbol}: {e}") from e
+    total = manual_target_total(with_target_changes(securities, changes))
+    if total > 100:
+        raise ValueError(f"manual targets add up to {total:g}%, more than 100%")
+    return changes
+
+
+def with_target_changes(
+    securities: list[dict],
+    changes: dict[str, dict[str, Any] | None],
+    updated_at: str | None = None,
+) -> list[dict]:
+    """Copies of the security rows with `changes` applied as manual targets."""
+    result = []
+    for security in securities:
+        if security.get("symbol") not in changes:
+            result.append(security)
+            continue
+        target = changes[security["symbol"]]
+        result.append(
+            {
+                **security,
+                "target_weight_pct": target["target_pct"] if target else None,
+                "target_weight_mode": target["mode"] if target else None,
+                "target_weight_source": "manual" if target else None,
+                "target_weight_updated_at": updated_at if target else None,
+            }
+        )
+    return result
+
+
+def manual_target_total(securities: list[dict]) -> float:
+    """Sum of manual targets in percent."""
+    return sum(
+        target["target_pct"]
+        for target in (position_target(security) for security in securities)
+        if target and target["source"] == "manual"
+    )
+
+
+def position_target(security: dict) -> dict[str, Any] | None:
+    """The target stored on a security row, or None when it has none."""
+    target_pct = security.get("target_weight_pct")
+    if target_pct is None:
+        return None
+    return {
+        "target_pct": float(target_pct),
+        "mode": security.get("target_weight_mode") or "soft",
+        "source": security.get("target_weight_source") or "manual",
+        "updated_at": security.get("target_weight_updated_at"),
+    }
+
+
+def apply_position_targets(
+    weights: dict[str, float],
+    targets: dict[str, dict[str, Any]],
+    *,
+    budget: float,
+    max_position: float,
+) -> dict[str, float]:
+    """Fit manual position targets into ideal weights.
+
+    Args:
+        weights: symbol -> weight as a fraction of the portfolio
+        targets: symbol -> {"target_pct", "mode"} for manual targets only
+        budget: Portfolio fraction to invest (everything but the cash target)
+        max_position: Per-position cap as a fraction of the portfolio
+
+    Returns:
+        symbol -> weight as a fraction of the portfolio; zero weights are dropped
+    """
+    if not targets or budget <= 0:
+        return dict(weights)
+
+    def capped(symbol: str) -> float:
+        return max(0.0, min(targets[symbol]["target_pct"] / 100.0, max_position))
+
+    hard = {symbol: capped(symbol) for symbol, target in targets.items() if target["mode"] == "hard"}
+    hard_total = sum(hard.values())
+    if hard_total > budget:
+        hard = {symbol: weight * budget / hard_total for symbol, weight in hard.items()}
+        hard_total = budget
+    result = {symbol: weight for symbol, weight in hard.items() if weight > 0}
+
+    remaining = budget - hard_total
+    flexible = {symbol: weight for symbol, weight in weights.items() if symbol not in targets}
+    flexible.update({symbol: capped(symbol) for symbol, target in targets.items() if target["mode"] == "soft"})
+    flexible_total = sum(weight for weight in flexible.values() if weight > 0)
+    if remaining <= 0 or flexible_total <= 0:
+        return result
+    if flexible_total > remaining:
+        # Too much to fit: everything flexible, soft targets included, shrinks alike.
+        fitted = {symbol: weight * remaining / flexible_total for symbol, weight in flexible.items()}
+    else:
+        # Room to spare: spread it like the strategy would, keeping soft targets
+        # where the user put them.
+        soft = {symbol: weight for symbol, weight in flexible.items() if symbol in targets}
+        others = {symbol: weight for symbol, weight in flexible.items() if symbol not in targets}
+        fitted = dict(soft)
+        spare = remaining - sum(soft.values())
+        if others and spare > 0:
+            fitted.update(
+                {symbol: weight * spare for symbol, weight in apply_max_cap(others, max_position / spare).items()}
+            )
+    result.update({symbol: weight for symbol, weight in fitted.items() if weight > 0})
+    return result
+
+
+def position_target_drift(
+    securities: list[dict],
+    current: dict[str, float],
+    ideal: dict[str, float],
+) -> list[dict[str, Any]]:
+    """Drift of every targeted position from its target, largest first.
+
+    Weights are fractions; the rows are in percent.
+    """
+    rows = []
+    for security in securities:
+        target = position_target(security)
+        if target is None:
+            continue
+        symbol = security["symbol"]
+        current_pct = current.get(symbol, 0.0) * 100
+        rows.append(
+            {
+                "symbol": symbol,
+                "name": security.get("name"),
+                **target,
+                "current_pct": current_pct,
+                "ideal_pct": ideal.get(symbol, 0.0) * 100,
+                "drift_pct": current_pct - target["target_pct"],
+            }
+        )
+    rows.sort(key=lambda row: (-abs(row["drift_pct"]), row["symbol"]))
+    return rows
@@ -0,0 +1,90 @@
+"""Trading volume screening for the universe.
+
+The daily `security:liquidity` job measures each active security's average
+daily volume (ADV, shares) and turnover (close x volume, converted to EUR)
+over the last `adv_lookback_days` stored price rows with a volume, and stores
+them on the security record (`adv_volume`, `adv_turnover_eur`,
+`adv_updated_at`).
+
+- `max_order_pct_adv` caps any single planner order at that percentage of
+  ADV, rounded down to whole lots. 0 disables the cap.
+- a security whose turnover falls below `min_adv_turnover_eur` is flagged
+  `illiquid` and not bought until it recovers; sells stay possible (within
+  the order cap). 0 disables the flag.
+
+Securities without volume history are neither capped nor flagged, and
+backtests ignore both because the stored values describe today's market.
+"""
+
+from __future__ import annotations
+
+import math
+from datetime import datetime, timezone
+from typing import Any
+
+from .liquidity import ToEur
+
+ADV_LOOKBACK_DAYS_KEY = "adv_lookback_days"
+MAX_ORDER_PCT_ADV_KEY = "max_order_pct_adv"
+MIN_ADV_TURNOVER_KEY = "min_adv_turnover_eur"
+
+
+def average_daily_volume(prices: list[dict], lookback_days: int) -> tuple[float, float] | None:
+    """(ADV in shares, average turnover in the security's currency); None without volume data.
+
+    Args:
+        prices: Price rows, newest first (as returned by `get_prices`)
+        lookback_days: How many of the newest rows with a volume to average
+    """
+    rows = [
+        row
+        for row in prices
+        if isinstance(row.get("volume"), int | float)
+        and row["volume"] > 0
+        and isinstance(row.get("close"), int | float)
+        and row["close"] > 0
+    ][: max(1, lookback_days)]
+    if not rows:
+        return None
+    volume = sum(float(row["volume"]) for row in rows) / len(rows)
+    turnover = sum(float(row["volume"]) * float(row["close"]) for row in rows) / len(rows)
+    return volume, turnover
+
+
+def max_order_quantity(adv_volume: Any, max_pct_adv: float, lot_size: int = 1) -> int | None:
+    """Largest order allowed by the ADV cap, in whole lots; None when uncapped."""
+    if max_pct_adv <= 0 or not isinstance(adv_volume, int | float) or not math.isfinite(adv_volume):
+        return None
+    if adv_volume <= 0:
+        return None
+    lot = max(1, int(lot_size or 1))
+    return int(adv_volume * max_pct_adv / 100 // lot) * lot
+
+
+def is_illiquid(adv_turnover_eur: float | None, min_turnover_eur: float) -> bool:
+    return adv_turnover_eur is not None and min_turnover_eur > 0 and adv_turnover_eur < min_turnover_eur
+
+
+async def update_security_volume(
+    db,
+    security: dict,
+    lookback_days: int,
+    min_turnover_eur: float,
+    to_eur: ToEur,
+) -> dict[str, Any] | None:
+    """Measure and store one security's ADV; returns the stored fields, or None without volume data."""
+    symbol = security["symbol"]
+    measured = average_daily_volume(await db.get_prices(symbol, days=lookback_days * 2), lookback_days)
+    if measured is None:
+        return None
+    volume, turnover = measured
+    currency = security.get("currency") or "EUR"
+    turnover_eur = turnover if currency == "EUR" else await to_eur(turnover, currency)
+    fields = {
+        "adv_volume": round(volume, 2),
+        "adv_turnover_eur": round(turnover_eur, 2),
+        "illiquid": 1 if is_illiquid(turnover_eur, min_turnover_eur) else 0,
+        "adv_updated_at": datetime.now(timezone.utc).isoformat(),
+    }
+    await db.upsert_security(symbol, **fields)
+    return fields
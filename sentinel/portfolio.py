@@ -13,9 +13,11 @@ from typing import Optional
 from sentinel.broker import Broker
 from sentinel.currency import Currency
 from sentinel.database import Database
+from sentinel.exposure import check_account_exposure
+from sentinel.identifiers import IdentifierService
 from sentinel.security import Security
 from sentinel.settings import Settings
-from sentinel.universe import BROKER_POSITION_UNIVERSE_SOURCE, import_security_from_broker
+from sentinel.universe import BROKER_POSITION_UNIVERSE_SOURCE, archive_closed_positions, import_security_from_broker
 from sentinel.utils.positions import PositionCalculator
 
 
@@ -41,14 +43,16 @@ class Portfolio:
     async def sync(self) -> "Portfolio":
         """Sync portfolio state from broker to database."""
         data = await self._broker.get_portfolio()
+        identifiers = IdentifierService(self._db)
 
         # Update positions and securities
+        broker_symbols = set()
         for pos in data.get("positions", []):
-            symbol = pos["symbol"]
+            symbol = await identifiers.canonical(pos["symbol"])
+            broker_symbols.add(symbol)
 
             # Ensure security exists in database
-            existing = await self._db.get_security(symbol)
-            if not existing or int(existing.get("active", 0) or 0) == 0:
+            if not await identifiers.is_active(symbol):
                 await import_security_from_broker(
                     self._db,
                     self._broker,
@@ -67,25 +71,35 @@ class Portfolio:
                 updated_at="now",
             )
 
-        # Zero out positions that no longer exist in the broker account
-        broker_symbols = {pos["symbol"] for pos in data.get("positions", [])}
-        db_positions = await self._db.get_all_positions()
+        # Zero out positions (long or short) that no longer exist in the broker account
+        db_positions = await self._db.get_all_positions() + await self._db.get_short_positions()
+        closed = []
         for pos in db_positions:
             if pos["symbol"] not in broker_symbols:
                 await self._db.upsert_position(pos["symbol"], quantity=0, updated_at="now")
+                closed.append(pos["symbol"])
+        # Securities kept only for these positions leave the universe now.
+        await archive_closed_positions(self._db, closed)
 
         # Store cash balances in memory and database
         self._cash = data.get("cash", {})
         await self._db.set_cash_balances(self._cash)
         return self
 
+    async def check_exposure(self) -> dict:
+        """Check the synced account for shorts and margin (see sentinel.exposure)."""
+        return await check_account_exposure(self._db, self._currency, self._settings)
+
     # -------------------------------------------------------------------------
     # Value Calculations
     # -------------------------------------------------------------------------
 
     async def total_value(self, currency: str = "EUR") -> float:
-        """Get total portfolio value in specified currency (default EUR)."""
-        positions = await self._db.get_all_positions()
+        """Get total portfolio value in specified currency (default EUR).
+
+        Short positions count at their negative value.
+        """
+        positions = await self._db.get_all_positions() + await self._db.get_short_positions()
 
         # Sum cash in all currencies, converted to EUR
         total = await self.total_cash_eur()
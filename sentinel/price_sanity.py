@@ -0,0 +1,275 @@
+"""
+Price sanity - keeps absurd broker prices out of price history and quotes.
+
+Brokers occasionally return a price shifted by a power of ten, a stale zero
+or a quote in the wrong currency. PriceSanityChecker runs over incoming
+quotes and the recent part of incoming daily OHLCV rows before they are
+written, and checks each value against the last known price:
+
+- zero_or_negative: close/price <= 0
+- decimal_shift: ~10x, 100x, ... or 1/10x, 1/100x, ... the reference
+- out_of_band: moved more than `price_sanity_max_move_pct` from the reference
+- currency_mismatch: the quote names a currency other than the security's
+
+Suspect values go into the `price_quarantine` table instead, where they can
+be accepted (written after all) or rejected through /api/prices/quarantine.
+A rejected value is not quarantined again. When a security collects
+`price_sanity_alert_count` quarantined values within ALERT_WINDOW_SECONDS a
+critical LED alert is raised.
+
+The scheduled price and quote sync jobs run the checker; a manual
+POST /api/securities/{symbol}/sync-prices writes what the broker returns.
+
+Only daily rows from RECHECK_DAYS before the last stored date onwards are
+checked; older history is rewritten as before and is left to
+sentinel.price_validator, which cleans series on read.
+
+Usage:
+    checker = PriceSanityChecker(db)
+    rows = await checker.check_prices("AAPL.US", rows)
+    quotes = await checker.check_quotes(quotes)
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import math
+import time
+from typing import Any
+
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+MAX_MOVE_KEY = "price_sanity_max_move_pct"
+ALERT_COUNT_KEY = "price_sanity_alert_count"
+DEFAULT_MAX_MOVE_PCT = 50.0
+DEFAULT_ALERT_COUNT = 3
+
+RECHECK_DAYS = 10
+ALERT_WINDOW_SECONDS = 86400
+# |log10(ratio)| this close to a whole number >= 1 counts as a decimal shift.
+DECIMAL_SHIFT_TOLERANCE = 0.05
+QUOTE_CURRENCY_FIELDS = ("currency", "curr", "x_curr")
+STATUSES = ("pending", "accepted", "rejected")
+
+
+def validate_max_move_pct(value: Any) -> float:
+    """Validate the daily band limit.
+
+    Raises:
+        ValueError: If it is not a number between 1 and 1000.
+    """
+    if isinstance(value, bool) or not isinstance(value, int | float) or not 1 <= value <= 1000:
+        raise ValueError(f"{MAX_MOVE_KEY} must be a number between 1 and 1000")
+    return float(value)
+
+
+def validate_alert_count(value: Any) -> int:
+    """Validate the anomaly count that raises an alert.
+
+    Raises:
+        ValueError: If it is not a whole number of at least 1.
+    """
+    if isinstance(value, bool) or not isinstance(value, int) or value < 1:
+        raise ValueError(f"{ALERT_COUNT_KEY} must be a whole number >= 1")
+    return value
+
+
+def anomaly(value: float | None, reference: float | None, max_move_pct: float) -> str | None:
+    """Why `value` is suspect next to `reference`, or None if it looks sane."""
+    if value is None:
+        return None
+    if value <= 0:
+        return "zero_or_negative"
+    if not reference or reference <= 0:
+        return None
+    shift = math.log10(value / reference)
+    if abs(shift) >= 1 - DECIMAL_SHIFT_TOLERANCE and abs(shift - round(shift)) <= DECIMAL_SHIFT_TOLERANCE:
+        return "decimal_shift"
+    if abs(value / reference - 1) * 100 > max_move_pct:
+        return "out_of_band"
+    return None
+
+
+def _number(value: Any) -> float | None:
+    if isinstance(value, bool) or value is None:
+        return None
+    try:
+        return float(value)
+    except (TypeError, ValueError):
+        return None
+
+
+def _latest_before(closes: dict[str, float], date: str) -> float | None:
+    earlier = [d for d in closes if d < date]
+    return closes[max(earlier)] if earlier else None
+
+
+def _quote_currency(quote: dict) -> str | None:
+    for field in QUOTE_CURRENCY_FIELDS:
+        value = quote.get(field)
+        if isinstance(value, str) and value.strip():
+            return value.strip().upper()
+    return None
+
+
+class PriceSanityChecker:
+    """Filters incoming prices and quotes, quarantining suspect values."""
+
+    def __init__(self, db, settings: Settings | None = None):
+        self._db = db
+        self._settings = settings
+        self._max_move_pct: float | None = None
+        self._alert_count = DEFAULT_ALERT_COUNT
+
+    async def _setting(self, key: str, default, validate):
+        # Read through the checker's own db so simulations use their copy.
+        value = await self._db.get_setting(key)
+        try:
+            return default if value is None else validate(value)
+        except ValueError:
+            return default
+
+    async def _limits(self) -> float:
+        if self._max_move_pct is None:
+            self._max_move_pct = await self._setting(MAX_MOVE_KEY, DEFAULT_MAX_MOVE_PCT, validate_max_move_pct)
+            self._alert_count = await self._setting(ALERT_COUNT_KEY, DEFAULT_ALERT_COUNT, validate_alert_count)
+        return self._max_move_pct
+
+    async def check_prices(self, symbol: str, rows: list[dict]) -> list[dict]:
+        """Daily rows safe to save; suspect rows in the recheck window are quarantined."""
+        if not rows:
+            return rows
+        max_move_pct = await self._limits()
+        stored = await self._db.get_prices(symbol, days=RECHECK_DAYS)
+        stored = stored if isinstance(stored, list) else []
+        stored_close = {row["date"]: row.get("close") for row in stored}
+
+        ordered = sorted(rows, key=lambda row: row["date"])
+        if stored:
+            cutoff = min(stored_close)
+        else:
+            cutoff = ordered[max(0, len(ordered) - RECHECK_DAYS)]["date"]
+
+        # Known good closes by date: stored rows, then incoming rows as they pass.
+        known = {date: close for date, close in ((d, _number(c)) for d, c in stored_close.items()) if close}
+        accepted: list[dict] = []
+        suspect: list[tuple[dict, float | None, str]] = []
+        for row in ordered:
+            close = _number(row.get("close"))
+            if row["date"] >= cutoff:
+                reference = known.get(row["date"]) or _latest_before(known, row["date"])
+                reason = anomaly(close, reference, max_move_pct)
+                if reason:
+                    suspect.append((row, reference, reason))
+                    continue
+            accepted.append(row)
+            if close and close > 0:
+                known[row["date"]] = close
+
+        added = 0
+        for row, reference, reason in suspect:
+            close = _number(row.get("close"))
+            added += await self._quarantine(symbol, "price", row.get("date"), close, reference, reason, row)
+        await self._alert_if_repeated(symbol, added)
+        return accepted
+
+    async def check_quotes(self, quotes: dict[str, dict]) -> dict[str, dict]:
+        """Quotes safe to store; suspect quotes are quarantined."""
+        if not quotes:
+            return quotes
+        max_move_pct = await self._limits()
+        securities = await self._db.get_all_securities(active_only=False)
+        securities = {s["symbol"]: s for s in securities} if isinstance(securities, list) else {}
+        closes = await self._db.get_prices_bulk(list(quotes), days=1)
+        closes = closes if isinstance(closes, dict) else {}
+
+        accepted: dict[str, dict] = {}
+        for symbol, quote in quotes.items():
+            security = securities.get(symbol, {})
+            price = _number(quote.get("price"))
+            reference = self._last_quote_price(security) or _number((closes.get(symbol) or [{}])[0].get("close"))
+            reason = anomaly(price, reference, max_move_pct)
+            currency = _quote_currency(quote)
+            if not reason and currency and security.get("currency") and currency != security["currency"].upper():
+                reason = "currency_mismatch"
+            if reason:
+                await self._alert_if_repeated(
+                    symbol, await self._quarantine(symbol, "quote", None, price, reference, reason, quote)
+                )
+                continue
+            accepted[symbol] = quote
+        return accepted
+
+    @staticmethod
+    def _last_quote_price(security: dict) -> float | None:
+        raw = security.get("quote_data")
+        if not raw:
+            return None
+        try:
+            quote = json.loads(raw) if isinstance(raw, str) else raw
+        except (json.JSONDecodeError, TypeError):
+            return None
+        price = _number(quote.get("price")) if isinstance(quote, dict) else None
+        return price if price and price > 0 else None
+
+    async def _quarantine(
+        self,
+        symbol: str,
+        kind: str,
+        date: str | None,
+        value: float | None,
+        reference: float | None,
+        reason: str,
+        payload: dict,
+    ) -> int:
+        """Store a suspect value; returns 1 if it was new, 0 if already quarantined or rejected."""
+        added = await self._db.add_price_quarantine(
+            symbol=symbol,
+            kind=kind,
+            date=date,
+            value=value,
+            reference=reference,
+            reason=reason,
+            payload=json.dumps(payload, default=str),
+        )
+        if added:
+            logger.warning(
+                f"Quarantined {kind} for {symbol}{f' on {date}' if date else ''}: {value} vs {reference} ({reason})"
+            )
+        return 1 if added else 0
+
+    async def _alert_if_repeated(self, symbol: str, added: int) -> None:
+        """Alert once when this batch takes `symbol` to the alert count within the window."""
+        if not added:
+            return
+        count = await self._db.count_price_quarantine(symbol, since=int(time.time()) - ALERT_WINDOW_SECONDS)
+        if not isinstance(count, int) or not count - added < self._alert_count <= count:
+            return
+        logger.error(f"{symbol} had {count} suspect prices in the last 24h; review /api/prices/quarantine")
+        try:
+            from sentinel.led.alerts import ALERT_CRITICAL, AlertManager
+
+            await AlertManager(self._settings or Settings()).trigger(ALERT_CRITICAL)
+        except Exception as e:
+            logger.warning(f"Failed to queue price anomaly alert: {e}")
+
+
+async def resolve_quarantine(db, entry_id: int, accept: bool) -> dict | None:
+    """Accept (write the value after all) or reject a pending entry.
+
+    Returns the entry, or None if there is no pending entry with that id.
+    """
+    entry = await db.get_price_quarantine_entry(entry_id)
+    if entry is None or entry["status"] != "pending":
+        return None
+    if accept:
+        payload = json.loads(entry["payload"])
+        if entry["kind"] == "price":
+            await db.save_prices(entry["symbol"], [payload])
+        else:
+            await db.update_quotes_bulk({entry["symbol"]: payload})
+    await db.resolve_price_quarantine(entry_id, "accepted" if accept else "rejected")
+    return await db.get_price_quarantine_entry(entry_id)
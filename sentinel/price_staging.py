@@ -0,0 +1,166 @@
+"""
+Price staging - write-ahead validation of fetched daily candles.
+
+The price sync writes every fetched candle into `price_staging` with its
+validation verdict before anything touches `prices`:
+
+- invalid: no date, or a close that is missing or <= 0
+- duplicate: the same date more than once with different values
+  (identical copies are collapsed into one)
+- ohlc_inconsistent: high below low, or open/close outside [low, high]
+- spike: an isolated jump past price_validator's spike/crash limits that the
+  next candle reverses
+
+Only the remaining rows are merged into `prices`; merged rows are then
+cleared from staging, while rejected rows stay there as the sync's report
+(`price_sync_reports`, see /api/prices/sync-reports). Date gaps longer than
+GAP_DAYS are reported but nothing is rejected for them. Staged rows left by
+a sync that died before merging are discarded when the next sync begins.
+
+Usage:
+    staging = PriceStaging(db)
+    await staging.begin()
+    rows = await staging.stage("AAPL.US", fetched)
+    await staging.merge("AAPL.US", rows)
+    report = await staging.finish()
+"""
+
+from __future__ import annotations
+
+import logging
+from datetime import date as date_type
+
+from sentinel.price_validator import MAX_PRICE_CHANGE_PCT, MIN_PRICE_CHANGE_PCT
+
+logger = logging.getLogger(__name__)
+
+GAP_DAYS = 10
+KEEP_REPORTS = 30
+CANDLE_FIELDS = ("open", "high", "low", "close")
+
+
+def _number(value) -> float | None:
+    if isinstance(value, bool) or value is None:
+        return None
+    try:
+        return float(value)
+    except (TypeError, ValueError):
+        return None
+
+
+def _change_pct(value: float, reference: float) -> float:
+    return (value - reference) / reference * 100.0
+
+
+def _extreme(change_pct: float) -> bool:
+    return change_pct > MAX_PRICE_CHANGE_PCT or change_pct < MIN_PRICE_CHANGE_PCT
+
+
+def _ohlc_consistent(row: dict, close: float) -> bool:
+    high, low = _number(row.get("high")), _number(row.get("low"))
+    if high is not None and low is not None and high < low:
+        return False
+    for value in (_number(row.get("open")), close):
+        if value is None:
+            continue
+        if (high is not None and value > high) or (low is not None and value < low):
+            return False
+    return True
+
+
+def _gaps(dates: list[str]) -> list[dict]:
+    gaps = []
+    for earlier, later in zip(dates, dates[1:]):
+        try:
+            days = (date_type.fromisoformat(later) - date_type.fromisoformat(earlier)).days
+        except ValueError:
+            continue
+        if days > GAP_DAYS:
+            gaps.append({"from": earlier, "to": later, "days": days})
+    return gaps
+
+
+def validate_candles(rows: list[dict]) -> tuple[list[dict], list[tuple[dict, str]], list[dict]]:
+    """Split candles into (accepted, [(row, reason)], gaps); accepted rows are sorted by date."""
+    rejected: list[tuple[dict, str]] = []
+    by_date: dict[str, list[dict]] = {}
+    for row in rows:
+        close = _number(row.get("close"))
+        if not isinstance(row.get("date"), str) or not row["date"] or close is None or close <= 0:
+            rejected.append((row, "invalid"))
+        elif not _ohlc_consistent(row, close):
+            rejected.append((row, "ohlc_inconsistent"))
+        else:
+            by_date.setdefault(row["date"], []).append(row)
+
+    candidates: list[dict] = []
+    for day in sorted(by_date):
+        copies = by_date[day]
+        if len({tuple(_number(row.get(field)) for field in CANDLE_FIELDS) for row in copies}) > 1:
+            rejected.extend((row, "duplicate") for row in copies)
+        else:
+            candidates.append(copies[0])
+
+    accepted: list[dict] = []
+    for index, row in enumerate(candidates):
+        close = float(row["close"])
+        if accepted and index + 1 < len(candidates):
+            jump = _change_pct(close, float(accepted[-1]["close"]))
+            back = _change_pct(float(candidates[index + 1]["close"]), close)
+            if _extreme(jump) and _extreme(back) and (jump > 0) != (back > 0):
+                rejected.append((row, "spike"))
+                continue
+        accepted.append(row)
+    return accepted, rejected, _gaps([row["date"] for row in accepted])
+
+
+class PriceStaging:
+    """One price sync's staging run: begin, stage/merge per symbol, finish."""
+
+    def __init__(self, db):
+        self._db = db
+        self.sync_id: int | None = None
+        self._counts = {"symbols": 0, "fetched": 0, "rejected": 0, "quarantined": 0, "merged": 0}
+        self._gaps: dict[str, list[dict]] = {}
+        self._staged: dict[str, int] = {}
+
+    async def begin(self) -> int:
+        """Drop rows an interrupted sync left staged and open a new report."""
+        discarded = await self._db.discard_staged_prices()
+        if isinstance(discarded, int) and discarded:
+            logger.warning(f"Discarded {discarded} staged price rows from an interrupted sync")
+        self.sync_id = await self._db.create_price_sync_report()
+        return self.sync_id
+
+    async def stage(self, symbol: str, rows: list[dict]) -> list[dict]:
+        """Stage a symbol's fetched candles; returns the ones that passed validation."""
+        accepted, rejected, gaps = validate_candles(rows)
+        verdicts = [(row, None) for row in accepted] + [(row, reason) for row, reason in rejected]
+        await self._db.stage_prices(self.sync_id, symbol, verdicts)
+        self._counts["symbols"] += 1
+        self._counts["fetched"] += len(rows)
+        self._counts["rejected"] += len(rejected)
+        self._staged[symbol] = len(accepted)
+        if gaps:
+            self._gaps[symbol] = gaps
+        if rejected:
+            reasons = sorted({reason for _, reason in rejected})
+            logger.warning(f"Rejected {len(rejected)}/{len(rows)} fetched candles for {symbol}: {', '.join(reasons)}")
+        return accepted
+
+    async def merge(self, symbol: str, rows: list[dict]) -> None:
+        """Write validated rows to `prices` and clear the symbol's staged rows.
+
+        `rows` may be fewer than `stage` returned when the sanity check
+        quarantined some of them.
+        """
+        if rows:
+            await self._db.save_prices(symbol, rows)
+        await self._db.clear_staged_prices(self.sync_id, symbol)
+        self._counts["quarantined"] += max(0, self._staged.get(symbol, 0) - len(rows))
+        self._counts["merged"] += len(rows)
+
+    async def finish(self) -> dict | None:
+        """Close the report and prune old ones; returns the report."""
+        await self._db.finish_price_sync_report(self.sync_id, self._counts, self._gaps, keep=KEEP_REPORTS)
+        return await self._db.get_price_sync_report(self.sync_id)
@@ -0,0 +1,416 @@
+"""
+Monte Carlo projection of the whole portfolio years ahead.
+
+Return and risk come from the current allocation: each position's weight in
+the portfolio, and the mean and covariance of its daily log returns over the
+last `LOOKBACK_DAYS` price rows. Cash earns nothing. The weighted mean and
+the variance `w' Σ w` give one monthly log-return distribution, as if the
+portfolio were held at today's weights throughout.
+
+Each simulated path grows the current value month by month with returns
+drawn from that distribution and adds the monthly deposit (by default the
+rolling six-month average net deposit). The result is percentile bands per
+year and, with a target, the share of paths that end at or above it.
+
+Positions with too little price history are left out of the estimate (see
+below); the rest of the invested weight stands in for them and
+`coverage_pct` says how much of the invested value was estimated. Sampling
+is seeded, so the same inputs give the same bands.
+
+The covariance comes from the `covariance_estimator` setting (see
+sentinel.covariance); build_covariance_report() compares the estimators on
+the live portfolio. The `factor` estimator takes its covariance from the
+factor model (sentinel.factors) and falls back to `sample` while a position
+has no stored exposures.
+
+A position with less than a year of returns (or less than the longest
+history, when no position has a year) is handled by `short_history_policy`
+instead of counting its missing days as days without a move:
+
+  - `exclude`: left out, like positions under `MIN_HISTORY_DAYS`
+  - `shrink` (default): its own returns are blended with the mean return of
+    full-history positions in the same industry (of all of them when the
+    industry has none), its own weight growing with its history; days before
+    its first price take the peers' return. Under `MIN_HISTORY_DAYS` it is
+    left out.
+  - `proxy`: its own returns as they are, and the peers' return on the days
+    before its first price, however short its history
+
+`short_history` in the results says per position which policy was applied.
+"""
+
+from __future__ import annotations
+
+import inspect
+import math
+from typing import Any
+
+import numpy as np
+
+from sentinel.covariance import (
+    DEFAULT_ESTIMATOR,
+    covariance_diagnostics,
+    estimate_covariance,
+    load_covariance_estimator,
+)
+
+LOOKBACK_DAYS = 756
+MIN_HISTORY_DAYS = 60
+FULL_HISTORY_DAYS = 252
+TRADING_DAYS_PER_MONTH = 21
+DEFAULT_YEARS = 20
+MAX_YEARS = 50
+DEFAULT_PATHS = 2000
+MAX_PATHS = 10_000
+PERCENTILES = (10, 25, 50, 75, 90)
+
+SHORT_HISTORY_KEY = "short_history_policy"
+SHORT_HISTORY_POLICIES = ("exclude", "shrink", "proxy")
+DEFAULT_SHORT_HISTORY_POLICY = "shrink"
+
+
+def _daily_log_returns(rows: list[dict]) -> dict[str, float]:
+    """Log returns keyed by ISO date, from price rows in any order."""
+    ordered = sorted((r for r in rows if (r.get("close") or 0) > 0), key=lambda r: r["date"])
+    return {
+        cur["date"]: math.log(float(cur["close"]) / float(prev["close"]))
+        for prev, cur in zip(ordered, ordered[1:], strict=False)
+    }
+
+
+def validate_short_history_policy(value: Any) -> str:
+    """Validate `short_history_policy`.
+
+    Raises:
+        ValueError: If it is not one of SHORT_HISTORY_POLICIES.
+    """
+    if value not in SHORT_HISTORY_POLICIES:
+        raise ValueError(f"{SHORT_HISTORY_KEY} must be one of {', '.join(SHORT_HISTORY_POLICIES)}")
+    return value
+
+
+async def load_short_history_policy(db) -> str:
+    """The configured policy; the default when the setting is missing or malformed."""
+    getter = getattr(db, "get_setting", None)
+    if not callable(getter):
+        return DEFAULT_SHORT_HISTORY_POLICY
+    value = getter(SHORT_HISTORY_KEY, DEFAULT_SHORT_HISTORY_POLICY)
+    if inspect.isawaitable(value):
+        value = await value
+    try:
+        return validate_short_history_policy(value)
+    except ValueError:
+        return DEFAULT_SHORT_HISTORY_POLICY
+
+
+async def load_industries(db) -> dict[str, str]:
+    """Industry per symbol, for short-history proxies; empty when the securities cannot be read."""
+    getter = getattr(db, "get_all_securities", None)
+    if not callable(getter):
+        return {}
+    securities = getter(active_only=False)
+    if inspect.isawaitable(securities):
+        securities = await securities
+    if not isinstance(securities, list):
+        return {}
+    return {sec["symbol"]: (sec.get("industry") or "").strip() for sec in securities if isinstance(sec, dict)}
+
+
+def _peer_average(
+    symbol: str,
+    series: dict[str, dict[str, float]],
+    dates: list[str],
+    industries: dict[str, str],
+) -> tuple[list[float], str]:
+    """Mean daily return of the full-history positions in `symbol`'s industry, or of all of them."""
+    industry = industries.get(symbol)
+    peers = [s for s in series if industry and industries.get(s) == industry]
+    source = "industry" if peers else "portfolio"
+    peers = peers or list(series)
+    return [sum(series[p].get(d, 0.0) for p in peers) / len(peers) for d in dates], source
+
+
+def _aligned_returns(
+    weights: dict[str, float],
+    prices_by_symbol: dict[str, list[dict]],
+    policy: str = DEFAULT_SHORT_HISTORY_POLICY,
+    industries: dict[str, str] | None = None,
+) -> tuple[list[str], list[list[float]], list[float], float, dict[str, dict[str, Any]]]:
+    """Daily log-return rows over the union of trading days, short histories handled by `policy`.
+
+    Returns:
+        (symbols, rows, weights, coverage, short_history); weights are rescaled
+        so the estimated positions carry the whole invested weight, and
+        short_history says per short or excluded position how it was handled
+    """
+    invested = sum(w for w in weights.values() if w > 0)
+    returns = {s: _daily_log_returns(prices_by_symbol.get(s) or []) for s, w in weights.items() if w > 0}
+    if invested <= 0 or not returns:
+        return [], [], [], 0.0, {}
+
+    # A position is short next to a year of history, or next to the longest
+    # history when none has a year.
+    full_days = max(MIN_HISTORY_DAYS, min(FULL_HISTORY_DAYS, max(len(r) for r in returns.values())))
+    series = {s: r for s, r in returns.items() if len(r) >= full_days}
+    short_history: dict[str, dict[str, Any]] = {}
+    short = {}
+    for symbol, own in returns.items():
+        if symbol in series:
+            continue
+        if not series or policy == "exclude" or (policy == "shrink" and len(own) < MIN_HISTORY_DAYS):
+            short_history[symbol] = {"days": len(own), "policy": "excluded"}
+        else:
+            short[symbol] = own
+    if not series:
+        return [], [], [], 0.0, short_history
+
+    # Markets close on different days; a missing day counts as no move.
+    dates = sorted(set().union(*series.values()))[-LOOKBACK_DAYS:]
+    columns = {s: [r.get(d, 0.0) for d in dates] for s, r in series.items()}
+    for symbol, own in short.items():
+        peer, source = _peer_average(symbol, series, dates, industries or {})
+        if policy == "proxy":
+            # Own returns where there are any, the peers' before.
+            columns[symbol] = [own.get(d, p) for d, p in zip(dates, peer, strict=True)]
+            short_history[symbol] = {"days": len(own), "policy": "proxied", "proxy": source}
+        else:
+            own_weight = len(own) / full_days
+            columns[symbol] = [
+                own_weight * own[d] + (1 - own_weight) * p if d in own else p
+                for d, p in zip(dates, peer, strict=True)
+            ]
+            short_history[symbol] = {
+                "days": len(own),
+                "policy": "shrunk",
+                "proxy": source,
+                "own_weight": round(own_weight, 3),
+            }
+
+    symbols = sorted(columns)
+    covered = sum(weights[s] for s in symbols)
+    rows = [[columns[s][i] for s in symbols] for i in range(len(dates))]
+    return symbols, rows, [weights[s] * invested / covered for s in symbols], covered / invested, short_history
+
+
+def portfolio_estimates(
+    weights: dict[str, float],
+    prices_by_symbol: dict[str, list[dict]],
+    estimator: str = DEFAULT_ESTIMATOR,
+    policy: str = DEFAULT_SHORT_HISTORY_POLICY,
+    industries: dict[str, str] | None = None,
+    factor_model=None,
+) -> dict[str, Any]:
+    """Monthly log-return mean and volatility of the allocation.
+
+    Args:
+        weights: Fraction of the whole portfolio per symbol; the rest is cash
+        prices_by_symbol: Price rows per symbol
+        estimator: Covariance estimator (see sentinel.covariance)
+        policy: How positions with short price history are estimated
+        industries: Industry per symbol, for short-history proxies
+        factor_model: A sentinel.factors.FactorModel, for the `factor` estimator
+
+    Returns:
+        {"monthly_mean", "monthly_volatility", "coverage", "short_history",
+        "estimator"} where coverage is the share of invested weight in the
+        estimate and estimator the one actually used
+    """
+    symbols, rows, scaled, coverage, short_history = _aligned_returns(weights, prices_by_symbol, policy, industries)
+    if not symbols:
+        return {
+            "monthly_mean": 0.0,
+            "monthly_volatility": 0.0,
+            "coverage": 0.0,
+            "short_history": short_history,
+            "estimator": estimator,
+        }
+
+    matrix = np.asarray(rows, dtype=float)
+    w = np.asarray(scaled, dtype=float)
+    mean = matrix.mean(axis=0)
+    factor_cov = factor_model.covariance(symbols) if estimator == "factor" and factor_model else None
+    if estimator == "factor" and factor_cov is None:
+        estimator = "sample"
+    cov = estimate_covariance(matrix, estimator, factor_cov)
+    return {
+        "monthly_mean": float(w @ mean) * TRADING_DAYS_PER_MONTH,
+        "monthly_volatility": math.sqrt(max(0.0, float(w @ cov @ w)) * TRADING_DAYS_PER_MONTH),
+        "coverage": coverage,
+        "short_history": short_history,
+        "estimator": estimator,
+    }
+
+
+def historical_cvar(
+    weights: dict[str, float],
+    prices_by_symbol: dict[str, list[dict]],
+    level: float = 0.95,
+    policy: str = DEFAULT_SHORT_HISTORY_POLICY,
+    industries: dict[str, str] | None = None,
+) -> float | None:
+    """Mean daily simple return of the allocation on its worst (1 - level) days; None without history."""
+    _symbols, rows, scaled, _coverage, _short = _aligned_returns(weights, prices_by_symbol, policy, industries)
+    if not rows:
+        return None
+    daily = sorted(math.expm1(sum(w * r for w, r in zip(scaled, row, strict=True))) for row in rows)
+    tail = daily[: max(1, math.floor(len(daily) * (1 - level)))]
+    return sum(tail) / len(tail)
+
+
+def simulate_projection(
+    start_value: float,
+    monthly_deposit: float,
+    years: int,
+    monthly_mean: float,
+    monthly_volatility: float,
+    *,
+    paths: int = DEFAULT_PATHS,
+    target: float | None = None,
+    seed: int = 0,
+) -> dict[str, Any]:
+    """Simulate `paths` monthly paths over `years`; amounts in EUR.
+
+    Returns:
+        {"bands": [{"year", "p10", "p25", "p50", "p75", "p90"}], "probability"}
+        with one band per year, year 0 being today; probability is None
+        without a target
+    """
+    rng = np.random.default_rng(seed)
+    values = np.full(paths, max(0.0, float(start_value)))
+    bands = [_band(0, values)]
+    for month in range(1, years * 12 + 1):
+        shocks = rng.normal(monthly_mean, monthly_volatility, size=paths)
+        values = np.maximum(0.0, values * np.exp(shocks) + monthly_deposit)
+        if month % 12 == 0:
+            bands.append(_band(month // 12, values))
+    probability = None if target is None else round(float((values >= target).mean()), 4)
+    return {"bands": bands, "probability": probability}
+
+
+def _band(year: int, values) -> dict[str, Any]:
+    quantiles = np.quantile(values, [p / 100 for p in PERCENTILES])
+    return {"year": year, **{f"p{p}": round(float(q), 2) for p, q in zip(PERCENTILES, quantiles, strict=True)}}
+
+
+def validate_projection_params(years: int, paths: int, target_eur: float | None, monthly_deposit_eur: float | None):
+    """Raises ValueError when a projection parameter is out of range."""
+    if not 1 <= years <= MAX_YEARS:
+        raise ValueError(f"years must be between 1 and {MAX_YEARS}")
+    if not 100 <= paths <= MAX_PATHS:
+        raise ValueError(f"paths must be between 100 and {MAX_PATHS}")
+    if target_eur is not None and (not math.isfinite(target_eur) or target_eur <= 0):
+        raise ValueError("target_eur must be a positive number")
+    if monthly_deposit_eur is not None and not math.isfinite(monthly_deposit_eur):
+        raise ValueError("monthly_deposit_eur must be a number")
+
+
+async def _live_weights(db, currency) -> tuple[float, dict[str, float]]:
+    """Total portfolio value in EUR and each position's share of it."""
+    from sentinel.portfolio import Portfolio
+    from sentinel.utils.positions import PositionCalculator
+
+    total_value = await Portfolio(db=db, currency=currency).total_value()
+    pos_calc = PositionCalculator(currency_converter=currency)
+    values: dict[str, float] = {}
+    for pos in await db.get_all_positions():
+        if pos.get("quantity") and pos.get("current_price"):
+            values[pos["symbol"]] = await pos_calc.calculate_value_eur(
+                pos["quantity"], pos["current_price"], pos.get("currency", "EUR")
+            )
+    weights = {s: v / total_value for s, v in values.items() if v > 0} if total_value > 0 else {}
+    return total_value, weights
+
+
+async def build_projection(
+    db,
+    currency,
+    *,
+    years: int = DEFAULT_YEARS,
+    paths: int = DEFAULT_PATHS,
+    target_eur: float | None = None,
+    monthly_deposit_eur: float | None = None,
+) -> dict[str, Any]:
+    """Project the live portfolio; see the module docstring."""
+    from sentinel.planner.deposit_history import DepositHistoryHelper
+
+    total_value, weights = await _live_weights(db, currency)
+    prices = {s: await db.get_prices(s, days=LOOKBACK_DAYS + 1) for s in weights}
+    from sentinel.factors import load_factor_model
+
+    estimator = await load_covariance_estimator(db)
+    factor_model = await load_factor_model(db) if estimator == "factor" else None
+    policy = await load_short_history_policy(db)
+    estimates = portfolio_estimates(weights, prices, estimator, policy, await load_industries(db), factor_model)
+
+    if monthly_deposit_eur is None:
+        monthly_deposit_eur = await DepositHistoryHelper(db, currency).get_rolling_6m_avg_net_deposit()
+    simulation = simulate_projection(
+        total_value,
+        monthly_deposit_eur,
+        years,
+        estimates["monthly_mean"],
+        estimates["monthly_volatility"],
+        paths=paths,
+        target=target_eur,
+    )
+    return {
+        "years": years,
+        "paths": paths,
+        "start_value_eur": round(total_value, 2),
+        "monthly_deposit_eur": round(float(monthly_deposit_eur), 2),
+        "expected_annual_return_pct": round(math.expm1(estimates["monthly_mean"] * 12) * 100, 2),
+        "annual_volatility_pct": round(estimates["monthly_volatility"] * math.sqrt(12) * 100, 2),
+        "invested_pct": round(sum(weights.values()) * 100, 2),
+        "coverage_pct": round(estimates["coverage"] * 100, 2),
+        "covariance_estimator": estimates["estimator"],
+        "short_history_policy": policy,
+        "short_history": estimates["short_history"],
+        "target_eur": target_eur,
+        "probability": simulation["probability"],
+        "bands": simulation["bands"],
+    }
+
+
+async def build_covariance_report(db, currency) -> dict[str, Any]:
+    """Every covariance estimator fitted to the live portfolio's returns, for comparison.
+
+    Returns:
+        {"estimator", "positions", "days", "coverage_pct", "short_history_policy",
+        "short_history", "estimators": {name: {"condition_number",
+        "annual_volatility_pct", "shrinkage"}}}; `factor` is among the
+        estimators when every position has factor exposures
+    """
+    from sentinel.factors import load_factor_model
+
+    _total_value, weights = await _live_weights(db, currency)
+    prices = {s: await db.get_prices(s, days=LOOKBACK_DAYS + 1) for s in weights}
+    policy = await load_short_history_policy(db)
+    symbols, rows, scaled, coverage, short_history = _aligned_returns(
+        weights, prices, policy, await load_industries(db)
+    )
+    estimators = {}
+    if symbols:
+        factor_model = await load_factor_model(db)
+        diagnostics = covariance_diagnostics(
+            np.asarray(rows, dtype=float),
+            np.asarray(scaled, dtype=float),
+            factor_model.covariance(symbols) if factor_model else None,
+        )
+        annualize = math.sqrt(TRADING_DAYS_PER_MONTH * 12) * 100
+        estimators = {
+            name: {
+                "condition_number": result["condition_number"],
+                "annual_volatility_pct": round(result["daily_volatility"] * annualize, 2),
+                "shrinkage": result["shrinkage"],
+            }
+            for name, result in diagnostics.items()
+        }
+    return {
+        "estimator": await load_covariance_estimator(db),
+        "positions": len(symbols),
+        "days": len(rows),
+        "coverage_pct": round(coverage * 100, 2),
+        "short_history_policy": policy,
+        "short_history": short_history,
+        "estimators": estimators,
+    }
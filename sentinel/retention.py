@@ -0,0 +1,220 @@
+"""
+Data retention - one policy per table, applied by a single job.
+
+`TABLES` lists the tables a policy may govern and how their rows age. A
+policy keeps rows for `keep_days`, keeps the newest `keep_rows`, or both (a
+row goes once it breaks either limit; null means no limit). `action` is
+`delete`, `archive` or `export`:
+- `archive` writes the rows to a compressed CSV file in `data/archive`
+  (see sentinel.archive) and deletes them only once the file is written.
+- `export` writes the rows that aged past `keep_days` since the last run
+  and deletes nothing. It is the only action for records.
+
+The `retention_policies` setting overrides `DEFAULT_POLICIES` per table and
+per field. The `system:retention` job applies every policy; its result,
+including the pages SQLite freed, is kept in planner state under
+`RETENTION_STATE_KEY`. Freed pages are reused by later writes; the file
+itself only shrinks on VACUUM.
+
+Records (cash flows, prices) are re-synced from the broker, so deleting them
+would only bring them back; they can be exported for cold storage but are
+never deleted. Trades and dividends are not governed. Features with their
+own window (idempotency keys, the news sentiment window, the planner
+snapshot cap) still prune as they write; a policy can only make those tables
+shorter.
+
+Usage:
+    result = await run_retention(db)
+"""
+
+from __future__ import annotations
+
+import logging
+import time
+from dataclasses import dataclass
+from typing import Any
+
+from sentinel.archive import upload_archive, write_archive
+
+logger = logging.getLogger(__name__)
+
+RETENTION_POLICIES_KEY = "retention_policies"
+RETENTION_STATE_KEY = "retention:last_run"
+EXPORTED_STATE_PREFIX = "retention:exported:"
+ACTIONS = ("delete", "archive", "export")
+
+
+@dataclass(frozen=True)
+class RetentionTable:
+    time_column: str  # unix seconds the age is measured from (may be an expression)
+    order_column: str  # newest first for keep_rows
+    condition: str | None = None  # rows a policy may touch at all
+    description: str = ""
+    records: bool = False  # exported only, never deleted
+
+
+_DATE_SECONDS = "CAST(strftime('%s', date) AS INTEGER)"
+
+TABLES: dict[str, RetentionTable] = {
+    "job_history": RetentionTable("executed_at", "executed_at", description="Job runs"),
+    "price_quarantine": RetentionTable(
+        "resolved_at", "resolved_at", "status != 'pending'", "Resolved quarantined prices and quotes"
+    ),
+    "concentration_breaches": RetentionTable(
+        "resolved_at", "resolved_at", "resolved_at IS NOT NULL", "Resolved concentration breaches"
+    ),
+    "planner_snapshots": RetentionTable("created_at", "id", description="Planner cycle snapshots"),
+    "valuation_snapshots": RetentionTable("ts", "ts", description="Daily-thinned live valuations"),
+    "news_headlines": RetentionTable("published_at", "published_at", description="News headlines"),
+    "cash_flows": RetentionTable(_DATE_SECONDS, "date", description="Cash flows (export only)", records=True),
+    "prices": RetentionTable(_DATE_SECONDS, "date", description="Daily prices (export only)", records=True),
+}
+
+DEFAULT_POLICIES: dict[str, dict[str, Any]] = {
+    "job_history": {"keep_days": 90, "keep_rows": None, "action": "archive"},
+    "price_quarantine": {"keep_days": 180, "keep_rows": None, "action": "archive"},
+    "concentration_breaches": {"keep_days": 365, "keep_rows": None, "action": "archive"},
+    "planner_snapshots": {"keep_days": None, "keep_rows": 500, "action": "delete"},
+    "valuation_snapshots": {"keep_days": None, "keep_rows": None, "action": "archive"},
+    "news_headlines": {"keep_days": None, "keep_rows": None, "action": "delete"},
+    "cash_flows": {"keep_days": None, "keep_rows": None, "action": "export"},
+    "prices": {"keep_days": None, "keep_rows": None, "action": "export"},
+}
+
+
+def _limit(value: Any, name: str) -> int | None:
+    if value is None:
+        return None
+    if isinstance(value, bool) or not isinstance(value, int) or value < 1:
+        raise ValueError(f"{name} must be a whole number of at least 1, or null")
+    return value
+
+
+def validate_retention_policies(raw: Any) -> dict[str, dict[str, Any]]:
+    """Validate a `retention_policies` value: {table: {"keep_days"?, "keep_rows"?, "action"?}}.
+
+    Raises:
+        ValueError: If a table is not governed or a field is malformed.
+    """
+    if not isinstance(raw, dict):
+        raise ValueError(f"{RETENTION_POLICIES_KEY} must be an object keyed by table")
+    policies: dict[str, dict[str, Any]] = {}
+    for table, policy in raw.items():
+        if table not in TABLES:
+            raise ValueError(f"{RETENTION_POLICIES_KEY}: unknown table '{table}'; one of {', '.join(TABLES)}")
+        if not isinstance(policy, dict) or set(policy) - {"keep_days", "keep_rows", "action"}:
+            raise ValueError(f"{RETENTION_POLICIES_KEY}.{table} must be an object with keep_days, keep_rows, action")
+        clean: dict[str, Any] = {}
+        for key in ("keep_days", "keep_rows"):
+            if key in policy:
+                clean[key] = _limit(policy[key], f"{RETENTION_POLICIES_KEY}.{table}.{key}")
+        if "action" in policy:
+            if policy["action"] not in ACTIONS:
+                raise ValueError(f"{RETENTION_POLICIES_KEY}.{table}.action must be one of {', '.join(ACTIONS)}")
+            clean["action"] = policy["action"]
+        merged = {**DEFAULT_POLICIES[table], **clean}
+        if TABLES[table].records and merged["action"] != "export":
+            raise ValueError(f"{RETENTION_POLICIES_KEY}.{table}: records can only be exported")
+        if merged["action"] == "export" and merged["keep_rows"] is not None:
+            raise ValueError(f"{RETENTION_POLICIES_KEY}.{table}: export works by keep_days only")
+        policies[table] = clean
+    return policies
+
+
+def effective_policies(overrides: dict[str, dict[str, Any]] | None) -> dict[str, dict[str, Any]]:
+    """Defaults with per-field overrides applied, for every governed table."""
+    overrides = overrides or {}
+    return {table: {**DEFAULT_POLICIES[table], **overrides.get(table, {})} for table in TABLES}
+
+
+async def load_policies(db) -> dict[str, dict[str, Any]]:
+    """Effective policies; a malformed setting falls back to the defaults."""
+    raw = await db.get_setting(RETENTION_POLICIES_KEY)
+    try:
+        overrides = validate_retention_policies(raw) if raw is not None else {}
+    except ValueError as e:
+        logger.warning(f"Ignoring {RETENTION_POLICIES_KEY}: {e}")
+        overrides = {}
+    return effective_policies(overrides)
+
+
+def _where(table: str, policy: dict[str, Any], now: int, since: int | None) -> tuple[str | None, list]:
+    """SQL condition for the rows a policy acts on now, or None when it has no limit."""
+    spec = TABLES[table]
+    limits, params = [], []
+    if policy["keep_days"] is not None:
+        limits.append(f"{spec.time_column} < ?")
+        params.append(now - policy["keep_days"] * 86400)
+    if policy["keep_rows"] is not None:
+        limits.append(f"rowid NOT IN (SELECT rowid FROM {table} ORDER BY {spec.order_column} DESC LIMIT ?)")
+        params.append(policy["keep_rows"])
+    if not limits:
+        return None, []
+    where = f"({' OR '.join(limits)})"
+    if since is not None:
+        where += f" AND {spec.time_column} >= ?"
+        params.append(since)
+    if spec.condition:
+        where += f" AND {spec.condition}"
+    return where, params
+
+
+async def _apply(db, table: str, policy: dict[str, Any], now: int) -> dict[str, Any]:
+    action = policy["action"]
+    since = await db.get_planner_state(EXPORTED_STATE_PREFIX + table) if action == "export" else None
+    where, params = _where(table, policy, now, since)
+    result: dict[str, Any] = {"rows": 0, "archived": 0, "bytes_freed": 0}
+    if where is None:
+        return result
+    if action != "delete":
+        columns, rows = await db.get_retention_rows(table, where, params)
+        if rows:
+            path = write_archive(table, columns, rows, now=now)
+            await upload_archive(db, path)
+            result.update(archived=len(rows), file=str(path))
+    if action == "export":
+        await db.set_planner_state(EXPORTED_STATE_PREFIX + table, now - policy["keep_days"] * 86400)
+        return result
+    if action == "archive" and not result["archived"]:
+        return result
+    result.update(await db.delete_retention_rows(table, where, params))
+    return result
+
+
+async def run_retention(db, now: int | None = None) -> dict[str, Any]:
+    """Apply every policy and store the result.
+
+    Returns:
+        {"ran_at", "rows", "archived", "bytes_freed", "tables": {table: {...}}};
+        rows counts deletions, archived the rows written to archive files
+    """
+    now = now if now is not None else int(time.time())
+    tables = {}
+    for table, policy in (await load_policies(db)).items():
+        try:
+            tables[table] = await _apply(db, table, policy, now)
+        except Exception as e:
+            logger.warning(f"Retention failed for {table}: {e}")
+            tables[table] = {"rows": 0, "archived": 0, "bytes_freed": 0, "error": str(e)}
+    result = {
+        "ran_at": now,
+        "rows": sum(t["rows"] for t in tables.values()),
+        "archived": sum(t["archived"] for t in tables.values()),
+        "bytes_freed": sum(t["bytes_freed"] for t in tables.values()),
+        "tables": tables,
+    }
+    await db.set_planner_state(RETENTION_STATE_KEY, result)
+    if result["rows"] or result["archived"]:
+        logger.info(f"Retention removed {result['rows']} rows, archived {result['archived']}")
+    return result
+
+
+async def retention_overview(db) -> dict[str, Any]:
+    """Policies per table with their description, and the last run."""
+    policies = await load_policies(db)
+    return {
+        "policies": [
+            {"table": table, "description": TABLES[table].description, **policy} for table, policy in policies.items()
+        ],
+        "last_run": await db.get_planner_state(RETENTION_STATE_KEY),
+    }
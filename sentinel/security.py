@@ -13,6 +13,7 @@ from typing import Optional
 
 from sentinel.broker import Broker
 from sentinel.database import Database
+from sentinel.exclusions import describe_exclusion, exclusion_matches, load_exclusion_lists
 from sentinel.settings import Settings
 
 # Duplicate trade protection: skip if traded within this many minutes
@@ -194,6 +195,10 @@ class Security:
         """
         if not self.allow_buy:
             raise ValueError(f"Buying {self.symbol} is not allowed")
+        if self._data:
+            exclusions = exclusion_matches(self._data, await load_exclusion_lists(self._db))
+            if exclusions:
+                raise ValueError(f"Buying {self.symbol} is not allowed: {describe_exclusion(exclusions)}")
 
         # Duplicate trade protection
         if await self._has_recent_trade():
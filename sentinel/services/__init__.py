@@ -4,7 +4,8 @@ Services encapsulate business operations that span multiple domain objects
 or require complex orchestration beyond what individual models provide.
 """
 
+from sentinel.services.history import PortfolioHistoryService
 from sentinel.services.portfolio import PortfolioService
 from sentinel.services.valuation import PortfolioValuationService
 
-__all__ = ["PortfolioService", "PortfolioValuationService"]
+__all__ = ["PortfolioHistoryService", "PortfolioService", "PortfolioValuationService"]
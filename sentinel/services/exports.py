@@ -0,0 +1,218 @@
+"""
+Ledger exports in the import formats of other portfolio trackers.
+
+- Ghostfolio: the JSON file its "Import activities" dialog accepts.
+- Portfolio Performance: a CSV for its account transactions import wizard,
+  with Buy/Sell rows carrying the security so one import fills both the
+  deposit account and the securities account.
+
+Both are built from the synced ledger: stock trades, the dividends table and
+card deposits/withdrawals. Dividends are exported net, as credited, so tax
+cash flows are not exported separately. FX conversions move cash between
+currencies only and are left out. Commissions are converted to the trade's
+currency on the trade date.
+
+Ghostfolio has no cash deposit activity, so deposits and withdrawals appear
+only in the Portfolio Performance file.
+"""
+
+from __future__ import annotations
+
+import csv
+import io
+from datetime import datetime, timezone
+from typing import Any
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.identifiers import security_isin
+
+TRADE_HISTORY_LIMIT = 100000
+
+PORTFOLIO_PERFORMANCE_COLUMNS = (
+    "Date",
+    "Type",
+    "Security Name",
+    "ISIN",
+    "Ticker Symbol",
+    "Shares",
+    "Value",
+    "Fees",
+    "Transaction Currency",
+    "Note",
+)
+
+
+def yahoo_symbol(symbol: str) -> str | None:
+    """Yahoo Finance ticker for a Tradernet symbol, when it maps one to one.
+
+    Only US listings do: `AAPL.US` is `AAPL`. Other exchanges need their own
+    Yahoo suffix, which the Tradernet symbol does not carry.
+    """
+    if symbol.endswith(".US"):
+        return symbol[: -len(".US")]
+    return None
+
+
+def _trade_date(trade: dict) -> str:
+    return datetime.fromtimestamp(int(trade["executed_at"])).date().isoformat()
+
+
+def _iso_utc(day: str) -> str:
+    return f"{day}T00:00:00.000Z"
+
+
+class LedgerExportService:
+    """Turns ledger events into Ghostfolio and Portfolio Performance imports."""
+
+    def __init__(self, db: Database | None = None, currency: Currency | None = None):
+        self._db = db or Database()
+        self._currency = currency or Currency()
+
+    async def events(self) -> list[dict[str, Any]]:
+        """Trades, dividends, deposits and withdrawals as uniform events, oldest first.
+
+        Each event has date, type (BUY/SELL/DIVIDEND/DEPOSIT/WITHDRAWAL), amount
+        (positive, in `currency`) and, for security events, symbol, name, isin,
+        quantity, unit_price and fee.
+        """
+        securities = {s["symbol"]: s for s in await self._db.get_all_securities(active_only=False)}
+        events = []
+
+        for trade in await self._db.get_trades(limit=TRADE_HISTORY_LIMIT):
+            symbol = trade["symbol"]
+            if "/" in symbol:
+                continue
+            security = securities.get(symbol, {})
+            currency = security.get("currency") or "EUR"
+            day = _trade_date(trade)
+            quantity = float(trade["quantity"])
+            price = float(trade["price"])
+            events.append(
+                {
+                    **self._security_fields(symbol, security),
+                    "date": day,
+                    "type": trade["side"],
+                    "quantity": quantity,
+                    "unit_price": price,
+                    "amount": quantity * price,
+                    "fee": await self._fee(trade, currency, day),
+                    "currency": currency,
+                    "note": f"Trade {trade['broker_trade_id']}",
+                    "_order": (1, int(trade["executed_at"])),
+                }
+            )
+
+        for dividend in await self._db.get_dividends():
+            symbol = dividend["symbol"]
+            events.append(
+                {
+                    **self._security_fields(symbol, securities.get(symbol, {})),
+                    "date": dividend["date"][:10],
+                    "type": "DIVIDEND",
+                    "quantity": 1.0,
+                    "unit_price": float(dividend["amount"]),
+                    "amount": float(dividend["amount"]),
+                    "fee": 0.0,
+                    "currency": dividend["currency"],
+                    "note": f"Dividend {dividend['id']}",
+                    "_order": (2, 0),
+                }
+            )
+
+        for flow in await self._db.get_cash_flows():
+            if flow["type_id"] not in ("card", "card_payout"):
+                continue
+            events.append(
+                {
+                    "date": flow["date"][:10],
+                    "type": "DEPOSIT" if flow["type_id"] == "card" else "WITHDRAWAL",
+                    "amount": abs(float(flow["amount"])),
+                    "currency": flow["currency"],
+                    "note": flow.get("comment") or "",
+                    "_order": (0, 0),
+                }
+            )
+
+        # Same day: cash arrives before it is spent, dividends after trades.
+        events.sort(key=lambda e: (e["date"], e["_order"]))
+        for event in events:
+            event.pop("_order")
+        return events
+
+    async def ghostfolio(self) -> dict[str, Any]:
+        """Ghostfolio import JSON with BUY, SELL and DIVIDEND activities."""
+        activities = []
+        for event in await self.events():
+            if event["type"] not in ("BUY", "SELL", "DIVIDEND"):
+                continue
+            ticker = event["ticker"]
+            activities.append(
+                {
+                    "type": event["type"],
+                    "date": _iso_utc(event["date"]),
+                    "symbol": ticker or event["symbol"],
+                    # Listings without a Yahoo ticker are imported as manual assets.
+                    "dataSource": "YAHOO" if ticker else "MANUAL",
+                    "currency": event["currency"],
+                    "quantity": event["quantity"],
+                    "unitPrice": event["unit_price"],
+                    "fee": round(event["fee"], 2),
+                    "comment": event["note"],
+                }
+            )
+        return {
+            "meta": {"date": datetime.now(timezone.utc).isoformat(timespec="milliseconds").replace("+00:00", "Z")},
+            "activities": activities,
+        }
+
+    async def portfolio_performance_csv(self) -> str:
+        """Portfolio Performance account transactions CSV."""
+        types = {"BUY": "Buy", "SELL": "Sell", "DIVIDEND": "Dividend", "DEPOSIT": "Deposit", "WITHDRAWAL": "Removal"}
+        rows = []
+        for event in await self.events():
+            fee = event.get("fee", 0.0)
+            amount = event["amount"]
+            # Value is the cash booked on the account: fees are paid on top of a
+            # buy and taken out of a sale.
+            if event["type"] == "BUY":
+                amount += fee
+            elif event["type"] == "SELL":
+                amount -= fee
+            trade = event["type"] in ("BUY", "SELL")
+            rows.append(
+                {
+                    "Date": event["date"],
+                    "Type": types[event["type"]],
+                    "Security Name": event.get("name", ""),
+                    "ISIN": event.get("isin") or "",
+                    "Ticker Symbol": event.get("ticker") or "",
+                    "Shares": event["quantity"] if trade else "",
+                    "Value": round(amount, 2),
+                    "Fees": round(fee, 2) if trade else "",
+                    "Transaction Currency": event["currency"],
+                    "Note": event["note"],
+                }
+            )
+        buffer = io.StringIO()
+        writer = csv.DictWriter(buffer, fieldnames=PORTFOLIO_PERFORMANCE_COLUMNS)
+        writer.writeheader()
+        writer.writerows(rows)
+        return buffer.getvalue()
+
+    @staticmethod
+    def _security_fields(symbol: str, security: dict) -> dict[str, Any]:
+        return {
+            "symbol": symbol,
+            "name": security.get("name") or symbol,
+            "isin": security_isin(security),
+            "ticker": yahoo_symbol(symbol),
+        }
+
+    async def _fee(self, trade: dict, currency: str, day: str) -> float:
+        commission = float(trade.get("commission") or 0.0)
+        commission_currency = trade.get("commission_currency") or "EUR"
+        if not commission or commission_currency == currency:
+            return commission
+        commission_eur = await self._currency.to_eur_for_date(commission, commission_currency, day)
+        return commission_eur / await self._currency.get_rate_for_date(currency, day)
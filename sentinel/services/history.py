@@ -0,0 +1,173 @@
+"""Portfolio value history: live valuation snapshots and the chart series built from them."""
+
+from __future__ import annotations
+
+import logging
+import time
+from datetime import datetime, timezone
+from typing import Any
+
+from sentinel.broker import Broker
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.services.valuation import PortfolioValuationService
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+RESOLUTIONS = ("1h", "1d", "1w")
+DEFAULT_HISTORY_DAYS = {"1h": 7, "1d": 365, "1w": 1825}
+MAX_HISTORY_DAYS = 3650
+INTRADAY_DAYS_KEY = "portfolio_history_intraday_days"
+
+
+def _bucket(ts: int, resolution: str) -> tuple:
+    moment = datetime.fromtimestamp(ts, tz=timezone.utc)
+    if resolution == "1h":
+        return (moment.date(), moment.hour)
+    if resolution == "1w":
+        return moment.isocalendar()[:2]
+    return (moment.date(),)
+
+
+def downsample(points: list[dict[str, Any]], resolution: str) -> list[dict[str, Any]]:
+    """Keep the last point of every hour, UTC day or ISO week, oldest first."""
+    latest: dict[tuple, dict[str, Any]] = {}
+    for point in sorted(points, key=lambda p: p["ts"]):
+        latest[_bucket(point["ts"], resolution)] = point
+    return list(latest.values())
+
+
+def _valuation_point(snapshot: dict) -> dict[str, Any]:
+    data = snapshot["data"]
+    return {
+        "ts": snapshot["ts"],
+        "total_value_eur": data.get("total_value_eur"),
+        "positions_value_eur": data.get("positions_value_eur"),
+        "cash_eur": data.get("cash_eur"),
+        "positions": {symbol: p.get("value_eur") for symbol, p in (data.get("positions") or {}).items()},
+        "source": "valuation",
+    }
+
+
+def _reconstructed_point(snapshot: dict) -> dict[str, Any]:
+    data = snapshot["data"]
+    positions = {symbol: p.get("value_eur", 0.0) for symbol, p in (data.get("positions") or {}).items()}
+    positions_value = round(sum(positions.values()), 2)
+    cash_eur = float(data.get("cash_eur", 0.0) or 0.0)
+    return {
+        "ts": snapshot["date"],
+        "total_value_eur": round(positions_value + cash_eur, 2),
+        "positions_value_eur": positions_value,
+        "cash_eur": cash_eur,
+        "positions": positions,
+        "source": "reconstructed",
+    }
+
+
+class PortfolioHistoryService:
+    """Records live valuation snapshots and serves downsampled value history."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        broker: Broker | None = None,
+        currency: Currency | None = None,
+        settings: Settings | None = None,
+    ):
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._currency = currency or Currency()
+        self._settings = settings or Settings()
+
+    async def record(self, now_ts: int | None = None) -> dict[str, Any]:
+        """Persist the current valuation, then thin intra-day snapshots past the retention window.
+
+        Returns:
+            The stored snapshot data
+        """
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        valuation = await PortfolioValuationService(db=self._db, broker=self._broker, currency=self._currency).current()
+
+        positions = {}
+        currencies = set()
+        for position in valuation["positions"]:
+            currency = position.get("currency") or "EUR"
+            currencies.add(currency)
+            positions[position["symbol"]] = {
+                "quantity": position.get("quantity"),
+                "price": position.get("current_price"),
+                "currency": currency,
+                "value_eur": round(float(position.get("value_eur") or 0.0), 2),
+            }
+        cash = {currency: round(float(amount), 2) for currency, amount in (valuation.get("cash") or {}).items()}
+        currencies.update(cash)
+
+        data = {
+            "total_value_eur": round(valuation["total_value_eur"], 2),
+            "positions_value_eur": round(valuation["total_positions_eur"], 2),
+            "cash_eur": round(valuation["total_cash_eur"], 2),
+            "positions": positions,
+            "cash": cash,
+            "fx_rates": {currency: await self._currency.get_rate(currency) for currency in sorted(currencies)},
+        }
+        await self._db.save_valuation_snapshot(now_ts, data)
+
+        intraday_days = max(1, int(await self._settings.get(INTRADAY_DAYS_KEY, 14) or 14))
+        thinned = await self._db.thin_valuation_snapshots(now_ts - intraday_days * 86400)
+        if thinned:
+            logger.info("Thinned %d intra-day valuation snapshots older than %d days", thinned, intraday_days)
+        return data
+
+    async def value_as_of(self, ts: int) -> dict[str, Any] | None:
+        """The latest history point at or before `ts`, live or reconstructed, without positions."""
+        candidates = []
+        live = await self._db.get_valuation_snapshots(end_ts=ts)
+        if live:
+            candidates.append(_valuation_point(live[-1]))
+        reconstructed = await self._db.get_portfolio_snapshot_as_of(ts)
+        if reconstructed:
+            candidates.append(_reconstructed_point(reconstructed))
+        if not candidates:
+            return None
+        point = max(candidates, key=lambda p: p["ts"])
+        point.pop("positions")
+        return point
+
+    async def history(
+        self,
+        resolution: str = "1d",
+        days: int | None = None,
+        *,
+        include_positions: bool = False,
+        now_ts: int | None = None,
+    ) -> dict[str, Any]:
+        """Portfolio value series for charts.
+
+        Live valuation snapshots are used where they exist; days before them fall
+        back to the reconstructed daily snapshots (`1d`/`1w` only).
+
+        Raises:
+            ValueError: If resolution or days is invalid.
+        """
+        if resolution not in RESOLUTIONS:
+            raise ValueError(f"resolution must be one of {', '.join(RESOLUTIONS)}")
+        days = DEFAULT_HISTORY_DAYS[resolution] if days is None else days
+        if not 1 <= days <= MAX_HISTORY_DAYS:
+            raise ValueError(f"days must be between 1 and {MAX_HISTORY_DAYS}")
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        start_ts = now_ts - days * 86400
+
+        points = [_valuation_point(s) for s in await self._db.get_valuation_snapshots(start_ts=start_ts)]
+        if resolution != "1h":
+            live_days = {_bucket(point["ts"], "1d") for point in points}
+            for snapshot in await self._db.get_portfolio_snapshots(days):
+                if snapshot["date"] >= start_ts and _bucket(snapshot["date"], "1d") not in live_days:
+                    points.append(_reconstructed_point(snapshot))
+
+        series = downsample(points, resolution)
+        for point in series:
+            point["date"] = datetime.fromtimestamp(point["ts"], tz=timezone.utc).date().isoformat()
+            if not include_positions:
+                point.pop("positions")
+        return {"resolution": resolution, "days": days, "points": series}
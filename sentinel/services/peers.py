@@ -0,0 +1,99 @@
+"""Peer comparison: a security against the best-scored securities like it."""
+
+from __future__ import annotations
+
+from typing import Any
+
+from sentinel.database import Database
+from sentinel.identifiers import IdentifierService, security_isin
+from sentinel.planner.preferences import normalize_user_multiplier
+from sentinel.strategy import compute_contrarian_signal
+
+PEER_MATCHES = ("industry", "geography", "both")
+MAX_PEERS = 50
+# Same price window the allocation engine scores securities on.
+PRICE_DAYS = 300
+
+# Contrarian signal components shown side by side; `score` (the planner's
+# opportunity score) is the total the peers are ranked by.
+SIGNAL_COMPONENTS = ("dip_score", "capitulation_score", "core_rank", "dd252", "mom60", "vol20")
+
+
+class PeerService:
+    """Ranks a security's peers from the universe in one pass over the database."""
+
+    def __init__(self, db: Database | None = None):
+        self._db = db or Database()
+
+    async def find_security(self, symbol_or_isin: str) -> dict | None:
+        """Look a security up by symbol, symbol variant or ISIN."""
+        return await IdentifierService(self._db).get_security(symbol_or_isin)
+
+    async def peers(self, security: dict, *, match: str = "industry", limit: int = 5) -> dict[str, Any]:
+        """Compare `security` with the top `limit` active securities sharing its industry/geography.
+
+        Raises:
+            ValueError: If match or limit is invalid, or the security lacks the matched field.
+        """
+        if match not in PEER_MATCHES:
+            raise ValueError(f"match must be one of {', '.join(PEER_MATCHES)}")
+        if not 1 <= limit <= MAX_PEERS:
+            raise ValueError(f"limit must be between 1 and {MAX_PEERS}")
+        fields = ("industry", "geography") if match == "both" else (match,)
+        for field in fields:
+            if not security.get(field):
+                raise ValueError(f"{security['symbol']} has no {field}")
+
+        universe = await self._db.get_all_securities(active_only=True)
+        group = [
+            candidate
+            for candidate in universe
+            if candidate["symbol"] != security["symbol"]
+            and all(candidate.get(field) == security.get(field) for field in fields)
+        ]
+        members = [security, *group]
+        symbols = [member["symbol"] for member in members]
+        prices = await self._db.get_prices_bulk(symbols, days=PRICE_DAYS)
+        forecasts = await self._db.get_latest_forecast_scores(symbols, scope="combined")
+        news = await self._db.get_news_sentiment(symbols)
+
+        rows = {
+            member["symbol"]: self._row(member, prices.get(member["symbol"], []), forecasts, news) for member in members
+        }
+        ranked = sorted(rows.values(), key=lambda row: (-row["score"], row["symbol"]))
+        for position, row in enumerate(ranked, start=1):
+            row["rank"] = position
+
+        return {
+            "symbol": security["symbol"],
+            "match": match,
+            "industry": security.get("industry"),
+            "geography": security.get("geography"),
+            "group_size": len(members),
+            "security": rows[security["symbol"]],
+            "peers": [row for row in ranked if row["symbol"] != security["symbol"]][:limit],
+        }
+
+    @staticmethod
+    def _row(security: dict, price_rows: list[dict], forecasts: dict, news: dict) -> dict[str, Any]:
+        symbol = security["symbol"]
+        closes = [float(row["close"]) for row in reversed(price_rows) if row.get("close") is not None]
+        signal = compute_contrarian_signal(closes)
+        forecast = forecasts.get(symbol) or {}
+        sentiment = news.get(symbol) or {}
+        return {
+            "symbol": symbol,
+            "name": security.get("name"),
+            "isin": security_isin(security),
+            "industry": security.get("industry"),
+            "geography": security.get("geography"),
+            "currency": security.get("currency"),
+            "price": closes[-1] if closes else None,
+            "score": float(signal.get("opp_score", 0.0) or 0.0),
+            "components": {
+                **{key: signal.get(key) for key in SIGNAL_COMPONENTS},
+                "user_multiplier": normalize_user_multiplier(security.get("user_multiplier", 0.5)),
+                "forecast_score": forecast.get("score"),
+                "news_sentiment": sentiment.get("score"),
+            },
+        }
@@ -0,0 +1,263 @@
+"""Downloadable reports: positions and tax-year trade CSVs, and the monthly PDF summary.
+
+CSVs keep `.` decimals and ISO dates; the PDF summary is written in the configured locale.
+"""
+
+from __future__ import annotations
+
+import csv
+import io
+import time
+from datetime import date, datetime, timezone
+from typing import Any
+
+from sentinel.broker import Broker
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.localization import LOCALES, Locale
+from sentinel.services.history import PortfolioHistoryService
+from sentinel.services.valuation import PortfolioValuationService
+from sentinel.utils.pdf import text_pdf
+
+# ghostfolio and portfolio-performance are ledger exports for other trackers, built by
+# sentinel.services.exports; journal is the research notes document from sentinel.notes.
+REPORTS = ("positions", "trades", "monthly", "ghostfolio", "portfolio-performance", "journal")
+# Trades are exported for a whole year at once; far more than any account makes.
+MAX_TRADES = 100_000
+MIN_YEAR = 1970
+MAX_YEAR = 9998
+
+POSITION_COLUMNS = (
+    "symbol",
+    "name",
+    "quantity",
+    "avg_cost",
+    "price",
+    "currency",
+    "value_local",
+    "value_eur",
+    "invested_eur",
+    "profit_pct",
+    "weight_pct",
+)
+TRADE_COLUMNS = (
+    "date",
+    "symbol",
+    "side",
+    "quantity",
+    "price",
+    "currency",
+    "value_local",
+    "commission",
+    "commission_currency",
+    "fx_rate",
+    "value_eur",
+    "broker_trade_id",
+)
+
+
+def _csv(columns: tuple[str, ...], rows: list[dict]) -> str:
+    buffer = io.StringIO()
+    writer = csv.DictWriter(buffer, fieldnames=columns, extrasaction="ignore")
+    writer.writeheader()
+    writer.writerows(rows)
+    return buffer.getvalue()
+
+
+def _month_bounds(year: int, month: int) -> tuple[date, date]:
+    """First day of the month and first day of the next month."""
+    start = date(year, month, 1)
+    end = date(year + 1, 1, 1) if month == 12 else date(year, month + 1, 1)
+    return start, end
+
+
+def _utc_ts(day: date) -> int:
+    return int(datetime(day.year, day.month, day.day, tzinfo=timezone.utc).timestamp())
+
+
+def _trade_date(trade: dict) -> str:
+    return datetime.fromtimestamp(int(trade["executed_at"])).date().isoformat()
+
+
+class ReportService:
+    """Builds the exportable reports from the database and the live valuation."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        broker: Broker | None = None,
+        currency: Currency | None = None,
+        locale: Locale | None = None,
+    ):
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._currency = currency or Currency()
+        self._locale = locale or LOCALES["en"]
+
+    async def positions_csv(self) -> str:
+        """Current positions at live prices, largest first."""
+        valuation = await PortfolioValuationService(db=self._db, broker=self._broker, currency=self._currency).current()
+        total = valuation["total_value_eur"]
+        rows = [
+            {
+                "symbol": position["symbol"],
+                "name": position.get("name"),
+                "quantity": position.get("quantity"),
+                "avg_cost": position.get("avg_cost"),
+                "price": position.get("current_price"),
+                "currency": position.get("currency"),
+                "value_local": round(position["value_local"], 2),
+                "value_eur": round(position["value_eur"], 2),
+                "invested_eur": round(position["invested_eur"], 2),
+                "profit_pct": round(position["profit_pct"], 2),
+                "weight_pct": round(position["value_eur"] / total * 100, 2) if total > 0 else 0.0,
+            }
+            for position in sorted(valuation["positions"], key=lambda p: -p["value_eur"])
+        ]
+        return _csv(POSITION_COLUMNS, rows)
+
+    async def trades(self, start_date: str, end_date: str) -> list[dict[str, Any]]:
+        """Trades executed between two dates (inclusive), oldest first, valued in EUR on the trade date."""
+        trades = await self._db.get_trades(start_date=start_date, end_date=end_date, limit=MAX_TRADES)
+        securities = await self._db.get_all_securities(active_only=False)
+        currencies = {security["symbol"]: security.get("currency") for security in securities}
+        rows = []
+        for trade in sorted(trades, key=lambda t: (t["executed_at"], t["id"])):
+            symbol = trade["symbol"]
+            # FX conversions are quoted in their second currency (EUR/USD is priced in USD).
+            currency = symbol.split("/", 1)[1] if "/" in symbol else currencies.get(symbol) or "EUR"
+            trade_date = _trade_date(trade)
+            value_local = float(trade["quantity"]) * float(trade["price"])
+            fx_rate = await self._currency.get_rate_for_date(currency, trade_date)
+            rows.append(
+                {
+                    "date": trade_date,
+                    "symbol": symbol,
+                    "side": trade["side"],
+                    "quantity": trade["quantity"],
+                    "price": trade["price"],
+                    "currency": currency,
+                    "value_local": round(value_local, 2),
+                    "commission": trade.get("commission") or 0.0,
+                    "commission_currency": trade.get("commission_currency") or "EUR",
+                    "fx_rate": fx_rate,
+                    "value_eur": round(value_local * fx_rate, 2),
+                    "broker_trade_id": trade["broker_trade_id"],
+                }
+            )
+        return rows
+
+    async def trades_csv(self, year: int) -> str:
+        """Every trade of a tax (calendar) year.
+
+        Raises:
+            ValueError: If the year is out of range.
+        """
+        if not MIN_YEAR <= year <= MAX_YEAR:
+            raise ValueError(f"year must be between {MIN_YEAR} and {MAX_YEAR}")
+        return _csv(TRADE_COLUMNS, await self.trades(f"{year}-01-01", f"{year}-12-31"))
+
+    async def monthly_summary(self, year: int, month: int, *, now_ts: int | None = None) -> dict[str, Any]:
+        """Value, performance, deposits, dividends and trades of one calendar month.
+
+        The month's value change is split into net deposits and performance; a
+        month still in progress ends at the latest snapshot.
+
+        Raises:
+            ValueError: If the year or month is invalid or has not started yet.
+        """
+        if not MIN_YEAR <= year <= MAX_YEAR:
+            raise ValueError(f"year must be between {MIN_YEAR} and {MAX_YEAR}")
+        if not 1 <= month <= 12:
+            raise ValueError("month must be between 1 and 12")
+        start, end = _month_bounds(year, month)
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        if _utc_ts(start) > now_ts:
+            raise ValueError(f"{start:%Y-%m} has not started yet")
+        last_day = date.fromordinal(end.toordinal() - 1).isoformat()
+
+        history = PortfolioHistoryService(db=self._db, broker=self._broker, currency=self._currency)
+        opening = await history.value_as_of(_utc_ts(start) - 1)
+        closing = await history.value_as_of(min(_utc_ts(end) - 1, now_ts))
+
+        net_deposits = 0.0
+        for flow in await self._db.get_cash_flows(start_date=start.isoformat(), end_date=last_day):
+            if flow["type_id"] not in ("card", "card_payout"):
+                continue
+            amount_eur = await self._currency.to_eur_for_date(flow["amount"], flow["currency"], flow["date"])
+            net_deposits += amount_eur if flow["type_id"] == "card" else -abs(amount_eur)
+
+        start_value = opening["total_value_eur"] if opening else None
+        end_value = closing["total_value_eur"] if closing else None
+        performance_eur = None
+        performance_pct = None
+        if end_value is not None:
+            performance_eur = end_value - (start_value or 0.0) - net_deposits
+            invested = (start_value or 0.0) + max(net_deposits, 0.0)
+            performance_pct = performance_eur / invested * 100 if invested > 0 else None
+
+        dividends = [d for d in await self._db.get_dividends(start_date=start.isoformat()) if d["date"] <= last_day]
+        dividends_by_symbol: dict[str, float] = {}
+        for dividend in dividends:
+            symbol = dividend["symbol"]
+            dividends_by_symbol[symbol] = dividends_by_symbol.get(symbol, 0.0) + dividend["value"]
+
+        trades = await self.trades(start.isoformat(), last_day)
+        return {
+            "month": f"{start:%Y-%m}",
+            "start_date": start.isoformat(),
+            "end_date": last_day,
+            "start_value_eur": start_value,
+            "end_value_eur": end_value,
+            "net_deposits_eur": round(net_deposits, 2),
+            "performance_eur": None if performance_eur is None else round(performance_eur, 2),
+            "performance_pct": None if performance_pct is None else round(performance_pct, 2),
+            "dividends_eur": round(sum(dividends_by_symbol.values()), 2),
+            "dividends": [
+                {"symbol": symbol, "value_eur": round(value, 2)}
+                for symbol, value in sorted(dividends_by_symbol.items(), key=lambda item: (-item[1], item[0]))
+            ],
+            "trades": trades,
+            "bought_eur": round(sum(t["value_eur"] for t in trades if t["side"] == "BUY"), 2),
+            "sold_eur": round(sum(t["value_eur"] for t in trades if t["side"] == "SELL"), 2),
+        }
+
+    async def monthly_pdf(self, year: int, month: int, *, now_ts: int | None = None) -> bytes:
+        """The monthly summary as a printable PDF."""
+        summary = await self.monthly_summary(year, month, now_ts=now_ts)
+        locale = self._locale
+
+        def eur(value: float | None) -> str:
+            return locale.text("not_available") if value is None else locale.money(value)
+
+        labels = {
+            key: locale.text(key)
+            for key in ("value_start", "value_end", "net_deposits", "performance", "dividends", "bought_sold")
+        }
+        width = max(19, max(len(label) for label in labels.values()) + 2)
+        pct = summary["performance_pct"]
+        title = locale.text("monthly_title", month=locale.month(year, month))
+        lines = [
+            title,
+            locale.text("period", start=locale.date(summary["start_date"]), end=locale.date(summary["end_date"])),
+            "",
+            f"{labels['value_start']:<{width}}{eur(summary['start_value_eur'])}",
+            f"{labels['value_end']:<{width}}{eur(summary['end_value_eur'])}",
+            f"{labels['net_deposits']:<{width}}{eur(summary['net_deposits_eur'])}",
+            f"{labels['performance']:<{width}}{eur(summary['performance_eur'])}"
+            + ("" if pct is None else f" ({locale.percent(pct, 2, sign=True)})"),
+            f"{labels['dividends']:<{width}}{eur(summary['dividends_eur'])}",
+            f"{labels['bought_sold']:<{width}}{eur(summary['bought_eur'])} / {eur(summary['sold_eur'])}",
+            "",
+            labels["dividends"],
+        ]
+        none = [f"  {locale.text('none')}"]
+        lines += [f"  {d['symbol']:<16} {eur(d['value_eur']):>20}" for d in summary["dividends"]] or none
+        lines += ["", locale.text("trades")]
+        lines += [
+            f"  {locale.date(t['date'])}  {locale.text(t['side'].lower()):<9}  {t['symbol']:<16} "
+            f"{locale.number(t['quantity'], None):>10} @ {locale.number(t['price'], None):<10} "
+            f"{t['currency']:<3} {eur(t['value_eur']):>16}"
+            for t in summary["trades"]
+        ] or none
+        return text_pdf(lines, title=title)
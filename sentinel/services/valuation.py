@@ -119,7 +119,8 @@ class PortfolioValuationService:
         except Exception as e:
             logger.warning("Live broker account valuation unavailable; falling back to database positions: %s", e)
 
-        return await self._db.get_all_positions(), await self._db.get_cash_balances()
+        positions = await self._db.get_all_positions() + await self._db.get_short_positions()
+        return positions, await self._db.get_cash_balances()
 
     async def _quotes(self, symbols: list[str]) -> dict[str, dict]:
         if not symbols:
@@ -11,7 +11,9 @@ All settings are stored in the database and editable via the web UI.
 No hardcoded magic numbers.
 """
 
-from typing import Any
+from contextlib import contextmanager
+from contextvars import ContextVar
+from typing import Any, Iterator
 
 from sentinel.database import Database
 from sentinel.utils.decorators import singleton
@@ -21,25 +23,57 @@ DEFAULTS = {
     # Trading mode: 'research' or 'live'
     # In research mode, no actual trades are executed
     "trading_mode": "research",
+    # Global kill switch; set via /api/trading/pause (see sentinel.trading_pause)
+    "trading_pause": None,
+    # Paused scheduled jobs, all or per job type; set via /api/jobs/pause
+    # (see sentinel.job_pause)
+    "job_pause": None,
+    # Recurring local-time windows in which only maintenance jobs run.
+    # See sentinel.job_pause.
+    "maintenance_windows": [],
     # Transaction costs
     "transaction_fee_fixed": 2.0,  # Fixed fee per trade (EUR)
     "transaction_fee_percent": 0.2,  # Percentage fee (0.2%)
+    "transaction_fx_spread_percent": 0.0,  # FX conversion spread on non-EUR trades (%)
+    "transaction_min_commission": 0.0,  # Floor for fixed + percentage fee (EUR)
+    # Per-exchange/currency overrides of the four fields above, keyed by
+    # market_id or currency: {"NYSE": {"fixed_fee": 1.0, "fx_spread_percent": 0.5}}
+    "transaction_cost_profiles": {},
     # Position limits (for planner)
     "max_position_pct": 25,  # Hard cap per security
     "min_position_pct": 2,  # Min 2% position size
     "min_trade_value": 400.0,  # Minimum trade value (EUR)
     # Cash management
     "min_cash_buffer": 0.005,  # Keep 0.5% cash minimum
+    "min_cash_buffer_eur": 0.0,  # Absolute cash minimum (EUR); the larger buffer applies
+    "cash_currency_floors": {},  # Cash kept per currency, e.g. {"USD": 200}
+    # Known upcoming cash needs kept out of buy budgets:
+    # [{"label": "Tax bill", "amount": 1500, "currency": "EUR", "due_date": "2026-06-30"}]
+    "pending_obligations": [],
+    # Matched savings-plan deposits are invested without waiting for entry
+    # timing for this many days (see sentinel.planner.savings).
+    "savings_plan_new_money_days": 30,
     "target_cash_pct": 0,  # Fully invested strategy
     "simulated_cash_eur": None,  # Override cash in research mode (None = use real)
     # Rebalancing
     "rebalance_threshold_pct": 5,  # Rebalance when 5% off target
+    # Per-target drift bands in percentage points (see sentinel.planner.drift),
+    # e.g. {"position": 2, "geography": {"default": 3, "US": 5}}
+    "rebalance_drift_bands": {},
+    # Pre-earnings freeze: block trades in a security this many days before
+    # its next earnings date (0 = off). Dates live in the earnings calendar.
+    "earnings_freeze_days": 0,
     # Performance chart benchmark: trailing-1Y return overlaid on the portfolio's
     # rolling TWR line. VWCE.EU (FTSE All-World ETF) = the "plain index" yardstick.
     "performance_benchmark_symbol": "VWCE.EU",
+    # Market indices (from the synced `benchmarks` table) the portfolio is
+    # tracked against at /api/analytics/benchmark; the first one is shown on
+    # the LED ticker.
+    "benchmark_symbols": ["SP500.IDX"],
     # Dividend reinvestment
     "max_dividend_reinvestment_boost": 0.15,  # Max score boost for uninvested dividends
     # API
+    "idempotency_key_retention_hours": 24,  # How long Idempotency-Key results are replayed
     "tradernet_api_key": "",
     "tradernet_api_secret": "",
     # Freedom24 web-session login (needed for PRAAMS portfolio-structure data
@@ -88,6 +122,56 @@ DEFAULTS = {
     "forecasting_score_max_age_days": 14,
     "forecasting_timing_weight": 0.15,
     "forecasting_request_timeout_seconds": 840,
+    # News sentiment: headlines per security from an RSS feed (sync:news),
+    # scored with a small lexicon and decayed by age. The URL template may use
+    # {symbol}, {ticker} and {query}; empty = ingestion off. The planner nudges
+    # opportunity scores by news_timing_weight (0 = sentiment not used).
+    "news_enabled": False,
+    "news_feed_url_template": "",
+    "news_half_life_days": 3,
+    "news_max_age_days": 30,
+    "news_score_max_age_days": 3,
+    "news_timing_weight": 0.0,
+    # Bid/ask spread (sentinel.planner.spread): buys of securities quoted wider
+    # than this many basis points are skipped, and narrower spreads scale the
+    # opportunity score down. 0 = spreads only count as trading cost.
+    "planner_max_spread_bps": 150,
+    # Trading volume (sentinel.planner.volume): average daily volume is taken
+    # over this many trading days, a single order may be at most this
+    # percentage of it, and securities trading less than this many EUR a day
+    # are flagged illiquid and not bought. 0 disables the cap / the flag.
+    "adv_lookback_days": 20,
+    "max_order_pct_adv": 5.0,
+    "min_adv_turnover_eur": 25000.0,
+    # Investment goals (sentinel.planner.goals): the portfolio is projected
+    # with this expected annual return and volatility. When the most important
+    # goal's probability falls below goal_min_probability the planner leans
+    # in, by at most goal_max_tilt (0 = goals never change planning).
+    "goal_expected_return_pct": 6.0,
+    "goal_volatility_pct": 15.0,
+    "goal_min_probability": 0.6,
+    "goal_max_tilt": 0.5,
+    # Dividend income (sentinel.planner.income): the forward twelve-month
+    # income of the held positions is compared with this target (0 = none).
+    # With planner_objective "income" the planner leans toward held dividend
+    # payers while income is short of it; "total_return" ignores income.
+    "income_target_annual_eur": 0.0,
+    "planner_objective": "total_return",
+    # How projections and target set comparisons estimate the covariance of
+    # daily returns: sample, ledoit_wolf, ewma, semi or factor. See sentinel.covariance.
+    "covariance_estimator": "sample",
+    # How those estimates treat positions with less than a year of prices:
+    # exclude, shrink (toward industry peers) or proxy. See sentinel.projections.
+    "short_history_policy": "shrink",
+    # Portfolio history: live valuation snapshots older than this many days
+    # are thinned to the last one of each day.
+    "portfolio_history_intraday_days": 14,
+    # Monte Carlo robustness check: re-score the top-K recommendations over
+    # bootstrapped price paths. Off by default; it is CPU-bound on the UNO Q.
+    "planner_monte_carlo_enabled": False,
+    "planner_monte_carlo_paths": 500,
+    "planner_monte_carlo_horizon_days": 20,
+    "planner_monte_carlo_top_k": 5,
     # User-conviction target. The stored `user_multiplier` slider value defines
     # long-term relative weights and decays toward neutral (0.5) by
     # `user_multiplier_decay_factor` every
@@ -96,15 +180,131 @@ DEFAULTS = {
     "clara_preference_strength": 5.0,
     "user_multiplier_decay_factor": 0.90,
     "user_multiplier_decay_interval_days": 7,
+    # Language and number/date/currency format of the LED ticker and the
+    # monthly PDF summary: en, de, fr, it, es, nl or pt. See sentinel.localization.
+    "locale": "en",
+    # LED ticker template: ordered segments such as
+    # {"text": "NEXT {next_action}", "when": "pending_trades"}; [] keeps the
+    # built-in lines. See sentinel.led.ticker.
+    "ticker_template": [],
     # LED Display (Arduino UNO Q orbital visualization)
     "led_display_enabled": False,  # Disabled by default for dev environments
     "led_brightness": 200,  # Global LED brightness 0-255
+    # Display mode rotation by local time of day (slots may wrap midnight).
+    # A manual override with expiry (led_display_mode_override) takes precedence.
+    "led_display_schedule": [
+        {"mode": "ticker", "start": "09:00", "end": "17:30"},
+        {"mode": "health", "start": "17:30", "end": "23:00"},
+        {"mode": "stats", "start": "23:00", "end": "09:00"},
+    ],
+    "led_display_mode_override": None,
+    # Buzzer alerts; quiet hours (local, may wrap midnight) mute all but critical alerts.
+    "led_buzzer_enabled": True,
+    "led_buzzer_quiet_hours": {"start": "22:00", "end": "07:00"},
+    "led_buzzer_last_alert": None,
+    # Auto-deploy (scripts/auto-deploy.sh, see sentinel.deployments): "stable"
+    # tracks the newest v* release tag, "beta" tracks main. Deploys wait for
+    # every universe market to close and, when set, for the local window
+    # {"start": "HH:MM", "end": "HH:MM"} (may wrap midnight).
+    "deploy_channel": "beta",
+    "deploy_when_markets_closed": True,
+    "deploy_window": None,
+    # Clock drift check (sentinel.clock): SNTP server and the offset beyond
+    # which scheduling decisions are flagged as made under suspected drift.
+    "clock_ntp_server": "pool.ntp.org",
+    "clock_drift_threshold_seconds": 30,
+    # Log each job's next eligible run (UTC and exchange-local) on every
+    # market status check; see GET /api/jobs/schedule-audit.
+    "job_schedule_audit": False,
+    # Job duration budgets in seconds (sentinel.performance); a run longer
+    # than budget x factor logs a warning. See GET /api/system/performance.
+    "job_duration_budgets": {
+        "sync:portfolio": 60,
+        "sync:prices": 300,
+        "sync:quotes": 60,
+        "sync:trades": 120,
+        "trading:rebalance": 120,
+        "planning:refresh": 120,
+        "snapshot:valuation": 60,
+        "forecast:run": 1800,
+    },
+    "job_budget_warning_factor": 1.5,
+    # Price sanity: incoming prices/quotes moving more than this % from the last
+    # known price are quarantined for review; this many quarantined values for
+    # one security within 24h raise an alert. See sentinel.price_sanity.
+    "price_sanity_max_move_pct": 50,
+    "price_sanity_alert_count": 3,
+    # Single-position concentration (% of portfolio): warn opens a tracked
+    # breach, critical escalates it with an alert and a reduction plan.
+    # See sentinel.concentration.
+    "concentration_alert_levels": {"warn_pct": 15, "critical_pct": 20},
+    # Cash analytics (sentinel.cash_analytics): excess cash over a currency's
+    # planned need worth at least cash_idle_min_eur counts as idle; foreign
+    # cash idle for cash_idle_days is suggested for conversion to EUR.
+    # Suggestions are only executed through the approval endpoint when
+    # cash_conversion_approvals is on.
+    "cash_idle_min_eur": 100,
+    "cash_idle_days": 7,
+    "cash_conversion_approvals": False,
+    # Execution policy for planner orders (sentinel.execution): per-venue
+    # order rate limit, no orders near the session open/close, and TWAP
+    # slicing of large recommendations. All off by default.
+    "execution_policy": {
+        "max_orders_per_minute": 0,
+        "avoid_open_minutes": 0,
+        "avoid_close_minutes": 0,
+        "twap_slices": 1,
+        "twap_min_value_eur": 0,
+    },
+    # Shared secret for admin endpoints such as profiling (X-Admin-Token
+    # header); empty disables them.
+    "admin_token": "",
     # Cloudflare R2 Backup
     "r2_account_id": "",
     "r2_access_key": "",
     "r2_secret_key": "",
     "r2_bucket_name": "",
     "r2_backup_retention_days": 30,
+    # Also upload retention archive files (data/archive) to the R2 bucket.
+    # See sentinel.archive.
+    "archive_r2_upload": False,
+    # Research-mode chaos testing: chance (0-1) per call of an injected broker
+    # timeout, SQLite busy error or job failure. See sentinel.faults.
+    "fault_injection": {
+        "enabled": False,
+        "seed": None,
+        "broker_timeout": 0.0,
+        "sqlite_busy": 0.0,
+        "job_failure": 0.0,
+    },
+    # Days job run artifacts (reports, weights) are kept. See sentinel.artifacts.
+    "job_artifact_retention_days": 30,
+    # CPU/memory thresholds at which scheduled low-priority jobs (forecasts,
+    # indicators, backfills) are slowed down or held back. See sentinel.governor.
+    "resource_governor": {
+        "enabled": True,
+        "cpu_throttle_pct": 75.0,
+        "cpu_pause_pct": 92.0,
+        "cpu_resume_pct": 50.0,
+        "memory_throttle_pct": 85.0,
+        "memory_pause_pct": 93.0,
+        "memory_resume_pct": 75.0,
+        "max_defer_seconds": 600,
+        "throttle_sleep_ms": 250,
+        "low_priority_jobs": [
+            "forecast:run",
+            "forecast:evaluate",
+            "security:technical",
+            "security:liquidity",
+            "security:factors",
+            "snapshot:backfill",
+            "sync:news",
+            "system:retention",
+        ],
+    },
+    # Named CSV column mappings for statement imports, beside the built-in
+    # sentinel_trades and sentinel_cash_flows. See sentinel.statement_import.
+    "statement_import_templates": {},
 }
 
 REMOVED_SETTINGS = {
@@ -126,6 +326,87 @@ REMOVED_SETTINGS = {
     "user_multiplier_blend_pct",
 }
 
+# Strategy tuning keys; the batch settings endpoint requires all of them together.
+STRATEGY_KEYS = {
+    "strategy_min_opp_score",
+    "strategy_ideal_qualifying_threshold",
+    "strategy_core_timing_min_score",
+    "strategy_core_timing_min_dip_score",
+    "strategy_fallback_wait_days",
+    "strategy_entry_t1_dd",
+    "strategy_entry_t2_dd",
+    "strategy_entry_t3_dd",
+    "strategy_entry_memory_days",
+    "strategy_memory_max_boost",
+    "strategy_opportunity_addon_threshold",
+    "strategy_max_opportunity_buys_per_cycle",
+    "strategy_max_new_opportunity_buys_per_cycle",
+}
+
+# Settings that feed the planner; changing one invalidates planner caches.
+PLANNER_SETTING_KEYS = {
+    *STRATEGY_KEYS,
+    "clara_preference_strength",
+    "user_multiplier_decay_factor",
+    "user_multiplier_decay_interval_days",
+    "max_position_pct",
+    "min_position_pct",
+    "min_cash_buffer",
+    "min_cash_buffer_eur",
+    "cash_currency_floors",
+    "pending_obligations",
+    "savings_plan_new_money_days",
+    "earnings_freeze_days",
+    "target_cash_pct",
+    "min_trade_value",
+    "transaction_fee_fixed",
+    "transaction_fee_percent",
+    "transaction_fx_spread_percent",
+    "transaction_min_commission",
+    "transaction_cost_profiles",
+    "max_dividend_reinvestment_boost",
+    "strategy_lot_standard_max_pct",
+    "strategy_lot_coarse_max_pct",
+    "strategy_coarse_max_new_lots_per_cycle",
+    "cooldown_enabled",
+    "strategy_opportunity_cooloff_days",
+    "strategy_core_cooloff_days",
+    "strategy_same_side_cooloff_days",
+    "strategy_rotation_time_stop_days",
+    "strategy_max_funding_sells_per_cycle",
+    "strategy_max_funding_turnover_pct",
+    "strategy_funding_conviction_bias",
+    "planner_monte_carlo_enabled",
+    "planner_monte_carlo_paths",
+    "planner_monte_carlo_horizon_days",
+    "planner_monte_carlo_top_k",
+    "news_timing_weight",
+    "planner_max_spread_bps",
+    "max_order_pct_adv",
+    "goal_min_probability",
+    "goal_max_tilt",
+    "income_target_annual_eur",
+    "planner_objective",
+}
+
+
+# Per-task, in-memory overrides for what-if runs (see settings_overrides).
+_overrides: ContextVar[dict[str, Any] | None] = ContextVar("settings_overrides", default=None)
+
+
+@contextmanager
+def settings_overrides(values: dict[str, Any]) -> Iterator[None]:
+    """Temporarily override setting values for the current async context.
+
+    Overrides are visible to every Settings.get() in this task (and tasks it
+    spawns) but are never written to the database.
+    """
+    token = _overrides.set({**(_overrides.get() or {}), **values})
+    try:
+        yield
+    finally:
+        _overrides.reset(token)
+
 
 @singleton
 class Settings:
@@ -140,6 +421,9 @@ class Settings:
         """Get a setting value."""
         if key in REMOVED_SETTINGS:
             return default
+        overrides = _overrides.get()
+        if overrides and key in overrides:
+            return overrides[key]
         value = await self._db.get_setting(key)
         if value is None:
             return default if default is not None else DEFAULTS.get(key)
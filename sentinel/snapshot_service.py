@@ -85,6 +85,17 @@ def _snapshot_write_plan(
     ]
 
 
+def _is_stock_symbol(symbol: str) -> bool:
+    """FX pairs ("EUR/USD") and broker-internal instruments ("+...", "DGT...") are not positions."""
+    if "/" in symbol:
+        return False
+    if symbol.startswith("+"):
+        return False
+    if symbol.startswith("DGT"):
+        return False
+    return True
+
+
 def _apply_stock_position_trade(positions: dict[str, float], symbol: str, side: str, quantity: float) -> None:
     current_quantity = positions.get(symbol, 0.0)
     next_quantity = current_quantity + quantity if side == "BUY" else current_quantity - quantity
@@ -264,16 +275,7 @@ class SnapshotService:
             )
 
             # Filter out FX pairs and options — only keep actual stock positions
-            def is_stock_symbol(symbol: str) -> bool:
-                if "/" in symbol:
-                    return False
-                if symbol.startswith("+"):
-                    return False
-                if symbol.startswith("DGT"):
-                    return False
-                return True
-
-            stock_trades = [t for t in trades if is_stock_symbol(t["symbol"])]
+            stock_trades = [t for t in trades if _is_stock_symbol(t["symbol"])]
             excluded = len(trades) - len(stock_trades)
             logger.info(f"Processing {len(stock_trades)} stock trades (excluded {excluded} FX/options)")
 
@@ -394,10 +396,10 @@ class SnapshotService:
                     _apply_trade_cash(cash_balances, trade, security_currency=sec_curr)
 
                     if trade["side"] == "BUY":
-                        if is_stock_symbol(symbol):
+                        if _is_stock_symbol(symbol):
                             _apply_stock_position_trade(positions, symbol, trade["side"], qty)
                     else:  # SELL
-                        if is_stock_symbol(symbol):
+                        if _is_stock_symbol(symbol):
                             _apply_stock_position_trade(positions, symbol, trade["side"], qty)
 
                     last_trade_idx += 1
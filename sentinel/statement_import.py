@@ -0,0 +1,403 @@
+"""
+Statement import - trades and cash flows from CSV statements.
+
+History from before the broker account, or from another broker, only exists
+as statements. A template maps a statement's columns onto ledger fields:
+
+    {
+        "kind": "trades",                  # or "cash_flows"
+        "delimiter": ",",
+        "decimal": ".",                    # "," for 1.234,56 style numbers
+        "date_format": "%d.%m.%Y",         # strptime format; "iso" for ISO 8601
+        "columns": {"date": "Date", "symbol": "Ticker", "side": "Type",
+                    "quantity": "Shares", "price": "Price", "currency": "Currency"},
+        "sides": {"BUY": ["buy", "kauf"], "SELL": ["sell", "verkauf"]},
+        "defaults": {"currency": "EUR"}    # constants for fields without a column
+    }
+
+Trades need date, symbol, side, quantity and price (optional: id,
+commission, commission_currency, currency); cash flows need date, type,
+amount and currency (optional: id, comment). BUILTIN_TEMPLATES read
+Sentinel's own column names; more are stored in the
+`statement_import_templates` setting.
+
+`preview` parses and validates without writing: per-line errors, symbols
+outside the universe, and which rows are new. `import_statement` writes the
+valid rows through the ledger upserts. Every row gets a stable key - the
+statement's own ID when mapped, otherwise a hash of the row and its
+occurrence in the file - under the import's `source` name, so importing the
+same file again adds nothing.
+"""
+
+from __future__ import annotations
+
+import csv
+import hashlib
+import io
+import json
+import logging
+from collections import Counter
+from datetime import datetime
+from typing import Any
+
+from sentinel.database.base import cash_flow_hash
+from sentinel.identifiers import IdentifierService
+
+logger = logging.getLogger(__name__)
+
+TEMPLATES_KEY = "statement_import_templates"
+KINDS = ("trades", "cash_flows")
+REQUIRED_FIELDS = {
+    "trades": ("date", "symbol", "side", "quantity", "price"),
+    "cash_flows": ("date", "type", "amount", "currency"),
+}
+OPTIONAL_FIELDS = {
+    "trades": ("id", "commission", "commission_currency", "currency"),
+    "cash_flows": ("id", "comment"),
+}
+DEFAULT_SIDES = {"BUY": ["buy", "b"], "SELL": ["sell", "s"]}
+TEMPLATE_FIELDS = {"kind", "delimiter", "decimal", "date_format", "columns", "sides", "defaults"}
+MAX_ROWS = 20000
+MAX_ERRORS = 100
+SAMPLE_ROWS = 20
+
+BUILTIN_TEMPLATES: dict[str, dict[str, Any]] = {
+    "sentinel_trades": {
+        "kind": "trades",
+        "columns": {
+            field: field for field in REQUIRED_FIELDS["trades"] + OPTIONAL_FIELDS["trades"]
+        },
+    },
+    "sentinel_cash_flows": {
+        "kind": "cash_flows",
+        "columns": {
+            field: field for field in REQUIRED_FIELDS["cash_flows"] + OPTIONAL_FIELDS["cash_flows"]
+        },
+    },
+}
+
+
+def validate_template(raw: Any) -> dict[str, Any]:
+    """Validate one template and fill in its defaults.
+
+    Raises:
+        ValueError: On unknown fields or kinds, a missing required field, or
+            malformed delimiter, decimal, sides or defaults.
+    """
+    if not isinstance(raw, dict):
+        raise ValueError("template must be an object")
+    unknown = set(raw) - TEMPLATE_FIELDS
+    if unknown:
+        raise ValueError(f"template has unknown fields: {', '.join(sorted(unknown))}")
+    kind = raw.get("kind")
+    if kind not in KINDS:
+        raise ValueError(f"template kind must be one of {', '.join(KINDS)}")
+    delimiter = raw.get("delimiter", ",")
+    if not isinstance(delimiter, str) or len(delimiter) != 1:
+        raise ValueError("template delimiter must be one character")
+    decimal = raw.get("decimal", ".")
+    if decimal not in (".", ","):
+        raise ValueError("template decimal must be '.' or ','")
+    date_format = raw.get("date_format", "iso")
+    if not isinstance(date_format, str) or not date_format:
+        raise ValueError("template date_format must be a strptime format or 'iso'")
+
+    fields = REQUIRED_FIELDS[kind] + OPTIONAL_FIELDS[kind]
+    columns = raw.get("columns")
+    defaults = raw.get("defaults", {})
+    if not isinstance(columns, dict) or not all(isinstance(v, str) and v for v in columns.values()):
+        raise ValueError("template columns must map fields to column names")
+    if not isinstance(defaults, dict) or not all(isinstance(v, str | int | float) for v in defaults.values()):
+        raise ValueError("template defaults must map fields to constant values")
+    for name, mapping in (("columns", columns), ("defaults", defaults)):
+        unknown = set(mapping) - set(fields)
+        if unknown:
+            raise ValueError(f"template {name} has unknown {kind} fields: {', '.join(sorted(unknown))}")
+    missing = [field for field in REQUIRED_FIELDS[kind] if field not in columns and field not in defaults]
+    if missing:
+        raise ValueError(f"template does not map required fields: {', '.join(missing)}")
+
+    sides = raw.get("sides", DEFAULT_SIDES)
+    if (
+        not isinstance(sides, dict)
+        or set(sides) != {"BUY", "SELL"}
+        or not all(isinstance(v, list) and all(isinstance(x, str) for x in v) for v in sides.values())
+    ):
+        raise ValueError("template sides must list the values meaning BUY and SELL")
+    return {
+        "kind": kind,
+        "delimiter": delimiter,
+        "decimal": decimal,
+        "date_format": date_format,
+        "columns": dict(columns),
+        "sides": {side: [v.strip().lower() for v in values] for side, values in sides.items()},
+        "defaults": dict(defaults),
+    }
+
+
+def validate_templates(raw: Any) -> dict[str, dict[str, Any]]:
+    """Validate the `statement_import_templates` setting: template name -> template."""
+    if not isinstance(raw, dict):
+        raise ValueError(f"{TEMPLATES_KEY} must be an object of named templates")
+    templates = {}
+    for name, template in raw.items():
+        if name in BUILTIN_TEMPLATES:
+            raise ValueError(f"{TEMPLATES_KEY}: {name} is a built-in template")
+        try:
+            templates[name] = validate_template(template)
+        except ValueError as e:
+            raise ValueError(f"{TEMPLATES_KEY}.{name}: {e}") from e
+    return templates
+
+
+async def load_templates(settings) -> dict[str, dict[str, Any]]:
+    """Built-in and configured templates, validated."""
+    templates = {name: validate_template(t) for name, t in BUILTIN_TEMPLATES.items()}
+    stored = await settings.get(TEMPLATES_KEY, {}) or {}
+    for name, template in stored.items():
+        try:
+            templates[name] = validate_template(template)
+        except ValueError as e:
+            logger.warning(f"Ignoring statement import template {name}: {e}")
+    return templates
+
+
+def _number(value: str, decimal: str) -> float:
+    text = value.strip().replace(" ", "").replace("\u00a0", "")
+    if decimal == ",":
+        text = text.replace(".", "").replace(",", ".")
+    else:
+        text = text.replace(",", "")
+    return float(text)
+
+
+def _date(value: str, date_format: str) -> datetime:
+    text = value.strip()
+    if date_format == "iso":
+        return datetime.fromisoformat(text)
+    return datetime.strptime(text, date_format)
+
+
+def _field(row: dict[str, str], template: dict[str, Any], field: str) -> str:
+    column = template["columns"].get(field)
+    if column is not None and (row.get(column) or "").strip():
+        return row[column].strip()
+    return str(template["defaults"].get(field, ""))
+
+
+def _parse_row(row: dict[str, str], template: dict[str, Any]) -> dict[str, Any]:
+    """One statement row as ledger fields.
+
+    Raises:
+        ValueError: When a required field is empty or does not parse.
+    """
+    kind = template["kind"]
+    values = {f: _field(row, template, f) for f in REQUIRED_FIELDS[kind] + OPTIONAL_FIELDS[kind]}
+    empty = [f for f in REQUIRED_FIELDS[kind] if not values[f]]
+    if empty:
+        raise ValueError(f"empty {', '.join(empty)}")
+    try:
+        when = _date(values["date"], template["date_format"])
+    except ValueError as e:
+        raise ValueError(f"date {values['date']!r} does not match {template['date_format']}") from e
+
+    if kind == "cash_flows":
+        try:
+            amount = _number(values["amount"], template["decimal"])
+        except ValueError as e:
+            raise ValueError(f"amount {values['amount']!r} is not a number") from e
+        return {
+            "id": values["id"] or None,
+            "date": when.date().isoformat(),
+            "type_id": values["type"],
+            "amount": amount,
+            "currency": values["currency"].upper(),
+            "comment": values["comment"],
+        }
+
+    side = next((s for s, names in template["sides"].items() if values["side"].lower() in names), None)
+    if side is None:
+        raise ValueError(f"side {values['side']!r} is neither BUY nor SELL")
+    numbers = {}
+    for field in ("quantity", "price", "commission"):
+        if field == "commission" and not values[field]:
+            numbers[field] = 0.0
+            continue
+        try:
+            numbers[field] = _number(values[field], template["decimal"])
+        except ValueError as e:
+            raise ValueError(f"{field} {values[field]!r} is not a number") from e
+    # Some statements sign quantities by side; the side column decides.
+    quantity = abs(numbers["quantity"])
+    if quantity == 0 or numbers["price"] <= 0:
+        raise ValueError("quantity and price must be positive")
+    currency = values["currency"].upper()
+    return {
+        "id": values["id"] or None,
+        "executed_at": int(when.timestamp()),
+        "date": when.isoformat(),
+        "symbol": values["symbol"],
+        "side": side,
+        "quantity": quantity,
+        "price": numbers["price"],
+        "commission": abs(numbers["commission"]),
+        "commission_currency": (values["commission_currency"] or currency or "EUR").upper(),
+        "currency": currency or None,
+    }
+
+
+def _row_key(source: str, parsed: dict[str, Any], occurrence: int) -> str:
+    if parsed["id"]:
+        return f"csv:{source}:{parsed['id']}"
+    content = json.dumps({k: v for k, v in parsed.items() if k != "id"}, sort_keys=True)
+    digest = hashlib.sha256(f"{content}#{occurrence}".encode()).hexdigest()[:16]
+    return f"csv:{source}:{digest}"
+
+
+def _trade_raw(parsed: dict[str, Any], key: str, source: str) -> dict[str, Any]:
+    # `curr_c` is where the ledger replay looks for a trade's settlement currency.
+    raw = {"source": f"csv:{source}", "import_key": key, **parsed}
+    if parsed["currency"]:
+        raw["curr_c"] = parsed["currency"]
+    return raw
+
+
+def _cash_flow_raw(parsed: dict[str, Any], key: str, source: str) -> dict[str, Any]:
+    return {"source": f"csv:{source}", "import_key": key, **parsed}
+
+
+class StatementImporter:
+    """Parses CSV statements with a template and imports them into the ledger."""
+
+    def __init__(self, db, identifiers: IdentifierService | None = None):
+        self._db = db
+        self._identifiers = identifiers or IdentifierService(db)
+
+    async def _parse(self, text: str, template: dict[str, Any], source: str) -> dict[str, Any]:
+        reader = csv.DictReader(io.StringIO(text.lstrip("\ufeff")), delimiter=template["delimiter"])
+        headers = reader.fieldnames or []
+        required = [
+            f for f in REQUIRED_FIELDS[template["kind"]] if f in template["columns"] and f not in template["defaults"]
+        ]
+        missing = sorted({template["columns"][f] for f in required if template["columns"][f] not in headers})
+        if missing:
+            raise ValueError(f"statement has no column {', '.join(repr(c) for c in missing)}")
+
+        rows: list[dict[str, Any]] = []
+        errors: list[dict[str, Any]] = []
+        invalid = 0
+        occurrences: Counter[str] = Counter()
+        unknown: set[str] = set()
+        for line, row in enumerate(reader, start=2):
+            if line - 1 > MAX_ROWS:
+                raise ValueError(f"statement has more than {MAX_ROWS} rows; split it")
+            if not any((value or "").strip() for value in row.values() if isinstance(value, str)):
+                continue
+            try:
+                parsed = _parse_row(row, template)
+            except ValueError as e:
+                invalid += 1
+                if len(errors) < MAX_ERRORS:
+                    errors.append({"line": line, "error": str(e)})
+                continue
+            content = json.dumps(parsed, sort_keys=True)
+            occurrences[content] += 1
+            key = _row_key(source, parsed, occurrences[content])
+            if template["kind"] == "trades":
+                symbol = await self._identifiers.resolve(parsed["symbol"])
+                if symbol is None:
+                    unknown.add(parsed["symbol"])
+                else:
+                    parsed["symbol"] = symbol
+                rows.append({"line": line, "key": key, "row": parsed, "raw": _trade_raw(parsed, key, source)})
+            else:
+                raw = _cash_flow_raw(parsed, key, source)
+                rows.append({"line": line, "key": cash_flow_hash(raw), "row": parsed, "raw": raw})
+
+        if template["kind"] == "trades":
+            existing = await self._db.existing_trade_ids([r["key"] for r in rows])
+        else:
+            existing = await self._db.existing_cash_flow_hashes([r["key"] for r in rows])
+        for r in rows:
+            r["new"] = r["key"] not in existing
+        return {"rows": rows, "errors": errors, "invalid": invalid, "unknown_symbols": sorted(unknown)}
+
+    async def preview(self, text: str, template: dict[str, Any], source: str) -> dict[str, Any]:
+        """Parse and validate a statement without writing anything.
+
+        Returns:
+            {"kind", "source", "valid", "invalid", "new", "existing", "errors",
+             "unknown_symbols", "sample"}: `errors` has at most MAX_ERRORS
+            line errors, `sample` the first SAMPLE_ROWS parsed rows with a
+            `new` flag.
+
+        Raises:
+            ValueError: When a required field's column is missing or the file is too large.
+        """
+        parsed = await self._parse(text, template, source)
+        rows = parsed["rows"]
+        new = sum(1 for r in rows if r["new"])
+        return {
+            "kind": template["kind"],
+            "source": source,
+            "valid": len(rows),
+            "invalid": parsed["invalid"],
+            "new": new,
+            "existing": len(rows) - new,
+            "errors": parsed["errors"],
+            "unknown_symbols": parsed["unknown_symbols"],
+            "sample": [{"line": r["line"], "new": r["new"], **r["row"]} for r in rows[:SAMPLE_ROWS]],
+        }
+
+    async def import_statement(self, text: str, template: dict[str, Any], source: str) -> dict[str, Any]:
+        """Import the valid rows of a statement; rows already imported are skipped.
+
+        Returns:
+            The preview of the statement as it was before the import, plus
+            `imported`, the number of rows written.
+        """
+        parsed = await self._parse(text, template, source)
+        imported = 0
+        for r in parsed["rows"]:
+            if not r["new"]:
+                continue
+            row = r["row"]
+            if template["kind"] == "trades":
+                row_id = await self._db.upsert_trade(
+                    broker_trade_id=r["key"],
+                    symbol=row["symbol"],
+                    side=row["side"],
+                    quantity=row["quantity"],
+                    price=row["price"],
+                    executed_at=row["executed_at"],
+                    raw_data=r["raw"],
+                    commission=row["commission"],
+                    commission_currency=row["commission_currency"],
+                )
+            else:
+                row_id = await self._db.upsert_cash_flow(
+                    date=row["date"],
+                    type_id=row["type_id"],
+                    amount=row["amount"],
+                    currency=row["currency"],
+                    comment=row["comment"],
+                    raw_data=r["raw"],
+                )
+            if row_id:
+                imported += 1
+        if imported:
+            await self._db.invalidate_planner_cache()
+        logger.info(f"Imported {imported} {template['kind']} from statement {source}")
+        rows = parsed["rows"]
+        new = sum(1 for r in rows if r["new"])
+        return {
+            "kind": template["kind"],
+            "source": source,
+            "valid": len(rows),
+            "invalid": parsed["invalid"],
+            "new": new,
+            "existing": len(rows) - new,
+            "imported": imported,
+            "errors": parsed["errors"],
+            "unknown_symbols": parsed["unknown_symbols"],
+        }
@@ -0,0 +1,129 @@
+"""
+systemd service integration (sd_notify).
+
+Under `Type=notify` systemd sets NOTIFY_SOCKET. The app reports READY=1
+once startup has finished and STOPPING=1 when shutdown begins. With
+`WatchdogSec=` set, systemd also passes WATCHDOG_USEC and restarts the
+service unless WATCHDOG=1 arrives within that time. The watchdog pings at a
+third of the interval, and only while the app is healthy: a hung event loop
+sends nothing and a stopped job scheduler is reported as unhealthy, so both
+lead to a restart.
+
+A restart can also be requested over the API. The process then shuts down
+gracefully and exits with RESTART_EXIT_CODE, which the unit restarts on.
+
+Outside systemd every notification is a no-op.
+
+Usage:
+    notify("READY=1")
+    watchdog = Watchdog(healthy=lambda: scheduler_running())
+    watchdog.start()
+"""
+
+from __future__ import annotations
+
+import asyncio
+import logging
+import os
+import signal
+import socket
+from typing import Callable
+
+logger = logging.getLogger(__name__)
+
+# EX_TEMPFAIL; matches RestartForceExitStatus= in systemd/sentinel.service.
+RESTART_EXIT_CODE = 75
+
+_restart_requested = False
+
+
+def notify(*states: str) -> bool:
+    """Send state lines (e.g. "READY=1") to systemd.
+
+    Returns:
+        True if a notification socket is set and the message was sent.
+    """
+    address = os.environ.get("NOTIFY_SOCKET")
+    if not address or not states:
+        return False
+    if address.startswith("@"):
+        # Abstract namespace socket.
+        address = "\0" + address[1:]
+    try:
+        with socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM | socket.SOCK_CLOEXEC) as sock:
+            sock.connect(address)
+            sock.sendall("\n".join(states).encode())
+    except OSError as e:
+        logger.warning(f"sd_notify failed: {e}")
+        return False
+    return True
+
+
+def supervised() -> bool:
+    """Whether systemd is listening for notifications from this process."""
+    return bool(os.environ.get("NOTIFY_SOCKET"))
+
+
+def watchdog_interval() -> float | None:
+    """Seconds between watchdog pings, or None when the watchdog is off."""
+    usec = os.environ.get("WATCHDOG_USEC")
+    pid = os.environ.get("WATCHDOG_PID")
+    if not usec or (pid and pid != str(os.getpid())):
+        return None
+    try:
+        timeout = int(usec) / 1_000_000
+    except ValueError:
+        return None
+    return timeout / 3 if timeout > 0 else None
+
+
+class Watchdog:
+    """Pings the systemd watchdog while `healthy()` holds."""
+
+    def __init__(self, healthy: Callable[[], bool], interval: float | None = None):
+        self._healthy = healthy
+        self._interval = interval if interval is not None else watchdog_interval()
+        self._task: asyncio.Task | None = None
+
+    @property
+    def enabled(self) -> bool:
+        return self._interval is not None
+
+    def start(self) -> None:
+        """Start pinging; a no-op when systemd has no watchdog configured."""
+        if self.enabled and self._task is None:
+            logger.info(f"systemd watchdog enabled, pinging every {self._interval:.0f}s")
+            self._task = asyncio.create_task(self._run())
+
+    async def stop(self) -> None:
+        if self._task:
+            self._task.cancel()
+            try:
+                await self._task
+            except asyncio.CancelledError:
+                pass
+            self._task = None
+
+    async def _run(self) -> None:
+        while True:
+            if self._healthy():
+                notify("WATCHDOG=1")
+            else:
+                logger.warning("Skipping systemd watchdog ping: job scheduler not running")
+            await asyncio.sleep(self._interval)
+
+
+def request_restart(delay: float = 0.5) -> None:
+    """Shut down gracefully after `delay` seconds and exit with RESTART_EXIT_CODE.
+
+    The delay lets the API response go out first. Uvicorn treats SIGTERM as
+    a graceful shutdown, so the app's lifespan cleanup still runs.
+    """
+    global _restart_requested
+    _restart_requested = True
+    asyncio.get_running_loop().call_later(delay, os.kill, os.getpid(), signal.SIGTERM)
+
+
+def restart_requested() -> bool:
+    """Whether the process is shutting down to be restarted."""
+    return _restart_requested
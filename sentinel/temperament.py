@@ -0,0 +1,228 @@
+"""
+Temperament questionnaire - derive strategy settings from a few questions.
+
+Seven multiple-choice questions score two traits between 0 and 1:
+
+- risk tolerance (horizon, reaction to losses, bearable loss, income
+  stability) sizes positions and the cash target
+- aggression (pace, dip entry, idle cash) sets how early and how often the
+  strategy buys opportunities
+
+Each trait is the mean of its answers. `derive_settings` maps the traits
+onto existing settings, with an explanation per setting; the middle answer
+on every question gives the shipped defaults.
+
+Every take is stored as a new version in `temperament_profiles`, so the
+questionnaire can be retaken and versions compared. Deriving settings
+never changes them; a version is applied explicitly.
+"""
+
+from __future__ import annotations
+
+from typing import Any
+
+# Bump when questions or options change; stored with each take.
+QUESTIONNAIRE_REVISION = 1
+
+QUESTIONS: list[dict[str, Any]] = [
+    {
+        "id": "horizon",
+        "trait": "risk_tolerance",
+        "text": "When will you need most of this money?",
+        "options": [
+            {"id": "short", "text": "Within 3 years", "score": 0.0},
+            {"id": "medium", "text": "In 3 to 10 years", "score": 0.5},
+            {"id": "long", "text": "Not for 10 years or more", "score": 1.0},
+        ],
+    },
+    {
+        "id": "loss_reaction",
+        "trait": "risk_tolerance",
+        "text": "The portfolio falls 20% in a month. What do you do?",
+        "options": [
+            {"id": "sell", "text": "Sell to stop the losses", "score": 0.0},
+            {"id": "hold", "text": "Hold and wait", "score": 0.5},
+            {"id": "buy_more", "text": "Buy more while it is cheap", "score": 1.0},
+        ],
+    },
+    {
+        "id": "max_loss",
+        "trait": "risk_tolerance",
+        "text": "What yearly loss could you sit through without changing plans?",
+        "options": [
+            {"id": "10", "text": "Up to 10%", "score": 0.0},
+            {"id": "25", "text": "Up to 25%", "score": 0.5},
+            {"id": "40", "text": "40% or more", "score": 1.0},
+        ],
+    },
+    {
+        "id": "income",
+        "trait": "risk_tolerance",
+        "text": "How stable is your income outside this portfolio?",
+        "options": [
+            {"id": "unstable", "text": "Irregular or at risk", "score": 0.0},
+            {"id": "stable", "text": "Stable", "score": 0.5},
+            {"id": "very_stable", "text": "Very stable, with savings aside", "score": 1.0},
+        ],
+    },
+    {
+        "id": "pace",
+        "trait": "aggression",
+        "text": "How fast should new money be put to work?",
+        "options": [
+            {"id": "patient", "text": "Slowly, waiting for good prices", "score": 0.0},
+            {"id": "balanced", "text": "Steadily", "score": 0.5},
+            {"id": "quick", "text": "As soon as possible", "score": 1.0},
+        ],
+    },
+    {
+        "id": "dip_entry",
+        "trait": "aggression",
+        "text": "How far should a good security fall before buying?",
+        "options": [
+            {"id": "deep", "text": "Deep falls only", "score": 0.0},
+            {"id": "moderate", "text": "A moderate fall", "score": 0.5},
+            {"id": "early", "text": "The first small dip", "score": 1.0},
+        ],
+    },
+    {
+        "id": "cash_idle",
+        "trait": "aggression",
+        "text": "How do you feel about cash waiting for an opportunity?",
+        "options": [
+            {"id": "fine", "text": "Fine, good entries are worth waiting for", "score": 0.0},
+            {"id": "some", "text": "Fine for a few weeks", "score": 0.5},
+            {"id": "dislike", "text": "Idle cash bothers me", "score": 1.0},
+        ],
+    },
+]
+
+TRAITS = ("risk_tolerance", "aggression")
+
+
+def _lerp(low: float, high: float, t: float) -> float:
+    return low + (high - low) * t
+
+
+def validate_answers(data: Any) -> dict[str, str]:
+    """Validate questionnaire answers: every question id mapped to one of its option ids.
+
+    Raises:
+        ValueError: If an answer is missing, unknown or malformed.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("answers must be an object")
+    known = {q["id"] for q in QUESTIONS}
+    unknown = sorted(set(data) - known)
+    if unknown:
+        raise ValueError(f"unknown questions: {', '.join(unknown)}")
+    answers: dict[str, str] = {}
+    for question in QUESTIONS:
+        answer = data.get(question["id"])
+        options = [o["id"] for o in question["options"]]
+        if answer not in options:
+            raise ValueError(f"'{question['id']}' must be one of: {', '.join(options)}")
+        answers[question["id"]] = answer
+    return answers
+
+
+def temperament_scores(answers: dict[str, str]) -> dict[str, float]:
+    """Score each trait between 0 and 1 as the mean of its answers."""
+    scores: dict[str, list[float]] = {trait: [] for trait in TRAITS}
+    for question in QUESTIONS:
+        option = next(o for o in question["options"] if o["id"] == answers[question["id"]])
+        scores[question["trait"]].append(option["score"])
+    return {trait: round(sum(values) / len(values), 4) for trait, values in scores.items()}
+
+
+def derive_settings(scores: dict[str, float]) -> list[dict[str, Any]]:
+    """Derive setting values from the trait scores.
+
+    Returns:
+        [{"key", "value", "explanation"}] in a stable order
+    """
+    risk = min(1.0, max(0.0, float(scores["risk_tolerance"])))
+    aggression = min(1.0, max(0.0, float(scores["aggression"])))
+    r_pct = round(risk * 100)
+    a_pct = round(aggression * 100)
+
+    max_position = round(_lerp(15, 35, risk))
+    cash = round(max(0.0, 10 * (1 - 2 * risk)), 1)
+    t1 = round(_lerp(-0.14, -0.06, aggression), 3)
+    min_opp = round(_lerp(0.65, 0.45, aggression), 2)
+    wait_days = round(_lerp(50, 10, aggression))
+    buys = 1 + round(max(0.0, aggression - 0.5) * 4)
+
+    return [
+        {
+            "key": "max_position_pct",
+            "value": max_position,
+            "explanation": f"Risk tolerance {r_pct}% allows up to {max_position}% in one position (15% to 35%).",
+        },
+        {
+            "key": "target_cash_pct",
+            "value": cash,
+            "explanation": (
+                f"Risk tolerance {r_pct}% keeps {cash}% in cash; only below 50% is a cash cushion held (up to 10%)."
+            ),
+        },
+        {
+            "key": "strategy_entry_t1_dd",
+            "value": t1,
+            "explanation": f"Aggression {a_pct}% starts buying opportunities after a {abs(t1):.0%} fall (14% to 6%).",
+        },
+        {
+            "key": "strategy_entry_t2_dd",
+            "value": round(t1 - 0.06, 3),
+            "explanation": f"The second entry tier sits 6 points deeper, at a {abs(t1 - 0.06):.0%} fall.",
+        },
+        {
+            "key": "strategy_entry_t3_dd",
+            "value": round(t1 - 0.12, 3),
+            "explanation": f"The third entry tier sits 12 points deeper, at a {abs(t1 - 0.12):.0%} fall.",
+        },
+        {
+            "key": "strategy_min_opp_score",
+            "value": min_opp,
+            "explanation": f"Aggression {a_pct}% accepts opportunities scoring {min_opp} or more (0.65 to 0.45).",
+        },
+        {
+            "key": "strategy_fallback_wait_days",
+            "value": wait_days,
+            "explanation": (
+                f"Aggression {a_pct}% waits {wait_days} days for a good entry before buying anyway (50 to 10)."
+            ),
+        },
+        {
+            "key": "strategy_max_opportunity_buys_per_cycle",
+            "value": buys,
+            "explanation": f"Aggression {a_pct}% allows {buys} opportunity buy(s) per cycle; above 50% adds more.",
+        },
+        {
+            "key": "strategy_max_new_opportunity_buys_per_cycle",
+            "value": buys,
+            "explanation": f"The same {buys} buy(s) per cycle apply to securities not yet held.",
+        },
+    ]
+
+
+def diff_profiles(old: dict | None, new: dict) -> dict[str, Any]:
+    """Compare two stored profiles: changed answers, scores and settings.
+
+    Returns:
+        {"from_version", "to_version", "answers", "scores", "settings"} where each
+        section lists {"key", "from", "to"} for the entries that differ
+    """
+
+    def changes(before: dict, after: dict) -> list[dict[str, Any]]:
+        keys = list(after) + [k for k in before if k not in after]
+        return [{"key": k, "from": before.get(k), "to": after.get(k)} for k in keys if before.get(k) != after.get(k)]
+
+    old = old or {}
+    return {
+        "from_version": old.get("version"),
+        "to_version": new.get("version"),
+        "answers": changes(old.get("answers") or {}, new.get("answers") or {}),
+        "scores": changes(old.get("scores") or {}, new.get("scores") or {}),
+        "settings": changes(old.get("settings") or {}, new.get("settings") or {}),
+    }
@@ -0,0 +1,107 @@
+"""
+Trading pause - a global kill switch for order placement.
+
+While trading is paused no order reaches the broker: the `trading:execute`
+job is skipped, the buy/sell and approve endpoints answer 409, and the broker
+refuses orders from any other path (currency exchange, scripts). Syncs,
+planning and everything else read-only keep running.
+
+A pause either expires on its own after `hours`, or lasts until it is
+resumed. Like the LED display mode override, an expired pause needs no
+cleanup; it simply stops being reported as active.
+
+Usage:
+    pause = TradingPause()
+    await pause.pause(hours=24, reason="Earnings week")
+    if await pause.is_paused():
+        ...
+    await pause.resume()
+"""
+
+from __future__ import annotations
+
+import math
+import time
+from typing import Any
+
+from sentinel.settings import Settings
+
+PAUSE_KEY = "trading_pause"
+DEFAULT_PAUSE_HOURS = 24
+MAX_PAUSE_HOURS = 30 * 24
+MAX_REASON_LENGTH = 200
+
+# Jobs that submit orders; the scheduler skips them while paused.
+TRADING_JOBS = frozenset({"trading:execute"})
+
+
+def _not_paused() -> dict[str, Any]:
+    return {"paused": False, "paused_at_ts": None, "expires_at_ts": None, "reason": None}
+
+
+def describe(status: dict[str, Any]) -> str:
+    """Human-readable reason used in API errors and logs."""
+    if status.get("expires_at_ts") is None:
+        return "Trading is paused until resumed"
+    return f"Trading is paused until {time.strftime('%Y-%m-%d %H:%M', time.localtime(status['expires_at_ts']))}"
+
+
+class TradingPause:
+    """Reads and changes the global trading pause."""
+
+    def __init__(self, settings: Settings | None = None):
+        self._settings = settings or Settings()
+
+    async def status(self, now_ts: int | None = None) -> dict[str, Any]:
+        """Get the active pause, or `paused: False` if unset or expired."""
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        raw = await self._settings.get(PAUSE_KEY)
+        if not isinstance(raw, dict) or not raw.get("paused"):
+            return _not_paused()
+        expires_at_ts = raw.get("expires_at_ts")
+        if expires_at_ts is not None and (not isinstance(expires_at_ts, int) or expires_at_ts <= now_ts):
+            return _not_paused()
+        return {
+            "paused": True,
+            "paused_at_ts": raw.get("paused_at_ts"),
+            "expires_at_ts": expires_at_ts,
+            "reason": raw.get("reason"),
+        }
+
+    async def is_paused(self, now_ts: int | None = None) -> bool:
+        return (await self.status(now_ts=now_ts))["paused"]
+
+    async def pause(
+        self,
+        hours: float | None = DEFAULT_PAUSE_HOURS,
+        reason: str | None = None,
+        now_ts: int | None = None,
+    ) -> dict[str, Any]:
+        """Pause trading for `hours` hours, or until resumed when `hours` is None.
+
+        Raises:
+            ValueError: If hours or reason is malformed.
+        """
+        if hours is not None:
+            if isinstance(hours, bool) or not isinstance(hours, int | float) or not math.isfinite(hours):
+                raise ValueError("hours must be a number or null")
+            if not 0 < hours <= MAX_PAUSE_HOURS:
+                raise ValueError(f"hours must be in (0, {MAX_PAUSE_HOURS}]")
+        if reason is not None:
+            if not isinstance(reason, str):
+                raise ValueError("reason must be a string")
+            reason = reason.strip()[:MAX_REASON_LENGTH] or None
+
+        now_ts = int(time.time()) if now_ts is None else now_ts
+        pause = {
+            "paused": True,
+            "paused_at_ts": now_ts,
+            "expires_at_ts": None if hours is None else now_ts + int(round(hours * 3600)),
+            "reason": reason,
+        }
+        await self._settings.set(PAUSE_KEY, pause)
+        return pause
+
+    async def resume(self) -> None:
+        """Lift the pause immediately."""
+        await self._settings.set(PAUSE_KEY, None)
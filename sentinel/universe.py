@@ -1,4 +1,14 @@
-"""Freedom24 Favorites to Sentinel universe reconciliation."""
+"""Freedom24 Favorites to Sentinel universe reconciliation.
+
+A security leaving the universe is archived, never deleted: it turns
+inactive (hidden from planning and the active universe) with buys and sells
+disabled, and `archived_at` / `archive_reason` record when and why. Its
+prices, trades, dividends and other history stay. Archiving happens when a
+ticker without a position leaves Favorites (`removed`), and when a position
+kept after its ticker left Favorites is fully closed (`position_closed`).
+Adding the ticker back (POST /api/securities or Favorites) re-activates it
+and clears the archive fields.
+"""
 
 from __future__ import annotations
 
@@ -7,10 +17,14 @@ from dataclasses import dataclass, field
 from datetime import datetime, timezone
 from typing import Any
 
+from sentinel.identifiers import IdentifierService, load_security_merges
+
 logger = logging.getLogger(__name__)
 
 FREEDOM24_UNIVERSE_SOURCE = "freedom24_default"
 BROKER_POSITION_UNIVERSE_SOURCE = "broker_position"
+ARCHIVE_REMOVED = "removed"
+ARCHIVE_POSITION_CLOSED = "position_closed"
 
 
 @dataclass
@@ -169,6 +183,8 @@ async def import_security_from_broker(
         "allow_buy": 1,
         "allow_sell": 1,
         "universe_source": universe_source,
+        "archived_at": None,
+        "archive_reason": None,
     }
     if universe_last_seen_at is not None:
         security_data["universe_last_seen_at"] = universe_last_seen_at
@@ -180,6 +196,7 @@ async def import_security_from_broker(
     await db.upsert_security(symbol, **security_data)
     if broker_info:
         await db.update_security_metadata(symbol, broker_info, market_id)
+    IdentifierService(db).invalidate()
 
     prices_count = 0
     if fetch_prices:
@@ -221,7 +238,7 @@ async def apply_removed_from_favorites_rule(db, symbol: str) -> dict[str, Any]:
             "quantity": quantity,
         }
 
-    await db.upsert_security(symbol, active=0, allow_buy=0, allow_sell=0)
+    archived_at = await archive_security(db, symbol, ARCHIVE_REMOVED)
     return {
         "symbol": symbol,
         "active": False,
@@ -229,9 +246,40 @@ async def apply_removed_from_favorites_rule(db, symbol: str) -> dict[str, Any]:
         "allow_sell": False,
         "retained_position": False,
         "quantity": 0.0,
+        "archived_at": archived_at,
     }
 
 
+async def archive_security(db, symbol: str, reason: str) -> str:
+    """Take a security out of the active universe, keeping its history; returns archived_at."""
+    archived_at = utc_now_iso()
+    await db.upsert_security(
+        symbol, active=0, allow_buy=0, allow_sell=0, archived_at=archived_at, archive_reason=reason
+    )
+    IdentifierService(db).invalidate()
+    return archived_at
+
+
+async def archive_closed_positions(db, symbols: list[str]) -> list[str]:
+    """Archive securities kept only for a position that is now closed; returns their symbols."""
+    archived = []
+    for symbol in symbols:
+        security = await db.get_security(symbol)
+        if (
+            not security
+            or _as_int_flag(security.get("active")) == 0
+            or security.get("universe_source") != BROKER_POSITION_UNIVERSE_SOURCE
+            or _as_int_flag(security.get("allow_buy"), default=1) != 0
+            or await _position_quantity(db, symbol) != 0
+        ):
+            continue
+        await archive_security(db, symbol, ARCHIVE_POSITION_CLOSED)
+        archived.append(symbol)
+    if archived:
+        logger.info("Archived %s after their positions closed", ", ".join(archived))
+    return archived
+
+
 async def reconcile_universe_from_freedom24_default_list(db, broker) -> UniverseReconciliationResult:
     """Reconcile Sentinel's active universe against the Freedom24 default list."""
     result = UniverseReconciliationResult()
@@ -242,7 +290,9 @@ async def reconcile_universe_from_freedom24_default_list(db, broker) -> Universe
         result.skipped.append("default_list")
         return result
 
-    favorite_symbols = _tickers_from_default_list(stock_list)
+    # Favorites still listing a merged duplicate count for its canonical record.
+    merged = {m["symbol"]: m["canonical"] for m in await load_security_merges(db)}
+    favorite_symbols = {merged.get(symbol, symbol) for symbol in _tickers_from_default_list(stock_list)}
     if not favorite_symbols:
         logger.warning("Skipping universe reconciliation: default Freedom24 list has no tickers")
         result.skipped.append("empty_default_list")
@@ -4,9 +4,122 @@ Fee Calculator - Single source of truth for transaction fee calculations.
 Usage:
     calculator = FeeCalculator(settings)
     fee = await calculator.calculate(1000.0)
+    fee = await calculator.calculate(1000.0, market_id="NYSE", currency="USD")
     breakdown = await calculator.calculate_batch(trades)
+
+Costs come from a per-exchange/currency cost model. Each profile has a fixed
+fee, a percentage fee, a minimum commission (floor for fixed + percentage)
+and an FX conversion spread charged on non-EUR trades. Profiles are stored in
+the `transaction_cost_profiles` setting keyed by Tradernet market_id or
+currency (market_id wins); fields a profile omits, and securities without a
+profile, use the global transaction_fee_* settings.
+
+The bid/ask spread is not a broker fee; `spread_cost` prices crossing half
+of a quoted spread, and batches report it next to the fees.
 """
 
+from __future__ import annotations
+
+import math
+from dataclasses import dataclass
+from typing import Any
+
+COST_PROFILES_KEY = "transaction_cost_profiles"
+
+# Profile field -> global setting it defaults to.
+PROFILE_FIELDS = {
+    "fixed_fee": "transaction_fee_fixed",
+    "fee_percent": "transaction_fee_percent",
+    "fx_spread_percent": "transaction_fx_spread_percent",
+    "min_commission": "transaction_min_commission",
+}
+
+
+@dataclass(frozen=True)
+class CostProfile:
+    """Transaction costs for one exchange or currency. Percentages are decimals."""
+
+    fixed_fee: float = 0.0
+    fee_pct: float = 0.0
+    fx_spread_pct: float = 0.0
+    min_commission: float = 0.0
+
+    def commission(self, trade_value_eur: float) -> float:
+        return max(self.min_commission, self.fixed_fee + trade_value_eur * self.fee_pct)
+
+    def cost(self, trade_value_eur: float, currency: str = "EUR") -> float:
+        fx_cost = trade_value_eur * self.fx_spread_pct if currency != "EUR" else 0.0
+        return self.commission(trade_value_eur) + fx_cost
+
+    def linear_terms(self, currency: str = "EUR") -> tuple[float, float]:
+        """(fixed, pct) approximation for callers that take a linear fee.
+
+        The minimum commission becomes the fixed floor and the FX spread is
+        folded into the percentage for non-EUR trades.
+        """
+        fx_pct = self.fx_spread_pct if currency != "EUR" else 0.0
+        return max(self.fixed_fee, self.min_commission), self.fee_pct + fx_pct
+
+
+def spread_cost(trade_value_eur: float, spread_bps: float | None) -> float:
+    """Cost of crossing half a bid/ask spread (in basis points of the mid) on a trade."""
+    if not spread_bps or spread_bps <= 0:
+        return 0.0
+    return trade_value_eur * spread_bps / 20000
+
+
+class TransactionCostModel:
+    """Resolves the cost profile for a trade and prices it."""
+
+    def __init__(self, default: CostProfile, profiles: dict[str, CostProfile] | None = None):
+        self.default = default
+        self.profiles = dict(profiles or {})
+
+    def profile_for(self, market_id: Any = None, currency: str | None = None) -> CostProfile:
+        if market_id is not None and str(market_id) in self.profiles:
+            return self.profiles[str(market_id)]
+        if currency and currency in self.profiles:
+            return self.profiles[currency]
+        return self.default
+
+    def cost(self, trade_value_eur: float, market_id: Any = None, currency: str | None = "EUR") -> float:
+        currency = currency or "EUR"
+        return self.profile_for(market_id, currency).cost(trade_value_eur, currency)
+
+
+def validate_cost_profiles(raw: Any) -> dict[str, dict[str, float]]:
+    """Validate a `transaction_cost_profiles` value.
+
+    Raises:
+        ValueError: If a key or field is malformed.
+    """
+    if raw is None:
+        return {}
+    if not isinstance(raw, dict):
+        raise ValueError("cost profiles must be an object keyed by market_id or currency")
+    normalized: dict[str, dict[str, float]] = {}
+    for key, profile in raw.items():
+        if not isinstance(key, str) or not key.strip():
+            raise ValueError("cost profile keys must be non-empty strings")
+        if not isinstance(profile, dict):
+            raise ValueError(f"cost profile {key!r} must be an object")
+        unknown = sorted(set(profile) - set(PROFILE_FIELDS))
+        if unknown:
+            raise ValueError(f"cost profile {key!r} has unknown field(s): {unknown}")
+        fields: dict[str, float] = {}
+        for field_name, value in profile.items():
+            if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value) or value < 0:
+                raise ValueError(f"cost profile {key!r}: {field_name} must be a non-negative number")
+            fields[field_name] = float(value)
+        normalized[key.strip()] = fields
+    return normalized
+
+
+def _number(value: Any, default: float) -> float:
+    if isinstance(value, bool) or not isinstance(value, int | float):
+        return default
+    return float(value)
+
 
 class FeeCalculator:
     """Calculates transaction fees for trades."""
@@ -46,18 +159,46 @@ class FeeCalculator:
         pct_fee = await settings.get("transaction_fee_percent", 0.2) / 100
         return fixed_fee, pct_fee
 
-    async def calculate(self, trade_value_eur: float) -> float:
+    async def get_cost_model(self) -> TransactionCostModel:
+        """Build the per-exchange/currency cost model from settings."""
+        settings = await self._get_settings()
+        defaults = {
+            "fixed_fee": _number(await settings.get("transaction_fee_fixed", 2.0), 2.0),
+            "fee_percent": _number(await settings.get("transaction_fee_percent", 0.2), 0.2),
+            "fx_spread_percent": _number(await settings.get("transaction_fx_spread_percent", 0.0), 0.0),
+            "min_commission": _number(await settings.get("transaction_min_commission", 0.0), 0.0),
+        }
+
+        def profile(fields: dict[str, float]) -> CostProfile:
+            merged = {**defaults, **fields}
+            return CostProfile(
+                fixed_fee=merged["fixed_fee"],
+                fee_pct=merged["fee_percent"] / 100,
+                fx_spread_pct=merged["fx_spread_percent"] / 100,
+                min_commission=merged["min_commission"],
+            )
+
+        raw = await settings.get(COST_PROFILES_KEY, {})
+        try:
+            stored = validate_cost_profiles(raw if isinstance(raw, dict) else None)
+        except ValueError:
+            stored = {}
+        return TransactionCostModel(profile({}), {key: profile(fields) for key, fields in stored.items()})
+
+    async def calculate(self, trade_value_eur: float, market_id: Any = None, currency: str = "EUR") -> float:
         """
         Calculate transaction cost for a given trade value.
 
         Args:
             trade_value_eur: Trade value in EUR
+            market_id: Tradernet market of the security, for exchange-specific fees
+            currency: Trading currency; non-EUR trades pay the FX spread
 
         Returns:
             Total transaction cost in EUR
         """
-        fixed_fee, pct_fee = await self.get_fee_config()
-        return fixed_fee + (trade_value_eur * pct_fee)
+        model = await self.get_cost_model()
+        return model.cost(trade_value_eur, market_id=market_id, currency=currency)
 
     def calculate_with_config(self, trade_value_eur: float, fixed_fee: float, pct_fee: float) -> float:
         """
@@ -81,7 +222,9 @@ class FeeCalculator:
         Calculate fees for a batch of trades.
 
         Args:
-            trades: List of trade dicts with 'action' and 'value_eur' keys
+            trades: List of trade dicts with 'action' and 'value_eur' keys,
+                optionally 'market_id' and 'currency' for exchange-specific fees,
+                and 'spread_bps' for the bid/ask spread cost
 
         Returns:
             Dict with fee breakdown:
@@ -93,28 +236,33 @@ class FeeCalculator:
                 'num_sells': int,
                 'total_buy_value': float,
                 'total_sell_value': float,
+                'spread_costs': float,  # half-spread cost, not part of total_fees
             }
         """
-        fixed_fee, pct_fee = await self.get_fee_config()
+        model = await self.get_cost_model()
 
         num_buys = 0
         num_sells = 0
         total_buy_value = 0.0
         total_sell_value = 0.0
+        buy_fees = 0.0
+        sell_fees = 0.0
+        spread_costs = 0.0
 
         for trade in trades:
             action = trade.get("action", "")
             value = abs(trade.get("value_eur", 0))
+            fee = model.cost(value, market_id=trade.get("market_id"), currency=trade.get("currency", "EUR"))
+            spread_costs += spread_cost(value, trade.get("spread_bps"))
 
             if action == "buy":
                 num_buys += 1
                 total_buy_value += value
+                buy_fees += fee
             elif action == "sell":
                 num_sells += 1
                 total_sell_value += value
-
-        buy_fees = num_buys * fixed_fee + total_buy_value * pct_fee
-        sell_fees = num_sells * fixed_fee + total_sell_value * pct_fee
+                sell_fees += fee
 
         return {
             "total_fees": buy_fees + sell_fees,
@@ -124,4 +272,5 @@ class FeeCalculator:
             "num_sells": num_sells,
             "total_buy_value": total_buy_value,
             "total_sell_value": total_sell_value,
+            "spread_costs": spread_costs,
         }
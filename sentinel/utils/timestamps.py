@@ -0,0 +1,16 @@
+"""
+Timestamp formatting shared by the API routers.
+
+Usage:
+    to_iso_utc(1792130400)  # "2026-10-16T06:00:00+00:00"
+    to_iso_utc(None)        # None
+"""
+
+from datetime import datetime, timezone
+
+
+def to_iso_utc(ts: int | None) -> str | None:
+    """ISO 8601 UTC time for a Unix timestamp, or None when there is none."""
+    if ts is None:
+        return None
+    return datetime.fromtimestamp(ts, tz=timezone.utc).isoformat()
@@ -0,0 +1,17 @@
+"""Shared fixtures."""
+
+import pytest
+
+from sentinel.clock import FakeClock
+from sentinel.mock_broker import DEFAULT_START, MockBroker
+
+
+@pytest.fixture
+def mock_clock():
+    return FakeClock(DEFAULT_START)
+
+
+@pytest.fixture
+def mock_broker(mock_clock):
+    """A connected MockBroker on `mock_clock`, with no securities or cash."""
+    return MockBroker(clock=mock_clock)
@@ -1,10 +1,23 @@
 """Tests for jobs/market.py - Market checker."""
 
+from datetime import datetime, timezone
 from unittest.mock import AsyncMock
 
 import pytest
 
-from sentinel.jobs.market import BrokerMarketChecker
+from sentinel.jobs.market import (
+    UNKNOWN_MARKET,
+    BrokerMarketChecker,
+    market_close_state_key,
+    markets_due_after_close,
+    next_close,
+    next_open,
+    session_at,
+)
+
+
+def _utc(*args) -> datetime:
+    return datetime(*args, tzinfo=timezone.utc)
 
 
 class TestBrokerMarketChecker:
@@ -117,3 +130,96 @@ class TestBrokerMarketChecker:
 
         await checker.ensure_fresh()
         mock_broker.get_market_status.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_market_states_by_id(self, mock_broker):
+        """Verify market_states maps broker market ids to open/closed."""
+        checker = BrokerMarketChecker(mock_broker)
+        await checker.refresh()
+
+        assert checker.market_states() == {"1": True, "2": False, "3": True}
+
+
+class TestMarketsDueAfterClose:
+    """Tests for per-market close tracking."""
+
+    @staticmethod
+    def _checker(states: dict[str, bool]):
+        checker = BrokerMarketChecker(AsyncMock())
+        checker._market_data = {f"M{i}": {"i": i, "s": "OPEN" if o else "CLOSED"} for i, o in states.items()}
+        return checker
+
+    @pytest.mark.asyncio
+    async def test_closed_markets_not_yet_run_are_due(self):
+        db = AsyncMock()
+        db.get_planner_state = AsyncMock(return_value=["2"])
+        checker = self._checker({1: True, 2: False, 3: False})
+
+        due, done = await markets_due_after_close(db, checker, "sync:prices")
+
+        assert due == {"3"}
+        assert done == {"2"}
+        db.get_planner_state.assert_awaited_once_with(market_close_state_key("sync:prices"), [])
+
+    @pytest.mark.asyncio
+    async def test_reopened_market_is_due_after_next_close(self):
+        db = AsyncMock()
+        db.get_planner_state = AsyncMock(return_value=["1"])
+
+        due, done = await markets_due_after_close(db, self._checker({1: True, 2: True}), "sync:prices")
+        assert due == set()
+        assert done == set()
+
+        db.get_planner_state = AsyncMock(return_value=sorted(done))
+        due, _ = await markets_due_after_close(db, self._checker({1: False, 2: True}), "sync:prices")
+        assert due == {"1"}
+
+    @pytest.mark.asyncio
+    async def test_unknown_market_is_due_once_everything_closed(self):
+        db = AsyncMock()
+        db.get_planner_state = AsyncMock(return_value=[])
+
+        due, _ = await markets_due_after_close(db, self._checker({1: False, 2: True}), "sync:prices")
+        assert UNKNOWN_MARKET not in due
+
+        due, _ = await markets_due_after_close(db, self._checker({1: False, 2: False}), "sync:prices")
+        assert due == {"1", "2", UNKNOWN_MARKET}
+
+    @pytest.mark.asyncio
+    async def test_no_market_data_means_nothing_due(self):
+        db = AsyncMock()
+        db.get_planner_state = AsyncMock(return_value=[])
+
+        assert await markets_due_after_close(db, self._checker({}), "sync:prices") == (set(), set())
+
+
+class TestExchangeHours:
+    """Projected sessions follow each exchange's own DST rules."""
+
+    def test_us_open_moves_with_us_dst(self):
+        assert next_open("NASDAQ", _utc(2026, 2, 10, 0, 0)) == _utc(2026, 2, 10, 14, 30)
+        assert next_open("NASDAQ", _utc(2026, 3, 10, 0, 0)) == _utc(2026, 3, 10, 13, 30)
+
+    def test_spring_mismatch_week(self):
+        """US clocks change on 8 March 2026, Europe's on 29 March."""
+        assert next_close("XETRA", _utc(2026, 3, 10, 12, 0)) == _utc(2026, 3, 10, 16, 30)
+        assert next_close("XETRA", _utc(2026, 3, 31, 12, 0)) == _utc(2026, 3, 31, 15, 30)
+        assert next_open("NASDAQ", _utc(2026, 3, 31, 0, 0)) == _utc(2026, 3, 31, 13, 30)
+
+    def test_autumn_mismatch_week(self):
+        """Europe falls back on 25 October 2026, the US on 1 November."""
+        assert next_close("XETRA", _utc(2026, 10, 27, 12, 0)) == _utc(2026, 10, 27, 16, 30)
+        assert next_open("NASDAQ", _utc(2026, 10, 27, 0, 0)) == _utc(2026, 10, 27, 13, 30)
+        assert next_open("NASDAQ", _utc(2026, 11, 3, 0, 0)) == _utc(2026, 11, 3, 14, 30)
+
+    def test_exchange_without_dst(self):
+        assert next_open("HKEX", _utc(2026, 7, 7, 0, 0)) == _utc(2026, 7, 7, 1, 30)
+        assert next_open("HKEX", _utc(2026, 12, 8, 0, 0)) == _utc(2026, 12, 8, 1, 30)
+
+    def test_weekend_skips_to_monday(self):
+        assert next_open("LSE", _utc(2026, 10, 16, 17, 0)) == _utc(2026, 10, 19, 7, 0)
+
+    def test_session_at(self):
+        assert session_at("LSE", _utc(2026, 7, 7, 12, 0)) == (_utc(2026, 7, 7, 7, 0), _utc(2026, 7, 7, 15, 30))
+        assert session_at("LSE", _utc(2026, 7, 7, 15, 30)) is None
+        assert session_at("LSE", _utc(2026, 7, 11, 12, 0)) is None
@@ -192,6 +192,119 @@ class TestRunnerStop:
         assert runner._scheduler is None
 
 
+class TestShutdownDrain:
+    """Tests for draining running jobs on shutdown."""
+
+    @pytest.mark.asyncio
+    async def test_drain_waits_for_jobs_within_timeout(self, mock_db):
+        """A job that finishes within its drain timeout completes normally."""
+        import asyncio
+
+        from sentinel.jobs import runner
+
+        async def slow_sync(**kwargs):
+            await asyncio.sleep(0.05)
+
+        with (
+            patch.dict(runner.TASK_REGISTRY, {"sync:trades": (slow_sync, [])}),
+            patch.object(runner, "_deps", {"db": mock_db}),
+        ):
+            task = asyncio.create_task(runner._run_task("sync:trades", {"market_timing": 0}, skip_timing_check=True))
+            await asyncio.sleep(0.01)
+            report = await runner.drain({"sync:trades": 1})
+
+        assert [e["job_type"] for e in report["drained"]] == ["sync:trades"]
+        assert report["abandoned"] == []
+        assert (await task)["status"] == "completed"
+        mock_db.set_planner_state.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_drain_cancels_and_queues_overdue_jobs(self, mock_db):
+        """A job past its drain timeout is cancelled, logged and queued to rerun."""
+        import asyncio
+
+        from sentinel.jobs import runner
+
+        async def stuck(**kwargs):
+            await asyncio.sleep(60)
+
+        mock_db.get_planner_state = AsyncMock(return_value=["sync:prices"])
+        with (
+            patch.dict(runner.TASK_REGISTRY, {"forecast:run": (stuck, [])}),
+            patch.object(runner, "_deps", {"db": mock_db}),
+        ):
+            task = asyncio.create_task(runner._run_task("forecast:run", {"market_timing": 0}, skip_timing_check=True))
+            await asyncio.sleep(0.01)
+            report = await runner.drain({"forecast:run": 0.01})
+
+        assert [e["job_type"] for e in report["abandoned"]] == ["forecast:run"]
+        assert task.cancelled()
+        assert runner._in_flight == {}
+        assert mock_db.log_job_execution.call_args.args[2] == "interrupted"
+        mock_db.set_planner_state.assert_called_once_with(runner.INTERRUPTED_JOBS_KEY, ["forecast:run", "sync:prices"])
+
+    @pytest.mark.asyncio
+    async def test_drain_covers_overlapping_runs_of_one_job(self, mock_db):
+        """A run_now overlapping a scheduled run of the same job is drained too."""
+        import asyncio
+
+        from sentinel.jobs import runner
+
+        async def stuck(**kwargs):
+            await asyncio.sleep(60)
+
+        mock_db.get_planner_state = AsyncMock(return_value=[])
+        with (
+            patch.dict(runner.TASK_REGISTRY, {"forecast:run": (stuck, [])}),
+            patch.object(runner, "_deps", {"db": mock_db}),
+        ):
+            tasks = [
+                asyncio.create_task(runner._run_task("forecast:run", {"market_timing": 0}, skip_timing_check=True))
+                for _ in range(2)
+            ]
+            await asyncio.sleep(0.01)
+            report = await runner.drain({"forecast:run": 0.01})
+
+        assert [e["job_type"] for e in report["abandoned"]] == ["forecast:run", "forecast:run"]
+        assert all(task.cancelled() for task in tasks)
+        assert runner._in_flight == {}
+        mock_db.set_planner_state.assert_called_once_with(runner.INTERRUPTED_JOBS_KEY, ["forecast:run"])
+
+    @pytest.mark.asyncio
+    async def test_interrupted_jobs_rerun_after_startup(self, mock_db):
+        """Jobs queued by the last drain run again, with their market timing."""
+        from sentinel.jobs import runner
+
+        task = AsyncMock()
+        mock_db.get_planner_state = AsyncMock(return_value=["sync:portfolio", "removed:job"])
+        with (
+            patch.dict(runner.TASK_REGISTRY, {"sync:portfolio": (task, [])}),
+            patch.object(runner, "_deps", {"db": mock_db}),
+        ):
+            await runner._rerun_interrupted()
+
+        mock_db.set_planner_state.assert_called_once_with(runner.INTERRUPTED_JOBS_KEY, [])
+        task.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_interrupted_trading_jobs_are_not_rerun(self, mock_db):
+        """A cancelled trade may already be at the broker; it waits for its next scheduled run."""
+        from sentinel.jobs import runner
+
+        trade, sync = AsyncMock(), AsyncMock()
+        mock_db.get_planner_state = AsyncMock(return_value=["trading:execute", "sync:portfolio"])
+        with (
+            patch.dict(runner.TASK_REGISTRY, {"trading:execute": (trade, []), "sync:portfolio": (sync, [])}),
+            patch.object(runner, "_deps", {"db": mock_db}),
+            patch.object(runner, "TradingPause") as pause,
+        ):
+            pause.return_value.is_paused = AsyncMock(return_value=False)
+            await runner._rerun_interrupted()
+
+        trade.assert_not_awaited()
+        sync.assert_awaited_once()
+
+
 class TestRunnerReschedule:
     """Tests for rescheduling jobs."""
 
@@ -377,6 +490,183 @@ class TestRunTask:
         assert runner._current_job is None
 
 
+class TestClockDriftAnnotation:
+    """Market timing decisions made while the clock is suspect are flagged."""
+
+    @staticmethod
+    def _setup(runner, mock_db, mock_market_checker, clock_state):
+        mock_db.get_planner_state = AsyncMock(return_value=clock_state)
+        runner._deps = {"db": mock_db, "broker": MagicMock(), "market_checker": mock_market_checker}
+        runner._current_job = None
+
+    @pytest.mark.asyncio
+    async def test_completed_run_is_annotated(self, mock_db, mock_market_checker):
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, mock_market_checker, {"offset_seconds": 95.4, "suspect": True})
+        mock_market_checker.is_any_market_open.return_value = True
+        task = AsyncMock()
+        with patch.dict(runner.TASK_REGISTRY, {"sync:quotes": (task, ["db", "broker"])}):
+            await runner._run_task("sync:quotes", {"market_timing": 2})
+
+        mock_db.log_job_execution.assert_awaited_once()
+        assert mock_db.log_job_execution.await_args.args[3] == "suspected clock drift (+95s)"
+
+    @pytest.mark.asyncio
+    async def test_skip_is_annotated(self, mock_db, mock_market_checker):
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, mock_market_checker, {"offset_seconds": -40.0, "suspect": True})
+        mock_market_checker.is_any_market_open.return_value = False
+
+        result = await runner._run_task("trading:execute", {"market_timing": 2})
+
+        assert result == {"skipped": True, "reason": "market_timing", "clock_drift": "suspected clock drift (-40s)"}
+
+    @pytest.mark.asyncio
+    async def test_any_time_jobs_are_not_annotated(self, mock_db, mock_market_checker):
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, mock_market_checker, {"offset_seconds": 95.0, "suspect": True})
+        task = AsyncMock()
+        with patch.dict(runner.TASK_REGISTRY, {"sync:quotes": (task, ["db", "broker"])}):
+            await runner._run_task("sync:quotes", {"market_timing": 0})
+
+        assert mock_db.log_job_execution.await_args.args[3] is None
+
+
+class TestScheduleAudit:
+    """Tests for the projected next eligible run of each job."""
+
+    @staticmethod
+    def _setup(runner, mock_db, schedules):
+        mock_db.get_job_schedules = AsyncMock(return_value=schedules)
+        runner._deps = {"db": mock_db}
+        runner._scheduler = None
+
+    @staticmethod
+    def _schedule(job_type, timing):
+        return {"job_type": job_type, "interval_minutes": 60, "market_timing": timing}
+
+    @pytest.mark.asyncio
+    async def test_after_close_waits_for_last_us_close(self, mock_db):
+        """In the US/EU DST mismatch week New York closes at 20:00 UTC."""
+        from datetime import timezone
+
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, [self._schedule("sync:prices", 1), self._schedule("sync:portfolio", 0)])
+        now = datetime(2026, 3, 10, 15, 0, tzinfo=timezone.utc)
+
+        after_close, any_time = await runner.schedule_audit(now)
+
+        assert after_close["next_eligible_run_utc"] == "2026-03-10T20:00:00+00:00"
+        assert after_close["market_timezone"] == "America/New_York"
+        assert after_close["next_eligible_run_local"] == "2026-03-10T16:00:00-04:00"
+        assert any_time["next_eligible_run_utc"] == now.isoformat()
+        assert any_time["market"] is None
+
+    @pytest.mark.asyncio
+    async def test_during_open_waits_for_first_open(self, mock_db):
+        """On a weekend the first open is Hong Kong on Monday morning."""
+        from datetime import timezone
+
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, [self._schedule("trading:execute", 2)])
+        now = datetime(2026, 10, 17, 12, 0, tzinfo=timezone.utc)
+
+        [entry] = await runner.schedule_audit(now)
+
+        assert entry["market"] == "HKEX"
+        assert entry["next_eligible_run_utc"] == "2026-10-19T01:30:00+00:00"
+        assert entry["next_eligible_run_local"] == "2026-10-19T09:30:00+08:00"
+
+    def test_first_run_from_rounds_up_to_interval(self):
+        from datetime import timezone
+
+        from sentinel.jobs import runner
+
+        next_run = datetime(2026, 3, 10, 15, 10, tzinfo=timezone.utc)
+        eligible = datetime(2026, 3, 10, 20, 0, tzinfo=timezone.utc)
+
+        result = runner._first_run_from(next_run, timedelta(minutes=60), eligible)
+
+        assert result == datetime(2026, 3, 10, 20, 10, tzinfo=timezone.utc)
+        assert runner._first_run_from(eligible, timedelta(minutes=60), next_run) == eligible
+
+
+class TestEachMarketCloseTiming:
+    """Tests for per-market close scheduling (market_timing 4)."""
+
+    @staticmethod
+    def _setup(runner, mock_db, states: dict[str, bool], done: list[str]):
+        checker = MagicMock()
+        checker.ensure_fresh = AsyncMock()
+        checker.market_states = MagicMock(return_value=states)
+        mock_db.get_planner_state = AsyncMock(return_value=done)
+        mock_db.set_planner_state = AsyncMock()
+        mock_db.get_all_securities = AsyncMock(
+            return_value=[
+                {"symbol": "AAPL.US", "data": {"mrkt": {"mkt_id": 1}}},
+                {"symbol": "700.HK", "data": {"mrkt": {"mkt_id": 2}}},
+                {"symbol": "NOMKT.EU", "data": None},
+            ]
+        )
+        runner._deps = {"db": mock_db, "broker": MagicMock(), "market_checker": checker}
+        runner._current_job = None
+
+    @pytest.mark.asyncio
+    async def test_runs_for_securities_of_closed_market(self, mock_db):
+        """HK securities sync after the HKEX close while the US is still open."""
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, {"1": True, "2": False}, [])
+        task = AsyncMock()
+        with patch.dict(runner.TASK_REGISTRY, {"sync:quotes": (task, ["db", "broker"])}):
+            result = await runner._run_task("sync:quotes", {"market_timing": 4})
+
+        assert result["status"] == "completed"
+        assert task.await_args.kwargs == {"symbols": ["700.HK"]}
+        mock_db.set_planner_state.assert_awaited_once_with("market_close_runs:sync:quotes", ["2"])
+
+    @pytest.mark.asyncio
+    async def test_skips_when_closed_markets_already_ran(self, mock_db):
+        """A market runs once per close."""
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, {"1": True, "2": False}, ["2"])
+        task = AsyncMock()
+        with patch.dict(runner.TASK_REGISTRY, {"sync:quotes": (task, ["db", "broker"])}):
+            result = await runner._run_task("sync:quotes", {"market_timing": 4})
+
+        assert result == {"skipped": True, "reason": "market_timing"}
+        task.assert_not_awaited()
+
+    @pytest.mark.asyncio
+    async def test_failed_run_is_retried(self, mock_db):
+        """Markets are only recorded after a successful run."""
+        from sentinel.jobs import runner
+
+        self._setup(runner, mock_db, {"1": False, "2": False}, [])
+        task = AsyncMock(side_effect=RuntimeError("boom"))
+        with patch.dict(runner.TASK_REGISTRY, {"sync:quotes": (task, ["db", "broker"])}):
+            result = await runner._run_task("sync:quotes", {"market_timing": 4})
+
+        assert result["status"] == "failed"
+        assert task.await_args.kwargs == {"symbols": ["700.HK", "AAPL.US", "NOMKT.EU"]}
+        mock_db.set_planner_state.assert_not_awaited()
+
+    def test_unscoped_jobs_fall_back_to_after_market_close(self):
+        """Jobs that can't run per market wait for every market to close."""
+        from sentinel.jobs.runner import _check_market_timing
+
+        mock_checker = MagicMock()
+        mock_checker.is_any_market_open.return_value = True
+
+        assert _check_market_timing(4, mock_checker) is False
+
+
 class TestGetStatus:
     """Tests for get_status function."""
 
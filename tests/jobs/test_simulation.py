@@ -0,0 +1,153 @@
+"""Deterministic scheduling tests on a simulated clock.
+
+The harness steps a FakeClock through whole weeks, derives market opens and
+closes from EXCHANGE_HOURS (CalendarMarketChecker) and fires each job on its
+interval ticks through the runner's real market timing checks.
+"""
+
+import json
+import os
+import tempfile
+from collections import defaultdict
+from datetime import datetime, timedelta, timezone
+from unittest.mock import patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.clock import FakeClock
+from sentinel.database import Database
+from sentinel.jobs import runner
+from sentinel.jobs.market import EXCHANGE_HOURS, CalendarMarketChecker, session_at
+
+
+def _utc(*args) -> datetime:
+    return datetime(*args, tzinfo=timezone.utc)
+
+
+def _weekdays(start: datetime, weeks: int) -> list[datetime]:
+    return [start + timedelta(days=d) for d in range(7 * weeks) if (start + timedelta(days=d)).weekday() < 5]
+
+
+class Simulation:
+    """Runs jobs on a FakeClock, recording when each ran and for which symbols."""
+
+    def __init__(self, db, start: datetime, schedules: list[dict], step: timedelta = timedelta(minutes=5)):
+        self.db = db
+        self.clock = FakeClock(start)
+        self.checker = CalendarMarketChecker(self.clock)
+        self.schedules = schedules
+        self.step = step
+        self.runs: dict[str, list[tuple[datetime, list[str] | None]]] = defaultdict(list)
+        self._next_tick = {schedule["job_type"]: start for schedule in schedules}
+
+    def _task(self, job_type: str):
+        async def task(db, symbols=None):
+            self.runs[job_type].append((self.clock.now(), symbols))
+
+        return task
+
+    async def run_for(self, duration: timedelta) -> None:
+        end = self.clock.now() + duration
+        registry = {s["job_type"]: (self._task(s["job_type"]), ["db"]) for s in self.schedules}
+        with (
+            patch.dict(runner.TASK_REGISTRY, registry),
+            patch.object(runner, "_deps", {"db": self.db, "market_checker": self.checker}),
+            patch.object(runner, "_clock", self.clock),
+        ):
+            while self.clock.now() < end:
+                now = self.clock.now()
+                for schedule in self.schedules:
+                    job_type = schedule["job_type"]
+                    if now < self._next_tick[job_type]:
+                        continue
+                    await runner._run_task(job_type, schedule)
+                    interval = runner._get_interval(schedule, self.checker.is_any_market_open())
+                    self._next_tick[job_type] = now + timedelta(minutes=interval)
+                self.clock.advance(self.step)
+
+    def run_times(self, job_type: str, symbol: str | None = None) -> list[datetime]:
+        return [at for at, symbols in self.runs[job_type] if symbol is None or symbol in (symbols or [])]
+
+
+@pytest_asyncio.fixture
+async def db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    database = Database(path)
+    await database.connect()
+    for symbol, market in [("SAP.GR", "XETRA"), ("AAPL.US", "NASDAQ")]:
+        await database.upsert_security(symbol, name=symbol, active=1, data=json.dumps({"mrkt": {"mkt_id": market}}))
+
+    yield database
+
+    await database.close()
+    database.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _schedule(job_type: str, timing: int, interval: int, interval_open: int | None = None) -> dict:
+    return {
+        "job_type": job_type,
+        "market_timing": timing,
+        "interval_minutes": interval,
+        "interval_market_open_minutes": interval_open,
+    }
+
+
+@pytest.mark.asyncio
+async def test_during_open_jobs_only_run_in_sessions(db):
+    sim = Simulation(db, _utc(2026, 3, 9), [_schedule("trading:check_markets", 2, 60, 30)])
+    await sim.run_for(timedelta(days=7))
+
+    runs = sim.run_times("trading:check_markets")
+    assert runs
+    assert all(any(session_at(market, at) for market in EXCHANGE_HOURS) for at in runs)
+    assert not [at for at in runs if at.weekday() >= 5]
+
+
+@pytest.mark.asyncio
+async def test_all_closed_jobs_wait_for_every_exchange(db):
+    sim = Simulation(db, _utc(2026, 3, 9), [_schedule("forecast:run", 3, 60)])
+    await sim.run_for(timedelta(days=7))
+
+    runs = sim.run_times("forecast:run")
+    assert {at.date() for at in runs} == {(_utc(2026, 3, 9) + timedelta(days=d)).date() for d in range(7)}
+    assert not [at for at in runs if any(session_at(market, at) for market in EXCHANGE_HOURS)]
+
+
+@pytest.mark.asyncio
+async def test_each_market_close_follows_dst_per_exchange(db):
+    """Fast-forward across Europe's DST change (29 March 2026).
+
+    New York switched three weeks earlier and closes at 20:00 UTC in both
+    weeks; Frankfurt closes at 16:30 UTC before the switch and 15:30 after.
+    """
+    start = _utc(2026, 3, 23)
+    sim = Simulation(db, start, [_schedule("sync:quotes", 4, 5)])
+    await sim.run_for(timedelta(days=14))
+
+    # The first run covers every closed market; after that each symbol syncs once per close.
+    xetra = sim.run_times("sync:quotes", "SAP.GR")[1:]
+    nasdaq = sim.run_times("sync:quotes", "AAPL.US")[1:]
+    assert sim.run_times("sync:quotes")[0] == start
+
+    expected_xetra = [
+        day.replace(hour=16 if day < _utc(2026, 3, 29) else 15, minute=30) for day in _weekdays(start, 2)
+    ]
+    assert xetra == expected_xetra
+    assert nasdaq == [day.replace(hour=20) for day in _weekdays(start, 2)]
+
+
+@pytest.mark.asyncio
+async def test_run_durations_use_the_simulated_clock(db):
+    sim = Simulation(db, _utc(2026, 3, 9), [_schedule("sync:portfolio", 0, 60)])
+    await sim.run_for(timedelta(hours=3))
+
+    assert sim.run_times("sync:portfolio") == [_utc(2026, 3, 9, h) for h in range(3)]
+    history = await db.get_job_history(limit=10)
+    assert {entry["duration_ms"] for entry in history} == {0}
@@ -1,6 +1,6 @@
 from dataclasses import asdict
 from typing import Any
-from unittest.mock import AsyncMock, MagicMock
+from unittest.mock import AsyncMock, MagicMock, patch
 
 import pytest
 
@@ -99,6 +99,49 @@ async def test_submitted_trade_updates_state_only_after_broker_fill():
     db.delete_planner_state.assert_any_await(SUBMITTED_TRADE_STATE_KEY)
 
 
+@pytest.mark.asyncio
+async def test_new_money_buy_is_booked_at_the_confirmed_fill():
+    rec = _rec(reason_code="new_money", quantity=10, value_delta_eur=500.0, price=100.0)
+    db = MagicMock()
+    db.get_planner_state = AsyncMock(
+        return_value={"order_id": "123", "submitted_at": 1_700_000_000, "recommendation": asdict(rec)}
+    )
+    db.get_trades = AsyncMock(
+        return_value=[
+            {"symbol": rec.symbol, "quantity": 4.0, "price": 90.0, "executed_at": 1, "raw_data": {"order_id": 123}}
+        ]
+    )
+    db.get_strategy_state = AsyncMock(return_value=None)
+    db.upsert_strategy_state = AsyncMock()
+    db.delete_planner_state = AsyncMock()
+    db.mark_savings_invested = AsyncMock()
+
+    with patch("sentinel.settings.Settings") as MockSettings:
+        MockSettings.return_value.get = AsyncMock(return_value=30)
+        assert await _reconcile_submitted_trade(db) is True
+
+    # 4 of 10 shares at 90 instead of 100: 500 EUR * 360 / 1000.
+    assert db.mark_savings_invested.await_args.args[0] == pytest.approx(180.0)
+
+
+@pytest.mark.asyncio
+async def test_unfilled_new_money_buy_books_nothing():
+    rec = _rec(reason_code="new_money")
+    db = MagicMock()
+    db.get_planner_state = AsyncMock(
+        return_value={"order_id": "123", "submitted_at": 1_700_000_000, "recommendation": asdict(rec)}
+    )
+    db.get_trades = AsyncMock(return_value=[])
+    db.get_job_schedule = AsyncMock(return_value=None)
+    db.delete_planner_state = AsyncMock()
+    db.mark_savings_invested = AsyncMock()
+
+    assert await _reconcile_submitted_trade(db) is True
+
+    db.delete_planner_state.assert_awaited_once_with(SUBMITTED_TRADE_STATE_KEY)
+    db.mark_savings_invested.assert_not_awaited()
+
+
 @pytest.mark.asyncio
 async def test_strategy_state_rotation_resets_tranche():
     db = MagicMock()
@@ -768,6 +768,47 @@ class TestTradingExecute:
         security.buy.assert_not_awaited()
         assert mock_db.set_planner_state.await_args.args[1]["order_id"] == "sell-order"
 
+    @pytest.mark.asyncio
+    async def test_execute_research_mode_reports_liquidity_block_at_info(
+        self, mock_broker, mock_db, mock_planner, mock_portfolio
+    ):
+        from sentinel.jobs.tasks import trading_execute
+        from sentinel.planner.models import TradeRecommendation
+
+        mock_broker.connected = True
+        rec = TradeRecommendation(
+            symbol="AAPL.US",
+            action="buy",
+            current_allocation=0.0,
+            target_allocation=0.1,
+            allocation_delta=0.1,
+            current_value_eur=0.0,
+            target_value_eur=1000.0,
+            value_delta_eur=1000.0,
+            quantity=10,
+            price=100.0,
+            currency="USD",
+            lot_size=1,
+            contrarian_score=0.8,
+            priority=1.0,
+            reason="test",
+            execution_rank=1,
+        )
+        mock_planner.get_recommendations = AsyncMock(return_value=[rec])
+        mock_db.get_all_securities = AsyncMock(return_value=[{"symbol": "AAPL.US", "data": '{"mrkt": {"mkt_id": 1}}'}])
+        mock_broker.get_market_status = AsyncMock(return_value={"m": [{"i": 1, "n2": "NASDAQ", "s": "OPEN"}]})
+
+        with (
+            patch("sentinel.settings.Settings") as MockSettings,
+            patch("sentinel.jobs.tasks.liquidity_block_reason", AsyncMock(return_value="below reserve")),
+            patch("sentinel.jobs.tasks.logger") as logger,
+        ):
+            MockSettings.return_value.get = AsyncMock(return_value="research")
+            await trading_execute(mock_broker, mock_db, mock_planner, mock_portfolio)
+
+        logger.warning.assert_not_called()
+        assert any("would BUY" in c.args[0] and "below reserve" in c.args[0] for c in logger.info.call_args_list)
+
     @pytest.mark.asyncio
     async def test_execute_skips_when_orders_pending(self, mock_broker, mock_db, mock_planner, mock_portfolio):
         """No new orders are submitted while previous orders are still outstanding."""
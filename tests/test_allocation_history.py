@@ -0,0 +1,124 @@
+"""Tests for allocation run history and target weight drift."""
+
+import os
+import tempfile
+from unittest.mock import MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.planner.allocation_history import (
+    allocation_constraints,
+    compare_runs,
+    effective_holdings,
+    record_allocation_run,
+    weight_drift,
+)
+
+CONFIG = {
+    "max_position_pct": 25,
+    "strategy_ideal_qualifying_threshold": 0.5,
+    "clara_preference_strength": 1.0,
+}
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ("", "-wal", "-shm"):
+        target = path + ext
+        if os.path.exists(target):
+            os.unlink(target)
+
+
+def _constraints(targets=None, groups=None):
+    return allocation_constraints(CONFIG, 0.05, targets or {}, groups or [])
+
+
+def test_effective_holdings():
+    assert effective_holdings({"A": 0.25, "B": 0.25}) == pytest.approx(2.0)
+    assert effective_holdings({"A": 0.9, "B": 0.05, "C": 0.05}) == pytest.approx(1 / (0.9**2 + 2 * 0.05**2))
+    assert effective_holdings({}) is None
+
+
+@pytest.mark.asyncio
+async def test_identical_results_only_refresh_the_latest_run(temp_db):
+    first = await record_allocation_run(temp_db, {"A": 0.5, "B": 0.45}, "clara_risk", _constraints(), now=100)
+    again = await record_allocation_run(temp_db, {"A": 0.50001, "B": 0.45}, "clara_risk", _constraints(), now=200)
+    assert again == first
+
+    [run] = await temp_db.get_allocation_runs()
+    assert (run["created_at"], run["last_seen_at"]) == (100, 200)
+    assert run["turnover"] is None
+
+    targets = {"B": {"target_pct": 45.0, "mode": "hard", "source": "manual", "updated_at": "x"}}
+    second = await record_allocation_run(temp_db, {"A": 0.5, "B": 0.45}, "clara_risk", _constraints(targets), now=300)
+    third = await record_allocation_run(temp_db, {"A": 0.3, "C": 0.65}, "clara_risk", _constraints(targets), now=400)
+    assert len({first, second, third}) == 3
+
+    latest = await temp_db.get_latest_allocation_run()
+    assert latest["id"] == third
+    assert latest["turnover"] == pytest.approx(0.65)
+    assert latest["constraints"]["position_targets"] == {"B": {"target_pct": 45.0, "mode": "hard"}}
+
+
+@pytest.mark.asyncio
+async def test_a_database_without_history_is_tolerated():
+    assert await record_allocation_run(object(), {"A": 1.0}, "clara_risk", _constraints()) is None
+
+
+def test_compare_runs_reports_weight_and_constraint_changes():
+    groups = [{"id": 1, "name": "Core", "target_pct": 40.0, "symbols": ["B", "A"]}]
+    before = {"id": 1, "weights": {"A": 0.3, "B": 0.6}, "constraints": _constraints()}
+    after = {"id": 2, "weights": {"B": 0.5, "C": 0.4}, "constraints": _constraints(groups=groups)}
+
+    result = compare_runs(before, after)
+
+    assert result["turnover"] == pytest.approx(0.4)
+    assert [row["symbol"] for row in result["weights"]] == ["C", "A", "B"]
+    assert result["weights"][1] == {"symbol": "A", "from_pct": 30.0, "to_pct": 0.0, "change_pct": -30.0}
+    assert (result["added"], result["removed"]) == (["C"], ["A"])
+    assert result["constraints"] == {
+        "groups": {"from": {}, "to": {"Core": {"target_pct": 40.0, "symbols": ["A", "B"]}}}
+    }
+
+
+def test_weight_drift_fills_missing_symbols_with_zero():
+    runs = [
+        {"id": 1, "created_at": 1, "weights": {"A": 0.5}},
+        {"id": 2, "created_at": 2, "weights": {"A": 0.25, "B": 0.5}, "turnover": 0.5},
+    ]
+    drift = weight_drift(runs)
+    assert drift["symbols"] == {"A": [50.0, 25.0], "B": [0.0, 50.0]}
+    assert [run["turnover"] for run in drift["runs"]] == [None, 0.5]
+    assert list(weight_drift(runs, ["B"])["symbols"]) == ["B"]
+
+
+@pytest.mark.asyncio
+async def test_compare_endpoint_defaults_to_the_two_latest_runs(temp_db):
+    from sentinel.api.routers import planner as planner_router
+
+    deps = MagicMock()
+    deps.db = temp_db
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.compare_allocation_runs(deps)
+    assert exc.value.status_code == 404
+
+    await record_allocation_run(temp_db, {"A": 0.5}, "clara_risk", _constraints(), now=100)
+    await record_allocation_run(temp_db, {"A": 0.7}, "clara_risk", _constraints(), now=200)
+
+    result = await planner_router.compare_allocation_runs(deps)
+    assert result["weights"] == [{"symbol": "A", "from_pct": 50.0, "to_pct": 70.0, "change_pct": 20.0}]
+
+    drift = await planner_router.get_allocation_drift(deps, symbols="A, B")
+    assert drift["symbols"] == {"A": [50.0, 70.0], "B": [0.0, 0.0]}
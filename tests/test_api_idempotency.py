@@ -0,0 +1,185 @@
+"""Tests for Idempotency-Key handling on side-effecting endpoints."""
+
+import asyncio
+import os
+import tempfile
+import time
+from types import SimpleNamespace
+from unittest.mock import AsyncMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException, Response
+
+from sentinel.api import idempotency
+from sentinel.api.idempotency import REPLAY_HEADER, request_hash, run_idempotent
+from sentinel.database import Database
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _deps(db, retention_hours=24):
+    settings = SimpleNamespace(get=AsyncMock(return_value=retention_hours))
+    return SimpleNamespace(db=db, settings=settings)
+
+
+def test_request_hash_ignores_key_order():
+    assert request_hash({"symbol": "AAPL.US", "quantity": 2}) == request_hash({"quantity": 2, "symbol": "AAPL.US"})
+    assert request_hash({"quantity": 2}) != request_hash({"quantity": 3})
+
+
+@pytest.mark.asyncio
+async def test_without_key_always_runs(temp_db):
+    handler = AsyncMock(return_value={"order_id": "1"})
+
+    for _ in range(2):
+        await run_idempotent(_deps(temp_db), Response(), "securities:buy", None, {"quantity": 1}, handler)
+
+    assert handler.await_count == 2
+
+
+@pytest.mark.asyncio
+async def test_retry_replays_stored_result(temp_db):
+    handler = AsyncMock(return_value={"order_id": "1"})
+    payload = {"symbol": "AAPL.US", "quantity": 2}
+
+    first = await run_idempotent(_deps(temp_db), Response(), "securities:buy", "k1", payload, handler)
+    replay_response = Response()
+    second = await run_idempotent(_deps(temp_db), replay_response, "securities:buy", "k1", payload, handler)
+
+    assert first == second == {"order_id": "1"}
+    assert handler.await_count == 1
+    assert replay_response.headers[REPLAY_HEADER] == "true"
+
+
+@pytest.mark.asyncio
+async def test_keys_are_scoped_per_endpoint(temp_db):
+    handler = AsyncMock(return_value={"order_id": "1"})
+    payload = {"symbol": "AAPL.US", "quantity": 2}
+
+    await run_idempotent(_deps(temp_db), Response(), "securities:buy", "k1", payload, handler)
+    await run_idempotent(_deps(temp_db), Response(), "securities:sell", "k1", payload, handler)
+
+    assert handler.await_count == 2
+
+
+@pytest.mark.asyncio
+async def test_reuse_with_different_parameters_is_rejected(temp_db):
+    handler = AsyncMock(return_value={"order_id": "1"})
+    await run_idempotent(_deps(temp_db), Response(), "securities:buy", "k1", {"quantity": 2}, handler)
+
+    with pytest.raises(HTTPException) as exc:
+        await run_idempotent(_deps(temp_db), Response(), "securities:buy", "k1", {"quantity": 5}, handler)
+
+    assert exc.value.status_code == 422
+    assert handler.await_count == 1
+
+
+@pytest.mark.asyncio
+@pytest.mark.parametrize("key", ["", "   ", "x" * 256])
+async def test_malformed_key_is_rejected(temp_db, key):
+    handler = AsyncMock()
+
+    with pytest.raises(HTTPException) as exc:
+        await run_idempotent(_deps(temp_db), Response(), "jobs:run", key, {}, handler)
+
+    assert exc.value.status_code == 400
+    handler.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_failed_request_can_be_retried(temp_db):
+    handler = AsyncMock(side_effect=[HTTPException(status_code=400, detail="rejected"), {"order_id": "2"}])
+
+    with pytest.raises(HTTPException):
+        await run_idempotent(_deps(temp_db), Response(), "securities:buy", "k1", {"quantity": 1}, handler)
+    result = await run_idempotent(_deps(temp_db), Response(), "securities:buy", "k1", {"quantity": 1}, handler)
+
+    assert result == {"order_id": "2"}
+    assert handler.await_count == 2
+
+
+@pytest.mark.asyncio
+async def test_rejected_result_is_not_stored(temp_db):
+    handler = AsyncMock(return_value={"status": "failed", "error": "boom"})
+
+    for _ in range(2):
+        await run_idempotent(
+            _deps(temp_db),
+            Response(),
+            "jobs:run",
+            "k1",
+            {"job_type": "sync:prices"},
+            handler,
+            should_store=lambda result: result.get("status") != "failed",
+        )
+
+    assert handler.await_count == 2
+    assert await temp_db.get_idempotency_record("jobs:run", "k1") is None
+
+
+@pytest.mark.asyncio
+async def test_expired_keys_are_pruned(temp_db):
+    await temp_db.save_idempotency_record("jobs:run", "old", request_hash({}), {"status": "ok"})
+    await temp_db.conn.execute(
+        "UPDATE idempotency_keys SET created_at = ? WHERE key = 'old'",
+        (int(time.time()) - 2 * 3600,),
+    )
+    await temp_db.conn.commit()
+    handler = AsyncMock(return_value={"status": "ok"})
+
+    await run_idempotent(_deps(temp_db, retention_hours=1), Response(), "jobs:run", "old", {}, handler)
+
+    assert handler.await_count == 1
+
+
+@pytest.mark.asyncio
+async def test_lock_is_kept_while_requests_wait(temp_db):
+    """A request arriving while another holds the key's lock queues behind it."""
+    gates = [asyncio.Event() for _ in range(3)]
+    running = []
+    overlapped = False
+
+    async def handler():
+        nonlocal overlapped
+        gate = gates[len(running)]
+        running.append(gate)
+        overlapped = overlapped or sum(not g.is_set() for g in running) > 1
+        await gate.wait()
+        return {"status": "failed"}
+
+    def request():
+        return asyncio.create_task(
+            run_idempotent(_deps(temp_db), Response(), "jobs:run", "k1", {}, handler, should_store=lambda result: False)
+        )
+
+    first = request()
+    await asyncio.sleep(0.01)
+    second = request()
+    await asyncio.sleep(0.01)
+    gates[0].set()
+    await first
+    third = request()
+    await asyncio.sleep(0.01)
+
+    assert not overlapped
+    gates[1].set()
+    gates[2].set()
+    await asyncio.gather(second, third)
+    assert idempotency._locks == {} and idempotency._waiters == {}
@@ -39,7 +39,7 @@ async def test_get_job_schedules_returns_all(db):
     """GET /api/jobs/schedules should return all schedules."""
     schedules = await db.get_job_schedules()
 
-    assert len(schedules) == 19
+    assert len(schedules) == 26
 
     # Check structure (no longer has enabled, dependencies, is_parameterized fields)
     schedule = schedules[0]
@@ -192,7 +192,14 @@ async def test_all_job_types_present(db):
         "planning:refresh",
         "forecast:run",
         "forecast:evaluate",
+        "sync:news",
+        "snapshot:valuation",
+        "security:technical",
+        "security:liquidity",
+        "security:factors",
         "backup:r2",
+        "system:clock_check",
+        "system:retention",
     ]
 
     schedules = await db.get_job_schedules()
@@ -208,5 +215,5 @@ async def test_categories_present(db):
     schedules = await db.get_job_schedules()
     categories = set(s["category"] for s in schedules)
 
-    expected = {"sync", "trading", "forecast", "backup"}
+    expected = {"sync", "trading", "forecast", "backup", "system"}
     assert categories == expected
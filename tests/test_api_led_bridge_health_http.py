@@ -112,3 +112,35 @@ async def test_led_status_includes_bridge_health(temp_db_path):
     assert bridge["is_stale"] is True
     assert bridge["last_error"] == "Request 'hm.u' timed out after 10s"
     assert isinstance(status_payload["broker_connected"], bool)
+
+
+@pytest.mark.asyncio
+async def test_led_bridge_health_tracks_api_connectivity(temp_db_path):
+    client = _build_client()
+    now = int(time.time())
+    body = {
+        "bridge_ok": True,
+        "last_attempt_ts": now,
+        "last_success_ts": now,
+        "consecutive_failures": 0,
+        "api_ok": False,
+        "api_failures": 3,
+        "last_api_success_ts": now - 120,
+        "app_instance": "arduino-app/sentinel",
+    }
+    resp = client.post("/api/led/bridge/health", json=body)
+    assert resp.status_code == 200
+
+    payload = client.get("/api/led/bridge/health").json()
+    assert payload["bridge_ok"] is True
+    assert payload["api_ok"] is False
+    assert payload["api_failures"] == 3
+    assert payload["last_api_success_at"] is not None
+
+
+@pytest.mark.asyncio
+async def test_led_bridge_health_api_ok_unknown_by_default(temp_db_path):
+    client = _build_client()
+    payload = client.get("/api/led/bridge/health").json()
+    assert payload["api_ok"] is None
+    assert payload["api_failures"] == 0
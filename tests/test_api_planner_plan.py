@@ -80,6 +80,8 @@ async def test_recommendations_endpoint_includes_fixed_plan_snapshot():
     deps = MagicMock()
     deps.settings.get = AsyncMock(return_value=100.0)
     deps.db.get_job_schedule = AsyncMock(return_value={"interval_minutes": 60, "interval_market_open_minutes": 20})
+    deps.db.get_planner_state = AsyncMock(return_value=None)
+    deps.db.get_all_securities = AsyncMock(return_value=[])
 
     with (
         patch.object(planner_router, "Planner", return_value=planner),
@@ -98,6 +98,19 @@ async def test_set_setting_invalidates_planner_cache_for_recommendation_settings
     assert await deps.db.cache_get("planner:recommendations:100.00") is None
 
 
+@pytest.mark.asyncio
+async def test_set_setting_validates_transaction_cost_profiles(deps):
+    from sentinel.api.routers.settings import set_setting
+
+    with pytest.raises(HTTPException) as exc:
+        await set_setting("transaction_cost_profiles", {"value": {"USD": {"fee_percent": -1}}}, deps)
+    assert exc.value.status_code == 400
+
+    result = await set_setting("transaction_cost_profiles", {"value": {"USD": {"fx_spread_percent": 0.25}}}, deps)
+    assert result == {"status": "ok"}
+    assert await deps.settings.get("transaction_cost_profiles") == {"USD": {"fx_spread_percent": 0.25}}
+
+
 @pytest.mark.asyncio
 async def test_set_settings_batch_persists_strategy_values(deps):
     from sentinel.api.routers.settings import set_settings_batch
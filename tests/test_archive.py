@@ -0,0 +1,72 @@
+"""Tests for retention archive files."""
+
+import os
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel.archive import archive_table, list_archives, read_archive, restore_archive, write_archive
+from sentinel.database import Database
+
+NOW = 1_800_000_000
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_round_trip_keeps_nulls(tmp_path):
+    path = write_archive("job_history", ["id", "error"], [(1, None), (2, "timeout")], tmp_path, now=NOW)
+
+    assert path.name == "job_history-20270115T080000Z.csv.gz"
+    assert read_archive(path) == (["id", "error"], [("1", None), ("2", "timeout")])
+
+
+def test_same_second_gets_new_file(tmp_path):
+    first = write_archive("job_history", ["id"], [(1,)], tmp_path, now=NOW)
+    second = write_archive("job_history", ["id"], [(2,)], tmp_path, now=NOW)
+
+    assert first != second
+    assert archive_table(second) == "job_history"
+    assert [f["table"] for f in list_archives(tmp_path)] == ["job_history", "job_history"]
+
+
+@pytest.mark.asyncio
+async def test_restore_skips_rows_still_present(temp_db, tmp_path):
+    await temp_db.log_job_execution("job-1", "sync:prices", "completed", None, 10, 0)
+    cursor = await temp_db.conn.execute("SELECT * FROM job_history")
+    rows = [tuple(row) for row in await cursor.fetchall()]
+    columns = [col[0] for col in cursor.description]
+    path = write_archive("job_history", columns, rows, tmp_path, now=NOW)
+    await temp_db.conn.execute("DELETE FROM job_history")
+    await temp_db.conn.commit()
+
+    first = await restore_archive(temp_db, path)
+    second = await restore_archive(temp_db, path)
+
+    assert (first["restored"], second["restored"]) == (1, 0)
+    cursor = await temp_db.conn.execute("SELECT duration_ms, error FROM job_history")
+    assert tuple(await cursor.fetchone()) == (10, None)
+
+
+@pytest.mark.asyncio
+async def test_restore_rejects_unknown_table(temp_db, tmp_path):
+    path = write_archive("trades", ["id"], [(1,)], tmp_path, now=NOW)
+
+    with pytest.raises(ValueError):
+        await restore_archive(temp_db, path)
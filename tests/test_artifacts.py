@@ -0,0 +1,97 @@
+"""Tests for job run artifacts."""
+
+import json
+import os
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel import artifacts
+from sentinel.artifacts import (
+    INLINE_MAX_BYTES,
+    add_artifact,
+    begin_run,
+    end_run,
+    prune_artifacts,
+    read_artifact,
+    store_artifacts,
+)
+from sentinel.database import Database
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ("", "-wal", "-shm"):
+        target = path + ext
+        if os.path.exists(target):
+            os.unlink(target)
+
+
+def test_add_artifact_outside_a_run_is_a_no_op():
+    end_run()
+    add_artifact("report", {"rows": 1})
+
+    with pytest.raises(ValueError, match="Invalid artifact name"):
+        add_artifact("../etc/passwd", "x")
+
+
+@pytest.mark.asyncio
+async def test_small_artifacts_are_stored_inline(temp_db):
+    run_id = await temp_db.log_job_execution("system:retention", "system:retention", "completed", None, 10, 0)
+    collected = begin_run()
+    add_artifact("report", {"rows": 3})
+    add_artifact("notes", "kept 3 rows")
+    add_artifact("report", {"rows": 4})
+    end_run()
+
+    assert await store_artifacts(temp_db, run_id, "system:retention", collected) == 2
+
+    stored = await temp_db.get_job_artifacts(run_id=run_id)
+    assert sorted(a["name"] for a in stored) == ["notes", "report"]
+    report = next(a for a in stored if a["name"] == "report")
+    assert report["in_file"] is False
+    assert artifacts.download_name(report) == f"system-retention-{run_id}-report.json"
+    assert json.loads(read_artifact(await temp_db.get_job_artifact(report["id"]))) == {"rows": 4}
+    history = await temp_db.get_job_history(limit=1)
+    assert history[0]["id"] == run_id
+
+
+@pytest.mark.asyncio
+async def test_large_artifacts_go_to_files_and_are_pruned(temp_db, tmp_path):
+    run_id = await temp_db.log_job_execution("planning:refresh", "planning:refresh", "completed", None, 10, 0)
+    collected = begin_run()
+    add_artifact("blob", b"x" * (INLINE_MAX_BYTES + 1))
+    end_run()
+
+    await store_artifacts(temp_db, run_id, "planning:refresh", collected, directory=tmp_path)
+
+    [meta] = await temp_db.get_job_artifacts(job_type="planning:refresh")
+    assert meta["in_file"] is True
+    artifact = await temp_db.get_job_artifact(meta["id"])
+    assert artifact["data"] is None
+    assert read_artifact(artifact) == b"x" * (INLINE_MAX_BYTES + 1)
+
+    assert await prune_artifacts(temp_db, keep_days=30) == 0
+    assert await prune_artifacts(temp_db, keep_days=1, now=artifact["created_at"] + 2 * 86400) == 1
+    assert await temp_db.get_job_artifacts() == []
+    assert not (tmp_path / str(run_id)).exists()
+
+
+@pytest.mark.asyncio
+async def test_store_without_a_run_id_stores_nothing(temp_db):
+    collected = begin_run()
+    add_artifact("report", {})
+    end_run()
+
+    assert await store_artifacts(temp_db, None, "system:retention", collected) == 0
+    assert await temp_db.get_job_artifacts() == []
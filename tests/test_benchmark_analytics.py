@@ -0,0 +1,115 @@
+"""Tests for benchmark tracking and relative performance."""
+
+from datetime import date, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+from fastapi import HTTPException
+
+from sentinel.benchmark_analytics import (
+    build_benchmark_analytics,
+    relative_performance,
+    ticker_text,
+    validate_benchmark_symbols,
+)
+
+START = date(2026, 1, 1)
+
+
+def _series(days: int, portfolio_multiple: float = 2.0, deposit_day: int | None = None):
+    """Daily P&L points and index closes where the portfolio moves `portfolio_multiple`x the index."""
+    daily, rows = [], []
+    value, deposits, close = 1000.0, 1000.0, 100.0
+    for i in range(days):
+        on = (START + timedelta(days=i)).isoformat()
+        if i:
+            index_return = 0.01 if i % 2 else -0.005
+            close *= 1 + index_return
+            value *= 1 + portfolio_multiple * index_return
+        if i == deposit_day:
+            value += 500.0
+            deposits += 500.0
+        daily.append({"date": on, "total_value_eur": value, "net_deposits_eur": deposits})
+        rows.append({"date": on, "close": close})
+    return daily, rows
+
+
+class TestRelativePerformance:
+    def test_tracking_metrics_ignore_deposits(self):
+        daily, rows = _series(61, deposit_day=20)
+        result = relative_performance(daily, rows, as_of=date(2026, 3, 2), window_days=60, rolling_days=30)
+
+        benchmark = rows[-1]["close"] / rows[0]["close"] - 1
+        portfolio = 1.0
+        for i in range(1, 61):
+            portfolio *= 1 + 2 * (0.01 if i % 2 else -0.005)
+        assert result["benchmark_pct"] == round(benchmark * 100, 2)
+        assert result["portfolio_pct"] == round((portfolio - 1) * 100, 2)
+        assert result["tracking_difference_pct"] == round((portfolio - 1 - benchmark) * 100, 2)
+        assert result["beta"] == 2.0
+        assert result["tracking_error_pct"] > 0
+        assert result["ytd"]["relative_pct"] == result["tracking_difference_pct"]
+
+        assert result["rolling"][0]["date"] == "2026-01-31"
+        assert result["rolling"][-1]["date"] == "2026-03-02"
+
+    def test_index_closes_fill_non_trading_days(self):
+        daily, rows = _series(40, portfolio_multiple=1.0)
+        # Drop every 7th close, as if the index did not trade that day.
+        rows = [row for i, row in enumerate(rows) if i % 7 != 3]
+        result = relative_performance(daily, rows, as_of=date(2026, 2, 9), window_days=39)
+
+        assert result["benchmark_pct"] is not None
+        assert result["samples"] == 39
+
+    def test_thin_history_returns_nulls(self):
+        daily, rows = _series(10)
+        result = relative_performance(daily, rows, as_of=date(2026, 1, 10), window_days=365)
+
+        assert result["portfolio_pct"] is None
+        assert result["beta"] is None
+        assert result["ytd"]["relative_pct"] is not None
+        assert relative_performance([], rows, as_of=date(2026, 1, 10))["rolling"] == []
+
+
+def test_ticker_text_uses_first_benchmark_with_ytd():
+    analytics = {
+        "benchmarks": [
+            {"symbol": "DAX.IDX", "ytd": {"relative_pct": None}},
+            {"symbol": "SP500.IDX", "ytd": {"relative_pct": 1.234}},
+        ]
+    }
+    assert ticker_text(analytics) == "vs SP500: +1.2% YTD"
+    assert ticker_text({"benchmarks": []}) is None
+
+
+def test_validate_benchmark_symbols():
+    assert validate_benchmark_symbols([" SP500.IDX ", "DAX.IDX", "SP500.IDX"]) == ["SP500.IDX", "DAX.IDX"]
+    for bad in ("SP500.IDX", [""], [5]):
+        with pytest.raises(ValueError):
+            validate_benchmark_symbols(bad)
+
+
+@pytest.mark.asyncio
+async def test_build_reports_unavailable_benchmarks():
+    db = MagicMock()
+    db.get_portfolio_snapshots = AsyncMock(return_value=[])
+    db.get_benchmarks = AsyncMock(return_value=[{"symbol": "SP500.IDX", "name": "Index S&P 500"}])
+    db.get_benchmark_prices = AsyncMock(return_value=[])
+    settings = MagicMock()
+    settings.get = AsyncMock(return_value=["SP500.IDX"])
+
+    result = await build_benchmark_analytics(db, MagicMock(), settings, as_of=date(2026, 3, 1))
+
+    assert result["benchmarks"][0]["name"] == "Index S&P 500"
+    assert result["benchmarks"][0]["available"] is False
+    assert result["benchmarks"][0]["tracking_difference_pct"] is None
+
+
+@pytest.mark.asyncio
+async def test_endpoint_rejects_out_of_range_windows():
+    from sentinel.api.routers.portfolio import get_benchmark_analytics
+
+    with pytest.raises(HTTPException) as exc:
+        await get_benchmark_analytics(MagicMock(), window_days=5)
+    assert exc.value.status_code == 400
@@ -0,0 +1,132 @@
+"""Tests for ISIN -> broker symbol mappings."""
+
+import json
+import os
+import tempfile
+from unittest.mock import MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.broker_symbols import (
+    delete_mapping,
+    detect_broker_symbols,
+    mapping_report,
+    order_warnings,
+    set_manual_mapping,
+    validate_mapping,
+)
+from sentinel.database import Database
+from sentinel.identifiers import IdentifierService
+
+ASML = "NL0010273215"
+SAP = "DE0007164600"
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    await db.upsert_security("ASML.EU", name="ASML", active=1, data=json.dumps({"issue_nb": ASML}))
+    await db.upsert_security("ASML.GR", name="ASML Xetra", active=0, data=json.dumps({"issue_nb": ASML}))
+    await db.upsert_security("SAP.EU", name="SAP", active=1, data=json.dumps({"issue_nb": SAP}))
+    await db.upsert_security("SAP.GR", name="SAP Xetra", active=1, data=json.dumps({"issue_nb": SAP}))
+    await db.upsert_security("NEW.US", name="No ISIN", active=1)
+
+    yield db
+
+    IdentifierService(db).invalidate()
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+@pytest.mark.asyncio
+async def test_detect_maps_single_active_listing_and_reports_conflicts(temp_db):
+    result = await detect_broker_symbols(temp_db)
+
+    assert result["added"] == [ASML]
+    assert result["conflicts"] == [{"isin": SAP, "symbols": ["SAP.EU", "SAP.GR"]}]
+    assert (await temp_db.get_broker_symbol(ASML))["broker_symbol"] == "ASML.EU"
+    assert await temp_db.get_broker_symbol(SAP) is None
+
+    again = await detect_broker_symbols(temp_db)
+    assert again["added"] == again["updated"] == again["removed"] == []
+
+
+@pytest.mark.asyncio
+async def test_detect_never_overwrites_manual_mappings(temp_db):
+    await set_manual_mapping(temp_db, ASML, "asml.gr")
+    await detect_broker_symbols(temp_db)
+
+    mapping = await temp_db.get_broker_symbol(ASML)
+    assert mapping["broker_symbol"] == "ASML.GR"
+    assert mapping["source"] == "manual"
+
+
+@pytest.mark.asyncio
+async def test_detect_removes_auto_mappings_to_missing_symbols(temp_db):
+    await temp_db.set_broker_symbol("US0378331005", "AAPL.US", "auto")
+    result = await detect_broker_symbols(temp_db)
+    assert result["removed"] == ["US0378331005"]
+
+
+@pytest.mark.asyncio
+async def test_manual_mapping_steers_isin_resolution(temp_db):
+    await set_manual_mapping(temp_db, SAP, "SAP.GR")
+    assert await IdentifierService(temp_db).resolve(SAP) == "SAP.GR"
+
+    # Two active listings and no mapping: ambiguous.
+    assert await delete_mapping(temp_db, SAP)
+    assert await IdentifierService(temp_db).resolve(SAP) is None
+    assert not await delete_mapping(temp_db, SAP)
+
+
+@pytest.mark.asyncio
+async def test_manual_mapping_requires_known_security(temp_db):
+    with pytest.raises(ValueError):
+        await set_manual_mapping(temp_db, SAP, "SAP.XX")
+
+
+@pytest.mark.asyncio
+async def test_order_warnings(temp_db):
+    await detect_broker_symbols(temp_db)
+    await set_manual_mapping(temp_db, SAP, "SAP.EU")
+
+    assert await order_warnings(temp_db, "ASML.EU") == []
+    assert await order_warnings(temp_db, "SAP.GR") == [f"{SAP} trades as SAP.EU at the broker, not SAP.GR"]
+    assert "no ISIN" in (await order_warnings(temp_db, "NEW.US"))[0]
+
+
+@pytest.mark.asyncio
+async def test_mapping_report_lists_unmapped_active_securities(temp_db):
+    await detect_broker_symbols(temp_db)
+    report = await mapping_report(temp_db)
+
+    assert [row["listings"] for row in report["mappings"]] == [["ASML.EU", "ASML.GR"]]
+    assert sorted(row["symbol"] for row in report["unmapped"]) == ["NEW.US", "SAP.EU", "SAP.GR"]
+
+
+def test_validate_mapping():
+    assert validate_mapping(" nl0010273215", {"broker_symbol": " ASML.EU "}) == (ASML, "ASML.EU")
+    for isin, body in [("ASML", {"broker_symbol": "ASML.EU"}), (ASML, {}), (ASML, [])]:
+        with pytest.raises(ValueError):
+            validate_mapping(isin, body)
+
+
+@pytest.mark.asyncio
+async def test_put_endpoint_rejects_unknown_symbol(temp_db):
+    from sentinel.api.routers.broker_symbols import put_broker_symbol
+
+    deps = MagicMock()
+    deps.db = temp_db
+    with pytest.raises(HTTPException) as exc:
+        await put_broker_symbol(ASML, {"broker_symbol": "NOPE.US"}, deps)
+    assert exc.value.status_code == 400
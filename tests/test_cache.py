@@ -10,7 +10,7 @@ These tests verify the intended behavior of the Cache class:
 
 from unittest.mock import patch
 
-from sentinel.cache import Cache, CacheEntry
+from sentinel.cache import BoundedCache, Cache, CacheEntry, estimate_size
 
 
 class TestCacheBasicOperations:
@@ -319,6 +319,87 @@ class TestCacheClassMethods:
         assert cache2.get("c") is None
 
 
+class TestCacheBounds:
+    """Tests for LRU eviction and size accounting."""
+
+    def test_evicts_least_recently_used_entry(self):
+        """A full cache drops the entry read or written longest ago."""
+        cache = BoundedCache("test_lru", max_entries=2)
+        cache.set("a", 1)
+        cache.set("b", 2)
+        cache.get("a")
+        cache.set("c", 3)
+
+        assert cache.get("b") is None
+        assert cache.get("a") == 1
+        assert cache.get("c") == 3
+        assert cache.stats()["evictions"] == 1
+
+    def test_overwrite_does_not_evict(self):
+        cache = BoundedCache("test_overwrite_bounded", max_entries=2)
+        cache.set("a", 1)
+        cache.set("b", 2)
+        cache.set("a", 10)
+
+        assert len(cache) == 2
+        assert cache.stats()["evictions"] == 0
+
+    def test_byte_limit_evicts_until_values_fit(self):
+        value = "x" * 1000
+        limit = estimate_size(value) * 2 + 10
+        cache = BoundedCache("test_bytes", max_bytes=limit)
+        for key in ["a", "b", "c"]:
+            cache.set(key, value)
+
+        stats = cache.stats()
+        assert len(cache) == 2
+        assert stats["bytes"] <= limit
+        assert stats["evictions"] == 1
+        assert cache.get("a") is None
+
+    def test_value_larger_than_byte_limit_is_kept_alone(self):
+        cache = BoundedCache("test_huge", max_bytes=10)
+        cache.set("small", 1)
+        cache.set("huge", "y" * 1000)
+
+        assert cache.get("huge") is not None
+        assert len(cache) == 1
+
+    def test_invalidate_and_clear_release_bytes(self):
+        cache = BoundedCache("test_release", max_bytes=10**6)
+        cache.set("a", [1, 2, 3])
+        cache.set("b", {"k": "v"})
+        cache.invalidate("a")
+        assert cache.stats()["bytes"] == estimate_size({"k": "v"})
+        cache.clear()
+        assert cache.stats()["bytes"] == 0
+
+    def test_no_ttl_never_expires(self):
+        cache = BoundedCache("test_no_ttl", ttl_seconds=None)
+        with patch("sentinel.cache.time") as mock_time:
+            mock_time.time.return_value = 1000.0
+            cache.set("key", "value")
+            mock_time.time.return_value = 10**12
+            assert cache.get("key") == "value"
+
+    def test_expired_reads_are_counted(self):
+        cache = BoundedCache("test_expirations", ttl_seconds=10)
+        with patch("sentinel.cache.time") as mock_time:
+            mock_time.time.return_value = 1000.0
+            cache.set("key", "value")
+            mock_time.time.return_value = 1011.0
+            assert cache.get("key") is None
+        assert cache.stats()["expirations"] == 1
+
+    def test_bounded_caches_are_not_registered(self):
+        Cache._instances.clear()
+        BoundedCache("test_unnamed")
+        assert Cache.get_all_stats() == {}
+
+    def test_estimate_size_follows_containers(self):
+        assert estimate_size({"a": "x" * 500}) > estimate_size({"a": "x"}) + 400
+
+
 class TestCacheEntry:
     """Tests for CacheEntry dataclass."""
 
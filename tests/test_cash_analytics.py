@@ -0,0 +1,108 @@
+"""Tests for per-currency cash analytics and conversion suggestions."""
+
+import os
+import tempfile
+from types import SimpleNamespace
+
+import pytest
+import pytest_asyncio
+
+from sentinel.cash_analytics import cash_analytics, matching_suggestion, planned_needs, suggest_conversions
+from sentinel.database import Database
+
+NOW = 1_800_000_000
+DAY = 86400
+RATES = {"EUR": 1.0, "USD": 0.9, "GBP": 1.2}
+
+
+async def _to_eur(amount: float, currency: str) -> float:
+    return amount * RATES[currency]
+
+
+def _getter(settings: dict):
+    async def get(key, default=None):
+        return settings.get(key, default)
+
+    return get
+
+
+def _buy(currency: str, quantity: int, price: float):
+    return SimpleNamespace(action="buy", currency=currency, quantity=quantity, price=price)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_planned_needs():
+    needs = planned_needs(
+        [_buy("USD", 10, 50), _buy("EUR", 2, 100), SimpleNamespace(action="sell", currency="USD", quantity=1, price=9)],
+        {"USD": 200},
+        [{"label": "Tax", "amount": 300, "currency": "EUR"}],
+        buffer_eur=50,
+    )
+    assert needs["USD"] == {"floor": 200, "obligations": 0, "buys": 500, "buffer": 0, "total": 700}
+    assert needs["EUR"]["total"] == 550
+
+
+def test_suggestions_cover_shortfalls_before_idle_conversions():
+    currencies = {
+        "EUR": {"excess_eur": -600.0, "rate_to_eur": 1.0, "idle_days": None},
+        "USD": {"excess_eur": 2700.0, "rate_to_eur": 0.9, "idle_days": 10.0},
+        "GBP": {"excess_eur": 1200.0, "rate_to_eur": 1.2, "idle_days": 3.0},
+    }
+    suggestions = suggest_conversions(currencies, min_eur=100, idle_days=7)
+
+    assert suggestions == [
+        {"from": "USD", "to": "EUR", "amount": 666.67, "amount_eur": 600.0, "reason": "shortfall"},
+        {"from": "USD", "to": "EUR", "amount": 2333.33, "amount_eur": 2100.0, "reason": "idle"},
+    ]
+    assert matching_suggestion(suggestions, "USD", "EUR", 500) == suggestions[0]
+    assert matching_suggestion(suggestions, "GBP", "EUR", 500) is None
+
+
+@pytest.mark.asyncio
+async def test_cash_analytics_tracks_idle_duration(temp_db):
+    await temp_db.set_cash_balances({"EUR": 50.0, "USD": 3000.0})
+    for days_ago, usd in [(30, 100.0), (20, 2500.0), (10, 3000.0), (1, 3000.0)]:
+        await temp_db.save_valuation_snapshot(NOW - days_ago * DAY, {"cash": {"EUR": 50.0, "USD": usd}})
+
+    result = await cash_analytics(
+        temp_db,
+        _getter({"min_cash_buffer": 0, "cash_currency_floors": {"USD": 200}}),
+        _to_eur,
+        [_buy("USD", 4, 100)],
+        total_value_eur=10_000,
+        now=NOW,
+    )
+
+    usd = result["currencies"]["USD"]
+    assert usd["need"]["total"] == 600
+    assert usd["excess_eur"] == pytest.approx(2160)
+    assert usd["idle_since"] == NOW - 20 * DAY
+    assert usd["idle_days"] == 20.0
+    assert result["currencies"]["EUR"]["idle_since"] is None
+    assert [(s["from"], s["to"], s["reason"]) for s in result["suggestions"]] == [("USD", "EUR", "idle")]
+
+
+@pytest.mark.asyncio
+async def test_cash_analytics_without_idle_history(temp_db):
+    await temp_db.set_cash_balances({"USD": 3000.0})
+    result = await cash_analytics(temp_db, _getter({}), _to_eur, [], total_value_eur=0, now=NOW)
+
+    assert result["currencies"]["USD"]["idle_since"] is None
+    assert result["suggestions"] == []
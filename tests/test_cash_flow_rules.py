@@ -0,0 +1,110 @@
+"""Tests for classifying generic cash flows by their description."""
+
+import json
+import os
+import re
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel.cash_flow_rules import CashFlowClassifier, extract_isin, learn_pattern, validate_rule
+from sentinel.database import Database
+
+APPLE = "US0378331005"
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+    await db.upsert_security("AAPL.US", name="Apple", currency="USD", data=json.dumps({"issue_nb": APPLE}))
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ("", "-wal", "-shm"):
+        target = path + ext
+        if os.path.exists(target):
+            os.unlink(target)
+
+
+async def _flow(db, comment, type_id="correction", amount=10.0, date="2026-05-14", source=None):
+    raw = {"date": date, "type_id": type_id, "amount": amount, "comment": comment}
+    if source:
+        raw["source"] = source
+    return await db.upsert_cash_flow(date, type_id, amount, "USD", comment, raw)
+
+
+def test_isin_extraction_checks_the_checksum():
+    assert extract_isin(f"Payout Apple {APPLE} 0.25") == APPLE
+    assert extract_isin("Payout US0378331006") is None
+    assert extract_isin("Deposit") is None
+
+
+def test_learned_pattern_generalises_numbers_and_isins():
+    pattern = learn_pattern(f"Payout  Apple {APPLE} 0.25 per share")
+
+    assert re.search(pattern, f"Payout Apple {APPLE} 0.26 per share", re.IGNORECASE)
+    assert not re.search(pattern, "Payout Microsoft 0.26 per share", re.IGNORECASE)
+
+
+def test_rule_validation():
+    with pytest.raises(ValueError, match="valid regex"):
+        validate_rule({"pattern": "(", "type_id": "dividend"})
+    with pytest.raises(ValueError, match="type_id"):
+        validate_rule({"pattern": "x", "type_id": "bonus"})
+
+
+@pytest.mark.asyncio
+async def test_builtin_rules_classify_dividends_and_taxes(temp_db):
+    dividend = await _flow(temp_db, f"Dividend Apple {APPLE}")
+    tax = await _flow(temp_db, "Withholding tax on dividend", amount=-1.5)
+    other = await _flow(temp_db, "Adjustment 2026-05")
+
+    result = await CashFlowClassifier(temp_db).classify_pending()
+
+    assert result == {"classified": 2, "pending": 1}
+    flow = await temp_db.get_cash_flow(dividend)
+    assert flow["type_id"] == "dividend"
+    assert flow["broker_type_id"] == "correction"
+    assert flow["classified_by"] == "builtin:dividend"
+    assert (flow["isin"], flow["symbol"]) == (APPLE, "AAPL.US")
+    assert (await temp_db.get_cash_flow(tax))["type_id"] == "tax"
+    queue = await CashFlowClassifier(temp_db).review_queue()
+    assert [f["id"] for f in queue] == [other]
+
+
+@pytest.mark.asyncio
+async def test_manual_classification_learns_a_rule(temp_db):
+    first = await _flow(temp_db, "Payout Apple 0.25 per share", date="2026-02-14")
+    second = await _flow(temp_db, "Payout Apple 0.26 per share", date="2026-05-14")
+    elsewhere = await _flow(temp_db, "Payout Apple 0.26 per share", date="2026-05-15", source="csv:bank")
+    classifier = CashFlowClassifier(temp_db)
+
+    result = await classifier.classify(first, "dividend", symbol="aapl.us")
+
+    assert result["cash_flow"]["classified_by"] == "manual"
+    assert result["cash_flow"]["symbol"] == "AAPL.US"
+    assert result["rule"]["broker"] == "tradernet"
+    assert result["classified"] == 1
+    assert (await temp_db.get_cash_flow(second))["classified_by"] == f"rule:{result['rule']['id']}"
+    assert [f["id"] for f in await classifier.review_queue()] == [elsewhere]
+
+
+@pytest.mark.asyncio
+async def test_manual_classification_errors(temp_db):
+    flow = await _flow(temp_db, "")
+    classifier = CashFlowClassifier(temp_db)
+
+    assert await classifier.classify(9999, "dividend") is None
+    with pytest.raises(ValueError, match="no description"):
+        await classifier.classify(flow, "dividend")
+    with pytest.raises(ValueError, match="unknown symbol"):
+        await classifier.classify(flow, "dividend", symbol="NOPE.US", learn=False)
+    result = await classifier.classify(flow, "card", learn=False)
+    assert result["rule"] is None
+    assert result["cash_flow"]["type_id"] == "card"
@@ -0,0 +1,151 @@
+"""Tests for the system clock drift check."""
+
+import os
+import socket
+import struct
+import tempfile
+import threading
+import time
+
+import pytest
+import pytest_asyncio
+
+from sentinel import clock
+from sentinel.clock import CLOCK_STATE_KEY, ClockMonitor, sntp_offset, suspected_drift, validate_drift_threshold
+from sentinel.database import Database
+from sentinel.led.alerts import LAST_ALERT_KEY
+from sentinel.settings import Settings
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    settings._db = db
+    await settings.init_defaults()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _offsets(monkeypatch, ntp=None, http=None):
+    """Stub both time sources; None makes a source fail."""
+
+    def fake_sntp(server, *args, **kwargs):
+        if ntp is None:
+            raise OSError("timed out")
+        return ntp
+
+    async def fake_http(*args, **kwargs):
+        if http is None:
+            raise ValueError("no Date header")
+        return http
+
+    monkeypatch.setattr(clock, "sntp_offset", fake_sntp)
+    monkeypatch.setattr(clock, "http_date_offset", fake_http)
+
+
+def _fake_ntp_server(offset: float) -> tuple[int, threading.Thread]:
+    """Answer one SNTP request with the local time shifted by `offset`."""
+    sock = socket.socket(socket.AF_INET, socket.SOCK_DGRAM)
+    sock.bind(("127.0.0.1", 0))
+    sock.settimeout(5)
+
+    def ntp(ts):
+        ts += 2208988800
+        return struct.pack("!II", int(ts), int((ts % 1) * 2**32))
+
+    def serve():
+        with sock:
+            _, addr = sock.recvfrom(48)
+            now = time.time() + offset
+            sock.sendto(b"\x1c" + 31 * b"\0" + ntp(now) + ntp(now), addr)
+
+    thread = threading.Thread(target=serve)
+    thread.start()
+    return sock.getsockname()[1], thread
+
+
+def test_sntp_offset_reads_server_timestamps():
+    port, thread = _fake_ntp_server(120.0)
+    offset = sntp_offset("127.0.0.1", port=port)
+    thread.join()
+    assert offset == pytest.approx(120.0, abs=0.5)
+
+
+@pytest.mark.parametrize("value", [0, -5, True, "30", None])
+def test_validate_drift_threshold_rejects(value):
+    with pytest.raises(ValueError):
+        validate_drift_threshold(value)
+
+
+@pytest.mark.asyncio
+async def test_check_within_threshold(temp_db, monkeypatch):
+    _offsets(monkeypatch, ntp=1.5)
+
+    state = await ClockMonitor(temp_db).check()
+
+    assert state["suspect"] is False
+    assert state["offset_seconds"] == 1.5
+    assert state["source"] == "ntp:pool.ntp.org"
+    assert await temp_db.get_planner_state(CLOCK_STATE_KEY) == state
+    assert await suspected_drift(temp_db) is None
+
+
+@pytest.mark.asyncio
+async def test_drift_alerts_once(temp_db, monkeypatch):
+    _offsets(monkeypatch, ntp=-95.0)
+    monitor = ClockMonitor(temp_db)
+
+    state = await monitor.check()
+    await monitor.check()
+
+    assert state["suspect"] is True
+    assert await suspected_drift(temp_db) == -95.0
+    alert = await Settings().get(LAST_ALERT_KEY)
+    assert alert["pattern"] == "critical"
+    assert alert["seq"] == 1
+
+
+@pytest.mark.asyncio
+async def test_falls_back_to_http_date(temp_db, monkeypatch):
+    _offsets(monkeypatch, ntp=None, http=40.0)
+
+    state = await ClockMonitor(temp_db).check()
+
+    assert state["source"] == f"http:{clock.HTTP_TIME_URL}"
+    assert state["suspect"] is True
+    assert "ntp pool.ntp.org" in state["error"]
+
+
+@pytest.mark.asyncio
+async def test_failed_check_keeps_previous_verdict(temp_db, monkeypatch):
+    _offsets(monkeypatch, ntp=60.0)
+    await ClockMonitor(temp_db).check()
+    _offsets(monkeypatch)
+
+    state = await ClockMonitor(temp_db).check()
+
+    assert state["offset_seconds"] is None
+    assert state["suspect"] is True
+
+
+@pytest.mark.asyncio
+async def test_threshold_setting(temp_db, monkeypatch):
+    _offsets(monkeypatch, ntp=10.0)
+    await Settings().set("clock_drift_threshold_seconds", 5)
+
+    state = await ClockMonitor(temp_db).check()
+
+    assert state["threshold_seconds"] == 5.0
+    assert state["suspect"] is True
@@ -0,0 +1,125 @@
+"""Tests for concentration breach tracking."""
+
+import os
+import tempfile
+from unittest.mock import AsyncMock, patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.concentration import (
+    ConcentrationMonitor,
+    check_concentration,
+    level_for,
+    validate_concentration_levels,
+    with_duration,
+)
+from sentinel.database import Database
+
+LEVELS = {"warn_pct": 15.0, "critical_pct": 20.0}
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_validate_levels():
+    assert validate_concentration_levels({"warn_pct": 10, "critical_pct": 12.5}) == {
+        "warn_pct": 10.0,
+        "critical_pct": 12.5,
+    }
+    for bad in [
+        None,
+        {"warn_pct": 15},
+        {"warn_pct": 20, "critical_pct": 15},
+        {"warn_pct": 0, "critical_pct": 15},
+        {"warn_pct": 15, "critical_pct": 120},
+        {"warn_pct": True, "critical_pct": 20},
+    ]:
+        with pytest.raises(ValueError):
+            validate_concentration_levels(bad)
+
+
+def test_level_for():
+    assert level_for(14.9, LEVELS) is None
+    assert level_for(15, LEVELS) == "warn"
+    assert level_for(20, LEVELS) == "critical"
+
+
+@pytest.mark.asyncio
+async def test_breach_escalates_and_resolves(temp_db):
+    monitor = ConcentrationMonitor(temp_db, settings=AsyncMock())
+    manager = AsyncMock()
+    with patch("sentinel.led.alerts.AlertManager", return_value=manager):
+        result = await monitor.check({"ASML.EU": 0.16, "AAPL.US": 0.05}, now=1000)
+        assert result == {"opened": ["ASML.EU"], "escalated": [], "resolved": []}
+        manager.trigger.assert_not_awaited()
+
+        result = await monitor.check({"ASML.EU": 0.22}, now=2000)
+        assert result["escalated"] == ["ASML.EU"]
+        manager.trigger.assert_awaited_once()
+
+        # Staying critical does not alert again.
+        await monitor.check({"ASML.EU": 0.21}, now=3000)
+        manager.trigger.assert_awaited_once()
+
+        await monitor.check({"ASML.EU": 0.17}, now=4000)
+        [breach] = await temp_db.get_concentration_breaches()
+        assert (breach["level"], breach["peak_pct"], breach["escalated_at"]) == ("warn", pytest.approx(22), 2000)
+
+        result = await monitor.check({"ASML.EU": 0.10}, now=5000)
+        assert result["resolved"] == ["ASML.EU"]
+
+    assert await temp_db.get_concentration_breaches() == []
+    [breach] = await temp_db.get_concentration_breaches(status="resolved")
+    assert with_duration(breach)["duration_seconds"] == 4000
+    assert breach["last_pct"] == pytest.approx(10)
+
+
+@pytest.mark.asyncio
+async def test_sold_position_resolves_its_breach(temp_db):
+    monitor = ConcentrationMonitor(temp_db)
+    await monitor.check({"ASML.EU": 0.16}, now=1000)
+
+    result = await monitor.check({}, now=1500)
+    assert result["resolved"] == ["ASML.EU"]
+    assert with_duration((await temp_db.get_concentration_breaches(status="all"))[0])["duration_seconds"] == 500
+
+
+@pytest.mark.asyncio
+async def test_levels_setting(temp_db):
+    await temp_db.set_setting("concentration_alert_levels", {"warn_pct": 30, "critical_pct": 40})
+    result = await ConcentrationMonitor(temp_db).check({"ASML.EU": 0.25}, now=1000)
+    assert result["opened"] == []
+
+
+@pytest.mark.asyncio
+async def test_critical_breach_gets_a_reduction_plan(temp_db):
+    planner = AsyncMock()
+    planner.get_current_allocations.return_value = {"ASML.EU": 0.25}
+    plan = {"target_pct": 15.0, "trades": [{"action": "sell", "quantity": 4}]}
+    reduce = AsyncMock(return_value=plan)
+    with (
+        patch("sentinel.concentration.plan_reduction", reduce),
+        patch("sentinel.led.alerts.AlertManager", return_value=AsyncMock()),
+    ):
+        result = await check_concentration(temp_db, planner, broker=None, settings=AsyncMock())
+
+    assert result["escalated"] == ["ASML.EU"]
+    reduce.assert_awaited_once_with(temp_db, None, "ASML.EU", 15.0)
+    [breach] = await temp_db.get_concentration_breaches()
+    assert with_duration(breach)["plan"] == plan
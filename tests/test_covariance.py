@@ -0,0 +1,100 @@
+"""Tests for the covariance estimators."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import numpy as np
+import pytest
+
+from sentinel.covariance import (
+    ESTIMATORS,
+    condition_number,
+    covariance_diagnostics,
+    estimate_covariance,
+    ewma_covariance,
+    ledoit_wolf,
+    load_covariance_estimator,
+    semicovariance,
+    validate_covariance_estimator,
+)
+from sentinel.projections import portfolio_estimates
+
+
+def _returns(days: int, assets: int, seed: int = 0) -> np.ndarray:
+    return np.random.default_rng(seed).normal(0.0, 0.01, size=(days, assets))
+
+
+def test_sample_is_the_unbiased_covariance():
+    matrix = _returns(200, 3)
+    assert np.allclose(estimate_covariance(matrix, "sample"), np.cov(matrix, rowvar=False))
+
+    with pytest.raises(ValueError):
+        estimate_covariance(matrix, "robust")
+
+
+def test_ledoit_wolf_conditions_short_history():
+    matrix = _returns(20, 30)
+
+    assert condition_number(estimate_covariance(matrix, "sample")) is None
+    cov, shrinkage = ledoit_wolf(matrix)
+    assert 0 < shrinkage <= 1
+    assert condition_number(cov) is not None
+    # Shrinkage keeps the average variance.
+    assert np.trace(cov) == pytest.approx(np.trace(np.cov(matrix, rowvar=False)))
+
+
+def test_ewma_weights_recent_days_more():
+    calm_then_volatile = np.concatenate([_returns(300, 1) * 0.2, _returns(60, 1, seed=1) * 3])
+
+    assert ewma_covariance(calm_then_volatile)[0, 0] > 1.5 * np.var(calm_then_volatile, ddof=1)
+
+
+def test_semicovariance_counts_only_the_downside():
+    swing = np.array([[0.01], [-0.01]] * 60)
+
+    assert semicovariance(swing)[0, 0] == pytest.approx(np.var(swing, ddof=1) / 2)
+    assert semicovariance(np.abs(swing))[0, 0] == pytest.approx(np.var(np.abs(swing), ddof=1) / 2)
+
+
+def test_diagnostics_report_every_estimator():
+    diagnostics = covariance_diagnostics(_returns(250, 4))
+
+    assert list(diagnostics) == [e for e in ESTIMATORS if e != "factor"]
+    assert diagnostics["ledoit_wolf"]["shrinkage"] is not None
+    assert diagnostics["sample"]["shrinkage"] is None
+    assert diagnostics["ledoit_wolf"]["condition_number"] <= diagnostics["sample"]["condition_number"]
+    assert all(d["daily_volatility"] > 0 for d in diagnostics.values())
+
+
+def test_factor_estimator_needs_the_factor_covariance():
+    matrix = _returns(250, 2)
+    factor_cov = np.array([[2e-4, 1e-4], [1e-4, 3e-4]])
+
+    assert np.array_equal(estimate_covariance(matrix, "factor", factor_cov), factor_cov)
+    assert np.allclose(estimate_covariance(matrix, "factor"), estimate_covariance(matrix, "sample"))
+    assert list(covariance_diagnostics(matrix, factor_cov=factor_cov)) == list(ESTIMATORS)
+
+
+def test_projection_estimates_use_the_estimator():
+    rows, close = [], 100.0
+    for i, r in enumerate([0.01, -0.01] * 60):
+        close *= 1 + r
+        rows.append({"date": f"2024-{1 + i // 28:02d}-{1 + i % 28:02d}", "close": close})
+
+    sample = portfolio_estimates({"A": 1.0}, {"A": rows})
+    semi = portfolio_estimates({"A": 1.0}, {"A": rows}, "semi")
+    assert semi["monthly_volatility"] == pytest.approx(sample["monthly_volatility"] / np.sqrt(2), rel=0.05)
+    assert semi["monthly_mean"] == sample["monthly_mean"]
+
+
+@pytest.mark.asyncio
+async def test_estimator_setting():
+    assert validate_covariance_estimator("ledoit_wolf") == "ledoit_wolf"
+    with pytest.raises(ValueError):
+        validate_covariance_estimator("shrunk")
+
+    db = MagicMock()
+    db.get_setting = AsyncMock(return_value="ewma")
+    assert await load_covariance_estimator(db) == "ewma"
+    db.get_setting = AsyncMock(return_value="shrunk")
+    assert await load_covariance_estimator(db) == "sample"
+    assert await load_covariance_estimator(object()) == "sample"
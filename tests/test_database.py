@@ -11,13 +11,15 @@ These tests verify the intended behavior of the Database class:
 
 import json
 import os
+import sqlite3
 import tempfile
 from datetime import datetime
 
 import pytest
 import pytest_asyncio
 
-from sentinel.database import Database
+from sentinel.database import PROFILES, Config, Database
+from sentinel.database.config import config_from_env
 
 
 def _ts(iso: str) -> int:
@@ -49,6 +51,13 @@ async def temp_db():
             os.unlink(wal_path)
 
 
+@pytest.fixture
+def db_path():
+    """Path for a database that a test connects itself."""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        yield os.path.join(tmpdir, "test.db")
+
+
 class TestDatabaseConnection:
     """Tests for database connection management."""
 
@@ -99,6 +108,114 @@ class TestDatabaseConnection:
             _ = temp_db.conn
 
 
+class TestConnectionTuning:
+    """Tests for connection profiles and lock contention metrics."""
+
+    async def _pragma(self, db, name):
+        cursor = await db.conn.execute(f"PRAGMA {name}")
+        return (await cursor.fetchone())[0]
+
+    @pytest.mark.asyncio
+    async def test_profile_pragmas_are_applied(self, db_path):
+        db = Database(db_path)
+        await db.connect(PROFILES["sync_heavy"])
+        try:
+            assert db.config.name == "sync_heavy"
+            assert await self._pragma(db, "busy_timeout") == 60000
+            assert await self._pragma(db, "synchronous") == 1  # NORMAL
+            assert await self._pragma(db, "cache_size") == -8192
+            assert await self._pragma(db, "journal_mode") == "wal"
+        finally:
+            await db.close()
+            db.remove_from_cache()
+
+    @pytest.mark.asyncio
+    async def test_busy_error_and_lock_wait_are_counted(self, db_path):
+        db = Database(db_path)
+        await db.connect(Config(busy_timeout_ms=50, lock_wait_threshold_ms=20))
+        other = sqlite3.connect(db_path)
+        try:
+            other.execute("BEGIN IMMEDIATE")
+            with pytest.raises(sqlite3.OperationalError, match="locked"):
+                await db.conn.execute("INSERT INTO settings (key, value) VALUES ('k', 'v')")
+
+            stats = db.contention_stats()
+            assert stats["busy_errors"] == 1
+            assert stats["lock_waits"] == 1
+            assert stats["lock_wait_ms_max"] >= 40
+            assert "INSERT INTO settings" in stats["last_busy_error"]["sql"]
+        finally:
+            other.rollback()
+            other.close()
+            await db.close()
+            db.remove_from_cache()
+
+    @pytest.mark.asyncio
+    async def test_reads_are_not_counted_as_lock_waits(self, temp_db):
+        before = temp_db.contention_stats()
+        await temp_db.get_setting("missing")
+        after = temp_db.contention_stats()
+        assert after["statements"] == before["statements"] + 1
+        assert after["writes"] == before["writes"]
+
+    def test_config_from_env(self, monkeypatch):
+        monkeypatch.delenv("SENTINEL_DB_PROFILE", raising=False)
+        monkeypatch.delenv("SENTINEL_DB_BUSY_TIMEOUT_MS", raising=False)
+        monkeypatch.delenv("SENTINEL_DB_SYNCHRONOUS", raising=False)
+        assert config_from_env() == PROFILES["default"]
+
+        monkeypatch.setenv("SENTINEL_DB_PROFILE", "low_memory")
+        monkeypatch.setenv("SENTINEL_DB_BUSY_TIMEOUT_MS", "5000")
+        monkeypatch.setenv("SENTINEL_DB_SYNCHRONOUS", "full")
+        config = config_from_env()
+        assert (config.name, config.busy_timeout_ms, config.synchronous) == ("low_memory", 5000, "FULL")
+
+    def test_config_from_env_rejects_bad_values(self, monkeypatch):
+        monkeypatch.setenv("SENTINEL_DB_PROFILE", "turbo")
+        with pytest.raises(ValueError, match="turbo"):
+            config_from_env()
+
+        monkeypatch.setenv("SENTINEL_DB_PROFILE", "default")
+        monkeypatch.setenv("SENTINEL_DB_SYNCHRONOUS", "SOMETIMES")
+        with pytest.raises(ValueError):
+            config_from_env()
+
+
+class TestReadReplica:
+    """Tests for the read-only replica connection."""
+
+    @pytest.mark.asyncio
+    async def test_replica_reads_committed_writes(self, temp_db):
+        await temp_db.set_setting("replica_key", "value")
+        assert temp_db.replica is not temp_db
+        assert await temp_db.replica.get_setting("replica_key") == "value"
+
+    @pytest.mark.asyncio
+    async def test_replica_rejects_writes(self, temp_db):
+        with pytest.raises(sqlite3.OperationalError, match="readonly"):
+            await temp_db.replica.set_setting("replica_key", "value")
+
+    @pytest.mark.asyncio
+    async def test_replica_is_not_a_singleton(self, temp_db):
+        assert Database(str(temp_db._path)) is temp_db
+        assert temp_db.replica.replica is temp_db.replica
+        temp_db.replica.remove_from_cache()
+        assert Database(str(temp_db._path)) is temp_db
+
+    @pytest.mark.asyncio
+    async def test_replica_contention_is_reported(self, temp_db):
+        await temp_db.replica.get_setting("missing")
+        assert temp_db.contention_stats()["replica"]["statements"] >= 1
+
+    @pytest.mark.asyncio
+    async def test_close_closes_replica(self, temp_db):
+        replica = temp_db.replica
+        await temp_db.close()
+        assert temp_db.replica is temp_db
+        with pytest.raises(RuntimeError):
+            _ = replica.conn
+
+
 class TestSettings:
     """Tests for settings operations."""
 
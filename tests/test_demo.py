@@ -0,0 +1,70 @@
+"""Tests for the demo mode data generator."""
+
+import os
+import random
+import tempfile
+from datetime import date
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.demo import DEMO_UNIVERSE, _business_days, demo_enabled, generate_prices, seed_demo
+from sentinel.ledger import LedgerService
+
+TODAY = date(2026, 10, 16)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_demo_enabled(monkeypatch):
+    monkeypatch.delenv("SENTINEL_DEMO", raising=False)
+    assert demo_enabled() is False
+    monkeypatch.setenv("SENTINEL_DEMO", "1")
+    assert demo_enabled() is True
+
+
+def test_prices_are_deterministic_per_seed():
+    days = _business_days(date(2026, 1, 1), TODAY)
+
+    first = generate_prices(random.Random(7), days)
+
+    assert first == generate_prices(random.Random(7), days)
+    assert first != generate_prices(random.Random(8), days)
+    assert all(row["low"] <= row["close"] <= row["high"] for rows in first.values() for row in rows)
+
+
+@pytest.mark.asyncio
+async def test_seed_fills_empty_database_once(temp_db):
+    counts = await seed_demo(temp_db, seed=42, today=TODAY)
+
+    assert counts["securities"] == len(DEMO_UNIVERSE)
+    assert counts["trades"] > 0
+    assert await temp_db.get_all_positions()
+    assert await temp_db.get_setting("trading_mode") == "research"
+    assert (await seed_demo(temp_db, seed=42, today=TODAY))["securities"] == 0
+
+
+@pytest.mark.asyncio
+async def test_positions_and_cash_match_ledger_replay(temp_db):
+    await seed_demo(temp_db, seed=42, today=TODAY)
+
+    report = await LedgerService(temp_db).check_consistency()
+
+    assert report["consistent"], report
@@ -0,0 +1,94 @@
+"""Tests for the auto-deploy history and deploy policy."""
+
+import json
+from datetime import datetime
+
+import pytest
+
+from sentinel.deployments import (
+    blocked_commit,
+    deploy_policy,
+    load_deploy_history,
+    validate_deploy_channel,
+    validate_deploy_window,
+)
+
+
+def test_history_is_newest_first_and_skips_bad_lines(tmp_path):
+    path = tmp_path / "deploy-history.jsonl"
+    entries = [
+        {"ts": 1, "status": "success", "from_commit": "a", "to_commit": "b"},
+        {"ts": 2, "status": "rolled_back", "from_commit": "b", "to_commit": "c", "detail": "GET /api/health failed"},
+        {"ts": 3, "status": "success", "from_commit": "b", "to_commit": "d"},
+    ]
+    lines = [json.dumps(entries[0]), json.dumps(entries[1]), "not json", json.dumps(entries[2])]
+    path.write_text("\n".join(lines) + "\n")
+
+    assert [e["ts"] for e in load_deploy_history(path=path)] == [3, 2, 1]
+    assert [e["ts"] for e in load_deploy_history(limit=2, path=path)] == [3, 2]
+
+
+def test_missing_files(tmp_path):
+    assert load_deploy_history(path=tmp_path / "missing.jsonl") == []
+    assert blocked_commit(path=tmp_path / "missing") is None
+
+
+def test_blocked_commit(tmp_path):
+    path = tmp_path / "deploy-failed-commit"
+    path.write_text("c0ffee\n")
+    assert blocked_commit(path=path) == "c0ffee"
+
+
+class _Settings:
+    def __init__(self, **values):
+        self._values = values
+
+    async def get(self, key, default=None):
+        return self._values.get(key, default)
+
+
+NASDAQ_OPEN = {"markets": [{"name": "NASDAQ", "status": "OPEN", "is_open": True}], "any_open": True}
+ALL_CLOSED = {"markets": [{"name": "NASDAQ", "status": "CLOSE", "is_open": False}], "any_open": False}
+
+
+@pytest.mark.asyncio
+async def test_policy_waits_for_markets_to_close():
+    policy = await deploy_policy(_Settings(), NASDAQ_OPEN, now=datetime(2026, 10, 16, 17, 0))
+    assert policy["channel"] == "beta"
+    assert policy["allowed"] is False
+    assert policy["reason"] == "markets open: NASDAQ"
+
+    policy = await deploy_policy(_Settings(deploy_when_markets_closed=False), NASDAQ_OPEN)
+    assert policy["allowed"] is True
+    assert policy["reason"] is None
+
+
+@pytest.mark.asyncio
+async def test_policy_deploy_window_wraps_midnight():
+    settings = _Settings(deploy_channel="stable", deploy_window={"start": "22:00", "end": "06:00"})
+
+    policy = await deploy_policy(settings, ALL_CLOSED, now=datetime(2026, 10, 16, 23, 30))
+    assert (policy["channel"], policy["allowed"]) == ("stable", True)
+
+    policy = await deploy_policy(settings, ALL_CLOSED, now=datetime(2026, 10, 16, 12, 0))
+    assert policy["allowed"] is False
+    assert policy["reason"] == "outside deploy window 22:00-06:00"
+
+
+@pytest.mark.asyncio
+async def test_policy_blocks_on_invalid_settings():
+    policy = await deploy_policy(_Settings(deploy_channel="nightly"), ALL_CLOSED)
+    assert policy["allowed"] is False
+    assert "deploy_channel" in policy["reason"]
+
+
+def test_validators():
+    assert validate_deploy_channel("stable") == "stable"
+    with pytest.raises(ValueError):
+        validate_deploy_channel("nightly")
+    assert validate_deploy_window(None) is None
+    assert validate_deploy_window({"start": " 22:00", "end": "06:00"}) == {"start": "22:00", "end": "06:00"}
+    with pytest.raises(ValueError):
+        validate_deploy_window({"start": "22:00", "end": "22:00"})
+    with pytest.raises(ValueError):
+        validate_deploy_window("22:00-06:00")
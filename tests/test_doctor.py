@@ -0,0 +1,127 @@
+"""Tests for the configuration doctor."""
+
+import os
+import tempfile
+from collections import namedtuple
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.clock import CLOCK_STATE_KEY
+from sentinel.database import Database
+from sentinel.doctor import DISK_FAIL_BYTES, DISK_WARN_BYTES, Doctor
+
+Usage = namedtuple("Usage", "total used free")
+MARKETS = {"markets": [{"name": "NASDAQ", "status": "OPEN", "is_open": True}], "any_open": True}
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _broker(answers=True):
+    broker = MagicMock()
+    broker.connected = True
+    broker.get_market_status = AsyncMock(return_value={"m": []} if answers else None)
+    return broker
+
+
+async def _run(db, broker, markets=MARKETS, free=10 * 1024**3) -> dict:
+    with (
+        patch("sentinel.doctor.universe_market_status", AsyncMock(return_value=markets)),
+        patch("sentinel.doctor.shutil.disk_usage", return_value=Usage(0, 0, free)),
+    ):
+        report = await Doctor(db, broker).run()
+    return {c["name"]: c for c in report["checks"]} | {"status": report["status"]}
+
+
+async def _set_credentials(db):
+    await db.set_setting("tradernet_api_key", "key")
+    await db.set_setting("tradernet_api_secret", "secret")
+
+
+@pytest.mark.asyncio
+async def test_all_pass(temp_db):
+    await _set_credentials(temp_db)
+
+    checks = await _run(temp_db, _broker())
+
+    assert checks["status"] == "pass"
+    assert checks["display"]["detail"] == "Disabled"
+
+
+@pytest.mark.asyncio
+async def test_missing_credentials_warn_and_skip_broker(temp_db):
+    broker = _broker()
+
+    checks = await _run(temp_db, broker)
+
+    assert checks["credentials"]["status"] == "warn"
+    assert checks["broker"]["detail"] == "Skipped: no credentials"
+    assert checks["market_hours"]["status"] == "warn"
+    broker.get_market_status.assert_not_called()
+
+
+@pytest.mark.asyncio
+async def test_half_credentials_fail(temp_db):
+    await temp_db.set_setting("tradernet_api_key", "key")
+
+    checks = await _run(temp_db, _broker())
+
+    assert checks["status"] == "fail"
+    assert checks["credentials"]["hint"] == "Set tradernet_api_secret in Settings"
+
+
+@pytest.mark.asyncio
+async def test_unreachable_broker_fails(temp_db):
+    await _set_credentials(temp_db)
+
+    checks = await _run(temp_db, _broker(answers=False))
+
+    assert checks["broker"]["status"] == "fail"
+    assert checks["broker"]["hint"]
+
+
+@pytest.mark.asyncio
+async def test_market_without_session_hours_warns(temp_db):
+    await _set_credentials(temp_db)
+    markets = {"markets": [{"name": "TSE", "status": "CLOSED", "is_open": False}], "any_open": False}
+
+    checks = await _run(temp_db, _broker(), markets=markets)
+
+    assert checks["market_hours"]["status"] == "warn"
+    assert checks["market_hours"]["detail"] == "No session hours for TSE"
+
+
+@pytest.mark.asyncio
+async def test_disk_thresholds(temp_db):
+    await _set_credentials(temp_db)
+
+    assert (await _run(temp_db, _broker(), free=DISK_WARN_BYTES - 1))["disk"]["status"] == "warn"
+    assert (await _run(temp_db, _broker(), free=DISK_FAIL_BYTES - 1))["disk"]["status"] == "fail"
+
+
+@pytest.mark.asyncio
+async def test_suspect_clock_warns(temp_db):
+    await _set_credentials(temp_db)
+    await temp_db.set_planner_state(CLOCK_STATE_KEY, {"suspect": True, "offset_seconds": -95.0})
+
+    checks = await _run(temp_db, _broker())
+
+    assert checks["clock"]["status"] == "warn"
+    assert checks["clock"]["detail"] == "Clock off by -95s"
@@ -0,0 +1,195 @@
+"""Tests for duplicate security detection and merging."""
+
+import json
+import os
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.duplicates import (
+    canonical_isin,
+    find_duplicates,
+    group_duplicates,
+    merge_securities,
+    merged_overrides,
+    name_similarity,
+)
+from sentinel.identifiers import IdentifierService
+
+SAP_DATA = json.dumps({"issue_nb": "DE0007164600"})
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    IdentifierService(db).invalidate()
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _security(symbol, name, isin=None, currency="EUR", **extra):
+    data = json.dumps({"issue_nb": isin}) if isin else None
+    return {"symbol": symbol, "name": name, "currency": currency, "data": data, "active": 1, **extra}
+
+
+def test_isin_and_name_normalization():
+    assert canonical_isin(" de-0007 164600 ") == "DE0007164600"
+    assert canonical_isin("SAP.EU") is None
+    assert name_similarity("Enel S.p.A.", "ENEL SpA") == 1.0
+    assert name_similarity("Siemens AG", "SIEMENS") == 1.0
+    assert name_similarity("Inc.", "Apple Inc.") == 0.0
+
+
+def test_groups_by_isin_and_name():
+    securities = [
+        _security("SAP.EU", "SAP", "DE0007164600"),
+        _security("SAP.GR", "SAP Xetra", "DE 0007164600"),
+        _security("SAP.US", "SAP ADR", "DE0007164600", currency="USD"),
+        _security("ENEL.EU", "Enel S.p.A.", "IT0003128367"),
+        _security("ENEL.IT", "ENEL SpA"),
+        _security("ENI.EU", "Eni S.p.A.", "IT0003132476"),
+    ]
+
+    groups = group_duplicates(securities, held={"SAP.GR"})
+
+    assert groups == [
+        {
+            "canonical": "ENEL.EU",
+            "isin": "IT0003128367",
+            "duplicates": [{"symbol": "ENEL.IT", "match": "name", "similarity": 1.0}],
+        },
+        {
+            "canonical": "SAP.GR",
+            "isin": "DE0007164600",
+            "duplicates": [{"symbol": "SAP.EU", "match": "isin", "similarity": 1.0}],
+        },
+    ]
+    # A broker symbol mapping outranks the held listing.
+    assert group_duplicates(securities, mapped={"SAP.EU"}, held={"SAP.GR"})[1]["canonical"] == "SAP.EU"
+
+
+def test_different_isins_are_never_name_duplicates():
+    securities = [_security("A.EU", "Alphabet Inc", "US02079K3059"), _security("B.EU", "Alphabet", "US02079K1079")]
+    assert group_duplicates(securities) == []
+
+
+def test_merged_overrides():
+    canonical = {
+        "aliases": "SAP, Walldorf",
+        "user_multiplier": 0.5,
+        "user_multiplier_updated_at": "2026-01-01T00:00:00+00:00",
+        "target_weight_pct": None,
+        "allow_buy": 1,
+        "allow_sell": 1,
+        "active": 0,
+    }
+    duplicate = {
+        "aliases": "sap, SAP Xetra",
+        "user_multiplier": 0.9,
+        "user_multiplier_updated_at": "2026-03-01T00:00:00+00:00",
+        "user_multiplier_source": "clara",
+        "user_multiplier_analysis": "Core holding.",
+        "target_weight_pct": 4.0,
+        "target_weight_mode": "soft",
+        "target_weight_source": "manual",
+        "target_weight_updated_at": "2026-03-01T00:00:00+00:00",
+        "allow_buy": 0,
+        "allow_sell": 1,
+        "active": 1,
+    }
+
+    overrides = merged_overrides(canonical, duplicate)
+
+    assert overrides["aliases"] == "SAP, Walldorf, SAP Xetra"
+    assert (overrides["user_multiplier"], overrides["user_multiplier_analysis"]) == (0.9, "Core holding.")
+    assert (overrides["target_weight_pct"], overrides["target_weight_mode"]) == (4.0, "soft")
+    assert (overrides["allow_buy"], overrides["active"]) == (0, 1)
+    assert "allow_sell" not in overrides
+    assert merged_overrides(duplicate, canonical) == {"aliases": "sap, SAP Xetra, Walldorf"}
+
+
+async def _seed(db):
+    await db.upsert_security("SAP.EU", name="SAP", currency="EUR", active=1, data=SAP_DATA)
+    await db.upsert_security("SAP.GR", name="SAP Xetra", currency="EUR", active=1, data=SAP_DATA, aliases="Xetra")
+    await db.save_prices("SAP.EU", [{"date": "2026-01-02", "close": 100.0}])
+    await db.save_prices("SAP.GR", [{"date": "2026-01-02", "close": 99.0}, {"date": "2026-01-05", "close": 101.0}])
+    await db.upsert_trade("T1", "SAP.GR", "BUY", 5, 90.0, 1767225600, {})
+    await db.upsert_position("SAP.EU", quantity=10, avg_cost=80.0, currency="EUR")
+    await db.upsert_position("SAP.GR", quantity=5, avg_cost=110.0, currency="EUR")
+    group_id = await db.create_security_group("Software")
+    await db.add_security_group_members(group_id, ["SAP.GR"])
+    await db.create_exclusion_list(name="Watch", symbols=["SAP.GR", "SAP.EU"])
+    await db.set_broker_symbol("DE0007164600", "SAP.GR", source="manual")
+    return group_id
+
+
+@pytest.mark.asyncio
+async def test_merge_consolidates_into_the_canonical_record(temp_db):
+    group_id = await _seed(temp_db)
+
+    assert [g["canonical"] for g in await find_duplicates(temp_db)] == ["SAP.GR"]
+    preview = await merge_securities(temp_db, "SAP.EU", "SAP.GR", dry_run=True)
+    assert preview["rows"] == {
+        "prices": 2,
+        "trades": 1,
+        "security_group_members": 1,
+        "positions": 1,
+        "exclusion_lists": 1,
+        "broker_symbols": 1,
+    }
+    assert await temp_db.get_security("SAP.GR") is not None
+
+    result = await merge_securities(temp_db, "SAP.EU", "SAP.GR")
+
+    assert result["rows"]["prices"] == 1
+    assert await temp_db.get_security("SAP.GR") is None
+    assert (await temp_db.get_security("SAP.EU"))["aliases"] == "Xetra"
+    assert [p["close"] for p in await temp_db.get_prices("SAP.EU")] == [101.0, 100.0]
+    assert [t["symbol"] for t in await temp_db.get_trades()] == ["SAP.EU"]
+    position = await temp_db.get_position("SAP.EU")
+    assert (position["quantity"], position["avg_cost"]) == (15, pytest.approx(90.0))
+    assert await temp_db.get_position("SAP.GR") is None
+    assert (await temp_db.get_security_group(group_id))["symbols"] == ["SAP.EU"]
+    assert (await temp_db.get_exclusion_lists())[0]["symbols"] == ["SAP.EU"]
+    assert (await temp_db.get_broker_symbol("DE0007164600"))["broker_symbol"] == "SAP.EU"
+    assert await find_duplicates(temp_db) == []
+
+    ids = IdentifierService(temp_db)
+    for value in ("SAP.GR", "sap:gr", "DE0007164600"):
+        assert await ids.resolve(value) == "SAP.EU"
+
+
+@pytest.mark.asyncio
+async def test_merging_again_repoints_earlier_merges(temp_db):
+    await _seed(temp_db)
+    await temp_db.upsert_security("SAP.DE", name="SAP", currency="EUR", active=1)
+
+    await merge_securities(temp_db, "SAP.EU", "SAP.GR")
+    await merge_securities(temp_db, "SAP.DE", "SAP.EU")
+
+    merges = {m["symbol"]: m["canonical"] for m in await temp_db.get_security_merges()}
+    assert merges == {"SAP.GR": "SAP.DE", "SAP.EU": "SAP.DE"}
+    assert await IdentifierService(temp_db).resolve("SAP.GR") == "SAP.DE"
+
+
+@pytest.mark.asyncio
+async def test_merge_rejects_unknown_and_identical_symbols(temp_db):
+    await _seed(temp_db)
+
+    with pytest.raises(ValueError):
+        await merge_securities(temp_db, "SAP.EU", "SAP.EU")
+    with pytest.raises(LookupError):
+        await merge_securities(temp_db, "SAP.EU", "SAP.US")
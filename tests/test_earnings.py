@@ -0,0 +1,138 @@
+"""Tests for the earnings calendar and the pre-earnings trade freeze."""
+
+import os
+import tempfile
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.earnings import (
+    frozen_symbols,
+    get_earnings_freeze,
+    upcoming_earnings,
+    validate_earnings_date,
+    validate_freeze_days,
+)
+
+TODAY = date(2026, 10, 16)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+class FakeSettings:
+    def __init__(self, values: dict | None = None):
+        self.values = dict(values or {})
+
+    async def get(self, key, default=None):
+        return self.values.get(key, default)
+
+
+class TestValidation:
+    @pytest.mark.parametrize("raw", [0, 3, 30, 5.0])
+    def test_accepts_freeze_days(self, raw):
+        assert validate_freeze_days(raw) == int(raw)
+
+    @pytest.mark.parametrize("raw", [-1, 31, 2.5, "3", True, None])
+    def test_rejects_freeze_days(self, raw):
+        with pytest.raises(ValueError):
+            validate_freeze_days(raw)
+
+    def test_earnings_date_must_be_iso(self):
+        assert validate_earnings_date(" 2026-10-18 ") == "2026-10-18"
+        for raw in ["18/10/2026", "2026-13-01", 20261018, None]:
+            with pytest.raises(ValueError):
+                validate_earnings_date(raw)
+
+
+class TestFreezeWindow:
+    ROWS = [
+        {"symbol": "AAPL.US", "date": "2026-10-19"},
+        {"symbol": "AAPL.US", "date": "2026-10-17"},
+        {"symbol": "MSFT.US", "date": "2026-10-20"},
+        {"symbol": "SAP.EU", "date": "2026-10-15"},
+    ]
+
+    def test_freezes_from_n_days_before_until_the_date(self):
+        assert frozen_symbols(self.ROWS, TODAY, 3) == {"AAPL.US": "2026-10-17"}
+        assert frozen_symbols(self.ROWS, TODAY, 4) == {"AAPL.US": "2026-10-17", "MSFT.US": "2026-10-20"}
+        assert frozen_symbols(self.ROWS, date(2026, 10, 20), 3) == {"MSFT.US": "2026-10-20"}
+
+    def test_zero_days_disables_the_freeze(self):
+        assert frozen_symbols(self.ROWS, TODAY, 0) == {}
+
+    @pytest.mark.asyncio
+    async def test_tolerates_databases_without_earnings_calendar(self):
+        assert await get_earnings_freeze(object(), TODAY, 3) == {}
+
+        db = MagicMock()
+        db.get_earnings_dates = AsyncMock(return_value=self.ROWS)
+        assert await get_earnings_freeze(db, TODAY, 3) == {"AAPL.US": "2026-10-17"}
+        db.get_earnings_dates.assert_awaited_once_with(start_date="2026-10-16", end_date="2026-10-19")
+
+
+@pytest.mark.asyncio
+async def test_upcoming_earnings_lists_held_positions(temp_db):
+    await temp_db.upsert_security("AAPL.US", name="Apple Inc.", currency="USD")
+    await temp_db.upsert_security("MSFT.US", name="Microsoft", currency="USD")
+    await temp_db.upsert_position("AAPL.US", quantity=5, avg_cost=150.0)
+    await temp_db.upsert_earnings_date("AAPL.US", "2026-10-18")
+    await temp_db.upsert_earnings_date("AAPL.US", "2026-12-01", source="feed")
+    await temp_db.upsert_earnings_date("MSFT.US", "2026-10-17")
+
+    result = await upcoming_earnings(temp_db, FakeSettings({"earnings_freeze_days": 3}), as_of=TODAY)
+
+    assert result["freeze_days"] == 3
+    assert [(e["symbol"], e["date"], e["days_until"], e["frozen"]) for e in result["earnings"]] == [
+        ("AAPL.US", "2026-10-18", 2, True)
+    ]
+    assert result["earnings"][0]["name"] == "Apple Inc."
+
+
+@pytest.mark.asyncio
+async def test_security_earnings_endpoints(temp_db):
+    from sentinel.api.routers.securities import (
+        delete_security_earnings,
+        get_security_earnings,
+        put_security_earnings,
+    )
+
+    deps = MagicMock()
+    deps.db = temp_db
+    await temp_db.upsert_security("AAPL.US", name="Apple Inc.", currency="USD")
+
+    with pytest.raises(HTTPException) as exc:
+        await put_security_earnings("AAPL.US", {"date": "next week"}, deps)
+    assert exc.value.status_code == 400
+    with pytest.raises(HTTPException) as exc:
+        await put_security_earnings("NOPE.US", {"date": "2026-10-18"}, deps)
+    assert exc.value.status_code == 404
+
+    await put_security_earnings("AAPL.US", {"date": "2026-10-18"}, deps)
+    listed = await put_security_earnings("AAPL.US", {"date": "2026-10-18", "source": "ir-site"}, deps)
+    assert [(e["date"], e["source"]) for e in listed] == [("2026-10-18", "ir-site")]
+
+    await delete_security_earnings("AAPL.US", "2026-10-18", deps)
+    assert await get_security_earnings("AAPL.US", deps) == []
+    with pytest.raises(HTTPException) as exc:
+        await delete_security_earnings("AAPL.US", "2026-10-18", deps)
+    assert exc.value.status_code == 404
@@ -0,0 +1,96 @@
+"""Tests for optional SQLCipher encryption at rest."""
+
+import sqlite3
+
+import pytest
+
+from sentinel.database import PROFILES, Database, encryption
+from sentinel.database.encryption import PASSPHRASE_ENV, connect, database_key, decrypt_database, encrypt_database
+
+
+class TestKeys:
+    def test_no_passphrase_means_no_key(self, monkeypatch):
+        monkeypatch.delenv(PASSPHRASE_ENV, raising=False)
+        assert database_key("/data/sentinel.db") is None
+
+    def test_key_is_deterministic_and_per_file(self, monkeypatch):
+        monkeypatch.setenv(PASSPHRASE_ENV, "correct horse")
+        key = database_key("/data/sentinel.db")
+        assert len(key) == 64
+        assert database_key("/elsewhere/sentinel.db") == key
+        assert database_key("/data/sentinel_fundamentals.db") != key
+        assert database_key("/data/sentinel.db", "battery staple") != key
+
+    def test_plaintext_connection_without_key(self, tmp_path):
+        connection = connect(tmp_path / "plain.db", None)
+        try:
+            assert isinstance(connection, sqlite3.Connection)
+        finally:
+            connection.close()
+
+    def test_missing_driver_is_explained(self, monkeypatch, tmp_path):
+        monkeypatch.setattr(encryption, "sqlcipher", None)
+        with pytest.raises(RuntimeError, match="sqlcipher3"):
+            connect(tmp_path / "secret.db", "00" * 32)
+
+    def test_encrypted_profile(self):
+        assert PROFILES["encrypted"].cache_size_kib > PROFILES["default"].cache_size_kib
+        assert PROFILES["encrypted"].synchronous == "NORMAL"
+
+    def test_encrypt_requires_passphrase(self, monkeypatch, tmp_path):
+        monkeypatch.delenv(PASSPHRASE_ENV, raising=False)
+        with pytest.raises(RuntimeError, match=PASSPHRASE_ENV):
+            encrypt_database(tmp_path / "sentinel.db")
+
+
+class TestSQLCipher:
+    @pytest.fixture(autouse=True)
+    def _driver(self, monkeypatch):
+        pytest.importorskip("sqlcipher3")
+        monkeypatch.setenv(PASSPHRASE_ENV, "correct horse")
+
+    @pytest.mark.asyncio
+    async def test_database_opens_encrypted(self, tmp_path):
+        path = tmp_path / "sentinel.db"
+        db = Database(str(path))
+        try:
+            await db.connect()
+            assert db.encrypted
+            await db.set_setting("currency", "EUR")
+            assert await db.get_setting("currency") == "EUR"
+        finally:
+            await db.close()
+            db.remove_from_cache()
+        with open(path, "rb") as f:
+            assert not f.read(16).startswith(b"SQLite format 3")
+
+    def test_wrong_passphrase_is_rejected(self, monkeypatch, tmp_path):
+        path = tmp_path / "sentinel.db"
+        connection = connect(path, database_key(path))
+        connection.execute("CREATE TABLE t (x)")
+        connection.commit()
+        connection.close()
+        with pytest.raises(ValueError, match="wrong passphrase"):
+            connect(path, database_key(path, "battery staple"))
+
+    def test_encrypt_and_decrypt_round_trip(self, tmp_path):
+        path = tmp_path / "sentinel.db"
+        plain = sqlite3.connect(path)
+        plain.execute("CREATE TABLE t (x)")
+        plain.execute("INSERT INTO t VALUES (42)")
+        plain.commit()
+        plain.close()
+
+        result = encrypt_database(path)
+        assert result["original"].endswith(".unencrypted")
+        connection = connect(path, database_key(path))
+        assert connection.execute("SELECT x FROM t").fetchone()[0] == 42
+        connection.close()
+        with pytest.raises(ValueError, match="already encrypted"):
+            encrypt_database(path)
+
+        (tmp_path / "sentinel.db.unencrypted").unlink()
+        decrypt_database(path)
+        plain = sqlite3.connect(path)
+        assert plain.execute("SELECT x FROM t").fetchone()[0] == 42
+        plain.close()
@@ -0,0 +1,161 @@
+"""Tests for ESG/custom exclusion lists."""
+
+import json
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.exclusions import (
+    describe_exclusion,
+    exclusion_matches,
+    get_excluded_securities,
+    security_isin,
+    validate_exclusion_list,
+)
+from sentinel.security import Security
+
+NO_TOBACCO = {
+    "id": 1,
+    "name": "No tobacco",
+    "reason": "Personal values",
+    "symbols": ["MO.US"],
+    "isins": ["GB0002875804"],
+    "industries": ["Tobacco"],
+    "active": 1,
+}
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+class TestValidation:
+    def test_normalizes_entries(self):
+        fields = validate_exclusion_list(
+            {
+                "name": " No tobacco ",
+                "symbols": ["mo.us", "MO.US"],
+                "isins": ["gb0002875804"],
+                "industries": ["Tobacco"],
+            }
+        )
+        assert fields == {
+            "name": "No tobacco",
+            "symbols": ["MO.US"],
+            "isins": ["GB0002875804"],
+            "industries": ["Tobacco"],
+        }
+
+    @pytest.mark.parametrize(
+        "data",
+        [
+            {"name": "Empty"},
+            {"name": "", "symbols": ["MO.US"]},
+            {"name": "Bad ISIN", "isins": ["US03783310"]},
+            {"name": "Bad list", "symbols": "MO.US"},
+            {"name": "Bad active", "symbols": ["MO.US"], "active": 1},
+        ],
+    )
+    def test_rejects_malformed_lists(self, data):
+        with pytest.raises(ValueError):
+            validate_exclusion_list(data)
+
+    def test_partial_update_may_switch_off(self):
+        assert validate_exclusion_list({"active": False}, partial=True) == {"active": 0}
+
+
+class TestMatching:
+    def test_matches_symbol_isin_and_industry(self):
+        by_symbol = {"symbol": "MO.US", "industry": "Food"}
+        by_isin = {"symbol": "BATS.EU", "data": json.dumps({"issue_nb": "GB0002875804"})}
+        by_industry = {"symbol": "PM.US", "industry": "tobacco"}
+
+        assert [m["matched"] for m in exclusion_matches(by_symbol, [NO_TOBACCO])] == ["symbol"]
+        assert [m["matched"] for m in exclusion_matches(by_isin, [NO_TOBACCO])] == ["isin"]
+        assert [m["matched"] for m in exclusion_matches(by_industry, [NO_TOBACCO])] == ["industry"]
+        assert exclusion_matches({"symbol": "AAPL.US", "industry": "Technology"}, [NO_TOBACCO]) == []
+
+    def test_inactive_lists_do_not_match(self):
+        assert exclusion_matches({"symbol": "MO.US"}, [{**NO_TOBACCO, "active": 0}]) == []
+
+    def test_isin_falls_back_to_quote_data(self):
+        assert security_isin({"data": "{}", "quote_data": json.dumps({"issue_nb": "us0378331005"})}) == "US0378331005"
+        assert security_isin({"data": "not json"}) is None
+
+    def test_describe(self):
+        matches = exclusion_matches({"symbol": "MO.US"}, [NO_TOBACCO])
+        assert describe_exclusion(matches) == "Excluded by No tobacco (symbol): Personal values"
+
+
+@pytest.mark.asyncio
+async def test_buy_refuses_excluded_security():
+    db = MagicMock()
+    db.get_trades = AsyncMock(return_value=[])
+    db.get_exclusion_lists = AsyncMock(return_value=[NO_TOBACCO])
+    broker = MagicMock()
+    broker.buy = AsyncMock(return_value="ORDER123")
+
+    security = Security("MO.US", db=db, broker=broker)
+    security._data = {"symbol": "MO.US", "currency": "EUR", "min_lot": 1, "allow_buy": 1, "allow_sell": 1}
+
+    with pytest.raises(ValueError, match="No tobacco"):
+        await security.buy(10)
+    broker.buy.assert_not_called()
+    db.get_exclusion_lists.assert_awaited_once_with(active_only=True)
+
+
+@pytest.mark.asyncio
+async def test_exclusion_endpoints(temp_db):
+    from sentinel.api.routers.exclusions import (
+        create_exclusion_list,
+        delete_exclusion_list,
+        get_excluded_securities_endpoint,
+        get_exclusion_lists,
+        update_exclusion_list,
+    )
+
+    deps = MagicMock()
+    deps.db = temp_db
+    await temp_db.upsert_security("PM.US", name="Philip Morris", industry="Tobacco")
+    await temp_db.upsert_security("AAPL.US", name="Apple", industry="Technology")
+
+    with pytest.raises(HTTPException) as exc:
+        await create_exclusion_list({"name": "Nothing"}, deps)
+    assert exc.value.status_code == 400
+
+    created = await create_exclusion_list({"name": "No tobacco", "industries": ["Tobacco"]}, deps)
+    listed = await get_exclusion_lists(deps)
+    assert listed["lists"][0]["industries"] == ["Tobacco"]
+    assert listed["lists"][0]["symbols"] == []
+
+    excluded = await get_excluded_securities_endpoint(deps)
+    assert [(s["symbol"], s["reason"]) for s in excluded["securities"]] == [
+        ("PM.US", "Excluded by No tobacco (industry)")
+    ]
+
+    await update_exclusion_list(created["id"], {"active": False}, deps)
+    assert await get_excluded_securities(temp_db) == {}
+
+    await delete_exclusion_list(created["id"], deps)
+    with pytest.raises(HTTPException) as exc:
+        await update_exclusion_list(created["id"], {"name": "Gone"}, deps)
+    assert exc.value.status_code == 404
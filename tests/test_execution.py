@@ -0,0 +1,160 @@
+"""Tests for the execution policy: venue rate limits, session edges and TWAP slicing."""
+
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.execution import (
+    ExecutionPolicy,
+    ExecutionThrottle,
+    child_quantity,
+    session_position,
+    validate_execution_policy,
+)
+from sentinel.planner.models import TradeRecommendation
+
+DAY = 1760572800  # 2025-10-16 00:00 UTC
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    await db.upsert_security("ASML.EU", name="ASML", data='{"mrkt": {"mkt_id": 5}}')
+    await db.upsert_security("SAP.EU", name="SAP", data='{"mrkt": {"mkt_id": 5}}')
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _rec(symbol="ASML.EU", quantity=10, price=600.0, lot_size=1):
+    return TradeRecommendation(
+        symbol=symbol,
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=5.0,
+        allocation_delta=5.0,
+        current_value_eur=0.0,
+        target_value_eur=quantity * price,
+        value_delta_eur=quantity * price,
+        quantity=quantity,
+        price=price,
+        currency="EUR",
+        lot_size=lot_size,
+        contrarian_score=0.0,
+        priority=1.0,
+        reason="test",
+    )
+
+
+def _broker(now="10:30:00"):
+    broker = MagicMock()
+    broker.get_market_status = AsyncMock(
+        return_value={"t": f"2025-10-16 {now}", "m": [{"i": 5, "s": "OPEN", "o": "10:00:00", "c": "18:30:00"}]}
+    )
+    return broker
+
+
+def test_validate_policy():
+    assert validate_execution_policy({"twap_slices": 4, "twap_min_value_eur": 2500.5}) == {
+        "max_orders_per_minute": 0,
+        "avoid_open_minutes": 0,
+        "avoid_close_minutes": 0,
+        "twap_slices": 4,
+        "twap_min_value_eur": 2500.5,
+    }
+    for bad in [None, {"bogus": 1}, {"twap_slices": 0}, {"avoid_open_minutes": -1}, {"max_orders_per_minute": 1.5}]:
+        with pytest.raises(ValueError):
+            validate_execution_policy(bad)
+    assert not ExecutionPolicy().active
+
+
+def test_session_position_and_child_quantity():
+    status = {"t": "2025-10-16 01:00:00", "m": [{"i": 7, "o": "22:00:00", "c": "02:00:00"}]}
+    assert session_position(status, "7") == (180, 60)
+    assert session_position(status, "8") is None
+    assert session_position({"m": status["m"]}, "7") is None
+    assert child_quantity(10, 4) == 3
+    assert child_quantity(10, 4, min_lot=5) == 5
+    assert child_quantity(7, 1) == 7
+
+
+@pytest.mark.asyncio
+async def test_session_edges(temp_db):
+    policy = ExecutionPolicy(avoid_open_minutes=45, avoid_close_minutes=15)
+    assert (await ExecutionThrottle(temp_db, _broker("10:30:00"), policy).check(_rec(), now=DAY))[0] == (
+        "within the first 45 minutes of the session"
+    )
+    assert (await ExecutionThrottle(temp_db, _broker("18:20:00"), policy).check(_rec(), now=DAY))[0] == (
+        "within the last 15 minutes of the session"
+    )
+    assert await ExecutionThrottle(temp_db, _broker("12:00:00"), policy).check(_rec(), now=DAY) == (None, 10)
+
+
+@pytest.mark.asyncio
+async def test_rate_limit_per_venue(temp_db):
+    policy = ExecutionPolicy(max_orders_per_minute=1)
+    throttle = ExecutionThrottle(temp_db, _broker(), policy)
+    with patch("time.time", return_value=DAY):
+        await temp_db.insert_order(order_id="1", symbol="SAP.EU", side="buy", quantity=1, source="planner")
+
+    blocked, _ = await throttle.check(_rec(), now=DAY + 30)
+    assert blocked == "1 orders on venue 5 in the last minute"
+    assert (await throttle.check(_rec(), now=DAY + 61))[0] is None
+
+
+@pytest.mark.asyncio
+async def test_twap_slices_and_spacing(temp_db):
+    policy = ExecutionPolicy(twap_slices=3, twap_min_value_eur=1000)
+    throttle = ExecutionThrottle(temp_db, _broker("12:30:00"), policy)
+    now = DAY + 12 * 3600
+
+    assert await throttle.check(_rec(quantity=1, price=100.0), now=now) == (None, 1)  # below the TWAP size
+
+    assert await throttle.check(_rec(quantity=10), now=now) == (None, 4)
+    await throttle.record(_rec(quantity=10), now=now)
+    # 360 minutes to the close left for 2 slices -> one every 180 minutes.
+    blocked, _ = await throttle.check(_rec(quantity=6), now=now + 60)
+    assert blocked == f"next TWAP slice of ASML.EU due at {now + 180 * 60}"
+
+    assert await throttle.check(_rec(quantity=6), now=now + 180 * 60) == (None, 3)
+    await throttle.record(_rec(quantity=6), now=now + 180 * 60)
+    # The last slice takes whatever is still missing.
+    assert await throttle.check(_rec(quantity=3), now=now + 540 * 60) == (None, 3)
+
+    # A new day starts a new schedule.
+    await temp_db.set_planner_state("execution:twap:ASML.EU:buy", {"day": "2025-10-15", "slices_done": 2, "next_at": 0})
+    assert await throttle.check(_rec(quantity=9), now=now) == (None, 3)
+
+
+@pytest.mark.asyncio
+async def test_submit_trade_places_twap_slice(temp_db):
+    from sentinel.jobs.tasks import submit_trade
+
+    policy = ExecutionPolicy(twap_slices=2)
+    execute = AsyncMock(return_value="901")
+    with (
+        patch("sentinel.execution.load_execution_policy", AsyncMock(return_value=policy)),
+        patch("sentinel.jobs.tasks._open_order_for", AsyncMock(return_value=None)),
+        patch("sentinel.jobs.tasks.order_warnings", AsyncMock()),
+        patch("sentinel.jobs.tasks._execute_trade", execute),
+        patch("sentinel.led.alerts.AlertManager", return_value=AsyncMock()),
+    ):
+        assert await submit_trade(temp_db, _broker(), _rec(quantity=10)) == "901"
+
+    assert execute.await_args.args[1].quantity == 5
+    state = await temp_db.get_planner_state("execution:twap:ASML.EU:buy")
+    assert state["slices_done"] == 1
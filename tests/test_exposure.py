@@ -0,0 +1,122 @@
+"""Tests for short position and margin detection."""
+
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.exposure import (
+    EXPOSURE_STATE_KEY,
+    AccountExposureError,
+    account_exposure,
+    check_account_exposure,
+    describe_exposure,
+)
+from sentinel.health import HealthService
+from sentinel.planner import Planner
+from sentinel.planner.models import PlannerState
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _currency():
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, code: amount if code == "EUR" else amount / 2)
+    return currency
+
+
+def test_planner_state_shorts_and_margin():
+    state = PlannerState(
+        positions=[{"symbol": "AAA", "quantity": 5}, {"symbol": "XYZ", "quantity": -3, "value_eur": -300.0}],
+        cash_balances={"EUR": -1250.0},
+    )
+    exposure = account_exposure(state.short_positions(), state.margin_eur())
+
+    assert exposure == {
+        "ok": False,
+        "shorts": [{"symbol": "XYZ", "quantity": -3.0, "value_eur": -300.0}],
+        "margin_eur": 1250.0,
+    }
+    assert describe_exposure(exposure) == "Refusing to plan: account is short 3 XYZ, 1250.00 EUR borrowed on margin"
+    assert account_exposure([], 0)["ok"]
+    assert PlannerState(positions=[], cash_balances={"EUR": 10.0}).margin_eur() == 0.0
+
+
+@pytest.mark.asyncio
+async def test_check_alerts_once_and_clears(temp_db):
+    await temp_db.upsert_position("XYZ.US", quantity=-2, current_price=50.0, currency="USD")
+    # USD below zero but covered by EUR is not margin.
+    await temp_db.set_cash_balances({"EUR": 500.0, "USD": -200.0})
+    alerts = AsyncMock()
+
+    with patch("sentinel.led.alerts.AlertManager", return_value=alerts):
+        exposure = await check_account_exposure(temp_db, _currency(), MagicMock())
+        assert exposure["shorts"] == [{"symbol": "XYZ.US", "quantity": -2.0, "value_eur": -50.0}]
+        assert exposure["margin_eur"] == 0
+        await check_account_exposure(temp_db, _currency(), MagicMock())
+        alerts.trigger.assert_awaited_once()
+
+        await temp_db.upsert_position("XYZ.US", quantity=0)
+        assert (await check_account_exposure(temp_db, _currency(), MagicMock()))["ok"]
+
+    assert (await temp_db.get_planner_state(EXPOSURE_STATE_KEY))["ok"]
+
+
+@pytest.mark.asyncio
+async def test_portfolio_values_shorts(temp_db):
+    from sentinel.portfolio import Portfolio
+
+    await temp_db.upsert_position("AAA.EU", quantity=10, current_price=20.0, currency="EUR")
+    await temp_db.upsert_position("XYZ.EU", quantity=-2, current_price=30.0, currency="EUR")
+    await temp_db.set_cash_balances({"EUR": 100.0})
+    settings = MagicMock()
+    settings.get = AsyncMock(return_value=None)
+    portfolio = Portfolio(db=temp_db, broker=MagicMock(), settings=settings, currency=_currency())
+
+    assert await portfolio.total_value() == pytest.approx(240.0)
+
+
+@pytest.mark.asyncio
+async def test_planner_refuses_exposed_account():
+    planner = Planner(db=MagicMock(), broker=MagicMock(), portfolio=MagicMock())
+    planner._planning_inputs = AsyncMock()
+    state = PlannerState(positions=[{"symbol": "XYZ", "quantity": -3}], cash_balances={"EUR": 100.0})
+
+    assert await planner.get_recommendations(state=state) == []
+    with pytest.raises(AccountExposureError, match="short 3 XYZ"):
+        await planner.get_recommendations_with_plan(state=state)
+    planner._planning_inputs.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_health_report_degraded_while_exposed(temp_db):
+    await temp_db.set_planner_state(EXPOSURE_STATE_KEY, account_exposure([], 800.0))
+    broker = MagicMock(connected=True)
+
+    with patch.object(HealthService, "_migrations_check", return_value={"ok": True, "detail": None}):
+        report = await HealthService(temp_db, broker).report(scheduler_running=True)
+
+    assert report["checks"]["account"] == {
+        "ok": False,
+        "detail": "Refusing to plan: account is 800.00 EUR borrowed on margin",
+    }
+    assert report["status"] == "degraded"
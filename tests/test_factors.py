@@ -0,0 +1,146 @@
+"""Tests for the index factor model."""
+
+import os
+import tempfile
+from datetime import date, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import numpy as np
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.factors import (
+    FactorModel,
+    factor_returns,
+    load_factor_model,
+    regress_security,
+    update_security_factors,
+)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ("", "-wal", "-shm"):
+        target = path + ext
+        if os.path.exists(target):
+            os.unlink(target)
+
+
+def _dates(n: int) -> list[str]:
+    return [(date(2023, 1, 2) + timedelta(days=i)).isoformat() for i in range(n)]
+
+
+def _factors(n: int = 300) -> dict[str, dict[str, float]]:
+    rng = np.random.default_rng(0)
+    dates = _dates(n)
+    return {
+        name: dict(zip(dates, rng.normal(0.0, scale, n), strict=True))
+        for name, scale in (("market", 0.01), ("size", 0.005), ("region:DE", 0.004), ("region:US", 0.004))
+    }
+
+
+def _security_returns(factors: dict[str, dict[str, float]]) -> dict[str, float]:
+    noise = np.random.default_rng(1).normal(0.0, 0.002, len(factors["market"]))
+    return {
+        d: 0.0001 + 1.2 * m - 0.3 * factors["size"][d] + 0.8 * factors["region:DE"][d] + e
+        for (d, m), e in zip(factors["market"].items(), noise, strict=True)
+    }
+
+
+def test_regression_recovers_the_exposures():
+    factors = _factors()
+
+    exposure = regress_security(_security_returns(factors), factors, "DE")
+
+    assert exposure["region"] == "DE"
+    assert exposure["betas"] == {
+        "market": pytest.approx(1.2, abs=0.05),
+        "size": pytest.approx(-0.3, abs=0.1),
+        "region:DE": pytest.approx(0.8, abs=0.1),
+    }
+    assert exposure["r_squared"] > 0.9
+    assert exposure["residual_vol"] == pytest.approx(0.002 * np.sqrt(252), rel=0.15)
+    assert exposure["samples"] == 300
+
+
+def test_regression_needs_history_and_skips_the_all_region():
+    factors = _factors()
+    returns = _security_returns(factors)
+
+    assert regress_security(dict(list(returns.items())[:100]), factors, "DE") is None
+    assert set(regress_security(returns, factors, "ALL")["betas"]) == {"market", "size"}
+
+
+def test_factor_returns_from_index_prices():
+    def rows(*closes):
+        return [{"date": d, "close": c} for d, c in zip(_dates(len(closes)), closes, strict=True)]
+
+    factors = factor_returns({"SP500.IDX": rows(100, 101), "RUT.IDX": rows(100, 103), "DAX.IDX": rows(100, 98)})
+    day = _dates(2)[1]
+
+    assert factors["market"][day] == pytest.approx(0.02 / 3)
+    assert factors["size"][day] == pytest.approx(0.02)
+    assert factors["region:US"][day] == pytest.approx(0.02 - 0.02 / 3)
+    assert factors["region:DE"][day] == pytest.approx(-0.02 - 0.02 / 3)
+    assert "region:UK" not in factors
+    assert factor_returns({}) == {}
+
+
+def test_factor_model_covariance():
+    model = FactorModel(
+        names=["market"],
+        factor_covariance=np.array([[1e-4]]),
+        exposures={
+            "A": {"betas": {"market": 1.0}, "residual_vol": 0.0},
+            "B": {"betas": {"market": 2.0}, "residual_vol": 0.01 * np.sqrt(252)},
+        },
+    )
+
+    assert np.allclose(model.covariance(["A", "B"]), [[1e-4, 2e-4], [2e-4, 5e-4]])
+    assert model.covariance(["A", "C"]) is None
+
+
+@pytest.mark.asyncio
+async def test_update_stores_the_exposures():
+    factors = _factors()
+    prices, close = [], 100.0
+    for d, r in _security_returns(factors).items():
+        close *= 1 + r
+        prices.append({"date": d, "close": close})
+    db = MagicMock()
+    db.get_prices = AsyncMock(return_value=[{"date": "2023-01-01", "close": 100.0}] + prices)
+    db.upsert_factor_exposure = AsyncMock()
+
+    exposure = await update_security_factors(db, {"symbol": "SAP.DE", "geography": "DE"}, factors, now=5)
+
+    assert exposure["betas"]["market"] == pytest.approx(1.2, abs=0.05)
+    db.upsert_factor_exposure.assert_awaited_once_with("SAP.DE", updated_at=5, **exposure)
+
+
+@pytest.mark.asyncio
+async def test_exposures_round_trip(temp_db):
+    await temp_db.upsert_factor_exposure("SAP.DE", "DE", {"market": 0.9}, 0.02, 0.15, 0.5, 700, 1)
+    await temp_db.upsert_factor_exposure("SAP.DE", "DE", {"market": 1.1, "region:DE": 0.6}, 0.01, 0.14, 0.6, 720, 2)
+
+    exposures = await temp_db.get_factor_exposures()
+    assert list(exposures) == ["SAP.DE"]
+    assert exposures["SAP.DE"]["betas"] == {"market": 1.1, "region:DE": 0.6}
+    assert exposures["SAP.DE"]["updated_at"] == 2
+    assert await temp_db.get_factor_exposures([]) == {}
+    assert await temp_db.get_factor_exposures(["AAPL.US"]) == {}
+
+
+@pytest.mark.asyncio
+async def test_no_model_without_exposures(temp_db):
+    assert await load_factor_model(temp_db) is None
+    assert await load_factor_model(object()) is None
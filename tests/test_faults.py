@@ -0,0 +1,124 @@
+"""Tests for research-mode fault injection."""
+
+import sqlite3
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.database.config import ContentionStats, InstrumentedConnection
+from sentinel.faults import (
+    DEFAULT_CONFIG,
+    FaultInjected,
+    FaultInjector,
+    FaultyClient,
+    injector,
+    validate_fault_injection,
+)
+
+
+@pytest.fixture
+def faults():
+    """The shared injector, switched back off after the test."""
+    yield injector
+    injector.configure(dict(DEFAULT_CONFIG), "research")
+
+
+def _config(**overrides):
+    return validate_fault_injection({"enabled": True, "seed": 1, **overrides})
+
+
+def test_validate_fills_defaults():
+    assert validate_fault_injection({"job_failure": 0.5})["broker_timeout"] == 0.0
+
+
+@pytest.mark.parametrize(
+    "raw",
+    [
+        [],
+        {"unknown": 1},
+        {"enabled": 1},
+        {"seed": 1.5},
+        {"broker_timeout": 1.5},
+        {"sqlite_busy": -0.1},
+        {"job_failure": True},
+    ],
+)
+def test_validate_rejects(raw):
+    with pytest.raises(ValueError):
+        validate_fault_injection(raw)
+
+
+def test_inactive_unless_enabled_in_research_mode():
+    faults = FaultInjector()
+    faults.configure(_config(job_failure=1.0), "live")
+    faults.maybe_fail("job_failure", "sync:trades")
+    faults.configure(_config(job_failure=1.0, enabled=False), "research")
+    faults.maybe_fail("job_failure", "sync:trades")
+
+    assert not faults.active
+    assert faults.counts["job_failure"] == 0
+
+
+def test_rate_and_seed_are_reproducible():
+    def run():
+        faults = FaultInjector()
+        faults.configure(_config(job_failure=0.3, seed=42), "research")
+        outcomes = []
+        for _ in range(200):
+            try:
+                faults.maybe_fail("job_failure", "job")
+                outcomes.append(False)
+            except FaultInjected:
+                outcomes.append(True)
+        return outcomes
+
+    first = run()
+    assert first == run()
+    assert 30 < sum(first) < 90
+
+
+@pytest.mark.asyncio
+async def test_load_reads_settings():
+    values = {"fault_injection": {"enabled": True, "broker_timeout": 1.0}, "trading_mode": "research"}
+
+    async def get(key, default=None):
+        return values.get(key, default)
+
+    faults = FaultInjector()
+    await faults.load(get)
+    assert faults.active
+    values["trading_mode"] = "live"
+    await faults.load(get)
+    assert not faults.active
+    assert faults.status()["config"]["broker_timeout"] == 1.0
+
+
+def test_faulty_client_times_out_sdk_calls(faults):
+    client = MagicMock()
+    client.get_quotes.return_value = {"quotes": []}
+    client.timeout = 30
+    wrapped = FaultyClient(client)
+
+    assert wrapped.get_quotes(["ACME.EU"]) == {"quotes": []}
+    faults.configure(_config(broker_timeout=1.0), "research")
+    assert wrapped.timeout == 30
+    with pytest.raises(TimeoutError):
+        wrapped.get_quotes(["ACME.EU"])
+    assert client.get_quotes.call_count == 1
+    assert faults.counts["broker_timeout"] >= 1
+
+
+@pytest.mark.asyncio
+async def test_instrumented_connection_injects_busy_errors(faults):
+    connection = MagicMock()
+    connection.execute = AsyncMock(return_value="cursor")
+    stats = ContentionStats(20)
+    conn = InstrumentedConnection(connection, stats)
+
+    assert await conn.execute("SELECT 1") == "cursor"
+    faults.configure(_config(sqlite_busy=1.0), "research")
+    with pytest.raises(sqlite3.OperationalError, match="database is locked"):
+        await conn.execute("INSERT INTO t VALUES (?)", (1,))
+
+    assert connection.execute.await_count == 1
+    assert stats.busy_errors == 1
@@ -0,0 +1,102 @@
+"""Tests for fee and commission analytics."""
+
+from datetime import date, datetime, timezone
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.fee_analytics import build_fee_analytics, is_fee_flow, summarize
+
+
+def _trade(day, symbol="ACME.EU", value=1000.0, commission=2.0, id=1):
+    return {"id": id, "symbol": symbol, "side": "BUY", "date": day, "value_eur": value, "commission_eur": commission}
+
+
+def test_fee_flow_types():
+    assert is_fee_flow("commission")
+    assert is_fee_flow("custody_commission")
+    assert not is_fee_flow("block_commission")
+    assert not is_fee_flow("unblock_commission")
+    assert not is_fee_flow("dividend")
+
+
+def test_summary_per_period_and_symbol():
+    trades = [
+        _trade("2026-03-10", id=3),
+        _trade("2026-01-05", symbol="OTHER.US", value=500.0, commission=1.0, id=2),
+        _trade("2025-11-20", id=1),
+    ]
+    fees = [{"date": "2026-01-31", "fee_eur": 3.0}]
+    values = [("2026-01-15", 10000.0), ("2026-02-15", 12000.0)]
+
+    report = summarize(trades, fees, values, today=date(2026, 3, 31))
+
+    assert report["totals"]["total_eur"] == 8.0
+    assert report["totals"]["pct_of_volume"] == pytest.approx(5 / 2500 * 100)
+    by_year = {row["period"]: row for row in report["by_year"]}
+    assert by_year["2026"]["total_eur"] == 6.0
+    assert by_year["2026"]["avg_portfolio_eur"] == 11000.0
+    assert by_year["2026"]["pct_of_portfolio"] == pytest.approx(6 / 11000 * 100, abs=1e-4)
+    assert by_year["2025"]["pct_of_portfolio"] is None
+    assert [row["period"] for row in report["by_month"]] == ["2025-11", "2026-01", "2026-03"]
+    assert report["by_symbol"][0] == {
+        "symbol": "ACME.EU",
+        "trades": 2,
+        "commissions_eur": 4.0,
+        "traded_volume_eur": 2000.0,
+        "pct_of_volume": 0.2,
+    }
+    assert report["trades"][0]["pct_of_value"] == 0.2
+
+
+def test_projection_scales_short_history():
+    report = summarize([_trade("2026-03-02")], [], [("2026-03-31", 20000.0)], today=date(2026, 3, 31))
+
+    projection = report["projection"]
+    assert projection["days_covered"] == 30
+    assert projection["annual_eur"] == pytest.approx(2 * 365 / 30, abs=0.01)
+    assert projection["annual_pct_of_portfolio"] == pytest.approx(2 * 365 / 30 / 20000 * 100, abs=1e-3)
+
+    too_short = summarize([_trade("2026-03-30")], [], [], today=date(2026, 3, 31))
+    assert too_short["projection"]["annual_eur"] is None
+
+
+@pytest.mark.asyncio
+async def test_build_converts_fees_to_eur():
+    ts = int(datetime(2026, 2, 1, tzinfo=timezone.utc).timestamp())
+    db = MagicMock()
+    db.get_all_securities = AsyncMock(return_value=[{"symbol": "ACME.US", "currency": "USD"}])
+    db.get_trades = AsyncMock(
+        return_value=[
+            {
+                "id": 1,
+                "symbol": "ACME.US",
+                "side": "BUY",
+                "quantity": 10,
+                "price": 100.0,
+                "commission": 2.0,
+                "commission_currency": "USD",
+                "executed_at": ts,
+                "raw_data": {},
+            }
+        ]
+    )
+    db.get_cash_flows = AsyncMock(
+        return_value=[
+            {"date": "2026-02-28", "type_id": "commission", "amount": -5.0, "currency": "EUR"},
+            {"date": "2026-02-28", "type_id": "block_commission", "amount": -9.0, "currency": "EUR"},
+            {"date": "2026-02-28", "type_id": "card", "amount": 1000.0, "currency": "EUR"},
+        ]
+    )
+    db.get_portfolio_snapshots = AsyncMock(return_value=[])
+    currency = MagicMock()
+    currency.to_eur_for_date = AsyncMock(
+        side_effect=lambda amount, curr, day: amount * (0.5 if curr == "USD" else 1.0)
+    )
+
+    report = await build_fee_analytics(db, currency, today=date(2026, 3, 1))
+
+    assert report["trades"][0]["value_eur"] == 500.0
+    assert report["trades"][0]["commission_eur"] == 1.0
+    assert report["totals"]["account_fees_eur"] == 5.0
+    assert report["totals"]["total_eur"] == 6.0
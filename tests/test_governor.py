@@ -0,0 +1,115 @@
+"""Tests for the resource governor that holds low-priority jobs back under load."""
+
+import pytest
+
+from sentinel.governor import NORMAL, PAUSED, THROTTLED, ResourceGovernor, validate_resource_governor
+
+
+class FakeTime:
+    """A clock that only moves when the governor sleeps, plus a load that changes over time."""
+
+    def __init__(self, loads):
+        self.now = 0.0
+        self.loads = loads  # [(from time, cpu_pct, memory_pct)], in time order
+        self.slept = []
+
+    def clock(self):
+        return self.now
+
+    async def sleep(self, seconds):
+        self.slept.append(seconds)
+        self.now += seconds
+
+    def sample(self):
+        cpu, memory = next((c, m) for start, c, m in reversed(self.loads) if self.now >= start)
+        return {"cpu_pct": cpu, "memory_pct": memory, "load_1m": 1.0}
+
+
+def _governor(loads, **config):
+    fake = FakeTime(loads)
+    governor = ResourceGovernor(sampler=fake.sample, clock=fake.clock, sleep=fake.sleep)
+    governor.configure(validate_resource_governor(config))
+    return governor, fake
+
+
+def test_validate_fills_defaults():
+    config = validate_resource_governor({"cpu_pause_pct": 95})
+    assert config["cpu_pause_pct"] == 95.0
+    assert "forecast:run" in config["low_priority_jobs"]
+
+
+@pytest.mark.parametrize(
+    "raw",
+    [
+        [],
+        {"unknown": 1},
+        {"enabled": "yes"},
+        {"cpu_pause_pct": 150},
+        {"cpu_resume_pct": 80},
+        {"memory_throttle_pct": 95},
+        {"max_defer_seconds": -1},
+        {"low_priority_jobs": "forecast:run"},
+    ],
+)
+def test_validate_rejects(raw):
+    with pytest.raises(ValueError):
+        validate_resource_governor(raw)
+
+
+def test_state_has_hysteresis():
+    governor, fake = _governor([(0, 95, 40), (2, 70, 40), (4, 40, 40)])
+
+    assert governor.state() == PAUSED
+    fake.now = 2
+    # Below the pause and throttle thresholds but not yet idle.
+    assert governor.state() == THROTTLED
+    fake.now = 4
+    assert governor.state() == NORMAL
+
+
+def test_memory_pressure_and_missing_samples():
+    governor, _ = _governor([(0, 10, 88)])
+    assert governor.state() == THROTTLED
+
+    governor = ResourceGovernor(sampler=lambda: None)
+    assert governor.state() == NORMAL
+    assert governor.status()["sample"] is None
+
+
+@pytest.mark.asyncio
+async def test_admit_defers_until_the_load_eases():
+    governor, fake = _governor([(0, 99, 40), (12, 30, 40)])
+
+    assert await governor.admit("forecast:run") is True
+    assert fake.now >= 12
+    assert governor.counts == {"deferred": 1, "skipped": 0, "throttled_checkpoints": 0}
+
+
+@pytest.mark.asyncio
+async def test_admit_skips_after_max_defer():
+    governor, fake = _governor([(0, 99, 40)], max_defer_seconds=30)
+
+    assert await governor.admit("security:technical") is False
+    assert fake.now == 30
+    assert governor.counts["skipped"] == 1
+
+
+@pytest.mark.asyncio
+async def test_only_low_priority_jobs_are_held_back():
+    governor, fake = _governor([(0, 99, 99)])
+    assert await governor.admit("trading:execute") is True
+
+    governor.configure(validate_resource_governor({"enabled": False}))
+    assert await governor.admit("forecast:run") is True
+    assert fake.slept == []
+
+
+@pytest.mark.asyncio
+async def test_checkpoint_sleeps_while_throttled():
+    governor, fake = _governor([(0, 80, 40)], throttle_sleep_ms=100)
+
+    await governor.checkpoint()
+    await governor.checkpoint()
+
+    assert fake.slept == [0.1, 0.1]
+    assert governor.counts["throttled_checkpoints"] == 2
@@ -0,0 +1,124 @@
+"""Tests for the liveness, readiness and health report checks."""
+
+import os
+import tempfile
+import time
+from unittest.mock import MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.clock import CLOCK_STATE_KEY
+from sentinel.database import Database
+from sentinel.health import SYNC_STALE_FACTOR, HealthService, liveness
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    await db.seed_default_job_schedules()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _broker(connected=True):
+    broker = MagicMock()
+    broker.connected = connected
+    return broker
+
+
+async def _complete_all_syncs(db):
+    for schedule in await db.get_job_schedules():
+        if schedule["category"] == "sync":
+            await db.log_job_execution(schedule["job_type"], schedule["job_type"], "completed", None, 10, 0)
+
+
+def test_liveness():
+    result = liveness()
+    assert result["status"] == "alive"
+    assert result["uptime_seconds"] >= 0
+
+
+@pytest.mark.asyncio
+async def test_readiness(temp_db):
+    health = HealthService(temp_db, _broker())
+
+    ready = await health.readiness(scheduler_running=True)
+    assert ready["ready"] is True
+    assert ready["checks"]["migrations"] == {"ok": True, "detail": None}
+
+    not_ready = await health.readiness(scheduler_running=False)
+    assert not_ready["ready"] is False
+    assert not_ready["checks"]["scheduler"]["detail"] == "scheduler not running"
+
+
+@pytest.mark.asyncio
+async def test_readiness_with_closed_database(temp_db):
+    await temp_db.close()
+    ready = await HealthService(temp_db, _broker()).readiness(scheduler_running=True)
+    assert ready["ready"] is False
+    assert ready["checks"]["database"]["ok"] is False
+    assert ready["checks"]["migrations"]["detail"] == "database not open"
+
+
+@pytest.mark.asyncio
+async def test_report_flags_stale_syncs(temp_db):
+    await _complete_all_syncs(temp_db)
+    now = int(time.time())
+    health = HealthService(temp_db, _broker())
+
+    report = await health.report(scheduler_running=True, now_ts=now)
+    assert report["status"] == "ok"
+    assert all(not s["stale"] for s in report["syncs"])
+    assert "trading:execute" not in [s["job_type"] for s in report["syncs"]]
+
+    # sync:portfolio runs every 30 minutes while markets are closed.
+    report = await health.report(scheduler_running=True, now_ts=now + 30 * 60 * SYNC_STALE_FACTOR + 60)
+    assert report["status"] == "degraded"
+    portfolio = next(s for s in report["syncs"] if s["job_type"] == "sync:portfolio")
+    assert portfolio["stale"] is True
+    assert portfolio["threshold_seconds"] == 30 * 60 * SYNC_STALE_FACTOR
+    assert "sync:portfolio" in report["checks"]["syncs"]["detail"]
+    assert "sync:dividends" not in report["checks"]["syncs"]["detail"]
+
+
+@pytest.mark.asyncio
+async def test_report_status(temp_db):
+    await _complete_all_syncs(temp_db)
+
+    report = await HealthService(temp_db, _broker(connected=False)).report(scheduler_running=True)
+    assert report["status"] == "degraded"
+    assert report["checks"]["broker"]["ok"] is False
+
+    report = await HealthService(temp_db, _broker()).report(scheduler_running=False)
+    assert report["status"] == "unhealthy"
+    assert report["ready"] is False
+
+
+@pytest.mark.asyncio
+async def test_suspect_clock_degrades(temp_db):
+    await _complete_all_syncs(temp_db)
+    await temp_db.set_planner_state(CLOCK_STATE_KEY, {"offset_seconds": -75.2, "suspect": True})
+
+    report = await HealthService(temp_db, _broker()).report(scheduler_running=True)
+    assert report["status"] == "degraded"
+    assert report["checks"]["clock"] == {"ok": False, "detail": "clock off by -75s"}
+    assert report["clock"]["offset_seconds"] == -75.2
+
+
+@pytest.mark.asyncio
+async def test_never_run_sync_is_stale(temp_db):
+    report = await HealthService(temp_db, _broker()).report(scheduler_running=True)
+    assert report["status"] == "degraded"
+    assert all(s["stale"] and s["last_success_ts"] is None for s in report["syncs"])
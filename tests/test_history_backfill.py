@@ -0,0 +1,103 @@
+"""Tests for importing the broker's full trade and cash flow history."""
+
+import os
+import tempfile
+from datetime import date, timedelta
+
+import pytest
+import pytest_asyncio
+
+from sentinel import history_backfill
+from sentinel.database import Database
+from sentinel.history_backfill import HistoryBackfill, windows
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ("", "-wal", "-shm"):
+        target = path + ext
+        if os.path.exists(target):
+            os.unlink(target)
+
+
+@pytest_asyncio.fixture
+async def broker(mock_broker, mock_clock, temp_db):
+    """Three buys on three days and a sell, after a deposit."""
+    await temp_db.upsert_security("ACME.EU", name="Acme", currency="EUR")
+    mock_broker.add_security("ACME.EU", price=10.0)
+    mock_broker.deposit(5000.0, date="2026-01-02")
+    for price in (10.0, 12.0, 14.0):
+        mock_broker.set_quote("ACME.EU", price)
+        await mock_broker.buy("ACME.EU", 10)
+        mock_clock.advance(timedelta(days=1))
+    await mock_broker.sell("ACME.EU", 5)
+    return mock_broker
+
+
+def test_windows_cover_the_range_without_overlap():
+    ranges = windows(date(2024, 1, 1), date(2025, 3, 1), days=365)
+    assert ranges == [(date(2024, 1, 1), date(2024, 12, 30)), (date(2024, 12, 31), date(2025, 3, 1))]
+    assert windows(date(2025, 3, 2), date(2025, 3, 1)) == []
+
+
+@pytest.mark.asyncio
+async def test_backfill_imports_history_and_replays_cost_basis(temp_db, broker):
+    report = await HistoryBackfill(temp_db, broker).run(since="2025-01-01", until="2026-01-31")
+
+    assert report["windows"] == 2
+    assert report["trades"] == {"fetched": 4, "imported": 4, "existing": 0, "unclassified": 0}
+    assert report["cash_flows"]["imported"] == 1
+    assert report["unknown_symbols"] == []
+    position = report["ledger"]["positions"]["ACME.EU"]
+    assert position["quantity"] == pytest.approx(25)
+    assert position["avg_cost"] == pytest.approx(12.0)
+
+
+@pytest.mark.asyncio
+async def test_backfill_skips_existing_rows(temp_db, broker):
+    await HistoryBackfill(temp_db, broker).run(since="2026-01-01", until="2026-01-31")
+    report = await HistoryBackfill(temp_db, broker).run(since="2026-01-01", until="2026-01-31")
+
+    assert report["trades"] == {"fetched": 4, "imported": 0, "existing": 4, "unclassified": 0}
+    assert report["cash_flows"]["existing"] == 1
+    assert await temp_db.get_trades_count() == 4
+
+
+@pytest.mark.asyncio
+async def test_full_pages_are_split(temp_db, broker, monkeypatch):
+    monkeypatch.setattr(history_backfill, "TRADES_PAGE_LIMIT", 2)
+
+    report = await HistoryBackfill(temp_db, broker).run(since="2026-01-01", until="2026-01-31")
+
+    assert report["trades"]["imported"] == 4
+    calls = [call for call in broker.calls if call[0] == "get_trades_history"]
+    assert len(calls) > 1
+
+
+@pytest.mark.asyncio
+async def test_unclassified_rows_are_reported(temp_db, broker):
+    broker.deposit(3.0, date="2026-01-03", type_id="bonus", comment="Promo")
+    broker.add_security("OLDCO.US", price=5.0, currency="USD")
+    await broker.buy("OLDCO.US", 1)
+
+    report = await HistoryBackfill(temp_db, broker).run(since="2026-01-01", until="2026-01-31")
+
+    assert report["unknown_symbols"] == ["OLDCO.US"]
+    assert report["cash_flows"]["unclassified"] == 1
+    assert report["unclassified"][0]["reason"] == "unknown type 'bonus', replayed as a deposit"
+
+
+@pytest.mark.asyncio
+async def test_backfill_needs_a_connected_broker(temp_db, broker):
+    broker.disconnect()
+    with pytest.raises(RuntimeError, match="not connected"):
+        await HistoryBackfill(temp_db, broker).run()
@@ -0,0 +1,99 @@
+"""Tests for symbol/ISIN canonicalization."""
+
+import json
+import os
+import tempfile
+from unittest.mock import AsyncMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.identifiers import IdentifierService, normalize_isin, normalize_symbol
+from sentinel.portfolio import Portfolio
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    await db.upsert_security("ASML.EU", name="ASML", active=1, data=json.dumps({"issue_nb": "NL0010273215"}))
+    await db.upsert_security("SAP.EU", name="SAP", active=1)
+    await db.upsert_security("SAP.GR", name="SAP Xetra", active=0)
+    await db.upsert_security("KO.US", name="Coca-Cola", active=1)
+    await db.upsert_security("KO.EU", name="Coca-Cola EU", active=1)
+
+    yield db
+
+    IdentifierService(db).invalidate()
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_normalize_symbol():
+    assert normalize_symbol(" asml.eu ") == "ASML.EU"
+    assert normalize_symbol("ASML:EU") == "ASML.EU"
+    assert normalize_symbol("asml eu") == "ASML.EU"
+
+
+def test_normalize_isin():
+    assert normalize_isin(" nl0010273215 ") == "NL0010273215"
+    assert normalize_isin("ASML.EU") is None
+
+
+@pytest.mark.asyncio
+async def test_resolves_symbol_variants_and_isin(temp_db):
+    ids = IdentifierService(temp_db)
+    for value in ["ASML.EU", "asml.eu", "ASML:EU", "nl0010273215", "ASML"]:
+        assert await ids.resolve(value) == "ASML.EU"
+    assert await ids.isin_for("asml.eu") == "NL0010273215"
+    assert await ids.symbol_for("NL0010273215") == "ASML.EU"
+
+
+@pytest.mark.asyncio
+async def test_bare_ticker_prefers_the_single_active_listing(temp_db):
+    ids = IdentifierService(temp_db)
+    assert await ids.resolve("SAP") == "SAP.EU"
+    assert await ids.resolve("KO") is None
+    assert await ids.resolve("UNKNOWN.US") is None
+    assert await ids.canonical("UNKNOWN.US") == "UNKNOWN.US"
+
+
+@pytest.mark.asyncio
+async def test_index_is_cached_until_invalidated(temp_db):
+    ids = IdentifierService(temp_db)
+    assert await ids.resolve("NEW.US") is None
+
+    await temp_db.upsert_security("NEW.US", name="New", active=1)
+    assert await ids.resolve("NEW.US") is None
+
+    ids.invalidate()
+    assert await ids.resolve("new.us") == "NEW.US"
+    assert await ids.is_active("NEW.US")
+    assert not await ids.is_active("SAP.GR")
+
+
+@pytest.mark.asyncio
+async def test_get_security_by_isin(temp_db):
+    security = await IdentifierService(temp_db).get_security("NL0010273215")
+    assert security["symbol"] == "ASML.EU"
+
+
+@pytest.mark.asyncio
+async def test_portfolio_sync_stores_positions_under_the_stored_symbol(temp_db):
+    broker = AsyncMock()
+    broker.get_portfolio = AsyncMock(
+        return_value={"positions": [{"symbol": "asml.eu", "quantity": 3, "currency": "EUR"}], "cash": {}}
+    )
+
+    await Portfolio(db=temp_db, broker=broker).sync()
+
+    assert (await temp_db.get_position("ASML.EU"))["quantity"] == 3
+    broker.get_security_info.assert_not_awaited()
@@ -0,0 +1,126 @@
+"""Tests for the incremental technical indicator engine."""
+
+import math
+import os
+import tempfile
+from datetime import date, timedelta
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.indicators import INDICATOR_FIELDS, IndicatorEngine, update_indicators
+
+
+def _bars(count: int, start: date = date(2026, 1, 1)) -> list[dict]:
+    bars = []
+    for i in range(count):
+        close = 100 + 10 * math.sin(i / 5) + i * 0.2
+        bars.append(
+            {
+                "date": (start + timedelta(days=i)).isoformat(),
+                "open": close,
+                "high": close + 1,
+                "low": close - 1,
+                "close": close,
+                "volume": 1000,
+            }
+        )
+    return bars
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_values_appear_once_there_is_enough_history():
+    engine = IndicatorEngine()
+    rows = [engine.step(bar) for bar in _bars(60)]
+
+    assert rows[13]["rsi14"] is None and rows[14]["rsi14"] is not None
+    assert rows[12]["atr14"] is None and rows[13]["atr14"] is not None
+    assert rows[24]["macd"] is None and rows[25]["macd"] is not None
+    assert rows[32]["macd_signal"] is None and rows[33]["macd_signal"] is not None
+    assert rows[48]["sma50"] is None and rows[49]["sma50"] == pytest.approx(sum(b["close"] for b in _bars(50)) / 50)
+    assert rows[49]["ema_sma_cross"] in (1, -1)
+    assert 0 <= rows[-1]["rsi14"] <= 100
+
+
+def test_rsi_and_bollinger_extremes():
+    engine = IndicatorEngine()
+    rising = [{"date": f"d{i:03d}", "close": 100 + i} for i in range(30)]
+    row = [engine.step(bar) for bar in rising][-1]
+
+    assert row["rsi14"] == 100.0
+    assert row["bollinger_pct_b"] > 0.5
+    assert row["atr14"] == pytest.approx(1.0, abs=0.05)
+
+
+@pytest.mark.asyncio
+async def test_incremental_update_matches_full_recompute(temp_db):
+    bars = _bars(120)
+    await temp_db.save_prices("AAPL.US", bars[:80])
+    assert await update_indicators(temp_db, "AAPL.US") == 80
+
+    await temp_db.save_prices("AAPL.US", bars[80:])
+    assert await update_indicators(temp_db, "AAPL.US") == 40
+    assert await update_indicators(temp_db, "AAPL.US") == 0
+
+    engine = IndicatorEngine()
+    expected = [engine.step(bar) for bar in bars][-1]
+    latest = await temp_db.get_latest_indicators("AAPL.US")
+    for field in INDICATOR_FIELDS:
+        assert latest[field] == pytest.approx(expected[field]), field
+
+
+@pytest.mark.asyncio
+async def test_rewritten_history_triggers_full_recompute(temp_db):
+    bars = _bars(40)
+    await temp_db.save_prices("AAPL.US", bars)
+    await update_indicators(temp_db, "AAPL.US")
+
+    await temp_db.save_prices("AAPL.US", [{**bars[-1], "close": bars[-1]["close"] + 5}])
+    assert await update_indicators(temp_db, "AAPL.US") == 40
+    assert len(await temp_db.get_indicators("AAPL.US")) == 40
+
+
+@pytest.mark.asyncio
+async def test_indicators_endpoint_accepts_isin(temp_db):
+    from unittest.mock import MagicMock
+
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.securities import get_security_indicators
+    from sentinel.identifiers import IdentifierService
+
+    await temp_db.upsert_security("ASML.EU", name="ASML", active=1, data='{"issue_nb": "NL0010273215"}')
+    await temp_db.save_prices("ASML.EU", _bars(20))
+    await update_indicators(temp_db, "ASML.EU")
+    deps = MagicMock()
+    deps.db = temp_db
+    try:
+        result = await get_security_indicators("NL0010273215", deps, days=5)
+        assert result["symbol"] == "ASML.EU"
+        assert len(result["history"]) == 5
+        assert result["latest"]["date"] == "2026-01-20"
+        assert "state" not in result["latest"]
+
+        with pytest.raises(HTTPException) as exc:
+            await get_security_indicators("NOPE.US", deps, days=5)
+        assert exc.value.status_code == 404
+    finally:
+        IdentifierService(temp_db).invalidate()
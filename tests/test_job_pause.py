@@ -0,0 +1,107 @@
+"""Tests for pausing scheduled jobs and maintenance windows."""
+
+from datetime import datetime
+from unittest.mock import AsyncMock, patch
+
+import pytest
+
+from sentinel.job_pause import PAUSE_KEY, JobPause, active_window, validate_maintenance_windows
+from sentinel.jobs import runner
+
+NOW = 1_800_000_000
+# A Sunday and the Monday after it.
+SUNDAY_NIGHT = datetime(2026, 10, 18, 23, 30)
+MONDAY_EARLY = datetime(2026, 10, 19, 1, 0)
+MONDAY_NOON = datetime(2026, 10, 19, 12, 0)
+
+
+class FakeSettings:
+    def __init__(self, values: dict | None = None):
+        self.values = dict(values or {})
+
+    async def get(self, key, default=None):
+        return self.values.get(key, default)
+
+    async def set(self, key, value):
+        self.values[key] = value
+
+
+def test_validate_windows():
+    windows = validate_maintenance_windows([{"start": " 23:00", "end": "02:00", "days": ["sun", "mon"]}])
+    assert windows == [{"start": "23:00", "end": "02:00", "days": ["mon", "sun"], "reason": None}]
+
+    for raw in (
+        {},
+        [{"start": "02:00", "end": "02:00"}],
+        [{"start": "25:00", "end": "02:00"}],
+        [{"start": "01:00", "end": "02:00", "days": ["funday"]}],
+        [{"start": "01:00", "end": "02:00", "every": "day"}],
+    ):
+        with pytest.raises(ValueError):
+            validate_maintenance_windows(raw)
+
+
+def test_window_past_midnight_belongs_to_its_start_day():
+    windows = validate_maintenance_windows([{"start": "23:00", "end": "02:00", "days": ["sun"]}])
+
+    assert active_window(windows, SUNDAY_NIGHT) is not None
+    assert active_window(windows, MONDAY_EARLY) is not None
+    assert active_window(windows, MONDAY_NOON) is None
+    assert active_window(windows, datetime(2026, 10, 19, 23, 30)) is None
+
+
+@pytest.mark.asyncio
+async def test_pause_per_job_and_globally():
+    settings = FakeSettings()
+    pause = JobPause(settings)
+
+    await pause.pause("forecast:run", hours=None, reason=" Model upgrade ", now_ts=NOW)
+    assert await pause.blocks("forecast:run", now_ts=NOW, now=MONDAY_NOON) == "jobs_paused"
+    assert await pause.blocks("sync:prices", now_ts=NOW, now=MONDAY_NOON) is None
+    assert settings.values[PAUSE_KEY]["jobs"]["forecast:run"]["reason"] == "Model upgrade"
+
+    await pause.pause(hours=2, now_ts=NOW)
+    assert await pause.blocks("sync:prices", now_ts=NOW + 3600, now=MONDAY_NOON) == "jobs_paused"
+    assert await pause.blocks("sync:prices", now_ts=NOW + 2 * 3600, now=MONDAY_NOON) is None
+
+    await pause.resume("forecast:run")
+    assert (await pause.status(now_ts=NOW))["jobs"] == {}
+    assert (await pause.status(now_ts=NOW))["all"] is not None
+    await pause.resume()
+    assert settings.values[PAUSE_KEY] is None
+
+
+@pytest.mark.asyncio
+async def test_pause_rejects_bad_hours():
+    pause = JobPause(FakeSettings())
+    with pytest.raises(ValueError):
+        await pause.pause(hours=0)
+    with pytest.raises(ValueError):
+        await pause.pause(hours=True)
+
+
+@pytest.mark.asyncio
+async def test_only_maintenance_jobs_run_in_a_window():
+    pause = JobPause(FakeSettings({"maintenance_windows": [{"start": "11:00", "end": "13:00"}]}))
+
+    assert await pause.blocks("trading:execute", now_ts=NOW, now=MONDAY_NOON) == "maintenance_window"
+    assert await pause.blocks("backup:r2", now_ts=NOW, now=MONDAY_NOON) is None
+    assert await pause.blocks("trading:execute", now_ts=NOW, now=MONDAY_EARLY) is None
+
+
+@pytest.mark.asyncio
+async def test_scheduler_skips_paused_jobs_but_not_manual_runs():
+    task = AsyncMock()
+    with (
+        patch.dict(runner.TASK_REGISTRY, {"sync:prices": (task, [])}),
+        patch.object(runner, "_deps", {}),
+        patch.object(runner, "JobPause") as pause_cls,
+        patch.object(runner, "_load_governor", AsyncMock()),
+    ):
+        pause_cls.return_value.blocks = AsyncMock(return_value="jobs_paused")
+        skipped = await runner._run_task("sync:prices", {"market_timing": 0})
+        manual = await runner._run_task("sync:prices", {"market_timing": 0}, skip_timing_check=True)
+
+    assert skipped == {"skipped": True, "reason": "jobs_paused"}
+    assert manual["status"] == "completed"
+    task.assert_awaited_once()
@@ -0,0 +1,120 @@
+"""Tests for LED buzzer alerts and quiet hours."""
+
+import os
+import tempfile
+import time
+from datetime import datetime
+
+import pytest
+import pytest_asyncio
+from fastapi import FastAPI
+from fastapi.testclient import TestClient
+
+from sentinel.api.routers.settings import led_router
+from sentinel.database import Database
+from sentinel.led.alerts import (
+    ALERT_CODES,
+    ALERT_CRITICAL,
+    ALERT_TRADE_EXECUTED,
+    ALERT_TTL_SEC,
+    AlertManager,
+    validate_quiet_hours,
+)
+from sentinel.settings import Settings
+
+NIGHT = datetime(2026, 1, 5, 23, 30)
+NOON = datetime(2026, 1, 5, 12, 0)
+
+
+@pytest_asyncio.fixture
+async def temp_db_path():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    settings._db = db
+    await settings.init_defaults()
+
+    yield path
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _build_client() -> TestClient:
+    app = FastAPI()
+    app.include_router(led_router, prefix="/api")
+    return TestClient(app)
+
+
+class TestValidateQuietHours:
+    def test_none_disables(self):
+        assert validate_quiet_hours(None) is None
+
+    @pytest.mark.parametrize("value", ["22:00", {"start": "22:00"}, {"start": "22:00", "end": "22:00"}])
+    def test_rejects_invalid(self, value):
+        with pytest.raises(ValueError):
+            validate_quiet_hours(value)
+
+
+@pytest.mark.asyncio
+async def test_quiet_hours_mute_trade_alerts_but_not_critical(temp_db_path):
+    manager = AlertManager()
+
+    muted = await manager.trigger(ALERT_TRADE_EXECUTED, now=NIGHT)
+    assert muted == {"status": "suppressed", "reason": "quiet_hours"}
+
+    critical = await manager.trigger(ALERT_CRITICAL, now=NIGHT)
+    assert critical["status"] == "queued"
+
+    daytime = await manager.trigger(ALERT_TRADE_EXECUTED, now=NOON)
+    assert daytime["status"] == "queued"
+    assert daytime["seq"] == critical["seq"] + 1
+
+
+@pytest.mark.asyncio
+async def test_disabled_buzzer_suppresses_unless_forced(temp_db_path):
+    manager = AlertManager()
+    await manager.set_enabled(False)
+
+    assert (await manager.trigger(ALERT_CRITICAL, now=NOON))["reason"] == "disabled"
+    assert (await manager.trigger(ALERT_CRITICAL, now=NOON, force=True))["status"] == "queued"
+
+
+@pytest.mark.asyncio
+async def test_pending_alert_expires(temp_db_path):
+    manager = AlertManager()
+    alert = await manager.trigger(ALERT_TRADE_EXECUTED, now=NOON)
+
+    fresh = await manager.pending(now_ts=alert["ts"] + 10)
+    assert fresh == {"alert_seq": alert["seq"], "alert": ALERT_CODES[ALERT_TRADE_EXECUTED]}
+
+    expired = await manager.pending(now_ts=alert["ts"] + ALERT_TTL_SEC + 1)
+    assert expired == {"alert_seq": alert["seq"], "alert": 0}
+
+
+@pytest.mark.asyncio
+async def test_buzzer_endpoints(temp_db_path):
+    client = _build_client()
+
+    config = client.get("/api/led/buzzer").json()
+    assert config["enabled"] is True
+    assert config["quiet_hours"] == {"start": "22:00", "end": "07:00"}
+
+    resp = client.put("/api/led/buzzer", json={"quiet_hours": None})
+    assert resp.status_code == 200
+    assert resp.json()["quiet_hours"] is None
+    assert client.put("/api/led/buzzer", json={"quiet_hours": {"start": "x", "end": "y"}}).status_code == 400
+
+    queued = client.post("/api/led/buzzer/test", json={"pattern": "critical"}).json()
+    assert queued["status"] == "queued"
+    assert queued["ts"] <= int(time.time())
+    assert client.get("/api/led/buzzer").json()["alert"] == ALERT_CODES[ALERT_CRITICAL]
+
+    assert client.post("/api/led/buzzer/test", json={"pattern": "siren"}).status_code == 400
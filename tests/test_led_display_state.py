@@ -0,0 +1,56 @@
+"""Tests for the batched, versioned LED display state."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from sentinel.led.display_state import build_display_state, state_version, to_frame
+
+STATE = {"value": 52310, "return_pct": 7, "has_recs": 1, "broker_connected": 1, "mode": 0, "alert_seq": 3, "alert": 1}
+
+
+class TestStateVersion:
+    def test_same_state_same_version(self):
+        assert state_version(dict(STATE)) == state_version(dict(reversed(list(STATE.items()))))
+
+    def test_any_field_change_changes_version(self):
+        for field in STATE:
+            changed = {**STATE, field: STATE[field] + 1}
+            assert state_version(changed) != state_version(STATE), field
+
+    def test_version_fits_signed_int32(self):
+        assert 0 <= state_version(STATE) <= 0x7FFFFFFF
+
+
+def test_frame_layout():
+    assert to_frame(STATE) == [state_version(STATE), 52310, 7, 1, 1, 0, 3, 1]
+
+
+@pytest.mark.asyncio
+async def test_build_display_state_clamps_and_tolerates_planner_failure():
+    broker = MagicMock()
+    broker.connected = False
+    settings = MagicMock()
+    settings.get = AsyncMock(return_value=100.0)
+    valuation = MagicMock()
+    valuation.current = AsyncMock(return_value={"total_value_eur": 1e12, "portfolio_return_pct": -250.0})
+
+    with (
+        patch("sentinel.led.display_state.PortfolioValuationService", return_value=valuation),
+        patch("sentinel.led.display_state.get_open_market_symbols", AsyncMock(side_effect=RuntimeError("down"))),
+        patch("sentinel.led.display_state.ModeManager") as MockModes,
+    ):
+        MockModes.return_value.current_mode = AsyncMock(return_value="stats")
+        result = await build_display_state(db=MagicMock(), broker=broker, settings=settings)
+
+    assert result["state"] == {
+        "value": 99999999,
+        "return_pct": -99,
+        "has_recs": 0,
+        "broker_connected": 0,
+        "mode": 2,
+        "alert_seq": 0,
+        "alert": 0,
+    }
+    assert result["frame"] == to_frame(result["state"])
+    assert result["version"] == result["frame"][0]
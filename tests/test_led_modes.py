@@ -0,0 +1,163 @@
+"""Tests for LED display mode scheduling and manual overrides."""
+
+import os
+import tempfile
+import time
+from datetime import datetime
+
+import pytest
+import pytest_asyncio
+from fastapi import FastAPI
+from fastapi.testclient import TestClient
+
+from sentinel.api.routers.settings import led_router
+from sentinel.database import Database
+from sentinel.led.modes import (
+    MODE_HEALTH,
+    MODE_STATS,
+    MODE_TICKER,
+    ModeManager,
+    mode_for_time,
+    validate_schedule,
+)
+from sentinel.settings import Settings
+
+SCHEDULE = [
+    {"mode": "ticker", "start": "09:00", "end": "17:30"},
+    {"mode": "health", "start": "17:30", "end": "23:00"},
+    {"mode": "stats", "start": "23:00", "end": "09:00"},
+]
+
+
+@pytest_asyncio.fixture
+async def temp_db_path():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    settings._db = db
+    await settings.init_defaults()
+
+    yield path
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _build_client() -> TestClient:
+    app = FastAPI()
+    app.include_router(led_router, prefix="/api")
+    return TestClient(app)
+
+
+class TestModeForTime:
+    def test_daytime_slot(self):
+        assert mode_for_time(SCHEDULE, datetime(2026, 1, 5, 10, 0)) == MODE_TICKER
+
+    def test_slot_end_is_exclusive(self):
+        assert mode_for_time(SCHEDULE, datetime(2026, 1, 5, 17, 30)) == MODE_HEALTH
+
+    def test_slot_wrapping_midnight(self):
+        assert mode_for_time(SCHEDULE, datetime(2026, 1, 5, 23, 30)) == MODE_STATS
+        assert mode_for_time(SCHEDULE, datetime(2026, 1, 5, 3, 0)) == MODE_STATS
+
+    def test_uncovered_time_falls_back_to_ticker(self):
+        slots = [{"mode": "stats", "start": "01:00", "end": "02:00"}]
+        assert mode_for_time(slots, datetime(2026, 1, 5, 12, 0)) == MODE_TICKER
+
+
+class TestValidateSchedule:
+    def test_accepts_valid_schedule(self):
+        assert validate_schedule(SCHEDULE) == SCHEDULE
+
+    @pytest.mark.parametrize(
+        "slots",
+        [
+            [],
+            "ticker",
+            [{"mode": "disco", "start": "09:00", "end": "10:00"}],
+            [{"mode": "ticker", "start": "9am", "end": "10:00"}],
+            [{"mode": "ticker", "start": "24:00", "end": "10:00"}],
+            [{"mode": "ticker", "start": "10:00", "end": "10:00"}],
+        ],
+    )
+    def test_rejects_invalid_schedule(self, slots):
+        with pytest.raises(ValueError):
+            validate_schedule(slots)
+
+
+@pytest.mark.asyncio
+async def test_override_takes_precedence_until_expiry(temp_db_path):
+    manager = ModeManager()
+    await manager.set_schedule(SCHEDULE)
+    noon = datetime(2026, 1, 5, 12, 0)
+    now_ts = int(noon.timestamp())
+
+    await manager.set_override(MODE_STATS, 30, now_ts=now_ts)
+    resolved = await manager.resolve(noon)
+    assert resolved["mode"] == MODE_STATS
+    assert resolved["source"] == "override"
+
+    later = datetime.fromtimestamp(now_ts + 31 * 60)
+    resolved = await manager.resolve(later)
+    assert resolved["mode"] == MODE_TICKER
+    assert resolved["source"] == "schedule"
+
+
+@pytest.mark.asyncio
+async def test_clear_override_restores_schedule(temp_db_path):
+    manager = ModeManager()
+    await manager.set_override(MODE_HEALTH, 60)
+    await manager.clear_override()
+    assert await manager.get_override() is None
+
+
+@pytest.mark.asyncio
+async def test_set_override_rejects_bad_input(temp_db_path):
+    manager = ModeManager()
+    with pytest.raises(ValueError):
+        await manager.set_override("disco", 10)
+    with pytest.raises(ValueError):
+        await manager.set_override(MODE_TICKER, 0)
+
+
+@pytest.mark.asyncio
+async def test_mode_override_endpoints(temp_db_path):
+    client = _build_client()
+
+    resp = client.put("/api/led/mode/override", json={"mode": "health", "minutes": 15})
+    assert resp.status_code == 200
+    assert resp.json()["expires_at_ts"] > int(time.time())
+
+    mode = client.get("/api/led/mode").json()
+    assert mode["mode"] == "health"
+    assert mode["mode_code"] == 1
+    assert mode["source"] == "override"
+
+    assert client.delete("/api/led/mode/override").status_code == 200
+    assert client.get("/api/led/mode").json()["source"] == "schedule"
+
+    bad = client.put("/api/led/mode/override", json={"mode": "disco", "minutes": 15})
+    assert bad.status_code == 400
+
+
+@pytest.mark.asyncio
+async def test_schedule_endpoints(temp_db_path):
+    client = _build_client()
+
+    default = client.get("/api/led/schedule").json()
+    assert [slot["mode"] for slot in default["slots"]] == ["ticker", "health", "stats"]
+
+    slots = [{"mode": "stats", "start": "00:00", "end": "12:00"}, {"mode": "ticker", "start": "12:00", "end": "00:00"}]
+    resp = client.put("/api/led/schedule", json={"slots": slots})
+    assert resp.status_code == 200
+    assert client.get("/api/led/schedule").json()["slots"] == slots
+
+    bad = client.put("/api/led/schedule", json={"slots": [{"mode": "ticker", "start": "x", "end": "y"}]})
+    assert bad.status_code == 400
@@ -0,0 +1,91 @@
+"""Tests for LED ticker templates."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.led.state import Trade
+from sentinel.led.ticker import (
+    MISSING,
+    TickerContentService,
+    market_regime,
+    render_template,
+    template_placeholders,
+    validate_ticker_template,
+)
+from sentinel.localization import LOCALES
+
+TEMPLATE = [
+    {"text": "PORTFOLIO {total_value} {day_change_pct}"},
+    {"text": "{pending_trades} TRADES, NEXT {next_action}", "when": "pending_trades"},
+    {"text": " MARKET {regime} ", "when": None},
+]
+
+
+def test_validate_ticker_template():
+    assert validate_ticker_template(None) == []
+    assert validate_ticker_template(TEMPLATE) == [
+        {"text": "PORTFOLIO {total_value} {day_change_pct}", "when": None},
+        {"text": "{pending_trades} TRADES, NEXT {next_action}", "when": "pending_trades"},
+        {"text": "MARKET {regime}", "when": None},
+    ]
+    assert template_placeholders("{{literal}} {regime}") == ["regime"]
+    for bad in (
+        {"text": "x"},
+        [{"text": "x"}] * 11,
+        [{"text": "  "}],
+        [{"text": "x" * 201}],
+        [{"text": "{cash}"}],
+        [{"text": "{total_value:>10}"}],
+        [{"text": "{total_value!r}"}],
+        [{"text": "unbalanced {"}],
+        [{"text": "x", "when": "cash"}],
+        [{"text": "x", "color": "red"}],
+    ):
+        with pytest.raises(ValueError):
+            validate_ticker_template(bad)
+
+
+def test_render_template_keeps_order_and_skips_conditional_segments():
+    segments = validate_ticker_template(TEMPLATE)
+    values = {"total_value": "EUR 30,000", "pending_trades": "2", "next_action": "BUY EUR 645.75 AMD.EU"}
+
+    assert render_template(segments, values) == [
+        f"PORTFOLIO EUR 30,000 {MISSING}",
+        "2 TRADES, NEXT BUY EUR 645.75 AMD.EU",
+        f"MARKET {MISSING}",
+    ]
+    assert render_template(segments, {"regime": "HAUSSE"}) == [f"PORTFOLIO {MISSING} {MISSING}", "MARKET HAUSSE"]
+    assert render_template(list(reversed(segments)), {"pending_trades": ""})[0] == f"MARKET {MISSING}"
+
+
+def test_market_regime():
+    rising = [{"date": f"2026-{i // 28 + 1:02d}-{i % 28 + 1:02d}", "close": 100 + i} for i in range(200)]
+
+    assert market_regime(rising) == "bull"
+    assert market_regime(list(reversed(rising))) == "bull"  # rows come newest first from the database
+    assert market_regime(rising[:-1] + [{"date": "2026-12-31", "close": 50}]) == "bear"
+    assert market_regime(rising[:199]) is None
+
+
+@pytest.mark.asyncio
+async def test_ticker_lines_use_the_stored_template():
+    settings = MagicMock()
+    settings.get = AsyncMock(return_value=[{"text": "NEXT {next_action}", "when": "pending_trades"}])
+    content = TickerContentService(settings, MagicMock())
+    content.values = AsyncMock(
+        side_effect=lambda locale, trades: {
+            "next_action": trades[0].to_display_string(locale) if trades else "",
+            "pending_trades": str(len(trades)) if trades else "",
+        }
+    )
+    content.benchmark_text = AsyncMock(return_value="vs SP500: +1.2% YTD")
+    trade = Trade(action="BUY", amount=645.75, symbol="AMD.EU")
+
+    assert await content.ticker_lines(LOCALES["de"], [trade]) == ["NEXT KAUFEN 645,75 EUR AMD.EU"]
+    assert await content.ticker_lines(LOCALES["en"], []) == []
+    # Without a template: each trade, then the benchmark line.
+    assert await content.ticker_lines(LOCALES["en"], [trade], []) == ["BUY EUR 645.75 AMD.EU", "vs SP500: +1.2% YTD"]
+
+    settings.get = AsyncMock(return_value=[{"text": "{cash}"}])
+    assert await content.template() == []
@@ -0,0 +1,159 @@
+"""Tests for ledger replay and the replay/live consistency check."""
+
+from datetime import datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.ledger import LedgerService, cash_timeline, compare_states, explain_negative_balance, replay_events
+
+
+def _ts(iso: str) -> int:
+    return int(datetime.fromisoformat(f"{iso}T12:00:00").timestamp())
+
+
+def _trade(trade_id: str, symbol: str, side: str, quantity: float, price: float, on: str, **extra) -> dict:
+    return {
+        "broker_trade_id": trade_id,
+        "symbol": symbol,
+        "side": side,
+        "quantity": quantity,
+        "price": price,
+        "commission": extra.pop("commission", 0.0),
+        "commission_currency": "EUR",
+        "executed_at": _ts(on),
+        "raw_data": extra,
+    }
+
+
+def _flow(on: str, type_id: str, amount: float, currency: str = "EUR") -> dict:
+    return {"date": on, "type_id": type_id, "amount": amount, "currency": currency, "raw_data": {}}
+
+
+TRADES = [
+    _trade("1", "AAA.US", "BUY", 10, 100.0, "2026-01-05", curr_c="USD"),
+    _trade("2", "EUR/USD", "SELL", 2000, 1.1, "2026-01-02"),
+    _trade("3", "AAA.US", "BUY", 10, 120.0, "2026-02-05", curr_c="USD"),
+    _trade("3", "AAA.US", "BUY", 10, 120.0, "2026-02-05", curr_c="USD"),
+    _trade("4", "AAA.US", "SELL", 5, 130.0, "2026-03-05", curr_c="USD", commission=2.0),
+    _trade("5", "BBB.EU", "BUY", 4, 50.0, "2026-03-10"),
+    _trade("6", "BBB.EU", "SELL", 4, 55.0, "2026-04-10"),
+]
+FLOWS = [
+    _flow("2026-01-01", "card", 5000.0),
+    _flow("2026-03-15", "dividend", 12.5, "USD"),
+    _flow("2026-03-20", "block", -300.0),
+]
+
+
+class TestReplay:
+    def test_rebuilds_positions_cost_basis_and_cash(self):
+        state = replay_events(TRADES, FLOWS, {"AAA.US": "USD", "BBB.EU": "EUR"})
+
+        assert state["events"] == {"trades": 6, "cash_flows": 3}
+        assert state["positions"] == {"AAA.US": {"quantity": 15.0, "avg_cost": 110.0}}
+        # EUR: 5000 deposit - 2000 FX - 200 BBB + 220 BBB - 2 commission (block is not trading cash)
+        assert state["cash"]["EUR"] == pytest.approx(3018.0)
+        # USD: 2200 FX - 1000 - 1200 + 650 + 12.5 dividend
+        assert state["cash"]["USD"] == pytest.approx(662.5)
+
+    def test_as_of_stops_at_date(self):
+        state = replay_events(TRADES, FLOWS, {}, as_of="2026-01-31")
+
+        assert state["last_event_date"] == "2026-01-05"
+        assert state["positions"] == {"AAA.US": {"quantity": 10.0, "avg_cost": 100.0}}
+
+    def test_closed_position_resets_cost_basis(self):
+        trades = [
+            _trade("1", "CCC.EU", "BUY", 2, 10.0, "2026-01-01"),
+            _trade("2", "CCC.EU", "SELL", 2, 12.0, "2026-01-02"),
+            _trade("3", "CCC.EU", "BUY", 1, 20.0, "2026-01-03"),
+        ]
+        assert replay_events(trades, [], {})["positions"]["CCC.EU"]["avg_cost"] == 20.0
+
+
+class TestConsistency:
+    def test_reports_only_mismatches(self):
+        replayed = {
+            "positions": {"A": {"quantity": 10.0, "avg_cost": 100.0}, "B": {"quantity": 2.0, "avg_cost": 50.0}},
+            "cash": {"EUR": 100.0, "USD": 20.0},
+        }
+        live_positions = [
+            {"symbol": "A", "quantity": 10.0, "avg_cost": 100.2},
+            {"symbol": "B", "quantity": 3.0, "avg_cost": 45.0},
+            {"symbol": "C", "quantity": 1.0, "avg_cost": 5.0},
+        ]
+
+        report = compare_states(replayed, live_positions, {"EUR": 100.004, "USD": 25.0})
+
+        assert report["consistent"] is False
+        assert [(p["symbol"], p["issues"]) for p in report["positions"]] == [
+            ("B", ["quantity", "avg_cost"]),
+            ("C", ["quantity"]),
+        ]
+        assert report["cash"] == [{"currency": "USD", "replayed": 20.0, "live": 25.0, "difference": -5.0}]
+
+    @pytest.mark.asyncio
+    async def test_service_compares_replay_with_live_tables(self):
+        db = MagicMock()
+        db.get_trades = AsyncMock(return_value=[_trade("1", "BBB.EU", "BUY", 4, 50.0, "2026-03-10")])
+        db.get_cash_flows = AsyncMock(return_value=[_flow("2026-03-01", "card", 500.0)])
+        db.get_all_securities = AsyncMock(return_value=[{"symbol": "BBB.EU", "currency": "EUR"}])
+        db.get_all_positions = AsyncMock(return_value=[{"symbol": "BBB.EU", "quantity": 4, "avg_cost": 50.0}])
+        db.get_cash_balances = AsyncMock(return_value={"EUR": 300.0})
+
+        report = await LedgerService(db).check_consistency()
+
+        assert report["consistent"] is True
+        assert report["events"] == {"trades": 1, "cash_flows": 1}
+
+
+class TestNegativeBalance:
+    def test_timeline_splits_trades_fees_and_cash_flows(self):
+        timeline = cash_timeline(TRADES, FLOWS, {"AAA.US": "USD", "BBB.EU": "EUR"}, "EUR")
+
+        assert [(e["kind"], e["amount"]) for e in timeline] == [
+            ("deposit", 5000.0),
+            ("fx", -2000.0),
+            ("fee", -2.0),
+            ("trade", -200.0),
+            ("trade", 220.0),
+        ]
+        assert timeline[-1]["balance"] == pytest.approx(3018.0)
+
+    def test_finds_the_entry_that_took_the_balance_negative(self):
+        trades = [
+            _trade("1", "AAA.US", "BUY", 10, 100.0, "2026-03-10", curr_c="USD"),
+            _trade("2", "AAA.US", "BUY", 2, 100.0, "2026-03-12", curr_c="USD"),
+        ]
+        flows = [
+            _flow("2026-03-01", "card", 1150.0, "USD"),
+            _flow("2026-03-11", "dividend", 5.0, "USD"),
+            _flow("2026-03-13", "tax", -1.0, "USD"),
+        ]
+        timeline = cash_timeline(trades, flows, {}, "USD")
+        analysis = explain_negative_balance(timeline, "USD")
+
+        assert analysis["negative"] is True
+        assert analysis["balance"] == -46.0
+        assert analysis["negative_since"] == "2026-03-12"
+        assert analysis["balance_before"] == 155.0
+        assert (analysis["cause"]["kind"], analysis["cause"]["ref"]) == ("trade", "2")
+        assert [e["amount"] for e in analysis["largest_outflows"]] == [-200.0, -1.0]
+        assert analysis["window_by_kind"] == {"trade": -1200.0, "dividend": 5.0, "tax": -1.0}
+
+    def test_positive_balance_has_no_cause(self):
+        timeline = cash_timeline([], [_flow("2026-03-01", "card", 10.0)], {}, "EUR")
+        assert explain_negative_balance(timeline, "EUR") == {"currency": "EUR", "negative": False, "balance": 10.0}
+
+    @pytest.mark.asyncio
+    async def test_service_compares_with_live_balance(self):
+        db = MagicMock()
+        db.get_trades = AsyncMock(return_value=[_trade("1", "BBB.EU", "BUY", 4, 50.0, "2026-03-10")])
+        db.get_cash_flows = AsyncMock(return_value=[_flow("2026-03-01", "card", 150.0)])
+        db.get_all_securities = AsyncMock(return_value=[])
+
+        analysis = await LedgerService(db).explain_negative_balance("eur", live_balance=-60.0)
+
+        assert analysis["cause"]["description"] == "BUY 4 BBB.EU"
+        assert analysis["ledger_matches_live"] is False
@@ -0,0 +1,67 @@
+"""Tests for locale formatting and translated ticker texts."""
+
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.benchmark_analytics import ticker_text
+from sentinel.led.state import Trade
+from sentinel.localization import LOCALES, ascii_text, get_locale, load_locale, validate_locale
+
+EN, DE, FR, NL = LOCALES["en"], LOCALES["de"], LOCALES["fr"], LOCALES["nl"]
+
+
+def test_numbers_and_percentages():
+    assert EN.number(1234567.891) == "1,234,567.89"
+    assert DE.number(1234567.891) == "1.234.567,89"
+    assert FR.number(-1234.5, 1) == "-1 234,5"
+    assert DE.number(1234.5, None) == "1.234,5"
+    assert EN.number(10.0, None) == "10"
+    assert EN.percent(1.234, sign=True) == "+1.2%"
+    assert DE.percent(-0.25, 2) == "-0,25 %"
+
+
+def test_money_places_the_symbol_or_code():
+    assert EN.money(1874.62) == "€1,874.62"
+    assert EN.money(-5, "USD") == "-$5.00"
+    assert EN.money(1874.62, ascii_only=True) == "EUR 1,874.62"
+    assert DE.money(1874.62) == "1.874,62 €"
+    assert DE.money(100, "CHF", decimals=0) == "100 CHF"
+    assert NL.money(1874.62) == "€ 1.874,62"
+
+
+def test_dates_and_texts():
+    assert EN.date("2026-03-31") == "2026-03-31"
+    assert DE.date(date(2026, 3, 31)) == "31.03.2026"
+    assert FR.month(2026, 3) == "03/2026"
+    assert DE.text("period", start="01.03.2026", end="31.03.2026") == "01.03.2026 bis 31.03.2026"
+    # Untranslated keys fall back to English.
+    assert DE.text("health", broker="OK", bridge="AUS") == "BROKER OK BRIDGE AUS"
+    assert DE.ticker_text("stats_unavailable") == "STATISTIK NICHT VERFUGBAR"
+    assert ascii_text("JUSQU'À 14:00 €") == "JUSQU'A 14:00 "
+
+
+def test_ticker_lines():
+    trade = Trade(action="SELL", amount=1874.62, symbol="BYD.285.AS", sell_pct=51.7)
+    assert trade.to_display_string() == "SELL $1,874.62 (51%) BYD.285.AS"
+    assert trade.to_display_string(EN) == "SELL EUR 1,874.62 (51%) BYD.285.AS"
+    assert trade.to_display_string(DE) == "VERKAUFEN 1.874,62 EUR (51 %) BYD.285.AS"
+    assert Trade(action="BUY", amount=645.75, symbol="AMD.EU").to_display_string(FR) == "ACHAT 645,75 EUR AMD.EU"
+
+    analytics = {"benchmarks": [{"symbol": "SP500.IDX", "ytd": {"relative_pct": 1.234}}]}
+    assert ticker_text(analytics, DE) == "ggu. SP500: +1,2 % YTD"
+
+
+@pytest.mark.asyncio
+async def test_locale_setting():
+    assert validate_locale("de") == "de"
+    for bad in ("DE", "el", None):
+        with pytest.raises(ValueError):
+            validate_locale(bad)
+
+    settings = MagicMock()
+    settings.get = AsyncMock(return_value="it")
+    assert (await load_locale(settings)).code == "it"
+    assert (await load_locale(MagicMock())).code == "en"
+    assert get_locale("xx") is EN
@@ -0,0 +1,141 @@
+"""Tests for deploy-time schema migration gating."""
+
+import sqlite3
+from pathlib import Path
+from unittest.mock import patch
+
+import pytest
+
+from sentinel.database.main import SCHEMA
+from sentinel.database.migrations import (
+    apply_migrations,
+    migration_status,
+    pending_migrations,
+    rollback_migrations,
+    schema_tables,
+)
+from sentinel.database.versions import MIGRATIONS, latest_version
+
+NUMBERED = [f"migration {migration.label}" for migration in MIGRATIONS]
+CASH_FLOW_COLUMNS = ["broker_type_id", "classified_by", "isin", "symbol"]
+ADDED_COLUMNS = [
+    "add column securities.target_weight_updated_at",
+    *(f"add column cash_flows.{column}" for column in CASH_FLOW_COLUMNS),
+]
+
+
+def _old_database(path):
+    """A database from before valuation snapshots, target weight timestamps and cash flow classification."""
+    conn = sqlite3.connect(path)
+    conn.executescript(SCHEMA)
+    conn.execute("DROP TABLE valuation_snapshots")
+    conn.execute("ALTER TABLE securities DROP COLUMN target_weight_updated_at")
+    for column in CASH_FLOW_COLUMNS:
+        conn.execute(f"ALTER TABLE cash_flows DROP COLUMN {column}")
+    conn.execute("INSERT INTO securities (symbol, name) VALUES ('AAPL.US', 'Apple')")
+    conn.commit()
+    conn.close()
+
+
+def test_schema_tables():
+    tables = schema_tables()
+    assert tables[:2] == ["settings", "securities"]
+    assert "valuation_snapshots" in tables
+
+
+def test_pending_migrations_lists_missing_tables_and_columns(tmp_path):
+    path = tmp_path / "sentinel.db"
+    assert pending_migrations(path) == ["create database"]
+
+    _old_database(path)
+    pending = pending_migrations(path)
+    assert pending == ["create table valuation_snapshots", *ADDED_COLUMNS, *NUMBERED]
+
+
+@pytest.mark.asyncio
+async def test_apply_migrations_backs_up_first(tmp_path):
+    path = tmp_path / "sentinel.db"
+    _old_database(path)
+
+    result = await apply_migrations(path, tmp_path / "backups")
+
+    assert result["applied"] == ["create table valuation_snapshots", *ADDED_COLUMNS, *NUMBERED]
+    assert result["from_version"] == 0
+    assert pending_migrations(Path(result["backup"])) == result["applied"]
+    assert pending_migrations(path) == []
+    conn = sqlite3.connect(path)
+    assert set(CASH_FLOW_COLUMNS) <= {row[1] for row in conn.execute("PRAGMA table_info(cash_flows)")}
+    conn.close()
+    assert await apply_migrations(path, tmp_path / "backups") == {
+        "applied": [],
+        "backup": None,
+        "from_version": latest_version(),
+    }
+
+
+@pytest.mark.asyncio
+async def test_failed_migration_restores_backup(tmp_path):
+    path = tmp_path / "sentinel.db"
+    _old_database(path)
+
+    async def broken_migration(self):
+        await self.conn.execute("ALTER TABLE securities ADD COLUMN half_done TEXT")
+        await self.conn.commit()
+        raise RuntimeError("migration bug")
+
+    with patch("sentinel.database.main.Database._migrate_schema", broken_migration):
+        with pytest.raises(RuntimeError, match="migration bug"):
+            await apply_migrations(path, tmp_path / "backups")
+
+    assert len(pending_migrations(path)) == 1 + len(ADDED_COLUMNS) + len(MIGRATIONS)
+    conn = sqlite3.connect(path)
+    assert "half_done" not in [row[1] for row in conn.execute("PRAGMA table_info(securities)")]
+    assert conn.execute("SELECT name FROM securities").fetchall() == [("Apple",)]
+    conn.close()
+
+
+@pytest.mark.asyncio
+async def test_dry_run_changes_nothing(tmp_path):
+    path = tmp_path / "sentinel.db"
+    _old_database(path)
+
+    result = await apply_migrations(path, tmp_path / "backups", dry_run=True)
+
+    assert result == {"pending": pending_migrations(path), "from_version": 0, "dry_run": True}
+    assert len(result["pending"]) == 1 + len(ADDED_COLUMNS) + len(MIGRATIONS)
+    assert not (tmp_path / "backups").exists()
+
+
+@pytest.mark.asyncio
+async def test_numbered_migrations_are_recorded_and_reverted(tmp_path):
+    path = tmp_path / "sentinel.db"
+    _old_database(path)
+    await apply_migrations(path, tmp_path / "backups")
+
+    status = migration_status(path)
+    assert status["version"] == status["latest"] == latest_version()
+    assert all(m["applied_at"] for m in status["migrations"])
+    assert status["pending"] == []
+
+    dry = rollback_migrations(path, tmp_path / "backups", target=0, dry_run=True)
+    assert dry["reverted"] == [m.label for m in reversed(MIGRATIONS)]
+    assert migration_status(path)["version"] == latest_version()
+
+    result = rollback_migrations(path, tmp_path / "backups", target=0)
+    assert result["version"] == 0
+    assert Path(result["backup"]).exists()
+    assert migration_status(path)["pending"] == NUMBERED
+    conn = sqlite3.connect(path)
+    indexes = {row[0] for row in conn.execute("SELECT name FROM sqlite_master WHERE type = 'index'")}
+    conn.close()
+    assert "idx_prices_symbol_date" in indexes
+
+    assert rollback_migrations(path, tmp_path / "backups", target=0)["reverted"] == []
+
+
+def test_rollback_rejects_bad_targets(tmp_path):
+    with pytest.raises(ValueError, match="does not exist"):
+        rollback_migrations(tmp_path / "missing.db", tmp_path / "backups", target=0)
+    _old_database(tmp_path / "sentinel.db")
+    with pytest.raises(ValueError, match="negative"):
+        rollback_migrations(tmp_path / "sentinel.db", tmp_path / "backups", target=-1)
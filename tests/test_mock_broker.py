@@ -0,0 +1,170 @@
+"""Tests for the in-process MockBroker, and sync and order paths run against it."""
+
+import os
+import tempfile
+from datetime import timedelta
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.jobs.tasks import sync_cashflows, sync_trades
+from sentinel.mock_broker import FILLED, PARTIAL, REJECTED
+from sentinel.orders import FILLED as ORDER_FILLED
+from sentinel.orders import PARTIALLY_FILLED, OrderService
+from sentinel.portfolio import Portfolio
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ("", "-wal", "-shm"):
+        target = path + ext
+        if os.path.exists(target):
+            os.unlink(target)
+
+
+@pytest.fixture
+def broker(mock_broker):
+    mock_broker.add_security("ACME.EU", price=10.0, market="EU", market_id=1)
+    mock_broker.deposit(5000.0, date="2026-01-02")
+    return mock_broker
+
+
+@pytest.mark.asyncio
+async def test_market_order_fills_immediately(broker):
+    order_id = await broker.buy("ACME.EU", 100)
+
+    orders = await broker.get_orders(active_only=False)
+    portfolio = await broker.get_portfolio()
+    assert orders[0]["order_id"] == order_id
+    assert orders[0]["status"] == FILLED
+    assert portfolio["positions"][0]["quantity"] == 100
+    assert portfolio["cash"]["EUR"] == pytest.approx(4000.0)
+    assert not await broker.has_pending_orders()
+
+
+@pytest.mark.asyncio
+async def test_partial_fills_follow_the_clock(broker, mock_clock):
+    broker.set_fills("ACME.EU", latency=timedelta(minutes=5), slices=2)
+    await broker.buy("ACME.EU", 101)
+
+    assert (await broker.get_orders())[0]["remaining"] == 101
+    mock_clock.advance(timedelta(minutes=5))
+    order = (await broker.get_orders())[0]
+    assert order["status"] == PARTIAL
+    assert order["remaining"] == 51
+    mock_clock.advance(timedelta(minutes=5))
+    assert await broker.get_orders() == []
+    trades = await broker.get_trades_history()
+    assert [t["q"] for t in trades] == [50, 51]
+    assert trades[1]["date"] == "2026-01-05T10:10:00"
+
+
+@pytest.mark.asyncio
+async def test_commission_and_limit_price(mock_clock):
+    from sentinel.mock_broker import MockBroker
+
+    broker = MockBroker(clock=mock_clock, commission_rate=0.01)
+    broker.add_security("ACME.EU", price=10.0)
+    broker.set_position("ACME.EU", 10, 8.0)
+    await broker.sell("ACME.EU", 10, price=12.0)
+
+    trade = (await broker.get_trades_history())[0]
+    portfolio = await broker.get_portfolio()
+    assert trade["side"] == "SELL"
+    assert trade["commission"] == pytest.approx(1.2)
+    assert portfolio["positions"] == []
+    assert portfolio["cash"]["EUR"] == pytest.approx(118.8)
+
+
+@pytest.mark.asyncio
+async def test_rejected_and_cancelled_orders(broker, mock_clock):
+    broker.set_fills("ACME.EU", latency=timedelta(minutes=5))
+    assert await broker.buy("UNKNOWN.EU", 1) is not None
+    order_id = await broker.buy("ACME.EU", 5)
+
+    assert await broker.cancel_order(order_id)
+    mock_clock.advance(timedelta(hours=1))
+    statuses = {o["symbol"]: o["status"] for o in await broker.get_orders(active_only=False)}
+    assert statuses["UNKNOWN.EU"] == REJECTED
+    assert statuses["ACME.EU"] == 31
+    assert await broker.get_trades_history() == []
+
+
+@pytest.mark.asyncio
+async def test_failure_injection(broker):
+    broker.fail("get_orders", times=2)
+    broker.fail("buy", error=TimeoutError("boom"))
+
+    assert await broker.get_orders() is None
+    assert await broker.get_orders() is None
+    assert await broker.get_orders() == []
+    with pytest.raises(TimeoutError):
+        await broker.buy("ACME.EU", 1)
+    assert await broker.buy("ACME.EU", 1) is not None
+    assert [name for name, _ in broker.calls].count("get_orders") == 3
+    with pytest.raises(ValueError):
+        broker.fail("no_such_method")
+
+
+@pytest.mark.asyncio
+async def test_sync_trades_and_cashflows_from_mock(temp_db, broker):
+    broker.set_fills("ACME.EU", slices=2)
+    broker.deposit(-10.0, date="2026-01-03", type_id="tax", comment="Tax")
+    await broker.buy("ACME.EU", 10)
+
+    await sync_trades(temp_db, broker)
+    await sync_cashflows(temp_db, broker)
+    await sync_trades(temp_db, broker)
+
+    trades = await temp_db.get_trades(symbol="ACME.EU")
+    flows = await temp_db.get_cash_flows()
+    assert sorted(t["quantity"] for t in trades) == [5, 5]
+    assert {t["raw_data"]["order_id"] for t in trades} == {(await broker.get_orders(False))[0]["order_id"]}
+    assert sorted(f["type_id"] for f in flows) == ["card", "tax"]
+
+
+@pytest.mark.asyncio
+async def test_sync_skips_when_broker_fails(temp_db, broker):
+    await broker.buy("ACME.EU", 10)
+    broker.fail("get_trades_history")
+
+    await sync_trades(temp_db, broker)
+
+    assert await temp_db.get_trades() == []
+
+
+@pytest.mark.asyncio
+async def test_order_service_tracks_partial_fills(temp_db, broker, mock_clock):
+    broker.set_fills("ACME.EU", latency=timedelta(minutes=1), slices=2)
+    service = OrderService(temp_db, broker)
+    order_id = await broker.buy("ACME.EU", 10)
+    await service.record(order_id, "ACME.EU", "buy", 10, source="planner")
+
+    mock_clock.advance(timedelta(minutes=1))
+    await service.sync(now=int(mock_clock.now().timestamp()))
+    assert (await temp_db.get_order(order_id))["status"] == PARTIALLY_FILLED
+    mock_clock.advance(timedelta(minutes=1))
+    await service.sync(now=int(mock_clock.now().timestamp()))
+    assert (await temp_db.get_order(order_id))["status"] == ORDER_FILLED
+
+
+@pytest.mark.asyncio
+async def test_portfolio_sync_from_mock(temp_db, broker):
+    broker.set_history("ACME.EU", {"2026-01-02": 9.5})
+    await broker.buy("ACME.EU", 20)
+
+    await Portfolio(db=temp_db, broker=broker).sync()
+
+    position = await temp_db.get_position("ACME.EU")
+    assert position["quantity"] == 20
+    assert await temp_db.get_cash_balances() == {"EUR": pytest.approx(4800.0)}
@@ -0,0 +1,134 @@
+"""Tests for news ingestion and per-security sentiment."""
+
+import os
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.news import (
+    Headline,
+    NewsSourceError,
+    RssNewsSource,
+    decayed_sentiment,
+    ingest_news,
+    parse_rss,
+    score_headline,
+    sentiment_timing_score,
+)
+
+NOW = 1_800_000_000
+DAY = 86400
+
+FEED = """<?xml version="1.0"?>
+<rss version="2.0"><channel>
+  <item>
+    <title>Apple beats estimates on record iPhone sales</title>
+    <link>https://example.com/a</link>
+    <pubDate>Fri, 15 Jan 2027 08:00:00 GMT</pubDate>
+  </item>
+  <item><title>Undated headline</title></item>
+</channel></rss>"""
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+class FakeSource:
+    name = "fake"
+
+    def __init__(self, headlines: dict[str, list[Headline]], failing: set[str] | None = None):
+        self.headlines = headlines
+        self.failing = failing or set()
+
+    async def fetch(self, symbol, security_name=None):
+        if symbol in self.failing:
+            raise NewsSourceError("feed down")
+        return self.headlines.get(symbol, [])
+
+
+class TestSentiment:
+    @pytest.mark.parametrize(
+        "title,expected",
+        [
+            ("Apple beats estimates, shares surge", 1.0),
+            ("Regulators open probe as sales slump", -1.0),
+            ("Profit rises despite lawsuit", 1 / 3),
+            ("Company holds annual meeting", 0.0),
+            ("Outlook not strong", -1.0),
+        ],
+    )
+    def test_score_headline(self, title, expected):
+        assert score_headline(title) == pytest.approx(expected)
+
+    def test_decay_halves_weight_per_half_life(self):
+        rows = [
+            {"sentiment": 1.0, "published_at": NOW},
+            {"sentiment": -1.0, "published_at": NOW - 3 * DAY},
+        ]
+        result = decayed_sentiment(rows, now_ts=NOW, half_life_days=3)
+        assert result["weight"] == pytest.approx(1.5)
+        assert result["score"] == pytest.approx(0.5 / 1.5)
+
+    def test_few_stale_headlines_barely_move_the_score(self):
+        rows = [{"sentiment": 1.0, "published_at": NOW - 9 * DAY}]
+        assert decayed_sentiment(rows, now_ts=NOW, half_life_days=3)["score"] == pytest.approx(0.125)
+
+    def test_timing_score_maps_onto_forecast_scale(self):
+        assert [sentiment_timing_score(s) for s in (-1.0, 0.0, 1.0, 2.0)] == [0.0, 0.5, 1.0, 1.0]
+
+
+class TestRss:
+    def test_parse_rss_skips_undated_items(self):
+        headlines = parse_rss("AAPL.US", FEED, source="rss")
+        assert [(h.title, h.url) for h in headlines] == [
+            ("Apple beats estimates on record iPhone sales", "https://example.com/a")
+        ]
+        assert headlines[0].published_at == NOW
+
+    def test_malformed_feed_raises(self):
+        with pytest.raises(NewsSourceError):
+            parse_rss("AAPL.US", "<rss><channel>")
+
+    def test_url_template_placeholders(self):
+        source = RssNewsSource("https://news.example/rss?s={ticker}&full={symbol}&q={query}")
+        assert source.url_for("BRK.B.US", "Berkshire Hathaway") == (
+            "https://news.example/rss?s=BRK.B&full=BRK.B.US&q=Berkshire+Hathaway"
+        )
+
+
+@pytest.mark.asyncio
+async def test_ingest_stores_headlines_and_refreshes_sentiment(temp_db):
+    fresh = Headline("AAPL.US", "Apple shares surge", NOW - DAY, url="https://example.com/1")
+    expired = Headline("AAPL.US", "Apple shares plunge", NOW - 40 * DAY, url="https://example.com/2")
+    source = FakeSource({"AAPL.US": [fresh, expired]}, failing={"MSFT.US"})
+    securities = [{"symbol": "AAPL.US", "name": "Apple"}, {"symbol": "MSFT.US", "name": "Microsoft"}]
+
+    result = await ingest_news(temp_db, source, securities, now_ts=NOW, half_life_days=3, max_age_days=30)
+    again = await ingest_news(temp_db, source, securities, now_ts=NOW, half_life_days=3, max_age_days=30)
+
+    assert result == {"securities": 2, "headlines": 1, "failed": 1}
+    assert again["failed"] == 1
+    headlines = await temp_db.get_news_headlines("AAPL.US")
+    assert [(h["title"], h["sentiment"], h["source"]) for h in headlines] == [("Apple shares surge", 1.0, "fake")]
+
+    sentiment = await temp_db.get_news_sentiment(["AAPL.US", "MSFT.US"])
+    assert sentiment["AAPL.US"]["headline_count"] == 1
+    assert 0 < sentiment["AAPL.US"]["score"] < 1
+    assert sentiment["MSFT.US"]["score"] == 0.0
@@ -0,0 +1,116 @@
+"""Tests for per-security research notes and attachments."""
+
+import base64
+import os
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.notes import (
+    MAX_ATTACHMENT_BYTES,
+    excerpt,
+    journal_markdown,
+    load_note_summaries,
+    note_history,
+    validate_attachment,
+    validate_note,
+)
+
+DAY = 86_400
+T0 = 1_790_000_000
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_validate_note():
+    assert validate_note({"body": "Thesis: *margins*\n\n"}) == "Thesis: *margins*"
+    assert validate_note({"body": ""}) == ""
+    for bad in ({}, {"body": 3}, {"body": "x" * 20_001}, "text"):
+        with pytest.raises(ValueError):
+            validate_note(bad)
+
+
+def test_validate_attachment():
+    content = base64.b64encode(b"%PDF-1.7").decode()
+
+    assert validate_attachment({"filename": " call.pdf ", "content_type": "Application/PDF", "content": content}) == (
+        "call.pdf",
+        "application/pdf",
+        b"%PDF-1.7",
+    )
+    assert validate_attachment({"filename": "a.bin", "content": content})[1] == "application/octet-stream"
+    too_big = base64.b64encode(b"x" * (MAX_ATTACHMENT_BYTES + 1)).decode()
+    for bad in (
+        {"filename": "../a.pdf", "content": content},
+        {"filename": "a.pdf", "content_type": "pdf", "content": content},
+        {"filename": "a.pdf", "content": "not base64!"},
+        {"filename": "a.pdf", "content": ""},
+        {"filename": "a.pdf", "content": too_big},
+    ):
+        with pytest.raises(ValueError):
+            validate_attachment(bad)
+
+
+def test_excerpt_cuts_at_a_word():
+    assert excerpt("short note") == "short note"
+    assert excerpt("alpha beta gamma", length=12) == "alpha beta…"
+
+
+def test_history_merges_revisions_and_attachments():
+    revisions = [{"id": 2, "body": "", "created_at": T0 + 3 * DAY}, {"id": 1, "body": "v1", "created_at": T0}]
+    attachments = [{"id": 1, "filename": "a.pdf", "size": 3, "created_at": T0 + DAY, "deleted_at": T0 + 2 * DAY}]
+
+    assert [e["type"] for e in note_history(revisions, attachments)] == [
+        "note",
+        "attachment_deleted",
+        "attachment_added",
+        "note",
+    ]
+
+
+@pytest.mark.asyncio
+async def test_revisions_attachments_and_journal(temp_db):
+    await temp_db.upsert_security("SAP.DE", name="SAP SE", currency="EUR", active=1)
+    await temp_db.add_security_note("SAP.DE", "First take.", created_at=T0)
+    await temp_db.add_security_note("SAP.DE", "Waiting for margins.", created_at=T0 + DAY)
+    kept = await temp_db.add_security_attachment("SAP.DE", "call.pdf", "application/pdf", b"%PDF", created_at=T0)
+    dropped = await temp_db.add_security_attachment("SAP.DE", "old.xlsx", "text/csv", b"a,b", created_at=T0)
+    await temp_db.add_security_attachment("ASML.NL", "deck.pdf", "application/pdf", b"%PDF", created_at=T0)
+
+    assert await temp_db.delete_security_attachment(dropped, deleted_at=T0 + DAY)
+    assert not await temp_db.delete_security_attachment(dropped, deleted_at=T0 + DAY)
+
+    assert [n["body"] for n in await temp_db.get_security_note_history("SAP.DE")] == [
+        "Waiting for margins.",
+        "First take.",
+    ]
+    assert [a["id"] for a in await temp_db.get_security_attachments("SAP.DE")] == [kept]
+    assert (await temp_db.get_security_attachment(kept))["content"] == b"%PDF"
+
+    summaries = await load_note_summaries(temp_db)
+    assert summaries["SAP.DE"] == {"excerpt": "Waiting for margins.", "updated_at": T0 + DAY, "attachments": 1}
+    assert summaries["ASML.NL"]["excerpt"] == ""
+
+    journal = await journal_markdown(temp_db)
+    assert journal.index("## ASML.NL") < journal.index("## SAP.DE — SAP SE")
+    assert "Waiting for margins." in journal
+    assert "### Earlier revisions" in journal and "First take." in journal
+    assert "- old.xlsx (3 bytes, added" in journal and "deleted" in journal
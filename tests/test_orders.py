@@ -0,0 +1,183 @@
+"""Tests for local order tracking, cancel/modify and the duplicate-order guard."""
+
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.identifiers import IdentifierService
+from sentinel.orders import (
+    BrokerRefused,
+    OrderService,
+    OrderStateError,
+    can_transition,
+    status_from_broker,
+)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _broker(orders=None):
+    broker = MagicMock()
+    broker.connected = True
+    broker.get_orders = AsyncMock(return_value=orders or [])
+    broker.cancel_order = AsyncMock(return_value=True)
+    broker.buy = AsyncMock(return_value="502")
+    broker.sell = AsyncMock(return_value="503")
+    return broker
+
+
+def _broker_order(order_id, status, symbol="ASML.EU"):
+    return {"order_id": order_id, "symbol": symbol, "side": "buy", "quantity": 2, "price": 600.0, "status": status}
+
+
+def test_status_mapping_and_transitions():
+    assert status_from_broker(10) == "pending"
+    assert status_from_broker("21") == "filled"
+    assert status_from_broker(75) == "pending"
+    assert status_from_broker(99) is None
+    assert status_from_broker(None) is None
+    assert can_transition("pending", "filled")
+    assert can_transition("cancel_requested", "pending")
+    assert not can_transition("filled", "cancelled")
+    assert not can_transition("partially_filled", "pending")
+
+
+@pytest.mark.asyncio
+async def test_record_skips_research_orders(temp_db):
+    service = OrderService(temp_db, _broker())
+    assert await service.record("RESEARCH-BUY-ASML.EU-2", "ASML.EU", "buy", 2) is None
+    order = await service.record("501", "ASML.EU", "BUY", 2, source="planner")
+    assert (order["status"], order["side"], order["source"]) == ("pending", "buy", "planner")
+    assert [o["order_id"] for o in await service.list_orders()] == ["501"]
+    with pytest.raises(ValueError):
+        await service.list_orders("bogus")
+
+
+@pytest.mark.asyncio
+async def test_sync_follows_broker_statuses(temp_db):
+    broker = _broker()
+    service = OrderService(temp_db, broker)
+    await service.record("501", "ASML.EU", "buy", 2)
+    await service.record("502", "SAP.EU", "buy", 1)
+    await service.record("503", "AAPL.US", "sell", 3)
+    await temp_db.update_order("503", status="filled")
+    broker.get_orders.return_value = [
+        _broker_order("501", 21),
+        _broker_order("502", 12, "SAP.EU"),
+        _broker_order("503", 31, "AAPL.US"),
+        _broker_order("600", 10, "MSFT.US"),
+    ]
+
+    result = await service.sync()
+
+    assert result == {"updated": 2, "imported": 1, "expired": 0}
+    assert (await temp_db.get_order("501"))["status"] == "filled"
+    assert (await temp_db.get_order("502"))["broker_status"] == 12
+    # A filled order does not go back to cancelled.
+    assert (await temp_db.get_order("503"))["status"] == "filled"
+    assert (await temp_db.get_order("600"))["source"] == "broker"
+
+
+@pytest.mark.asyncio
+async def test_sync_expires_stale_orders_and_tolerates_broker_failure(temp_db):
+    broker = _broker()
+    service = OrderService(temp_db, broker)
+    await service.record("501", "ASML.EU", "buy", 2)
+
+    assert await service.sync() == {"updated": 0, "imported": 0, "expired": 0}
+    result = await service.sync(now=(await temp_db.get_order("501"))["updated_at"] + 86400)
+    assert result["expired"] == 1
+    assert (await temp_db.get_order("501"))["status"] == "expired"
+
+    broker.get_orders.return_value = None
+    assert await service.sync() is None
+
+
+@pytest.mark.asyncio
+async def test_cancel(temp_db):
+    broker = _broker()
+    service = OrderService(temp_db, broker)
+    await service.record("501", "ASML.EU", "buy", 2)
+
+    order = await service.cancel("501")
+    assert order["status"] == "cancel_requested"
+    broker.cancel_order.assert_awaited_once_with("501")
+    with pytest.raises(OrderStateError):
+        await service.cancel("501")
+
+    await service.record("502", "SAP.EU", "buy", 1)
+    broker.cancel_order.return_value = False
+    with pytest.raises(BrokerRefused):
+        await service.cancel("502")
+    assert (await temp_db.get_order("502"))["status"] == "pending"
+
+
+@pytest.mark.asyncio
+async def test_modify_cancels_and_replaces(temp_db):
+    broker = _broker()
+    service = OrderService(temp_db, broker)
+    await service.record("501", "ASML.EU", "buy", 2, price=600.0, source="planner")
+
+    with pytest.raises(ValueError):
+        await service.modify("501")
+    with pytest.raises(ValueError):
+        await service.modify("501", quantity=1.5)
+
+    replacement = await service.modify("501", price=590.0)
+
+    broker.buy.assert_awaited_once_with("ASML.EU", 2, 590.0)
+    assert (replacement["order_id"], replacement["replaces"], replacement["source"]) == ("502", "501", "planner")
+    original = await temp_db.get_order("501")
+    assert (original["status"], original["replaced_by"]) == ("cancel_requested", "502")
+
+
+@pytest.mark.asyncio
+async def test_open_order_for_matches_isin(temp_db):
+    await temp_db.upsert_security("ASML.EU", name="ASML", data='{"issue_nb": "NL0010273215"}')
+    await temp_db.upsert_security("ASML.US", name="ASML ADR", data='{"issue_nb": "NL0010273215"}')
+    IdentifierService(temp_db).invalidate()
+    service = OrderService(temp_db, _broker())
+    await service.record("501", "ASML.EU", "buy", 2)
+
+    assert (await service.open_order_for("ASML.US"))["order_id"] == "501"
+    assert await service.open_order_for("SAP.EU") is None
+    await temp_db.update_order("501", status="filled")
+    assert await service.open_order_for("ASML.US") is None
+
+
+@pytest.mark.asyncio
+async def test_submit_trade_skips_security_with_open_order(temp_db):
+    from sentinel.jobs.tasks import submit_trade
+
+    broker = _broker([_broker_order("501", 10)])
+    await OrderService(temp_db, broker).record("501", "ASML.EU", "buy", 2)
+    rec = MagicMock(symbol="ASML.EU", action="buy", quantity=1)
+    execute = AsyncMock(return_value="777")
+
+    with (
+        patch("sentinel.jobs.tasks.order_warnings", AsyncMock()),
+        patch("sentinel.jobs.tasks._execute_trade", execute),
+    ):
+        assert await submit_trade(temp_db, broker, rec) is None
+
+    execute.assert_not_awaited()
@@ -0,0 +1,110 @@
+"""Tests for the peer comparison service."""
+
+import json
+import os
+import tempfile
+from unittest.mock import MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.services.peers import PeerService
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _prices(closes: list[float]) -> list[dict]:
+    return [{"date": f"2026-{1 + i // 28:02d}-{1 + i % 28:02d}", "close": close} for i, close in enumerate(closes)]
+
+
+async def _seed(db):
+    # A steady climb scores nothing; a deep drawdown scores as a dip.
+    rising = [100.0 + i for i in range(260)]
+    dipped = [100.0 + i for i in range(200)] + [300.0 - 3 * i for i in range(60)]
+    await db.upsert_security(
+        "AAPL.US",
+        name="Apple",
+        industry="Technology",
+        geography="US",
+        active=1,
+        data=json.dumps({"issue_nb": "US0378331005"}),
+    )
+    await db.upsert_security("MSFT.US", name="Microsoft", industry="Technology", geography="US", active=1)
+    await db.upsert_security("SAP.EU", name="SAP", industry="Technology", geography="EU", active=1)
+    await db.upsert_security("KO.US", name="Coca-Cola", industry="Beverages", geography="US", active=1)
+    await db.save_prices("AAPL.US", _prices(rising))
+    await db.save_prices("MSFT.US", _prices(dipped))
+    await db.save_prices("SAP.EU", _prices(rising))
+    await db.save_prices("KO.US", _prices(dipped))
+
+
+@pytest.mark.asyncio
+async def test_ranks_industry_peers_by_score(temp_db):
+    await _seed(temp_db)
+    service = PeerService(temp_db)
+
+    result = await service.peers(await temp_db.get_security("AAPL.US"))
+
+    assert result["group_size"] == 3
+    assert [peer["symbol"] for peer in result["peers"]] == ["MSFT.US", "SAP.EU"]
+    assert result["peers"][0]["rank"] == 1
+    assert result["peers"][0]["score"] > result["security"]["score"]
+    assert result["security"]["isin"] == "US0378331005"
+    assert result["security"]["components"]["forecast_score"] is None
+
+
+@pytest.mark.asyncio
+async def test_match_both_narrows_the_group(temp_db):
+    await _seed(temp_db)
+    service = PeerService(temp_db)
+
+    result = await service.peers(await temp_db.get_security("AAPL.US"), match="both", limit=1)
+
+    assert result["group_size"] == 2
+    assert [peer["symbol"] for peer in result["peers"]] == ["MSFT.US"]
+
+
+@pytest.mark.asyncio
+async def test_find_security_by_isin(temp_db):
+    await _seed(temp_db)
+    service = PeerService(temp_db)
+
+    assert (await service.find_security("us0378331005"))["symbol"] == "AAPL.US"
+    assert await service.find_security("US5949181045") is None
+
+
+@pytest.mark.asyncio
+async def test_peers_endpoint_errors(temp_db):
+    from sentinel.api.routers.securities import get_security_peers
+
+    await _seed(temp_db)
+    await temp_db.upsert_security("NEW.US", name="No industry", active=1)
+    deps = MagicMock()
+    deps.db = temp_db
+
+    with pytest.raises(HTTPException) as exc:
+        await get_security_peers("NOPE.US", deps)
+    assert exc.value.status_code == 404
+
+    for symbol, kwargs in [("AAPL.US", {"match": "sector"}), ("AAPL.US", {"limit": 0}), ("NEW.US", {})]:
+        with pytest.raises(HTTPException) as exc:
+            await get_security_peers(symbol, deps, **kwargs)
+        assert exc.value.status_code == 400
@@ -0,0 +1,176 @@
+"""Tests for runtime metrics, profiling and job duration budgets."""
+
+import gc
+import os
+import tempfile
+from types import SimpleNamespace
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel import performance
+from sentinel.api.dependencies import require_admin
+from sentinel.database import Database
+from sentinel.performance import (
+    GCTimer,
+    check_budget,
+    cpu_profile,
+    heap_profile,
+    recent_overruns,
+    runtime_metrics,
+    start_heap_trace,
+    stop_heap_trace,
+    validate_budget_factor,
+    validate_budgets,
+)
+from sentinel.settings import Settings
+
+
+@pytest_asyncio.fixture
+async def settings():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    settings._db = db
+    await settings.init_defaults()
+    performance._overruns.clear()
+
+    yield settings
+
+    performance._overruns.clear()
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+class TestBudgets:
+    @pytest.mark.asyncio
+    async def test_run_within_factor_is_not_an_overrun(self, settings):
+        await settings.set("job_duration_budgets", {"sync:prices": 100})
+        assert await check_budget("sync:prices", 149_000, settings) is None
+        assert recent_overruns() == []
+
+    @pytest.mark.asyncio
+    async def test_run_over_budget_times_factor_is_recorded(self, settings):
+        await settings.set("job_duration_budgets", {"sync:prices": 100})
+        overrun = await check_budget("sync:prices", 151_000, settings)
+
+        assert overrun["duration_seconds"] == 151.0
+        assert overrun["budget_seconds"] == 100.0
+        assert overrun["factor"] == 1.5
+        assert recent_overruns() == [overrun]
+
+    @pytest.mark.asyncio
+    async def test_factor_setting_applies(self, settings):
+        await settings.set("job_duration_budgets", {"sync:prices": 100})
+        await settings.set("job_budget_warning_factor", 1)
+        assert await check_budget("sync:prices", 101_000, settings) is not None
+
+    @pytest.mark.asyncio
+    async def test_jobs_without_budget_are_ignored(self, settings):
+        await settings.set("job_duration_budgets", {})
+        assert await check_budget("sync:prices", 10_000_000, settings) is None
+
+    @pytest.mark.asyncio
+    async def test_invalid_settings_fall_back(self, settings):
+        await settings.set("job_duration_budgets", "fast")
+        await settings.set("job_budget_warning_factor", 0)
+        job_budgets, factor = await performance.budgets(settings)
+        assert job_budgets == {}
+        assert factor == performance.DEFAULT_BUDGET_FACTOR
+
+    @pytest.mark.asyncio
+    async def test_overruns_listed_newest_first(self, settings):
+        await settings.set("job_duration_budgets", {"a:job": 1, "b:job": 1})
+        await check_budget("a:job", 5000, settings)
+        await check_budget("b:job", 5000, settings)
+        assert [o["job_type"] for o in recent_overruns()] == ["b:job", "a:job"]
+
+    def test_validate_budgets(self):
+        assert validate_budgets({"sync:prices": 300}) == {"sync:prices": 300.0}
+        for bad in [[], {"sync:prices": 0}, {"sync:prices": "5"}, {"sync:prices": True}]:
+            with pytest.raises(ValueError):
+                validate_budgets(bad)
+
+    def test_validate_budget_factor(self):
+        assert validate_budget_factor(2) == 2.0
+        for bad in [0.5, "2", True, None]:
+            with pytest.raises(ValueError):
+                validate_budget_factor(bad)
+
+
+class TestRuntimeMetrics:
+    def test_reports_memory_gc_and_threads(self):
+        metrics = runtime_metrics()
+        assert metrics["memory"]["max_rss_mb"] > 0
+        assert len(metrics["gc"]["counts"]) == 3
+        assert metrics["threads"] >= 1
+
+    def test_gc_timer_measures_collections(self):
+        GCTimer.install()
+        try:
+            before = GCTimer.collections
+            gc.collect()
+            assert GCTimer.collections > before
+            assert runtime_metrics()["gc"]["timed"] is True
+        finally:
+            GCTimer.uninstall()
+
+
+class TestProfiling:
+    @pytest.mark.asyncio
+    async def test_cpu_profile_returns_report(self):
+        report = await cpu_profile(0.05, limit=5)
+        assert "function calls" in report
+
+    @pytest.mark.asyncio
+    async def test_cpu_profile_rejects_bad_arguments(self):
+        with pytest.raises(ValueError):
+            await cpu_profile(0)
+        with pytest.raises(ValueError):
+            await cpu_profile(performance.MAX_PROFILE_SECONDS + 1)
+        with pytest.raises(ValueError):
+            await cpu_profile(1, sort="bogus")
+
+    def test_heap_profile_requires_tracing(self):
+        stop_heap_trace()
+        with pytest.raises(RuntimeError):
+            heap_profile()
+
+        assert start_heap_trace() is True
+        try:
+            assert start_heap_trace() is False
+            _blocks = [bytearray(1024) for _ in range(100)]
+            top = heap_profile(limit=5)
+            assert top and {"location", "size_kb", "count"} <= set(top[0])
+            assert runtime_metrics()["memory"]["heap"] is not None
+        finally:
+            assert stop_heap_trace() is True
+
+
+class TestRequireAdmin:
+    @pytest.mark.asyncio
+    async def test_disabled_without_token_setting(self, settings):
+        with pytest.raises(HTTPException) as exc:
+            await require_admin(SimpleNamespace(settings=settings), x_admin_token="anything")
+        assert exc.value.status_code == 403
+
+    @pytest.mark.asyncio
+    async def test_wrong_or_missing_token_is_rejected(self, settings):
+        await settings.set("admin_token", "s3cret")
+        for token in [None, "wrong"]:
+            with pytest.raises(HTTPException) as exc:
+                await require_admin(SimpleNamespace(settings=settings), x_admin_token=token)
+            assert exc.value.status_code == 401
+
+    @pytest.mark.asyncio
+    async def test_matching_token_passes(self, settings):
+        await settings.set("admin_token", "s3cret")
+        await require_admin(SimpleNamespace(settings=settings), x_admin_token="s3cret")
@@ -0,0 +1,151 @@
+"""Tests for the what-if comparison of position target sets."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from fastapi import HTTPException
+
+from sentinel.planner.comparison import allocation_metrics, metric_delta, validate_comparison
+from sentinel.planner.models import LongTermPlan, LongTermTarget, PlannerState, TradeRecommendation
+
+
+def _prices(daily_returns, start=100.0):
+    rows, close = [{"date": "2023-12-31", "close": start}], start
+    for i, r in enumerate(daily_returns):
+        close *= 1 + r
+        rows.append({"date": f"2024-{1 + i // 28:02d}-{1 + i % 28:02d}", "close": close})
+    return list(reversed(rows))
+
+
+def _target(symbol, weight, current_value=0.0):
+    return LongTermTarget(
+        symbol=symbol,
+        clara_score=0.5,
+        opportunity_score=0.5,
+        target_allocation=weight,
+        current_value_eur=current_value,
+        target_value_eur=weight * 10_000,
+        gap_eur=weight * 10_000 - current_value,
+    )
+
+
+def _plan(targets):
+    return LongTermPlan(
+        as_of_date="2026-10-16",
+        horizon_end_date="2027-10-16",
+        horizon_months=12,
+        current_total_value_eur=10_000.0,
+        avg_monthly_net_deposit_eur=0.0,
+        expected_contributions_eur=0.0,
+        terminal_portfolio_value_eur=10_000.0,
+        targets=targets,
+    )
+
+
+def test_validation():
+    current, proposed = validate_comparison(
+        {"proposed": {"AAA": {"target_pct": 20, "mode": "hard"}, "BBB": None}, "min_trade_value": 50}
+    )
+    assert current.position_targets == {}
+    assert proposed.position_targets == {"AAA": {"target_pct": 20.0, "mode": "hard"}, "BBB": None}
+    assert proposed.min_trade_value == 50.0
+
+    for data in [{}, {"proposed": {"AAA": {"target_pct": 120}}}, {"proposed": {}, "settings": {}}, []]:
+        with pytest.raises(ValueError):
+            validate_comparison(data)
+
+
+@pytest.mark.asyncio
+async def test_metrics_of_target_weights():
+    prices = {"DIV": _prices([0.01, -0.01] * 60), "NEW": _prices([0.001] * 120)}
+    db = MagicMock()
+    db.get_prices = AsyncMock(side_effect=lambda symbol, days=None: prices[symbol])
+    db.get_dividends = AsyncMock(return_value=[{"symbol": "DIV", "value": 30.0}, {"symbol": "DIV", "value": 20.0}])
+
+    metrics = await allocation_metrics(db, _plan([_target("DIV", 0.5, 1_000.0), _target("NEW", 0.3)]))
+
+    # 5% trailing yield on today's DIV holding, applied to its 5,000 EUR target.
+    assert metrics["expected_dividend_income_eur"] == pytest.approx(250.0)
+    assert metrics["dividend_coverage_pct"] == pytest.approx(62.5)
+    assert metrics["invested_pct"] == 80.0
+    assert metrics["coverage_pct"] == 100.0
+    assert metrics["cvar_95_pct"] == pytest.approx(-0.47, abs=0.01)
+    assert metrics["annual_volatility_pct"] > 0
+
+    empty = await allocation_metrics(db, _plan([]))
+    assert empty["cvar_95_pct"] is None
+    assert empty["expected_dividend_income_eur"] == 0.0
+
+
+def test_delta():
+    current = {"expected_annual_return_pct": 6.0, "cvar_95_pct": None, "invested_pct": 90.0}
+    proposed = {"expected_annual_return_pct": 7.5, "cvar_95_pct": -2.0, "invested_pct": 95.0}
+    delta = metric_delta(current, proposed)
+    assert delta["expected_annual_return_pct"] == 1.5
+    assert delta["cvar_95_pct"] is None
+    assert delta["invested_pct"] == 5.0
+
+
+@pytest.mark.asyncio
+async def test_compare_endpoint_runs_both_sets():
+    import sentinel.api.routers.planner as planner_router
+
+    rec = TradeRecommendation(
+        symbol="NEW",
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.3,
+        allocation_delta=0.3,
+        current_value_eur=0.0,
+        target_value_eur=3_000.0,
+        value_delta_eur=3_000.0,
+        quantity=30,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=1.0,
+        reason="test",
+    )
+    seen = []
+
+    async def fake_run(overrides, db=None, broker=None):
+        seen.append(overrides.position_targets)
+        if overrides.position_targets:
+            return [rec], _plan([_target("NEW", 0.3)]), PlannerState(positions=[], cash_balances={"EUR": 5_000.0})
+        return [], _plan([]), PlannerState(positions=[], cash_balances={"EUR": 5_000.0})
+
+    deps = MagicMock()
+    deps.db.get_prices = AsyncMock(return_value=_prices([0.001] * 120))
+    deps.db.get_dividends = AsyncMock(return_value=[])
+    deps.db.get_all_securities = AsyncMock(return_value=[{"symbol": "NEW", "market_id": None}])
+    model = MagicMock()
+    model.cost.return_value = 3.5
+    fees = MagicMock()
+    fees.get_cost_model = AsyncMock(return_value=model)
+    fees.calculate_batch = AsyncMock(
+        return_value={
+            "total_sell_value": 0.0,
+            "total_buy_value": 3_000.0,
+            "total_fees": 3.5,
+            "sell_fees": 0.0,
+            "buy_fees": 3.5,
+        }
+    )
+
+    with (
+        patch("sentinel.planner.comparison.run_dry_run", side_effect=fake_run),
+        patch.object(planner_router, "FeeCalculator", return_value=fees),
+    ):
+        result = await planner_router.compare_allocations(deps, {"proposed": {"NEW": {"target_pct": 30}}})
+
+    assert seen == [{}, {"NEW": {"target_pct": 30.0, "mode": "soft"}}]
+    assert result["current"]["recommendations"] == []
+    assert result["proposed"]["allocation"] == [{"symbol": "NEW", "target_pct": 30.0}]
+    assert result["proposed"]["recommendations"][0]["fee_eur"] == 3.5
+    assert result["proposed"]["summary"]["cash_after_plan"] == pytest.approx(1_996.5)
+    assert result["delta"]["invested_pct"] == 30.0
+
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.compare_allocations(deps, {"current": {}})
+    assert exc.value.status_code == 400
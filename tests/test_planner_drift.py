@@ -0,0 +1,81 @@
+"""Tests for per-target rebalancing drift bands."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.analyzer import PortfolioAnalyzer
+from sentinel.planner.drift import evaluate_drift_bands, validate_drift_bands
+
+SECURITIES = {
+    "A": {"symbol": "A", "geography": "US", "industry": "Tech", "currency": "USD"},
+    "B": {"symbol": "B", "geography": "US", "industry": "Health", "currency": "USD"},
+    "C": {"symbol": "C", "geography": "DE", "industry": "Tech", "currency": "EUR"},
+}
+
+
+class TestValidation:
+    def test_normalizes_numbers_and_overrides(self):
+        assert validate_drift_bands({"position": 2, "geography": {"default": 3, " US ": 5}}) == {
+            "position": {"default": 2.0},
+            "geography": {"default": 3.0, "US": 5.0},
+        }
+        assert validate_drift_bands(None) == {}
+
+    def test_rejects_malformed_bands(self):
+        for bad in ([], {"sector": 2}, {"position": 0}, {"position": True}, {"geography": {"": 3}}):
+            with pytest.raises(ValueError):
+                validate_drift_bands(bad)
+
+
+class TestEvaluate:
+    def test_group_bands_catch_drift_hidden_in_positions(self):
+        current = {"A": 0.27, "B": 0.27, "C": 0.46}
+        ideal = {"A": 0.25, "B": 0.25, "C": 0.50}
+
+        rows = evaluate_drift_bands(current, ideal, SECURITIES, validate_drift_bands({"position": 5, "geography": 3}))
+
+        assert {(r["scope"], r["group"], r["drift_pct"]) for r in rows if r["breached"]} == {
+            ("geography", "US", 4.0),
+            ("geography", "DE", -4.0),
+        }
+        assert not any(r["breached"] for r in rows if r["scope"] == "position")
+
+    def test_overrides_without_default_only_cover_named_groups(self):
+        rows = evaluate_drift_bands(
+            {"A": 0.6, "C": 0.4},
+            {"A": 0.5, "C": 0.5},
+            SECURITIES,
+            validate_drift_bands({"industry": {"Tech": 1}, "currency": {"USD": 20}}),
+        )
+
+        assert [(r["scope"], r["group"], r["breached"]) for r in rows] == [
+            ("currency", "USD", False),
+            ("industry", "Tech", False),
+        ]
+
+
+@pytest.mark.asyncio
+async def test_breached_band_triggers_rebalance(monkeypatch):
+    values = {"rebalance_threshold_pct": 5, "rebalance_drift_bands": {"geography": 3}}
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    db = MagicMock()
+    db.get_all_securities = AsyncMock(return_value=list(SECURITIES.values()))
+    analyzer = PortfolioAnalyzer(db=db, portfolio=MagicMock(), currency=MagicMock(), settings=settings)
+    analyzer.get_current_allocations = AsyncMock(return_value={"A": 0.27, "B": 0.27, "C": 0.46})
+
+    async def fake_ideal(_calculator):
+        return {"A": 0.25, "B": 0.25, "C": 0.50}
+
+    monkeypatch.setattr("sentinel.planner.allocation.AllocationCalculator.calculate_ideal_portfolio", fake_ideal)
+
+    summary = await analyzer.get_rebalance_summary()
+    assert summary["max_deviation"] < 0.05
+    assert summary["breached_bands"] == 2
+    assert summary["status"] == "needs_rebalance"
+    assert summary["needs_rebalance"] is True
+
+    drift = await analyzer.get_drift_bands()
+    assert drift["breached_count"] == 2
+    assert {row["group"] for row in drift["bands"]} == {"US", "DE"}
@@ -0,0 +1,156 @@
+"""Tests for investment goals and the planner goal tilt."""
+
+import os
+import tempfile
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.planner.goals import (
+    GOALS_STATE_KEY,
+    goal_tilt,
+    load_goal_tilt,
+    months_until,
+    project_goal,
+    refresh_goal_projection,
+    validate_goal,
+)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _settings(values=None):
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: (values or {}).get(key, default))
+    return settings
+
+
+class TestValidation:
+    def test_normalizes_fields(self):
+        goal = validate_goal({"name": " House ", "target_amount_eur": 50000, "target_date": "2099-06-30"})
+        assert goal == {"name": "House", "target_amount_eur": 50000.0, "target_date": "2099-06-30"}
+        assert validate_goal({"priority": 2, "active": False}, partial=True) == {"priority": 2, "active": 0}
+
+    @pytest.mark.parametrize(
+        "data",
+        [
+            {"name": "House", "target_amount_eur": 0, "target_date": "2099-06-30"},
+            {"name": "House", "target_amount_eur": 1000, "target_date": "2000-01-01"},
+            {"name": "House", "target_amount_eur": 1000, "target_date": "June"},
+            {"name": "House", "target_amount_eur": 1000, "target_date": "2099-06-30", "priority": 0},
+        ],
+    )
+    def test_rejects_malformed_goals(self, data):
+        with pytest.raises(ValueError):
+            validate_goal(data)
+
+
+def test_projection():
+    assert months_until("2027-10-16", today=date(2026, 10, 16)) == 12
+    assert months_until("2020-01-01", today=date(2026, 10, 16)) == 0
+
+    riskless = project_goal(10_000, 100, 11_000, 12, 0.0, 0.0)
+    assert riskless["expected_eur"] == pytest.approx(11_200)
+    assert riskless["p50_eur"] == pytest.approx(11_200)
+    assert riskless["probability"] == 1.0
+    assert riskless["required_monthly_eur"] == pytest.approx(83.33, abs=0.01)
+
+    risky = project_goal(10_000, 100, 11_200, 12, 0.0, 20.0, seed=1)
+    assert 0.3 < risky["probability"] < 0.7
+    assert risky["p10_eur"] < risky["p50_eur"] < risky["p90_eur"]
+    assert project_goal(10_000, 100, 11_200, 12, 0.0, 20.0, seed=1) == risky
+
+    assert project_goal(5_000, 0, 6_000, 0, 6.0, 15.0)["probability"] == 0.0
+
+
+def test_tilt_grows_with_shortfall_and_priority():
+    rows = [{"probability": 0.3, "priority": 1}, {"probability": 0.0, "priority": 2}]
+    assert goal_tilt(rows, 0.6, 0.5) == pytest.approx(0.25)
+    assert goal_tilt([{"probability": 0.0, "priority": 1}], 0.6, 0.5) == pytest.approx(0.5)
+    assert goal_tilt([{"probability": 0.9, "priority": 1}], 0.6, 0.5) == 0.0
+    assert goal_tilt(rows, 0.6, 0) == 0.0
+
+
+@pytest.mark.asyncio
+async def test_refresh_stores_projection_for_the_tilt(temp_db):
+    await temp_db.create_goal(name="Far", target_amount_eur=1_000_000.0, target_date="2099-01-01", priority=1)
+    await temp_db.create_goal(name="Near", target_amount_eur=100.0, target_date="2099-01-01", priority=2)
+    settings = _settings({"goal_volatility_pct": 0.0})
+
+    projection = await refresh_goal_projection(temp_db, settings, 1_000.0, 0.0)
+
+    assert [(g["name"], g["on_track"]) for g in projection["goals"]] == [("Far", False), ("Near", True)]
+    assert projection["tilt"] == pytest.approx(0.5)
+    assert (await temp_db.get_planner_state(GOALS_STATE_KEY))["tilt"] == pytest.approx(0.5)
+    assert await load_goal_tilt(temp_db, settings) == pytest.approx(0.5)
+    assert await load_goal_tilt(temp_db, _settings({"goal_max_tilt": 0})) == 0.0
+
+
+def _flat_prices():
+    return [{"date": f"2025-01-{(i % 28) + 1:02d}", "close": 100.0} for i in range(300)]
+
+
+@pytest.mark.asyncio
+async def test_tilt_shrinks_live_cash_target():
+    db = MagicMock()
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+    db.get_all_securities = AsyncMock(return_value=[{"symbol": "AAA", "user_multiplier": 1.0}])
+    db.get_prices = AsyncMock(return_value=_flat_prices())
+    db.get_uninvested_dividends = AsyncMock(return_value={})
+    db.get_planner_state = AsyncMock(return_value={"goals": [{"probability": 0.0, "priority": 1}]})
+    settings = _settings(
+        {
+            "max_dividend_reinvestment_boost": 0,
+            "strategy_ideal_qualifying_threshold": 0.65,
+            "max_position_pct": 100,
+            "target_cash_pct": 20,
+        }
+    )
+    calculator = AllocationCalculator(db=db, settings=settings)
+
+    assert await calculator.calculate_ideal_portfolio() == {"AAA": pytest.approx(0.9)}
+    assert await calculator.calculate_ideal_portfolio(as_of_date="2025-01-15") == {"AAA": pytest.approx(0.8)}
+
+
+@pytest.mark.asyncio
+async def test_goal_endpoints(temp_db):
+    from sentinel.api.routers.goals import create_goal, delete_goal, get_goals, update_goal
+
+    deps = MagicMock()
+    deps.db = temp_db
+
+    with pytest.raises(HTTPException) as exc:
+        await create_goal({"name": "House"}, deps)
+    assert exc.value.status_code == 400
+
+    house = await create_goal({"name": "House", "target_amount_eur": 50000, "target_date": "2099-06-30"}, deps)
+    await create_goal({"name": "Car", "target_amount_eur": 9000, "target_date": "2098-01-01", "priority": 2}, deps)
+    await update_goal(house["id"], {"priority": 3}, deps)
+    assert [g["name"] for g in (await get_goals(deps))["goals"]] == ["Car", "House"]
+
+    await delete_goal(house["id"], deps)
+    with pytest.raises(HTTPException) as exc:
+        await update_goal(house["id"], {"priority": 1}, deps)
+    assert exc.value.status_code == 404
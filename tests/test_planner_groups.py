@@ -0,0 +1,185 @@
+"""Tests for security groups and group-level targets."""
+
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.planner.groups import (
+    apply_group_targets,
+    group_allocations,
+    validate_members,
+    validate_security_group,
+)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+class TestValidation:
+    def test_normalizes_fields(self):
+        assert validate_security_group({"name": " Dividend core ", "description": "", "target_pct": 40}) == {
+            "name": "Dividend core",
+            "description": None,
+            "target_pct": 40.0,
+        }
+        assert validate_security_group({"target_pct": None}, partial=True) == {"target_pct": None}
+        assert validate_members({"symbols": ["ko.us", "KO.US", "pep.us"]}) == ["KO.US", "PEP.US"]
+
+    @pytest.mark.parametrize(
+        "data",
+        [{}, {"name": ""}, {"name": "G", "target_pct": "40"}, {"name": "G", "target_pct": 120}],
+    )
+    def test_rejects_malformed_groups(self, data):
+        with pytest.raises(ValueError):
+            validate_security_group(data)
+
+    @pytest.mark.parametrize("data", [{}, {"symbols": []}, {"symbols": [" "]}, {"symbols": "KO.US"}])
+    def test_rejects_malformed_members(self, data):
+        with pytest.raises(ValueError):
+            validate_members(data)
+
+
+class TestApplyGroupTargets:
+    WEIGHTS = {"A": 0.2, "B": 0.2, "C": 0.3, "D": 0.3}
+
+    def test_members_share_the_target_and_others_the_rest(self):
+        groups = [{"id": 1, "symbols": ["A", "B"], "target_pct": 60}]
+        result = apply_group_targets(self.WEIGHTS, groups, budget=1.0, max_position=1.0)
+        assert result == {
+            "A": pytest.approx(0.3),
+            "B": pytest.approx(0.3),
+            "C": pytest.approx(0.2),
+            "D": pytest.approx(0.2),
+        }
+
+    def test_groups_without_target_change_nothing(self):
+        groups = [{"id": 1, "symbols": ["A", "B"], "target_pct": None}]
+        assert apply_group_targets(self.WEIGHTS, groups, budget=1.0, max_position=1.0) == self.WEIGHTS
+
+    def test_pinned_members_keep_their_weight(self):
+        groups = [{"id": 1, "symbols": ["A", "B"], "target_pct": 60}]
+        result = apply_group_targets(self.WEIGHTS, groups, budget=1.0, max_position=1.0, pinned={"A"})
+        assert result["A"] == pytest.approx(0.2)
+        assert result["B"] == pytest.approx(0.4)
+        assert result["C"] + result["D"] == pytest.approx(0.4)
+
+    def test_members_are_capped_and_unweighted_members_not_bought(self):
+        groups = [{"id": 1, "symbols": ["A", "B", "Z"], "target_pct": 80}]
+        result = apply_group_targets(self.WEIGHTS, groups, budget=1.0, max_position=0.35)
+        assert "Z" not in result
+        assert result["A"] == pytest.approx(0.35)
+        assert result["B"] == pytest.approx(0.35)
+        assert result["C"] + result["D"] == pytest.approx(0.3)
+
+
+def test_group_allocations_with_ungrouped_remainder():
+    groups = [{"id": 1, "name": "Dividend core", "symbols": ["A", "B"], "target_pct": 50.0}]
+    rows = group_allocations(groups, current={"A": 0.3, "B": 0.25, "C": 0.45}, ideal={"A": 0.25, "C": 0.75})
+    assert [(r["name"], round(r["current_pct"], 6), round(r["ideal_pct"], 6)) for r in rows] == [
+        ("Dividend core", 55.0, 25.0),
+        ("Ungrouped", 45.0, 75.0),
+    ]
+    assert rows[0]["drift_pct"] == pytest.approx(5.0)
+    assert rows[1]["symbols"] == ["C"]
+
+
+def _flat_prices():
+    return [{"date": f"2025-01-{(i % 28) + 1:02d}", "close": 100.0} for i in range(300)]
+
+
+@pytest.mark.asyncio
+async def test_ideal_portfolio_respects_group_targets():
+    db = MagicMock()
+    db.get_all_securities = AsyncMock(
+        return_value=[{"symbol": symbol, "user_multiplier": 1.0} for symbol in ("AAA", "BBB", "CCC", "DDD")]
+    )
+    db.get_prices = AsyncMock(return_value=_flat_prices())
+    db.get_uninvested_dividends = AsyncMock(return_value={})
+    db.get_security_groups = AsyncMock(return_value=[{"id": 1, "symbols": ["AAA"], "target_pct": 40.0}])
+    values = {
+        "max_dividend_reinvestment_boost": 0,
+        "strategy_ideal_qualifying_threshold": 0.65,
+        "max_position_pct": 100,
+        "target_cash_pct": 0,
+    }
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    calculator = AllocationCalculator(db=db, settings=settings)
+
+    ideal = await calculator.calculate_ideal_portfolio(as_of_date="2025-01-15")
+
+    assert ideal == {
+        "AAA": pytest.approx(0.4),
+        "BBB": pytest.approx(0.2),
+        "CCC": pytest.approx(0.2),
+        "DDD": pytest.approx(0.2),
+    }
+
+
+@pytest.mark.asyncio
+async def test_group_endpoints(temp_db):
+    from sentinel.api.routers.groups import (
+        add_group_members,
+        create_security_group,
+        delete_security_group,
+        get_security_groups,
+        remove_group_member,
+        update_security_group,
+    )
+
+    deps = MagicMock()
+    deps.db = temp_db
+    for symbol in ("KO.US", "PEP.US", "NVDA.US"):
+        await temp_db.upsert_security(symbol, name=symbol)
+
+    core = await create_security_group({"name": "Dividend core", "target_pct": 60}, deps)
+    growth = await create_security_group({"name": "Satellite growth"}, deps)
+    with pytest.raises(HTTPException) as exc:
+        await create_security_group({"name": "dividend core"}, deps)
+    assert exc.value.status_code == 409
+    with pytest.raises(HTTPException) as exc:
+        await update_security_group(growth["id"], {"target_pct": 50}, deps)
+    assert exc.value.status_code == 400
+
+    await add_group_members(core["id"], {"symbols": ["ko.us", "NVDA.US"]}, deps)
+    moved = await add_group_members(growth["id"], {"symbols": ["NVDA.US"]}, deps)
+    assert moved["moved"] == [{"symbol": "NVDA.US", "from": "Dividend core"}]
+    with pytest.raises(HTTPException) as exc:
+        await add_group_members(core["id"], {"symbols": ["NOPE.US"]}, deps)
+    assert exc.value.status_code == 400
+
+    listed = {g["name"]: g["symbols"] for g in (await get_security_groups(deps))["groups"]}
+    assert listed == {"Dividend core": ["KO.US"], "Satellite growth": ["NVDA.US"]}
+
+    await remove_group_member(core["id"], "KO.US", deps)
+    with pytest.raises(HTTPException) as exc:
+        await remove_group_member(core["id"], "KO.US", deps)
+    assert exc.value.status_code == 404
+
+    await delete_security_group(growth["id"], deps)
+    assert await temp_db.get_security_group(growth["id"]) is None
+    assert [g["name"] for g in await temp_db.get_security_groups()] == ["Dividend core"]
+    with pytest.raises(HTTPException) as exc:
+        await update_security_group(growth["id"], {"name": "Gone"}, deps)
+    assert exc.value.status_code == 404
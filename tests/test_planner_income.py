@@ -0,0 +1,173 @@
+"""Tests for the dividend income projection and the income objective."""
+
+import os
+import tempfile
+from datetime import date, datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.planner.income import (
+    INCOME_STATE_KEY,
+    compute_income_projection,
+    income_multipliers,
+    income_tilt,
+    load_income_multipliers,
+    project_position_income,
+    quantity_on,
+    validate_income_target,
+    validate_planner_objective,
+)
+
+TODAY = date(2025, 12, 31)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _settings(values=None):
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: (values or {}).get(key, default))
+    return settings
+
+
+def _trade(day: str, side: str, quantity: float) -> dict:
+    executed_at = int(datetime.fromisoformat(f"{day}T12:00:00").timestamp())
+    return {"executed_at": executed_at, "side": side, "quantity": quantity}
+
+
+TRADES = [_trade("2025-01-01", "BUY", 10), _trade("2025-08-01", "BUY", 15), _trade("2025-10-01", "SELL", 5)]
+DIVIDENDS = [
+    {"symbol": "ENEL.EU", "date": "2025-09-15", "value": 25.0},
+    {"symbol": "ENEL.EU", "date": "2025-06-15", "value": 10.0},
+    {"symbol": "ENEL.EU", "date": "2024-09-15", "value": 8.0},
+]
+
+
+def test_quantity_follows_the_trades():
+    assert quantity_on(TRADES, "2025-01-01") == 0
+    assert quantity_on(TRADES, "2025-09-15") == 25
+    assert quantity_on(TRADES, "2025-12-31") == 20
+
+
+def test_payouts_are_taken_per_share_and_repeated_on_todays_quantity():
+    income = project_position_income(DIVIDENDS, TRADES, 20, TODAY)
+
+    # 10 EUR on 10 shares and 25 EUR on 25 shares: 2 EUR a share over the last year.
+    assert income["annual_income_eur"] == pytest.approx(40.0)
+    assert income["payments"] == 2
+    assert income["schedule"] == [("2026-06-15", pytest.approx(20.0)), ("2026-09-15", pytest.approx(20.0))]
+    # Before the first trade today's quantity stands in: 8 EUR / 20 shares.
+    assert income["growth_pct"] == pytest.approx(400.0)
+
+
+def test_tilt_grows_with_the_shortfall():
+    assert income_tilt(500, 1000, "income") == 0.5
+    assert income_tilt(1500, 1000, "income") == 0.0
+    assert income_tilt(500, 0, "income") == 1.0
+    assert income_tilt(500, 1000, "total_return") == 0.0
+
+    assert income_multipliers({"A": 6.0, "B": 3.0, "C": 0.0}, 0.5) == {"A": 1.5, "B": 1.25}
+    assert income_multipliers({"A": 6.0}, 0.0) == {}
+
+
+def test_setting_validators():
+    assert validate_planner_objective("income") == "income"
+    assert validate_income_target(1200) == 1200.0
+    for bad in ("growth", None):
+        with pytest.raises(ValueError):
+            validate_planner_objective(bad)
+    for bad in (-1, True, "100", float("nan")):
+        with pytest.raises(ValueError):
+            validate_income_target(bad)
+
+
+@pytest.mark.asyncio
+async def test_projection_against_the_target():
+    db = MagicMock()
+    db.get_dividends = AsyncMock(return_value=DIVIDENDS)
+    db.get_trades = AsyncMock(return_value=TRADES)
+    db.get_all_positions = AsyncMock(
+        return_value=[
+            {"symbol": "ENEL.EU", "quantity": 20, "current_price": 40.0, "currency": "EUR"},
+            {"symbol": "ASML.EU", "quantity": 1, "current_price": 1200.0, "currency": "EUR"},
+        ]
+    )
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda value, _currency: value)
+    settings = _settings({"income_target_annual_eur": 160.0, "planner_objective": "income"})
+
+    projection = await compute_income_projection(db, settings, currency, today=TODAY)
+
+    assert projection["annual_income_eur"] == 40.0
+    assert (projection["progress_pct"], projection["shortfall_eur"], projection["on_track"]) == (25.0, 120.0, False)
+    assert projection["tilt"] == 0.75
+    assert [p["symbol"] for p in projection["positions"]] == ["ENEL.EU", "ASML.EU"]
+    assert projection["positions"][0]["yield_pct"] == 5.0
+    assert projection["positions"][1]["annual_income_eur"] == 0.0
+    assert projection["months"] == [{"month": "2026-06", "income_eur": 20.0}, {"month": "2026-09", "income_eur": 20.0}]
+    db.get_trades.assert_awaited_once_with(symbol="ENEL.EU", limit=100_000)
+
+
+@pytest.mark.asyncio
+async def test_stored_projection_drives_the_multipliers(temp_db):
+    settings = _settings({"planner_objective": "income", "income_target_annual_eur": 200.0})
+    assert await load_income_multipliers(temp_db, settings) == {}
+
+    state = {"annual_income_eur": 100.0, "positions": [{"symbol": "A", "yield_pct": 4.0}, {"symbol": "B"}]}
+    await temp_db.set_planner_state(INCOME_STATE_KEY, state)
+
+    assert await load_income_multipliers(temp_db, settings) == {"A": 1.5}
+    assert await load_income_multipliers(temp_db, _settings()) == {}
+
+
+def _flat_prices():
+    return [{"date": f"2025-01-{(i % 28) + 1:02d}", "close": 100.0} for i in range(300)]
+
+
+@pytest.mark.asyncio
+async def test_income_objective_tilts_live_weights_toward_yield():
+    state = {"annual_income_eur": 0.0, "positions": [{"symbol": "AAA", "yield_pct": 6.0}]}
+    db = MagicMock()
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+    db.get_all_securities = AsyncMock(
+        return_value=[{"symbol": "AAA", "user_multiplier": 1.0}, {"symbol": "BBB", "user_multiplier": 1.0}]
+    )
+    db.get_prices = AsyncMock(return_value=_flat_prices())
+    db.get_uninvested_dividends = AsyncMock(return_value={})
+    db.get_planner_state = AsyncMock(side_effect=lambda key: state if key == INCOME_STATE_KEY else None)
+    settings = _settings(
+        {
+            "max_dividend_reinvestment_boost": 0,
+            "strategy_ideal_qualifying_threshold": 0.65,
+            "max_position_pct": 100,
+            "target_cash_pct": 0,
+            "planner_objective": "income",
+        }
+    )
+    calculator = AllocationCalculator(db=db, settings=settings)
+
+    assert await calculator.calculate_ideal_portfolio() == {"AAA": pytest.approx(2 / 3), "BBB": pytest.approx(1 / 3)}
+    assert await calculator.calculate_ideal_portfolio(as_of_date="2025-01-15") == {
+        "AAA": pytest.approx(0.5),
+        "BBB": pytest.approx(0.5),
+    }
@@ -0,0 +1,211 @@
+"""Tests for the planner liquidity policy (cash buffer, currency floors, obligations)."""
+
+from dataclasses import replace
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.liquidity import (
+    LiquidityPolicy,
+    load_liquidity_policy,
+    validate_currency_floors,
+    validate_pending_obligations,
+)
+from sentinel.planner.models import TradeRecommendation
+
+
+async def _usd_to_eur(amount, currency):
+    return amount * 0.5 if currency == "USD" else amount
+
+
+def _buy(value_eur: float = 1000.0) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol="B",
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=value_eur,
+        value_delta_eur=value_eur,
+        quantity=int(value_eur / 100),
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.8,
+        priority=2.0,
+        reason="buy",
+    )
+
+
+def _engine(settings: dict, cash: float) -> RebalanceEngine:
+    engine = RebalanceEngine(db=MagicMock())
+    engine._settings = MagicMock()
+    engine._settings.get = AsyncMock(
+        side_effect=lambda key, default=None: {
+            "transaction_fee_fixed": 0.0,
+            "transaction_fee_percent": 0.0,
+            "min_cash_buffer": 0.0,
+            **settings,
+        }.get(key, default)
+    )
+    engine._portfolio = MagicMock()
+    engine._portfolio.total_cash_eur = AsyncMock(return_value=cash)
+    engine._currency = MagicMock()
+    engine._currency.get_rate = AsyncMock(return_value=1.0)
+    engine._currency.to_eur = AsyncMock(side_effect=_usd_to_eur)
+    engine._generate_deficit_sells = AsyncMock(return_value=[])
+    return engine
+
+
+class TestLiquidityPolicy:
+    @pytest.mark.asyncio
+    async def test_reserve_uses_larger_buffer_plus_floors_and_obligations(self):
+        policy = LiquidityPolicy(
+            buffer_pct=0.01,
+            buffer_eur=250.0,
+            currency_floors={"USD": 200.0, "EUR": 50.0},
+            obligations=[{"label": "Tax", "amount": 400.0, "currency": "EUR"}],
+        )
+
+        reserve = await policy.reserve(10_000.0, _usd_to_eur)
+
+        assert reserve["buffer_eur"] == 250.0
+        assert reserve["currency_floors_eur"] == 150.0
+        assert reserve["obligations_eur"] == 400.0
+        assert reserve["total_eur"] == 800.0
+
+        assert (await policy.reserve(50_000.0, _usd_to_eur, include_account=False))["total_eur"] == 500.0
+
+    @pytest.mark.asyncio
+    async def test_past_due_obligations_are_not_reserved(self):
+        policy = LiquidityPolicy(
+            obligations=[
+                {"label": "Tax", "amount": 400.0, "currency": "EUR", "due_date": "2026-06-30"},
+                {"label": "Rent", "amount": 100.0, "currency": "EUR"},
+            ]
+        )
+
+        due = await policy.reserve(0.0, _usd_to_eur, as_of=date(2026, 6, 30))
+        past_due = await policy.reserve(0.0, _usd_to_eur, as_of=date(2026, 7, 1))
+
+        assert due["obligations_eur"] == 500.0
+        assert past_due["obligations_eur"] == 100.0
+        assert [o["label"] for o in policy.open_obligations(date(2026, 7, 1))] == ["Rent"]
+
+    @pytest.mark.asyncio
+    async def test_load_ignores_malformed_stored_values(self):
+        stored = {
+            "min_cash_buffer": "lots",
+            "min_cash_buffer_eur": 100,
+            "cash_currency_floors": ["USD"],
+            "pending_obligations": [{"amount": -5}],
+        }
+
+        async def get(key, default):
+            return stored.get(key, default)
+
+        policy = await load_liquidity_policy(get)
+
+        assert policy.buffer_pct == 0.005
+        assert policy.buffer_eur == 100.0
+        assert policy.currency_floors == {}
+        assert policy.obligations == []
+
+    def test_validators(self):
+        assert validate_currency_floors({" usd ": 200}) == {"USD": 200.0}
+        assert validate_pending_obligations([{"amount": 10, "label": "Rent"}]) == [
+            {"label": "Rent", "amount": 10.0, "currency": "EUR"}
+        ]
+        assert validate_pending_obligations([{"amount": 10, "due_date": "2026-06-30"}])[0]["due_date"] == "2026-06-30"
+
+        for bad in ([], {"USD": -1}, {"": 5}, {"USD": True}):
+            with pytest.raises(ValueError):
+                validate_currency_floors(bad)
+        for bad in ({}, [5], [{"label": "x"}], [{"amount": 1, "currency": ""}], [{"amount": 1, "due_date": 3}]):
+            with pytest.raises(ValueError):
+                validate_pending_obligations(bad)
+        for due_date in ("tomorrow", "2026-13-45", ""):
+            with pytest.raises(ValueError):
+                validate_pending_obligations([{"amount": 1, "due_date": due_date}])
+
+
+class TestCashConstraintReserve:
+    @pytest.mark.asyncio
+    async def test_obligations_and_floors_are_not_spent_on_buys(self):
+        engine = _engine(
+            {
+                "min_cash_buffer_eur": 100.0,
+                "cash_currency_floors": {"USD": 200.0},
+                "pending_obligations": [{"label": "Withdrawal", "amount": 300.0}],
+            },
+            cash=1000.0,
+        )
+
+        result = await engine._apply_cash_constraint(
+            [_buy()],
+            min_trade_value=100.0,
+            total_value=10_000.0,
+            symbol_convictions={"B": 0.8},
+        )
+
+        # 1000 cash - (100 buffer + 100 USD floor + 300 obligation) leaves 500.
+        assert [(r.symbol, r.quantity) for r in result] == [("B", 5)]
+
+    @pytest.mark.asyncio
+    async def test_backtests_only_apply_the_buffer(self):
+        engine = _engine(
+            {"min_cash_buffer_eur": 100.0, "pending_obligations": [{"amount": 500.0}]},
+            cash=1000.0,
+        )
+
+        result = await engine._apply_cash_constraint(
+            [_buy()],
+            min_trade_value=100.0,
+            as_of_date="2025-01-15",
+            total_value=10_000.0,
+            symbol_convictions={"B": 0.8},
+        )
+
+        assert [(r.symbol, r.quantity) for r in result] == [("B", 9)]
+
+
+class TestExecutionGuard:
+    @staticmethod
+    def _portfolio(cash: float, total: float):
+        portfolio = MagicMock()
+        portfolio.total_cash_eur = AsyncMock(return_value=cash)
+        portfolio.total_value = AsyncMock(return_value=total)
+        return portfolio
+
+    @staticmethod
+    def _settings(values: dict):
+        settings = MagicMock()
+        settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+        return settings
+
+    @pytest.mark.asyncio
+    async def test_blocks_buy_that_breaches_reserve(self):
+        from sentinel.jobs.tasks import liquidity_block_reason
+
+        values = {
+            "min_cash_buffer": 0.0,
+            "transaction_fee_fixed": 0.0,
+            "transaction_fee_percent": 0.0,
+            "pending_obligations": [{"amount": 500.0}],
+        }
+        with patch("sentinel.settings.Settings", return_value=self._settings(values)):
+            reason = await liquidity_block_reason(self._portfolio(1200.0, 10_000.0), _buy(1000.0))
+            assert "liquidity reserve" in reason
+
+            assert await liquidity_block_reason(self._portfolio(1600.0, 10_000.0), _buy(1000.0)) is None
+
+    @pytest.mark.asyncio
+    async def test_sells_and_unknown_state_pass(self):
+        from sentinel.jobs.tasks import liquidity_block_reason
+
+        sell = replace(_buy(), action="sell", value_delta_eur=-1000.0)
+        assert await liquidity_block_reason(self._portfolio(0.0, 0.0), sell) is None
+        assert await liquidity_block_reason(AsyncMock(), _buy()) is None
@@ -0,0 +1,86 @@
+"""Tests for the Monte Carlo robustness check on recommendations."""
+
+import math
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.monte_carlo import (
+    MIN_HISTORY_DAYS,
+    apply_monte_carlo,
+    robustness_stats,
+    simulate_horizon_returns,
+)
+
+
+def _rec(symbol: str, action: str, rank: int) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.1,
+        target_allocation=0.2,
+        allocation_delta=0.1,
+        current_value_eur=1000.0,
+        target_value_eur=2000.0,
+        value_delta_eur=1000.0,
+        quantity=1,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=1.0,
+        reason="test",
+        execution_rank=rank,
+    )
+
+
+def _trend(days: int, daily: float) -> list[float]:
+    # Alternate around the drift so the bootstrap has real dispersion.
+    closes = [100.0]
+    for i in range(days):
+        closes.append(closes[-1] * math.exp(daily + (0.01 if i % 2 else -0.01)))
+    return closes
+
+
+def test_insufficient_history_returns_none():
+    assert simulate_horizon_returns(_trend(MIN_HISTORY_DAYS - 1, 0.001), 100, 20, seed=1) is None
+    assert robustness_stats([], "buy", 100, 20, seed=1) is None
+
+
+def test_simulation_is_deterministic_per_seed():
+    closes = _trend(200, 0.001)
+    a = simulate_horizon_returns(closes, 200, 20, seed=7)
+    b = simulate_horizon_returns(closes, 200, 20, seed=7)
+    assert a is not None and b is not None
+    assert a.tolist() == b.tolist()
+    assert a.shape == (200,)
+
+
+def test_uptrend_favours_buys_over_sells():
+    closes = _trend(200, 0.004)
+    buy = robustness_stats(closes, "buy", 500, 20, seed=3)
+    sell = robustness_stats(closes, "sell", 500, 20, seed=3)
+
+    assert buy["favorable_prob"] > 0.8
+    assert sell["favorable_prob"] < 0.2
+    assert buy["p05"] <= buy["p50"] <= buy["p95"]
+    assert 0.0 <= sell["score"] <= buy["score"] <= 1.0
+    assert buy["paths"] == 500 and buy["horizon_days"] == 20
+
+
+def test_apply_scores_only_top_k_in_execution_order():
+    recs = [_rec("C", "buy", 3), _rec("A", "sell", 1), _rec("B", "buy", 2)]
+    closes = {"A": _trend(200, 0.0), "B": _trend(200, 0.002), "C": _trend(200, 0.002)}
+
+    result = apply_monte_carlo(recs, closes, top_k=2, paths=100, horizon_days=10)
+
+    assert [r.symbol for r in result] == ["C", "A", "B"]
+    assert result[0].robustness is None
+    assert result[1].robustness is not None
+    assert result[2].robustness["paths"] == 100
+
+
+def test_apply_clamps_configuration():
+    recs = [_rec("A", "buy", 1)]
+    result = apply_monte_carlo(recs, {"A": _trend(200, 0.001)}, top_k=1, paths=0, horizon_days=10_000)
+
+    assert result[0].robustness["paths"] == 1
+    assert result[0].robustness["horizon_days"] == 250
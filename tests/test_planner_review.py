@@ -0,0 +1,100 @@
+"""Tests for manual approve/reject of trade recommendations."""
+
+import os
+import tempfile
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException, Response
+
+from sentinel.database import Database
+from sentinel.planner.review import (
+    clear_rejection,
+    filter_rejected,
+    get_rejections,
+    reject_recommendation,
+    validate_target,
+)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _rec(symbol: str, action: str):
+    return SimpleNamespace(symbol=symbol, action=action)
+
+
+class TestValidateTarget:
+    def test_accepts_symbol_and_action(self):
+        assert validate_target(" AAPL.US ", "buy") == ("AAPL.US", "buy")
+
+    @pytest.mark.parametrize("symbol,action", [("", "buy"), (None, "sell"), ("AAPL.US", "hold")])
+    def test_rejects_bad_input(self, symbol, action):
+        with pytest.raises(ValueError):
+            validate_target(symbol, action)
+
+
+@pytest.mark.asyncio
+async def test_rejection_filters_until_expiry(temp_db):
+    now_ts = 1_800_000_000
+    await reject_recommendation(temp_db, "AAPL.US", "buy", hours=2, now_ts=now_ts)
+
+    recs = [_rec("AAPL.US", "buy"), _rec("AAPL.US", "sell"), _rec("MSFT.US", "buy")]
+    active = await get_rejections(temp_db, now_ts=now_ts + 60)
+    assert [(r.symbol, r.action) for r in filter_rejected(recs, active)] == [("AAPL.US", "sell"), ("MSFT.US", "buy")]
+
+    expired = await get_rejections(temp_db, now_ts=now_ts + 2 * 3600 + 1)
+    assert filter_rejected(recs, expired) == recs
+
+
+@pytest.mark.asyncio
+async def test_clear_rejection(temp_db):
+    await reject_recommendation(temp_db, "AAPL.US", "buy")
+    await clear_rejection(temp_db, "AAPL.US", "buy")
+    assert await get_rejections(temp_db) == {}
+
+
+@pytest.mark.asyncio
+async def test_reject_rejects_bad_hours(temp_db):
+    with pytest.raises(ValueError):
+        await reject_recommendation(temp_db, "AAPL.US", "buy", hours=0)
+
+
+@pytest.mark.asyncio
+async def test_approve_requires_live_trading_mode():
+    import sentinel.api.routers.planner as planner_router
+
+    deps = MagicMock()
+    deps.settings.get = AsyncMock(return_value="research")
+
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.approve_recommendation_endpoint({"symbol": "AAPL.US", "action": "buy"}, deps, Response())
+
+    assert exc.value.status_code == 409
+
+
+@pytest.mark.asyncio
+async def test_reject_endpoint_validates_action():
+    import sentinel.api.routers.planner as planner_router
+
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.reject_recommendation_endpoint({"symbol": "AAPL.US", "action": "hold"}, MagicMock())
+
+    assert exc.value.status_code == 400
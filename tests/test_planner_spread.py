@@ -0,0 +1,112 @@
+"""Tests for bid/ask spread awareness in planning."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.spread import quote_spread_bps, spread_adjusted_score, spread_too_wide
+from sentinel.utils.fees import FeeCalculator, spread_cost
+
+
+def test_quote_spread_bps():
+    assert quote_spread_bps({"bid": 99.0, "ask": 101.0}) == pytest.approx(200)
+    assert quote_spread_bps({"bbp": 99.9, "bap": 100.1}) == pytest.approx(20)
+    assert quote_spread_bps({"bid": 0, "ask": 101.0}) is None
+    assert quote_spread_bps({"bid": 101.0, "ask": 99.0}) is None
+    assert quote_spread_bps({"price": 100.0}) is None
+    assert quote_spread_bps(None) is None
+
+
+def test_spread_score_and_threshold():
+    assert spread_adjusted_score(0.8, None, 150) == 0.8
+    assert spread_adjusted_score(0.8, 75, 150) == pytest.approx(0.6)
+    assert spread_adjusted_score(0.8, 300, 150) == pytest.approx(0.4)
+    assert spread_adjusted_score(0.8, 75, 0) == 0.8
+    assert spread_too_wide(151, 150)
+    assert not spread_too_wide(150, 150)
+    assert not spread_too_wide(500, 0)
+    assert not spread_too_wide(None, 150)
+
+
+@pytest.mark.asyncio
+async def test_fee_batch_reports_spread_cost():
+    settings = MagicMock()
+    values = {"transaction_fee_fixed": 1.0, "transaction_fee_percent": 0.0}
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    summary = await FeeCalculator(settings).calculate_batch(
+        [
+            {"action": "buy", "value_eur": 1000.0, "spread_bps": 40},
+            {"action": "sell", "value_eur": 500.0},
+        ]
+    )
+    assert spread_cost(1000.0, 40) == pytest.approx(2.0)
+    assert summary["spread_costs"] == pytest.approx(2.0)
+    assert summary["total_fees"] == pytest.approx(2.0)
+
+
+def _engine(quotes: dict, max_spread_bps: float) -> RebalanceEngine:
+    db = MagicMock()
+    db.get_all_positions = AsyncMock(return_value=[])
+    db.get_all_securities = AsyncMock(
+        return_value=[
+            {"symbol": symbol, "currency": "EUR", "min_lot": 1, "allow_buy": 1, "allow_sell": 1}
+            for symbol in quotes
+        ]
+    )
+    db.get_prices = AsyncMock(return_value=[{"date": i, "close": 100.0} for i in range(300)])
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+    db.get_prices_for_symbols = None
+    db.get_latest_forecast_scores = None
+    db.get_strategy_states = None
+    db.get_latest_trades_for_symbols = None
+
+    engine = RebalanceEngine(db=db)
+    engine._broker = MagicMock()
+    engine._broker.get_quotes = AsyncMock(return_value=quotes)
+    settings_values = {
+        "min_trade_value": 100.0,
+        "strategy_min_opp_score": 0.55,
+        "max_position_pct": 50,
+        "strategy_opportunity_cooloff_days": 0,
+        "strategy_core_cooloff_days": 0,
+        "strategy_fallback_wait_days": 0,
+        "planner_max_spread_bps": max_spread_bps,
+    }
+    engine._settings = MagicMock()
+    engine._settings.get = AsyncMock(side_effect=lambda key, default=None: settings_values.get(key, default))
+    engine._portfolio = MagicMock()
+    engine._portfolio.total_cash_eur = AsyncMock(return_value=50_000.0)
+    engine._currency = MagicMock()
+    engine._currency.get_rate = AsyncMock(return_value=1.0)
+    engine._currency.to_eur = AsyncMock(side_effect=lambda amt, curr: amt)
+    engine._get_deficit_sells = AsyncMock(return_value=[])
+    engine._deposit_history = MagicMock()
+    engine._deposit_history.get_rolling_6m_avg_deposit = AsyncMock(return_value=500.0)
+    return engine
+
+
+@pytest.mark.asyncio
+@pytest.mark.parametrize(("quote", "max_spread_bps", "bought"), [
+    ({"price": 100.0, "bid": 99.95, "ask": 100.05}, 150, True),
+    ({"price": 100.0, "bid": 98.0, "ask": 102.0}, 150, False),
+    ({"price": 100.0, "bid": 98.0, "ask": 102.0}, 0, True),
+])
+async def test_wide_spread_candidates_are_not_bought(quote, max_spread_bps, bought):
+    engine = _engine({"AAPL": quote}, max_spread_bps)
+    with (
+        patch("sentinel.planner.rebalance.load_exclusion_lists", AsyncMock(return_value=[])),
+        patch("sentinel.planner.rebalance.get_earnings_freeze", AsyncMock(return_value={})),
+        patch("sentinel.planner.rebalance.get_new_money_eur", AsyncMock(return_value=0.0)),
+    ):
+        recs = await engine.get_recommendations(
+            ideal={"AAPL": 0.3},
+            current={"AAPL": 0.0},
+            total_value=20_000.0,
+        )
+
+    buys = [r for r in recs if r.action == "buy"]
+    assert bool(buys) is bought
+    if buys:
+        assert buys[0].spread_bps == pytest.approx(quote_spread_bps(quote), abs=0.01)
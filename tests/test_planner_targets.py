@@ -0,0 +1,190 @@
+"""Tests for position-level target weights."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.planner.analyzer import PortfolioAnalyzer
+from sentinel.planner.targets import (
+    apply_position_targets,
+    position_target,
+    position_target_drift,
+    validate_position_target,
+    validate_target_changes,
+    with_target_changes,
+)
+
+
+class TestValidation:
+    def test_defaults_to_soft(self):
+        assert validate_position_target({"target_pct": 8}) == {"target_pct": 8.0, "mode": "soft"}
+        assert validate_position_target({"target_pct": 0, "mode": "hard"}) == {"target_pct": 0.0, "mode": "hard"}
+
+    @pytest.mark.parametrize(
+        "data",
+        [{}, {"target_pct": "8"}, {"target_pct": True}, {"target_pct": 101}, {"target_pct": 8, "mode": "firm"}],
+    )
+    def test_rejects_malformed_targets(self, data):
+        with pytest.raises(ValueError):
+            validate_position_target(data)
+
+    def test_reads_target_from_security_row(self):
+        assert position_target({"symbol": "A"}) is None
+        assert position_target({"target_weight_pct": 5, "target_weight_mode": "hard"}) == {
+            "target_pct": 5.0,
+            "mode": "hard",
+            "source": "manual",
+            "updated_at": None,
+        }
+
+
+class TestBulkChanges:
+    SECURITIES = [
+        {"symbol": "A", "target_weight_pct": 40, "target_weight_mode": "hard", "target_weight_source": "manual"},
+        {"symbol": "B", "target_weight_pct": 30, "target_weight_source": "optimizer"},
+        {"symbol": "C"},
+    ]
+
+    def test_validates_and_applies_changes(self):
+        changes = validate_target_changes({"targets": {"B": {"target_pct": 50}, "A": None}}, self.SECURITIES)
+        assert changes == {"B": {"target_pct": 50.0, "mode": "soft"}, "A": None}
+
+        rows = {row["symbol"]: position_target(row) for row in with_target_changes(self.SECURITIES, changes)}
+        assert rows["A"] is None
+        assert rows["B"]["source"] == "manual"
+        assert rows["B"]["target_pct"] == 50.0
+
+    @pytest.mark.parametrize(
+        "data",
+        [
+            {},
+            {"targets": {}},
+            {"targets": {"X": {"target_pct": 5}}},
+            {"targets": {"C": {"target_pct": 500}}},
+            # Optimizer targets don't count, but A's manual 40% does.
+            {"targets": {"C": {"target_pct": 61}}},
+        ],
+    )
+    def test_rejects_bad_edits(self, data):
+        with pytest.raises(ValueError):
+            validate_target_changes(data, self.SECURITIES)
+
+
+class TestApply:
+    def test_hard_target_is_pinned_and_others_share_the_rest(self):
+        weights = {"A": 0.45, "B": 0.45}
+
+        result = apply_position_targets(
+            weights, {"C": {"target_pct": 30, "mode": "hard"}}, budget=0.9, max_position=1.0
+        )
+
+        assert result["C"] == pytest.approx(0.30)
+        assert result["A"] == pytest.approx(0.30)
+        assert result["B"] == pytest.approx(0.30)
+
+    def test_soft_target_shrinks_with_the_rest_when_overcommitted(self):
+        weights = {"A": 0.5, "B": 0.5}
+
+        result = apply_position_targets(
+            weights, {"A": {"target_pct": 100, "mode": "soft"}}, budget=1.0, max_position=1.0
+        )
+
+        assert result == {"A": pytest.approx(2 / 3), "B": pytest.approx(1 / 3)}
+
+    def test_soft_target_kept_when_there_is_room(self):
+        weights = {"A": 0.5, "B": 0.5}
+
+        result = apply_position_targets(
+            weights, {"A": {"target_pct": 20, "mode": "soft"}}, budget=1.0, max_position=1.0
+        )
+
+        assert result == {"A": pytest.approx(0.2), "B": pytest.approx(0.8)}
+
+    def test_targets_are_capped_and_hard_targets_fit_the_budget(self):
+        targets = {"A": {"target_pct": 80, "mode": "hard"}, "B": {"target_pct": 40, "mode": "hard"}}
+
+        result = apply_position_targets({"C": 1.0}, targets, budget=0.9, max_position=0.6)
+
+        assert result == {"A": pytest.approx(0.54), "B": pytest.approx(0.36)}
+
+    def test_zero_target_drops_the_position(self):
+        result = apply_position_targets(
+            {"A": 0.5, "B": 0.5}, {"A": {"target_pct": 0, "mode": "hard"}}, budget=1.0, max_position=1.0
+        )
+
+        assert result == {"B": pytest.approx(1.0)}
+
+
+def test_drift_rows_sorted_by_size():
+    securities = [
+        {"symbol": "A", "name": "Alpha", "target_weight_pct": 10, "target_weight_mode": "hard"},
+        {"symbol": "B", "target_weight_pct": 20, "target_weight_source": "optimizer"},
+        {"symbol": "C"},
+    ]
+
+    rows = position_target_drift(securities, {"A": 0.09, "B": 0.25}, {"A": 0.10, "B": 0.20})
+
+    assert [(r["symbol"], r["source"], round(r["drift_pct"], 6)) for r in rows] == [
+        ("B", "optimizer", 5.0),
+        ("A", "manual", -1.0),
+    ]
+    assert rows[1]["ideal_pct"] == pytest.approx(10.0)
+
+
+def _flat_prices():
+    return [{"date": f"2025-01-{(i % 28) + 1:02d}", "close": 100.0} for i in range(300)]
+
+
+@pytest.mark.asyncio
+async def test_ideal_portfolio_respects_manual_targets():
+    db = MagicMock()
+    db.get_all_securities = AsyncMock(
+        return_value=[
+            {"symbol": "AAA", "user_multiplier": 1.0},
+            {"symbol": "BBB", "user_multiplier": 1.0},
+            # Below the qualifying threshold, held only for its manual target.
+            {"symbol": "CCC", "user_multiplier": 0.5, "target_weight_pct": 20, "target_weight_mode": "hard"},
+            # Optimizer targets are for drift tracking only.
+            {
+                "symbol": "DDD",
+                "user_multiplier": 0.5,
+                "target_weight_pct": 20,
+                "target_weight_source": "optimizer",
+            },
+        ]
+    )
+    db.get_prices = AsyncMock(return_value=_flat_prices())
+    db.get_uninvested_dividends = AsyncMock(return_value={})
+    values = {
+        "max_dividend_reinvestment_boost": 0,
+        "strategy_ideal_qualifying_threshold": 0.65,
+        "max_position_pct": 100,
+        "target_cash_pct": 0,
+    }
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    calculator = AllocationCalculator(db=db, settings=settings)
+
+    ideal = await calculator.calculate_ideal_portfolio(as_of_date="2025-01-15")
+
+    assert ideal == {"AAA": pytest.approx(0.4), "BBB": pytest.approx(0.4), "CCC": pytest.approx(0.2)}
+    bundle = calculator.get_last_signal_bundle(as_of_date="2025-01-15")
+    assert bundle["sleeves"]["CCC"] == "core"
+    assert bundle["allocation_decomposition"]["symbols"]["CCC"]["position_target_mode"] == "hard"
+
+
+@pytest.mark.asyncio
+async def test_analyzer_reports_target_drift(monkeypatch):
+    db = MagicMock()
+    db.get_all_securities = AsyncMock(return_value=[{"symbol": "A", "target_weight_pct": 10}])
+    analyzer = PortfolioAnalyzer(db=db, portfolio=MagicMock(), currency=MagicMock(), settings=MagicMock())
+    analyzer.get_current_allocations = AsyncMock(return_value={"A": 0.14})
+
+    async def fake_ideal(_calculator):
+        return {"A": 0.10}
+
+    monkeypatch.setattr("sentinel.planner.allocation.AllocationCalculator.calculate_ideal_portfolio", fake_ideal)
+
+    result = await analyzer.get_position_targets()
+    assert [(r["symbol"], round(r["drift_pct"], 6)) for r in result["targets"]] == [("A", 4.0)]
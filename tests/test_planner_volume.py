@@ -0,0 +1,150 @@
+"""Tests for average daily volume screening and the per-order ADV cap."""
+
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.volume import average_daily_volume, is_illiquid, max_order_quantity, update_security_volume
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _bars(volumes, close=10.0):
+    return [{"date": f"2026-09-{day:02d}", "close": close, "volume": volume} for day, volume in volumes]
+
+
+def test_average_daily_volume():
+    prices = [
+        {"date": "2026-10-03", "close": 10.0, "volume": 300},
+        {"date": "2026-10-02", "close": 20.0, "volume": None},
+        {"date": "2026-10-01", "close": 20.0, "volume": 100},
+        {"date": "2026-09-30", "close": 5.0, "volume": 1000},
+    ]
+    assert average_daily_volume(prices, 2) == (200.0, 2500.0)
+    assert average_daily_volume([{"close": 1.0, "volume": 0}], 20) is None
+
+
+def test_max_order_quantity_and_flag():
+    assert max_order_quantity(10_000, 5.0) == 500
+    assert max_order_quantity(10_000, 5.0, lot_size=300) == 300
+    assert max_order_quantity(10_000, 1.0, lot_size=300) == 0
+    assert max_order_quantity(None, 5.0) is None
+    assert max_order_quantity(10_000, 0) is None
+    assert is_illiquid(1000.0, 25000.0)
+    assert not is_illiquid(1000.0, 0)
+    assert not is_illiquid(None, 25000.0)
+
+
+@pytest.mark.asyncio
+async def test_update_security_volume_stores_eur_turnover(temp_db):
+    await temp_db.upsert_security("ACME.US", name="Acme", currency="USD")
+    await temp_db.save_prices("ACME.US", _bars([(1, 1000), (2, 3000), (3, 2000)]))
+    to_eur = AsyncMock(side_effect=lambda amount, currency: amount * 0.5)
+
+    fields = await update_security_volume(temp_db, await temp_db.get_security("ACME.US"), 2, 10_000.0, to_eur)
+
+    assert (fields["adv_volume"], fields["adv_turnover_eur"], fields["illiquid"]) == (2500.0, 12500.0, 0)
+    stored = await temp_db.get_security("ACME.US")
+    assert (stored["adv_volume"], stored["illiquid"]) == (2500.0, 0)
+
+    fields = await update_security_volume(temp_db, stored, 2, 20_000.0, to_eur)
+    assert fields["illiquid"] == 1
+    assert (await temp_db.get_security("ACME.US"))["illiquid"] == 1
+
+    await temp_db.upsert_security("NEW.EU", name="New", currency="EUR")
+    assert await update_security_volume(temp_db, await temp_db.get_security("NEW.EU"), 20, 0, to_eur) is None
+
+
+@pytest.mark.asyncio
+async def test_security_liquidity_job(temp_db):
+    from sentinel.jobs.tasks import security_liquidity
+
+    await temp_db.upsert_security("THIN.EU", name="Thin", currency="EUR")
+    await temp_db.save_prices("THIN.EU", _bars([(1, 100), (2, 100)]))
+    settings = AsyncMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: {"min_adv_turnover_eur": 5000.0}.get(key, default))
+
+    with patch("sentinel.settings.Settings", return_value=settings):
+        await security_liquidity(temp_db)
+
+    stored = await temp_db.get_security("THIN.EU")
+    assert (stored["adv_turnover_eur"], stored["illiquid"]) == (1000.0, 1)
+    assert stored["adv_updated_at"]
+
+
+async def _recommend(security: dict, max_pct_adv: float):
+    db = MagicMock()
+    db.get_all_positions = AsyncMock(return_value=[])
+    db.get_all_securities = AsyncMock(return_value=[security])
+    db.get_prices = AsyncMock(return_value=[{"date": i, "close": 100.0} for i in range(300)])
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+    db.get_prices_for_symbols = None
+    db.get_latest_forecast_scores = None
+    db.get_strategy_states = None
+    db.get_latest_trades_for_symbols = None
+
+    engine = RebalanceEngine(db=db)
+    engine._broker = MagicMock()
+    engine._broker.get_quotes = AsyncMock(return_value={"AAPL": {"price": 100.0}})
+    settings_values = {
+        "min_trade_value": 100.0,
+        "max_position_pct": 50,
+        "strategy_opportunity_cooloff_days": 0,
+        "strategy_core_cooloff_days": 0,
+        "strategy_fallback_wait_days": 0,
+        "max_order_pct_adv": max_pct_adv,
+    }
+    engine._settings = MagicMock()
+    engine._settings.get = AsyncMock(side_effect=lambda key, default=None: settings_values.get(key, default))
+    engine._portfolio = MagicMock()
+    engine._portfolio.total_cash_eur = AsyncMock(return_value=50_000.0)
+    engine._currency = MagicMock()
+    engine._currency.get_rate = AsyncMock(return_value=1.0)
+    engine._currency.to_eur = AsyncMock(side_effect=lambda amt, curr: amt)
+    engine._get_deficit_sells = AsyncMock(return_value=[])
+    engine._deposit_history = MagicMock()
+    engine._deposit_history.get_rolling_6m_avg_deposit = AsyncMock(return_value=500.0)
+
+    with (
+        patch("sentinel.planner.rebalance.load_exclusion_lists", AsyncMock(return_value=[])),
+        patch("sentinel.planner.rebalance.get_earnings_freeze", AsyncMock(return_value={})),
+        patch("sentinel.planner.rebalance.get_new_money_eur", AsyncMock(return_value=0.0)),
+    ):
+        recs = await engine.get_recommendations(ideal={"AAPL": 0.3}, current={"AAPL": 0.0}, total_value=20_000.0)
+    return [r for r in recs if r.action == "buy"]
+
+
+@pytest.mark.asyncio
+async def test_buys_are_capped_by_adv_and_skip_illiquid_securities():
+    security = {"symbol": "AAPL", "currency": "EUR", "min_lot": 1, "allow_buy": 1, "allow_sell": 1}
+
+    uncapped = await _recommend(security, 5.0)
+    assert uncapped and uncapped[0].quantity > 10
+
+    capped = await _recommend({**security, "adv_volume": 200.0}, 5.0)
+    assert capped[0].quantity == 10
+
+    assert (await _recommend({**security, "adv_volume": 200.0}, 0))[0].quantity == uncapped[0].quantity
+    assert await _recommend({**security, "illiquid": 1}, 5.0) == []
@@ -0,0 +1,190 @@
+"""Tests for what-if planner dry runs."""
+
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.planner.dry_run import DryRunOverrides, _DryRunDatabase, run_dry_run
+from sentinel.settings import Settings, settings_overrides
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    settings._db = db
+    await settings.init_defaults()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+class TestDryRunOverrides:
+    def test_defaults(self):
+        overrides = DryRunOverrides.from_payload(None)
+        assert overrides.extra_cash_eur == 0.0
+        assert overrides.exclude_symbols == []
+        assert overrides.settings == {}
+        assert overrides.min_trade_value is None
+
+    def test_normalizes_payload(self):
+        overrides = DryRunOverrides.from_payload(
+            {
+                "extra_cash_eur": 5000,
+                "exclude_symbols": [" AAPL.US", "AAPL.US", "MSFT.US"],
+                "settings": {"strategy_min_opp_score": 0.3},
+            }
+        )
+        assert overrides.extra_cash_eur == 5000.0
+        assert overrides.exclude_symbols == ["AAPL.US", "MSFT.US"]
+        assert overrides.settings == {"strategy_min_opp_score": 0.3}
+
+    @pytest.mark.parametrize(
+        "payload",
+        [
+            {"extra_cash_eur": -1},
+            {"extra_cash_eur": "5000"},
+            {"exclude_symbols": "AAPL.US"},
+            {"settings": {"trading_mode": "live"}},
+            {"settings": {"strategy_min_opp_score": "high"}},
+            {"min_trade_value": -5},
+            {"deposit": 5000},
+        ],
+    )
+    def test_rejects_invalid_payload(self, payload):
+        with pytest.raises(ValueError):
+            DryRunOverrides.from_payload(payload)
+
+
+@pytest.mark.asyncio
+async def test_settings_overrides_are_scoped_and_not_persisted(temp_db):
+    settings = Settings()
+    original = await settings.get("strategy_min_opp_score")
+
+    with settings_overrides({"strategy_min_opp_score": 0.99}):
+        assert await settings.get("strategy_min_opp_score") == 0.99
+
+    assert await settings.get("strategy_min_opp_score") == original
+    assert await temp_db.get_setting("strategy_min_opp_score") == original
+
+
+@pytest.mark.asyncio
+async def test_dry_run_database_bypasses_caches(temp_db):
+    dry_db = _DryRunDatabase(temp_db)
+    await temp_db.cache_set("planner:ideal_portfolio", "{}")
+
+    assert await dry_db.cache_get("planner:ideal_portfolio") is None
+    await dry_db.cache_set("planner:ideal_portfolio", '{"X": 1}')
+    await dry_db.set_planner_state("submitted_trade", {"order_id": "1"})
+
+    assert await temp_db.cache_get("planner:ideal_portfolio") == "{}"
+    assert await temp_db.get_planner_state("submitted_trade") is None
+
+
+@pytest.mark.asyncio
+async def test_run_dry_run_applies_overrides(temp_db):
+    await temp_db.upsert_security("AAPL.US", name="Apple", active=1)
+    await temp_db.upsert_security("MSFT.US", name="Microsoft", active=1)
+
+    valuation = MagicMock()
+    valuation.current = AsyncMock(return_value={"positions": [], "total_cash_eur": 1000.0})
+    seen: dict = {}
+
+    async def fake_plan(**kwargs):
+        seen.update(kwargs)
+        seen["min_opp_score"] = await Settings().get("strategy_min_opp_score")
+        return [], MagicMock()
+
+    planner = MagicMock()
+    planner.get_recommendations_with_plan = AsyncMock(side_effect=fake_plan)
+
+    overrides = DryRunOverrides.from_payload(
+        {"extra_cash_eur": 5000, "exclude_symbols": ["AAPL.US"], "settings": {"strategy_min_opp_score": 0.42}}
+    )
+    with (
+        patch("sentinel.planner.dry_run.PortfolioValuationService", return_value=valuation),
+        patch("sentinel.planner.dry_run.Planner", return_value=planner),
+    ):
+        _, _, state = await run_dry_run(overrides, db=temp_db, broker=MagicMock())
+
+    assert state.cash_eur() == 6000.0
+    assert seen["eligible_symbols"] == {"MSFT.US"}
+    assert seen["min_opp_score"] == 0.42
+    assert await Settings().get("strategy_min_opp_score") != 0.42
+
+
+@pytest.mark.asyncio
+async def test_dry_run_endpoint_rejects_bad_overrides():
+    import sentinel.api.routers.planner as planner_router
+
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.planning_dry_run(MagicMock(), {"extra_cash_eur": -10})
+
+    assert exc.value.status_code == 400
+
+
+@pytest.mark.asyncio
+async def test_dry_run_database_applies_position_targets(temp_db):
+    await temp_db.upsert_security("AAPL.US", name="Apple", active=1)
+    await temp_db.upsert_security("MSFT.US", name="Microsoft", active=1)
+    await temp_db.set_position_target("MSFT.US", target_pct=5, mode="soft", source="manual", updated_at=None)
+
+    overrides = DryRunOverrides.from_payload(
+        {"position_targets": {"AAPL.US": {"target_pct": 10, "mode": "hard"}, "MSFT.US": None}}
+    )
+    dry_db = _DryRunDatabase(temp_db, overrides.position_targets)
+
+    rows = {row["symbol"]: row for row in await dry_db.get_all_securities(active_only=True)}
+    assert (rows["AAPL.US"]["target_weight_pct"], rows["AAPL.US"]["target_weight_mode"]) == (10.0, "hard")
+    assert rows["MSFT.US"]["target_weight_pct"] is None
+    assert (await dry_db.get_security("AAPL.US"))["target_weight_source"] == "manual"
+    assert (await temp_db.get_security("MSFT.US"))["target_weight_pct"] == 5
+
+
+@pytest.mark.asyncio
+async def test_bulk_target_endpoints(temp_db):
+    import sentinel.api.routers.planner as planner_router
+
+    await temp_db.upsert_security("AAPL.US", name="Apple", active=1)
+    await temp_db.upsert_security("MSFT.US", name="Microsoft", active=1)
+    deps = MagicMock()
+    deps.db = temp_db
+
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.put_position_targets(deps, {"targets": {"AAPL.US": {"target_pct": 101}}})
+    assert exc.value.status_code == 400
+
+    captured = {}
+
+    async def fake_run(overrides, db=None, broker=None):
+        captured["targets"] = overrides.position_targets
+        state = MagicMock()
+        state.cash_eur.return_value = 100.0
+        return [], MagicMock(), state
+
+    body = {"targets": {"AAPL.US": {"target_pct": 60, "mode": "hard"}}}
+    with patch.object(planner_router, "run_dry_run", side_effect=fake_run):
+        preview = await planner_router.preview_position_targets(deps, body)
+    assert captured["targets"] == {"AAPL.US": {"target_pct": 60.0, "mode": "hard"}}
+    assert preview["changes"] == [{"symbol": "AAPL.US", "before": None, "after": {"target_pct": 60.0, "mode": "hard"}}]
+    assert preview["summary"]["cash_after_plan"] == 100.0
+    assert (await temp_db.get_security("AAPL.US"))["target_weight_pct"] is None
+
+    result = await planner_router.put_position_targets(deps, body)
+    assert result["manual_total_pct"] == 60.0
+    assert (await temp_db.get_security("AAPL.US"))["target_weight_pct"] == 60.0
@@ -0,0 +1,234 @@
+"""Tests for planner snapshots and cycle-to-cycle diffs."""
+
+import os
+import tempfile
+from dataclasses import replace
+from unittest.mock import MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.snapshots import diff_snapshots, record_snapshot
+from sentinel.settings import Settings
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    settings._db = db
+    await settings.init_defaults()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _rec(symbol: str, action: str, quantity: int, rank: int) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.1,
+        target_allocation=0.2,
+        allocation_delta=0.1,
+        current_value_eur=1000.0,
+        target_value_eur=2000.0,
+        value_delta_eur=1000.0 if action == "buy" else -1000.0,
+        quantity=quantity,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=1.0,
+        reason="test",
+        execution_rank=rank,
+    )
+
+
+def _snapshot(snapshot_id, cash, securities, recommendations, settings=None):
+    return {
+        "id": snapshot_id,
+        "created_at": 1_800_000_000 + snapshot_id,
+        "source": "planning:refresh",
+        "context": {
+            "cash_eur": cash,
+            "total_value_eur": 10_000.0,
+            "settings": settings or {"strategy_min_opp_score": 0.5},
+            "securities": securities,
+        },
+        "recommendations": recommendations,
+    }
+
+
+def _security(price, score, target=0.1, current=0.1, quantity=10):
+    return {
+        "price": price,
+        "quantity": quantity,
+        "opp_score": score,
+        "target_pct": target,
+        "current_pct": current,
+        "trade_blocked": False,
+    }
+
+
+class TestDiffSnapshots:
+    def test_identical_snapshots_have_no_changes(self):
+        securities = {"AAPL.US": _security(100.0, 0.6)}
+        recs = [{"symbol": "AAPL.US", "action": "buy", "quantity": 5}]
+        diff = diff_snapshots(_snapshot(1, 500.0, securities, recs), _snapshot(2, 500.0, securities, recs))
+
+        assert diff["inputs"] == {}
+        assert diff["sequence"]["unchanged"] is True
+        assert diff["explanations"] == []
+
+    def test_explains_sequence_change_with_inputs(self):
+        before = _snapshot(
+            1,
+            500.0,
+            {"AAPL.US": _security(100.0, 0.6), "MSFT.US": _security(300.0, 0.2)},
+            [{"symbol": "AAPL.US", "action": "buy", "quantity": 5}],
+        )
+        after = _snapshot(
+            2,
+            2500.0,
+            {"AAPL.US": _security(100.02, 0.605), "MSFT.US": _security(250.0, 0.7)},
+            [
+                {"symbol": "MSFT.US", "action": "buy", "quantity": 8},
+                {"symbol": "AAPL.US", "action": "buy", "quantity": 5},
+            ],
+            settings={"strategy_min_opp_score": 0.4},
+        )
+
+        diff = diff_snapshots(before, after)
+
+        assert diff["inputs"]["cash_eur"] == {"from": 500.0, "to": 2500.0}
+        assert diff["inputs"]["settings"] == {"strategy_min_opp_score": {"from": 0.5, "to": 0.4}}
+        # AAPL moved by less than the noise tolerances.
+        assert "AAPL.US" not in diff["inputs"]["securities"]
+        assert set(diff["inputs"]["securities"]["MSFT.US"]) == {"price", "opp_score"}
+
+        assert diff["sequence"]["added"] == [{"symbol": "MSFT.US", "action": "buy", "rank": 1}]
+        assert diff["sequence"]["moved"] == [{"symbol": "AAPL.US", "action": "buy", "from": 1, "to": 2}]
+
+        added = next(e for e in diff["explanations"] if e["change"] == "added")
+        assert added["symbol"] == "MSFT.US"
+        assert "price" in added["symbol_inputs"]
+        assert added["portfolio_inputs"] == ["cash_eur", "settings"]
+
+    def test_reports_universe_and_resize_changes(self):
+        before = _snapshot(
+            1,
+            500.0,
+            {"AAPL.US": _security(100.0, 0.6)},
+            [{"symbol": "AAPL.US", "action": "sell", "quantity": 5}],
+        )
+        after = _snapshot(
+            2,
+            500.0,
+            {"AAPL.US": _security(100.0, 0.6, quantity=7), "SAP.EU": _security(120.0, 0.4)},
+            [{"symbol": "AAPL.US", "action": "sell", "quantity": 2}],
+        )
+
+        diff = diff_snapshots(before, after)
+
+        assert diff["inputs"]["securities"]["SAP.EU"] == {"universe": {"from": False, "to": True}}
+        assert diff["inputs"]["securities"]["AAPL.US"]["quantity"] == {"from": 10, "to": 7}
+        assert diff["sequence"]["resized"] == [{"symbol": "AAPL.US", "action": "sell", "from": 5, "to": 2}]
+
+
+@pytest.mark.asyncio
+async def test_record_snapshot_persists_context_and_ranked_batch(temp_db):
+    planner = MagicMock()
+    planner.get_last_planning_inputs.return_value = {
+        "as_of_date": None,
+        "ideal": {"AAPL.US": 0.2},
+        "current": {"AAPL.US": 0.1},
+        "total_value_eur": 10_000.0,
+        "cash_eur": 750.0,
+        "signal_bundle": {"rebalance_signals": {"AAPL.US": {"opp_score": 0.8}}},
+        "security_data": {"AAPL.US": {"price": 180.0, "current_qty": 5}},
+    }
+
+    robustness = {"paths": 200, "horizon_days": 20, "expected_return": 0.012, "favorable_prob": 0.6, "score": 0.55}
+    recs = [_rec("MSFT.US", "buy", 2, rank=2), replace(_rec("AAPL.US", "buy", 3, rank=1), robustness=robustness)]
+    snapshot_id = await record_snapshot(temp_db, planner, recs, "planning:refresh")
+
+    snapshot = await temp_db.get_planner_snapshot(snapshot_id)
+    assert snapshot["source"] == "planning:refresh"
+    assert snapshot["context"]["cash_eur"] == 750.0
+    assert snapshot["context"]["securities"]["AAPL.US"]["opp_score"] == 0.8
+    assert snapshot["context"]["securities"]["AAPL.US"]["price"] == 180.0
+    assert "strategy_min_opp_score" in snapshot["context"]["settings"]
+    assert [r["symbol"] for r in snapshot["recommendations"]] == ["AAPL.US", "MSFT.US"]
+    assert [r["robustness"] for r in snapshot["recommendations"]] == [robustness, None]
+
+    listed = await temp_db.get_planner_snapshots()
+    assert listed[0]["id"] == snapshot_id
+    assert listed[0]["recommendation_count"] == 2
+
+
+@pytest.mark.asyncio
+async def test_record_snapshot_skips_backtest_runs(temp_db):
+    planner = MagicMock()
+    planner.get_last_planning_inputs.return_value = {"as_of_date": "2024-01-02", "ideal": {}}
+
+    assert await record_snapshot(temp_db, planner, [], "planning:refresh") is None
+    assert await temp_db.get_planner_snapshots() == []
+
+
+@pytest.mark.asyncio
+async def test_prune_keeps_newest_snapshots(temp_db):
+    ids = [await temp_db.insert_planner_snapshot("planning:refresh", {}, []) for _ in range(5)]
+
+    assert await temp_db.prune_planner_snapshots(2) == 3
+    assert [s["id"] for s in await temp_db.get_planner_snapshots()] == ids[-1:-3:-1]
+
+
+@pytest.mark.asyncio
+async def test_diff_endpoint_defaults_to_latest_pair(temp_db):
+    import sentinel.api.routers.planner as planner_router
+
+    first = await temp_db.insert_planner_snapshot("planning:refresh", {"cash_eur": 100.0}, [])
+    await temp_db.insert_planner_snapshot("planning:refresh", {"cash_eur": 150.0}, [])
+    latest = await temp_db.insert_planner_snapshot(
+        "trading:execute", {"cash_eur": 900.0}, [{"symbol": "AAPL.US", "action": "buy", "quantity": 1}]
+    )
+    deps = MagicMock()
+    deps.db = temp_db
+
+    diff = await planner_router.get_planning_diff(deps)
+    assert diff["to"]["id"] == latest
+    assert diff["from"]["id"] == latest - 1
+    assert diff["inputs"]["cash_eur"] == {"from": 150.0, "to": 900.0}
+
+    explicit = await planner_router.get_planning_diff(deps, from_id=first, to_id=latest)
+    assert explicit["from"]["id"] == first
+
+
+@pytest.mark.asyncio
+async def test_diff_endpoint_404s_without_snapshots(temp_db):
+    import sentinel.api.routers.planner as planner_router
+
+    deps = MagicMock()
+    deps.db = temp_db
+
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.get_planning_diff(deps)
+    assert exc.value.status_code == 404
+
+    only = await temp_db.insert_planner_snapshot("planning:refresh", {}, [])
+    with pytest.raises(HTTPException) as exc:
+        await planner_router.get_planning_diff(deps, to_id=only)
+    assert exc.value.status_code == 404
@@ -0,0 +1,146 @@
+"""Tests for live valuation snapshots and the portfolio history API."""
+
+import os
+import tempfile
+import time
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.services.history import PortfolioHistoryService, downsample
+
+DAY = 86400
+# Real midnight UTC, so reconstructed snapshots fall inside `days` windows.
+TODAY = int(time.time()) // DAY * DAY
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _service(db, retention_days=14):
+    currency = MagicMock()
+    currency.get_rate = AsyncMock(side_effect=lambda c: {"EUR": 1.0, "USD": 0.9}[c])
+    settings = MagicMock()
+    settings.get = AsyncMock(return_value=retention_days)
+    return PortfolioHistoryService(db=db, broker=MagicMock(), currency=currency, settings=settings)
+
+
+def _valuation(total_positions):
+    return {
+        "positions": [
+            {
+                "symbol": "AAPL.US",
+                "quantity": 10,
+                "current_price": 200.0,
+                "currency": "USD",
+                "value_eur": total_positions,
+            }
+        ],
+        "cash": {"EUR": 100.0},
+        "total_cash_eur": 100.0,
+        "total_positions_eur": total_positions,
+        "total_value_eur": total_positions + 100.0,
+    }
+
+
+@pytest.mark.asyncio
+async def test_record_stores_full_valuation(temp_db, monkeypatch):
+    monkeypatch.setattr(
+        "sentinel.services.history.PortfolioValuationService.current", AsyncMock(return_value=_valuation(1800.0))
+    )
+
+    data = await _service(temp_db).record(now_ts=TODAY)
+
+    assert data["total_value_eur"] == 1900.0
+    assert data["positions"]["AAPL.US"] == {"quantity": 10, "price": 200.0, "currency": "USD", "value_eur": 1800.0}
+    assert data["fx_rates"] == {"EUR": 1.0, "USD": 0.9}
+    assert await temp_db.get_valuation_snapshots() == [{"ts": TODAY, "data": data}]
+
+
+@pytest.mark.asyncio
+async def test_record_thins_old_intraday_snapshots(temp_db, monkeypatch):
+    monkeypatch.setattr(
+        "sentinel.services.history.PortfolioValuationService.current", AsyncMock(return_value=_valuation(1800.0))
+    )
+    old_day = TODAY - 20 * DAY
+    for ts in (old_day + 36000, old_day + 37800, old_day + 39600, TODAY - DAY + 36000, TODAY - DAY + 37800):
+        await temp_db.save_valuation_snapshot(ts, {"total_value_eur": 1.0})
+
+    await _service(temp_db, retention_days=14).record(now_ts=TODAY)
+
+    assert [s["ts"] for s in await temp_db.get_valuation_snapshots()] == [
+        old_day + 39600,
+        TODAY - DAY + 36000,
+        TODAY - DAY + 37800,
+        TODAY,
+    ]
+
+
+@pytest.mark.asyncio
+async def test_history_prefers_live_snapshots_over_reconstructed_days(temp_db):
+    await temp_db.upsert_portfolio_snapshot(
+        TODAY - 2 * DAY, {"positions": {"AAPL.US": {"quantity": 10, "value_eur": 1700.0}}, "cash_eur": 50.0}
+    )
+    await temp_db.upsert_portfolio_snapshot(
+        TODAY - DAY, {"positions": {"AAPL.US": {"quantity": 10, "value_eur": 1750.0}}, "cash_eur": 50.0}
+    )
+    for ts, value in ((TODAY - DAY + 36000, 1780.0), (TODAY - DAY + 37800, 1790.0)):
+        await temp_db.save_valuation_snapshot(
+            ts,
+            {
+                "total_value_eur": value + 50.0,
+                "positions_value_eur": value,
+                "cash_eur": 50.0,
+                "positions": {"AAPL.US": {"value_eur": value}},
+            },
+        )
+
+    result = await _service(temp_db).history("1d", 7, include_positions=True, now_ts=TODAY + 3600)
+
+    assert [(p["ts"], p["total_value_eur"], p["source"]) for p in result["points"]] == [
+        (TODAY - 2 * DAY, 1750.0, "reconstructed"),
+        (TODAY - DAY + 37800, 1840.0, "valuation"),
+    ]
+    assert result["points"][1]["positions"] == {"AAPL.US": 1790.0}
+
+    hourly = await _service(temp_db).history("1h", 7, now_ts=TODAY + 3600)
+    # 10:00 and 10:30 share an hour; reconstructed days are left out.
+    assert [(p["ts"], p["source"]) for p in hourly["points"]] == [(TODAY - DAY + 37800, "valuation")]
+    assert "positions" not in hourly["points"][0]
+
+
+def test_weekly_downsample_keeps_last_point_of_each_iso_week():
+    monday = 1791763200  # 2026-10-12, a Monday
+    points = [{"ts": monday + offset * DAY} for offset in (0, 3, 6, 7, 8)]
+
+    assert [p["ts"] for p in downsample(points, "1w")] == [monday + 6 * DAY, monday + 8 * DAY]
+
+
+@pytest.mark.asyncio
+async def test_history_endpoint_rejects_bad_params():
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.portfolio import get_portfolio_history
+
+    deps = MagicMock()
+    for kwargs in ({"resolution": "1m"}, {"days": 0}, {"days": 5000}):
+        with pytest.raises(HTTPException) as exc:
+            await get_portfolio_history(deps, **kwargs)
+        assert exc.value.status_code == 400
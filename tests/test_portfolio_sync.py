@@ -9,7 +9,7 @@ import pytest_asyncio
 
 from sentinel.database import Database
 from sentinel.portfolio import Portfolio
-from sentinel.universe import BROKER_POSITION_UNIVERSE_SOURCE
+from sentinel.universe import ARCHIVE_POSITION_CLOSED, BROKER_POSITION_UNIVERSE_SOURCE
 
 
 @pytest_asyncio.fixture
@@ -104,3 +104,25 @@ async def test_sync_self_heals_when_metadata_is_temporarily_unavailable(temp_db)
     assert prices == []
     assert position is not None
     assert position["quantity"] == 2
+
+
+@pytest.mark.asyncio
+async def test_sync_archives_a_removed_security_once_its_position_closes(temp_db):
+    await temp_db.upsert_security(
+        "GONE.EU", name="Gone", active=1, allow_buy=0, allow_sell=1, universe_source=BROKER_POSITION_UNIVERSE_SOURCE
+    )
+    await temp_db.upsert_security("KEPT.EU", name="Kept", active=1, allow_buy=1, allow_sell=1)
+    for symbol in ("GONE.EU", "KEPT.EU"):
+        await temp_db.upsert_position(symbol, quantity=3, current_price=10.0, currency="EUR")
+    await temp_db.upsert_trade("T1", "GONE.EU", "SELL", 3, 10.0, 1767225600, {})
+    broker = _broker_with_position("NEW.EU")
+
+    await Portfolio(db=temp_db, broker=broker).sync()
+
+    gone = await temp_db.get_security("GONE.EU")
+    kept = await temp_db.get_security("KEPT.EU")
+    assert (int(gone["active"]), int(gone["allow_sell"])) == (0, 0)
+    assert gone["archive_reason"] == ARCHIVE_POSITION_CLOSED
+    assert len(await temp_db.get_trades(symbol="GONE.EU")) == 1
+    assert int(kept["active"]) == 1
+    assert kept["archived_at"] is None
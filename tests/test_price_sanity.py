@@ -0,0 +1,151 @@
+"""Tests for the incoming price/quote sanity check."""
+
+import json
+import os
+import tempfile
+from unittest.mock import AsyncMock, patch
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.price_sanity import (
+    PriceSanityChecker,
+    anomaly,
+    resolve_quarantine,
+    validate_alert_count,
+    validate_max_move_pct,
+)
+
+
+def _row(date: str, close: float) -> dict:
+    return {"date": date, "open": close, "high": close, "low": close, "close": close, "volume": 1000}
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+    await db.upsert_security("ASML.EU", name="ASML", currency="EUR", active=1)
+    await db.save_prices("ASML.EU", [_row("2026-10-01", 650), _row("2026-10-02", 650)])
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_anomaly():
+    assert anomaly(650, 652, 50) is None
+    assert anomaly(0, 652, 50) == "zero_or_negative"
+    assert anomaly(6.52, 652, 50) == "decimal_shift"
+    assert anomaly(6520, 652, 50) == "decimal_shift"
+    assert anomaly(1100, 652, 50) == "out_of_band"
+    assert anomaly(1100, None, 50) is None
+
+
+def test_validators():
+    assert validate_max_move_pct(25) == 25.0
+    assert validate_alert_count(2) == 2
+    for bad in [0, 2000, "50", True]:
+        with pytest.raises(ValueError):
+            validate_max_move_pct(bad)
+    for bad in [0, 1.5, True]:
+        with pytest.raises(ValueError):
+            validate_alert_count(bad)
+
+
+@pytest.mark.asyncio
+async def test_check_prices_quarantines_suspect_rows(temp_db):
+    rows = [_row("2026-10-02", 650), _row("2026-10-03", 6.5), _row("2026-10-04", 655)]
+    accepted = await PriceSanityChecker(temp_db).check_prices("ASML.EU", rows)
+
+    assert [row["date"] for row in accepted] == ["2026-10-02", "2026-10-04"]
+    [entry] = await temp_db.get_price_quarantine()
+    assert (entry["kind"], entry["date"], entry["value"], entry["reference"], entry["reason"]) == (
+        "price",
+        "2026-10-03",
+        6.5,
+        650,
+        "decimal_shift",
+    )
+
+
+@pytest.mark.asyncio
+async def test_check_prices_leaves_older_history_alone(temp_db):
+    rows = [_row("2026-09-01", 0), _row("2026-10-02", 650)]
+    accepted = await PriceSanityChecker(temp_db).check_prices("ASML.EU", rows)
+
+    assert len(accepted) == 2
+    assert await temp_db.get_price_quarantine() == []
+
+
+@pytest.mark.asyncio
+async def test_check_prices_uses_band_setting(temp_db):
+    await temp_db.set_setting("price_sanity_max_move_pct", 10)
+    accepted = await PriceSanityChecker(temp_db).check_prices("ASML.EU", [_row("2026-10-03", 750)])
+
+    assert accepted == []
+    assert (await temp_db.get_price_quarantine())[0]["reason"] == "out_of_band"
+
+
+@pytest.mark.asyncio
+async def test_check_quotes(temp_db):
+    await temp_db.update_quotes_bulk({"ASML.EU": {"price": 652}})
+    quotes = {
+        "ASML.EU": {"price": 65.3},
+        "NEW.US": {"price": 10},
+    }
+    accepted = await PriceSanityChecker(temp_db).check_quotes(quotes)
+    assert list(accepted) == ["NEW.US"]
+
+    accepted = await PriceSanityChecker(temp_db).check_quotes({"ASML.EU": {"price": 653, "x_curr": "USD"}})
+    assert accepted == {}
+    reasons = [entry["reason"] for entry in await temp_db.get_price_quarantine(symbol="ASML.EU")]
+    assert reasons == ["currency_mismatch", "decimal_shift"]
+
+
+@pytest.mark.asyncio
+async def test_rejected_value_is_not_quarantined_again(temp_db):
+    checker = PriceSanityChecker(temp_db)
+    await checker.check_prices("ASML.EU", [_row("2026-10-03", 0)])
+    [entry] = await temp_db.get_price_quarantine()
+
+    assert (await resolve_quarantine(temp_db, entry["id"], accept=False))["status"] == "rejected"
+    assert await resolve_quarantine(temp_db, entry["id"], accept=True) is None
+
+    assert await checker.check_prices("ASML.EU", [_row("2026-10-03", 0)]) == []
+    assert await temp_db.get_price_quarantine() == []
+
+
+@pytest.mark.asyncio
+async def test_accept_writes_the_value(temp_db):
+    await PriceSanityChecker(temp_db).check_prices("ASML.EU", [_row("2026-10-03", 1200)])
+    [entry] = await temp_db.get_price_quarantine()
+
+    assert (await resolve_quarantine(temp_db, entry["id"], accept=True))["status"] == "accepted"
+    prices = await temp_db.get_prices("ASML.EU", days=1)
+    assert prices[0]["date"] == "2026-10-03"
+    assert prices[0]["close"] == 1200
+    assert json.loads(entry["payload"])["volume"] == 1000
+
+
+@pytest.mark.asyncio
+async def test_alerts_once_when_anomalies_repeat(temp_db):
+    await temp_db.set_setting("price_sanity_alert_count", 2)
+    manager = AsyncMock()
+    with patch("sentinel.led.alerts.AlertManager", return_value=manager):
+        checker = PriceSanityChecker(temp_db, settings=AsyncMock())
+        await checker.check_prices("ASML.EU", [_row("2026-10-03", 0)])
+        manager.trigger.assert_not_awaited()
+
+        await checker.check_prices("ASML.EU", [_row("2026-10-04", 0), _row("2026-10-05", 0)])
+        await checker.check_prices("ASML.EU", [_row("2026-10-03", 0)])
+        manager.trigger.assert_awaited_once()
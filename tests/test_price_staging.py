@@ -0,0 +1,123 @@
+"""Tests for write-ahead staging of fetched candles."""
+
+import os
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.price_staging import PriceStaging, validate_candles
+
+
+def _row(date: str, close: float, **fields) -> dict:
+    return {"date": date, "open": close, "high": close, "low": close, "close": close, "volume": 1000, **fields}
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def test_validate_candles_rejects_bad_rows():
+    rows = [
+        _row("2026-10-05", 101),
+        _row("2026-10-01", 100),
+        _row("2026-10-02", 100),
+        _row("2026-10-02", 100),
+        _row("2026-10-03", 100),
+        _row("2026-10-03", 105),
+        _row("2026-10-04", 100, high=90),
+        _row("2026-10-06", 0),
+        {"close": 100},
+    ]
+    accepted, rejected, gaps = validate_candles(rows)
+
+    assert [row["date"] for row in accepted] == ["2026-10-01", "2026-10-02", "2026-10-05"]
+    assert sorted(reason for _, reason in rejected) == [
+        "duplicate",
+        "duplicate",
+        "invalid",
+        "invalid",
+        "ohlc_inconsistent",
+    ]
+    assert gaps == []
+
+
+def test_validate_candles_rejects_isolated_spikes_only():
+    accepted, rejected, _ = validate_candles(
+        [_row("2026-10-01", 100), _row("2026-10-02", 5000), _row("2026-10-05", 101), _row("2026-10-06", 5)]
+    )
+    assert [row["close"] for row in accepted] == [100, 101, 5]
+    assert [(row["date"], reason) for row, reason in rejected] == [("2026-10-02", "spike")]
+
+    # A crash that sticks is real data.
+    accepted, rejected, _ = validate_candles([_row("2026-10-01", 100), _row("2026-10-02", 5), _row("2026-10-05", 5)])
+    assert len(accepted) == 3
+    assert rejected == []
+
+
+def test_validate_candles_reports_gaps():
+    _, _, gaps = validate_candles([_row("2026-09-01", 100), _row("2026-09-30", 100), _row("2026-10-01", 100)])
+    assert gaps == [{"from": "2026-09-01", "to": "2026-09-30", "days": 29}]
+
+
+@pytest.mark.asyncio
+async def test_staging_merges_valid_rows_and_reports_rejections(temp_db):
+    staging = PriceStaging(temp_db)
+    await staging.begin()
+    rows = await staging.stage("AAPL.US", [_row("2026-10-01", 100), _row("2026-10-02", 0), _row("2026-10-05", 101)])
+    await staging.merge("AAPL.US", rows[:1])
+    report = await staging.finish()
+
+    prices = await temp_db.get_prices("AAPL.US")
+    assert [row["date"] for row in prices] == ["2026-10-01"]
+    assert (report["fetched"], report["rejected"], report["quarantined"], report["merged"]) == (3, 1, 1, 1)
+    assert [(row["date"], row["reason"]) for row in report["rejected_rows"]] == [("2026-10-02", "invalid")]
+    assert report["finished_at"] is not None
+
+    cursor = await temp_db.conn.execute("SELECT COUNT(*) FROM price_staging WHERE status = 'staged'")
+    assert (await cursor.fetchone())[0] == 0
+
+
+@pytest.mark.asyncio
+async def test_begin_discards_rows_left_by_an_interrupted_sync(temp_db):
+    interrupted = PriceStaging(temp_db)
+    await interrupted.begin()
+    await interrupted.stage("AAPL.US", [_row("2026-10-01", 100)])
+
+    staging = PriceStaging(temp_db)
+    await staging.begin()
+    await staging.finish()
+
+    cursor = await temp_db.conn.execute("SELECT COUNT(*) FROM price_staging")
+    assert (await cursor.fetchone())[0] == 0
+    assert await temp_db.get_prices("AAPL.US") == []
+    reports = await temp_db.get_price_sync_reports()
+    assert [report["finished_at"] is None for report in reports] == [False, True]
+
+
+@pytest.mark.asyncio
+async def test_old_reports_are_pruned(temp_db):
+    for _ in range(3):
+        staging = PriceStaging(temp_db)
+        await staging.begin()
+        await staging.stage("AAPL.US", [_row("2026-10-01", 0)])
+        await temp_db.finish_price_sync_report(staging.sync_id, {}, {}, keep=2)
+
+    assert len(await temp_db.get_price_sync_reports()) == 2
+    cursor = await temp_db.conn.execute("SELECT COUNT(DISTINCT sync_id) FROM price_staging")
+    assert (await cursor.fetchone())[0] == 2
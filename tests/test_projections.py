@@ -0,0 +1,141 @@
+"""Tests for the Monte Carlo portfolio projection."""
+
+import math
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+from fastapi import HTTPException
+
+from sentinel.projections import _aligned_returns, build_projection, portfolio_estimates, simulate_projection
+
+
+def _prices(daily_returns, start=100.0):
+    rows, close = [{"date": "2023-12-31", "close": start}], start
+    for i, r in enumerate(daily_returns):
+        close *= 1 + r
+        rows.append({"date": f"2024-{1 + i // 28:02d}-{1 + i % 28:02d}", "close": close})
+    return list(reversed(rows))
+
+
+def test_estimates_weight_returns_and_covariance():
+    steady = _prices([0.001] * 120)
+    swing = _prices([0.01, -0.01] * 60)
+    mirror = _prices([-0.01, 0.01] * 60)
+
+    alone = portfolio_estimates({"A": 1.0}, {"A": steady})
+    assert alone["monthly_mean"] == pytest.approx(21 * math.log(1.001))
+    assert alone["monthly_volatility"] == pytest.approx(0.0, abs=1e-9)
+
+    # Perfectly opposed positions cancel out; on its own each one is volatile.
+    assert portfolio_estimates({"B": 1.0}, {"B": swing})["monthly_volatility"] > 0.04
+    hedged = portfolio_estimates({"B": 0.5, "C": 0.5}, {"B": swing, "C": mirror})
+    assert hedged["monthly_volatility"] == pytest.approx(0.0, abs=1e-3)
+
+    half_cash = portfolio_estimates({"A": 0.5}, {"A": steady})
+    assert half_cash["monthly_mean"] == pytest.approx(alone["monthly_mean"] / 2)
+
+
+def test_estimates_skip_short_history():
+    estimates = portfolio_estimates(
+        {"A": 0.3, "NEW": 0.3}, {"A": _prices([0.001] * 120), "NEW": _prices([0.05] * 10)}
+    )
+    assert estimates["coverage"] == pytest.approx(0.5)
+    assert estimates["monthly_mean"] == pytest.approx(0.6 * 21 * math.log(1.001))
+    assert portfolio_estimates({}, {})["coverage"] == 0.0
+
+
+def _dated(daily_returns, first_day, start=100.0):
+    """Price rows from day index `first_day` on, on the same calendar as _prices."""
+    rows, close = [], start
+    for i, r in enumerate([0.0, *daily_returns], start=first_day - 1):
+        close *= 1 + r
+        rows.append({"date": f"{2024 + i // 336}-{1 + i % 336 // 28:02d}-{1 + i % 28:02d}", "close": close})
+    return rows
+
+
+def test_short_history_policies():
+    prices = {
+        "OLD": _dated([0.01, -0.01] * 150, 1),
+        "PEER": _dated([0.002] * 300, 1),
+        "NEW": _dated([0.02] * 100, 201),
+        "TINY": _dated([0.03] * 10, 291),
+    }
+    weights = {"OLD": 0.25, "PEER": 0.25, "NEW": 0.25, "TINY": 0.25}
+    industries = {"PEER": "Software", "NEW": "Software", "TINY": "Banks"}
+
+    symbols, _rows, _w, coverage, short = _aligned_returns(weights, prices, "exclude", industries)
+    assert symbols == ["OLD", "PEER"]
+    assert coverage == 0.5
+    assert short == {"NEW": {"days": 100, "policy": "excluded"}, "TINY": {"days": 10, "policy": "excluded"}}
+
+    symbols, rows, _w, coverage, short = _aligned_returns(weights, prices, "shrink", industries)
+    assert symbols == ["NEW", "OLD", "PEER"]
+    assert coverage == 0.75
+    assert short["NEW"] == {"days": 100, "policy": "shrunk", "proxy": "industry", "own_weight": 0.397}
+    assert short["TINY"]["policy"] == "excluded"
+    new = [row[0] for row in rows]
+    assert new[0] == pytest.approx(math.log(1.002))
+    own = 100 / 252
+    assert new[-1] == pytest.approx(own * math.log(1.02) + (1 - own) * math.log(1.002))
+
+    symbols, rows, _w, coverage, short = _aligned_returns(weights, prices, "proxy", industries)
+    assert coverage == 1.0
+    assert short["TINY"] == {"days": 10, "policy": "proxied", "proxy": "portfolio"}
+    tiny = [row[symbols.index("TINY")] for row in rows]
+    assert tiny[-1] == pytest.approx(math.log(1.03))
+    assert tiny[0] == pytest.approx((math.log(1.01) + math.log(1.002)) / 2)
+
+
+def test_simulation_bands_and_probability():
+    flat = simulate_projection(10_000, 100, 2, 0.0, 0.0, paths=100, target=12_400)
+    assert [b["year"] for b in flat["bands"]] == [0, 1, 2]
+    assert flat["bands"][0]["p50"] == 10_000
+    assert flat["bands"][2] == {"year": 2, "p10": 12_400, "p25": 12_400, "p50": 12_400, "p75": 12_400, "p90": 12_400}
+    assert flat["probability"] == 1.0
+
+    risky = simulate_projection(10_000, 0, 5, 0.005, 0.05, paths=500, target=13_000, seed=3)
+    last = risky["bands"][-1]
+    assert last["p10"] < last["p25"] < last["p50"] < last["p75"] < last["p90"]
+    assert 0.2 < risky["probability"] < 0.8
+    assert simulate_projection(10_000, 0, 5, 0.005, 0.05, paths=500, target=13_000, seed=3) == risky
+    assert simulate_projection(10_000, 0, 1, 0.0, 0.0, paths=100)["probability"] is None
+
+
+@pytest.mark.asyncio
+async def test_build_projection_uses_live_portfolio(monkeypatch):
+    import sentinel.portfolio
+
+    class FakePortfolio:
+        def __init__(self, **kwargs):
+            pass
+
+        async def total_value(self):
+            return 20_000.0
+
+    monkeypatch.setattr(sentinel.portfolio, "Portfolio", FakePortfolio)
+    db = MagicMock()
+    db.get_all_positions = AsyncMock(
+        return_value=[{"symbol": "A", "quantity": 100, "current_price": 100.0, "currency": "EUR"}]
+    )
+    db.get_prices = AsyncMock(return_value=_prices([0.0] * 120))
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, curr: amount)
+
+    result = await build_projection(db, currency, years=3, paths=100, target_eur=50_000, monthly_deposit_eur=500)
+
+    assert result["start_value_eur"] == 20_000.0
+    assert result["invested_pct"] == 50.0
+    assert result["coverage_pct"] == 100.0
+    assert result["bands"][-1]["p50"] == pytest.approx(38_000.0)
+    assert result["probability"] == 0.0
+
+
+@pytest.mark.asyncio
+async def test_endpoint_rejects_bad_params():
+    from sentinel.api.routers.projections import get_monte_carlo_projection
+
+    for kwargs in [{"years": 0}, {"years": 60}, {"paths": 10}, {"target_eur": -5}]:
+        params = {"years": 20, "paths": 2000, "target_eur": None, "monthly_deposit_eur": None, **kwargs}
+        with pytest.raises(HTTPException) as exc:
+            await get_monte_carlo_projection(MagicMock(), **params)
+        assert exc.value.status_code == 400
@@ -0,0 +1,305 @@
+"""Tests for report exports and ledger exports for other trackers."""
+
+import csv
+import io
+import json
+import os
+import re
+import tempfile
+from datetime import datetime, timezone
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.localization import LOCALES
+from sentinel.services.exports import LedgerExportService, yahoo_symbol
+from sentinel.services.reports import ReportService
+from sentinel.utils.pdf import text_pdf
+
+
+def _ts(year, month, day, hour=12):
+    return int(datetime(year, month, day, hour, tzinfo=timezone.utc).timestamp())
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _service(db, locale=None):
+    currency = MagicMock()
+    rates = {"EUR": 1.0, "USD": 0.9}
+    currency.get_rate_for_date = AsyncMock(side_effect=lambda c, d: rates[c])
+    currency.to_eur_for_date = AsyncMock(side_effect=lambda amount, c, d: amount * rates[c])
+    return ReportService(db=db, broker=MagicMock(), currency=currency, locale=locale)
+
+
+async def _seed_trades(db):
+    await db.upsert_security("AAPL.US", name="Apple", currency="USD")
+    await db.upsert_trade("T1", "AAPL.US", "BUY", 10, 150.0, _ts(2025, 12, 30), {}, commission=1.5)
+    await db.upsert_trade("T2", "AAPL.US", "BUY", 5, 200.0, _ts(2026, 3, 10), {}, commission=1.0)
+    await db.upsert_trade("T3", "EUR/USD", "BUY", 100, 1.1, _ts(2026, 3, 2), {})
+    await db.upsert_trade("T4", "AAPL.US", "SELL", 2, 210.0, _ts(2026, 4, 1), {})
+
+
+class TestPdf:
+    def test_xref_offsets_point_at_objects(self):
+        pdf = text_pdf(["Hello (world)", "Price 12.50 EUR"], title="Test")
+        assert pdf.startswith(b"%PDF-1.4")
+        assert pdf.rstrip().endswith(b"%%EOF")
+        assert b"(Hello \\(world\\)) Tj" in pdf
+
+        startxref = int(re.search(rb"startxref\n(\d+)", pdf).group(1))
+        entries = pdf[startxref:].split(b"\n")[3:]
+        for number, entry in enumerate(entries[:5], start=1):
+            offset = int(entry[:10])
+            assert pdf[offset:].startswith(f"{number} 0 obj".encode())
+
+    def test_paginates_long_reports(self):
+        pdf = text_pdf([f"line {i}" for i in range(150)])
+        assert b"/Count 3" in pdf
+
+
+@pytest.mark.asyncio
+async def test_trades_csv_covers_the_tax_year(temp_db):
+    await _seed_trades(temp_db)
+
+    rows = list(csv.DictReader(io.StringIO(await _service(temp_db).trades_csv(2026))))
+
+    assert [row["broker_trade_id"] for row in rows] == ["T3", "T2", "T4"]
+    fx, buy, sell = rows
+    assert fx["currency"] == "USD"
+    assert buy["date"] == "2026-03-10"
+    assert buy["currency"] == "USD"
+    assert float(buy["value_local"]) == 1000.0
+    assert float(buy["fx_rate"]) == 0.9
+    assert float(buy["value_eur"]) == 900.0
+    assert float(buy["commission"]) == 1.0
+    assert sell["side"] == "SELL"
+
+
+@pytest.mark.asyncio
+async def test_trades_csv_rejects_bad_year(temp_db):
+    with pytest.raises(ValueError, match="year"):
+        await _service(temp_db).trades_csv(12026)
+
+
+@pytest.mark.asyncio
+async def test_positions_csv_has_weights(temp_db):
+    valuation = {
+        "positions": [
+            {
+                "symbol": "SMALL.EU",
+                "name": "Small",
+                "quantity": 1,
+                "avg_cost": 50.0,
+                "current_price": 100.0,
+                "currency": "EUR",
+                "value_local": 100.0,
+                "value_eur": 100.0,
+                "invested_eur": 50.0,
+                "profit_pct": 100.0,
+            },
+            {
+                "symbol": "BIG.EU",
+                "name": "Big",
+                "quantity": 3,
+                "avg_cost": 100.0,
+                "current_price": 100.0,
+                "currency": "EUR",
+                "value_local": 300.0,
+                "value_eur": 300.0,
+                "invested_eur": 300.0,
+                "profit_pct": 0.0,
+            },
+        ],
+        "total_value_eur": 500.0,
+    }
+    with patch("sentinel.services.reports.PortfolioValuationService") as valuation_service:
+        valuation_service.return_value.current = AsyncMock(return_value=valuation)
+        rows = list(csv.DictReader(io.StringIO(await _service(temp_db).positions_csv())))
+
+    assert [row["symbol"] for row in rows] == ["BIG.EU", "SMALL.EU"]
+    assert float(rows[0]["weight_pct"]) == 60.0
+    assert float(rows[1]["weight_pct"]) == 20.0
+
+
+@pytest.mark.asyncio
+async def test_monthly_summary_splits_deposits_from_performance(temp_db):
+    await _seed_trades(temp_db)
+    await temp_db.save_valuation_snapshot(_ts(2026, 2, 28, 20), {"total_value_eur": 10000.0})
+    await temp_db.save_valuation_snapshot(_ts(2026, 3, 31, 20), {"total_value_eur": 11500.0})
+    await temp_db.save_valuation_snapshot(_ts(2026, 4, 1, 20), {"total_value_eur": 99999.0})
+    await temp_db.upsert_cash_flow("2026-03-05", "card", 1000.0, "EUR", None, {"id": 1})
+    await temp_db.upsert_cash_flow("2026-03-20", "card_payout", 200.0, "EUR", None, {"id": 2})
+    await temp_db.upsert_cash_flow("2026-04-02", "card", 5000.0, "EUR", None, {"id": 3})
+    await temp_db.upsert_dividend("D1", "AAPL.US", "2026-03-15", 10.0, "USD", 9.0, {})
+    await temp_db.upsert_dividend("D2", "AAPL.US", "2026-04-15", 10.0, "USD", 9.0, {})
+
+    summary = await _service(temp_db).monthly_summary(2026, 3, now_ts=_ts(2026, 10, 1))
+
+    assert summary["month"] == "2026-03"
+    assert summary["end_date"] == "2026-03-31"
+    assert summary["start_value_eur"] == 10000.0
+    assert summary["end_value_eur"] == 11500.0
+    assert summary["net_deposits_eur"] == 800.0
+    assert summary["performance_eur"] == 700.0
+    assert summary["performance_pct"] == pytest.approx(700 / 10800 * 100, abs=0.01)
+    assert summary["dividends"] == [{"symbol": "AAPL.US", "value_eur": 9.0}]
+    assert [t["broker_trade_id"] for t in summary["trades"]] == ["T3", "T2"]
+    assert summary["bought_eur"] == pytest.approx(900.0 + 99.0)
+
+
+@pytest.mark.asyncio
+async def test_monthly_summary_rejects_future_months(temp_db):
+    with pytest.raises(ValueError, match="not started"):
+        await _service(temp_db).monthly_summary(2026, 11, now_ts=_ts(2026, 10, 16))
+    with pytest.raises(ValueError, match="month"):
+        await _service(temp_db).monthly_summary(2026, 13, now_ts=_ts(2026, 10, 16))
+
+
+@pytest.mark.asyncio
+async def test_monthly_pdf(temp_db):
+    await _seed_trades(temp_db)
+    pdf = await _service(temp_db).monthly_pdf(2026, 3, now_ts=_ts(2026, 10, 1))
+    assert pdf.startswith(b"%PDF")
+    assert b"Sentinel monthly summary - 2026-03" in pdf
+    assert b"AAPL.US" in pdf
+
+
+@pytest.mark.asyncio
+async def test_monthly_pdf_follows_the_locale(temp_db):
+    await _seed_trades(temp_db)
+    pdf = await _service(temp_db, LOCALES["de"]).monthly_pdf(2026, 3, now_ts=_ts(2026, 10, 1))
+
+    assert "Sentinel Monatsübersicht - 03.2026".encode("cp1252") in pdf
+    assert b"01.03.2026 bis 31.03.2026" in pdf
+    assert b"KAUFEN" in pdf
+
+
+@pytest.mark.asyncio
+async def test_export_endpoint(temp_db):
+    from sentinel.api.routers.reports import export_report
+
+    await _seed_trades(temp_db)
+    deps = MagicMock()
+    deps.db = temp_db
+
+    with patch("sentinel.api.routers.reports.ReportService", side_effect=lambda **kw: _service(kw["db"])):
+        response = await export_report(deps, report="trades", year=2025)
+        assert response.media_type == "text/csv"
+        assert response.headers["content-disposition"] == 'attachment; filename="trades-2025.csv"'
+        assert b"T1" in response.body
+
+        with pytest.raises(HTTPException) as exc:
+            await export_report(deps, report="tax")
+        assert exc.value.status_code == 400
+
+        with pytest.raises(HTTPException) as exc:
+            await export_report(deps, report="monthly", year=2026, month=0)
+        assert exc.value.status_code == 400
+
+
+def _exporter(db):
+    service = _service(db)
+    return LedgerExportService(db=db, currency=service._currency)
+
+
+async def _seed_ledger(db):
+    await _seed_trades(db)
+    await db.upsert_security("VWCE.EU", name="Vanguard FTSE All-World", currency="EUR")
+    await db.upsert_trade(
+        "T5", "VWCE.EU", "BUY", 4, 100.0, _ts(2026, 3, 5), {}, commission=0.9, commission_currency="USD"
+    )
+    await db.upsert_dividend("D1", "AAPL.US", "2026-03-15", 10.0, "USD", 9.0, {})
+    await db.upsert_cash_flow("2026-03-05", "card", 1000.0, "EUR", "Top-up", {"id": 1})
+    await db.upsert_cash_flow("2026-03-20", "card_payout", -200.0, "EUR", None, {"id": 2})
+    await db.upsert_cash_flow("2026-03-15", "dividend", 10.0, "USD", None, {"id": 3})
+
+
+def test_yahoo_symbol():
+    assert yahoo_symbol("AAPL.US") == "AAPL"
+    assert yahoo_symbol("VWCE.EU") is None
+
+
+@pytest.mark.asyncio
+async def test_ghostfolio_export(temp_db):
+    await _seed_ledger(temp_db)
+
+    activities = (await _exporter(temp_db).ghostfolio())["activities"]
+
+    assert [(a["type"], a["symbol"]) for a in activities] == [
+        ("BUY", "AAPL"),
+        ("BUY", "VWCE.EU"),
+        ("BUY", "AAPL"),
+        ("DIVIDEND", "AAPL"),
+        ("SELL", "AAPL"),
+    ]
+    vwce = activities[1]
+    assert vwce["dataSource"] == "MANUAL"
+    assert vwce["date"] == "2026-03-05T00:00:00.000Z"
+    assert vwce["fee"] == pytest.approx(0.81)
+    dividend = activities[3]
+    assert (dividend["quantity"], dividend["unitPrice"], dividend["currency"]) == (1.0, 10.0, "USD")
+
+
+@pytest.mark.asyncio
+async def test_portfolio_performance_export(temp_db):
+    await _seed_ledger(temp_db)
+
+    rows = list(csv.DictReader(io.StringIO(await _exporter(temp_db).portfolio_performance_csv())))
+    march = [row for row in rows if row["Date"].startswith("2026-03")]
+
+    assert [(row["Date"], row["Type"]) for row in march] == [
+        ("2026-03-05", "Deposit"),
+        ("2026-03-05", "Buy"),
+        ("2026-03-10", "Buy"),
+        ("2026-03-15", "Dividend"),
+        ("2026-03-20", "Removal"),
+    ]
+    deposit, vwce, aapl, dividend, removal = march
+    assert deposit["Value"] == "1000.0"
+    assert deposit["Shares"] == ""
+    assert vwce["Value"] == "400.81"
+    assert aapl["Ticker Symbol"] == "AAPL"
+    assert aapl["Transaction Currency"] == "USD"
+    # 1 EUR commission booked in USD at 0.9 EUR per USD.
+    assert aapl["Fees"] == "1.11"
+    assert aapl["Value"] == "1001.11"
+    assert dividend["Value"] == "10.0"
+    assert removal["Value"] == "200.0"
+    assert rows[-1]["Type"] == "Sell"
+    assert rows[-1]["Value"] == "420.0"
+
+
+@pytest.mark.asyncio
+async def test_export_endpoint_ledger_formats(temp_db):
+    from sentinel.api.routers.reports import export_report
+
+    await _seed_ledger(temp_db)
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.currency = _service(temp_db)._currency
+
+    response = await export_report(deps, report="ghostfolio")
+    assert response.media_type == "application/json"
+    assert len(json.loads(response.body)["activities"]) == 5
+
+    response = await export_report(deps, report="portfolio-performance")
+    assert response.headers["content-disposition"].startswith('attachment; filename="portfolio-performance-')
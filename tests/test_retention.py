@@ -0,0 +1,194 @@
+"""Tests for data retention policies."""
+
+import os
+import tempfile
+from pathlib import Path
+
+import pytest
+import pytest_asyncio
+
+from sentinel import archive
+from sentinel.database import Database
+from sentinel.retention import (
+    DEFAULT_POLICIES,
+    RETENTION_POLICIES_KEY,
+    effective_policies,
+    retention_overview,
+    run_retention,
+    validate_retention_policies,
+)
+
+NOW = 1_800_000_000
+DAY = 86400
+
+
+@pytest_asyncio.fixture
+async def temp_db(tmp_path, monkeypatch):
+    monkeypatch.setattr(archive, "ARCHIVE_DIR", tmp_path / "archive")
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+async def _add_job_runs(db, ages_days: list[int]) -> None:
+    for i, age in enumerate(ages_days):
+        await db.conn.execute(
+            "INSERT INTO job_history (job_id, job_type, status, duration_ms, executed_at) VALUES (?, ?, ?, ?, ?)",
+            (f"job-{i}", "sync:prices", "completed", 10, NOW - age * DAY),
+        )
+    await db.conn.commit()
+
+
+async def _count(db, table: str) -> int:
+    cursor = await db.conn.execute(f"SELECT COUNT(*) FROM {table}")  # noqa: S608
+    return (await cursor.fetchone())[0]
+
+
+def test_validate_policies():
+    assert validate_retention_policies({"job_history": {"keep_days": 30, "action": "archive"}}) == {
+        "job_history": {"keep_days": 30, "action": "archive"}
+    }
+    assert validate_retention_policies({"planner_snapshots": {"keep_rows": None}}) == {
+        "planner_snapshots": {"keep_rows": None}
+    }
+    for bad in [
+        None,
+        {"trades": {"keep_days": 30}},
+        {"job_history": {"keep_days": 0}},
+        {"job_history": {"keep_days": 1.5}},
+        {"job_history": {"keep_days": True}},
+        {"job_history": {"action": "truncate"}},
+        {"job_history": {"keep": 3}},
+        {"cash_flows": {"action": "delete"}},
+        {"prices": {"keep_rows": 100}},
+    ]:
+        with pytest.raises(ValueError):
+            validate_retention_policies(bad)
+
+
+def test_effective_policies_override_per_field():
+    policies = effective_policies({"job_history": {"keep_rows": 100}})
+    assert policies["job_history"] == {**DEFAULT_POLICIES["job_history"], "keep_rows": 100}
+    assert policies["planner_snapshots"] == DEFAULT_POLICIES["planner_snapshots"]
+
+
+@pytest.mark.asyncio
+async def test_keep_days_deletes_old_rows(temp_db):
+    await temp_db.set_setting(RETENTION_POLICIES_KEY, {"job_history": {"action": "delete"}})
+    await _add_job_runs(temp_db, [1, 30, 120, 400])
+
+    result = await run_retention(temp_db, now=NOW)
+
+    assert result["tables"]["job_history"]["rows"] == 2
+    assert await _count(temp_db, "job_history") == 2
+
+
+@pytest.mark.asyncio
+async def test_keep_rows_keeps_newest(temp_db):
+    await temp_db.set_setting(
+        RETENTION_POLICIES_KEY, {"job_history": {"keep_days": None, "keep_rows": 2, "action": "delete"}}
+    )
+    await _add_job_runs(temp_db, [1, 2, 3, 4, 5])
+
+    await run_retention(temp_db, now=NOW)
+
+    cursor = await temp_db.conn.execute("SELECT executed_at FROM job_history ORDER BY executed_at DESC")
+    assert [row[0] for row in await cursor.fetchall()] == [NOW - DAY, NOW - 2 * DAY]
+
+
+@pytest.mark.asyncio
+async def test_archive_writes_file_before_delete(temp_db):
+    await _add_job_runs(temp_db, [1, 200])
+
+    result = await run_retention(temp_db, now=NOW)
+
+    assert result["archived"] == 1
+    assert await _count(temp_db, "job_history") == 1
+    columns, rows = archive.read_archive(Path(result["tables"]["job_history"]["file"]))
+    assert rows[0][columns.index("executed_at")] == str(NOW - 200 * DAY)
+
+
+@pytest.mark.asyncio
+async def test_archive_keeps_rows_when_file_fails(temp_db, monkeypatch):
+    def fail(*args, **kwargs):
+        raise OSError("disk full")
+
+    monkeypatch.setattr("sentinel.retention.write_archive", fail)
+    await _add_job_runs(temp_db, [200])
+
+    result = await run_retention(temp_db, now=NOW)
+
+    assert result["tables"]["job_history"]["error"] == "disk full"
+    assert await _count(temp_db, "job_history") == 1
+
+
+@pytest.mark.asyncio
+async def test_export_copies_each_row_once(temp_db):
+    await temp_db.set_setting(RETENTION_POLICIES_KEY, {"cash_flows": {"keep_days": 30}})
+    for i, date in enumerate(["2026-01-01", "2026-07-01"]):
+        await temp_db.conn.execute(
+            """INSERT INTO cash_flows (content_hash, date, type_id, amount, currency, raw_data)
+               VALUES (?, ?, 'card', 100, 'EUR', '{}')""",
+            (f"hash-{i}", date),
+        )
+    await temp_db.conn.commit()
+    now = 1_785_000_000  # 2026-07-25
+
+    first = await run_retention(temp_db, now=now)
+    second = await run_retention(temp_db, now=now + 30 * DAY)
+    third = await run_retention(temp_db, now=now + 31 * DAY)
+
+    assert first["tables"]["cash_flows"]["archived"] == 1
+    assert second["tables"]["cash_flows"]["archived"] == 1
+    assert third["tables"]["cash_flows"]["archived"] == 0
+    assert await _count(temp_db, "cash_flows") == 2
+
+
+@pytest.mark.asyncio
+async def test_condition_spares_pending_quarantine(temp_db):
+    old = NOW - 400 * DAY
+    for status, resolved in [("pending", None), ("rejected", old)]:
+        await temp_db.conn.execute(
+            """INSERT INTO price_quarantine (symbol, kind, reason, payload, status, created_at, resolved_at)
+               VALUES ('AAPL.US', 'quote', 'spike', '{}', ?, ?, ?)""",
+            (status, old, resolved),
+        )
+    await temp_db.conn.commit()
+
+    await run_retention(temp_db, now=NOW)
+
+    cursor = await temp_db.conn.execute("SELECT status FROM price_quarantine")
+    assert [row[0] for row in await cursor.fetchall()] == ["pending"]
+
+
+@pytest.mark.asyncio
+async def test_overview_reports_last_run(temp_db):
+    assert (await retention_overview(temp_db))["last_run"] is None
+    await temp_db.set_setting(RETENTION_POLICIES_KEY, {"job_history": {"action": "delete"}})
+    await _add_job_runs(temp_db, [200])
+
+    await run_retention(temp_db, now=NOW)
+    overview = await retention_overview(temp_db)
+
+    assert overview["last_run"]["rows"] == 1
+    assert {p["table"] for p in overview["policies"]} == set(DEFAULT_POLICIES)
+
+
+@pytest.mark.asyncio
+async def test_malformed_setting_falls_back_to_defaults(temp_db):
+    await temp_db.set_setting(RETENTION_POLICIES_KEY, {"job_history": {"keep_days": -1}})
+
+    overview = await retention_overview(temp_db)
+
+    assert next(p for p in overview["policies"] if p["table"] == "job_history")["keep_days"] == 90
@@ -0,0 +1,206 @@
+"""Tests for savings plans: deposit matching and new-money allocation."""
+
+import os
+import tempfile
+from dataclasses import replace
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.savings import (
+    NEW_MONEY_REASON_CODE,
+    allocate_new_money,
+    book_new_money_fill,
+    match_period,
+    process_savings_deposits,
+    validate_savings_plan,
+)
+
+PLAN = {
+    "id": 1,
+    "name": "Monthly",
+    "amount": 500.0,
+    "currency": "EUR",
+    "day_of_month": 1,
+    "tolerance_pct": 10.0,
+    "window_days": 5,
+    "start_date": "2026-01-01",
+}
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _candidate(symbol: str, value: float, quantity: int, lot_size: int = 1) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.2,
+        allocation_delta=0.2,
+        current_value_eur=0.0,
+        target_value_eur=value,
+        value_delta_eur=value,
+        quantity=quantity,
+        price=value / quantity,
+        currency="EUR",
+        lot_size=lot_size,
+        contrarian_score=0.2,
+        priority=1.0,
+        reason="test",
+        timing_eligible=False,
+        target_gap_ratio=1.0,
+    )
+
+
+def _flow(amount: float, on: str, currency: str = "EUR", type_id: str = "card") -> dict:
+    return {"type_id": type_id, "amount": amount, "currency": currency, "date": on}
+
+
+class TestMatching:
+    def test_matches_amount_and_date_window_across_month_edge(self):
+        assert match_period(PLAN, _flow(500.0, "2026-03-02")) == "2026-03"
+        assert match_period(PLAN, _flow(470.0, "2026-02-27 10:15:00")) == "2026-03"
+
+    def test_rejects_other_amounts_dates_currencies_and_types(self):
+        assert match_period(PLAN, _flow(400.0, "2026-03-01")) is None
+        assert match_period(PLAN, _flow(500.0, "2026-03-15")) is None
+        assert match_period(PLAN, _flow(500.0, "2026-03-01", currency="USD")) is None
+        assert match_period(PLAN, _flow(500.0, "2026-03-01", type_id="dividend")) is None
+        assert match_period(PLAN, _flow(500.0, "2025-12-01")) is None
+
+    def test_validate_savings_plan(self):
+        plan = validate_savings_plan({"name": " Monthly ", "amount": 500, "day_of_month": 1})
+        assert plan["name"] == "Monthly"
+        assert plan["start_date"] == date.today().isoformat()
+
+        assert validate_savings_plan({"active": False}, partial=True) == {"active": 0}
+        for bad in ({"name": "x", "amount": 0, "day_of_month": 1}, {"name": "x", "amount": 5, "day_of_month": 31}):
+            with pytest.raises(ValueError):
+                validate_savings_plan(bad)
+        with pytest.raises(ValueError):
+            validate_savings_plan({"start_date": "01/02/2026"}, partial=True)
+
+
+@pytest.mark.asyncio
+async def test_process_matches_one_deposit_per_plan_and_month(temp_db):
+    plan_id = await temp_db.create_savings_plan(**validate_savings_plan(PLAN))
+    for on, amount in [("2026-03-01", 500.0), ("2026-03-02", 500.0), ("2026-03-10", 500.0), ("2026-04-01", 505.0)]:
+        await temp_db.upsert_cash_flow(on, "card", amount, "EUR", None, {"date": on, "amount": amount})
+
+    currency = MagicMock()
+    currency.to_eur_for_date = AsyncMock(side_effect=lambda amount, currency, date: amount)
+
+    assert await process_savings_deposits(temp_db, currency, today=date(2026, 4, 3)) == 2
+    assert await process_savings_deposits(temp_db, currency, today=date(2026, 4, 3)) == 0
+
+    deposits = await temp_db.get_savings_deposits(plan_id=plan_id)
+    assert [(d["period"], d["date"]) for d in deposits] == [("2026-04", "2026-04-01"), ("2026-03", "2026-03-01")]
+
+
+@pytest.mark.asyncio
+async def test_new_money_is_booked_oldest_first_at_the_fill(temp_db):
+    plan_id = await temp_db.create_savings_plan(**validate_savings_plan(PLAN))
+    today = date.today().isoformat()
+    await temp_db.record_savings_deposit(1, plan_id, "2026-09", today, 500.0)
+    await temp_db.record_savings_deposit(2, plan_id, "2026-10", today, 500.0)
+    assert await temp_db.get_savings_new_money_eur(today) == 1000.0
+
+    rec = _candidate("A", 700.0, 7)
+    assert await book_new_money_fill(temp_db, rec, 7, 100.0, 30) == 0.0
+    assert await temp_db.get_savings_new_money_eur(today) == 1000.0
+
+    rec = replace(rec, reason_code=NEW_MONEY_REASON_CODE)
+    # A cancelled or rejected order has no fill and books nothing.
+    assert await book_new_money_fill(temp_db, rec, 0, 100.0, 30) == 0.0
+    # A partial fill books what was bought, at the fill price.
+    assert await book_new_money_fill(temp_db, rec, 6, 95.0, 30) == 570.0
+    deposits = {d["cash_flow_id"]: d["invested_eur"] for d in await temp_db.get_savings_deposits()}
+    assert deposits == {1: 500.0, 2: 70.0}
+    assert await temp_db.get_savings_new_money_eur(today) == 430.0
+
+
+class TestNewMoneyAllocation:
+    def test_spreads_new_money_over_gaps_in_order(self):
+        allocated = allocate_new_money(
+            [_candidate("A", 300.0, 3), _candidate("B", 1000.0, 10), _candidate("C", 1000.0, 1)],
+            new_money_eur=550.0,
+            min_trade_value=100.0,
+        )
+
+        assert [(r.symbol, r.quantity, r.value_delta_eur) for r in allocated] == [("A", 3, 300.0), ("B", 2, 200.0)]
+        assert all(r.reason_code == NEW_MONEY_REASON_CODE for r in allocated)
+
+    def test_nothing_below_min_trade_value(self):
+        assert allocate_new_money([_candidate("A", 1000.0, 10)], new_money_eur=90.0, min_trade_value=100.0) == []
+
+    @pytest.mark.asyncio
+    async def test_planner_invests_new_money_without_waiting_for_fallback(self):
+        engine = RebalanceEngine(db=MagicMock())
+        engine._apply_cash_constraint = AsyncMock(side_effect=lambda recs, *_args, **_kwargs: recs)
+        engine._fallback_is_due = AsyncMock(return_value=False)
+
+        selected = await engine._select_executable_plan(
+            [_candidate("A", 1000.0, 10)],
+            min_trade_value=100.0,
+            fallback_wait_days=30,
+            as_of_date=None,
+            track_fallback_state=False,
+            cash_context={},
+            new_money_eur=500.0,
+        )
+
+        assert [(r.symbol, r.quantity, r.reason_code) for r in selected] == [("A", 5, NEW_MONEY_REASON_CODE)]
+        engine._fallback_is_due.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_savings_plan_endpoints(temp_db):
+    from sentinel.api.routers.trading import (
+        create_savings_plan,
+        delete_savings_plan,
+        get_savings_plans,
+        update_savings_plan,
+    )
+
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.settings = MagicMock()
+    deps.settings.get = AsyncMock(return_value=30)
+
+    with pytest.raises(HTTPException) as exc:
+        await create_savings_plan({"name": "Monthly", "amount": -1, "day_of_month": 1}, deps)
+    assert exc.value.status_code == 400
+
+    created = await create_savings_plan({"name": "Monthly", "amount": 500, "day_of_month": 1}, deps)
+    await update_savings_plan(created["id"], {"active": False}, deps)
+
+    listed = await get_savings_plans(deps)
+    assert listed["plans"][0]["active"] == 0
+    assert listed["new_money_eur"] == 0.0
+
+    await delete_savings_plan(created["id"], deps)
+    with pytest.raises(HTTPException) as exc:
+        await update_savings_plan(created["id"], {"amount": 600}, deps)
+    assert exc.value.status_code == 404
@@ -0,0 +1,121 @@
+"""Tests for importing CSV statements into the ledger."""
+
+import os
+import tempfile
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.statement_import import BUILTIN_TEMPLATES, StatementImporter, validate_template, validate_templates
+
+TRADES = """date,symbol,side,quantity,price,commission
+2018-03-01,ACME.EU,buy,10,12.5,1
+2018-03-01,ACME.EU,buy,10,12.5,1
+2018-06-01,ACME.EU,sell,-5,15,1
+2018-06-02,ACME.EU,hold,5,15,1
+2018-06-03,OLDCO.US,buy,2,abc,0
+"""
+
+GERMAN = validate_template(
+    {
+        "kind": "trades",
+        "delimiter": ";",
+        "decimal": ",",
+        "date_format": "%d.%m.%Y",
+        "columns": {"date": "Datum", "symbol": "Ticker", "side": "Typ", "quantity": "Stück", "price": "Kurs"},
+        "sides": {"BUY": ["Kauf"], "SELL": ["Verkauf"]},
+        "defaults": {"currency": "EUR"},
+    }
+)
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+    await db.upsert_security("ACME.EU", name="Acme", currency="EUR")
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ("", "-wal", "-shm"):
+        target = path + ext
+        if os.path.exists(target):
+            os.unlink(target)
+
+
+def _builtin(name):
+    return validate_template(BUILTIN_TEMPLATES[name])
+
+
+def test_template_validation():
+    with pytest.raises(ValueError, match="kind"):
+        validate_template({"kind": "orders", "columns": {}})
+    with pytest.raises(ValueError, match="required fields: price"):
+        validate_template({"kind": "trades", "columns": {"date": "d", "symbol": "s", "side": "t", "quantity": "q"}})
+    with pytest.raises(ValueError, match="unknown trades fields: isin"):
+        validate_template({"kind": "trades", "columns": {"isin": "ISIN"}})
+    with pytest.raises(ValueError, match="built-in"):
+        validate_templates({"sentinel_trades": {"kind": "trades"}})
+    assert GERMAN["sides"] == {"BUY": ["kauf"], "SELL": ["verkauf"]}
+
+
+@pytest.mark.asyncio
+async def test_preview_reports_errors_without_writing(temp_db):
+    preview = await StatementImporter(temp_db).preview(TRADES, _builtin("sentinel_trades"), "old")
+
+    assert preview["valid"] == 3
+    assert preview["invalid"] == 2
+    assert preview["new"] == 3
+    assert [e["line"] for e in preview["errors"]] == [5, 6]
+    assert "neither BUY nor SELL" in preview["errors"][0]["error"]
+    assert preview["sample"][2]["quantity"] == 5
+    assert await temp_db.get_trades_count() == 0
+
+
+@pytest.mark.asyncio
+async def test_import_is_idempotent(temp_db):
+    importer = StatementImporter(temp_db)
+    first = await importer.import_statement(TRADES, _builtin("sentinel_trades"), "old")
+    second = await importer.import_statement(TRADES, _builtin("sentinel_trades"), "old")
+
+    assert first["imported"] == 3
+    assert second["imported"] == 0
+    assert second["existing"] == 3
+    assert await temp_db.get_trades_count() == 3
+
+
+@pytest.mark.asyncio
+async def test_custom_template_parses_locale_numbers_and_dates(temp_db):
+    text = "Datum;Ticker;Typ;Stück;Kurs\n01.03.2018;ACME.EU;Kauf;1.000;12,50\n"
+
+    preview = await StatementImporter(temp_db).preview(text, GERMAN, "bank")
+
+    row = preview["sample"][0]
+    assert row["quantity"] == 1000
+    assert row["price"] == 12.5
+    assert row["date"].startswith("2018-03-01")
+    assert row["currency"] == "EUR"
+
+
+@pytest.mark.asyncio
+async def test_missing_required_column_is_rejected(temp_db):
+    with pytest.raises(ValueError, match="'price'"):
+        await StatementImporter(temp_db).preview("date,symbol,side,quantity\n", _builtin("sentinel_trades"), "old")
+
+
+@pytest.mark.asyncio
+async def test_cash_flows_import_once(temp_db):
+    text = "date,type,amount,currency,comment\n2017-01-05,card,1000,EUR,Deposit\n2017-02-05,card,1000,EUR,Deposit\n"
+    importer = StatementImporter(temp_db)
+
+    first = await importer.import_statement(text, _builtin("sentinel_cash_flows"), "bank")
+    second = await importer.preview(text, _builtin("sentinel_cash_flows"), "bank")
+
+    assert first["imported"] == 2
+    assert second["existing"] == 2
+    assert len(await temp_db.get_cash_flows()) == 2
@@ -0,0 +1,95 @@
+"""Tests for sd_notify, the systemd watchdog and the restart endpoint."""
+
+import asyncio
+import os
+import socket
+from unittest.mock import patch
+
+import pytest
+from fastapi import HTTPException
+
+from sentinel import systemd
+
+
+@pytest.fixture
+def notify_socket(tmp_path, monkeypatch):
+    path = tmp_path / "notify"
+    sock = socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM)
+    sock.bind(str(path))
+    sock.settimeout(1)
+    monkeypatch.setenv("NOTIFY_SOCKET", str(path))
+    yield sock
+    sock.close()
+
+
+def test_notify_sends_states(notify_socket):
+    assert systemd.supervised() is True
+    assert systemd.notify("READY=1", "STATUS=Serving") is True
+    assert notify_socket.recv(1024) == b"READY=1\nSTATUS=Serving"
+
+
+def test_notify_is_a_noop_outside_systemd(monkeypatch):
+    monkeypatch.delenv("NOTIFY_SOCKET", raising=False)
+    assert systemd.supervised() is False
+    assert systemd.notify("READY=1") is False
+
+
+def test_watchdog_interval(monkeypatch):
+    monkeypatch.delenv("WATCHDOG_USEC", raising=False)
+    monkeypatch.delenv("WATCHDOG_PID", raising=False)
+    assert systemd.watchdog_interval() is None
+
+    monkeypatch.setenv("WATCHDOG_USEC", "600000000")
+    assert systemd.watchdog_interval() == 200.0
+
+    # The watchdog belongs to another process (e.g. a forked child).
+    monkeypatch.setenv("WATCHDOG_PID", str(os.getpid() + 1))
+    assert systemd.watchdog_interval() is None
+
+
+@pytest.mark.asyncio
+async def test_watchdog_pings_only_while_healthy(notify_socket):
+    healthy = [True]
+    watchdog = systemd.Watchdog(healthy=lambda: healthy[0], interval=0.01)
+    watchdog.start()
+    await asyncio.sleep(0.05)
+    healthy[0] = False
+    await asyncio.sleep(0.02)
+    await watchdog.stop()
+
+    notify_socket.setblocking(False)
+    pings = []
+    try:
+        while True:
+            pings.append(notify_socket.recv(1024))
+    except BlockingIOError:
+        pass
+    assert pings
+    assert set(pings) == {b"WATCHDOG=1"}
+
+
+@pytest.mark.asyncio
+async def test_watchdog_disabled_without_interval(monkeypatch):
+    monkeypatch.delenv("WATCHDOG_USEC", raising=False)
+    watchdog = systemd.Watchdog(healthy=lambda: True)
+    assert watchdog.enabled is False
+    watchdog.start()
+    await watchdog.stop()
+
+
+@pytest.mark.asyncio
+async def test_restart_endpoint():
+    from sentinel.api.routers.system import restart
+
+    with patch("sentinel.api.routers.system.current_job", return_value="trading:execute"):
+        with pytest.raises(HTTPException) as exc:
+            await restart()
+        assert exc.value.status_code == 409
+
+    with (
+        patch("sentinel.api.routers.system.current_job", return_value=None),
+        patch("sentinel.api.routers.system.request_restart") as request_restart,
+    ):
+        result = await restart()
+    request_restart.assert_called_once()
+    assert result["exit_code"] == systemd.RESTART_EXIT_CODE
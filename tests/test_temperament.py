@@ -0,0 +1,108 @@
+"""Tests for the temperament questionnaire and derived settings."""
+
+import os
+import tempfile
+from unittest.mock import MagicMock
+
+import pytest
+import pytest_asyncio
+from fastapi import HTTPException
+
+from sentinel.database import Database
+from sentinel.settings import DEFAULTS
+from sentinel.temperament import QUESTIONS, derive_settings, diff_profiles, temperament_scores, validate_answers
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+
+    db = Database(path)
+    await db.connect()
+
+    yield db
+
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        p = path + ext
+        if os.path.exists(p):
+            os.unlink(p)
+
+
+def _answers(index):
+    return {q["id"]: q["options"][index]["id"] for q in QUESTIONS}
+
+
+def test_validation():
+    assert validate_answers(_answers(1)) == _answers(1)
+    for data in [None, {}, {**_answers(1), "horizon": "forever"}, {**_answers(1), "mood": "calm"}]:
+        with pytest.raises(ValueError):
+            validate_answers(data)
+
+
+def test_middle_answers_give_the_defaults():
+    scores = temperament_scores(_answers(1))
+    assert scores == {"risk_tolerance": 0.5, "aggression": 0.5}
+    for row in derive_settings(scores):
+        assert row["value"] == pytest.approx(DEFAULTS[row["key"]]), row["key"]
+        assert row["explanation"]
+
+
+def test_extremes():
+    cautious = {r["key"]: r["value"] for r in derive_settings(temperament_scores(_answers(0)))}
+    bold = {r["key"]: r["value"] for r in derive_settings(temperament_scores(_answers(2)))}
+
+    assert (cautious["max_position_pct"], cautious["target_cash_pct"]) == (15, 10.0)
+    assert (bold["max_position_pct"], bold["target_cash_pct"]) == (35, 0.0)
+    assert cautious["strategy_entry_t3_dd"] < cautious["strategy_entry_t2_dd"] < cautious["strategy_entry_t1_dd"]
+    assert bold["strategy_entry_t1_dd"] == pytest.approx(-0.06)
+    assert bold["strategy_entry_t3_dd"] >= -0.9
+    assert cautious["strategy_fallback_wait_days"] == 50
+    assert bold["strategy_max_opportunity_buys_per_cycle"] == 3
+
+
+def test_diff_lists_only_changes():
+    old = {"version": 1, "answers": {"a": "x", "b": "y"}, "scores": {"r": 0.5}, "settings": {"k": 1}}
+    new = {"version": 2, "answers": {"a": "x", "b": "z"}, "scores": {"r": 0.5}, "settings": {"k": 2}}
+
+    diff = diff_profiles(old, new)
+
+    assert diff["answers"] == [{"key": "b", "from": "y", "to": "z"}]
+    assert diff["scores"] == []
+    assert diff["settings"] == [{"key": "k", "from": 1, "to": 2}]
+    assert diff_profiles(None, new)["from_version"] is None
+
+
+@pytest.mark.asyncio
+async def test_retake_and_apply(temp_db):
+    from sentinel.api.routers.temperament import apply_version, get_diff, get_temperament, get_versions, submit_answers
+
+    deps = MagicMock()
+    deps.db = temp_db
+
+    with pytest.raises(HTTPException) as exc:
+        await get_temperament(deps)
+    assert exc.value.status_code == 404
+    with pytest.raises(HTTPException) as exc:
+        await submit_answers({"answers": {"horizon": "long"}}, deps)
+    assert exc.value.status_code == 400
+
+    first = await submit_answers({"answers": _answers(1)}, deps)
+    assert first["diff"]["from_version"] is None
+    assert first["applied_at"] is None
+    assert await temp_db.get_setting("max_position_pct") is None
+
+    second = await submit_answers({"answers": {**_answers(1), "horizon": "long"}, "apply": True}, deps)
+    assert second["diff"]["answers"] == [{"key": "horizon", "from": "medium", "to": "long"}]
+    assert second["applied_at"] is not None
+    assert await temp_db.get_setting("max_position_pct") == 28
+
+    assert [v["version"] for v in (await get_versions(deps))["versions"]] == [second["version"], first["version"]]
+    assert (await get_temperament(deps))["version"] == second["version"]
+    diff = await get_diff(deps, from_version=first["version"], to_version=None)
+    assert diff["settings"] == [{"key": "max_position_pct", "from": 25, "to": 28}]
+
+    await apply_version(first["version"], deps)
+    assert await temp_db.get_setting("max_position_pct") == 25
@@ -0,0 +1,127 @@
+"""Tests for the global trading pause (kill switch)."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from fastapi import HTTPException
+
+from sentinel.api.routers.trading import _place_order
+from sentinel.broker import Broker
+from sentinel.jobs import runner
+from sentinel.trading_pause import PAUSE_KEY, TradingPause, describe
+
+NOW = 1_800_000_000
+
+
+class FakeSettings:
+    def __init__(self, values: dict | None = None):
+        self.values = dict(values or {})
+
+    async def get(self, key, default=None):
+        return self.values.get(key, default)
+
+    async def set(self, key, value):
+        self.values[key] = value
+
+
+@pytest.fixture(autouse=True)
+def clear_broker_singleton():
+    if hasattr(Broker, "_clear"):
+        Broker._clear()  # type: ignore[attr-defined]
+    yield
+    if hasattr(Broker, "_clear"):
+        Broker._clear()  # type: ignore[attr-defined]
+
+
+class TestTradingPause:
+    @pytest.mark.asyncio
+    async def test_not_paused_by_default(self):
+        status = await TradingPause(FakeSettings()).status(now_ts=NOW)
+        assert status == {"paused": False, "paused_at_ts": None, "expires_at_ts": None, "reason": None}
+
+    @pytest.mark.asyncio
+    async def test_pause_expires_after_hours(self):
+        pause = TradingPause(FakeSettings())
+        await pause.pause(hours=24, reason="  Earnings week  ", now_ts=NOW)
+
+        status = await pause.status(now_ts=NOW + 3600)
+        assert status["paused"] is True
+        assert status["expires_at_ts"] == NOW + 24 * 3600
+        assert status["reason"] == "Earnings week"
+        assert await pause.is_paused(now_ts=NOW + 24 * 3600) is False
+
+    @pytest.mark.asyncio
+    async def test_pause_without_hours_lasts_until_resumed(self):
+        settings = FakeSettings()
+        pause = TradingPause(settings)
+        await pause.pause(hours=None, now_ts=NOW)
+
+        assert await pause.is_paused(now_ts=NOW + 365 * 86400) is True
+        assert describe(await pause.status(now_ts=NOW)) == "Trading is paused until resumed"
+
+        await pause.resume()
+        assert settings.values[PAUSE_KEY] is None
+        assert await pause.is_paused(now_ts=NOW) is False
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize("hours", [0, -1, 721, "24", True, float("nan")])
+    async def test_rejects_invalid_hours(self, hours):
+        settings = FakeSettings()
+        with pytest.raises(ValueError):
+            await TradingPause(settings).pause(hours=hours, now_ts=NOW)
+        assert PAUSE_KEY not in settings.values
+
+    @pytest.mark.asyncio
+    async def test_malformed_stored_value_is_not_paused(self):
+        settings = FakeSettings({PAUSE_KEY: {"paused": True, "expires_at_ts": "soon"}})
+        assert await TradingPause(settings).is_paused(now_ts=NOW) is False
+
+
+class TestEnforcement:
+    @pytest.mark.asyncio
+    async def test_broker_refuses_orders_while_paused(self):
+        broker = Broker()
+        broker._settings = FakeSettings({"trading_mode": "live"})
+        broker._trading = MagicMock()
+        await TradingPause(broker._settings).pause(hours=None)
+
+        assert await broker.buy("AAPL.US", 1) is None
+        assert await broker.sell("AAPL.US", 1) is None
+        broker._trading.buy.assert_not_called()
+        broker._trading.sell.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_broker_simulates_in_research_mode_when_not_paused(self):
+        broker = Broker()
+        broker._settings = FakeSettings({"trading_mode": "research"})
+
+        assert await broker.buy("AAPL.US", 2) == "RESEARCH-BUY-AAPL.US-2"
+
+    @pytest.mark.asyncio
+    async def test_order_endpoint_returns_409_while_paused(self):
+        deps = MagicMock()
+        deps.settings = FakeSettings()
+        await TradingPause(deps.settings).pause(hours=24)
+
+        with patch("sentinel.api.routers.trading.Security") as security_cls:
+            with pytest.raises(HTTPException) as exc:
+                await _place_order(deps, "AAPL.US", "buy", 1)
+
+        assert exc.value.status_code == 409
+        security_cls.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_scheduler_skips_trading_jobs_while_paused(self):
+        task = AsyncMock()
+        with (
+            patch.dict(runner.TASK_REGISTRY, {"trading:execute": (task, []), "sync:prices": (task, [])}),
+            patch.object(runner, "_deps", {}),
+            patch.object(runner, "TradingPause") as pause_cls,
+        ):
+            pause_cls.return_value.is_paused = AsyncMock(return_value=True)
+            skipped = await runner._run_task("trading:execute", {"market_timing": 0}, skip_timing_check=True)
+            synced = await runner._run_task("sync:prices", {"market_timing": 0}, skip_timing_check=True)
+
+        assert skipped == {"skipped": True, "reason": "trading_paused"}
+        assert synced["status"] == "completed"
+        task.assert_awaited_once()
@@ -2,15 +2,17 @@
 
 import os
 import tempfile
-from unittest.mock import AsyncMock
+from unittest.mock import AsyncMock, MagicMock
 
 import pytest
 import pytest_asyncio
 
 from sentinel.database import Database
 from sentinel.universe import (
+    ARCHIVE_REMOVED,
     BROKER_POSITION_UNIVERSE_SOURCE,
     FREEDOM24_UNIVERSE_SOURCE,
+    archive_security,
     reconcile_universe_from_freedom24_default_list,
 )
 
@@ -88,9 +90,43 @@ async def test_reconcile_removes_non_held_security_missing_from_favorites(temp_d
     assert int(row["active"]) == 0
     assert int(row["allow_buy"]) == 0
     assert int(row["allow_sell"]) == 0
+    assert row["archive_reason"] == ARCHIVE_REMOVED
+    assert row["archived_at"] is not None
     assert imported is not None
 
 
+@pytest.mark.asyncio
+async def test_reconcile_reactivates_archived_favorite(temp_db):
+    await temp_db.upsert_security("AMD.EU", name="AMD", active=1)
+    await temp_db.save_prices("AMD.EU", [{"date": "2025-06-02", "close": 90.0}])
+    await archive_security(temp_db, "AMD.EU", ARCHIVE_REMOVED)
+    broker = _broker_with_favorites("AMD.EU")
+
+    result = await reconcile_universe_from_freedom24_default_list(temp_db, broker)
+
+    row = await temp_db.get_security("AMD.EU")
+    assert result.reactivated == ["AMD.EU"]
+    assert (int(row["active"]), int(row["allow_buy"]), int(row["allow_sell"])) == (1, 1, 1)
+    assert (row["archived_at"], row["archive_reason"]) == (None, None)
+    assert [p["date"] for p in await temp_db.get_prices("AMD.EU")] == ["2026-01-01", "2025-06-02"]
+
+
+@pytest.mark.asyncio
+async def test_archived_endpoint_lists_only_archived_securities(temp_db):
+    from sentinel.api.routers.securities import get_archived_securities
+
+    await temp_db.upsert_security("MOH.GR", name="Motor Oil", active=1)
+    await temp_db.upsert_security("OLD.EU", name="Deactivated by hand", active=0)
+    await archive_security(temp_db, "MOH.GR", ARCHIVE_REMOVED)
+    deps = MagicMock()
+    deps.db = temp_db
+
+    archived = await get_archived_securities(deps)
+
+    assert [(s["symbol"], s["archive_reason"]) for s in archived] == [("MOH.GR", ARCHIVE_REMOVED)]
+    assert archived[0]["archived_at"] is not None
+
+
 @pytest.mark.asyncio
 async def test_reconcile_disables_buys_for_held_security_missing_from_favorites(temp_db):
     await temp_db.upsert_security("ASML.EU", name="ASML", active=1, allow_buy=1, allow_sell=1)
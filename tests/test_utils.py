@@ -3,14 +3,16 @@
 These tests verify the intended behavior of utility functions:
 1. Fee calculations
 2. Position value calculations
+3. Timestamp formatting
 """
 
 from unittest.mock import AsyncMock
 
 import pytest
 
-from sentinel.utils.fees import FeeCalculator
+from sentinel.utils.fees import CostProfile, FeeCalculator, TransactionCostModel, validate_cost_profiles
 from sentinel.utils.positions import PositionCalculator
+from sentinel.utils.timestamps import to_iso_utc
 
 # =============================================================================
 # Fee Calculator Tests
@@ -167,6 +169,95 @@ class TestFeeCalculator:
         assert result["total_buy_value"] == 500.0
 
 
+class TestTransactionCostModel:
+    """Tests for per-exchange/currency transaction cost profiles."""
+
+    @pytest.fixture
+    def calculator(self):
+        settings = AsyncMock()
+        settings.get = AsyncMock(
+            side_effect=lambda key, default: {
+                "transaction_fee_fixed": 2.0,
+                "transaction_fee_percent": 0.2,
+                "transaction_fx_spread_percent": 0.5,
+                "transaction_min_commission": 0.0,
+                "transaction_cost_profiles": {
+                    "USD": {"fx_spread_percent": 0.25},
+                    "NYSE": {"fixed_fee": 1.0, "fee_percent": 0.1, "min_commission": 3.0},
+                },
+            }.get(key, default)
+        )
+        return FeeCalculator(settings=settings)
+
+    def test_fx_spread_only_applies_to_non_eur(self):
+        profile = CostProfile(fixed_fee=2.0, fee_pct=0.002, fx_spread_pct=0.005)
+
+        assert abs(profile.cost(1000.0, "EUR") - 4.0) < 0.001
+        assert abs(profile.cost(1000.0, "USD") - 9.0) < 0.001
+
+    def test_min_commission_floors_commission(self):
+        profile = CostProfile(fixed_fee=0.5, fee_pct=0.001, min_commission=3.0)
+
+        assert profile.commission(100.0) == 3.0
+        assert abs(profile.commission(10_000.0) - 10.5) < 0.001
+        assert profile.linear_terms("EUR") == (3.0, 0.001)
+
+    def test_market_profile_wins_over_currency(self):
+        market = CostProfile(fixed_fee=1.0)
+        usd = CostProfile(fixed_fee=5.0)
+        default = CostProfile(fixed_fee=2.0)
+        model = TransactionCostModel(default, {"NYSE": market, "USD": usd})
+
+        assert model.profile_for("NYSE", "USD") is market
+        assert model.profile_for("LSE", "USD") is usd
+        assert model.profile_for(None, "GBP") is default
+
+    @pytest.mark.asyncio
+    async def test_profiles_inherit_global_settings(self, calculator):
+        model = await calculator.get_cost_model()
+
+        usd = model.profile_for(None, "USD")
+        assert usd.fixed_fee == 2.0
+        assert abs(usd.fx_spread_pct - 0.0025) < 1e-9
+        assert abs(model.default.fx_spread_pct - 0.005) < 1e-9
+
+    @pytest.mark.asyncio
+    async def test_calculate_uses_exchange_profile(self, calculator):
+        # NYSE: max(€3 minimum, €1 + 0.1% of €1000) + 0.5% global FX spread on USD
+        fee = await calculator.calculate(1000.0, market_id="NYSE", currency="USD")
+        assert abs(fee - 8.0) < 0.01
+
+        # No profile for EUR or XETR: global €2 + 0.2%
+        fee = await calculator.calculate(1000.0, market_id="XETR", currency="EUR")
+        assert abs(fee - 4.0) < 0.01
+
+    @pytest.mark.asyncio
+    async def test_calculate_batch_prices_each_trade(self, calculator):
+        trades = [
+            {"action": "buy", "value_eur": 1000.0, "market_id": "NYSE", "currency": "USD"},
+            {"action": "sell", "value_eur": 1000.0},
+        ]
+        result = await calculator.calculate_batch(trades)
+
+        assert abs(result["buy_fees"] - 8.0) < 0.01
+        assert abs(result["sell_fees"] - 4.0) < 0.01
+
+    def test_validate_cost_profiles(self):
+        assert validate_cost_profiles({" USD ": {"fee_percent": 1}}) == {"USD": {"fee_percent": 1.0}}
+        assert validate_cost_profiles(None) == {}
+
+        for bad in (
+            [],
+            {"USD": 1.0},
+            {"USD": {"spread": 0.1}},
+            {"USD": {"min_commission": -1}},
+            {"USD": {"fixed_fee": True}},
+            {"": {"fixed_fee": 1}},
+        ):
+            with pytest.raises(ValueError):
+                validate_cost_profiles(bad)
+
+
 # =============================================================================
 # Position Calculator Tests
 # =============================================================================
@@ -371,6 +462,21 @@ class TestPositionCalculator:
         assert result["total_value_eur"] == 0.0
 
 
+# =============================================================================
+# Timestamp Tests
+# =============================================================================
+
+
+class TestToIsoUtc:
+    """Tests for to_iso_utc."""
+
+    def test_formats_unix_timestamp_in_utc(self):
+        assert to_iso_utc(1792130400) == "2026-10-16T06:00:00+00:00"
+
+    def test_none_stays_none(self):
+        assert to_iso_utc(None) is None
+
+
 # =============================================================================
 # Edge Cases and Error Handling
 # =============================================================================
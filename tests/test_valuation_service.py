@@ -58,6 +58,7 @@ async def test_current_valuation_falls_back_to_database_account_state_when_broke
             }
         ]
     )
+    db.get_short_positions = AsyncMock(return_value=[])
     db.get_cash_balances = AsyncMock(return_value={"EUR": 50.0})
     db.get_all_securities = AsyncMock(return_value=[{"symbol": "TEST.EU", "currency": "EUR", "name": "Test"}])
 
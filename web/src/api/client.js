@@ -130,6 +130,14 @@ export const updateSecurityPreference = ({ symbol, user_multiplier, analysis })
     method: 'POST',
     body: JSON.stringify({ symbol, user_multiplier, analysis }),
   });
+export const getSecurityNotes = (symbol) => request(`/securities/${encodeURIComponent(symbol)}/notes`);
+export const saveSecurityNotes = (symbol, body) =>
+  request(`/securities/${encodeURIComponent(symbol)}/notes`, {
+    method: 'PUT',
+    body: JSON.stringify({ body }),
+  });
+export const securityAttachmentUrl = (symbol, id) =>
+  `${API_BASE}/securities/${encodeURIComponent(symbol)}/attachments/${id}`;
 
 // Markets
 export const getMarketsStatus = () => request('/markets/status');
@@ -177,3 +185,12 @@ export const getPortfolioComposition = () => request('/portfolio/composition');
 // Forecasts
 export const getForecastStatus = () => request('/forecasts/status');
 export const getSecurityForecast = (symbol) => request(`/forecasts/${encodeURIComponent(symbol)}`);
+
+// Projections
+export const getMonteCarloProjection = ({ years, targetEur } = {}) => {
+  const params = new URLSearchParams();
+  if (years) params.append('years', years);
+  if (targetEur) params.append('target_eur', targetEur);
+  const query = params.toString();
+  return request(`/projections/monte-carlo${query ? `?${query}` : ''}`);
+};
@@ -0,0 +1,171 @@
+/**
+ * Monte Carlo Card
+ *
+ * Retirement / FIRE view of /api/projections/monte-carlo: simulated portfolio
+ * value year by year with planned monthly deposits. The median line sits in
+ * front of two bands (p25–p75 and p10–p90); with a target set, the card
+ * shows the odds of reaching it by the end of the horizon.
+ *
+ * Renders nothing while the endpoint is unavailable.
+ */
+import { useMemo, useState } from 'react';
+import { Card, Group, NumberInput, SegmentedControl, Stack, Text } from '@mantine/core';
+import {
+  Area,
+  CartesianGrid,
+  ComposedChart,
+  Line,
+  ReferenceLine,
+  ResponsiveContainer,
+  Tooltip,
+  XAxis,
+  YAxis,
+} from 'recharts';
+import { catppuccin } from '../theme';
+import { useMonteCarloProjection } from '../hooks/useMonteCarloProjection';
+import { formatCompact, formatEur, formatPercent } from '../utils/formatting';
+
+const HORIZONS = ['10', '20', '30', '40'];
+
+function ChartTooltip({ active, payload, label }) {
+  if (!active || !payload?.length) return null;
+  const row = payload[0]?.payload || {};
+  return (
+    <div
+      style={{
+        background: 'var(--mantine-color-dark-7)',
+        border: '1px solid var(--mantine-color-dark-4)',
+        padding: '6px 8px',
+        borderRadius: 0,
+        fontSize: 11,
+      }}
+    >
+      <div style={{ fontWeight: 600, marginBottom: 4 }}>Year {label}</div>
+      <div style={{ color: catppuccin.blue }}>Median: {formatEur(row.p50, 0)}</div>
+      <div style={{ color: 'var(--mantine-color-gray-5)' }}>
+        50%: {formatEur(row.p25, 0)} – {formatEur(row.p75, 0)}
+      </div>
+      <div style={{ color: 'var(--mantine-color-gray-5)' }}>
+        80%: {formatEur(row.p10, 0)} – {formatEur(row.p90, 0)}
+      </div>
+    </div>
+  );
+}
+
+export function MonteCarloCard() {
+  const [years, setYears] = useState('20');
+  const [target, setTarget] = useState('');
+  const targetEur = typeof target === 'number' && target > 0 ? target : null;
+  const { data, isLoading, isError } = useMonteCarloProjection(Number(years), targetEur);
+
+  const series = useMemo(
+    () =>
+      (data?.bands || []).map((b) => ({
+        ...b,
+        // Stacked areas: transparent base up to the low bound, then the visible spread.
+        outerLow: b.p10,
+        outerSpread: Math.max(0, b.p90 - b.p10),
+        innerLow: b.p25,
+        innerSpread: Math.max(0, b.p75 - b.p25),
+      })),
+    [data]
+  );
+
+  if (isLoading || isError || !data || series.length === 0) return null;
+
+  const last = series[series.length - 1];
+
+  return (
+    <Card p="sm" withBorder>
+      <Stack gap="xs">
+        <Group justify="space-between" align="center">
+          <Text size="xs" c="dimmed" fw={600} tt="uppercase">
+            Monte Carlo projection
+          </Text>
+          <SegmentedControl
+            size="xs"
+            value={years}
+            onChange={setYears}
+            data={HORIZONS.map((h) => ({ value: h, label: `${h}Y` }))}
+          />
+        </Group>
+
+        <Group gap="xl">
+          <Stack gap={0}>
+            <Text size="xs" c="dimmed">{years}Y median</Text>
+            <Text size="md" fw={600} c={catppuccin.blue}>
+              {formatEur(last.p50, 0)}
+            </Text>
+          </Stack>
+          <Stack gap={0}>
+            <Text size="xs" c="dimmed">80% range</Text>
+            <Text size="md" fw={500}>
+              {formatCompact(last.p10)} – {formatCompact(last.p90)}
+            </Text>
+          </Stack>
+          {data.probability !== null && (
+            <Stack gap={0}>
+              <Text size="xs" c="dimmed">Reach target</Text>
+              <Text size="md" fw={600} c={data.probability >= 0.5 ? catppuccin.green : catppuccin.red}>
+                {formatPercent(data.probability * 100, false, 0)}
+              </Text>
+            </Stack>
+          )}
+        </Group>
+
+        <Group gap="xs" align="center">
+          <NumberInput
+            size="xs"
+            placeholder="Target (EUR)"
+            value={target}
+            onChange={setTarget}
+            min={0}
+            step={10000}
+            thousandSeparator=","
+            style={{ width: 160 }}
+          />
+          <Text size="xs" c="dimmed">
+            {formatPercent(data.expected_annual_return_pct)} / yr, {formatPercent(data.annual_volatility_pct, false)} vol,{' '}
+            {formatEur(data.monthly_deposit_eur, 0)} / month
+          </Text>
+        </Group>
+
+        <div style={{ width: '100%', height: 200 }}>
+          <ResponsiveContainer width="100%" height="100%">
+            <ComposedChart data={series} margin={{ top: 10, right: 16, left: 0, bottom: 0 }}>
+              <CartesianGrid strokeDasharray="3 3" opacity={0.2} />
+              <XAxis dataKey="year" tick={{ fontSize: 10, fill: 'var(--mantine-color-gray-5)' }} />
+              <YAxis
+                tick={{ fontSize: 10, fill: 'var(--mantine-color-gray-5)' }}
+                tickFormatter={(v) => formatCompact(v, 0)}
+              />
+              <Tooltip content={<ChartTooltip />} />
+              <Area type="monotone" dataKey="outerLow" stackId="outer" stroke="none" fill="transparent" />
+              <Area
+                type="monotone"
+                dataKey="outerSpread"
+                stackId="outer"
+                stroke="none"
+                fill={catppuccin.blue}
+                fillOpacity={0.12}
+              />
+              <Area type="monotone" dataKey="innerLow" stackId="inner" stroke="none" fill="transparent" />
+              <Area
+                type="monotone"
+                dataKey="innerSpread"
+                stackId="inner"
+                stroke="none"
+                fill={catppuccin.blue}
+                fillOpacity={0.22}
+              />
+              <Line type="monotone" dataKey="p50" stroke={catppuccin.blue} strokeWidth={2} dot={false} />
+              {targetEur && (
+                <ReferenceLine y={targetEur} stroke={catppuccin.green} strokeDasharray="4 4" />
+              )}
+            </ComposedChart>
+          </ResponsiveContainer>
+        </div>
+      </Stack>
+    </Card>
+  );
+}
@@ -5,9 +5,10 @@
  * - Aliases and security metadata
  * - Price and forecast charts
  * - Clara preference, current action, and opportunity signals
+ * - Research notes and attachments
  */
 import { Fragment, useState, useEffect } from 'react';
-import { useQuery } from '@tanstack/react-query';
+import { useQuery, useQueryClient } from '@tanstack/react-query';
 import {
   Group,
   Stack,
@@ -20,13 +21,20 @@ import {
   Box,
   Tooltip,
   ActionIcon,
+  Textarea,
+  Anchor,
 } from '@mantine/core';
 import { IconTrash, IconAlertTriangle } from '@tabler/icons-react';
 import { SecurityChart } from './SecurityChart';
 import { SecurityForecastCard } from './SecurityForecastCard';
 import { catppuccin } from '../theme';
 import { formatCurrencySymbol as formatCurrency, formatPercent } from '../utils/formatting';
-import { getSecurityForecast } from '../api/client';
+import {
+  getSecurityForecast,
+  getSecurityNotes,
+  saveSecurityNotes,
+  securityAttachmentUrl,
+} from '../api/client';
 
 // Aliases is stored as a comma-separated string but TagsInput wants an array.
 function parseCommaSeparated(value) {
@@ -49,16 +57,24 @@ function selectForecastPoints(forecastData) {
 export function SecurityExpandedRow({ security, onUpdate, onDelete }) {
   const [isUpdating, setIsUpdating] = useState(false);
   const [localMultiplier, setLocalMultiplier] = useState(null);
+  const [localNote, setLocalNote] = useState(null);
+  const queryClient = useQueryClient();
   const { data: forecastData } = useQuery({
     queryKey: ['forecast', security?.symbol],
     queryFn: () => getSecurityForecast(security.symbol),
     enabled: Boolean(security?.symbol),
     staleTime: 5 * 60 * 1000,
   });
+  const { data: notesData } = useQuery({
+    queryKey: ['securityNotes', security?.symbol],
+    queryFn: () => getSecurityNotes(security.symbol),
+    enabled: Boolean(security?.symbol),
+  });
 
   // Reset local state when security changes
   useEffect(() => {
     setLocalMultiplier(null);
+    setLocalNote(null);
   }, [security?.symbol]);
 
   if (!security) return null;
@@ -99,6 +115,25 @@ export function SecurityExpandedRow({ security, onUpdate, onDelete }) {
   } = security;
 
   const forecastChartPoints = selectForecastPoints(forecastData);
+  const savedNote = notesData?.note?.body || '';
+  const attachments = notesData?.attachments || [];
+
+  // Notes are saved when the field loses focus; every save becomes a revision.
+  const handleNoteSave = async () => {
+    if (localNote === null || localNote.trim() === savedNote) {
+      setLocalNote(null);
+      return;
+    }
+    setIsUpdating(true);
+    try {
+      const saved = await saveSecurityNotes(symbol, localNote);
+      queryClient.setQueryData(['securityNotes', symbol], saved);
+      queryClient.invalidateQueries({ queryKey: ['unified'] });
+      setLocalNote(null);
+    } finally {
+      setIsUpdating(false);
+    }
+  };
 
   const storedMultiplier = Math.max(0, Math.min(1, localMultiplier ?? user_multiplier ?? 0.5));
   const preferenceTimestamp = user_multiplier_updated_at
@@ -346,6 +381,38 @@ export function SecurityExpandedRow({ security, onUpdate, onDelete }) {
             </Table.Tbody>
           </Table>
         </Box>
+
+        <Box>
+          <Group justify="space-between" mb={4}>
+            <Text size="xs" c="dimmed" fw={600} tt="uppercase">Research notes</Text>
+            {notesData?.note?.updated_at && (
+              <Text size="xs" c="dimmed">
+                {new Date(notesData.note.updated_at * 1000).toLocaleString()}
+                {notesData.revisions > 1 && ` · ${notesData.revisions} revisions`}
+              </Text>
+            )}
+          </Group>
+          <Textarea
+            value={localNote ?? savedNote}
+            onChange={(e) => setLocalNote(e.currentTarget.value)}
+            onBlur={handleNoteSave}
+            placeholder="Thesis, risks, things to check (markdown)"
+            autosize
+            minRows={2}
+            maxRows={12}
+            size="xs"
+            disabled={isUpdating}
+          />
+          {attachments.length > 0 && (
+            <Group gap="sm" mt={4}>
+              {attachments.map((a) => (
+                <Anchor key={a.id} href={securityAttachmentUrl(symbol, a.id)} size="xs">
+                  {a.filename}
+                </Anchor>
+              ))}
+            </Group>
+          )}
+        </Box>
       </Stack>
     </Box>
   );
@@ -17,7 +17,7 @@ import {
   Switch,
   UnstyledButton,
 } from '@mantine/core';
-import { IconAlertTriangle, IconChevronUp, IconChevronDown, IconSelector, IconChevronRight } from '@tabler/icons-react';
+import { IconAlertTriangle, IconNotes, IconChevronUp, IconChevronDown, IconSelector, IconChevronRight } from '@tabler/icons-react';
 import { SecurityExpandedRow } from './SecurityExpandedRow';
 import { formatCurrencySymbol as formatCurrency, formatPercent } from '../utils/formatting';
 
@@ -171,6 +171,7 @@ export function SecurityTable({ securities, onUpdate, onDelete }) {
               has_position,
               recommendation,
               price_warning,
+              notes,
               active,
               allow_buy,
               allow_sell,
@@ -224,6 +225,11 @@ export function SecurityTable({ securities, onUpdate, onDelete }) {
                         <Group gap="xs">
                           <Text fw={600} size="sm">{symbol}</Text>
                           {!active && <Badge color="gray" size="xs">Inactive</Badge>}
+                          {notes && (
+                            <Tooltip label={notes.excerpt || `${notes.attachments} attachment(s)`} multiline maw={320}>
+                              <IconNotes size={14} color="var(--mantine-color-dimmed)" />
+                            </Tooltip>
+                          )}
                         </Group>
                         <Text size="xs" c="dimmed" lineClamp={1}>{name}</Text>
                       </Box>
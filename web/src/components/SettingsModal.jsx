@@ -120,6 +120,22 @@ export function SettingsModal({ opened, onClose }) {
                 ]}
               />
 
+              <Select
+                label="Language & Format"
+                description="LED ticker text and the monthly PDF summary"
+                value={settings?.locale || 'en'}
+                onChange={(value) => handleChange('locale', value)}
+                data={[
+                  { value: 'en', label: 'English (1,234.56)' },
+                  { value: 'de', label: 'Deutsch (1.234,56)' },
+                  { value: 'fr', label: 'Français (1 234,56)' },
+                  { value: 'it', label: 'Italiano (1.234,56)' },
+                  { value: 'es', label: 'Español (1.234,56)' },
+                  { value: 'nl', label: 'Nederlands (1.234,56)' },
+                  { value: 'pt', label: 'Português (1.234,56)' },
+                ]}
+              />
+
               <NumberInput
                 label="Max Position %"
                 description="Maximum allocation to a single security"
@@ -161,6 +177,16 @@ export function SettingsModal({ opened, onClose }) {
                 suffix="%"
               />
 
+              <NumberInput
+                label="Min Cash Buffer"
+                description="Absolute cash minimum; the larger buffer applies"
+                value={settings?.min_cash_buffer_eur ?? 0}
+                onChange={(value) => handleChange('min_cash_buffer_eur', value)}
+                min={0}
+                max={1000000}
+                prefix="EUR "
+              />
+
               <NumberInput
                 label="Min Trade Value"
                 description="Minimum trade value in EUR"
@@ -197,6 +223,28 @@ export function SettingsModal({ opened, onClose }) {
                 decimalScale={2}
                 suffix="%"
               />
+
+              <NumberInput
+                label="Minimum Commission"
+                description="Lowest commission charged per trade"
+                value={settings?.transaction_min_commission ?? 0}
+                onChange={(value) => handleChange('transaction_min_commission', value)}
+                min={0}
+                max={50}
+                decimalScale={2}
+                prefix="EUR "
+              />
+
+              <NumberInput
+                label="FX Conversion Spread %"
+                description="Spread paid on trades in a non-EUR currency"
+                value={settings?.transaction_fx_spread_percent ?? 0}
+                onChange={(value) => handleChange('transaction_fx_spread_percent', value)}
+                min={0}
+                max={5}
+                decimalScale={2}
+                suffix="%"
+              />
             </Stack>
           </Tabs.Panel>
 
@@ -523,6 +571,41 @@ export function SettingsModal({ opened, onClose }) {
                 max={0.5}
                 decimalScale={3}
               />
+
+              <Divider label="Monte Carlo robustness" labelPosition="left" mt="md" />
+
+              <Switch
+                label="Monte Carlo check"
+                description="Re-score the top recommendations over resampled price paths"
+                checked={Boolean(settings?.planner_monte_carlo_enabled ?? false)}
+                onChange={(event) => handleChange('planner_monte_carlo_enabled', event.currentTarget.checked)}
+              />
+
+              <NumberInput
+                label="Paths"
+                value={settings?.planner_monte_carlo_paths ?? 500}
+                onChange={(value) => handleChange('planner_monte_carlo_paths', value)}
+                min={50}
+                max={10000}
+              />
+
+              <NumberInput
+                label="Horizon"
+                value={settings?.planner_monte_carlo_horizon_days ?? 20}
+                onChange={(value) => handleChange('planner_monte_carlo_horizon_days', value)}
+                min={1}
+                max={250}
+                suffix=" trading days"
+              />
+
+              <NumberInput
+                label="Top Recommendations"
+                description="How many recommendations, in execution order, are simulated"
+                value={settings?.planner_monte_carlo_top_k ?? 5}
+                onChange={(value) => handleChange('planner_monte_carlo_top_k', value)}
+                min={1}
+                max={50}
+              />
             </Stack>
           </Tabs.Panel>
 
@@ -0,0 +1,18 @@
+import { useQuery } from '@tanstack/react-query';
+import { getMonteCarloProjection } from '../api/client';
+
+/**
+ * Monte Carlo projection of the whole portfolio from
+ * /api/projections/monte-carlo. Simulation is seeded server-side, so the
+ * result only moves with positions, prices and deposits; refetch rarely.
+ */
+export function useMonteCarloProjection(years, targetEur) {
+  return useQuery({
+    queryKey: ['monte-carlo-projection', years, targetEur || null],
+    queryFn: () => getMonteCarloProjection({ years, targetEur }),
+    refetchInterval: 30 * 60 * 1000,
+    refetchOnWindowFocus: false,
+    retry: 1,
+    staleTime: 5 * 60 * 1000,
+  });
+}
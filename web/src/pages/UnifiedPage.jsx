@@ -34,6 +34,7 @@ import { PeriodStatsTable } from '../components/PeriodStatsTable';
 import { PortfolioRatingCard } from '../components/PortfolioRatingCard';
 import { CompositionCard } from '../components/CompositionCard';
 import { ForwardReturnCard } from '../components/ForwardReturnCard';
+import { MonteCarloCard } from '../components/MonteCarloCard';
 import LoadingState from '../components/LoadingState';
 import ErrorState from '../components/ErrorState';
 import {
@@ -87,6 +88,7 @@ const DEFAULT_COLLAPSED_WIDGETS = {
   'inactive-securities': true,
   composition: true,
   'forward-return': true,
+  'monte-carlo': true,
 };
 
 export function hasDisabledTradePermission(security) {
@@ -714,6 +716,14 @@ function UnifiedPage() {
           >
             <ForwardReturnCard />
           </CollapsibleWidget>
+          <CollapsibleWidget
+            id="monte-carlo"
+            title="Monte Carlo Projection"
+            collapsed={collapsedWidgets['monte-carlo']}
+            onToggle={toggleWidget}
+          >
+            <MonteCarloCard />
+          </CollapsibleWidget>
         </Stack>
       </div>
 